@@ -2,19 +2,18 @@ package main
 
 import (
 	"bytes"
-	"encoding/binary"
 	"errors"
 	"fmt"
-	"io"
 	"sync"
 	"sync/atomic"
+	"time"
 
-	"github.com/boltdb/bolt"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/lightningnetwork/lnd/chainntnfs"
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/htlcswitch"
 	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/roasbeef/btcd/btcec"
 	"github.com/roasbeef/btcd/chaincfg/chainhash"
 	"github.com/roasbeef/btcd/txscript"
@@ -22,13 +21,518 @@ import (
 	"github.com/roasbeef/btcutil"
 )
 
-// retributionBucket stores retribution state on disk between detecting a
-// contract breach, broadcasting a justice transaction that sweeps the channel,
-// and finally witnessing the justice transaction confirm on the blockchain. It
-// is critical that such state is persisted on disk, so that if our node
-// restarts at any point during the retribution procedure, we can recover and
-// continue from the persisted state.
-var retributionBucket = []byte("retribution")
+// defaultStuckRetributionTimeout is the default duration a retribution may
+// linger in a single non-terminal phase before the breach arbiter's health
+// check reports it as stuck.
+const defaultStuckRetributionTimeout = 24 * time.Hour
+
+// justiceBaseFee is the fee paid by a justice transaction before any fee bumps
+// have been applied, if no fee estimator is available. Each bump tier doubles
+// the fee paid, see justiceFee.
+const justiceBaseFee = btcutil.Amount(5000)
+
+// commitSweepBaseFee is the fee paid by the transaction sweeping our outputs
+// from the remote party's commitment transaction after a unilateral close,
+// unless the configured MinFeeRate calls for more.
+const commitSweepBaseFee = btcutil.Amount(5000)
+
+const (
+	// defaultBreachConfDepth is the default number of confirmations a
+	// breach transaction must reach before justice is served.
+	defaultBreachConfDepth = 1
+
+	// defaultJusticeConfDepth is the default number of confirmations a
+	// justice transaction must reach before the retribution is finalized.
+	defaultJusticeConfDepth = 1
+
+	// defaultJusticeFeeTarget is the default confirmation target, in
+	// blocks, used to estimate the fee of justice transactions.
+	defaultJusticeFeeTarget = 1
+
+	// maxRelaxedJusticeFeeTarget is the confirmation target, in blocks,
+	// used to estimate the fee of justice transactions whose inputs the
+	// breaching party can't race us for, and the largest target used for
+	// those whose inputs they may only claim once a CSV delay has elapsed.
+	maxRelaxedJusticeFeeTarget = 144
+
+	// defaultJusticeRelayTimeout is the default time a justice
+	// transaction is given to reach the mempool, if VerifyJusticeRelay is
+	// set.
+	defaultJusticeRelayTimeout = 30 * time.Second
+
+	// defaultCommitSweepConfDepth is the default number of confirmations
+	// the sweep of a unilaterally closed channel must reach before the
+	// channel is marked as fully closed.
+	defaultCommitSweepConfDepth = 1
+
+	// defaultCloseSafetyDepth is the default number of
+	// confirmations the transaction unilaterally closing a channel must
+	// reach before the channel is marked as fully closed.
+	defaultCloseSafetyDepth = 1
+)
+
+// markChanClosedAttempts is the number of times marking a channel as fully
+// closed is attempted once justice has been served, before giving up.
+const markChanClosedAttempts = 3
+
+// markChanClosedBackoff is the delay between attempts to mark a channel as
+// fully closed.
+var markChanClosedBackoff = time.Second
+
+// startupRegisterAttempts is the number of times the registration for the
+// confirmation of a breach transaction is attempted during startup, before
+// it's deferred to the retributionReaper.
+const startupRegisterAttempts = 4
+
+// startupRegisterBackoff is the delay before the second attempt to register
+// for the confirmation of a breach transaction during startup, which doubles
+// with each further attempt.
+var startupRegisterBackoff = 250 * time.Millisecond
+
+// retributionReapInterval is the interval at which the store is scanned for
+// the records of completed retributions whose removal failed, so that it may
+// be retried.
+var retributionReapInterval = time.Minute
+
+// maxReapsPerInterval bounds the number of removals of completed retributions
+// attempted per retributionReapInterval.
+const maxReapsPerInterval = 10
+
+// reapFailureAlertThreshold is the number of consecutive failures to remove
+// the record of a completed retribution, after which the operator is alerted.
+const reapFailureAlertThreshold = 5
+
+// deferOutputBackoff is the delay between attempts to hand an output off to
+// the ExternalSweeper.
+var deferOutputBackoff = time.Second
+
+// justiceRelayAttempts is the number of times a justice transaction is
+// broadcast, if VerifyJusticeRelay is set, before we cease verifying that it
+// reaches the mempool.
+const justiceRelayAttempts = 3
+
+// justiceRacePollInterval is the interval at which the mempool is polled for
+// transactions competing with an unconfirmed justice transaction, if
+// JusticeFeeRace is set.
+var justiceRacePollInterval = 5 * time.Second
+
+// maxJusticeOutbidDoublings is the maximum number of times the fee of a justice
+// transaction is doubled in a single attempt to outbid a competing
+// transaction.
+const maxJusticeOutbidDoublings = 8
+
+// ErrJusticeBroadcast is returned when attempting to cancel a retribution whose
+// justice transaction has already been broadcast, and can no longer be
+// withdrawn.
+var ErrJusticeBroadcast = errors.New("justice transaction already broadcast")
+
+// ErrNoBreachProof is returned when no proof of breach has been recorded for a
+// channel, e.g. as its breach was detected before proofs were recorded.
+var ErrNoBreachProof = errors.New("no breach proof recorded")
+
+// ErrNotAwaitingApproval is returned when attempting to approve a retribution
+// whose justice transaction isn't awaiting the operator's approval.
+var ErrNotAwaitingApproval = errors.New("retribution not awaiting approval")
+
+// errRetributionCancelled is returned when a justice transaction isn't
+// broadcast because the retributions it would serve have been cancelled.
+var errRetributionCancelled = errors.New("retribution cancelled")
+
+// errBreachArbiterStopped is returned when attempting to start a breach
+// arbiter which has already been stopped.
+var errBreachArbiterStopped = errors.New("breach arbiter stopped")
+
+// ErrRevocationSecretMismatch is returned when the revocation secret within the
+// sign descriptor of a revoked output doesn't correspond to the revocation key
+// within the output's witness script, such that any witness generated for it
+// would be invalid.
+var ErrRevocationSecretMismatch = errors.New("revocation secret doesn't " +
+	"match revoked output's script")
+
+// ErrBreachAlreadyResolved is returned when attempting to recover the breach of
+// a channel whose retribution has already been completed. As the breached
+// outputs have been swept, pursuing it again could only fail.
+var ErrBreachAlreadyResolved = errors.New("breach already resolved")
+
+// BreachConfig bundles the required subsystems used by the breach arbiter. An
+// instance of BreachConfig is passed to newBreachArbiter during instantiation.
+type BreachConfig struct {
+	// ChainIO is used by the breach arbiter to determine the current height
+	// of the blockchain, which is used as the height hint when registering
+	// for confirmation notifications.
+	ChainIO lnwallet.BlockChainIO
+
+	// CloseLink allows the breach arbiter to shutdown any channel links for
+	// which it detects a breach, ensuring no further activity will
+	// continue across the link. The method accepts the link's channel
+	// point and a close type.
+	CloseLink func(*wire.OutPoint, htlcswitch.ChannelCloseType)
+
+	// DeleteChanState, if non-nil, deletes the state of a breached
+	// channel, marking it as pending close with the given summary. If nil,
+	// the channel's DeleteState method is used.
+	DeleteChanState func(*lnwallet.LightningChannel,
+		*channeldb.ChannelCloseSummary) error
+
+	// DB provides access to the user's channels, allowing the breach
+	// arbiter to determine the current state of a user's channels, and how
+	// it should respond to channel closure.
+	DB *channeldb.DB
+
+	// Estimator is used by the breach arbiter to determine an appropriate
+	// fee level when generating, signing, and broadcasting sweep
+	// transactions.
+	Estimator lnwallet.FeeEstimator
+
+	// Notifier provides a publish/subscribe interface for event driven
+	// notifications regarding the confirmation of txids.
+	Notifier chainntnfs.ChainNotifier
+
+	// Wallet is the wallet used to broadcast the sweep transactions
+	// crafted by the breach arbiter.
+	Wallet *lnwallet.LightningWallet
+
+	// Signer is used to sign every input of the sweep transactions crafted
+	// by the breach arbiter, allowing signing to be delegated to e.g. a
+	// remote signer or HSM. If nil, the Wallet's signer is used.
+	Signer lnwallet.Signer
+
+	// SweepScriptGen is a factory method that returns a fresh output
+	// script which the breach arbiter should sweep funds to. If nil, a
+	// fresh p2wkh script is obtained from the Wallet via
+	// newSweepPkScript.
+	SweepScriptGen func() ([]byte, error)
+
+	// PenaltySweepScriptGen, if non-nil, returns a fresh output script to
+	// which the penalty recovered from the breaching party, being the
+	// value of the revoked output and any HTLC outputs, is swept. Our own
+	// output is then swept separately to a script from SweepScriptGen,
+	// rather than the two being commingled within a single output.
+	PenaltySweepScriptGen func() ([]byte, error)
+
+	// SweepScriptPoolSize, if non-zero, is the number of fresh sweep
+	// scripts generated ahead of time via SweepScriptGen, and topped up in
+	// the background as they're handed out. This spares the crafting of
+	// justice transactions a round trip to the wallet at the time they're
+	// broadcast. Each script is still only ever used once.
+	SweepScriptPoolSize int
+
+	// Store is a persistent resource that maintains information regarding
+	// breached channels. This is used in conjunction with DB to recover
+	// from crashes, restarts, or other failures.
+	Store RetributionStore
+
+	// SweepAmountPolicy decides how the value swept by the justice and
+	// commitment sweep transactions is distributed across their outputs,
+	// and ensures that none of them are dust. If nil, an EvenSweepPolicy
+	// using the SweepDustThreshold, that assigns any rounding remainder to
+	// the first output, is used.
+	SweepAmountPolicy SweepAmountPolicy
+
+	// SweepDustThreshold is the smallest output value produced by the
+	// justice, commitment sweep and CPFP transactions, regardless of the
+	// SweepAmountPolicy. Raising it above the network's dust limit avoids
+	// creating economically marginal outputs: a penalty too small to be
+	// swept to its own output is swept along with our own funds, and a
+	// commitment sweep that would fall below it isn't made. It defaults
+	// to, and is never lower than, the network's dust limit.
+	SweepDustThreshold btcutil.Amount
+
+	// DataLossSuspected, if non-nil, reports whether our state for the
+	// given channel may be stale, e.g. because it was restored from a
+	// backup. In that case a commitment that appears revoked to us may in
+	// fact be the remote party's latest state, so rather than exacting
+	// retribution, the breach arbiter only sweeps our own output.
+	DataLossSuspected func(chanPoint *wire.OutPoint) bool
+
+	// WalletLocked, if non-nil, reports whether the wallet is locked, in
+	// which case the justice transaction can't be signed. Retributions
+	// are then blocked until the wallet is unlocked, as signalled via
+	// WalletUnlocked. Regardless, a retribution is also blocked should
+	// the Signer fail with lnwallet.ErrWalletLocked.
+	WalletLocked func() bool
+
+	// ResolveHTLC, if non-nil, is used to inform the htlc switch of the
+	// outcome of each HTLC swept by a justice transaction, so that any
+	// payment circuits through the breached channel are torn down. A
+	// non-nil preimage indicates the HTLC should be settled, otherwise it
+	// should be failed.
+	ResolveHTLC func(payHash [32]byte, amt lnwire.MilliSatoshi,
+		preimage *[32]byte) error
+
+	// Events, if non-nil, is notified of the notable events in the
+	// lifecycle of each retribution, such as those requiring action by
+	// the operator.
+	Events BreachEventNotifier
+
+	// BackupPruner, if non-nil, is notified once a channel has been fully
+	// resolved, either after justice has been served or after a
+	// unilateral close by the remote party has been swept, such that the
+	// static backup of the channel is no longer retained.
+	BackupPruner BackupPruner
+
+	// CompactSignDescriptors, if true, persists only a reference to the
+	// sign descriptors of the commitment outputs of each retribution,
+	// being the channel point, output index and revoked state number,
+	// rather than the full descriptors. Upon recovery, the descriptors
+	// are re-derived from the channel's static backup, as fetched via
+	// FetchChannelBackup, which must be set for this to take effect. The
+	// descriptors of HTLC outputs, which the backup can't reproduce, are
+	// persisted in full regardless.
+	CompactSignDescriptors bool
+
+	// FetchChannelBackup returns the static backup of the given channel,
+	// from which the sign descriptors of retributions persisted with
+	// CompactSignDescriptors are re-derived.
+	FetchChannelBackup func(chanPoint *wire.OutPoint) (
+		*channeldb.ChannelBackup, error)
+
+	// ConsolidationManager, if non-nil, is handed each output of our
+	// justice and commitment sweep transactions once they've confirmed,
+	// such that these breach-derived outputs can later be consolidated
+	// in batches during periods of low fees.
+	ConsolidationManager ConsolidationManager
+
+	// MetricsRegistry, if non-nil, is the registry with which the breach
+	// arbiter's metrics are registered upon starting, such that they can
+	// be scraped by a metrics system such as Prometheus.
+	MetricsRegistry MetricsRegistry
+
+	// DeferCommitSweeps, if true, causes our outputs within the remote
+	// party's commitment transaction to be handed off to the
+	// ExternalSweeper after a unilateral close, rather than being swept
+	// by the breach arbiter itself. The outputs remain persisted until
+	// the handoff succeeds. It's ignored unless an ExternalSweeper is
+	// set, and by default our outputs are swept right away.
+	DeferCommitSweeps bool
+
+	// ExternalSweeper takes over the sweeping of the outputs deferred by
+	// DeferCommitSweeps.
+	ExternalSweeper ExternalSweeper
+
+	// JusticeTxMarker, if non-empty, is carried by an OP_RETURN output
+	// appended to each justice transaction, e.g. to tag it for the
+	// operator's accounting. It may be at most MaxDataCarrierSize bytes,
+	// so that justice transactions remain standard.
+	JusticeTxMarker []byte
+
+	// SnapshotBalanceTolerance is the largest discrepancy tolerated
+	// between the local balance reported by a breached channel's state
+	// snapshot and the value of our output within the breach transaction.
+	// Should they differ by more, the snapshot is deemed inconsistent, and
+	// the value of the output is persisted as our settled balance instead.
+	SnapshotBalanceTolerance btcutil.Amount
+
+	// CommitSweepBatchWindow, if non-zero, is the duration for which the
+	// sweep of our outputs after a unilateral close is held back, so that
+	// the outputs of any further channels closed unilaterally in the
+	// meantime are swept by the same transaction, amortizing its fee.
+	CommitSweepBatchWindow time.Duration
+
+	// BatchPeerJustice, if true, causes the retributions for concurrent
+	// breaches of several channels with the same peer to be served by a
+	// single justice transaction, which is broadcast once all of their
+	// breach transactions have confirmed. A retribution whose breach has
+	// confirmed only waits for the others until halfway to the height at
+	// which the breaching party may first claim its outputs, after which
+	// it's served alone.
+	BatchPeerJustice bool
+
+	// StuckRetributionTimeout is the maximum duration a retribution may
+	// remain in any single non-terminal phase before HealthCheck reports
+	// it as stuck. If zero, defaultStuckRetributionTimeout is used.
+	StuckRetributionTimeout time.Duration
+
+	// BreachConfDepth is the number of confirmations a breach transaction
+	// must reach before justice is served. As the fee of the breach
+	// transaction is set by the cheating party, this only trades off the
+	// risk of a reorg against the time left to sweep the revoked outputs.
+	// If zero, defaultBreachConfDepth is used.
+	BreachConfDepth uint32
+
+	// JusticeConfDepth is the number of confirmations a justice
+	// transaction must reach before the retribution is finalized. If
+	// zero, defaultJusticeConfDepth is used.
+	JusticeConfDepth uint32
+
+	// JusticeFeeTarget is the confirmation target, in blocks, used to
+	// estimate the fee of justice transactions. As we fully control their
+	// fee, and they're time sensitive, this should be aggressive. If zero,
+	// defaultJusticeFeeTarget is used.
+	JusticeFeeTarget uint32
+
+	// MinFeeRate is the minimum fee rate, in satoshis per weight unit,
+	// paid by the transactions sweeping breached or force closed
+	// channels. It's applied as a floor beneath whatever the Estimator
+	// returns, so that our transactions aren't rejected for paying less
+	// than the network's minimum relay fee should it underestimate. If
+	// zero, no floor is applied.
+	MinFeeRate btcutil.Amount
+
+	// CommitSweepConfDepth is the number of confirmations the transaction
+	// sweeping our outputs from the remote party's commitment transaction
+	// must reach, after a unilateral close, before the channel is marked
+	// as fully closed. If zero, defaultCommitSweepConfDepth is used.
+	CommitSweepConfDepth uint32
+
+	// UnilateralCloseSafetyDepth is the number of confirmations the
+	// transaction unilaterally closing a channel must reach before the
+	// channel is marked as fully closed. Until then, the channel remains
+	// pending close, so that its closure is watched over anew should the
+	// closing transaction be reorged out. If zero,
+	// defaultCloseSafetyDepth is used.
+	UnilateralCloseSafetyDepth uint32
+
+	// ConfWaiter determines how the breach arbiter waits for the
+	// transactions it tracks to confirm. If nil, a PollingConfWaiter is
+	// used should ConfPollInterval be non-zero, and a NotifierConfWaiter
+	// backed by the Notifier otherwise.
+	ConfWaiter ConfirmationWaiter
+
+	// ConfPollInterval, if non-zero and no ConfWaiter is set, is the
+	// interval at which ChainIO is polled for the confirmation of tracked
+	// transactions, in place of relying on the Notifier's confirmation
+	// notifications.
+	ConfPollInterval time.Duration
+
+	// JusticeApprovalThreshold, if non-zero, is the value at risk above
+	// which a retribution's justice transaction isn't broadcast until the
+	// operator approves it via ApproveRetribution. Retributions at or
+	// below the threshold are carried out without approval.
+	JusticeApprovalThreshold btcutil.Amount
+
+	// JusticeApprovalTimeout, if non-zero, is the time a retribution
+	// awaits approval before JusticeApprovalAutoApprove decides its fate.
+	// If zero, approval is awaited indefinitely.
+	JusticeApprovalTimeout time.Duration
+
+	// JusticeApprovalAutoApprove, if true, causes a retribution whose
+	// approval has timed out to be approved automatically. Otherwise, the
+	// retribution is escalated via Events.ManualIntervention, and
+	// continues to await approval.
+	JusticeApprovalAutoApprove bool
+
+	// JusticeBumpSchedule lists the number of blocks that may elapse
+	// after a justice transaction is broadcast without it confirming,
+	// before its fee is bumped to the next tier. Each milestone reached
+	// doubles the fee of the justice transaction, which is then replaced.
+	// Bumps are driven by block notifications rather than wall-clock
+	// timers, so their timing is robust to variable block intervals. If
+	// empty, the fee of the justice transaction is never bumped.
+	JusticeBumpSchedule []uint32
+
+	// JusticeMinBumpBlocks is the minimum number of blocks that must
+	// elapse between successive fee bumps of a justice transaction,
+	// counting from its broadcast, regardless of the JusticeBumpSchedule.
+	// It bounds the frequency of bumps should the schedule be
+	// misconfigured. If zero, bumps are spaced by the schedule alone.
+	JusticeMinBumpBlocks uint32
+
+	// JusticeMinBumpInterval is the minimum duration that must elapse
+	// between successive fee bumps of a justice transaction, counting
+	// from its broadcast, so that rapid block production doesn't cause
+	// the fee to escalate within moments. If zero, bumps are spaced by
+	// the schedule alone.
+	JusticeMinBumpInterval time.Duration
+
+	// JusticeConfTimeout is the maximum duration a retribution may await
+	// confirmation of its justice transaction, across all fee bumps and
+	// replacements. Once exceeded, the retribution requires manual
+	// intervention: its justice transaction is no longer bumped,
+	// replaced or re-derived, and the operator is alerted. The
+	// retribution remains persisted, and is still finalized should a
+	// justice transaction confirm. If zero, a retribution awaits
+	// confirmation indefinitely.
+	JusticeConfTimeout time.Duration
+
+	// TxInMempool, if non-nil, reports whether the given transaction is
+	// within the mempool of the backing node. It allows a justice
+	// transaction that has been evicted from the mempool to be re-derived
+	// at the current fee estimate. If nil, eviction isn't detected.
+	TxInMempool func(txid *chainhash.Hash) (bool, error)
+
+	// BroadcastTargets are additional endpoints to which justice
+	// transactions are broadcast alongside the wallet, so that the loss or
+	// censorship of a transaction by any single backend doesn't prevent
+	// justice from being served.
+	BroadcastTargets []BroadcastTarget
+
+	// MempoolSpends, if non-nil, returns the transactions within the
+	// mempool of the backing node that spend any of the given outpoints.
+	// It's required by JusticeFeeRace.
+	MempoolSpends func(ops []wire.OutPoint) ([]MempoolSpend, error)
+
+	// JusticeFeeRace, if set, polls the mempool for transactions
+	// competing with an unconfirmed justice transaction by spending any
+	// of the same outputs, e.g. the breaching party sweeping their revoked
+	// output, and replaces the justice transaction with one outbidding any
+	// competitor paying a higher fee. It has no effect unless
+	// MempoolSpends is set.
+	JusticeFeeRace bool
+
+	// VerifyJusticeRelay, if set, verifies that each justice transaction
+	// broadcast reaches the mempool of the backing node within
+	// JusticeRelayTimeout, re-broadcasting it otherwise. This guards
+	// against backends whose PublishTransaction returns before the
+	// transaction is accepted, and which may silently fail to relay it.
+	// It has no effect unless MempoolSpends is set.
+	VerifyJusticeRelay bool
+
+	// JusticeRelayTimeout is the time a justice transaction is given to
+	// reach the mempool, if VerifyJusticeRelay is set. If zero,
+	// defaultJusticeRelayTimeout is used.
+	JusticeRelayTimeout time.Duration
+
+	// RetainBreachEvidence, if set, archives the retribution of each
+	// breach once justice has been served, along with its outcome, rather
+	// than deleting it. Archived breaches can be read via
+	// ArchivedBreaches.
+	RetainBreachEvidence bool
+
+	// JusticeBumpCPFP, if set, bumps the fee of a stuck justice
+	// transaction by broadcasting a child spending its output with a fee
+	// sufficient for the pair to pay the bumped fee rate (CPFP), rather
+	// than replacing it (RBF). This avoids re-signing the breached
+	// outputs, but requires the wallet to know of the justice
+	// transaction's output, otherwise we fall back to RBF. Wallet coins
+	// are added to the child should the justice output alone not cover
+	// its fee.
+	JusticeBumpCPFP bool
+
+	// MaxObserverWorkers, if non-zero, bounds the number of goroutines
+	// used to watch active channels for breaches. The channels are then
+	// multiplexed over a fixed pool of workers, rather than being watched
+	// by a dedicated breachObserver goroutine each.
+	MaxObserverWorkers int
+
+	// MaxActiveRetributions, if non-zero, bounds the number of
+	// retributions carried out concurrently. A retribution only counts
+	// towards the limit once its breach has confirmed and its justice is
+	// ready to be broadcast, so those awaiting confirmation, approval, an
+	// unlocked wallet or the release of a broadcast hold never hold up
+	// others. Any further retributions are queued until one completes,
+	// those with the most value at risk being served first.
+	MaxActiveRetributions int
+
+	// JusticeSigningWorkers is the number of inputs of a justice
+	// transaction whose witnesses are generated concurrently, which speeds
+	// up the signing of breaches with many HTLCs when the signer is slow,
+	// e.g. remote. It must only exceed one if the signer is safe for
+	// concurrent use. If zero, inputs are signed one at a time.
+	JusticeSigningWorkers int
+
+	// JusticeSLA, if non-zero, is the maximum duration that may elapse
+	// between a breach first being seen and its justice transaction
+	// confirming. Any retribution pending for longer is reported as
+	// overdue by the health check.
+	JusticeSLA time.Duration
+
+	// SkipUneconomicSelfOutput, if true, leaves our own output of the
+	// breach transaction out of the justice transaction should its value
+	// not cover the fee its inclusion adds, as is done for HTLC outputs.
+	// The revoked output is always swept regardless of its value.
+	SkipUneconomicSelfOutput bool
+}
 
 // breachArbiter is a special subsystem which is responsible for watching and
 // acting on the detection of any attempted uncooperative channel breaches by
@@ -37,24 +541,148 @@ var retributionBucket = []byte("retribution")
 // expected that the logic in this file never gets executed, but it is
 // important to have it in place just in case we encounter cheating channel
 // counterparties.
-// TODO(roasbeef): closures in config for subsystem pointers to decouple?
 type breachArbiter struct {
-	wallet     *lnwallet.LightningWallet
-	db         *channeldb.DB
-	notifier   chainntnfs.ChainNotifier
-	chainIO    lnwallet.BlockChainIO
-	estimator  lnwallet.FeeEstimator
-	htlcSwitch *htlcswitch.Switch
-
-	retributionStore RetributionStore
+	cfg *BreachConfig
 
 	// breachObservers is a map which tracks all the active breach
 	// observers we're currently managing. The key of the map is the
-	// funding outpoint of the channel, and the value is a channel which
+	// funding outpoint of the channel, and the value holds a channel which
 	// will be closed once we detect that the channel has been
 	// cooperatively closed, thereby killing the goroutine and freeing up
 	// resources.
-	breachObservers map[wire.OutPoint]chan struct{}
+	//
+	// NOTE: The map is only ever modified by the contractObserver
+	// goroutine, while holding observerMtx, but may be read from any
+	// goroutine holding a read lock.
+	breachObservers map[wire.OutPoint]*observerSignals
+
+	// observerMtx guards breachObservers.
+	observerMtx sync.RWMutex
+
+	// observerPool, if non-nil, is the bounded pool of workers over which
+	// the active channels are watched, in place of launching a
+	// breachObserver goroutine per channel.
+	observerPool *observerPool
+
+	// retributionSlots, if non-nil, bounds the number of retributions
+	// carried out concurrently.
+	retributionSlots *retributionSlots
+
+	// sweepScripts, if non-nil, is the pool of pre-generated scripts our
+	// sweeps pay to.
+	sweepScripts *sweepScriptPool
+
+	// observerActive is set to 1 while the contractObserver goroutine is
+	// running, and 0 otherwise.
+	//
+	// NOTE: This MUST be used atomically.
+	observerActive int32
+
+	// retMtx guards activeRetributions.
+	retMtx sync.Mutex
+
+	// activeRetributions tracks the current phase of each retribution
+	// that is being carried out by an exactRetribution goroutine, keyed by
+	// the channel point of the breached channel.
+	activeRetributions map[wire.OutPoint]*retributionStatus
+
+	// coopMtx guards coopCloses.
+	coopMtx sync.Mutex
+
+	// coopCloses maps the channel point of each channel we're
+	// cooperatively closing to the txid of its closing transaction. Should
+	// such a channel be breached, the closing transaction may confirm in
+	// place of the breach transaction.
+	coopCloses map[wire.OutPoint]chainhash.Hash
+
+	// unverifiedRetributions is the set of persisted retributions whose
+	// breach transaction could not be corroborated by the chain during
+	// startup. These are flagged for the operator instead of being acted
+	// upon.
+	unverifiedRetributions map[wire.OutPoint]struct{}
+
+	// unreconciledCloses holds the breached channels whose state failed
+	// to be deleted during startup, and which thus remain open within
+	// channeldb. Their close is retried before their retribution is
+	// finalized.
+	unreconciledCloses map[wire.OutPoint]*unreconciledClose
+
+	// unregisteredRetributions holds the retributions whose registration
+	// for the confirmation of their breach transaction failed during
+	// startup, keyed by channel point. They're re-registered by the
+	// retributionReaper, and are guarded by retMtx.
+	unregisteredRetributions map[wire.OutPoint]*unregisteredRetribution
+
+	// storeRecovery summarizes the recovery of the retribution store
+	// performed during startup.
+	storeRecovery *RetributionRecovery
+
+	// batchMtx guards peerBatches.
+	batchMtx sync.Mutex
+
+	// peerBatches holds, for each peer, the batch of retributions whose
+	// justice is to be served by a single transaction. It is only used if
+	// BatchPeerJustice is set.
+	peerBatches map[[33]byte]*peerJusticeBatch
+
+	// pendingCommitBatch is the batch of commitment sweeps currently
+	// waiting to be swept, or nil if there are none. It is only used if
+	// CommitSweepBatchWindow is set, and is guarded by batchMtx.
+	pendingCommitBatch *commitSweepBatch
+
+	// unlockMtx guards walletUnlocked.
+	unlockMtx sync.Mutex
+
+	// walletUnlocked is closed, and replaced, each time the wallet is
+	// unlocked, resuming the retributions blocked on the locked wallet.
+	walletUnlocked chan struct{}
+
+	// holdMtx guards broadcastHold and holdReleased.
+	holdMtx sync.Mutex
+
+	// broadcastHold is true while the broadcast of new justice
+	// transactions is held by the operator.
+	broadcastHold bool
+
+	// holdReleased is closed, and replaced, each time the broadcast hold
+	// is released, resuming the retributions it held.
+	holdReleased chan struct{}
+
+	// hitMtx guards hitClients and nextHitClientID.
+	hitMtx sync.Mutex
+
+	// hitClients are the subscribers to the blacklist hits, keyed by
+	// their client ID.
+	hitClients map[uint32]*BlacklistHitSubscription
+
+	// nextHitClientID is the ID given to the next subscriber to the
+	// blacklist hits.
+	nextHitClientID uint32
+
+	// statsMtx guards stats.
+	statsMtx sync.Mutex
+
+	// stats accumulates the latencies observed between detecting a breach
+	// and serving justice, which are exposed via Stats.
+	stats BreachStats
+
+	// observeTimeToJustice, if non-nil, records the time between
+	// detecting a breach and its justice transaction confirming, in
+	// seconds, within the histogram registered with the MetricsRegistry.
+	observeTimeToJustice func(float64)
+
+	// commitSweeps persists the sweeps of our outputs from the remote
+	// party's commitment transactions after unilateral closes, so they
+	// can be resumed across restarts.
+	commitSweeps *commitSweepStore
+
+	// closeWatches persists the height hints of the pending closes being
+	// watched, so they're watched from the same height across restarts.
+	closeWatches *closeWatchStore
+
+	// restoredChans persists the channels marked as restored from a
+	// backup, whose state may be stale.
+	restoredChans *restoredChanStore
 
 	// breachedContracts is a channel which is used internally within the
 	// struct to send the necessary information required to punish a
@@ -67,12 +695,28 @@ type breachArbiter struct {
 	// be watched.
 	newContracts chan *lnwallet.LightningChannel
 
+	// watchRequests delivers the channels registered via WatchChannel to
+	// the contractObserver goroutine.
+	watchRequests chan *watchRequest
+
+	// settleRequests delivers the channels settled via SettleChannel to
+	// the contractObserver goroutine.
+	settleRequests chan *settleRequest
+
 	// settledContracts is a channel by outside subsystems to notify
 	// the breachArbiter that a channel has peacefully been closed. Once a
 	// channel has been closed the arbiter no longer needs to watch for
 	// breach closes.
 	settledContracts chan *wire.OutPoint
 
+	// justiceInputs are the sources of the non-deterministic inputs to
+	// each justice transaction.
+	justiceInputs justiceTxInputs
+
+	// lifecycleMtx serializes Start and Stop, such that the breach
+	// arbiter is never stopped while it's still being started.
+	lifecycleMtx sync.Mutex
+
 	started uint32
 	stopped uint32
 	quit    chan struct{}
@@ -81,37 +725,165 @@ type breachArbiter struct {
 
 // newBreachArbiter creates a new instance of a breachArbiter initialized with
 // its dependent objects.
-func newBreachArbiter(wallet *lnwallet.LightningWallet, db *channeldb.DB,
-	notifier chainntnfs.ChainNotifier, h *htlcswitch.Switch,
-	chain lnwallet.BlockChainIO, fe lnwallet.FeeEstimator) *breachArbiter {
+func newBreachArbiter(cfg *BreachConfig) *breachArbiter {
+	if cfg.StuckRetributionTimeout == 0 {
+		cfg.StuckRetributionTimeout = defaultStuckRetributionTimeout
+	}
+	if cfg.BreachConfDepth == 0 {
+		cfg.BreachConfDepth = defaultBreachConfDepth
+	}
+	if cfg.JusticeConfDepth == 0 {
+		cfg.JusticeConfDepth = defaultJusticeConfDepth
+	}
+	if cfg.JusticeFeeTarget == 0 {
+		cfg.JusticeFeeTarget = defaultJusticeFeeTarget
+	}
+	if cfg.CommitSweepConfDepth == 0 {
+		cfg.CommitSweepConfDepth = defaultCommitSweepConfDepth
+	}
+	if cfg.JusticeRelayTimeout == 0 {
+		cfg.JusticeRelayTimeout = defaultJusticeRelayTimeout
+	}
+	if cfg.UnilateralCloseSafetyDepth == 0 {
+		cfg.UnilateralCloseSafetyDepth = defaultCloseSafetyDepth
+	}
+	if cfg.Signer == nil && cfg.Wallet != nil {
+		cfg.Signer = cfg.Wallet.Cfg.Signer
+	}
+	if cfg.ConfWaiter == nil && cfg.ConfPollInterval > 0 {
+		cfg.ConfWaiter = &PollingConfWaiter{
+			ChainIO:  cfg.ChainIO,
+			Interval: cfg.ConfPollInterval,
+		}
+	}
+	if cfg.SweepDustThreshold < lnwallet.DefaultDustLimit() {
+		cfg.SweepDustThreshold = lnwallet.DefaultDustLimit()
+	}
+	if cfg.SweepAmountPolicy == nil {
+		cfg.SweepAmountPolicy = &EvenSweepPolicy{
+			DustLimit: cfg.SweepDustThreshold,
+			Remainder: RemainderToFirst,
+		}
+	}
+	if cfg.SweepScriptGen == nil {
+		cfg.SweepScriptGen = func() ([]byte, error) {
+			return newSweepPkScript(cfg.Wallet)
+		}
+	}
+
+	b := &breachArbiter{
+		cfg:           cfg,
+		commitSweeps:  newCommitSweepStore(cfg.DB),
+		closeWatches:  newCloseWatchStore(cfg.DB),
+		restoredChans: newRestoredChanStore(cfg.DB),
+
+		breachObservers:        make(map[wire.OutPoint]*observerSignals),
+		activeRetributions:     make(map[wire.OutPoint]*retributionStatus),
+		peerBatches:            make(map[[33]byte]*peerJusticeBatch),
+		coopCloses:             make(map[wire.OutPoint]chainhash.Hash),
+		unverifiedRetributions: make(map[wire.OutPoint]struct{}),
+		unreconciledCloses:     make(map[wire.OutPoint]*unreconciledClose),
+		breachedContracts:      make(chan *retributionInfo),
+		newContracts:           make(chan *lnwallet.LightningChannel),
+		watchRequests:          make(chan *watchRequest),
+		settleRequests:         make(chan *settleRequest),
+		settledContracts:       make(chan *wire.OutPoint),
+		walletUnlocked:         make(chan struct{}),
+		holdReleased:           make(chan struct{}),
+		quit:                   make(chan struct{}),
+	}
 
-	return &breachArbiter{
-		wallet:     wallet,
-		db:         db,
-		notifier:   notifier,
-		chainIO:    chain,
-		htlcSwitch: h,
-		estimator:  fe,
+	if cfg.MaxObserverWorkers > 0 {
+		b.observerPool = newObserverPool(
+			cfg.MaxObserverWorkers, b.handleObserverEvent,
+			b.quit, &b.wg,
+		)
+	}
+	if cfg.MaxActiveRetributions > 0 {
+		b.retributionSlots = newRetributionSlots(
+			cfg.MaxActiveRetributions, b.quit,
+		)
+	}
+	if cfg.SweepScriptPoolSize > 0 {
+		b.sweepScripts = newSweepScriptPool(
+			cfg.SweepScriptPoolSize, cfg.SweepScriptGen,
+			b.quit, &b.wg,
+		)
+	}
+	b.justiceInputs = justiceTxInputs{
+		fee:         b.justiceFee,
+		sweepScript: b.sweepScript,
+	}
+	b.hitClients = make(map[uint32]*BlacklistHitSubscription)
+	b.unregisteredRetributions = make(
+		map[wire.OutPoint]*unregisteredRetribution,
+	)
+
+	return b
+}
 
-		retributionStore: newRetributionStore(db),
+// deleteChanState deletes the state of a breached channel, marking it as
+// pending close with the given summary, see DeleteChanState.
+func (b *breachArbiter) deleteChanState(channel *lnwallet.LightningChannel,
+	summary *channeldb.ChannelCloseSummary) error {
 
-		breachObservers:   make(map[wire.OutPoint]chan struct{}),
-		breachedContracts: make(chan *retributionInfo),
-		newContracts:      make(chan *lnwallet.LightningChannel),
-		settledContracts:  make(chan *wire.OutPoint),
-		quit:              make(chan struct{}),
+	if b.cfg.DeleteChanState != nil {
+		return b.cfg.DeleteChanState(channel, summary)
 	}
+
+	return channel.DeleteState(summary)
 }
 
 // Start is an idempotent method that officially starts the breachArbiter along
-// with all other goroutines it needs to perform its functions.
+// with all other goroutines it needs to perform its functions. A breach
+// arbiter that has already been stopped can't be started.
 func (b *breachArbiter) Start() error {
+	b.lifecycleMtx.Lock()
+	defer b.lifecycleMtx.Unlock()
+
 	if !atomic.CompareAndSwapUint32(&b.started, 0, 1) {
 		return nil
 	}
+	if atomic.LoadUint32(&b.stopped) == 1 {
+		return errBreachArbiterStopped
+	}
 
 	brarLog.Tracef("Starting breach arbiter")
 
+	if _, err := b.justiceMarkerScript(); err != nil {
+		return err
+	}
+
+	if err := b.registerMetrics(); err != nil {
+		return err
+	}
+
+	// Begin generating sweep scripts right away, so that they're at hand
+	// for any retribution resumed below.
+	if b.sweepScripts != nil {
+		b.sweepScripts.start()
+	}
+
+	// Before loading the pending retributions, we'll move aside any that
+	// have been corrupted, so that they can't prevent the remainder from
+	// being carried out.
+	recovery, err := b.cfg.Store.Recover()
+	if err != nil {
+		return err
+	}
+	for _, q := range recovery.Quarantined {
+		brarLog.Criticalf("Quarantined undecodable retribution of "+
+			"ChannelPoint(%v) stored under %x, manual inspection "+
+			"required: %v", q.ChanPoint, q.Key, q.Err)
+	}
+	if recovery.BucketErr != nil {
+		brarLog.Criticalf("Unable to read all pending retributions, "+
+			"manual inspection required: %v", recovery.BucketErr)
+	}
+	b.retMtx.Lock()
+	b.storeRecovery = recovery
+	b.retMtx.Unlock()
+
 	// We load all pending retributions from the database and
 	// deterministically reconstruct a channel close summary for each. In
 	// the event that a channel is still open after being breached, we can
@@ -119,9 +891,11 @@ func (b *breachArbiter) Start() error {
 	// breach is reflected in channeldb.
 	breachRetInfos := make(map[wire.OutPoint]retributionInfo)
 	closeSummaries := make(map[wire.OutPoint]channeldb.ChannelCloseSummary)
-	err := b.retributionStore.ForAll(func(ret *retributionInfo) error {
+	persistedRets := make(map[wire.OutPoint]struct{})
+	err = b.cfg.Store.ForAll(func(ret *retributionInfo) error {
 		// Extract emitted retribution information.
 		breachRetInfos[ret.chanPoint] = *ret
+		persistedRets[ret.chanPoint] = struct{}{}
 
 		// Deterministically reconstruct channel close summary from
 		// persisted retribution information and record in breach close
@@ -139,14 +913,117 @@ func (b *breachArbiter) Start() error {
 
 		return nil
 	})
-	if err != nil {
+
+	// A corrupted bucket has already been reported above, so we'll carry
+	// on with those retributions that could still be read.
+	if _, ok := err.(*retributionBucketError); !ok && err != nil {
 		return err
 	}
 
+	// Retributions persisted before the time their breach was first seen
+	// was recorded are deemed to have been first seen now, so that they
+	// remain subject to the JusticeSLA.
+	for chanPoint, retInfo := range breachRetInfos {
+		if !retInfo.breachDetectedAt.IsZero() {
+			continue
+		}
+
+		retInfo.breachDetectedAt = time.Now()
+		if err := b.cfg.Store.Add(&retInfo, b.quit); err != nil {
+			brarLog.Errorf("Unable to record first seen time of "+
+				"breach of ChannelPoint(%v): %v", chanPoint,
+				err)
+		}
+		breachRetInfos[chanPoint] = retInfo
+	}
+
+	// Retributions that were completed, but whose records failed to be
+	// removed, are left to the reaper. As their channels have already
+	// been marked fully closed, there's nothing left to resume.
+	for chanPoint, retInfo := range breachRetInfos {
+		if !retInfo.completed {
+			continue
+		}
+
+		brarLog.Infof("Retribution for ChannelPoint(%v) already "+
+			"completed, awaiting removal of its record", chanPoint)
+
+		delete(breachRetInfos, chanPoint)
+		delete(closeSummaries, chanPoint)
+	}
+
+	// Retributions whose sign descriptors were persisted by reference
+	// have them re-derived from the channel's backup. Should that fail,
+	// the retribution can't be carried out, so it's flagged for the
+	// operator and left untouched in the store. Its channel remains
+	// closed regardless, as a breach was recorded against it.
+	for chanPoint, retInfo := range breachRetInfos {
+		if err := b.rederiveSignDescs(&retInfo); err != nil {
+			brarLog.Criticalf("Unable to re-derive sign "+
+				"descriptors of breach of ChannelPoint(%v), "+
+				"skipping retribution: %v", chanPoint, err)
+
+			b.retMtx.Lock()
+			b.unverifiedRetributions[chanPoint] = struct{}{}
+			b.retMtx.Unlock()
+
+			delete(breachRetInfos, chanPoint)
+		}
+	}
+
+	// Should the daemon have gone down after a justice transaction
+	// confirmed, but before its retribution was removed from the store,
+	// the retribution only needs to be finalized. As the breached outputs
+	// have been spent, it can neither be corroborated nor resumed, since
+	// rebroadcasting justice would fail.
+	justiceServed := make(map[wire.OutPoint]struct{})
+	for chanPoint, retInfo := range breachRetInfos {
+		if b.justiceConfirmed(&retInfo) {
+			justiceServed[chanPoint] = struct{}{}
+		}
+	}
+
+	// Before acting on any of the persisted retributions, we'll ensure
+	// that the chain doesn't contradict any breach. If a record's outputs
+	// don't match those found on chain, it may have been corrupted or
+	// belong to another network, so rather than broadcasting a
+	// transaction that spends phantom outputs, we flag the record for the
+	// operator and leave it untouched in the store. A breach transaction
+	// that can't be found may simply be yet to confirm, so its
+	// retribution is resumed as usual, awaiting its confirmation.
+	for chanPoint, retInfo := range breachRetInfos {
+		if _, ok := justiceServed[chanPoint]; ok {
+			continue
+		}
+
+		if err := b.corroborateBreach(&retInfo); err != nil {
+			brarLog.Errorf("Unable to corroborate breach of "+
+				"ChannelPoint(%v), skipping retribution: %v",
+				chanPoint, err)
+
+			b.retMtx.Lock()
+			b.unverifiedRetributions[chanPoint] = struct{}{}
+			b.retMtx.Unlock()
+
+			delete(breachRetInfos, chanPoint)
+		}
+	}
+
+	// The links of all breached channels must be closed, so that no
+	// further activity takes place over them. This includes channels
+	// that were already marked as closed in a prior run, as the switch
+	// holds no record of the breach across restarts, and may have
+	// reinstated their links. Closing a link that doesn't exist is
+	// harmless.
+	for chanPoint := range closeSummaries {
+		chanPoint := chanPoint
+		b.cfg.CloseLink(&chanPoint, htlcswitch.CloseBreach)
+	}
+
 	// We need to query that database state for all currently active
 	// channels, each of these channels will need a goroutine assigned to
 	// it to watch for channel breaches.
-	activeChannels, err := b.db.FetchAllChannels()
+	activeChannels, err := b.cfg.DB.FetchAllChannels()
 	if err != nil && err != channeldb.ErrNoActiveChannels {
 		brarLog.Errorf("unable to fetch active channels: %v", err)
 		return err
@@ -183,8 +1060,8 @@ func (b *breachArbiter) Start() error {
 	channelsToWatch := make([]*lnwallet.LightningChannel, 0, nActive)
 	for _, chanState := range activeChannels {
 		// Initialize active channel from persisted channel state.
-		channel, err := lnwallet.NewLightningChannel(nil, b.notifier,
-			b.estimator, chanState)
+		channel, err := lnwallet.NewLightningChannel(nil, b.cfg.Notifier,
+			b.cfg.Estimator, chanState)
 		if err != nil {
 			brarLog.Errorf("unable to load channel from "+
 				"disk: %v", err)
@@ -199,27 +1076,39 @@ func (b *breachArbiter) Start() error {
 		// to be managed by the contractObserver.
 		chanPoint := chanState.FundingOutpoint
 		if closeSummary, ok := closeSummaries[chanPoint]; ok {
-			// Since this channel should not be open, we immediately
-			// notify the HTLC switch that this link should be
-			// closed, and that all activity on the link should
-			// cease.
-			b.htlcSwitch.CloseLink(
-				&chanState.FundingOutpoint,
-				htlcswitch.CloseBreach,
-			)
+			// Should the persisted remote identity have been
+			// corrupted, we'll fall back to that of the channel.
+			if closeSummary.RemotePub.X == nil {
+				closeSummary.RemotePub = chanState.IdentityPub
+			}
 
-			// Ensure channeldb is consistent with the persisted
-			// breach.
-			err := channel.DeleteState(&closeSummary)
+			// Its link has already been closed above, so we
+			// ensure channeldb is consistent with the persisted
+			// breach. It's the persisted retribution that drives
+			// the recovery of our funds, so should this fail, the
+			// retribution is carried out regardless, and the
+			// close retried before it's finalized.
+			err := b.deleteChanState(channel, &closeSummary)
 			if err != nil {
-				brarLog.Errorf("unable to delete channel "+
-					"state: %v", err)
-				return err
+				brarLog.Errorf("Unable to delete state of "+
+					"breached ChannelPoint(%v), will "+
+					"retry: %v", chanPoint, err)
+
+				b.retMtx.Lock()
+				b.unreconciledCloses[chanPoint] =
+					&unreconciledClose{
+						channel: channel,
+						summary: closeSummary,
+					}
+				b.retMtx.Unlock()
 			}
 
 			// Now that this channel is both breached _and_ closed,
 			// we can skip adding it to the `channelsToWatch` since
-			// we can begin the retribution process immediately.
+			// we can begin the retribution process immediately. As
+			// it's never watched, its state machine is stopped,
+			// leaving only its persisted state to be deleted.
+			channel.Stop()
 			continue
 		}
 
@@ -229,45 +1118,160 @@ func (b *breachArbiter) Start() error {
 	}
 
 	// TODO(roasbeef): instead use closure height of channel
-	_, currentHeight, err := b.chainIO.GetBestBlock()
+	_, currentHeight, err := b.cfg.ChainIO.GetBestBlock()
 	if err != nil {
 		return err
 	}
 
 	// Spawn the exactRetribution tasks to monitor and resolve any breaches
 	// that were loaded from the retribution store.
+	//
+	// NOTE: As each retribution races the breaching party, the
+	// confirmations of their breach transactions are registered for
+	// before the pending close channels below are watched, such that a
+	// loaded notifier serves the time-critical registrations first. These
+	// registrations must therefore remain synchronous.
 	for chanPoint, closeSummary := range closeSummaries {
+		// Retributions flagged for the operator above aren't resumed.
+		retInfo, ok := breachRetInfos[chanPoint]
+		if !ok {
+			continue
+		}
+
+		if _, ok := justiceServed[chanPoint]; ok {
+			b.wg.Add(1)
+			go b.fastForwardRetribution(&retInfo)
+			continue
+		}
+
 		// Register for a notification when the breach transaction is
-		// confirmed on chain.
+		// confirmed on chain. A failure to do so shouldn't prevent us
+		// from protecting the remaining channels, so the registration
+		// is instead retried later on.
 		breachTXID := closeSummary.ClosingTXID
-		confChan, err := b.notifier.RegisterConfirmationsNtfn(
-			&breachTXID, 1, uint32(currentHeight))
+		confChan, err := b.registerConfWithRetry(
+			&breachTXID, b.cfg.BreachConfDepth,
+			uint32(currentHeight),
+		)
 		if err != nil {
-			brarLog.Errorf("unable to register for conf updates "+
-				"for txid: %v, err: %v", breachTXID, err)
-			return err
+			brarLog.Errorf("Unable to register for confirmation "+
+				"of breach tx %v of ChannelPoint(%v), will "+
+				"retry: %v", breachTXID, chanPoint, err)
+
+			b.retMtx.Lock()
+			b.unregisteredRetributions[chanPoint] =
+				&unregisteredRetribution{
+					ret:        &retInfo,
+					breachTxid: breachTXID,
+					heightHint: uint32(currentHeight),
+				}
+			b.retMtx.Unlock()
+			continue
 		}
 
 		// Launch a new goroutine which to finalize the channel
 		// retribution after the breach transaction confirms.
-		retInfo := breachRetInfos[chanPoint]
 		b.wg.Add(1)
 		go b.exactRetribution(confChan, &retInfo)
 	}
 
 	// Start watching the remaining active channels!
+	if b.observerPool != nil {
+		b.observerPool.start()
+	}
 	b.wg.Add(1)
 	go b.contractObserver(channelsToWatch)
 
+	// Retry the removal of any completed retributions in the background.
+	b.wg.Add(1)
+	go b.retributionReaper()
+
+	// Resume the sweeps of any channels unilaterally closed by the remote
+	// party before we restarted. Each of these channels is marked as fully
+	// closed once its sweep confirms.
+	var pendingSweeps []*commitSweepInfo
+	err = b.commitSweeps.ForAll(func(sweep *commitSweepInfo) error {
+		pendingSweeps = append(pendingSweeps, sweep)
+		return nil
+	})
+	if err != nil {
+		brarLog.Errorf("unable to fetch commitment sweeps: %v", err)
+		return err
+	}
+
+	sweepingChans := make(map[wire.OutPoint]struct{})
+	for _, sweep := range pendingSweeps {
+		brarLog.Infof("Resuming sweep of force closed "+
+			"ChannelPoint(%v)", sweep.chanPoint)
+
+		sweepingChans[sweep.chanPoint] = struct{}{}
+
+		b.wg.Add(1)
+		go b.resolveCommitSweep(sweep)
+	}
+
 	// Additionally, we'll also want to retrieve any pending close or force
 	// close transactions to we can properly mark them as resolved in the
 	// database.
-	pendingCloseChans, err := b.db.FetchClosedChannels(true)
+	pendingCloseChans, err := b.cfg.DB.FetchClosedChannels(true)
 	if err != nil {
 		brarLog.Errorf("unable to fetch closing channels: %v", err)
 		return err
 	}
+
+	// Any close watched over before we restarted is watched from the
+	// height at which we first began watching it.
+	closeHints := make(map[wire.OutPoint]uint32)
+	err = b.closeWatches.ForAll(func(chanPoint wire.OutPoint,
+		heightHint uint32) error {
+
+		closeHints[chanPoint] = heightHint
+		return nil
+	})
+	if err != nil {
+		brarLog.Errorf("unable to fetch close height hints: %v", err)
+		return err
+	}
+
+	// The hints of closes no longer pending, e.g. as they were resolved
+	// elsewhere, are no longer needed.
+	pendingChans := make(map[wire.OutPoint]struct{})
+	for _, pendingClose := range pendingCloseChans {
+		pendingChans[pendingClose.ChanPoint] = struct{}{}
+	}
+	for chanPoint := range closeHints {
+		if _, ok := pendingChans[chanPoint]; ok {
+			continue
+		}
+		if err := b.closeWatches.Remove(&chanPoint); err != nil {
+			brarLog.Errorf("unable to remove close height hint of "+
+				"ChannelPoint(%v): %v", chanPoint, err)
+		}
+	}
+
+	// The pending close channels are only watched once every retribution
+	// has registered for the confirmation of its breach transaction above,
+	// as their closure isn't time-critical.
 	for _, pendingClose := range pendingCloseChans {
+		// A breached channel is also pending close, as its breach
+		// close summary is written once the breach is detected. Its
+		// closure is the responsibility of its retribution, which only
+		// marks it fully closed once justice has been served, so the
+		// breach takes precedence and we won't watch over it here.
+		// This also applies to retributions awaiting inspection by the
+		// operator, whose channel must remain pending close.
+		if _, ok := persistedRets[pendingClose.ChanPoint]; ok {
+			brarLog.Debugf("Deferring closure of ChannelPoint(%v) "+
+				"to its retribution", pendingClose.ChanPoint)
+			continue
+		}
+
+		// Likewise, a channel whose sweep we've resumed above is marked
+		// fully closed once the sweep confirms.
+		if _, ok := sweepingChans[pendingClose.ChanPoint]; ok {
+			continue
+		}
+
 		// If this channel was force closed, and we have a non-zero
 		// time-locked balance, then the utxoNursery is currently
 		// watching over it.  As a result we don't need to watch over
@@ -280,58 +1284,166 @@ func (b *breachArbiter) Start() error {
 		brarLog.Infof("Watching for the closure of ChannelPoint(%v)",
 			pendingClose.ChanPoint)
 
-		closeTXID := pendingClose.ClosingTXID
-		confNtfn, err := b.notifier.RegisterConfirmationsNtfn(
-			&closeTXID, 1, uint32(currentHeight),
-		)
-		if err != nil {
-			return err
+		heightHint, ok := closeHints[pendingClose.ChanPoint]
+		if !ok {
+			heightHint = uint32(currentHeight)
+			err := b.closeWatches.Add(
+				&pendingClose.ChanPoint, heightHint,
+			)
+			if err != nil {
+				brarLog.Errorf("unable to persist close height "+
+					"hint of ChannelPoint(%v): %v",
+					pendingClose.ChanPoint, err)
+			}
+		}
+
+		// A unilateral close is only considered final once its closing
+		// transaction has reached the safety depth, while a cooperative
+		// one is final after a single confirmation.
+		numConfs := uint32(1)
+		if pendingClose.CloseType == channeldb.ForceClose {
+			numConfs = b.cfg.UnilateralCloseSafetyDepth
 		}
 
 		b.wg.Add(1)
-		go func(chanPoint wire.OutPoint) {
+		go func(chanPoint wire.OutPoint, closeTXID chainhash.Hash) {
 			defer b.wg.Done()
 
-			// In the case that the ChainNotifier is shutting down,
-			// all subscriber notification channels will be closed,
-			// generating a nil receive.
-			select {
-			case confInfo, ok := <-confNtfn.Confirmed:
-				if !ok {
-					return
-				}
-
-				brarLog.Infof("ChannelPoint(%v) is "+
-					"fully closed, at height: %v",
-					chanPoint, confInfo.BlockHeight)
-
-				// TODO(roasbeef): need to store
-				// UnilateralCloseSummary on disk so can
-				// possibly sweep output here
+			// TODO(roasbeef): need to store
+			// UnilateralCloseSummary on disk so can possibly sweep
+			// output here
+			height, ok := b.waitForCloseConf(
+				&closeTXID, numConfs, heightHint,
+			)
+			if !ok {
+				return
+			}
 
-				err := b.db.MarkChanFullyClosed(&chanPoint)
-				if err != nil {
-					brarLog.Errorf("unable to mark chan "+
-						"as closed: %v", err)
-				}
+			brarLog.Infof("ChannelPoint(%v) is fully closed, at "+
+				"height: %v", chanPoint, height)
 
-			case <-b.quit:
+			err := b.cfg.DB.MarkChanFullyClosed(&chanPoint)
+			if err != nil {
+				brarLog.Errorf("unable to mark chan as "+
+					"closed: %v", err)
 				return
 			}
-		}(pendingClose.ChanPoint)
+
+			err = b.closeWatches.Remove(&chanPoint)
+			if err != nil {
+				brarLog.Errorf("unable to remove close height "+
+					"hint of ChannelPoint(%v): %v",
+					chanPoint, err)
+			}
+		}(pendingClose.ChanPoint, pendingClose.ClosingTXID)
+	}
+
+	return nil
+}
+
+// corroborateBreach ensures that the breach described by the passed
+// retribution isn't contradicted by the chain. The outputs we intend to sweep
+// must belong to the recorded breach transaction, and any of them found within
+// the UTXO set must have the value and script we expect. A non-nil error is
+// returned if the chain contradicts the breach. As the UTXO set may not
+// reflect the mempool, an output that can't be found is taken to belong to a
+// breach transaction that's yet to confirm, rather than contradicting it.
+//
+// TODO(roasbeef): use breach height as hint
+func (b *breachArbiter) corroborateBreach(ret *retributionInfo) error {
+	// Should either party's balance have been dust at the revoked state,
+	// its output is absent from the breach transaction, leaving nothing
+	// to check.
+	var outputs []*breachedOutput
+	for _, output := range []*breachedOutput{
+		ret.revokedOutput, ret.selfOutput,
+	} {
+		if output == nil || output.outpoint == (wire.OutPoint{}) {
+			continue
+		}
+		if output.outpoint.Hash != ret.commitHash {
+			return fmt.Errorf("output %v does not belong to "+
+				"breach transaction %v", output.outpoint,
+				ret.commitHash)
+		}
+
+		outputs = append(outputs, output)
 	}
 
+	for _, output := range outputs {
+		txOut, err := b.cfg.ChainIO.GetUtxo(&output.outpoint, 0)
+		if err != nil || txOut == nil {
+			continue
+		}
+
+		// The output exists, so we'll ensure that it matches what we
+		// have on record before considering the breach corroborated.
+		if btcutil.Amount(txOut.Value) != output.amt {
+			return fmt.Errorf("output %v has value %v on chain, "+
+				"expected %v", output.outpoint,
+				btcutil.Amount(txOut.Value), output.amt)
+		}
+		signOutput := output.signDescriptor.Output
+		if signOutput != nil && len(signOutput.PkScript) != 0 &&
+			!bytes.Equal(txOut.PkScript, signOutput.PkScript) {
+
+			return fmt.Errorf("output %v has unexpected "+
+				"pkScript %x", output.outpoint, txOut.PkScript)
+		}
+
+		return nil
+	}
+
+	brarLog.Infof("No outputs of breach tx %v of ChannelPoint(%v) found "+
+		"on chain, awaiting its confirmation", ret.commitHash,
+		ret.chanPoint)
+
 	return nil
 }
 
+// justiceConfirmed returns true if the most recently broadcast justice
+// transaction of the given retribution is known to have confirmed, which is
+// the case if its output is found within the UTXO set. Should the output have
+// already been spent by the wallet, the confirmation goes undetected, and the
+// retribution is resumed as usual.
+func (b *breachArbiter) justiceConfirmed(ret *retributionInfo) bool {
+	if ret.justiceTxid == (chainhash.Hash{}) {
+		return false
+	}
+
+	justiceOutput := wire.OutPoint{Hash: ret.justiceTxid}
+	txOut, err := b.cfg.ChainIO.GetUtxo(&justiceOutput, 0)
+	return err == nil && txOut != nil
+}
+
+// fastForwardRetribution finalizes a retribution loaded from the store whose
+// justice transaction has already confirmed.
+//
+// NOTE: This MUST be run as a goroutine.
+func (b *breachArbiter) fastForwardRetribution(breachInfo *retributionInfo) {
+	defer b.wg.Done()
+
+	brarLog.Infof("Justice tx %v for ChannelPoint(%v) confirmed before "+
+		"restart, finalizing retribution", breachInfo.justiceTxid,
+		breachInfo.chanPoint)
+
+	b.finalizeRetribution(breachInfo, time.Time{})
+}
+
 // Stop is an idempotent method that signals the breachArbiter to execute a
 // graceful shutdown. This function will block until all goroutines spawned by
-// the breachArbiter have gracefully exited.
+// the breachArbiter have gracefully exited, including those spawned by a
+// concurrent call to Start, which is awaited.
 func (b *breachArbiter) Stop() error {
 	if !atomic.CompareAndSwapUint32(&b.stopped, 0, 1) {
 		return nil
 	}
 
+	// Should Start be in progress, we'll wait for it to complete, so that
+	// none of the goroutines it spawns is missed.
+	b.lifecycleMtx.Lock()
+	defer b.lifecycleMtx.Unlock()
+
 	brarLog.Infof("Breach arbiter shutting down")
 
 	close(b.quit)
@@ -353,17 +1465,22 @@ func (b *breachArbiter) contractObserver(
 
 	defer b.wg.Done()
 
+	atomic.StoreInt32(&b.observerActive, 1)
+	defer atomic.StoreInt32(&b.observerActive, 0)
+
 	// For each active channel found within the database, we launch a
 	// detected breachObserver goroutine for that channel and also track
 	// the new goroutine within the breachObservers map so we can cancel it
 	// later if necessary.
 	for _, channel := range activeChannels {
-		settleSignal := make(chan struct{})
+		signals := newObserverSignals()
 		chanPoint := channel.ChannelPoint()
-		b.breachObservers[*chanPoint] = settleSignal
 
-		b.wg.Add(1)
-		go b.breachObserver(channel, settleSignal)
+		b.observerMtx.Lock()
+		b.breachObservers[*chanPoint] = signals
+		b.observerMtx.Unlock()
+
+		b.launchObserver(channel, signals, nil)
 	}
 
 	// TODO(roasbeef): need to ensure currentHeight passed in doesn't
@@ -373,92 +1490,44 @@ out:
 	for {
 		select {
 		case breachInfo := <-b.breachedContracts:
-			_, currentHeight, err := b.chainIO.GetBestBlock()
-			if err != nil {
-				brarLog.Errorf(
-					"unable to get best height: %v", err)
-			}
-
-			// A new channel contract has just been breached! We
-			// first register for a notification to be dispatched
-			// once the breach transaction (the revoked commitment
-			// transaction) has been confirmed in the chain to
-			// ensure we're not dealing with a moving target.
-			breachTXID := &breachInfo.commitHash
-			confChan, err := b.notifier.RegisterConfirmationsNtfn(
-				breachTXID, 1, uint32(currentHeight),
-			)
-			if err != nil {
-				brarLog.Errorf("unable to register for conf "+
-					"updates for txid: %v, err: %v",
-					breachTXID, err)
-				continue
-			}
-
-			brarLog.Warnf("A channel has been breached with "+
-				"txid: %v. Waiting for confirmation, then "+
-				"justice will be served!", breachTXID)
-
-			// With the retribution state persisted, channel close
-			// persisted, and notification registered, we launch a
-			// new goroutine which will finalize the channel
-			// retribution after the breach transaction has been
-			// confirmed.
+			// A new channel contract has just been breached! The
+			// confirmation of the breach transaction is awaited
+			// within a dedicated goroutine, so that a slow
+			// registration can't delay the processing of other
+			// breaches.
 			b.wg.Add(1)
-			go b.exactRetribution(confChan, breachInfo)
+			go b.awaitBreachConf(breachInfo)
 
+			b.observerMtx.Lock()
 			delete(b.breachObservers, breachInfo.chanPoint)
+			b.observerMtx.Unlock()
 
 		case contract := <-b.newContracts:
 			// A new channel has just been opened within the
 			// daemon, so we launch a new breachObserver to handle
 			// the detection of attempted contract breaches.
-			settleSignal := make(chan struct{})
-			chanPoint := contract.ChannelPoint()
-
-			// If the contract is already being watched, then an
-			// additional send indicates we have a stale version of
-			// the contract. So we'll cancel active watcher
-			// goroutine to create a new instance with the latest
-			// contract reference.
-			if oldSignal, ok := b.breachObservers[*chanPoint]; ok {
-				brarLog.Infof("ChannelPoint(%v) is now live, "+
-					"abandoning state contract for live "+
-					"version", chanPoint)
-				close(oldSignal)
-			}
-
-			b.breachObservers[*chanPoint] = settleSignal
-
-			brarLog.Debugf("New contract detected, launching " +
-				"breachObserver")
-
-			b.wg.Add(1)
-			go b.breachObserver(contract, settleSignal)
+			b.watchContract(contract, nil)
 
 			// TODO(roasbeef): add doneChan to signal to peer
 			// continue * peer send over to us on
 			// loadActiveChanenls, sync until we're aware so no
 			// state transitions
+
+		case req := <-b.watchRequests:
+			b.watchContract(req.channel, req.live)
+
 		case chanPoint := <-b.settledContracts:
 			// A new channel has been closed either unilaterally or
 			// cooperatively, as a result we no longer need a
 			// breachObserver detected to the channel.
-			killSignal, ok := b.breachObservers[*chanPoint]
-			if !ok {
+			if b.settleContract(chanPoint) == nil {
 				brarLog.Errorf("Unable to find contract: %v",
 					chanPoint)
-				continue
 			}
 
-			brarLog.Debugf("ChannelPoint(%v) has been settled, "+
-				"cancelling breachObserver", chanPoint)
+		case req := <-b.settleRequests:
+			req.stopped <- b.settleContract(req.chanPoint)
 
-			// If we had a breachObserver active, then we signal it
-			// for exit and also delete its state from our tracking
-			// map.
-			close(killSignal)
-			delete(b.breachObservers, *chanPoint)
 		case <-b.quit:
 			break out
 		}
@@ -467,117 +1536,1815 @@ out:
 	return
 }
 
-// exactRetribution is a goroutine which is executed once a contract breach has
-// been detected by a breachObserver. This function is responsible for
-// punishing a counterparty for violating the channel contract by sweeping ALL
-// the lingering funds within the channel into the daemon's wallet.
+// awaitBreachConf registers for the confirmation of the breach transaction of
+// the given retribution, and launches the exactRetribution goroutine which will
+// finalize the retribution once the breach transaction has confirmed.
 //
 // NOTE: This MUST be run as a goroutine.
-func (b *breachArbiter) exactRetribution(
-	confChan *chainntnfs.ConfirmationEvent,
-	breachInfo *retributionInfo) {
-
+func (b *breachArbiter) awaitBreachConf(breachInfo *retributionInfo) {
 	defer b.wg.Done()
 
-	// TODO(roasbeef): state needs to be checkpointed here
-
-	select {
-	case _, ok := <-confChan.Confirmed:
-		// If the second value is !ok, then the channel has been closed
-		// signifying a daemon shutdown, so we exit.
-		if !ok {
-			return
-		}
+	b.recordBreachDetected()
 
-		// Otherwise, if this is a real confirmation notification, then
-		// we fall through to complete our duty.
-	case <-b.quit:
-		return
+	_, currentHeight, err := b.cfg.ChainIO.GetBestBlock()
+	if err != nil {
+		brarLog.Errorf("unable to get best height: %v", err)
 	}
 
-	brarLog.Debugf("Breach transaction %v has been confirmed, sweeping "+
-		"revoked funds", breachInfo.commitHash)
-
-	// With the breach transaction confirmed, we now create the justice tx
-	// which will claim ALL the funds within the channel.
-	justiceTx, err := b.createJusticeTx(breachInfo)
+	// We first register for a notification to be dispatched once the
+	// breach transaction (the revoked commitment transaction) has been
+	// confirmed in the chain to ensure we're not dealing with a moving
+	// target.
+	breachTXID := &breachInfo.commitHash
+	confChan, err := b.registerConf(
+		breachTXID, b.cfg.BreachConfDepth, uint32(currentHeight),
+	)
 	if err != nil {
-		brarLog.Errorf("unable to create justice tx: %v", err)
+		brarLog.Errorf("unable to register for conf updates for "+
+			"txid: %v, err: %v", breachTXID, err)
 		return
 	}
 
-	brarLog.Debugf("Broadcasting justice tx: %v",
-		newLogClosure(func() string {
-			return spew.Sdump(justiceTx)
-		}))
+	brarLog.Warnf("A channel has been breached with txid: %v. Waiting "+
+		"for confirmation, then justice will be served!", breachTXID)
 
-	_, currentHeight, err := b.chainIO.GetBestBlock()
-	if err != nil {
-		brarLog.Errorf("unable to get current height: %v", err)
-		return
-	}
+	// With the retribution state persisted, channel close persisted, and
+	// notification registered, we launch a new goroutine which will
+	// finalize the channel retribution after the breach transaction has
+	// been confirmed.
+	b.wg.Add(1)
+	go b.exactRetribution(confChan, breachInfo)
+}
 
-	// Finally, broadcast the transaction, finalizing the channels'
-	// retribution against the cheating counterparty.
-	if err := b.wallet.PublishTransaction(justiceTx); err != nil {
-		brarLog.Errorf("unable to broadcast "+
-			"justice tx: %v", err)
-		return
-	}
+// watchContract launches a new breachObserver for the given contract,
+// replacing any stale observer of the same channel. If non-nil, the live
+// channel is closed once the observer is running.
+//
+// NOTE: This MUST only be called from the contractObserver goroutine.
+func (b *breachArbiter) watchContract(contract *lnwallet.LightningChannel,
+	live chan struct{}) {
 
-	// As a conclusionary step, we register for a notification to be
-	// dispatched once the justice tx is confirmed. After confirmation we
-	// notify the caller that initiated the retribution workflow that the
-	// deed has been done.
-	justiceTXID := justiceTx.TxHash()
-	confChan, err = b.notifier.RegisterConfirmationsNtfn(&justiceTXID, 1,
-		uint32(currentHeight))
-	if err != nil {
-		brarLog.Errorf("unable to register for conf for txid: %v",
-			justiceTXID)
-		return
+	signals := newObserverSignals()
+	chanPoint := contract.ChannelPoint()
+
+	// If the contract is already being watched, then an additional send
+	// indicates we have a stale version of the contract. So we'll cancel
+	// active watcher goroutine to create a new instance with the latest
+	// contract reference.
+	b.observerMtx.Lock()
+	if oldSignals, ok := b.breachObservers[*chanPoint]; ok {
+		brarLog.Infof("ChannelPoint(%v) is now live, abandoning "+
+			"state contract for live version", chanPoint)
+		close(oldSignals.settle)
 	}
+	b.breachObservers[*chanPoint] = signals
+	b.observerMtx.Unlock()
 
-	select {
-	case _, ok := <-confChan.Confirmed:
-		if !ok {
+	brarLog.Debugf("New contract detected, launching breachObserver")
+
+	b.launchObserver(contract, signals, live)
+}
+
+// settleContract signals the breachObserver of the given channel to exit, as
+// the channel no longer needs watching, and stops tracking it. The returned
+// channel is closed once the observer has been torn down, and is nil if the
+// channel wasn't being watched.
+//
+// NOTE: This MUST only be called from the contractObserver goroutine.
+func (b *breachArbiter) settleContract(
+	chanPoint *wire.OutPoint) chan struct{} {
+
+	b.observerMtx.Lock()
+	signals, ok := b.breachObservers[*chanPoint]
+	if !ok {
+		b.observerMtx.Unlock()
+		return nil
+	}
+
+	// If we had a breachObserver active, then we signal it for exit and
+	// also delete its state from our tracking map.
+	close(signals.settle)
+	delete(b.breachObservers, *chanPoint)
+	b.observerMtx.Unlock()
+
+	brarLog.Debugf("ChannelPoint(%v) has been settled, cancelling "+
+		"breachObserver", chanPoint)
+
+	return signals.stopped
+}
+
+// SettleChannel signals that the given channel no longer needs to be watched
+// for breaches, e.g. as it has been cooperatively closed. Unlike sending the
+// channel point over settledContracts, it only returns once the channel's
+// breachObserver has been torn down. ErrNoBreachObserver is returned if the
+// channel wasn't being watched.
+func (b *breachArbiter) SettleChannel(chanPoint *wire.OutPoint) error {
+	req := &settleRequest{
+		chanPoint: chanPoint,
+		stopped:   make(chan chan struct{}, 1),
+	}
+
+	select {
+	case b.settleRequests <- req:
+	case <-b.quit:
+		return errBreachArbiterExiting
+	}
+
+	var stopped chan struct{}
+	select {
+	case stopped = <-req.stopped:
+	case <-b.quit:
+		return errBreachArbiterExiting
+	}
+	if stopped == nil {
+		return ErrNoBreachObserver
+	}
+
+	select {
+	case <-stopped:
+		return nil
+	case <-b.quit:
+		return errBreachArbiterExiting
+	}
+}
+
+// ErrNoBreachObserver is returned by SettleChannel if the channel to be
+// settled wasn't being watched for breaches.
+var ErrNoBreachObserver = errors.New("channel not watched for breaches")
+
+// settleRequest is a request to stop watching a channel for breaches, made via
+// SettleChannel.
+type settleRequest struct {
+	chanPoint *wire.OutPoint
+
+	// stopped is sent the channel which is closed once the channel's
+	// breachObserver has been torn down, or nil if it had none.
+	stopped chan chan struct{}
+}
+
+// observerSignals are the signals shared between the contractObserver and the
+// observer of a single channel.
+type observerSignals struct {
+	// settle is closed once the channel no longer needs watching.
+	settle chan struct{}
+
+	// stopped is closed by the observer once it has stopped watching the
+	// channel, and handled whatever ended its watch.
+	stopped chan struct{}
+}
+
+// newObserverSignals creates the signals of a newly watched channel.
+func newObserverSignals() *observerSignals {
+	return &observerSignals{
+		settle:  make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// WatchChannel registers the given open channel to be watched for breaches,
+// replacing any stale version of it already being watched. Unlike sending the
+// channel over newContracts, it only returns once the channel's breachObserver
+// is running, so that the caller can be sure no breach of the channel goes
+// unnoticed from then on.
+func (b *breachArbiter) WatchChannel(
+	channel *lnwallet.LightningChannel) error {
+
+	req := &watchRequest{
+		channel: channel,
+		live:    make(chan struct{}),
+	}
+
+	select {
+	case b.watchRequests <- req:
+	case <-b.quit:
+		return errBreachArbiterExiting
+	}
+
+	select {
+	case <-req.live:
+		return nil
+	case <-b.quit:
+		return errBreachArbiterExiting
+	}
+}
+
+// watchRequest is a request to begin watching a channel for breaches, made
+// via WatchChannel.
+type watchRequest struct {
+	channel *lnwallet.LightningChannel
+
+	// live is closed once the channel's breachObserver is running.
+	live chan struct{}
+}
+
+// launchObserver begins watching the given contract for breaches until the
+// settle signal is closed, either on the observer pool if one is configured, or
+// within a dedicated breachObserver goroutine otherwise. If non-nil, the live
+// channel is closed once the contract is being watched.
+//
+// NOTE: This MUST only be called from the contractObserver goroutine.
+func (b *breachArbiter) launchObserver(contract *lnwallet.LightningChannel,
+	signals *observerSignals, live chan struct{}) {
+
+	if b.observerPool != nil {
+		b.observerPool.watch(
+			contract, signals.settle, signals.stopped, live,
+		)
+		return
+	}
+
+	b.wg.Add(1)
+	go b.breachObserver(contract, signals.settle, signals.stopped, live)
+}
+
+// TrackCoopClose records that the given channel is being cooperatively closed
+// by the transaction with the given txid. Should the channel be breached before
+// the close confirms, its retribution is only carried out if the breach
+// transaction confirms in place of the closing transaction.
+func (b *breachArbiter) TrackCoopClose(chanPoint *wire.OutPoint,
+	closeTxid chainhash.Hash) {
+
+	b.coopMtx.Lock()
+	b.coopCloses[*chanPoint] = closeTxid
+	b.coopMtx.Unlock()
+}
+
+// UntrackCoopClose forgets the cooperative close of the given channel, once it
+// has either confirmed or been superseded by a breach.
+func (b *breachArbiter) UntrackCoopClose(chanPoint *wire.OutPoint) {
+	b.coopMtx.Lock()
+	delete(b.coopCloses, *chanPoint)
+	b.coopMtx.Unlock()
+}
+
+// awaitCoopCloseConf registers for the confirmation of the cooperative close
+// of the given channel, if one is being tracked. A nil channel is returned
+// otherwise.
+func (b *breachArbiter) awaitCoopCloseConf(
+	chanPoint *wire.OutPoint) (<-chan *chainntnfs.TxConfirmation, error) {
+
+	b.coopMtx.Lock()
+	closeTxid, ok := b.coopCloses[*chanPoint]
+	b.coopMtx.Unlock()
+	if !ok {
+		return nil, nil
+	}
+
+	brarLog.Warnf("ChannelPoint(%v) was breached while being "+
+		"cooperatively closed by %v, awaiting the confirmation of "+
+		"either", chanPoint, closeTxid)
+
+	_, currentHeight, err := b.cfg.ChainIO.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	confEvent, err := b.registerConf(
+		&closeTxid, 1, uint32(currentHeight),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return confEvent.Confirmed, nil
+}
+
+// watchBreachReplacement registers for the spend of the funding output of the
+// given retribution, such that a replacement of its breach transaction can be
+// detected. Without the channel's backup, a replacement couldn't be
+// re-derived, so nil is returned and nothing is watched.
+func (b *breachArbiter) watchBreachReplacement(
+	breachInfo *retributionInfo) (*chainntnfs.SpendEvent, error) {
+
+	if breachInfo.backup == nil {
+		return nil, nil
+	}
+
+	_, currentHeight, err := b.cfg.ChainIO.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.cfg.Notifier.RegisterSpendNtfn(
+		&breachInfo.chanPoint, uint32(currentHeight),
+	)
+}
+
+// rederiveSignDescs re-derives the sign descriptors of the commitment outputs
+// of a retribution that were persisted by reference, from the static backup of
+// the channel at the revoked state broadcast by the breaching party.
+func (b *breachArbiter) rederiveSignDescs(ret *retributionInfo) error {
+	if !ret.selfOutput.signDescPending() &&
+		!ret.revokedOutput.signDescPending() {
+
+		return nil
+	}
+
+	if b.cfg.FetchChannelBackup == nil {
+		return errors.New("sign descriptors persisted by reference, " +
+			"but no channel backups are available")
+	}
+	backup, err := b.cfg.FetchChannelBackup(&ret.chanPoint)
+	if err != nil {
+		return fmt.Errorf("unable to fetch channel backup: %v", err)
+	}
+
+	localDesc, remoteDesc, err := lnwallet.DeriveBreachSignDescs(
+		backup, ret.revokedStateNum, ret.selfOutput.amt,
+		ret.revokedOutput.amt,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to derive sign descriptors of "+
+			"revoked state #%v: %v", ret.revokedStateNum, err)
+	}
+
+	if ret.selfOutput.signDescPending() {
+		ret.selfOutput.signDescriptor = *localDesc
+	}
+	if ret.revokedOutput.signDescPending() {
+		ret.revokedOutput.signDescriptor = *remoteDesc
+	}
+
+	return nil
+}
+
+// rederiveReplacedBreach re-derives the given retribution against the
+// transaction which replaced its breach transaction by spending the funding
+// output, as described by spend, which must be another revoked commitment of
+// the channel. The
+// retribution is updated in place and persisted, and the confirmation of the
+// replacement is returned to be awaited in place of that of the original.
+//
+// NOTE: As the backup lacks the revocation log, any HTLC outputs of the
+// replacement aren't swept.
+func (b *breachArbiter) rederiveReplacedBreach(breachInfo *retributionInfo,
+	spend *chainntnfs.SpendDetail) (chan *chainntnfs.TxConfirmation, error) {
+
+	retribution, err := lnwallet.NewBreachRetributionFromBackup(
+		breachInfo.backup, spend.SpendingTx,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	replacementInfo := b.newRetributionInfo(
+		&breachInfo.chanPoint, retribution, breachInfo.remoteIdentity,
+		breachInfo.capacity,
+		btcutil.Amount(retribution.LocalOutputSignDesc.Output.Value),
+	)
+	if err := replacementInfo.checkSweepable(); err != nil {
+		return nil, err
+	}
+
+	brarLog.Warnf("Breach tx %v of ChannelPoint(%v) was replaced by "+
+		"revoked state #%v with txid %v, re-deriving retribution",
+		breachInfo.commitHash, breachInfo.chanPoint,
+		retribution.RevokedStateNum, replacementInfo.commitHash)
+
+	breachInfo.commitHash = replacementInfo.commitHash
+	breachInfo.settledBalance = replacementInfo.settledBalance
+	breachInfo.selfOutput = replacementInfo.selfOutput
+	breachInfo.revokedOutput = replacementInfo.revokedOutput
+	breachInfo.htlcOutputs = replacementInfo.htlcOutputs
+	breachInfo.breachProof = replacementInfo.breachProof
+	breachInfo.revokedStateNum = replacementInfo.revokedStateNum
+	breachInfo.expectedJusticeFee = replacementInfo.expectedJusticeFee
+
+	if err := b.cfg.Store.Add(breachInfo, b.quit); err != nil {
+		return nil, err
+	}
+
+	confEvent, err := b.registerConf(
+		&breachInfo.commitHash, b.cfg.BreachConfDepth,
+		uint32(spend.SpendingHeight),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return confEvent.Confirmed, nil
+}
+
+// abandonSupersededBreach withdraws the retribution of a channel whose
+// cooperative close confirmed in place of the breach transaction. The channel
+// is marked as fully closed, as its funds have been settled cooperatively.
+func (b *breachArbiter) abandonSupersededBreach(breachInfo *retributionInfo) {
+	chanPoint := &breachInfo.chanPoint
+
+	brarLog.Infof("Cooperative close of ChannelPoint(%v) confirmed in "+
+		"place of breach transaction %v, abandoning retribution",
+		chanPoint, breachInfo.commitHash)
+
+	b.UntrackCoopClose(chanPoint)
+	b.clearRetributionPhase(chanPoint)
+
+	if err := b.markChanFullyClosed(chanPoint); err != nil {
+		brarLog.Errorf("unable to mark chan as closed, retaining "+
+			"retribution: %v", err)
+		return
+	}
+	if err := b.cfg.Store.Remove(chanPoint); err != nil {
+		brarLog.Errorf("unable to remove retribution from the db: %v",
+			err)
+	}
+}
+
+// awaitBreachConfs returns a channel over which a single confirmation is
+// delivered once the breach transaction, signalled by breachConf, and each of
+// the given additional transactions have confirmed. The returned channel is
+// closed without delivering a confirmation if the breach arbiter shuts down
+// beforehand.
+func (b *breachArbiter) awaitBreachConfs(
+	breachConf <-chan *chainntnfs.TxConfirmation,
+	txids []chainhash.Hash) (chan *chainntnfs.TxConfirmation, error) {
+
+	_, currentHeight, err := b.cfg.ChainIO.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	confs := []<-chan *chainntnfs.TxConfirmation{breachConf}
+	for i := range txids {
+		confEvent, err := b.registerConf(
+			&txids[i], b.cfg.BreachConfDepth,
+			uint32(currentHeight),
+		)
+		if err != nil {
+			return nil, err
+		}
+		confs = append(confs, confEvent.Confirmed)
+	}
+
+	allConfirmed := make(chan *chainntnfs.TxConfirmation, 1)
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		var lastConf *chainntnfs.TxConfirmation
+		for _, conf := range confs {
+			select {
+			case txConf, ok := <-conf:
+				if !ok {
+					close(allConfirmed)
+					return
+				}
+				lastConf = txConf
+
+			case <-b.quit:
+				close(allConfirmed)
+				return
+			}
+		}
+
+		allConfirmed <- lastConf
+	}()
+
+	return allConfirmed, nil
+}
+
+// numActiveObservers returns the number of channels currently being watched
+// for breaches.
+func (b *breachArbiter) numActiveObservers() int {
+	b.observerMtx.RLock()
+	defer b.observerMtx.RUnlock()
+
+	return len(b.breachObservers)
+}
+
+// IsWatching returns true if the channel with the given channel point is
+// currently being watched for breaches.
+func (b *breachArbiter) IsWatching(chanPoint *wire.OutPoint) bool {
+	b.observerMtx.RLock()
+	defer b.observerMtx.RUnlock()
+
+	_, ok := b.breachObservers[*chanPoint]
+	return ok
+}
+
+// exactRetribution is a goroutine which is executed once a contract breach has
+// been detected by a breachObserver. This function is responsible for
+// punishing a counterparty for violating the channel contract by sweeping ALL
+// the lingering funds within the channel into the daemon's wallet.
+//
+// NOTE: This MUST be run as a goroutine.
+func (b *breachArbiter) exactRetribution(
+	confChan *chainntnfs.ConfirmationEvent,
+	breachInfo *retributionInfo) {
+
+	defer b.wg.Done()
+
+	cancel := b.setRetributionPhase(
+		&breachInfo.chanPoint, retPhaseAwaitingBreachConf,
+	)
+	b.setValueAtRisk(&breachInfo.chanPoint, breachInfo.valueAtRisk())
+
+	// However the retribution ends, it's no longer active once we return,
+	// so it's neither reported as stuck nor mistaken for a live one.
+	defer b.releaseRetributionPhase(&breachInfo.chanPoint, cancel)
+
+	// A slot bounding the number of active retributions is only acquired
+	// once justice is ready to be served, so that retributions whose
+	// breach is yet to confirm, or which are parked, don't hold up others.
+	var slotHeld bool
+	defer func() {
+		if slotHeld {
+			b.retributionSlots.release()
+		}
+	}()
+
+	// The outputs we're entitled to may be spread across several
+	// transactions, e.g. should the remote party have broadcast a
+	// second-stage HTLC transaction before we acted. Justice can only be
+	// served once all of them have confirmed.
+	breachConf := confChan.Confirmed
+	if txids := breachInfo.breachTxids(); len(txids) > 1 {
+		var err error
+		breachConf, err = b.awaitBreachConfs(breachConf, txids[1:])
+		if err != nil {
+			brarLog.Errorf("unable to await confirmation of breach "+
+				"txs for ChannelPoint(%v): %v",
+				breachInfo.chanPoint, err)
+			return
+		}
+	}
+
+	var batch *peerJusticeBatch
+	if b.cfg.BatchPeerJustice {
+		batch = b.joinPeerBatch(breachInfo)
+	}
+
+	// Should we have cooperatively closed the channel as it was breached,
+	// both closing transactions spend the funding output, and only one of
+	// them can confirm. Until the breach transaction confirms, we also
+	// await that of our closing transaction, whose confirmation would
+	// render the retribution moot.
+	coopConf, err := b.awaitCoopCloseConf(&breachInfo.chanPoint)
+	if err != nil {
+		brarLog.Errorf("unable to await confirmation of cooperative "+
+			"close of ChannelPoint(%v): %v", breachInfo.chanPoint,
+			err)
+		return
+	}
+
+	// Until the breach transaction confirms, the breaching party may
+	// replace it with another of their revoked commitments, invalidating
+	// the outputs we intend to sweep, so we'll watch for the funding
+	// output being spent by any other transaction.
+	breachSpend, err := b.watchBreachReplacement(breachInfo)
+	if err != nil {
+		brarLog.Errorf("unable to watch for replacement of breach tx "+
+			"for ChannelPoint(%v): %v", breachInfo.chanPoint, err)
+	}
+	var replaced <-chan *chainntnfs.SpendDetail
+	if breachSpend != nil {
+		defer breachSpend.Cancel()
+		replaced = breachSpend.Spend
+	}
+
+	// TODO(roasbeef): state needs to be checkpointed here
+
+	// The retribution is driven by confirmation notifications, which may
+	// be delivered redundantly or out of order. Each one is interpreted
+	// by retributionTransition according to the phase we're in, ensuring
+	// that no work is repeated.
+	var (
+		phase       = retPhaseAwaitingBreachConf
+		justiceConf chan *chainntnfs.TxConfirmation
+		broadcastAt time.Time
+
+		// justiceTx is the most recently broadcast justice
+		// transaction, and cpfpChild is the child bumping its fee, if
+		// any. The child's confirmation implies that of its parent,
+		// so it's awaited via childConf alongside justiceConf.
+		justiceTx *wire.MsgTx
+		cpfpChild *wire.MsgTx
+		childConf chan *chainntnfs.TxConfirmation
+
+		// epochs delivers new blocks while awaiting confirmation of
+		// the justice transaction, if its fee is to be bumped.
+		epochs          <-chan *chainntnfs.BlockEpoch
+		broadcastHeight int32
+		bestHeight      int32
+
+		// lastBumpHeight and lastBumpAt record when the fee of the
+		// justice transaction was last chosen, either by its
+		// broadcast or by a bump, to space out successive bumps.
+		lastBumpHeight int32
+		lastBumpAt     time.Time
+
+		// racePolls fires while awaiting confirmation of the justice
+		// transaction, if the mempool is to be polled for competing
+		// transactions.
+		racePolls <-chan time.Time
+
+		// confTimeout fires once the justice transaction has awaited
+		// confirmation for longer than JusticeConfTimeout.
+		confTimeout <-chan time.Time
+
+		// approved is closed once the operator approves the justice
+		// transaction, and approvalTimeout fires once approval has
+		// been awaited for longer than JusticeApprovalTimeout.
+		approved        <-chan struct{}
+		approvalTimeout <-chan time.Time
+
+		// unlocked is closed once the wallet, which blocked the
+		// signing of the justice transaction, is unlocked.
+		unlocked <-chan struct{}
+
+		// released is closed once the broadcast hold, which held the
+		// justice transaction, is released.
+		released <-chan struct{}
+
+		// feeChecks delivers new blocks while the retribution is
+		// uneconomic, so that it's re-checked as fees change.
+		feeChecks *chainntnfs.BlockEpochEvent
+
+		// bumpEpochs, confTimer and raceTicker back epochs,
+		// confTimeout and racePolls respectively once justice has
+		// been served. Each is replaced, rather than added to, as
+		// justice is served anew.
+		bumpEpochs *chainntnfs.BlockEpochEvent
+		confTimer  *time.Timer
+		raceTicker *time.Ticker
+	)
+	defer func() {
+		if feeChecks != nil {
+			feeChecks.Cancel()
+		}
+		if bumpEpochs != nil {
+			bumpEpochs.Cancel()
+		}
+		if confTimer != nil {
+			confTimer.Stop()
+		}
+		if raceTicker != nil {
+			raceTicker.Stop()
+		}
+	}()
+
+	// Should approval have been requested before a restart, we'll resume
+	// awaiting it right away, as the breach transaction had confirmed.
+	if breachInfo.approval == justiceApprovalPending {
+		phase = retPhaseAwaitingApproval
+		approved, approvalTimeout = b.requestJusticeApproval(breachInfo)
+	}
+
+	// Should a justice transaction have been broadcast before a restart,
+	// it may confirm before the confirmation of the breach transaction is
+	// delivered, e.g. while the notifier catches up, in which case the
+	// retribution is finalized without serving justice anew.
+	if breachInfo.justiceTxid != (chainhash.Hash{}) {
+		ntfn, err := b.registerPriorJusticeConf(breachInfo)
+		if err != nil {
+			brarLog.Errorf("unable to register for conf of prior "+
+				"justice tx %v: %v", breachInfo.justiceTxid,
+				err)
+		} else {
+			justiceConf = ntfn.Confirmed
+		}
+	}
+
+	for {
+		var event retributionEvent
+		select {
+		case conf, ok := <-breachConf:
+			// If the second value is !ok, then the channel has
+			// been closed signifying a daemon shutdown, so we
+			// exit.
+			if !ok {
+				return
+			}
+			event = retEventBreachConfirmed
+			b.recordRetributionDeadline(breachInfo, conf)
+
+			// With the breach transaction confirmed, it can no
+			// longer be replaced.
+			replaced = nil
+
+		// The funding output has been spent, which may be by a
+		// transaction replacing the breach transaction.
+		case spend, ok := <-replaced:
+			if !ok {
+				return
+			}
+			replaced = nil
+
+			if phase != retPhaseAwaitingBreachConf ||
+				*spend.SpenderTxHash == breachInfo.commitHash {
+
+				continue
+			}
+
+			conf, err := b.rederiveReplacedBreach(breachInfo, spend)
+			if err != nil {
+				brarLog.Errorf("unable to re-derive "+
+					"retribution for ChannelPoint(%v) "+
+					"against tx %v: %v",
+					breachInfo.chanPoint,
+					spend.SpenderTxHash, err)
+				continue
+			}
+			breachConf = conf
+			b.setValueAtRisk(
+				&breachInfo.chanPoint, breachInfo.valueAtRisk(),
+			)
+			continue
+
+		case conf, ok := <-justiceConf:
+			if !ok {
+				return
+			}
+			event = retEventJusticeConfirmed
+			breachInfo.recordJusticeConf(conf)
+
+		// The child can't confirm before its parent, but as the pair
+		// was bumped as a package, they'll almost always confirm
+		// within the same block.
+		case conf, ok := <-childConf:
+			if !ok {
+				return
+			}
+			event = retEventJusticeConfirmed
+			breachInfo.recordJusticeConf(conf)
+
+		case epoch, ok := <-epochs:
+			if !ok {
+				return
+			}
+			event = retEventBlockEpoch
+			bestHeight = epoch.Height
+
+		case <-racePolls:
+			event = retEventMempoolPoll
+
+		case <-confTimeout:
+			event = retEventConfTimeout
+
+		case <-approved:
+			approved, approvalTimeout = nil, nil
+			event = retEventApproved
+
+		case <-unlocked:
+			unlocked = nil
+			event = retEventWalletUnlocked
+
+		case <-released:
+			released = nil
+			event = retEventHoldReleased
+
+		case <-approvalTimeout:
+			approvalTimeout = nil
+			event = retEventApprovalTimeout
+			if b.cfg.JusticeApprovalAutoApprove {
+				brarLog.Warnf("Approval of justice for "+
+					"ChannelPoint(%v) timed out, "+
+					"approving automatically",
+					breachInfo.chanPoint)
+
+				approved = nil
+				event = retEventApproved
+			}
+
+		// Our cooperative close confirmed in place of the breach
+		// transaction, so there's no justice to be served.
+		case _, ok := <-coopConf:
+			if !ok {
+				return
+			}
+			if batch != nil {
+				b.leavePeerBatch(batch, breachInfo)
+			}
+			b.abandonSupersededBreach(breachInfo)
+			return
+
+		// The operator has withdrawn this retribution, so there's
+		// nothing left for us to do.
+		case <-cancel:
+			brarLog.Infof("Retribution for ChannelPoint(%v) "+
+				"cancelled", breachInfo.chanPoint)
+			if batch != nil {
+				b.leavePeerBatch(batch, breachInfo)
+			}
+			return
+
+		case <-b.quit:
+			return
+		}
+
+		switch retributionTransition(phase, event) {
+		case retActionIgnore:
+			brarLog.Debugf("Ignoring %v for ChannelPoint(%v) in "+
+				"phase %v", event, breachInfo.chanPoint, phase)
+
+		case retActionBroadcast:
+			// Justice moving large amounts is only served once
+			// the operator has approved it.
+			switch {
+			case phase == retPhaseAwaitingApproval:
+				b.recordJusticeApproval(
+					breachInfo, justiceApprovalGranted,
+				)
+
+			case b.requiresJusticeApproval(breachInfo):
+				phase = retPhaseAwaitingApproval
+				approved, approvalTimeout =
+					b.requestJusticeApproval(breachInfo)
+				continue
+			}
+
+			// Fees may have changed since the breach was detected,
+			// so we'll only serve justice if it's still economic,
+			// re-checking with each block until it is.
+			breachInfo.expectedJusticeFee = b.estimateJusticeFee(
+				breachInfo,
+			)
+			if b.uneconomicBreach(breachInfo) {
+				if batch != nil {
+					b.leavePeerBatch(batch, breachInfo)
+					batch = nil
+				}
+
+				// Once the breaching party may claim the
+				// breached outputs, waiting for fees to fall
+				// is futile.
+				if b.breachContested(breachInfo) {
+					b.acknowledgeUneconomicBreach(breachInfo)
+					return
+				}
+				if phase == retPhaseUneconomic {
+					continue
+				}
+
+				epochEvent, err :=
+					b.cfg.Notifier.RegisterBlockEpochNtfn()
+				if err != nil {
+					brarLog.Errorf("unable to register for "+
+						"block epochs: %v", err)
+					b.acknowledgeUneconomicBreach(breachInfo)
+					return
+				}
+				feeChecks = epochEvent
+				epochs = epochEvent.Epochs
+
+				brarLog.Warnf("Justice for ChannelPoint(%v) "+
+					"is uneconomic, %v at risk against an "+
+					"expected fee of %v, awaiting lower "+
+					"fees", breachInfo.chanPoint,
+					breachInfo.valueAtRisk(),
+					breachInfo.expectedJusticeFee)
+
+				phase = retPhaseUneconomic
+				b.setRetributionPhase(
+					&breachInfo.chanPoint, phase,
+				)
+				continue
+			}
+			if feeChecks != nil {
+				feeChecks.Cancel()
+				feeChecks, epochs = nil, nil
+			}
+
+			// While the operator holds broadcasts, the retribution
+			// remains persisted, and resumes once released.
+			if hold := b.heldBroadcasts(); hold != nil {
+				if batch != nil {
+					b.leavePeerBatch(batch, breachInfo)
+					batch = nil
+				}
+				phase = retPhaseBroadcastHeld
+				released = hold
+				b.setRetributionPhase(
+					&breachInfo.chanPoint, phase,
+				)
+
+				brarLog.Infof("Broadcast of justice for "+
+					"ChannelPoint(%v) held",
+					breachInfo.chanPoint)
+				continue
+			}
+
+			// Justice can't be signed while the wallet is locked,
+			// so we'll wait for it to be unlocked, serving the
+			// retribution alone once it is.
+			if b.walletLocked() {
+				if batch != nil {
+					b.leavePeerBatch(batch, breachInfo)
+					batch = nil
+				}
+				phase = retPhaseWalletLocked
+				unlocked = b.blockOnLockedWallet(breachInfo)
+				continue
+			}
+
+			// Should too many retributions already be serving
+			// justice, we'll queue behind them until one
+			// completes.
+			if b.retributionSlots != nil && !slotHeld {
+				err := b.retributionSlots.acquire(
+					breachInfo.valueAtRisk(), cancel,
+				)
+				switch {
+				case err == errRetributionCancelled:
+					brarLog.Infof("Retribution for "+
+						"ChannelPoint(%v) cancelled",
+						breachInfo.chanPoint)
+					if batch != nil {
+						b.leavePeerBatch(
+							batch, breachInfo,
+						)
+					}
+					return
+				case err != nil:
+					return
+				}
+				slotHeld = true
+			}
+
+			brarLog.Debugf("Breach transaction %v has been "+
+				"confirmed, sweeping revoked funds",
+				breachInfo.commitHash)
+
+			// The breach transaction won the race against any
+			// cooperative close, which can no longer confirm.
+			coopConf = nil
+			b.UntrackCoopClose(&breachInfo.chanPoint)
+
+			// With the breach transaction confirmed, we now serve
+			// justice, either alone or alongside the other
+			// breaches of the same peer.
+			var err error
+			if batch != nil {
+				justiceTx, err = b.awaitPeerJustice(
+					batch, breachInfo, cancel,
+				)
+			} else {
+				justiceTx, err = b.serveJustice(
+					[]*retributionInfo{breachInfo},
+				)
+			}
+			switch {
+			case err == errRetributionCancelled:
+				brarLog.Infof("Retribution for "+
+					"ChannelPoint(%v) cancelled",
+					breachInfo.chanPoint)
+				return
+
+			// Any batch we were part of has been served, so once
+			// the wallet is unlocked we'll serve justice alone.
+			// Until then, our slot is freed for others.
+			case err == lnwallet.ErrWalletLocked:
+				batch = nil
+				if slotHeld {
+					b.retributionSlots.release()
+					slotHeld = false
+				}
+				phase = retPhaseWalletLocked
+				unlocked = b.blockOnLockedWallet(breachInfo)
+				continue
+
+			case err != nil:
+				brarLog.Errorf("unable to serve justice for "+
+					"ChannelPoint(%v): %v",
+					breachInfo.chanPoint, err)
+				return
+			}
+
+			broadcastAt = time.Now()
+			phase = retPhaseAwaitingJusticeConf
+
+			_, currentHeight, err := b.cfg.ChainIO.GetBestBlock()
+			if err != nil {
+				brarLog.Errorf("unable to get current "+
+					"height: %v", err)
+				return
+			}
+
+			// We register for a notification to be dispatched
+			// once the justice tx is confirmed, at which point
+			// we'll finalize the retribution.
+			justiceTXID := justiceTx.TxHash()
+			ntfn, err := b.registerConf(
+				&justiceTXID, b.cfg.JusticeConfDepth,
+				uint32(currentHeight),
+			)
+			if err != nil {
+				brarLog.Errorf("unable to register for conf "+
+					"for txid: %v", justiceTXID)
+				return
+			}
+			justiceConf = ntfn.Confirmed
+
+			// Should the justice transaction, or any replacement,
+			// fail to confirm in time, the operator must step in.
+			// The timeout spans every justice transaction served,
+			// so it's only started by the first.
+			if b.cfg.JusticeConfTimeout != 0 && confTimer == nil {
+				confTimer = time.NewTimer(
+					b.cfg.JusticeConfTimeout,
+				)
+				confTimeout = confTimer.C
+			}
+
+			// If configured, we'll poll the mempool for any
+			// transaction racing the justice transaction, so that
+			// we may outbid it. Batched justice transactions are
+			// never replaced.
+			racing := batch == nil && b.cfg.JusticeFeeRace &&
+				b.cfg.MempoolSpends != nil
+			switch {
+			case racing && raceTicker == nil:
+				raceTicker = time.NewTicker(
+					justiceRacePollInterval,
+				)
+				racePolls = raceTicker.C
+
+			case !racing && raceTicker != nil:
+				raceTicker.Stop()
+				raceTicker = nil
+				racePolls = nil
+			}
+
+			// Any blocks tracked for the prior justice
+			// transaction are no longer of interest.
+			if bumpEpochs != nil {
+				bumpEpochs.Cancel()
+				bumpEpochs = nil
+				epochs = nil
+			}
+
+			// If a bump schedule is configured, or evictions from
+			// the mempool can be detected, we'll track new blocks
+			// so that the justice transaction is bumped or
+			// re-derived should it fail to confirm in time.
+			// Batched justice transactions are shared by several
+			// retributions, and so are never bumped.
+			if batch != nil || (len(b.cfg.JusticeBumpSchedule) == 0 &&
+				b.cfg.TxInMempool == nil) {
+
+				continue
+			}
+			bumpEpochs, err = b.cfg.Notifier.RegisterBlockEpochNtfn()
+			if err != nil {
+				brarLog.Errorf("unable to register for block "+
+					"epochs, justice tx %v won't be bumped: "+
+					"%v", justiceTXID, err)
+				bumpEpochs = nil
+				continue
+			}
+
+			epochs = bumpEpochs.Epochs
+			broadcastHeight = currentHeight
+			lastBumpHeight, lastBumpAt = currentHeight, broadcastAt
+
+		case retActionBump:
+			// Should the justice transaction have been evicted
+			// from the mempool, rebroadcasting it unchanged is
+			// futile, so we'll re-derive it at the current fee
+			// estimate instead.
+			if b.justiceEvicted(justiceTx) {
+				rederivedTx, err := b.rederiveJustice(breachInfo)
+				if err != nil {
+					brarLog.Errorf("unable to re-derive "+
+						"evicted justice tx for "+
+						"ChannelPoint(%v): %v",
+						breachInfo.chanPoint, err)
+					continue
+				}
+
+				justiceTx = rederivedTx
+				cpfpChild = nil
+				childConf = nil
+
+				justiceTXID := justiceTx.TxHash()
+				ntfn, err := b.registerConf(
+					&justiceTXID, b.cfg.JusticeConfDepth,
+					uint32(broadcastHeight),
+				)
+				if err != nil {
+					brarLog.Errorf("unable to register for "+
+						"conf for txid: %v", justiceTXID)
+					return
+				}
+				justiceConf = ntfn.Confirmed
+
+				continue
+			}
+
+			// Determine the tier the fee should be at given the
+			// number of blocks that have elapsed since broadcast.
+			if bestHeight < broadcastHeight {
+				continue
+			}
+			tier := justiceBumpTier(
+				b.cfg.JusticeBumpSchedule,
+				uint32(bestHeight-broadcastHeight),
+			)
+			if tier <= breachInfo.bumpTier {
+				continue
+			}
+
+			// However many blocks have been connected, successive
+			// bumps are spaced out by the configured minimum.
+			tooSoon := b.bumpTooSoon(
+				lastBumpHeight, lastBumpAt, bestHeight,
+			)
+			if tooSoon {
+				brarLog.Debugf("Deferring bump of justice tx "+
+					"for ChannelPoint(%v) to tier %v, "+
+					"last bumped at height %v",
+					breachInfo.chanPoint, tier,
+					lastBumpHeight)
+				continue
+			}
+
+			// If preferred, we'll bump the fee via a child
+			// spending the justice transaction's output, as long
+			// as the wallet is able to spend it.
+			if b.cfg.JusticeBumpCPFP &&
+				b.justiceOutputSpendable(justiceTx) {
+
+				child, err := b.cpfpJustice(
+					breachInfo, justiceTx, cpfpChild, tier,
+				)
+				if err != nil {
+					brarLog.Errorf("unable to bump justice "+
+						"tx for ChannelPoint(%v) to "+
+						"tier %v via CPFP: %v",
+						breachInfo.chanPoint, tier, err)
+					continue
+				}
+				cpfpChild = child
+				lastBumpHeight = bestHeight
+				lastBumpAt = time.Now()
+
+				childTXID := child.TxHash()
+				ntfn, err := b.registerConf(
+					&childTXID, b.cfg.JusticeConfDepth,
+					uint32(broadcastHeight),
+				)
+				if err != nil {
+					brarLog.Errorf("unable to register for "+
+						"conf for txid: %v", childTXID)
+					continue
+				}
+				childConf = ntfn.Confirmed
+
+				continue
+			}
+
+			bumpedTx, err := b.bumpJustice(breachInfo, tier)
+			if err != nil {
+				brarLog.Errorf("unable to bump justice tx for "+
+					"ChannelPoint(%v) to tier %v: %v",
+					breachInfo.chanPoint, tier, err)
+				continue
+			}
+
+			// Any child of the replaced justice transaction is
+			// now invalid.
+			justiceTx = bumpedTx
+			lastBumpHeight, lastBumpAt = bestHeight, time.Now()
+			cpfpChild = nil
+			childConf = nil
+
+			// The replacement supersedes the prior justice
+			// transaction, so we'll now await its confirmation
+			// instead.
+			justiceTXID := justiceTx.TxHash()
+			ntfn, err := b.registerConf(
+				&justiceTXID, b.cfg.JusticeConfDepth,
+				uint32(broadcastHeight),
+			)
+			if err != nil {
+				brarLog.Errorf("unable to register for conf "+
+					"for txid: %v", justiceTXID)
+				return
+			}
+			justiceConf = ntfn.Confirmed
+
+		case retActionOutbid:
+			outbidTx, err := b.outbidJusticeRivals(breachInfo, justiceTx)
+			if err != nil {
+				brarLog.Errorf("unable to outbid transactions "+
+					"racing justice tx for ChannelPoint(%v): %v",
+					breachInfo.chanPoint, err)
+				continue
+			}
+			if outbidTx == nil {
+				continue
+			}
+
+			justiceTx = outbidTx
+			cpfpChild = nil
+			childConf = nil
+
+			justiceTXID := justiceTx.TxHash()
+			ntfn, err := b.registerConf(
+				&justiceTXID, b.cfg.JusticeConfDepth,
+				uint32(broadcastHeight),
+			)
+			if err != nil {
+				brarLog.Errorf("unable to register for conf "+
+					"for txid: %v", justiceTXID)
+				return
+			}
+			justiceConf = ntfn.Confirmed
+
+		case retActionEscalate:
+			// We'll no longer bump, replace or re-derive the
+			// justice transaction, but will continue to await the
+			// confirmation of those already broadcast.
+			phase = retPhaseNeedsIntervention
+			epochs = nil
+			racePolls = nil
+
+			b.setRetributionPhase(
+				&breachInfo.chanPoint, retPhaseNeedsIntervention,
+			)
+			b.escalateRetribution(breachInfo, justiceTx, broadcastAt)
+
+		case retActionEscalateApproval:
+			b.escalateJusticeApproval(breachInfo)
+
+		case retActionFinalize:
+			// Should the justice transaction have been bumped by a
+			// child, then its output has been spent by the child,
+			// whose outputs are the ones left in our wallet.
+			if cpfpChild != nil {
+				b.reportSweepOutputs(cpfpChild)
+			} else {
+				b.reportSweepOutputs(justiceTx)
+			}
+			b.finalizeRetribution(breachInfo, broadcastAt)
 			return
 		}
+	}
+}
+
+// registerPriorJusticeConf registers for the confirmation of the justice
+// transaction of the given retribution which was broadcast before a restart.
+func (b *breachArbiter) registerPriorJusticeConf(
+	breachInfo *retributionInfo) (*chainntnfs.ConfirmationEvent, error) {
+
+	_, currentHeight, err := b.cfg.ChainIO.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.registerConf(
+		&breachInfo.justiceTxid, b.cfg.JusticeConfDepth,
+		uint32(currentHeight),
+	)
+}
 
-		// TODO(roasbeef): factor in HTLCs
-		revokedFunds := breachInfo.revokedOutput.amt
-		totalFunds := revokedFunds + breachInfo.selfOutput.amt
+// requiresJusticeApproval returns true if the justice transaction of the given
+// retribution may only be broadcast once approved by the operator, as the
+// value at risk exceeds the JusticeApprovalThreshold.
+func (b *breachArbiter) requiresJusticeApproval(
+	breachInfo *retributionInfo) bool {
 
-		brarLog.Infof("Justice for ChannelPoint(%v) has "+
-			"been served, %v revoked funds (%v total) "+
-			"have been claimed", breachInfo.chanPoint,
-			revokedFunds, totalFunds)
+	if b.cfg.JusticeApprovalThreshold == 0 ||
+		breachInfo.approval == justiceApprovalGranted {
 
-		// With the channel closed, mark it in the database as such.
-		err := b.db.MarkChanFullyClosed(&breachInfo.chanPoint)
-		if err != nil {
-			brarLog.Errorf("unable to mark chan as closed: %v", err)
+		return false
+	}
+
+	return breachInfo.valueAtRisk() > b.cfg.JusticeApprovalThreshold
+}
+
+// requestJusticeApproval moves the given retribution into the phase in which
+// its justice transaction awaits the operator's approval, persisting the
+// request and alerting the operator. The returned channel is closed once the
+// retribution is approved, and the timeout channel, if non-nil, fires once
+// approval has been awaited for JusticeApprovalTimeout.
+func (b *breachArbiter) requestJusticeApproval(
+	breachInfo *retributionInfo) (<-chan struct{}, <-chan time.Time) {
+
+	approved := b.awaitRetributionApproval(&breachInfo.chanPoint)
+	b.recordJusticeApproval(breachInfo, justiceApprovalPending)
+
+	brarLog.Criticalf("Justice for ChannelPoint(%v) would sweep %v, "+
+		"exceeding the approval threshold of %v, awaiting operator "+
+		"approval", breachInfo.chanPoint, breachInfo.valueAtRisk(),
+		b.cfg.JusticeApprovalThreshold)
+
+	diag, err := b.DumpRetribution(&breachInfo.chanPoint)
+	if err != nil {
+		brarLog.Errorf("unable to assemble diagnostics for "+
+			"ChannelPoint(%v): %v", breachInfo.chanPoint, err)
+	} else {
+		b.notifyApprovalRequired(diag)
+	}
+
+	var timeout <-chan time.Time
+	if b.cfg.JusticeApprovalTimeout != 0 {
+		timeout = time.After(b.cfg.JusticeApprovalTimeout)
+	}
+
+	return approved, timeout
+}
+
+// recordJusticeApproval records the approval state of the given retribution,
+// persisting it so that it survives a restart. Failing to persist it is
+// logged, rather than interrupting the retribution.
+func (b *breachArbiter) recordJusticeApproval(breachInfo *retributionInfo,
+	approval justiceApproval) {
+
+	breachInfo.approval = approval
+	if err := b.cfg.Store.Add(breachInfo, b.quit); err != nil {
+		brarLog.Errorf("unable to persist approval state for "+
+			"ChannelPoint(%v): %v", breachInfo.chanPoint, err)
+	}
+}
+
+// recordRetributionDeadline derives the deadline of the given retribution from
+// the confirmation of its breach transaction, persisting it so that it
+// survives a restart. Failing to persist it is logged, rather than
+// interrupting the retribution.
+func (b *breachArbiter) recordRetributionDeadline(breachInfo *retributionInfo,
+	conf *chainntnfs.TxConfirmation) {
+
+	if conf == nil || conf.BlockHeight == 0 {
+		return
+	}
+
+	breachInfo.recordBreachHeight(conf.BlockHeight)
+	if breachInfo.deadlineHeight != 0 {
+		brarLog.Infof("Justice for ChannelPoint(%v) must confirm by "+
+			"height %v", breachInfo.chanPoint,
+			breachInfo.deadlineHeight)
+	}
+
+	if err := b.cfg.Store.Add(breachInfo, b.quit); err != nil {
+		brarLog.Errorf("unable to persist deadline for "+
+			"ChannelPoint(%v): %v", breachInfo.chanPoint, err)
+	}
+}
+
+// escalateJusticeApproval alerts the operator that the justice transaction of
+// the given retribution has awaited approval for longer than
+// JusticeApprovalTimeout. The retribution continues to await approval.
+func (b *breachArbiter) escalateJusticeApproval(breachInfo *retributionInfo) {
+	diag, err := b.DumpRetribution(&breachInfo.chanPoint)
+	if err != nil {
+		brarLog.Errorf("unable to assemble diagnostics for "+
+			"ChannelPoint(%v): %v", breachInfo.chanPoint, err)
+		return
+	}
+
+	brarLog.Criticalf("Justice for ChannelPoint(%v) has awaited approval "+
+		"for over %v, manual intervention is required: %v",
+		breachInfo.chanPoint, b.cfg.JusticeApprovalTimeout,
+		newLogClosure(func() string {
+			return spew.Sdump(diag)
+		}))
+
+	b.notifyManualIntervention(diag)
+}
+
+// walletLocked returns true if the WalletLocked hook reports that the wallet
+// is locked.
+func (b *breachArbiter) walletLocked() bool {
+	return b.cfg.WalletLocked != nil && b.cfg.WalletLocked()
+}
+
+// blockOnLockedWallet moves the given retribution into the phase in which it
+// awaits the unlocking of the wallet, alerting the operator. The retribution
+// remains persisted, and the returned channel is closed once the wallet is
+// unlocked.
+func (b *breachArbiter) blockOnLockedWallet(
+	breachInfo *retributionInfo) <-chan struct{} {
+
+	b.unlockMtx.Lock()
+	unlocked := b.walletUnlocked
+	b.unlockMtx.Unlock()
+
+	// The wallet may have been unlocked after signing failed, but before
+	// we began to await it, in which case we'll resume right away.
+	if b.cfg.WalletLocked != nil && !b.cfg.WalletLocked() {
+		resume := make(chan struct{})
+		close(resume)
+		unlocked = resume
+	}
+
+	b.setRetributionPhase(&breachInfo.chanPoint, retPhaseWalletLocked)
+
+	brarLog.Criticalf("Justice for ChannelPoint(%v) can't be signed as "+
+		"the wallet is locked, unlock it to sweep %v before the "+
+		"breach window closes", breachInfo.chanPoint,
+		breachInfo.valueAtRisk())
+
+	diag, err := b.DumpRetribution(&breachInfo.chanPoint)
+	if err != nil {
+		brarLog.Errorf("unable to assemble diagnostics for "+
+			"ChannelPoint(%v): %v", breachInfo.chanPoint, err)
+	} else {
+		b.notifyWalletLocked(diag)
+	}
+
+	return unlocked
+}
+
+// heldBroadcasts returns a channel that's closed once the broadcast hold is
+// released, or nil if broadcasts aren't held.
+func (b *breachArbiter) heldBroadcasts() <-chan struct{} {
+	b.holdMtx.Lock()
+	defer b.holdMtx.Unlock()
+
+	if !b.broadcastHold {
+		return nil
+	}
+	return b.holdReleased
+}
+
+// SetBroadcastHold holds, or releases, the broadcast of new justice
+// transactions, e.g. during maintenance of the chain backend. While held,
+// breaches are still detected and their retributions persisted, but any
+// retribution whose breach transaction confirms awaits the release of the
+// hold before broadcasting. Justice transactions already broadcast continue
+// to be tracked until they confirm.
+func (b *breachArbiter) SetBroadcastHold(hold bool) {
+	b.holdMtx.Lock()
+	defer b.holdMtx.Unlock()
+
+	if hold == b.broadcastHold {
+		return
+	}
+	b.broadcastHold = hold
+
+	if hold {
+		brarLog.Warnf("Holding broadcast of justice transactions")
+		return
+	}
+
+	close(b.holdReleased)
+	b.holdReleased = make(chan struct{})
+
+	brarLog.Infof("Broadcast hold released, resuming held retributions")
+}
+
+// WalletUnlocked notifies the breach arbiter that the wallet has been
+// unlocked, resuming each retribution blocked as its justice transaction
+// couldn't be signed.
+func (b *breachArbiter) WalletUnlocked() {
+	b.unlockMtx.Lock()
+	defer b.unlockMtx.Unlock()
+
+	close(b.walletUnlocked)
+	b.walletUnlocked = make(chan struct{})
+
+	brarLog.Infof("Wallet unlocked, resuming blocked retributions")
+}
+
+// escalateRetribution alerts the operator that the justice transaction of the
+// given retribution has failed to confirm within JusticeConfTimeout, and that
+// manual intervention is required.
+func (b *breachArbiter) escalateRetribution(breachInfo *retributionInfo,
+	justiceTx *wire.MsgTx, broadcastAt time.Time) {
+
+	diag, err := b.DumpRetribution(&breachInfo.chanPoint)
+	if err != nil {
+		brarLog.Errorf("unable to assemble diagnostics for "+
+			"ChannelPoint(%v): %v", breachInfo.chanPoint, err)
+		return
+	}
+
+	brarLog.Criticalf("Justice tx %v for ChannelPoint(%v) hasn't "+
+		"confirmed since %v despite %v broadcast attempts, manual "+
+		"intervention is required: %v", justiceTx.TxHash(),
+		breachInfo.chanPoint, broadcastAt, diag.BroadcastAttempts,
+		newLogClosure(func() string {
+			return spew.Sdump(diag)
+		}))
+
+	b.notifyManualIntervention(diag)
+}
+
+// markChanFullyClosed marks the channel as fully closed within the database,
+// retrying up to markChanClosedAttempts times should this fail.
+func (b *breachArbiter) markChanFullyClosed(chanPoint *wire.OutPoint) error {
+	var err error
+	for i := 0; i < markChanClosedAttempts; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(markChanClosedBackoff):
+			case <-b.quit:
+				return err
+			}
 		}
 
-		// Justice has been carried out; we can safely delete the
-		// retribution info from the database.
-		err = b.retributionStore.Remove(&breachInfo.chanPoint)
-		if err != nil {
-			brarLog.Errorf("unable to remove retribution "+
-				"from the db: %v", err)
+		err = b.cfg.DB.MarkChanFullyClosed(chanPoint)
+		if err == nil {
+			b.clearRestoredMark(chanPoint)
+			return nil
 		}
 
-		// TODO(roasbeef): add peer to blacklist?
+		brarLog.Warnf("Attempt %d to mark ChannelPoint(%v) as fully "+
+			"closed failed: %v", i+1, chanPoint, err)
+	}
+
+	return err
+}
 
-		// TODO(roasbeef): close other active channels with offending
-		// peer
+// clearRestoredMark clears any mark of the given fully closed channel as
+// restored from a backup, as it's no longer of use.
+func (b *breachArbiter) clearRestoredMark(chanPoint *wire.OutPoint) {
+	if err := b.restoredChans.Remove(chanPoint); err != nil {
+		brarLog.Errorf("Unable to clear restored mark of "+
+			"ChannelPoint(%v): %v", chanPoint, err)
+	}
+}
 
-		close(breachInfo.doneChan)
+// unreconciledClose is a breached channel whose state failed to be deleted
+// during startup, along with the summary its close is to be recorded with.
+type unreconciledClose struct {
+	channel *lnwallet.LightningChannel
+	summary channeldb.ChannelCloseSummary
+}
 
+// reconcileBreachClose retries the deletion of the state of the given
+// breached channel, should it have failed during startup, such that it may be
+// marked as fully closed.
+func (b *breachArbiter) reconcileBreachClose(chanPoint *wire.OutPoint) {
+	b.retMtx.Lock()
+	unreconciled, ok := b.unreconciledCloses[*chanPoint]
+	b.retMtx.Unlock()
+	if !ok {
 		return
-	case <-b.quit:
+	}
+
+	err := b.deleteChanState(unreconciled.channel, &unreconciled.summary)
+	if err != nil {
+		brarLog.Errorf("Unable to delete state of breached "+
+			"ChannelPoint(%v): %v", chanPoint, err)
+		return
+	}
+
+	b.retMtx.Lock()
+	delete(b.unreconciledCloses, *chanPoint)
+	b.retMtx.Unlock()
+}
+
+// markRetributionCompleted records that the given retribution has completed,
+// persisting it so that it survives a restart. Failing to persist it is
+// logged, rather than interrupting the retribution.
+func (b *breachArbiter) markRetributionCompleted(breachInfo *retributionInfo) {
+	breachInfo.completed = true
+	if err := b.cfg.Store.Add(breachInfo, b.quit); err != nil {
+		brarLog.Errorf("unable to record completion of retribution "+
+			"for ChannelPoint(%v): %v", breachInfo.chanPoint, err)
+	}
+}
+
+// removeRetribution removes the record of the given completed retribution
+// from the store, or archives it if RetainBreachEvidence is set.
+func (b *breachArbiter) removeRetribution(breachInfo *retributionInfo) error {
+	if !b.cfg.RetainBreachEvidence {
+		return b.cfg.Store.Remove(&breachInfo.chanPoint)
+	}
+
+	return b.cfg.Store.Archive(&ArchivedBreach{
+		JusticeTxid: breachInfo.justiceTxid,
+		Recovered:   breachInfo.recoveredFunds(),
+		ArchivedAt:  time.Now(),
+		retribution: breachInfo,
+	})
+}
+
+// retributionReaper periodically scans the store for the records of completed
+// retributions, retrying their removal, such that transient database errors
+// heal without requiring a restart. At most maxReapsPerInterval removals are
+// attempted per retributionReapInterval, and the operator is alerted once the
+// removal of a record has repeatedly failed.
+//
+// NOTE: This MUST be run as a goroutine.
+func (b *breachArbiter) retributionReaper() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(retributionReapInterval)
+	defer ticker.Stop()
+
+	failures := make(map[wire.OutPoint]uint32)
+	for {
+		select {
+		case <-ticker.C:
+			b.reregisterRetributions()
+			b.reapRetributions(failures)
+
+		case <-b.quit:
+			return
+		}
+	}
+}
+
+// unregisteredRetribution is a retribution whose registration for the
+// confirmation of its breach transaction failed during startup.
+type unregisteredRetribution struct {
+	ret        *retributionInfo
+	breachTxid chainhash.Hash
+	heightHint uint32
+}
+
+// registerConfWithRetry registers for the confirmation of the given
+// transaction, retrying up to startupRegisterAttempts times with an
+// exponential backoff should the registration fail.
+func (b *breachArbiter) registerConfWithRetry(txid *chainhash.Hash, numConfs,
+	heightHint uint32) (*chainntnfs.ConfirmationEvent, error) {
+
+	backoff := startupRegisterBackoff
+	var err error
+	for i := 0; i < startupRegisterAttempts; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-b.quit:
+				return nil, err
+			}
+			backoff *= 2
+		}
+
+		var confEvent *chainntnfs.ConfirmationEvent
+		confEvent, err = b.registerConf(txid, numConfs, heightHint)
+		if err == nil {
+			return confEvent, nil
+		}
+
+		brarLog.Warnf("Attempt %d to register for confirmation of "+
+			"tx %v failed: %v", i+1, txid, err)
+	}
+
+	return nil, err
+}
+
+// reregisterRetributions retries the registration for the confirmation of the
+// breach transaction of each retribution whose registration failed during
+// startup, launching the retribution once registered.
+func (b *breachArbiter) reregisterRetributions() {
+	b.retMtx.Lock()
+	pending := make(
+		[]*unregisteredRetribution, 0, len(b.unregisteredRetributions),
+	)
+	for _, unregistered := range b.unregisteredRetributions {
+		pending = append(pending, unregistered)
+	}
+	b.retMtx.Unlock()
+
+	for _, unregistered := range pending {
+		chanPoint := unregistered.ret.chanPoint
+		confChan, err := b.registerConf(
+			&unregistered.breachTxid, b.cfg.BreachConfDepth,
+			unregistered.heightHint,
+		)
+		if err != nil {
+			brarLog.Errorf("Unable to register for confirmation "+
+				"of breach tx %v of ChannelPoint(%v): %v",
+				unregistered.breachTxid, chanPoint, err)
+			continue
+		}
+
+		b.retMtx.Lock()
+		delete(b.unregisteredRetributions, chanPoint)
+		b.retMtx.Unlock()
+
+		brarLog.Infof("Registered for confirmation of breach tx %v of "+
+			"ChannelPoint(%v), resuming retribution",
+			unregistered.breachTxid, chanPoint)
+
+		b.wg.Add(1)
+		go b.exactRetribution(confChan, unregistered.ret)
+	}
+}
+
+// reapRetributions retries the removal of the records of up to
+// maxReapsPerInterval completed retributions, tracking the number of
+// consecutive failures to remove each within the given map.
+func (b *breachArbiter) reapRetributions(failures map[wire.OutPoint]uint32) {
+	var completed []*retributionInfo
+	err := b.cfg.Store.ForAll(func(ret *retributionInfo) error {
+		if ret.completed && len(completed) < maxReapsPerInterval {
+			completed = append(completed, ret)
+		}
+		return nil
+	})
+	if err != nil {
+		brarLog.Errorf("unable to scan for completed retributions: %v",
+			err)
 		return
 	}
+
+	for _, ret := range completed {
+		// A retribution still being finalized removes its own record.
+		b.retMtx.Lock()
+		_, active := b.activeRetributions[ret.chanPoint]
+		b.retMtx.Unlock()
+		if active {
+			continue
+		}
+
+		if err := b.removeRetribution(ret); err != nil {
+			failures[ret.chanPoint]++
+			numFailures := failures[ret.chanPoint]
+
+			brarLog.Errorf("Attempt %d to remove completed "+
+				"retribution for ChannelPoint(%v) failed: %v",
+				numFailures, ret.chanPoint, err)
+			if numFailures == reapFailureAlertThreshold {
+				brarLog.Criticalf("Unable to remove completed "+
+					"retribution for ChannelPoint(%v) "+
+					"after %d attempts, manual "+
+					"intervention may be required",
+					ret.chanPoint, numFailures)
+			}
+			continue
+		}
+		delete(failures, ret.chanPoint)
+
+		brarLog.Infof("Removed completed retribution for "+
+			"ChannelPoint(%v)", ret.chanPoint)
+
+		b.notifyChannelResolved(
+			ret.chanPoint, ret.recoveredFunds(),
+			ret.justiceConfHeight, ret.justiceBreakdown,
+		)
+		b.pruneChannelBackup(ret.chanPoint)
+	}
+}
+
+// finalizeRetribution completes a retribution whose justice transaction has
+// confirmed, closing the channel and removing the retribution from the store,
+// or archiving it if RetainBreachEvidence is set. broadcastAt is the time the
+// justice transaction was broadcast, or zero if it wasn't broadcast by us
+// during this run.
+func (b *breachArbiter) finalizeRetribution(breachInfo *retributionInfo,
+	broadcastAt time.Time) {
+
+	// TODO(roasbeef): factor in HTLCs
+	revokedFunds := breachInfo.revokedOutput.amt
+	totalFunds := breachInfo.recoveredFunds()
+
+	brarLog.Infof("Justice for ChannelPoint(%v) has "+
+		"been served at height %v, %v revoked funds (%v total) "+
+		"have been claimed", breachInfo.chanPoint,
+		breachInfo.justiceConfHeight, revokedFunds, totalFunds)
+	if breakdown := breachInfo.justiceBreakdown; breakdown != nil {
+		brarLog.Infof("Justice for ChannelPoint(%v) swept %v",
+			breachInfo.chanPoint, breakdown)
+	}
+
+	// With the channel closed, mark it in the database as such. Only
+	// once that has succeeded can we safely delete the retribution info
+	// from the database, as otherwise it is retained so that the channel
+	// can be reconciled on a subsequent startup.
+	resolved := true
+	b.reconcileBreachClose(&breachInfo.chanPoint)
+	err := b.markChanFullyClosed(&breachInfo.chanPoint)
+	if err != nil {
+		brarLog.Errorf("unable to mark chan as closed, retaining "+
+			"retribution: %v", err)
+		resolved = false
+	} else {
+		// We'll first record the retribution as completed, so that
+		// should its removal fail, the reaper retries it rather than
+		// the retribution being resumed after a restart.
+		b.markRetributionCompleted(breachInfo)
+
+		if err := b.removeRetribution(breachInfo); err != nil {
+			brarLog.Errorf("unable to remove retribution "+
+				"from the db, will retry: %v", err)
+			resolved = false
+		}
+	}
+
+	// With the HTLC outputs swept, the switch can now settle or
+	// fail back any circuits that went through the channel.
+	b.resolveHTLCs(breachInfo)
+
+	if resolved {
+		b.notifyChannelResolved(
+			breachInfo.chanPoint, totalFunds,
+			breachInfo.justiceConfHeight,
+			breachInfo.justiceBreakdown,
+		)
+		b.pruneChannelBackup(breachInfo.chanPoint)
+	}
+
+	if !broadcastAt.IsZero() {
+		b.recordJusticeLatency(breachInfo.breachDetectedAt,
+			broadcastAt, time.Now())
+	}
+
+	b.clearRetributionPhase(&breachInfo.chanPoint)
+
+	// TODO(roasbeef): add peer to blacklist?
+
+	// TODO(roasbeef): close other active channels with offending
+	// peer
+
+	// Retributions restored from disk have no one waiting on them.
+	if breachInfo.doneChan != nil {
+		close(breachInfo.doneChan)
+	}
 }
 
 // breachObserver notifies the breachArbiter contract observer goroutine that a
@@ -586,681 +3353,975 @@ func (b *breachArbiter) exactRetribution(
 // channel using the information provided within the BreachRetribution
 // generated due to the breach of channel contract. The funds will be swept
 // only after the breaching transaction receives a necessary number of
-// confirmations.
+// confirmations. If non-nil, the live channel is closed once the observer has
+// started, and the stopped channel once it exits.
 func (b *breachArbiter) breachObserver(contract *lnwallet.LightningChannel,
-	settleSignal chan struct{}) {
+	settleSignal, stopped, live chan struct{}) {
+
+	defer b.wg.Done()
+	if stopped != nil {
+		defer close(stopped)
+	}
+
+	chanPoint := contract.ChannelPoint()
+
+	brarLog.Debugf("Breach observer for ChannelPoint(%v) started",
+		chanPoint)
+
+	if live != nil {
+		close(live)
+	}
+
+	select {
+	// A read from this channel indicates that the contract has been
+	// settled cooperatively so we exit as our duties are no longer needed.
+	case <-settleSignal:
+		contract.Stop()
+
+	// The channel has been closed by a normal means: force closing with
+	// the latest commitment transaction.
+	case closeInfo := <-contract.UnilateralClose:
+		b.handleUnilateralClose(contract, closeInfo)
+
+	// A read from this channel indicates that a channel breach has been
+	// detected! So we notify the main coordination goroutine with the
+	// information needed to bring the counterparty to justice.
+	case breachInfo := <-contract.ContractBreach:
+		b.handleContractBreach(contract, breachInfo)
+
+	case <-b.quit:
+		return
+	}
+}
+
+// handleObserverEvent dispatches an event detected by the observer pool for
+// the given contract, mirroring the behaviour of breachObserver.
+func (b *breachArbiter) handleObserverEvent(contract *lnwallet.LightningChannel,
+	event observerEvent) {
+
+	switch {
+	case event.settled:
+		contract.Stop()
+
+	case event.closeInfo != nil:
+		b.handleUnilateralClose(contract, event.closeInfo)
+
+	case event.breachInfo != nil:
+		b.handleContractBreach(contract, event.breachInfo)
+	}
+}
+
+// observerEvent is the outcome of watching a single contract within the
+// observer pool. Exactly one of its fields is set.
+type observerEvent struct {
+	// settled indicates that the contract's settle signal was closed.
+	settled bool
+
+	// closeInfo is set if the contract was closed unilaterally by the
+	// remote party.
+	closeInfo *lnwallet.UnilateralCloseSummary
+
+	// breachInfo is set if the contract was breached.
+	breachInfo *lnwallet.BreachRetribution
+}
+
+// errBreachArbiterExiting is returned when the breach arbiter shuts down while
+// a retribution awaits a free slot.
+var errBreachArbiterExiting = errors.New("breach arbiter exiting")
+
+// handleUnilateralClose handles the closure of a watched channel by the remote
+// party's latest commitment transaction, sweeping our output once the closing
+// transaction has confirmed.
+func (b *breachArbiter) handleUnilateralClose(
+	contract *lnwallet.LightningChannel,
+	closeInfo *lnwallet.UnilateralCloseSummary) {
+
+	chanPoint := contract.ChannelPoint()
+
+	// Launch a goroutine to cancel out this contract within the
+	// breachArbiter's main goroutine.
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		select {
+		case b.settledContracts <- chanPoint:
+		case <-b.quit:
+		}
+	}()
 
-	defer b.wg.Done()
+	// As we just detected a channel was closed via a unilateral
+	// commitment broadcast by the remote party, we'll need to sweep our
+	// main commitment output, and any outstanding outgoing HTLC we had as
+	// well. The sweep is persisted, so that it can be resumed should we
+	// restart before it confirms.
+	//
+	// TODO(roasbeef): also notify utxoNursery, might've had
+	// outbound HTLC's in flight
+	b.beginCommitSweep(&commitSweepInfo{
+		chanPoint:   *chanPoint,
+		closeTxid:   *closeInfo.SpenderTxHash,
+		closeHeight: uint32(closeInfo.SpendingHeight),
+		inputs:      commitSweepInputs(closeInfo),
+	})
+}
+
+// handleContractBreach handles the breach of a watched channel, persisting the
+// retribution and handing it to the contractObserver to deal swift justice.
+func (b *breachArbiter) handleContractBreach(
+	contract *lnwallet.LightningChannel,
+	breachInfo *lnwallet.BreachRetribution) {
 
 	chanPoint := contract.ChannelPoint()
 
-	brarLog.Debugf("Breach observer for ChannelPoint(%v) started",
+	// If our state may be stale, broadcasting justice against
+	// what could be the remote party's legitimate state risks
+	// losing funds, so we'll fall back to recovering our own
+	// output instead.
+	if b.dataLossSuspected(chanPoint) {
+		b.deferBreachToRecovery(contract, breachInfo)
+		return
+	}
+
+	brarLog.Warnf("REVOKED STATE #%v FOR ChannelPoint(%v) "+
+		"broadcast, REMOTE PEER IS DOING SOMETHING "+
+		"SKETCHY!!!", breachInfo.RevokedStateNum,
 		chanPoint)
 
-	select {
-	// A read from this channel indicates that the contract has been
-	// settled cooperatively so we exit as our duties are no longer needed.
-	case <-settleSignal:
-		contract.Stop()
+	// Immediately notify the HTLC switch that this link has been
+	// breached in order to ensure any incoming or outgoing
+	// multi-hop HTLCs aren't sent over this link, nor any other
+	// links associated with this peer.
+	b.cfg.CloseLink(chanPoint, htlcswitch.CloseBreach)
+	chanInfo := contract.StateSnapshot()
+
+	// TODO(roasbeef): need to handle case of remote broadcast
+	// mid-local initiated state-transition, possible
+	// false-positive?
+
+	retInfo := b.newRetributionInfo(
+		chanPoint, breachInfo, chanInfo.RemoteIdentity,
+		chanInfo.Capacity, chanInfo.LocalBalance.ToSatoshis(),
+	)
+
+	// If we lack the material needed to sign for any of the breached
+	// outputs, or the revocation secret we hold doesn't match the revoked
+	// output, then a justice transaction can't be created, so rather than
+	// failing deep within the retribution, or at broadcast, we'll alert
+	// the operator right away.
+	if err := retInfo.checkSweepable(); err != nil {
+		b.abandonUnsweepableBreach(contract, retInfo, err)
 		return
+	}
 
-	// The channel has been closed by a normal means: force closing with
-	// the latest commitment transaction.
-	case closeInfo := <-contract.UnilateralClose:
-		// Launch a goroutine to cancel out this contract within the
-		// breachArbiter's main goroutine.
-		b.wg.Add(1)
-		go func() {
-			defer b.wg.Done()
-
-			select {
-			case b.settledContracts <- chanPoint:
-			case <-b.quit:
-			}
-		}()
+	// Persist the pending retribution state to disk. Should that fail,
+	// we'll abort rather than delete the channel's state below, which
+	// would leave nothing to resume the retribution from. The breach is
+	// then detected anew once we restart.
+	if err := b.cfg.Store.Add(retInfo, b.quit); err != nil {
+		brarLog.Criticalf("Unable to persist retribution for "+
+			"ChannelPoint(%v), aborting: %v", chanPoint, err)
+		return
+	}
 
-		// Next, we'll launch a goroutine to wait until the closing
-		// transaction has been confirmed so we can mark the contract
-		// as resolved in the database. This go routine is _not_
-		// tracked by the breach aribter's wait group since the callback
-		// may not be executed before shutdown, potentially leading to
-		// a deadlock.
-		//
-		// TODO(roasbeef): also notify utxoNursery, might've had
-		// outbound HTLC's in flight
-		go waitForChanToClose(uint32(closeInfo.SpendingHeight),
-			b.notifier, nil, chanPoint, closeInfo.SpenderTxHash,
-			func() {
-				// As we just detected a channel was closed via
-				// a unilateral commitment broadcast by the
-				// remote party, we'll need to sweep our main
-				// commitment output, and any outstanding
-				// outgoing HTLC we had as well.
-				//
-				// TODO(roasbeef): actually sweep HTLC's *
-				// ensure reliable confirmation
-				if closeInfo.SelfOutPoint != nil {
-					sweepTx, err := b.craftCommitSweepTx(
-						closeInfo,
-					)
-					if err != nil {
-						brarLog.Errorf("unable to "+
-							"generate sweep tx: %v",
-							err)
-						goto close
-					}
+	// The channel's state is deleted below, so we'll retain its backup
+	// in case the breach transaction is replaced before it confirms.
+	retInfo.backup = contract.Backup()
+
+	closeInfo := &channeldb.ChannelCloseSummary{
+		ChanPoint:      *chanPoint,
+		ClosingTXID:    breachInfo.BreachTransaction.TxHash(),
+		RemotePub:      &chanInfo.RemoteIdentity,
+		Capacity:       chanInfo.Capacity,
+		SettledBalance: chanInfo.LocalBalance.ToSatoshis(),
+		CloseType:      channeldb.BreachClose,
+		IsPending:      true,
+	}
+	if err := contract.DeleteState(closeInfo); err != nil {
+		brarLog.Errorf("unable to delete channel state: %v",
+			err)
+	}
 
-					err = b.wallet.PublishTransaction(
-						sweepTx,
-					)
-					if err != nil {
-						brarLog.Errorf("unable to "+
-							"broadcast tx: %v", err)
-					}
-				}
+	// Finally, we send the retribution information into the
+	// breachArbiter event loop to deal swift justice.
+	select {
+	case b.breachedContracts <- retInfo:
+	case <-b.quit:
+	}
+}
 
-			close:
-				brarLog.Infof("Force closed ChannelPoint(%v) "+
-					"is fully closed, updating DB",
-					chanPoint)
+// newRetributionInfo assembles the retribution for the breach of the channel
+// with the given channel point, described by breachInfo.
+func (b *breachArbiter) newRetributionInfo(chanPoint *wire.OutPoint,
+	breachInfo *lnwallet.BreachRetribution,
+	remoteIdentity btcec.PublicKey, capacity,
+	settledBalance btcutil.Amount) *retributionInfo {
+
+	// First we generate the witness generation function which will
+	// be used to sweep the output only we can satisfy on the
+	// commitment transaction. This output is just a regular p2wkh
+	// output.
+	localSignDesc := breachInfo.LocalOutputSignDesc
+	localWitness := func(tx *wire.MsgTx, hc *txscript.TxSigHashes,
+		inputIndex int) ([][]byte, error) {
+
+		desc := localSignDesc
+		desc.SigHashes = hc
+		desc.InputIndex = inputIndex
+
+		return lnwallet.CommitSpendNoDelay(
+			b.cfg.Signer, &desc, tx)
+	}
 
-				err := b.db.MarkChanFullyClosed(chanPoint)
-				if err != nil {
-					brarLog.Errorf("unable to mark chan "+
-						"as closed: %v", err)
-				}
-			})
+	// Next we create the witness generation function that will be
+	// used to sweep the cheating counterparty's output by taking
+	// advantage of the revocation clause within the output's
+	// witness script.
+	remoteSignDesc := breachInfo.RemoteOutputSignDesc
+	remoteWitness := func(tx *wire.MsgTx, hc *txscript.TxSigHashes,
+		inputIndex int) ([][]byte, error) {
 
-	// A read from this channel indicates that a channel breach has been
-	// detected! So we notify the main coordination goroutine with the
-	// information needed to bring the counterparty to justice.
-	case breachInfo := <-contract.ContractBreach:
-		brarLog.Warnf("REVOKED STATE #%v FOR ChannelPoint(%v) "+
-			"broadcast, REMOTE PEER IS DOING SOMETHING "+
-			"SKETCHY!!!", breachInfo.RevokedStateNum,
-			chanPoint)
-
-		// Immediately notify the HTLC switch that this link has been
-		// breached in order to ensure any incoming or outgoing
-		// multi-hop HTLCs aren't sent over this link, nor any other
-		// links associated with this peer.
-		b.htlcSwitch.CloseLink(chanPoint, htlcswitch.CloseBreach)
-		chanInfo := contract.StateSnapshot()
-
-		// TODO(roasbeef): need to handle case of remote broadcast
-		// mid-local initiated state-transition, possible
-		// false-positive?
-
-		// First we generate the witness generation function which will
-		// be used to sweep the output only we can satisfy on the
-		// commitment transaction. This output is just a regular p2wkh
-		// output.
-		localSignDesc := breachInfo.LocalOutputSignDesc
-		localWitness := func(tx *wire.MsgTx, hc *txscript.TxSigHashes,
-			inputIndex int) ([][]byte, error) {
-
-			desc := localSignDesc
-			desc.SigHashes = hc
-			desc.InputIndex = inputIndex
-
-			return lnwallet.CommitSpendNoDelay(
-				b.wallet.Cfg.Signer, &desc, tx)
-		}
+		desc := breachInfo.RemoteOutputSignDesc
+		desc.SigHashes = hc
+		desc.InputIndex = inputIndex
 
-		// Next we create the witness generation function that will be
-		// used to sweep the cheating counterparty's output by taking
-		// advantage of the revocation clause within the output's
-		// witness script.
-		remoteSignDesc := breachInfo.RemoteOutputSignDesc
-		remoteWitness := func(tx *wire.MsgTx, hc *txscript.TxSigHashes,
-			inputIndex int) ([][]byte, error) {
+		return lnwallet.CommitSpendRevoke(
+			b.cfg.Signer, &desc, tx)
+	}
 
-			desc := breachInfo.RemoteOutputSignDesc
-			desc.SigHashes = hc
-			desc.InputIndex = inputIndex
+	// The balances of the snapshot are only used for bookkeeping, so
+	// should they fail to reconcile with the outputs actually being swept,
+	// we'll defer to the latter.
+	settledBalance = b.reconcileSettledBalance(
+		chanPoint, capacity, settledBalance,
+		btcutil.Amount(localSignDesc.Output.Value),
+		btcutil.Amount(remoteSignDesc.Output.Value),
+	)
+
+	// Each HTLC output is swept via the revocation clause of its script,
+	// which depends on whether it was offered by the breaching party or
+	// by us.
+	htlcOutputs := make(
+		[]*breachedOutput, 0, len(breachInfo.HtlcRetributions),
+	)
+	for i := range breachInfo.HtlcRetributions {
+		htlc := &breachInfo.HtlcRetributions[i]
+		htlcAmt := btcutil.Amount(htlc.SignDesc.Output.Value)
+		htlcOutputs = append(htlcOutputs, &breachedOutput{
+			amt:            htlcAmt,
+			outpoint:       htlc.OutPoint,
+			signDescriptor: htlc.SignDesc,
+			witnessType:    htlc.WitnessType(),
+			refundTimeout:  htlc.RefundTimeout,
+			paymentHash:    htlc.PaymentHash,
+		})
+	}
 
-			return lnwallet.CommitSpendRevoke(
-				b.wallet.Cfg.Signer, &desc, tx)
-		}
+	// Assemble the retribution information that parameterizes the
+	// construction of transactions required to correct the breach.
+	ret := &retributionInfo{
+		commitHash: breachInfo.BreachTransaction.TxHash(),
+		chanPoint:  *chanPoint,
+
+		remoteIdentity: remoteIdentity,
+		capacity:       capacity,
+		settledBalance: settledBalance,
+
+		selfOutput: &breachedOutput{
+			amt:            btcutil.Amount(localSignDesc.Output.Value),
+			outpoint:       breachInfo.LocalOutpoint,
+			signDescriptor: localSignDesc,
+			witnessType:    lnwallet.CommitmentNoDelay,
+			witnessFunc:    localWitness,
+		},
+
+		revokedOutput: &breachedOutput{
+			amt:            btcutil.Amount(remoteSignDesc.Output.Value),
+			outpoint:       breachInfo.RemoteOutpoint,
+			signDescriptor: remoteSignDesc,
+			witnessType:    lnwallet.CommitmentRevoke,
+			witnessFunc:    remoteWitness,
+			contestDelay:   breachInfo.RemoteDelay,
+		},
+
+		htlcOutputs: htlcOutputs,
+
+		breachDetectedAt: time.Now(),
+
+		doneChan: make(chan struct{}),
+	}
 
-		// Assemble the retribution information that parameterizes the
-		// construction of transactions required to correct the breach.
-		// TODO(roasbeef): populate htlc breaches
-		retInfo := &retributionInfo{
-			commitHash: breachInfo.BreachTransaction.TxHash(),
-			chanPoint:  *chanPoint,
-
-			remoteIdentity: chanInfo.RemoteIdentity,
-			capacity:       chanInfo.Capacity,
-			settledBalance: chanInfo.LocalBalance.ToSatoshis(),
-
-			selfOutput: &breachedOutput{
-				amt:            btcutil.Amount(localSignDesc.Output.Value),
-				outpoint:       breachInfo.LocalOutpoint,
-				signDescriptor: localSignDesc,
-				witnessType:    lnwallet.CommitmentNoDelay,
-				witnessFunc:    localWitness,
-			},
-
-			revokedOutput: &breachedOutput{
-				amt:            btcutil.Amount(remoteSignDesc.Output.Value),
-				outpoint:       breachInfo.RemoteOutpoint,
-				signDescriptor: remoteSignDesc,
-				witnessType:    lnwallet.CommitmentRevoke,
-				witnessFunc:    remoteWitness,
-			},
-
-			htlcOutputs: []*breachedOutput{},
-
-			doneChan: make(chan struct{}),
+	// The breach transaction, together with the commitment secret the
+	// remote party revealed upon revoking it, proves their breach.
+	ret.breachProof = newBreachProof(chanPoint, breachInfo)
+	if ret.breachProof != nil {
+		if err := ret.breachProof.Verify(); err != nil {
+			brarLog.Warnf("Discarding unverifiable breach proof "+
+				"for ChannelPoint(%v): %v", chanPoint, err)
+			ret.breachProof = nil
 		}
+	}
 
-		// Persist the pending retribution state to disk.
-		if err := b.retributionStore.Add(retInfo); err != nil {
-			brarLog.Errorf("unable to persist "+
-				"retribution info to db: %v", err)
-		}
+	// If configured, only a reference to the sign descriptors of the
+	// commitment outputs is persisted, from which they're re-derived
+	// using the channel's backup upon recovery.
+	ret.revokedStateNum = breachInfo.RevokedStateNum
+	if b.cfg.CompactSignDescriptors && b.cfg.FetchChannelBackup != nil {
+		ret.selfOutput.signDescRef = true
+		ret.revokedOutput.signDescRef = true
+	}
 
-		closeInfo := &channeldb.ChannelCloseSummary{
-			ChanPoint:      *chanPoint,
-			ClosingTXID:    breachInfo.BreachTransaction.TxHash(),
-			RemotePub:      &chanInfo.RemoteIdentity,
-			Capacity:       chanInfo.Capacity,
-			SettledBalance: chanInfo.LocalBalance.ToSatoshis(),
-			CloseType:      channeldb.BreachClose,
-			IsPending:      true,
-		}
-		if err := contract.DeleteState(closeInfo); err != nil {
-			brarLog.Errorf("unable to delete channel state: %v",
-				err)
-		}
+	// We'll snapshot the fee the justice transaction is expected to pay,
+	// so that the projected recovery is known right away, before the
+	// justice transaction is crafted.
+	ret.expectedJusticeFee = b.estimateJusticeFee(ret)
 
-		// Finally, we send the retribution information into the
-		// breachArbiter event loop to deal swift justice.
-		select {
-		case b.breachedContracts <- retInfo:
-		case <-b.quit:
-		}
+	brarLog.Infof("Expecting justice tx for ChannelPoint(%v) to pay %v "+
+		"in fees, recovering %v", chanPoint, ret.expectedJusticeFee,
+		ret.projectedRecovery())
 
-	case <-b.quit:
-		return
-	}
+	return ret
 }
 
-// breachedOutput contains all the information needed to sweep a breached
-// output. A breached output is an output that we are now entitled to due to a
-// revoked commitment transaction being broadcast.
-type breachedOutput struct {
-	amt      btcutil.Amount
-	outpoint wire.OutPoint
+// estimateJusticeFee returns the fee a justice transaction sweeping the outputs
+// of the given retribution is currently expected to pay, ahead of any fee
+// bumps.
+func (b *breachArbiter) estimateJusticeFee(
+	ret *retributionInfo) btcutil.Amount {
 
-	signDescriptor lnwallet.SignDescriptor
-	witnessType    lnwallet.WitnessType
-	witnessFunc    lnwallet.WitnessGenerator
+	inputs := []*breachedOutput{ret.selfOutput, ret.revokedOutput}
+	inputs = append(inputs, ret.htlcOutputs...)
 
-	twoStageClaim bool
+	return b.justiceFee(inputs, 0)
 }
 
-// retributionInfo encapsulates all the data needed to sweep all the contested
-// funds within a channel whose contract has been breached by the prior
-// counterparty. This struct is used to create the justice transaction which
-// spends all outputs of the commitment transaction into an output controlled
-// by the wallet.
-type retributionInfo struct {
-	commitHash chainhash.Hash
-	chanPoint  wire.OutPoint
+// reconcileSettledBalance cross-checks the balances reported by a breached
+// channel's state snapshot against the values of our output and the revoked
+// output of the breach transaction, returning the settled balance to persist.
+// Should our settled balance differ from the value of our output by more than
+// the configured SnapshotBalanceTolerance, the value of the output is returned
+// in its stead.
+func (b *breachArbiter) reconcileSettledBalance(chanPoint *wire.OutPoint,
+	capacity, settledBalance, selfAmt,
+	revokedAmt btcutil.Amount) btcutil.Amount {
+
+	if selfAmt+revokedAmt > capacity {
+		brarLog.Warnf("Breached outputs of ChannelPoint(%v) worth %v "+
+			"exceed its capacity of %v", chanPoint,
+			selfAmt+revokedAmt, capacity)
+	}
 
-	// Fields copied from channel snapshot when a breach is detected. This
-	// is necessary for deterministically constructing the channel close
-	// summary in the event that the breach arbiter crashes before closing
-	// the channel.
-	remoteIdentity btcec.PublicKey
-	capacity       btcutil.Amount
-	settledBalance btcutil.Amount
+	diff := settledBalance - selfAmt
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff <= b.cfg.SnapshotBalanceTolerance {
+		return settledBalance
+	}
 
-	selfOutput *breachedOutput
+	brarLog.Warnf("Settled balance %v of ChannelPoint(%v) doesn't "+
+		"reconcile with our breached output worth %v, recording the "+
+		"latter", settledBalance, chanPoint, selfAmt)
 
-	revokedOutput *breachedOutput
+	return selfAmt
+}
 
-	htlcOutputs []*breachedOutput
+// BreachEventNotifier is notified by the breach arbiter of the notable events
+// in the lifecycle of each retribution. Each method is invoked within its own
+// goroutine, and any panic it raises is recovered, such that an implementation
+// may neither block nor crash the breach arbiter.
+type BreachEventNotifier interface {
+	// ChannelResolved is invoked once a channel has been marked as fully
+	// closed, either after justice has been served or after a unilateral
+	// close by the remote party has been resolved. The amount passed is
+	// the total value swept back into the wallet, and the height is that
+	// of the block in which the sweeping transaction confirmed, or zero
+	// if unknown. If justice was served, the breakdown of the funds it
+	// swept is passed, or nil if it wasn't recorded.
+	ChannelResolved(chanPoint wire.OutPoint, recovered btcutil.Amount,
+		confHeight uint32, breakdown *JusticeBreakdown)
+
+	// UnsweepableBreach is invoked if a breach is detected, but the
+	// signing material required to sweep the breached outputs is
+	// unavailable, e.g. after restoring from a backup. The funds in such
+	// channels require manual action by the operator.
+	UnsweepableBreach(breach *UnsweepableBreach)
+
+	// UneconomicBreach is invoked if, once a breach has confirmed, its
+	// outputs remain worth less than the fee required to sweep them
+	// until the breaching party may claim them. Such a breach is
+	// acknowledged, the channel closed and the peer blacklisted, but no
+	// justice transaction is broadcast.
+	UneconomicBreach(breach *UneconomicBreach)
+
+	// SkippedHTLC is invoked for each HTLC output left out of a justice
+	// transaction, as its witness couldn't be generated. The remaining
+	// outputs are still swept, while the skipped output requires manual
+	// action by the operator.
+	SkippedHTLC(skipped *SkippedHTLCOutput)
+
+	// ManualIntervention is invoked once a retribution's justice
+	// transaction has failed to confirm within JusticeConfTimeout.
+	ManualIntervention(diag *RetributionDiagnostic)
+
+	// ApprovalRequired is invoked once a retribution's justice
+	// transaction awaits the operator's approval.
+	ApprovalRequired(diag *RetributionDiagnostic)
+
+	// WalletLocked is invoked once a retribution is blocked as the wallet
+	// is locked. The wallet must be unlocked before the breach window
+	// closes.
+	WalletLocked(diag *RetributionDiagnostic)
+}
 
-	doneChan chan struct{}
+// BackupPruner manages the lifecycle of the static channel backups held by a
+// backup subsystem.
+type BackupPruner interface {
+	// PruneChannelBackup discards the static backup of the given channel,
+	// which has been fully resolved on-chain.
+	PruneChannelBackup(chanPoint wire.OutPoint) error
 }
 
-// createJusticeTx creates a transaction which exacts "justice" by sweeping ALL
-// the funds within the channel which we are now entitled to due to a breach of
-// the channel's contract by the counterparty. This function returns a *fully*
-// signed transaction with the witness for each input fully in place.
-func (b *breachArbiter) createJusticeTx(
-	r *retributionInfo) (*wire.MsgTx, error) {
+// ConsolidationManager tracks the wallet outputs created by the breach
+// arbiter's sweeps, such that they may be consolidated at a later time.
+type ConsolidationManager interface {
+	// ReportSweepOutput reports an output of a confirmed sweep
+	// transaction, along with its value. As several channels may share a
+	// single sweep transaction, an output may be reported more than once.
+	ReportSweepOutput(op wire.OutPoint, amt btcutil.Amount) error
+}
 
-	// First, we obtain a new public key script from the wallet which we'll
-	// sweep the funds to.
-	// TODO(roasbeef): possibly create many outputs to minimize change in
-	// the future?
-	pkScriptOfJustice, err := newSweepPkScript(b.wallet)
-	if err != nil {
-		return nil, err
-	}
+// DeferredOutput is an output of ours within the remote party's commitment
+// transaction, along with everything required to sweep it, which the breach
+// arbiter has left to an ExternalSweeper.
+type DeferredOutput struct {
+	// ChanPoint is the channel point of the closed channel.
+	ChanPoint wire.OutPoint
 
-	r.selfOutput.witnessFunc = r.selfOutput.witnessType.GenWitnessFunc(
-		&b.wallet.Cfg.Signer, &r.selfOutput.signDescriptor)
+	// OutPoint is the outpoint of the output to be swept.
+	OutPoint wire.OutPoint
 
-	r.revokedOutput.witnessFunc = r.revokedOutput.witnessType.GenWitnessFunc(
-		&b.wallet.Cfg.Signer, &r.revokedOutput.signDescriptor)
+	// Amount is the value of the output.
+	Amount btcutil.Amount
 
-	for i := range r.htlcOutputs {
-		r.htlcOutputs[i].witnessFunc = r.htlcOutputs[i].witnessType.GenWitnessFunc(
-			&b.wallet.Cfg.Signer, &r.htlcOutputs[i].signDescriptor)
-	}
+	// SignDesc is the descriptor required to sign for the output.
+	SignDesc lnwallet.SignDescriptor
 
-	// Before creating the actual TxOut, we'll need to calculate the proper
-	// fee to attach to the transaction to ensure a timely confirmation.
-	// TODO(roasbeef): remove hard-coded fee
-	totalAmt := r.selfOutput.amt + r.revokedOutput.amt
-	sweepedAmt := int64(totalAmt - 5000)
+	// WitnessType determines the witness spending the output.
+	WitnessType lnwallet.WitnessType
+}
 
-	// With the fee calculated, we can now create the justice transaction
-	// using the information gathered above.
-	justiceTx := wire.NewMsgTx(2)
-	justiceTx.AddTxOut(&wire.TxOut{
-		PkScript: pkScriptOfJustice,
-		Value:    sweepedAmt,
-	})
-	justiceTx.AddTxIn(&wire.TxIn{
-		PreviousOutPoint: r.selfOutput.outpoint,
-	})
-	justiceTx.AddTxIn(&wire.TxIn{
-		PreviousOutPoint: r.revokedOutput.outpoint,
-	})
+// ExternalSweeper sweeps the outputs the breach arbiter defers to it, rather
+// than sweeping them itself.
+type ExternalSweeper interface {
+	// DeferOutput hands the given output off to the sweeper, which is
+	// thereafter responsible for sweeping it. Once nil is returned, the
+	// breach arbiter forgets the output, so it must have been durably
+	// recorded by then. The same output may be handed off more than once,
+	// e.g. across a restart.
+	DeferOutput(output *DeferredOutput) error
+}
 
-	hashCache := txscript.NewTxSigHashes(justiceTx)
+// MempoolSpend describes an unconfirmed transaction within the mempool of the
+// backing node, which spends an outpoint of interest.
+type MempoolSpend struct {
+	// Txid is the txid of the spending transaction.
+	Txid chainhash.Hash
 
-	// Finally, using the witness generation functions attached to the
-	// retribution information, we'll populate the inputs with fully valid
-	// witnesses for both commitment outputs, and all the pending HTLCs at
-	// this state in the channel's history.
-	// TODO(roasbeef): handle the 2-layer HTLCs
-	localWitness, err := r.selfOutput.witnessFunc(justiceTx, hashCache, 0)
-	if err != nil {
-		return nil, err
-	}
-	justiceTx.TxIn[0].Witness = localWitness
+	// Fee is the absolute fee paid by the spending transaction.
+	Fee btcutil.Amount
 
-	remoteWitness, err := r.revokedOutput.witnessFunc(justiceTx, hashCache, 1)
-	if err != nil {
-		return nil, err
-	}
-	justiceTx.TxIn[1].Witness = remoteWitness
+	// Weight is the weight of the spending transaction.
+	Weight int64
+}
+
+// UnsweepableBreach describes a breach which was detected, but whose funds
+// can't be swept automatically since the signing material required to do so
+// is unavailable.
+type UnsweepableBreach struct {
+	// ChanPoint is the funding outpoint of the breached channel.
+	ChanPoint wire.OutPoint
 
-	return justiceTx, nil
+	// BreachTxid is the txid of the revoked commitment transaction which
+	// was broadcast by the remote party.
+	BreachTxid chainhash.Hash
+
+	// Reason describes the signing material that is missing.
+	Reason error
 }
 
-// craftCommitmentSweepTx creates a transaction to sweep the non-delayed output
-// within the commitment transaction that pays to us. We must manually sweep
-// this output as it uses a tweaked public key in its pkScript, so the wallet
-// won't immediacy be aware of it.
-//
-// TODO(roasbeef): alternative options
-//  * leave the output in the chain, use as input to future funding tx
-//  * leave output in the chain, extend wallet to add knowledge of how to claim
-func (b *breachArbiter) craftCommitSweepTx(
-	closeInfo *lnwallet.UnilateralCloseSummary) (*wire.MsgTx, error) {
-
-	// First, we'll fetch a fresh script that we can use to sweep the funds
-	// under the control of the wallet.
-	sweepPkScript, err := newSweepPkScript(b.wallet)
-	if err != nil {
-		return nil, err
-	}
+// UneconomicBreach describes a breach which was acknowledged, but not punished,
+// as the fee required to sweep its outputs exceeds their value.
+type UneconomicBreach struct {
+	// ChanPoint is the funding outpoint of the breached channel.
+	ChanPoint wire.OutPoint
 
-	// TODO(roasbeef): use proper fees
-	outputAmt := closeInfo.SelfOutputSignDesc.Output.Value
-	sweepAmt := int64(outputAmt - 5000)
+	// BreachTxid is the txid of the revoked commitment transaction which
+	// was broadcast by the remote party.
+	BreachTxid chainhash.Hash
 
-	if sweepAmt <= 0 {
-		// TODO(roasbeef): add output to special pool, can be swept
-		// when: funding a channel, sweeping time locked outputs, or
-		// delivering
-		// justice after a channel breach
-		return nil, fmt.Errorf("output to small to sweep in isolation")
-	}
+	// RemoteIdentity is the identity public key of the breaching party.
+	RemoteIdentity [33]byte
 
-	// With the amount we're sweeping computed, we can now creating the
-	// sweep transaction itself.
-	sweepTx := wire.NewMsgTx(1)
-	sweepTx.AddTxIn(&wire.TxIn{
-		PreviousOutPoint: *closeInfo.SelfOutPoint,
-	})
-	sweepTx.AddTxOut(&wire.TxOut{
-		PkScript: sweepPkScript,
-		Value:    int64(sweepAmt),
-	})
+	// ValueAtRisk is the total value of the breached outputs.
+	ValueAtRisk btcutil.Amount
 
-	// Next, we'll generate the signature required to satisfy the p2wkh
-	// witness program.
-	signDesc := closeInfo.SelfOutputSignDesc
-	signDesc.SigHashes = txscript.NewTxSigHashes(sweepTx)
-	signDesc.InputIndex = 0
-	sweepSig, err := b.wallet.Cfg.Signer.SignOutputRaw(sweepTx, signDesc)
-	if err != nil {
-		return nil, err
-	}
+	// ExpectedFee is the fee a justice transaction sweeping the breached
+	// outputs was expected to pay.
+	ExpectedFee btcutil.Amount
+}
 
-	// Finally, we'll manually craft the witness. The witness here is the
-	// exact same as a regular p2wkh witness, but we'll need to ensure that
-	// we use the tweaked public key as the last item in the witness stack
-	// which was originally used to created the pkScript we're spending.
-	witness := make([][]byte, 2)
-	witness[0] = append(sweepSig, byte(txscript.SigHashAll))
-	witness[1] = lnwallet.TweakPubKeyWithTweak(
-		signDesc.PubKey, signDesc.SingleTweak,
-	).SerializeCompressed()
+// SkippedHTLCOutput describes an HTLC output of a breach which was left out of
+// the justice transaction, as its witness couldn't be generated.
+type SkippedHTLCOutput struct {
+	// ChanPoint is the funding outpoint of the breached channel.
+	ChanPoint wire.OutPoint
 
-	sweepTx.TxIn[0].Witness = witness
+	// OutPoint is the outpoint of the skipped HTLC output.
+	OutPoint wire.OutPoint
 
-	brarLog.Infof("Sweeping commitment output with: %v", spew.Sdump(sweepTx))
+	// Amount is the value of the skipped HTLC output.
+	Amount btcutil.Amount
 
-	return sweepTx, nil
+	// Reason describes why the witness couldn't be generated.
+	Reason error
 }
 
-// RetributionStore provides an interface for managing a persistent map from
-// wire.OutPoint -> retributionInfo. Upon learning of a breach, a BreachArbiter
-// should record the retributionInfo for the breached channel, which serves a
-// checkpoint in the event that retribution needs to be resumed after failure.
-// A RetributionStore provides an interface for managing the persisted set, as
-// well as mapping user defined functions over the entire on-disk contents.
-//
-// Calls to RetributionStore may occur concurrently. A concrete instance of
-// RetributionStore should use appropriate synchronization primitives, or
-// be otherwise safe for concurrent access.
-type RetributionStore interface {
-	// Add persists the retributionInfo to disk, using the information's
-	// chanPoint as the key. This method should overwrite any existing
-	// entires found under the same key, and an error should be raised if
-	// the addition fails.
-	Add(retInfo *retributionInfo) error
-
-	// Remove deletes the retributionInfo from disk, if any exists, under
-	// the given key. An error should be re raised if the removal fails.
-	Remove(key *wire.OutPoint) error
-
-	// ForAll iterates over the existing on-disk contents and applies a
-	// chosen, read-only callback to each. This method should ensure that it
-	// immediately propagate any errors generated by the callback.
-	ForAll(cb func(*retributionInfo) error) error
-}
-
-// retributionStore handles persistence of retribution states to disk and is
-// backed by a boltdb bucket. The primary responsibility of the retribution
-// store is to ensure that we can recover from a restart in the middle of a
-// breached contract retribution.
-type retributionStore struct {
-	db *channeldb.DB
-}
-
-// newRetributionStore creates a new instance of a retributionStore.
-func newRetributionStore(db *channeldb.DB) *retributionStore {
-	return &retributionStore{
-		db: db,
-	}
-}
-
-// Add adds a retribution state to the retributionStore, which is then persisted
-// to disk.
-func (rs *retributionStore) Add(ret *retributionInfo) error {
-	return rs.db.Update(func(tx *bolt.Tx) error {
-		// If this is our first contract breach, the retributionBucket
-		// won't exist, in which case, we just create a new bucket.
-		retBucket, err := tx.CreateBucketIfNotExists(retributionBucket)
-		if err != nil {
-			return err
-		}
+// abandonUnsweepableBreach handles a breach for which the justice transaction
+// can't be signed. The channel is closed as breached and no longer watched,
+// but no retribution is persisted or attempted. Instead, the operator is
+// alerted so that the funds may be recovered manually.
+func (b *breachArbiter) abandonUnsweepableBreach(
+	contract *lnwallet.LightningChannel, retInfo *retributionInfo,
+	reason error) {
 
-		var outBuf bytes.Buffer
-		if err := writeOutpoint(&outBuf, &ret.chanPoint); err != nil {
-			return err
-		}
+	chanPoint := contract.ChannelPoint()
 
-		var retBuf bytes.Buffer
-		if err := ret.Encode(&retBuf); err != nil {
-			return err
+	brarLog.Criticalf("Breach of ChannelPoint(%v) by txid %v can't be "+
+		"swept automatically, manual action is required: %v",
+		chanPoint, retInfo.commitHash, reason)
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		select {
+		case b.settledContracts <- chanPoint:
+		case <-b.quit:
 		}
+	}()
+
+	closeInfo := &channeldb.ChannelCloseSummary{
+		ChanPoint:      *chanPoint,
+		ClosingTXID:    retInfo.commitHash,
+		RemotePub:      &retInfo.remoteIdentity,
+		Capacity:       retInfo.capacity,
+		SettledBalance: retInfo.settledBalance,
+		CloseType:      channeldb.BreachClose,
+		IsPending:      true,
+	}
+	if err := contract.DeleteState(closeInfo); err != nil {
+		brarLog.Errorf("unable to delete channel state: %v", err)
+	}
 
-		return retBucket.Put(outBuf.Bytes(), retBuf.Bytes())
+	b.notifyUnsweepableBreach(&UnsweepableBreach{
+		ChanPoint:  *chanPoint,
+		BreachTxid: retInfo.commitHash,
+		Reason:     reason,
 	})
 }
 
-// Remove removes a retribution state from the retributionStore database.
-func (rs *retributionStore) Remove(key *wire.OutPoint) error {
-	return rs.db.Update(func(tx *bolt.Tx) error {
-		retBucket := tx.Bucket(retributionBucket)
+// uneconomicBreach returns true if the value projected to be recovered by the
+// justice transaction of the given retribution, net of its fee, falls below the
+// SweepDustThreshold, such that no output worth relaying remains.
+func (b *breachArbiter) uneconomicBreach(ret *retributionInfo) bool {
+	return ret.projectedRecovery() < b.cfg.SweepDustThreshold
+}
 
-		// We return an error if the bucket is not already created,
-		// since normal operation of the breach arbiter should never try
-		// to remove a finalized retribution state that is not already
-		// stored in the db.
-		if retBucket == nil {
-			return errors.New("unable to remove retribution " +
-				"because the db bucket doesn't exist.")
+// breachContested returns true once the breaching party may claim every
+// breached output of the given retribution that they're able to contest, or
+// if there are none, such that waiting for fees to fall can no longer improve
+// the retribution.
+func (b *breachArbiter) breachContested(ret *retributionInfo) bool {
+	inputs := []*breachedOutput{ret.revokedOutput}
+	inputs = append(inputs, ret.htlcOutputs...)
+	for _, input := range inputs {
+		if input == nil {
+			continue
 		}
-
-		var outBuf bytes.Buffer
-		if err := writeOutpoint(&outBuf, key); err != nil {
-			return err
+		blocks, ok := b.blocksUntilContested(input)
+		if ok && blocks > 0 {
+			return false
 		}
+	}
 
-		return retBucket.Delete(outBuf.Bytes())
-	})
+	return true
 }
 
-// ForAll iterates through all stored retributions and executes the passed
-// callback function on each retribution.
-func (rs *retributionStore) ForAll(cb func(*retributionInfo) error) error {
-	return rs.db.View(func(tx *bolt.Tx) error {
-		// If the bucket does not exist, then there are no pending
-		// retributions.
-		retBucket := tx.Bucket(retributionBucket)
-		if retBucket == nil {
-			return nil
+// acknowledgeUneconomicBreach handles a confirmed breach whose outputs remain
+// worth less than the fee required to sweep them. The breach is archived as
+// uneconomic, which blacklists the breaching party, and the channel is marked
+// fully closed, without a justice transaction ever being broadcast.
+func (b *breachArbiter) acknowledgeUneconomicBreach(retInfo *retributionInfo) {
+	chanPoint := &retInfo.chanPoint
+
+	brarLog.Warnf("Breach of ChannelPoint(%v) by txid %v is uneconomic to "+
+		"punish, %v at risk against an expected fee of %v, skipping "+
+		"justice", chanPoint, retInfo.commitHash,
+		retInfo.valueAtRisk(), retInfo.expectedJusticeFee)
+
+	// Only once the channel has been marked fully closed can we archive
+	// the retribution, as otherwise it's retained so that it's re-checked
+	// on a subsequent startup.
+	b.reconcileBreachClose(chanPoint)
+	if err := b.markChanFullyClosed(chanPoint); err != nil {
+		brarLog.Errorf("unable to mark chan as closed, retaining "+
+			"retribution: %v", err)
+	} else {
+		retInfo.uneconomic = true
+		retInfo.completed = true
+		err := b.cfg.Store.Archive(&ArchivedBreach{
+			ArchivedAt:  time.Now(),
+			retribution: retInfo,
+		})
+		if err != nil {
+			brarLog.Errorf("Unable to archive uneconomic breach "+
+				"of ChannelPoint(%v): %v", chanPoint, err)
 		}
 
-		// Otherwise, we fetch each serialized retribution info,
-		// deserialize it, and execute the passed in callback function
-		// on it.
-		return retBucket.ForEach(func(outBytes, retBytes []byte) error {
-			ret := &retributionInfo{}
-			if err := ret.Decode(
-				bytes.NewBuffer(retBytes),
-			); err != nil {
-				return err
-			}
+		b.notifyChannelResolved(*chanPoint, 0, 0, nil)
+		b.pruneChannelBackup(*chanPoint)
+	}
+	b.clearRetributionPhase(chanPoint)
 
-			return cb(ret)
-		})
-	})
+	breach := &UneconomicBreach{
+		ChanPoint:   *chanPoint,
+		BreachTxid:  retInfo.commitHash,
+		ValueAtRisk: retInfo.valueAtRisk(),
+		ExpectedFee: retInfo.expectedJusticeFee,
+	}
+	if retInfo.hasRemoteIdentity() {
+		copy(
+			breach.RemoteIdentity[:],
+			retInfo.remoteIdentity.SerializeCompressed(),
+		)
+	}
+	b.notifyUneconomicBreach(breach)
+
+	// Retributions restored from disk have no one waiting on them.
+	if retInfo.doneChan != nil {
+		close(retInfo.doneChan)
+	}
 }
 
-// Encode serializes the retribution into the passed byte stream.
-func (ret *retributionInfo) Encode(w io.Writer) error {
-	var scratch [8]byte
+// notifyEvent invokes the given method of the configured BreachEventNotifier,
+// if any, within its own goroutine, recovering from any panic it raises.
+func (b *breachArbiter) notifyEvent(event string, chanPoint wire.OutPoint,
+	notify func(BreachEventNotifier)) {
 
-	if _, err := w.Write(ret.commitHash[:]); err != nil {
-		return err
+	if b.cfg.Events == nil {
+		return
 	}
 
-	if err := writeOutpoint(w, &ret.chanPoint); err != nil {
-		return err
-	}
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				brarLog.Errorf("%v notification for "+
+					"ChannelPoint(%v) panicked: %v",
+					event, chanPoint, r)
+			}
+		}()
 
-	if _, err := w.Write(
-		ret.remoteIdentity.SerializeCompressed()); err != nil {
-		return err
-	}
+		notify(b.cfg.Events)
+	}()
+}
 
-	binary.BigEndian.PutUint64(scratch[:8], uint64(ret.capacity))
-	if _, err := w.Write(scratch[:8]); err != nil {
-		return err
-	}
+// notifyUneconomicBreach notifies the BreachEventNotifier, if any, of the given
+// uneconomic breach.
+func (b *breachArbiter) notifyUneconomicBreach(breach *UneconomicBreach) {
+	b.notifyEvent("UneconomicBreach", breach.ChanPoint,
+		func(n BreachEventNotifier) {
+			n.UneconomicBreach(breach)
+		},
+	)
+}
 
-	binary.BigEndian.PutUint64(scratch[:8], uint64(ret.settledBalance))
-	if _, err := w.Write(scratch[:8]); err != nil {
-		return err
-	}
+// notifyUnsweepableBreach notifies the BreachEventNotifier, if any, of the
+// given unsweepable breach.
+func (b *breachArbiter) notifyUnsweepableBreach(breach *UnsweepableBreach) {
+	b.notifyEvent("UnsweepableBreach", breach.ChanPoint,
+		func(n BreachEventNotifier) {
+			n.UnsweepableBreach(breach)
+		},
+	)
+}
 
-	if err := ret.selfOutput.Encode(w); err != nil {
-		return err
-	}
+// notifyManualIntervention notifies the BreachEventNotifier, if any, of a
+// retribution requiring manual intervention.
+func (b *breachArbiter) notifyManualIntervention(diag *RetributionDiagnostic) {
+	b.notifyEvent("ManualIntervention", diag.ChanPoint,
+		func(n BreachEventNotifier) {
+			n.ManualIntervention(diag)
+		},
+	)
+}
 
-	if err := ret.revokedOutput.Encode(w); err != nil {
-		return err
-	}
+// notifyApprovalRequired notifies the BreachEventNotifier, if any, of a
+// retribution awaiting approval.
+func (b *breachArbiter) notifyApprovalRequired(diag *RetributionDiagnostic) {
+	b.notifyEvent("ApprovalRequired", diag.ChanPoint,
+		func(n BreachEventNotifier) {
+			n.ApprovalRequired(diag)
+		},
+	)
+}
 
-	numHtlcOutputs := len(ret.htlcOutputs)
-	if err := wire.WriteVarInt(w, 0, uint64(numHtlcOutputs)); err != nil {
-		return err
-	}
+// notifyWalletLocked notifies the BreachEventNotifier, if any, of a retribution
+// blocked by the locked wallet.
+func (b *breachArbiter) notifyWalletLocked(diag *RetributionDiagnostic) {
+	b.notifyEvent("WalletLocked", diag.ChanPoint,
+		func(n BreachEventNotifier) {
+			n.WalletLocked(diag)
+		},
+	)
+}
 
-	for i := 0; i < numHtlcOutputs; i++ {
-		if err := ret.htlcOutputs[i].Encode(w); err != nil {
-			return err
+// notifySkippedHTLC notifies the BreachEventNotifier, if any, of the given
+// skipped HTLC output.
+func (b *breachArbiter) notifySkippedHTLC(skipped *SkippedHTLCOutput) {
+	b.notifyEvent("SkippedHTLC", skipped.ChanPoint,
+		func(n BreachEventNotifier) {
+			n.SkippedHTLC(skipped)
+		},
+	)
+}
+
+// skipHTLCOutput removes the given HTLC output from the retribution, so that
+// the justice transaction sweeps its remaining outputs, and notifies the
+// operator that the output requires manual action.
+func (b *breachArbiter) skipHTLCOutput(r *retributionInfo,
+	htlc *breachedOutput, reason error) {
+
+	brarLog.Errorf("Skipping HTLC output %v of ChannelPoint(%v) worth %v "+
+		"within justice tx, its witness can't be generated: %v",
+		htlc.outpoint, r.chanPoint, htlc.amt, reason)
+
+	for i, htlcOutput := range r.htlcOutputs {
+		if htlcOutput == htlc {
+			r.htlcOutputs = append(
+				r.htlcOutputs[:i], r.htlcOutputs[i+1:]...,
+			)
+			break
 		}
 	}
 
-	return nil
+	b.notifySkippedHTLC(&SkippedHTLCOutput{
+		ChanPoint: r.chanPoint,
+		OutPoint:  htlc.outpoint,
+		Amount:    htlc.amt,
+		Reason:    reason,
+	})
 }
 
-// Dencode deserializes a retribution from the passed byte stream.
-func (ret *retributionInfo) Decode(r io.Reader) error {
-	var scratch [33]byte
-
-	if _, err := io.ReadFull(r, scratch[:32]); err != nil {
-		return err
-	}
-	hash, err := chainhash.NewHash(scratch[:32])
+// dataLossSuspected returns true if our state for the given channel may be
+// stale, as it's marked as restored from a backup, or DataLossSuspected
+// reports it as such.
+func (b *breachArbiter) dataLossSuspected(chanPoint *wire.OutPoint) bool {
+	restored, err := b.restoredChans.Has(chanPoint)
 	if err != nil {
-		return err
+		brarLog.Errorf("Unable to query whether ChannelPoint(%v) was "+
+			"restored from a backup: %v", chanPoint, err)
 	}
-	ret.commitHash = *hash
-
-	if err := readOutpoint(r, &ret.chanPoint); err != nil {
-		return err
+	if restored {
+		return true
 	}
 
-	if _, err = io.ReadFull(r, scratch[:33]); err != nil {
-		return err
-	}
-	remoteIdentity, err := btcec.ParsePubKey(scratch[:33], btcec.S256())
-	if err != nil {
-		return err
-	}
-	ret.remoteIdentity = *remoteIdentity
+	return b.cfg.DataLossSuspected != nil &&
+		b.cfg.DataLossSuspected(chanPoint)
+}
 
-	if _, err := io.ReadFull(r, scratch[:8]); err != nil {
-		return err
-	}
-	ret.capacity = btcutil.Amount(binary.BigEndian.Uint64(scratch[:8]))
+// MarkRestoredFromBackup marks the given channel as restored from a backup,
+// such that our state for it may be stale. Until the mark is cleared, a spend
+// of the channel by a commitment we believe to be revoked is deferred to
+// recovery rather than punished, see deferBreachToRecovery.
+func (b *breachArbiter) MarkRestoredFromBackup(
+	chanPoint *wire.OutPoint) error {
 
-	if _, err := io.ReadFull(r, scratch[:8]); err != nil {
-		return err
-	}
-	ret.settledBalance = btcutil.Amount(
-		binary.BigEndian.Uint64(scratch[:8]))
+	return b.restoredChans.Add(chanPoint)
+}
 
-	ret.selfOutput = &breachedOutput{}
-	if err := ret.selfOutput.Decode(r); err != nil {
-		return err
-	}
+// ClearRestoredFromBackup clears the mark of the given channel as restored
+// from a backup, e.g. once our state is known to be in sync with the remote
+// party's. The mark is also cleared once the channel is fully closed.
+func (b *breachArbiter) ClearRestoredFromBackup(
+	chanPoint *wire.OutPoint) error {
 
-	ret.revokedOutput = &breachedOutput{}
-	if err := ret.revokedOutput.Decode(r); err != nil {
-		return err
+	return b.restoredChans.Remove(chanPoint)
+}
+
+// deferBreachToRecovery handles the spend of a channel by a commitment that
+// appears revoked, but which may be the remote party's latest state since our
+// own state is possibly stale. The spend is treated as a unilateral close by
+// the remote party: the channel is closed as a force close, and only our
+// non-delayed output, which is ours regardless of which state was broadcast,
+// is swept. No justice transaction is broadcast.
+func (b *breachArbiter) deferBreachToRecovery(
+	contract *lnwallet.LightningChannel,
+	breachInfo *lnwallet.BreachRetribution) {
+
+	chanPoint := contract.ChannelPoint()
+	commitHash := breachInfo.BreachTransaction.TxHash()
+
+	brarLog.Warnf("Possibly revoked state #%v for ChannelPoint(%v) "+
+		"broadcast, but data loss is suspected: deferring to "+
+		"recovery rather than exacting retribution",
+		breachInfo.RevokedStateNum, chanPoint)
+
+	b.cfg.CloseLink(chanPoint, htlcswitch.CloseBreach)
+	chanInfo := contract.StateSnapshot()
+
+	// Cancel out this contract within the breachArbiter's main goroutine,
+	// as its duties are now limited to recovering our own output.
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		select {
+		case b.settledContracts <- chanPoint:
+		case <-b.quit:
+		}
+	}()
+
+	closeSummary := &channeldb.ChannelCloseSummary{
+		ChanPoint:      *chanPoint,
+		ClosingTXID:    commitHash,
+		RemotePub:      &chanInfo.RemoteIdentity,
+		Capacity:       chanInfo.Capacity,
+		SettledBalance: chanInfo.LocalBalance.ToSatoshis(),
+		CloseType:      channeldb.ForceClose,
+		IsPending:      true,
+	}
+	if err := contract.DeleteState(closeSummary); err != nil {
+		brarLog.Errorf("unable to delete channel state: %v", err)
 	}
 
-	numHtlcOutputsU64, err := wire.ReadVarInt(r, 0)
+	_, currentHeight, err := b.cfg.ChainIO.GetBestBlock()
 	if err != nil {
-		return err
+		brarLog.Errorf("unable to get current height: %v", err)
+		return
 	}
-	numHtlcOutputs := int(numHtlcOutputsU64)
 
-	ret.htlcOutputs = make([]*breachedOutput, numHtlcOutputs)
-	for i := 0; i < numHtlcOutputs; i++ {
-		ret.htlcOutputs[i] = &breachedOutput{}
-		if err := ret.htlcOutputs[i].Decode(r); err != nil {
-			return err
-		}
+	localSignDesc := breachInfo.LocalOutputSignDesc
+	closeInfo := &lnwallet.UnilateralCloseSummary{
+		SelfOutPoint:       &breachInfo.LocalOutpoint,
+		SelfOutputSignDesc: &localSignDesc,
 	}
 
-	return nil
+	b.beginCommitSweep(&commitSweepInfo{
+		chanPoint:   *chanPoint,
+		closeTxid:   commitHash,
+		closeHeight: uint32(currentHeight),
+		inputs:      commitSweepInputs(closeInfo),
+	})
 }
 
-// Encode serializes a breachedOutput into the passed byte stream.
-func (bo *breachedOutput) Encode(w io.Writer) error {
-	var scratch [8]byte
-
-	binary.BigEndian.PutUint64(scratch[:8], uint64(bo.amt))
-	if _, err := w.Write(scratch[:8]); err != nil {
-		return err
+// resolveHTLCs informs the htlc switch of the outcome of each HTLC swept by
+// the retribution's justice transaction. HTLCs swept with their preimage are
+// settled, while all others are failed, as the funds have been returned to us.
+func (b *breachArbiter) resolveHTLCs(breachInfo *retributionInfo) {
+	if b.cfg.ResolveHTLC == nil {
+		return
 	}
 
-	if err := writeOutpoint(w, &bo.outpoint); err != nil {
-		return err
-	}
+	for _, htlc := range breachInfo.htlcOutputs {
+		// HTLCs offered to us by the breaching party were never
+		// forwarded over this channel, so have no circuit awaiting
+		// their outcome here.
+		if htlc.witnessType == lnwallet.HtlcOfferedRevoke {
+			continue
+		}
 
-	if err := lnwallet.WriteSignDescriptor(
-		w, &bo.signDescriptor); err != nil {
-		return err
-	}
+		payHash, preimage, ok := htlc.htlcResolution()
+		if !ok {
+			brarLog.Warnf("Unable to resolve HTLC %v swept from "+
+				"ChannelPoint(%v): unknown payment hash",
+				htlc.outpoint, breachInfo.chanPoint)
+			continue
+		}
 
-	binary.BigEndian.PutUint16(scratch[:2], uint16(bo.witnessType))
-	if _, err := w.Write(scratch[:2]); err != nil {
-		return err
+		amt := lnwire.NewMSatFromSatoshis(htlc.amt)
+		if err := b.cfg.ResolveHTLC(payHash, amt, preimage); err != nil {
+			brarLog.Errorf("Unable to resolve HTLC %x swept from "+
+				"ChannelPoint(%v): %v", payHash[:],
+				breachInfo.chanPoint, err)
+		}
 	}
+}
 
-	if bo.twoStageClaim {
-		scratch[0] = 1
-	} else {
-		scratch[0] = 0
-	}
-	if _, err := w.Write(scratch[:1]); err != nil {
-		return err
-	}
+// notifyChannelResolved notifies the BreachEventNotifier, if any, via its
+// ChannelResolved method.
+func (b *breachArbiter) notifyChannelResolved(chanPoint wire.OutPoint,
+	recovered btcutil.Amount, confHeight uint32,
+	breakdown *JusticeBreakdown) {
 
-	return nil
+	b.notifyEvent("ChannelResolved", chanPoint, func(n BreachEventNotifier) {
+		n.ChannelResolved(chanPoint, recovered, confHeight, breakdown)
+	})
 }
 
-// Decode deserializes a breachedOutput from the passed byte stream.
-func (bo *breachedOutput) Decode(r io.Reader) error {
-	var scratch [8]byte
-
-	if _, err := io.ReadFull(r, scratch[:8]); err != nil {
-		return err
+// pruneChannelBackup notifies the BackupPruner, if one is configured, that the
+// given channel has been fully resolved. As with the BreachEventNotifier, the
+// pruner is invoked within its own goroutine, and any panic it raises is
+// recovered.
+func (b *breachArbiter) pruneChannelBackup(chanPoint wire.OutPoint) {
+	if b.cfg.BackupPruner == nil {
+		return
 	}
-	bo.amt = btcutil.Amount(binary.BigEndian.Uint64(scratch[:8]))
 
-	if err := readOutpoint(r, &bo.outpoint); err != nil {
-		return err
-	}
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				brarLog.Errorf("BackupPruner for "+
+					"ChannelPoint(%v) panicked: %v",
+					chanPoint, r)
+			}
+		}()
 
-	if err := lnwallet.ReadSignDescriptor(
-		r, &bo.signDescriptor); err != nil {
-		return err
-	}
+		err := b.cfg.BackupPruner.PruneChannelBackup(chanPoint)
+		if err != nil {
+			brarLog.Errorf("Unable to prune backup of "+
+				"ChannelPoint(%v): %v", chanPoint, err)
+		}
+	}()
+}
 
-	if _, err := io.ReadFull(r, scratch[:2]); err != nil {
-		return err
+// reportSweepOutputs reports each output of the given confirmed sweep
+// transaction to the ConsolidationManager, if one is configured.
+func (b *breachArbiter) reportSweepOutputs(tx *wire.MsgTx) {
+	if b.cfg.ConsolidationManager == nil || tx == nil {
+		return
 	}
-	bo.witnessType = lnwallet.WitnessType(
-		binary.BigEndian.Uint16(scratch[:2]))
 
-	if _, err := io.ReadFull(r, scratch[:1]); err != nil {
-		return err
-	}
-	if scratch[0] == 1 {
-		bo.twoStageClaim = true
-	} else {
-		bo.twoStageClaim = false
-	}
+	txid := tx.TxHash()
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				brarLog.Errorf("ConsolidationManager for sweep "+
+					"tx %v panicked: %v", txid, r)
+			}
+		}()
 
-	return nil
+		for i, txOut := range tx.TxOut {
+			op := wire.OutPoint{Hash: txid, Index: uint32(i)}
+			amt := btcutil.Amount(txOut.Value)
+
+			err := b.cfg.ConsolidationManager.ReportSweepOutput(
+				op, amt,
+			)
+			if err != nil {
+				brarLog.Errorf("Unable to report sweep "+
+					"output %v: %v", op, err)
+			}
+		}
+	}()
 }
@@ -4,16 +4,32 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/boltdb/bolt"
 	"github.com/btcsuite/btclog"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/lightningnetwork/lnd/chainntnfs"
 	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/htlcswitch"
 	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/shachain"
+	"github.com/roasbeef/btcd/blockchain"
 	"github.com/roasbeef/btcd/btcec"
 	"github.com/roasbeef/btcd/chaincfg/chainhash"
 	"github.com/roasbeef/btcd/txscript"
@@ -231,6 +247,13 @@ var (
 )
 
 func init() {
+	// Disable logging to prevent panics bc. of global state. The loggers
+	// are set once, as goroutines of earlier tests may still read them.
+	brarLog = btclog.Disabled
+	channeldb.UseLogger(btclog.Disabled)
+	htlcswitch.UseLogger(btclog.Disabled)
+	lnwallet.UseLogger(btclog.Disabled)
+
 	// Ensure that breached outputs are initialized before starting tests.
 	if err := initBreachedOutputs(); err != nil {
 		panic(err)
@@ -286,11 +309,13 @@ func (frs *failingRetributionStore) Restart() {
 	frs.mu.Unlock()
 }
 
-func (frs *failingRetributionStore) Add(retInfo *retributionInfo) error {
+func (frs *failingRetributionStore) Add(retInfo *retributionInfo,
+	quit <-chan struct{}) error {
+
 	frs.mu.Lock()
 	defer frs.mu.Unlock()
 
-	return frs.rs.Add(retInfo)
+	return frs.rs.Add(retInfo, quit)
 }
 
 func (frs *failingRetributionStore) Remove(key *wire.OutPoint) error {
@@ -307,6 +332,36 @@ func (frs *failingRetributionStore) ForAll(cb func(*retributionInfo) error) erro
 	return frs.rs.ForAll(cb)
 }
 
+func (frs *failingRetributionStore) Recover() (*RetributionRecovery, error) {
+	frs.mu.Lock()
+	defer frs.mu.Unlock()
+
+	return frs.rs.Recover()
+}
+
+func (frs *failingRetributionStore) Verify() (*StoreIntegrityReport, error) {
+	frs.mu.Lock()
+	defer frs.mu.Unlock()
+
+	return frs.rs.Verify()
+}
+
+func (frs *failingRetributionStore) Archive(breach *ArchivedBreach) error {
+	frs.mu.Lock()
+	defer frs.mu.Unlock()
+
+	return frs.rs.Archive(breach)
+}
+
+func (frs *failingRetributionStore) ForAllArchived(
+	cb func(*ArchivedBreach) error) error {
+
+	frs.mu.Lock()
+	defer frs.mu.Unlock()
+
+	return frs.rs.ForAllArchived(cb)
+}
+
 // Parse the pubkeys in the breached outputs.
 func initBreachedOutputs() error {
 	for i := range breachedOutputs {
@@ -391,11 +446,30 @@ func copyRetInfo(retInfo *retributionInfo) *retributionInfo {
 		chanPoint:      retInfo.chanPoint,
 		remoteIdentity: retInfo.remoteIdentity,
 		capacity:       retInfo.capacity,
+
+		rawRemoteIdentity: retInfo.rawRemoteIdentity,
+
 		settledBalance: retInfo.settledBalance,
 		selfOutput:     retInfo.selfOutput,
 		revokedOutput:  retInfo.revokedOutput,
 		htlcOutputs:    make([]*breachedOutput, nHtlcs),
-		doneChan:       retInfo.doneChan,
+
+		breachDetectedAt: retInfo.breachDetectedAt,
+		sweepPkScript:    retInfo.sweepPkScript,
+		justiceTxid:      retInfo.justiceTxid,
+
+		justiceConfHeight:  retInfo.justiceConfHeight,
+		expectedJusticeFee: retInfo.expectedJusticeFee,
+		approval:           retInfo.approval,
+		completed:          retInfo.completed,
+		breachProof:        retInfo.breachProof,
+		deadlineHeight:     retInfo.deadlineHeight,
+		penaltyPkScript:    retInfo.penaltyPkScript,
+		justiceBreakdown:   retInfo.justiceBreakdown,
+		revokedStateNum:    retInfo.revokedStateNum,
+		uneconomic:         retInfo.uneconomic,
+
+		doneChan: retInfo.doneChan,
 	}
 
 	for i, htlco := range retInfo.htlcOutputs {
@@ -405,22 +479,123 @@ func copyRetInfo(retInfo *retributionInfo) *retributionInfo {
 	return ret
 }
 
+// newTestBreachArbiter creates a breach arbiter from the given config for use
+// within tests. Unless set, an in-memory retribution store and a chain backend
+// at fundingBroadcastHeight are used.
+func newTestBreachArbiter(cfg *BreachConfig) *breachArbiter {
+	if cfg.Store == nil {
+		cfg.Store = newMockRetributionStore()
+	}
+	if cfg.ChainIO == nil {
+		cfg.ChainIO = &mockChainIO{}
+	}
+
+	return newBreachArbiter(cfg)
+}
+
+// newTestWallet returns a wallet whose keys are derived from alicePrivKey, and
+// which sends each transaction it publishes on the given channel.
+func newTestWallet(published chan *wire.MsgTx) *lnwallet.LightningWallet {
+	return &lnwallet.LightningWallet{
+		WalletController: &mockWalletController{
+			rootKey:               alicePrivKey,
+			publishedTransactions: published,
+		},
+		Cfg: lnwallet.Config{
+			Signer: &mockSigner{key: alicePrivKey},
+		},
+	}
+}
+
+// breachTestHarness bundles the fixtures shared by the tests of the breach
+// arbiter's handling of a channel with a test peer: the notifier delivering
+// its chain events, the channel database, Alice's side of the channel, and a
+// retribution store.
+type breachTestHarness struct {
+	notifier *txidNotifier
+	db       *channeldb.DB
+	alice    *lnwallet.LightningChannel
+	store    *mockRetributionStore
+	cleanUp  func()
+}
+
+// newBreachTestHarness creates a test peer along with its channel to Alice,
+// whose chain events are delivered by a txidNotifier.
+func newBreachTestHarness(t *testing.T) *breachTestHarness {
+	notifier := &txidNotifier{
+		mockNotifier: mockNotifier{
+			confChannel: make(chan *chainntnfs.TxConfirmation, 1),
+		},
+		txids: make(chan chainhash.Hash, 10),
+	}
+	alicePeer, alice, _, cleanUp, err := createTestPeer(
+		notifier, make(chan *wire.MsgTx, 10),
+	)
+	if err != nil {
+		t.Fatalf("unable to create test channels: %v", err)
+	}
+
+	return &breachTestHarness{
+		notifier: notifier,
+		db:       alicePeer.server.chanDB,
+		alice:    alice,
+		store:    newMockRetributionStore(),
+		cleanUp:  cleanUp,
+	}
+}
+
+// newBreachRetInfo returns a retribution for the breach of Alice's channel,
+// whose outputs are consistent with its commitment hash.
+func (h *breachTestHarness) newBreachRetInfo() *retributionInfo {
+	aliceState := h.alice.StateSnapshot()
+
+	ret := newBreachRetInfo()
+	ret.chanPoint = *aliceState.ChannelPoint
+	ret.remoteIdentity = aliceState.RemoteIdentity
+	ret.capacity = aliceState.Capacity
+
+	return ret
+}
+
+// closeTestChannel marks the given channel as pending close by the transaction
+// with the given txid.
+func closeTestChannel(t *testing.T, channel *lnwallet.LightningChannel,
+	closeTxid chainhash.Hash, closeType channeldb.ClosureType) {
+
+	state := channel.StateSnapshot()
+	err := channel.DeleteState(&channeldb.ChannelCloseSummary{
+		ChanPoint:   *state.ChannelPoint,
+		ClosingTXID: closeTxid,
+		RemotePub:   &state.RemoteIdentity,
+		Capacity:    state.Capacity,
+		CloseType:   closeType,
+		IsPending:   true,
+	})
+	if err != nil {
+		t.Fatalf("unable to close channel: %v", err)
+	}
+}
+
 // mockRetributionStore implements the RetributionStore interface and is backed
 // by an in-memory map. Access to the internal state is provided by a mutex.
 // TODO(cfromknecht) extend to support and test controlled failures.
 type mockRetributionStore struct {
-	mu    sync.Mutex
-	state map[wire.OutPoint]*retributionInfo
+	mu       sync.Mutex
+	state    map[wire.OutPoint]*retributionInfo
+	archived map[wire.OutPoint]*ArchivedBreach
 }
 
 func newMockRetributionStore() *mockRetributionStore {
 	return &mockRetributionStore{
-		mu:    sync.Mutex{},
-		state: make(map[wire.OutPoint]*retributionInfo),
+		mu:       sync.Mutex{},
+		state:    make(map[wire.OutPoint]*retributionInfo),
+		archived: make(map[wire.OutPoint]*ArchivedBreach),
 	}
 }
 
-func (rs *mockRetributionStore) Add(retInfo *retributionInfo) error {
+func (rs *mockRetributionStore) Add(retInfo *retributionInfo,
+	_ <-chan struct{}) error {
+
 	rs.mu.Lock()
 	rs.state[retInfo.chanPoint] = copyRetInfo(retInfo)
 	rs.mu.Unlock()
@@ -449,6 +624,63 @@ func (rs *mockRetributionStore) ForAll(cb func(*retributionInfo) error) error {
 	return nil
 }
 
+func (rs *mockRetributionStore) Recover() (*RetributionRecovery, error) {
+	return &RetributionRecovery{}, nil
+}
+
+func (rs *mockRetributionStore) Verify() (*StoreIntegrityReport, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	report := &StoreIntegrityReport{}
+	for chanPoint, ret := range rs.state {
+		if err := ret.verifyIntegrity(); err != nil {
+			chanPoint := chanPoint
+			report.Inconsistent = append(
+				report.Inconsistent, CorruptRetribution{
+					ChanPoint: &chanPoint,
+					Err:       err,
+				},
+			)
+			continue
+		}
+		report.Healthy++
+	}
+
+	return report, nil
+}
+
+func (rs *mockRetributionStore) Archive(breach *ArchivedBreach) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	archived := *breach
+	archived.retribution = copyRetInfo(breach.retribution)
+	archived.fillFromRetribution()
+
+	chanPoint := archived.retribution.chanPoint
+	rs.archived[chanPoint] = &archived
+	delete(rs.state, chanPoint)
+
+	return nil
+}
+
+func (rs *mockRetributionStore) ForAllArchived(
+	cb func(*ArchivedBreach) error) error {
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	for _, breach := range rs.archived {
+		archived := *breach
+		if err := cb(&archived); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 var retributionStoreTestSuite = []struct {
 	name string
 	test func(FailingRetributionStore, *testing.T)
@@ -473,6 +705,10 @@ var retributionStoreTestSuite = []struct {
 		"RemoveEmpty",
 		testRetributionStoreRemoveEmpty,
 	},
+	{
+		"Archive",
+		testRetributionStoreArchive,
+	},
 }
 
 // TestMockRetributionStore instantiates a mockRetributionStore and tests its
@@ -505,9 +741,6 @@ func TestChannelDBRetributionStore(t *testing.T) {
 	}
 	defer os.RemoveAll(tempDirName)
 
-	// Disable logging to prevent panics bc. of global state
-	channeldb.UseLogger(btclog.Disabled)
-
 	// Next, create channeldb for the first time.
 	db, err := channeldb.Open(tempDirName)
 	if err != nil {
@@ -547,6 +780,95 @@ func TestChannelDBRetributionStore(t *testing.T) {
 	}
 }
 
+// TestBatchedRetributionStore asserts that additions arriving together are
+// written within a single batch, and that none return before being durably
+// written.
+func TestBatchedRetributionStore(t *testing.T) {
+	tempDirName, err := ioutil.TempDir("", "channeldb")
+	if err != nil {
+		t.Fatalf("unable to initialize temp "+
+			"directory for channeldb: %v", err)
+	}
+	defer os.RemoveAll(tempDirName)
+
+	db, err := channeldb.Open(tempDirName)
+	if err != nil {
+		t.Fatalf("unable to open channeldb: %v", err)
+	}
+	defer db.Close()
+
+	rs := newBatchedRetributionStore(db, 50*time.Millisecond)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(retributions))
+	for i := range retributions {
+		wg.Add(1)
+		go func(ret *retributionInfo) {
+			defer wg.Done()
+			errs <- rs.Add(ret, nil)
+		}(&retributions[i])
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unable to add retribution: %v", err)
+		}
+	}
+
+	// Once all additions have returned, each must be visible to a store
+	// which doesn't batch, since they've been written to disk.
+	count := countRetributions(t, newRetributionStore(db))
+	if count != len(retributions) {
+		t.Fatalf("expected %v retributions on disk, found %v",
+			len(retributions), count)
+	}
+
+	rs.batchMtx.Lock()
+	pending := rs.pendingBatch
+	rs.batchMtx.Unlock()
+	if pending != nil {
+		t.Fatalf("batch still pending after all additions returned")
+	}
+}
+
+// TestBatchedRetributionStoreQuit asserts that an addition awaiting its batch
+// is abandoned once its quit channel is closed, rather than holding up its
+// caller until the batch is written.
+func TestBatchedRetributionStoreQuit(t *testing.T) {
+	tempDirName, err := ioutil.TempDir("", "channeldb")
+	if err != nil {
+		t.Fatalf("unable to initialize temp "+
+			"directory for channeldb: %v", err)
+	}
+	defer os.RemoveAll(tempDirName)
+
+	db, err := channeldb.Open(tempDirName)
+	if err != nil {
+		t.Fatalf("unable to open channeldb: %v", err)
+	}
+	defer db.Close()
+
+	rs := newBatchedRetributionStore(db, time.Hour)
+
+	quit := make(chan struct{})
+	errs := make(chan error, 1)
+	go func() {
+		errs <- rs.Add(&retributions[0], quit)
+	}()
+	close(quit)
+
+	select {
+	case err := <-errs:
+		if err != errStoreAddAborted {
+			t.Fatalf("expected %v, got %v", errStoreAddAborted, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("addition not abandoned upon quit")
+	}
+}
+
 // countRetributions uses a retribution store's ForAll to count the number of
 // elements emitted from the store.
 func countRetributions(t *testing.T, rs RetributionStore) int {
@@ -630,7 +952,7 @@ func testRetributionStoreOverwrite(frs FailingRetributionStore, t *testing.T) {
 
 	// Overwrite the initial entries again.
 	for i, retInfo := range retributions {
-		if err := frs.Add(&retInfo); err != nil {
+		if err := frs.Add(&retInfo, nil); err != nil {
 			t.Fatalf("unable to add to retribution %v to store: %v",
 				i, err)
 		}
@@ -642,6 +964,68 @@ func testRetributionStoreOverwrite(frs FailingRetributionStore, t *testing.T) {
 	}
 }
 
+// testRetributionStoreArchive ensures that archiving a retribution removes it
+// from the set iterated by ForAll, and that it persists within the archive
+// along with its outcome.
+func testRetributionStoreArchive(frs FailingRetributionStore, t *testing.T) {
+	testRetributionStoreAdds(frs, t, false)
+
+	ret := retributions[0]
+	ret.justiceConfHeight = 500123
+	justiceTxid := chainhash.Hash{0x42}
+	archivedAt := time.Unix(1500000000, 0)
+	err := frs.Archive(&ArchivedBreach{
+		JusticeTxid: justiceTxid,
+		Recovered:   ret.selfOutput.amt,
+		ArchivedAt:  archivedAt,
+		retribution: &ret,
+	})
+	if err != nil {
+		t.Fatalf("unable to archive retribution: %v", err)
+	}
+
+	frs.Restart()
+
+	if count := countRetributions(t, frs); count != len(retributions)-1 {
+		t.Fatalf("expected %v retributions, found %v",
+			len(retributions)-1, count)
+	}
+
+	var archived []*ArchivedBreach
+	err = frs.ForAllArchived(func(a *ArchivedBreach) error {
+		archived = append(archived, a)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unable to list archived breaches: %v", err)
+	}
+	if len(archived) != 1 {
+		t.Fatalf("expected 1 archived breach, found %v", len(archived))
+	}
+
+	a := archived[0]
+	switch {
+	case a.ChanPoint != ret.chanPoint:
+		t.Fatalf("expected chan point %v, got %v", ret.chanPoint,
+			a.ChanPoint)
+	case a.BreachTxid != ret.commitHash:
+		t.Fatalf("expected breach txid %v, got %v", ret.commitHash,
+			a.BreachTxid)
+	case a.JusticeTxid != justiceTxid:
+		t.Fatalf("expected justice txid %v, got %v", justiceTxid,
+			a.JusticeTxid)
+	case a.Recovered != ret.selfOutput.amt:
+		t.Fatalf("expected %v recovered, got %v", ret.selfOutput.amt,
+			a.Recovered)
+	case !a.ArchivedAt.Equal(archivedAt):
+		t.Fatalf("expected archival at %v, got %v", archivedAt,
+			a.ArchivedAt)
+	case a.JusticeConfHeight != ret.justiceConfHeight:
+		t.Fatalf("expected justice conf height %v, got %v",
+			ret.justiceConfHeight, a.JusticeConfHeight)
+	}
+}
+
 // testRetributionStoreAdds adds all of the test retributions to the database,
 // ensuring that the total number of elements increases by exactly 1 after each
 // operation.  If the `failing` flag is provide, the test will restart the
@@ -657,7 +1041,7 @@ func testRetributionStoreAdds(
 	for i, retInfo := range retributions {
 		// Snapshot number of entires before and after the addition.
 		nbefore := countRetributions(t, frs)
-		if err := frs.Add(&retInfo); err != nil {
+		if err := frs.Add(&retInfo, nil); err != nil {
 			t.Fatalf("unable to add to retribution %v to store: %v",
 				i, err)
 		}
@@ -808,3 +1192,9175 @@ restartCheck:
 		goto restartCheck
 	}
 }
+
+// TestBreachArbiterHealthCheck asserts that the health check reports the
+// number of pending retributions, and flags any retribution that has lingered
+// in a single phase beyond the configured timeout.
+func TestBreachArbiterHealthCheck(t *testing.T) {
+	store := newMockRetributionStore()
+	brar := newTestBreachArbiter(&BreachConfig{
+		Store:                   store,
+		StuckRetributionTimeout: time.Minute,
+	})
+
+	for i := range retributions {
+		if err := store.Add(&retributions[i], nil); err != nil {
+			t.Fatalf("unable to add retribution: %v", err)
+		}
+	}
+
+	// Mark the first retribution as having just entered its current
+	// phase, and backdate the second so that it exceeds the timeout.
+	brar.setRetributionPhase(
+		&retributions[0].chanPoint, retPhaseAwaitingBreachConf,
+	)
+	brar.setRetributionPhase(
+		&retributions[1].chanPoint, retPhaseAwaitingJusticeConf,
+	)
+	brar.activeRetributions[retributions[1].chanPoint].since =
+		time.Now().Add(-2 * time.Minute)
+
+	health, err := brar.HealthCheck()
+	if err != nil {
+		t.Fatalf("unable to perform health check: %v", err)
+	}
+
+	if health.ObserverRunning {
+		t.Fatalf("contract observer should not be running")
+	}
+	if health.PendingRetributions != len(retributions) {
+		t.Fatalf("expected %v pending retributions, found %v",
+			len(retributions), health.PendingRetributions)
+	}
+	if len(health.StuckRetributions) != 1 ||
+		health.StuckRetributions[0] != retributions[1].chanPoint {
+
+		t.Fatalf("expected stuck retribution %v, found %v",
+			retributions[1].chanPoint, health.StuckRetributions)
+	}
+	if health.Healthy() {
+		t.Fatalf("arbiter with stuck retribution reported as healthy")
+	}
+}
+
+// TestBreachArbiterHealthCheckSLA asserts that the health check reports the
+// time the oldest pending breach was first seen, and flags any retribution
+// pending for longer than the configured JusticeSLA.
+func TestBreachArbiterHealthCheckSLA(t *testing.T) {
+	store := newMockRetributionStore()
+	brar := newTestBreachArbiter(&BreachConfig{
+		Store:      store,
+		JusticeSLA: time.Hour,
+	})
+
+	// The first breach was seen beyond the SLA, while the second
+	// predates the recording of the time it was seen.
+	overdueAt := time.Now().Add(-2 * time.Hour)
+	seenAt := []time.Time{overdueAt, {}}
+	for i := range retributions {
+		ret := copyRetInfo(&retributions[i])
+		ret.breachDetectedAt = seenAt[i]
+		if err := store.Add(ret, nil); err != nil {
+			t.Fatalf("unable to add retribution: %v", err)
+		}
+	}
+
+	health, err := brar.HealthCheck()
+	if err != nil {
+		t.Fatalf("unable to perform health check: %v", err)
+	}
+
+	if !health.OldestBreachSeenAt.Equal(overdueAt) {
+		t.Fatalf("expected oldest breach seen at %v, got %v",
+			overdueAt, health.OldestBreachSeenAt)
+	}
+	expected := []OverdueRetribution{{
+		ChanPoint:    retributions[0].chanPoint,
+		BreachSeenAt: overdueAt,
+	}}
+	if !reflect.DeepEqual(health.OverdueRetributions, expected) {
+		t.Fatalf("expected overdue retributions %v, got %v",
+			spew.Sdump(expected),
+			spew.Sdump(health.OverdueRetributions))
+	}
+	if health.Healthy() {
+		t.Fatalf("arbiter with overdue retribution reported as " +
+			"healthy")
+	}
+}
+
+// TestCraftCommitSweepTxSweepScriptGen asserts that the commitment sweep
+// transaction pays to the script returned by the configured SweepScriptGen.
+func TestCraftCommitSweepTxSweepScriptGen(t *testing.T) {
+	sweepScript := []byte{
+		0x00, 0x14, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+		0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12,
+		0x13, 0x14,
+	}
+
+	signer := &mockSigner{key: alicePrivKey}
+	brar := newTestBreachArbiter(&BreachConfig{
+		Wallet: &lnwallet.LightningWallet{
+			Cfg: lnwallet.Config{Signer: signer},
+		},
+		SweepScriptGen: func() ([]byte, error) {
+			return sweepScript, nil
+		},
+	})
+
+	signDesc := breachSignDescs[0]
+	closeInfo := &lnwallet.UnilateralCloseSummary{
+		SelfOutPoint:       &breachOutPoints[0],
+		SelfOutputSignDesc: &signDesc,
+	}
+
+	// Only our own output is swept, which is spendable as soon as the
+	// commitment confirms.
+	inputs := commitSweepInputs(closeInfo)
+	if len(inputs) != 1 ||
+		inputs[0].witnessType != lnwallet.CommitmentNoDelay {
+
+		t.Fatalf("unexpected sweep inputs: %v", spew.Sdump(inputs))
+	}
+
+	sweepTx, err := brar.craftCommitSweepTx(inputs)
+	if err != nil {
+		t.Fatalf("unable to craft sweep tx: %v", err)
+	}
+
+	if len(sweepTx.TxOut) != 1 {
+		t.Fatalf("expected 1 output, found %v", len(sweepTx.TxOut))
+	}
+	if !bytes.Equal(sweepTx.TxOut[0].PkScript, sweepScript) {
+		t.Fatalf("sweep tx pays to unexpected script: %x",
+			sweepTx.TxOut[0].PkScript)
+	}
+}
+
+// TestBreachedOutputPreimageSerialization asserts that the payment preimage of
+// an HTLC output is persisted only for witness types that require it.
+func TestBreachedOutputPreimageSerialization(t *testing.T) {
+	htlcOutput := breachedOutputs[0]
+	htlcOutput.witnessType = lnwallet.HtlcAcceptedRemoteSuccess
+	htlcOutput.preimage = [32]byte{
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+		0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+		0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18,
+		0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f, 0x20,
+	}
+
+	var htlcBuf, plainBuf bytes.Buffer
+	if err := htlcOutput.Encode(&htlcBuf); err != nil {
+		t.Fatalf("unable to serialize htlc output: %v", err)
+	}
+	if err := breachedOutputs[0].Encode(&plainBuf); err != nil {
+		t.Fatalf("unable to serialize breached output: %v", err)
+	}
+
+	// Only the HTLC output should carry the additional 32-byte preimage.
+	if htlcBuf.Len() != plainBuf.Len()+32 {
+		t.Fatalf("expected htlc output to be 32 bytes longer, "+
+			"got %v vs %v", htlcBuf.Len(), plainBuf.Len())
+	}
+
+	desOutput := &breachedOutput{}
+	if err := desOutput.Decode(&htlcBuf); err != nil {
+		t.Fatalf("unable to deserialize htlc output: %v", err)
+	}
+	if !reflect.DeepEqual(&htlcOutput, desOutput) {
+		t.Fatalf("original and deserialized htlc outputs not equal:\n"+
+			"original     : %+v\ndeserialized : %+v\n",
+			htlcOutput, desOutput)
+	}
+}
+
+// TestBreachedOutputSignDescRefSerialization asserts that whether a sign
+// descriptor is persisted by reference survives a serialization round trip,
+// and that breached outputs are written in the same layout either way.
+func TestBreachedOutputSignDescRefSerialization(t *testing.T) {
+	ret := copyRetInfo(&retributions[0])
+	selfOutput := *ret.selfOutput
+	selfOutput.signDescRef = true
+	ret.selfOutput = &selfOutput
+
+	var buf bytes.Buffer
+	if err := ret.Encode(&buf); err != nil {
+		t.Fatalf("unable to serialize retribution: %v", err)
+	}
+	desRet := &retributionInfo{}
+	if err := desRet.Decode(&buf); err != nil {
+		t.Fatalf("unable to deserialize retribution: %v", err)
+	}
+	if !desRet.selfOutput.signDescRef ||
+		!desRet.selfOutput.signDescPending() {
+
+		t.Fatalf("expected sign descriptor persisted by reference")
+	}
+	if !desRet.selfOutput.signDescriptor.PubKey.IsEqual(
+		selfOutput.signDescriptor.PubKey) {
+
+		t.Fatalf("expected public key of sign descriptor to be kept")
+	}
+	if desRet.revokedOutput.signDescRef ||
+		desRet.revokedOutput.signDescPending() {
+
+		t.Fatalf("expected sign descriptor persisted in full")
+	}
+
+	// Outputs are written in the original layout, with the sign
+	// descriptor following the outpoint.
+	bo := &breachedOutputs[0]
+	var expected bytes.Buffer
+	var scratch [8]byte
+	binary.BigEndian.PutUint64(scratch[:], uint64(bo.amt))
+	expected.Write(scratch[:])
+	writeOutpoint(&expected, &bo.outpoint)
+	lnwallet.WriteSignDescriptor(&expected, &bo.signDescriptor)
+	binary.BigEndian.PutUint16(scratch[:2], uint16(bo.witnessType))
+	expected.Write(scratch[:2])
+	if bo.twoStageClaim {
+		expected.WriteByte(1)
+	} else {
+		expected.WriteByte(0)
+	}
+
+	var encoded bytes.Buffer
+	if err := bo.Encode(&encoded); err != nil {
+		t.Fatalf("unable to serialize breached output: %v", err)
+	}
+	if !bytes.Equal(encoded.Bytes(), expected.Bytes()) {
+		t.Fatalf("expected encoding %x, got %x", expected.Bytes(),
+			encoded.Bytes())
+	}
+}
+
+// mockBreachEvents is a BreachEventNotifier which forwards each event to the
+// corresponding function, if set.
+type mockBreachEvents struct {
+	channelResolved func(chanPoint wire.OutPoint,
+		recovered btcutil.Amount, confHeight uint32,
+		breakdown *JusticeBreakdown)
+	unsweepableBreach  func(*UnsweepableBreach)
+	uneconomicBreach   func(*UneconomicBreach)
+	skippedHTLC        func(*SkippedHTLCOutput)
+	manualIntervention func(*RetributionDiagnostic)
+	approvalRequired   func(*RetributionDiagnostic)
+	walletLocked       func(*RetributionDiagnostic)
+}
+
+func (m *mockBreachEvents) ChannelResolved(chanPoint wire.OutPoint,
+	recovered btcutil.Amount, confHeight uint32,
+	breakdown *JusticeBreakdown) {
+
+	if m.channelResolved != nil {
+		m.channelResolved(chanPoint, recovered, confHeight, breakdown)
+	}
+}
+
+func (m *mockBreachEvents) UnsweepableBreach(breach *UnsweepableBreach) {
+	if m.unsweepableBreach != nil {
+		m.unsweepableBreach(breach)
+	}
+}
+
+func (m *mockBreachEvents) UneconomicBreach(breach *UneconomicBreach) {
+	if m.uneconomicBreach != nil {
+		m.uneconomicBreach(breach)
+	}
+}
+
+func (m *mockBreachEvents) SkippedHTLC(skipped *SkippedHTLCOutput) {
+	if m.skippedHTLC != nil {
+		m.skippedHTLC(skipped)
+	}
+}
+
+func (m *mockBreachEvents) ManualIntervention(diag *RetributionDiagnostic) {
+	if m.manualIntervention != nil {
+		m.manualIntervention(diag)
+	}
+}
+
+func (m *mockBreachEvents) ApprovalRequired(diag *RetributionDiagnostic) {
+	if m.approvalRequired != nil {
+		m.approvalRequired(diag)
+	}
+}
+
+func (m *mockBreachEvents) WalletLocked(diag *RetributionDiagnostic) {
+	if m.walletLocked != nil {
+		m.walletLocked(diag)
+	}
+}
+
+// TestNotifyChannelResolved asserts that the BreachEventNotifier is notified
+// of the resolved channel, and that a panicking notifier does not crash the
+// arbiter.
+func TestNotifyChannelResolved(t *testing.T) {
+	type resolution struct {
+		chanPoint  wire.OutPoint
+		recovered  btcutil.Amount
+		confHeight uint32
+	}
+
+	resolved := make(chan resolution, 1)
+	brar := newTestBreachArbiter(&BreachConfig{
+		Events: &mockBreachEvents{
+			channelResolved: func(chanPoint wire.OutPoint,
+				recovered btcutil.Amount, confHeight uint32,
+				_ *JusticeBreakdown) {
+
+				resolved <- resolution{
+					chanPoint, recovered, confHeight,
+				}
+				panic("misbehaving subscriber")
+			},
+		},
+	})
+
+	brar.notifyChannelResolved(
+		breachOutPoints[0], btcutil.Amount(1000), 500, nil,
+	)
+
+	select {
+	case res := <-resolved:
+		if res.chanPoint != breachOutPoints[0] {
+			t.Fatalf("expected chan point %v, got %v",
+				breachOutPoints[0], res.chanPoint)
+		}
+		if res.recovered != btcutil.Amount(1000) {
+			t.Fatalf("expected 1000 recovered, got %v",
+				res.recovered)
+		}
+		if res.confHeight != 500 {
+			t.Fatalf("expected conf height 500, got %v",
+				res.confHeight)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("ChannelResolved was not notified")
+	}
+}
+
+// recordingBackupPruner is a BackupPruner which records the channels whose
+// backups it is asked to prune, failing each request with err.
+type recordingBackupPruner struct {
+	pruned chan wire.OutPoint
+	err    error
+}
+
+func (p *recordingBackupPruner) PruneChannelBackup(
+	chanPoint wire.OutPoint) error {
+
+	p.pruned <- chanPoint
+	return p.err
+}
+
+// TestPruneChannelBackup asserts that the BackupPruner is notified of the
+// resolved channel, and that a failure to prune does not affect the arbiter.
+func TestPruneChannelBackup(t *testing.T) {
+	pruner := &recordingBackupPruner{
+		pruned: make(chan wire.OutPoint, 1),
+		err:    errors.New("backup subsystem unavailable"),
+	}
+	brar := newTestBreachArbiter(&BreachConfig{
+		BackupPruner: pruner,
+	})
+
+	brar.pruneChannelBackup(breachOutPoints[0])
+
+	select {
+	case chanPoint := <-pruner.pruned:
+		if chanPoint != breachOutPoints[0] {
+			t.Fatalf("expected chan point %v, got %v",
+				breachOutPoints[0], chanPoint)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("backup pruner was not notified")
+	}
+}
+
+// TestCommitSweepBatch asserts that the commitment sweeps joining a batch
+// within its window are swept by a single transaction, with each being
+// attributed its share of the value swept, and that later sweeps form a new
+// batch.
+func TestCommitSweepBatch(t *testing.T) {
+	brar := newTestBreachArbiter(&BreachConfig{
+		Wallet: &lnwallet.LightningWallet{
+			Cfg: lnwallet.Config{
+				Signer: &mockSigner{key: alicePrivKey},
+			},
+		},
+		SweepScriptGen: func() ([]byte, error) {
+			return make([]byte, lnwallet.P2WPKHSize), nil
+		},
+		CommitSweepBatchWindow: 10 * time.Millisecond,
+	})
+
+	newSweep := func(i uint32, amt btcutil.Amount) *commitSweepInfo {
+		input := breachedOutputs[0]
+		input.amt = amt
+		input.outpoint = wire.OutPoint{
+			Hash:  chainhash.Hash{0x01},
+			Index: i,
+		}
+
+		return &commitSweepInfo{
+			chanPoint: wire.OutPoint{Index: i},
+			inputs:    []*breachedOutput{&input},
+		}
+	}
+
+	sweeps := []*commitSweepInfo{
+		newSweep(0, 1e6), newSweep(1, 2e6),
+	}
+	batch := brar.joinCommitSweepBatch(sweeps[0])
+	if brar.joinCommitSweepBatch(sweeps[1]) != batch {
+		t.Fatalf("sweeps within window not batched")
+	}
+
+	select {
+	case <-batch.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("batch not crafted")
+	}
+	if batch.err != nil {
+		t.Fatalf("unable to craft batch: %v", batch.err)
+	}
+
+	if len(batch.sweepTx.TxIn) != len(sweeps) {
+		t.Fatalf("expected batch to spend %v inputs, spends %v",
+			len(sweeps), len(batch.sweepTx.TxIn))
+	}
+
+	// Each sweep bears an equal share of the fee, as each has a single
+	// input, and the shares must account for the entire output.
+	var (
+		totalIn    btcutil.Amount
+		totalShare btcutil.Amount
+	)
+	for _, sweep := range sweeps {
+		totalIn += sweep.inputs[0].amt
+		totalShare += batch.sweptAmts[sweep.chanPoint]
+	}
+	totalOut := btcutil.Amount(batch.sweepTx.TxOut[0].Value)
+	if totalShare != totalOut {
+		t.Fatalf("shares sum to %v, expected %v", totalShare, totalOut)
+	}
+	fee := totalIn - totalOut
+	for _, sweep := range sweeps {
+		share := batch.sweptAmts[sweep.chanPoint]
+		if expected := sweep.inputs[0].amt - fee/2; share != expected {
+			t.Fatalf("expected share %v for ChannelPoint(%v), "+
+				"got %v", expected, sweep.chanPoint, share)
+		}
+	}
+
+	// Once crafted, the batch is closed to further sweeps.
+	late := newSweep(2, 1e6)
+	if lateBatch := brar.joinCommitSweepBatch(late); lateBatch == batch {
+		t.Fatalf("sweep joined already crafted batch")
+	} else {
+		<-lateBatch.done
+	}
+}
+
+// TestMinFeeRateFloor asserts that the configured MinFeeRate is applied as a
+// floor beneath the fee estimate, for both justice transactions and the sweeps
+// of force closed channels.
+func TestMinFeeRateFloor(t *testing.T) {
+	const minFeeRate = btcutil.Amount(25)
+
+	// The estimator returns an absurdly low fee rate, which would see our
+	// transactions rejected by the network.
+	brar := newTestBreachArbiter(&BreachConfig{
+		Estimator: &adjustableFeeEstimator{feePerWeight: 0},
+		Wallet: &lnwallet.LightningWallet{
+			Cfg: lnwallet.Config{
+				Signer: &mockSigner{key: alicePrivKey},
+			},
+		},
+		Signer:     &mockSigner{key: alicePrivKey},
+		MinFeeRate: minFeeRate,
+		SweepScriptGen: func() ([]byte, error) {
+			return []byte{0x00, 0x14}, nil
+		},
+	})
+
+	ret := newBreachRetInfo()
+	justiceTx, err := brar.createJusticeTx(ret, sweepTxNew)
+	if err != nil {
+		t.Fatalf("unable to create justice tx: %v", err)
+	}
+
+	inputs := []*breachedOutput{ret.selfOutput, ret.revokedOutput}
+	expFee := minFeeRate * btcutil.Amount(justiceTxWeight(inputs))
+	if fee := justiceTxFee(ret, justiceTx); fee != expFee {
+		t.Fatalf("expected justice tx fee %v, got %v", expFee, fee)
+	}
+
+	// The fixed fee of a commitment sweep must likewise be raised to the
+	// floor.
+	input := &breachedOutput{
+		amt:            btcutil.Amount(1e6),
+		outpoint:       breachOutPoints[0],
+		signDescriptor: breachSignDescs[0],
+		witnessType:    lnwallet.CommitmentNoDelay,
+	}
+	sweepTx, err := brar.craftCommitSweepTx([]*breachedOutput{input})
+	if err != nil {
+		t.Fatalf("unable to craft sweep tx: %v", err)
+	}
+
+	expFee = minFeeRate * btcutil.Amount(
+		justiceTxWeight([]*breachedOutput{input}),
+	)
+	if expFee <= commitSweepBaseFee {
+		t.Fatalf("floor of %v doesn't exceed the base fee", expFee)
+	}
+	fee := input.amt - btcutil.Amount(sweepTx.TxOut[0].Value)
+	if fee != expFee {
+		t.Fatalf("expected sweep tx fee %v, got %v", expFee, fee)
+	}
+}
+
+// breachChainIO is a mock implementation of the BlockChainIO interface whose
+// UTXO set is backed by an in-memory map.
+type breachChainIO struct {
+	mockChainIO
+
+	utxos map[wire.OutPoint]*wire.TxOut
+}
+
+func (c *breachChainIO) GetUtxo(op *wire.OutPoint,
+	heightHint uint32) (*wire.TxOut, error) {
+
+	txOut, ok := c.utxos[*op]
+	if !ok {
+		return nil, fmt.Errorf("output %v not found", op)
+	}
+
+	return txOut, nil
+}
+
+// newRevokedChainIO returns a breachChainIO within which the revoked output of
+// the given retribution remains unspent.
+func newRevokedChainIO(ret *retributionInfo) *breachChainIO {
+	revoked := ret.revokedOutput
+	return &breachChainIO{
+		utxos: map[wire.OutPoint]*wire.TxOut{
+			revoked.outpoint: {
+				Value:    int64(revoked.amt),
+				PkScript: revoked.signDescriptor.Output.PkScript,
+			},
+		},
+	}
+}
+
+// newBreachRetInfo returns a retribution whose outputs are consistent with its
+// commitment hash.
+func newBreachRetInfo() *retributionInfo {
+	commitHash := chainhash.Hash{0x01}
+
+	selfOutput := breachedOutputs[0]
+	selfOutput.outpoint = wire.OutPoint{Hash: commitHash, Index: 0}
+	revokedOutput := breachedOutputs[1]
+	revokedOutput.outpoint = wire.OutPoint{Hash: commitHash, Index: 1}
+
+	return &retributionInfo{
+		commitHash:    commitHash,
+		chanPoint:     breachOutPoints[0],
+		selfOutput:    &selfOutput,
+		revokedOutput: &revokedOutput,
+		htlcOutputs:   []*breachedOutput{},
+	}
+}
+
+// TestCorroborateBreach asserts that a persisted breach is only acted upon if
+// the chain doesn't contradict the breach transaction we have on record.
+func TestCorroborateBreach(t *testing.T) {
+	retInfo := newBreachRetInfo()
+	revokedOutput := retInfo.revokedOutput
+
+	chainIO := &breachChainIO{
+		utxos: make(map[wire.OutPoint]*wire.TxOut),
+	}
+	brar := newTestBreachArbiter(&BreachConfig{
+		ChainIO: chainIO,
+	})
+
+	// With an empty UTXO set, the breach transaction may yet be
+	// unconfirmed, so the breach mustn't be rejected.
+	if err := brar.corroborateBreach(retInfo); err != nil {
+		t.Fatalf("unconfirmed breach rejected: %v", err)
+	}
+
+	// An output with an unexpected value must also be rejected.
+	chainIO.utxos[revokedOutput.outpoint] = &wire.TxOut{
+		Value:    int64(revokedOutput.amt) + 1,
+		PkScript: revokedOutput.signDescriptor.Output.PkScript,
+	}
+	if err := brar.corroborateBreach(retInfo); err == nil {
+		t.Fatalf("breach corroborated by output with wrong value")
+	}
+
+	// Finally, once the revoked output is found as expected, the breach
+	// should be corroborated.
+	chainIO.utxos[revokedOutput.outpoint] = &wire.TxOut{
+		Value:    int64(revokedOutput.amt),
+		PkScript: revokedOutput.signDescriptor.Output.PkScript,
+	}
+	if err := brar.corroborateBreach(retInfo); err != nil {
+		t.Fatalf("unable to corroborate breach: %v", err)
+	}
+
+	// Should our balance have been dust at the revoked state, our output
+	// is absent from the breach transaction, which is no contradiction.
+	selfOutput := *retInfo.selfOutput
+	retInfo.selfOutput.outpoint = wire.OutPoint{}
+	retInfo.selfOutput.amt = 0
+	if err := brar.corroborateBreach(retInfo); err != nil {
+		t.Fatalf("breach without our output rejected: %v", err)
+	}
+	*retInfo.selfOutput = selfOutput
+
+	// A record whose outputs don't belong to the breach transaction is
+	// inconsistent, and should never be corroborated.
+	retInfo.commitHash = chainhash.Hash{0x02}
+	if err := brar.corroborateBreach(retInfo); err == nil {
+		t.Fatalf("breach corroborated with inconsistent outputs")
+	}
+}
+
+// TestEvenSweepPolicy asserts that the even sweep policy never distributes more
+// than the net amount, places the rounding remainder as configured, and refuses
+// to create dust outputs.
+func TestEvenSweepPolicy(t *testing.T) {
+	const (
+		dustLimit = btcutil.Amount(500)
+		fee       = btcutil.Amount(1000)
+	)
+
+	tests := []struct {
+		name       string
+		remainder  SweepRemainder
+		gross      btcutil.Amount
+		numOutputs int
+		expAmts    []btcutil.Amount
+		expErr     error
+	}{
+		{
+			name:       "single output exactly dust + 1",
+			remainder:  RemainderToFirst,
+			gross:      fee + dustLimit + 1,
+			numOutputs: 1,
+			expAmts:    []btcutil.Amount{dustLimit + 1},
+		},
+		{
+			name:       "single output exactly dust",
+			remainder:  RemainderToFirst,
+			gross:      fee + dustLimit,
+			numOutputs: 1,
+			expAmts:    []btcutil.Amount{dustLimit},
+		},
+		{
+			name:       "single output below dust",
+			remainder:  RemainderToFirst,
+			gross:      fee + dustLimit - 1,
+			numOutputs: 1,
+			expErr:     ErrSweepOutputDust,
+		},
+		{
+			name:       "fee exceeds gross",
+			remainder:  RemainderToFirst,
+			gross:      fee - 1,
+			numOutputs: 1,
+			expErr:     ErrSweepOutputDust,
+		},
+		{
+			name:       "split outputs exactly dust + 1",
+			remainder:  RemainderToFirst,
+			gross:      fee + 2*(dustLimit+1),
+			numOutputs: 2,
+			expAmts:    []btcutil.Amount{dustLimit + 1, dustLimit + 1},
+		},
+		{
+			name:       "split outputs one share below dust",
+			remainder:  RemainderToFirst,
+			gross:      fee + 2*dustLimit - 1,
+			numOutputs: 2,
+			expErr:     ErrSweepOutputDust,
+		},
+		{
+			name:       "remainder to first",
+			remainder:  RemainderToFirst,
+			gross:      fee + 3*dustLimit + 2,
+			numOutputs: 3,
+			expAmts: []btcutil.Amount{
+				dustLimit + 2, dustLimit, dustLimit,
+			},
+		},
+		{
+			name:       "remainder to last",
+			remainder:  RemainderToLast,
+			gross:      fee + 3*dustLimit + 2,
+			numOutputs: 3,
+			expAmts: []btcutil.Amount{
+				dustLimit, dustLimit, dustLimit + 2,
+			},
+		},
+		{
+			name:       "remainder to fee",
+			remainder:  RemainderToFee,
+			gross:      fee + 3*dustLimit + 2,
+			numOutputs: 3,
+			expAmts: []btcutil.Amount{
+				dustLimit, dustLimit, dustLimit,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		policy := &EvenSweepPolicy{
+			DustLimit: dustLimit,
+			Remainder: test.remainder,
+		}
+
+		amts, err := policy.Distribute(test.gross, fee, test.numOutputs)
+		if err != test.expErr {
+			t.Fatalf("%s: expected error %v, got %v", test.name,
+				test.expErr, err)
+		}
+		if !reflect.DeepEqual(amts, test.expAmts) {
+			t.Fatalf("%s: expected amounts %v, got %v", test.name,
+				test.expAmts, amts)
+		}
+	}
+}
+
+// TestRetributionDetectionTimeSerialization asserts that the breach detection
+// time survives a serialization round trip, and that retributions persisted
+// without one still decode, leaving the detection time unknown.
+func TestRetributionDetectionTimeSerialization(t *testing.T) {
+	ret := copyRetInfo(&retributions[0])
+	ret.breachDetectedAt = time.Unix(0, 1500000000123456789)
+
+	var buf bytes.Buffer
+	if err := ret.Encode(&buf); err != nil {
+		t.Fatalf("unable to serialize retribution: %v", err)
+	}
+
+	desRet := &retributionInfo{}
+	if err := desRet.Decode(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("unable to deserialize retribution: %v", err)
+	}
+	if !desRet.breachDetectedAt.Equal(ret.breachDetectedAt) {
+		t.Fatalf("expected detection time %v, got %v",
+			ret.breachDetectedAt, desRet.breachDetectedAt)
+	}
+
+	// A record written by an older version ends with the fields common to
+	// every version.
+	var legacy bytes.Buffer
+	if err := ret.encodeCommon(&legacy); err != nil {
+		t.Fatalf("unable to serialize retribution: %v", err)
+	}
+	desRet = &retributionInfo{}
+	if err := desRet.Decode(&legacy); err != nil {
+		t.Fatalf("unable to deserialize legacy retribution: %v", err)
+	}
+	if !desRet.breachDetectedAt.IsZero() {
+		t.Fatalf("expected unknown detection time, got %v",
+			desRet.breachDetectedAt)
+	}
+}
+
+// TestRetributionRecords asserts that records of an unknown type are ignored.
+func TestRetributionRecords(t *testing.T) {
+	ret := copyRetInfo(&retributions[0])
+	ret.breachDetectedAt = time.Unix(0, 1500000000123456789)
+	ret.justiceTxid = chainhash.Hash{0x01}
+	revokedOutput := *ret.revokedOutput
+	revokedOutput.contestDelay = 144
+	ret.revokedOutput = &revokedOutput
+
+	// A record of an unknown type, e.g. written by a later version, is
+	// skipped over.
+	var buf bytes.Buffer
+	if err := ret.encodeCommon(&buf); err != nil {
+		t.Fatalf("unable to serialize retribution: %v", err)
+	}
+	buf.Write([]byte{retributionRecordsMarker, retributionEncodingVersion})
+	wire.WriteVarInt(&buf, 0, 2)
+	wire.WriteVarInt(&buf, 0, 1<<20)
+	wire.WriteVarBytes(&buf, 0, []byte{0x01, 0x02})
+	wire.WriteVarInt(&buf, 0, retRecordJusticeTxid)
+	wire.WriteVarBytes(&buf, 0, ret.justiceTxid[:])
+
+	desRet := &retributionInfo{}
+	if err := desRet.Decode(&buf); err != nil {
+		t.Fatalf("unable to deserialize retribution: %v", err)
+	}
+	if desRet.justiceTxid != ret.justiceTxid {
+		t.Fatalf("expected justice txid %v, got %v", ret.justiceTxid,
+			desRet.justiceTxid)
+	}
+
+	// An unknown encoding version is rejected.
+	buf.Reset()
+	if err := ret.encodeCommon(&buf); err != nil {
+		t.Fatalf("unable to serialize retribution: %v", err)
+	}
+	buf.Write(
+		[]byte{retributionRecordsMarker, retributionEncodingVersion + 1},
+	)
+	if err := (&retributionInfo{}).Decode(&buf); err == nil {
+		t.Fatalf("expected unknown version to be rejected")
+	}
+}
+
+// TestRetributionCorruptRemoteIdentity asserts that a retribution whose remote
+// identity fails to parse is still decoded, without its remote identity, and
+// that it's re-encoded unchanged.
+func TestRetributionCorruptRemoteIdentity(t *testing.T) {
+	ret := copyRetInfo(&retributions[0])
+
+	var buf bytes.Buffer
+	if err := ret.Encode(&buf); err != nil {
+		t.Fatalf("unable to serialize retribution: %v", err)
+	}
+
+	// The remote identity follows the commitment hash and the channel
+	// point. Flipping its prefix yields an invalid public key.
+	identityOffset := chainhash.HashSize + 1 + chainhash.HashSize + 4
+	corrupted := buf.Bytes()
+	corrupted[identityOffset] = 0x05
+
+	desRet := &retributionInfo{}
+	if err := desRet.Decode(bytes.NewReader(corrupted)); err != nil {
+		t.Fatalf("unable to deserialize retribution: %v", err)
+	}
+	if desRet.hasRemoteIdentity() {
+		t.Fatalf("expected remote identity to be unknown")
+	}
+	if desRet.chanPoint != ret.chanPoint ||
+		desRet.capacity != ret.capacity ||
+		!reflect.DeepEqual(desRet.selfOutput, ret.selfOutput) ||
+		!reflect.DeepEqual(desRet.revokedOutput, ret.revokedOutput) {
+
+		t.Fatalf("remainder of retribution not decoded:\n"+
+			"original     : %+v\ndeserialized : %+v", ret, desRet)
+	}
+
+	var reencoded bytes.Buffer
+	if err := desRet.Encode(&reencoded); err != nil {
+		t.Fatalf("unable to re-serialize retribution: %v", err)
+	}
+	if !bytes.Equal(reencoded.Bytes(), corrupted) {
+		t.Fatalf("retribution not re-encoded unchanged")
+	}
+
+	// Without the identity, the retribution can't be attributed to a
+	// peer, though an archive of it must still be possible.
+	archived := &ArchivedBreach{retribution: desRet}
+	archived.fillFromRetribution()
+	if archived.RemoteIdentity != [33]byte{} {
+		t.Fatalf("expected unknown remote identity, got %x",
+			archived.RemoteIdentity)
+	}
+}
+
+// TestReconcileSettledBalance asserts that a settled balance reported by a
+// channel's state snapshot is only persisted if it reconciles with the value of
+// our breached output, within the configured tolerance.
+func TestReconcileSettledBalance(t *testing.T) {
+	tests := []struct {
+		name      string
+		tolerance btcutil.Amount
+		settled   btcutil.Amount
+		selfAmt   btcutil.Amount
+		expected  btcutil.Amount
+	}{
+		{
+			name:     "exact match",
+			settled:  500000,
+			selfAmt:  500000,
+			expected: 500000,
+		},
+		{
+			name:      "within tolerance",
+			tolerance: 10000,
+			settled:   500000,
+			selfAmt:   491000,
+			expected:  500000,
+		},
+		{
+			name:      "snapshot exceeds output",
+			tolerance: 10000,
+			settled:   500000,
+			selfAmt:   300000,
+			expected:  300000,
+		},
+		{
+			name:      "output exceeds snapshot",
+			tolerance: 10000,
+			settled:   0,
+			selfAmt:   300000,
+			expected:  300000,
+		},
+	}
+
+	for _, test := range tests {
+		brar := newTestBreachArbiter(&BreachConfig{
+			SnapshotBalanceTolerance: test.tolerance,
+		})
+
+		settled := brar.reconcileSettledBalance(
+			&breachOutPoints[0], btcutil.SatoshiPerBitcoin,
+			test.settled, test.selfAmt, 100000,
+		)
+		if settled != test.expected {
+			t.Fatalf("%s: expected settled balance %v, got %v",
+				test.name, test.expected, settled)
+		}
+	}
+}
+
+// TestBreachArbiterStats asserts that time-to-justice latencies are
+// aggregated correctly, and that retributions with an unknown detection time
+// only contribute to the broadcast to confirmation latency.
+func TestBreachArbiterStats(t *testing.T) {
+	brar := newTestBreachArbiter(&BreachConfig{})
+
+	start := time.Unix(1500000000, 0)
+	brar.recordJusticeLatency(start, start.Add(time.Minute),
+		start.Add(11*time.Minute))
+	brar.recordJusticeLatency(start, start.Add(3*time.Minute),
+		start.Add(33*time.Minute))
+	brar.recordJusticeLatency(time.Time{}, start, start.Add(time.Hour))
+
+	stats := brar.Stats()
+	if stats.JusticeServed != 3 {
+		t.Fatalf("expected 3 retributions served, got %v",
+			stats.JusticeServed)
+	}
+
+	detToBcast := stats.DetectionToBroadcast
+	if detToBcast.Count != 2 || detToBcast.Min != time.Minute ||
+		detToBcast.Max != 3*time.Minute ||
+		detToBcast.Mean() != 2*time.Minute {
+
+		t.Fatalf("unexpected detection to broadcast latency: %+v",
+			detToBcast)
+	}
+
+	bcastToConf := stats.BroadcastToConfirmation
+	if bcastToConf.Count != 3 || bcastToConf.Min != 10*time.Minute ||
+		bcastToConf.Max != time.Hour || bcastToConf.Last != time.Hour {
+
+		t.Fatalf("unexpected broadcast to confirmation latency: %+v",
+			bcastToConf)
+	}
+
+	detToConf := stats.DetectionToConfirmation
+	if detToConf.Count != 2 || detToConf.Total != 44*time.Minute {
+		t.Fatalf("unexpected detection to confirmation latency: %+v",
+			detToConf)
+	}
+}
+
+// TestCreateJusticeTxSweepScriptPolicy asserts that new justice transactions
+// pay to a fresh script, while replacements reuse the script of the justice
+// transaction they replace.
+func TestCreateJusticeTxSweepScriptPolicy(t *testing.T) {
+	var numScripts byte
+	brar := newTestBreachArbiter(&BreachConfig{
+		Wallet: &lnwallet.LightningWallet{
+			Cfg: lnwallet.Config{
+				Signer: &mockSigner{key: alicePrivKey},
+			},
+		},
+		SweepScriptGen: func() ([]byte, error) {
+			numScripts++
+			return []byte{0x00, 0x14, numScripts}, nil
+		},
+	})
+
+	retInfo := newBreachRetInfo()
+
+	// A replacement can't be crafted before an initial justice
+	// transaction.
+	if _, err := brar.createJusticeTx(retInfo, sweepTxReplacement); err == nil {
+		t.Fatalf("expected replacement without prior sweep to fail")
+	}
+
+	sweepScript := func(kind sweepTxKind) []byte {
+		justiceTx, err := brar.createJusticeTx(retInfo, kind)
+		if err != nil {
+			t.Fatalf("unable to create justice tx: %v", err)
+		}
+		return justiceTx.TxOut[0].PkScript
+	}
+
+	first := sweepScript(sweepTxNew)
+	if replacement := sweepScript(sweepTxReplacement); !bytes.Equal(
+		replacement, first) {
+
+		t.Fatalf("replacement pays to %x, expected original script %x",
+			replacement, first)
+	}
+
+	second := sweepScript(sweepTxNew)
+	if bytes.Equal(second, first) {
+		t.Fatalf("new justice tx reused sweep script %x", first)
+	}
+
+	// Any later replacement now reuses the script of the most recent
+	// justice transaction.
+	if replacement := sweepScript(sweepTxReplacement); !bytes.Equal(
+		replacement, second) {
+
+		t.Fatalf("replacement pays to %x, expected %x", replacement,
+			second)
+	}
+}
+
+// TestResolveHTLCs asserts that HTLCs swept with their preimage are settled
+// within the switch, that outgoing HTLCs swept via their revocation clause are
+// failed by their persisted payment hash, and that incoming HTLCs, along with
+// those whose payment hash is unknown, are skipped.
+func TestResolveHTLCs(t *testing.T) {
+	type resolution struct {
+		payHash  [32]byte
+		amt      lnwire.MilliSatoshi
+		preimage *[32]byte
+	}
+
+	var resolutions []resolution
+	brar := newTestBreachArbiter(&BreachConfig{
+		ResolveHTLC: func(payHash [32]byte, amt lnwire.MilliSatoshi,
+			preimage *[32]byte) error {
+
+			resolutions = append(resolutions, resolution{
+				payHash:  payHash,
+				amt:      amt,
+				preimage: preimage,
+			})
+			return nil
+		},
+	})
+
+	newHTLC := func(wt lnwallet.WitnessType, amt btcutil.Amount,
+		payHash [32]byte) *breachedOutput {
+
+		htlc := breachedOutputs[1]
+		htlc.witnessType = wt
+		htlc.amt = amt
+		htlc.paymentHash = payHash
+		return &htlc
+	}
+
+	successOutput := newHTLC(
+		lnwallet.HtlcAcceptedRemoteSuccess, 1000, [32]byte{},
+	)
+	successOutput.preimage = [32]byte{0x01, 0x02, 0x03}
+	outgoing := newHTLC(lnwallet.HtlcAcceptedRevoke, 2000, [32]byte{0x04})
+	incoming := newHTLC(lnwallet.HtlcOfferedRevoke, 3000, [32]byte{0x05})
+	unknown := newHTLC(lnwallet.HtlcAcceptedRevoke, 4000, [32]byte{})
+
+	retInfo := newBreachRetInfo()
+	retInfo.htlcOutputs = []*breachedOutput{
+		successOutput, outgoing, incoming, unknown,
+	}
+
+	// The payment hashes must survive being persisted, so that HTLCs are
+	// resolved even if justice is only served after a restart.
+	var buf bytes.Buffer
+	if err := retInfo.Encode(&buf); err != nil {
+		t.Fatalf("unable to serialize retribution: %v", err)
+	}
+	desRet := &retributionInfo{}
+	if err := desRet.Decode(&buf); err != nil {
+		t.Fatalf("unable to deserialize retribution: %v", err)
+	}
+
+	brar.resolveHTLCs(desRet)
+
+	expected := []resolution{
+		{
+			payHash:  sha256.Sum256(successOutput.preimage[:]),
+			amt:      lnwire.NewMSatFromSatoshis(successOutput.amt),
+			preimage: &successOutput.preimage,
+		},
+		{
+			payHash: outgoing.paymentHash,
+			amt:     lnwire.NewMSatFromSatoshis(outgoing.amt),
+		},
+	}
+	if len(resolutions) != len(expected) {
+		t.Fatalf("expected %v resolutions, got %v", len(expected),
+			len(resolutions))
+	}
+	for i, res := range resolutions {
+		exp := expected[i]
+		if res.payHash != exp.payHash {
+			t.Fatalf("unexpected payment hash: %x", res.payHash)
+		}
+		if res.amt != exp.amt {
+			t.Fatalf("unexpected amount: %v", res.amt)
+		}
+
+		switch {
+		case exp.preimage == nil && res.preimage != nil:
+			t.Fatalf("revoked htlc %x settled", res.payHash)
+		case exp.preimage != nil && (res.preimage == nil ||
+			*res.preimage != *exp.preimage):
+
+			t.Fatalf("htlc swept with preimage was not settled")
+		}
+	}
+}
+
+// TestCancelRetribution asserts that a retribution may only be cancelled before
+// its justice transaction is broadcast, and that cancelling it removes its
+// record and prevents the broadcast.
+func TestCancelRetribution(t *testing.T) {
+	store := newMockRetributionStore()
+	brar := newTestBreachArbiter(&BreachConfig{
+		Store: store,
+	})
+
+	pending := &retributions[0]
+	broadcast := &retributions[1]
+	for _, ret := range []*retributionInfo{pending, broadcast} {
+		if err := store.Add(ret, nil); err != nil {
+			t.Fatalf("unable to add retribution: %v", err)
+		}
+	}
+
+	cancel := brar.setRetributionPhase(
+		&pending.chanPoint, retPhaseAwaitingBreachConf,
+	)
+	brar.setRetributionPhase(&broadcast.chanPoint, retPhaseAwaitingBreachConf)
+	if !brar.beginJusticeBroadcast(&broadcast.chanPoint) {
+		t.Fatalf("unable to begin broadcast of uncancelled retribution")
+	}
+
+	// A retribution whose justice tx has been broadcast can't be
+	// cancelled.
+	err := brar.CancelRetribution(&broadcast.chanPoint)
+	if err != ErrJusticeBroadcast {
+		t.Fatalf("expected ErrJusticeBroadcast, got %v", err)
+	}
+
+	if err := brar.CancelRetribution(&pending.chanPoint); err != nil {
+		t.Fatalf("unable to cancel retribution: %v", err)
+	}
+
+	select {
+	case <-cancel:
+	default:
+		t.Fatalf("cancelled retribution was not signalled")
+	}
+
+	if brar.beginJusticeBroadcast(&pending.chanPoint) {
+		t.Fatalf("justice broadcast allowed for cancelled retribution")
+	}
+
+	// Only the cancelled retribution's record should have been removed.
+	if count := countRetributions(t, store); count != 1 {
+		t.Fatalf("expected 1 retribution in store, found %v", count)
+	}
+
+	// Cancelling the same retribution twice should fail, as it's no
+	// longer active.
+	if err := brar.CancelRetribution(&pending.chanPoint); err == nil {
+		t.Fatalf("expected cancelling twice to fail")
+	}
+}
+
+// TestCancelRetributionAfterFailedBroadcast asserts that a retribution whose
+// justice transaction fails to be broadcast may still be cancelled.
+func TestCancelRetributionAfterFailedBroadcast(t *testing.T) {
+	store := newMockRetributionStore()
+	brar := newTestBreachArbiter(&BreachConfig{
+		Estimator: lnwallet.StaticFeeEstimator{FeeRate: 50},
+		Store:     store,
+		SweepScriptGen: func() ([]byte, error) {
+			return nil, fmt.Errorf("no sweep script")
+		},
+	})
+
+	ret := newBreachRetInfo()
+	if err := store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+	brar.setRetributionPhase(&ret.chanPoint, retPhaseAwaitingBreachConf)
+
+	_, err := brar.serveJustice([]*retributionInfo{ret})
+	if err == nil {
+		t.Fatalf("expected justice to fail to be served")
+	}
+
+	if err := brar.CancelRetribution(&ret.chanPoint); err != nil {
+		t.Fatalf("unable to cancel retribution: %v", err)
+	}
+	if count := countRetributions(t, store); count != 0 {
+		t.Fatalf("expected no retributions in store, found %v", count)
+	}
+}
+
+// TestPeerJusticeBatch asserts that the retributions for concurrent breaches
+// by the same peer are served by a single justice transaction, broadcast once
+// all remaining breach transactions have confirmed, and that cancelled
+// retributions are left out.
+func TestPeerJusticeBatch(t *testing.T) {
+	published := make(chan *wire.MsgTx, 10)
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier: &mockNotifier{
+			epochChan: make(chan *chainntnfs.BlockEpoch),
+		},
+		Wallet: newTestWallet(published),
+		SweepScriptGen: func() ([]byte, error) {
+			return []byte{0x00, 0x14}, nil
+		},
+		BatchPeerJustice: true,
+	})
+
+	// Create three retributions against the same peer, each with its own
+	// breach transaction.
+	var rets []*retributionInfo
+	for i := 0; i < 3; i++ {
+		ret := newBreachRetInfo()
+		ret.commitHash = chainhash.Hash{byte(i + 1)}
+		ret.chanPoint.Index = uint32(i)
+		ret.selfOutput.outpoint.Hash = ret.commitHash
+		ret.revokedOutput.outpoint.Hash = ret.commitHash
+		ret.remoteIdentity = *alicePrivKey.PubKey()
+
+		rets = append(rets, ret)
+	}
+
+	type result struct {
+		tx  *wire.MsgTx
+		err error
+	}
+
+	var (
+		batches []*peerJusticeBatch
+		cancels []<-chan struct{}
+	)
+	for _, ret := range rets {
+		cancels = append(cancels, brar.setRetributionPhase(
+			&ret.chanPoint, retPhaseAwaitingBreachConf,
+		))
+		batches = append(batches, brar.joinPeerBatch(ret))
+	}
+	if batches[0] != batches[1] || batches[1] != batches[2] {
+		t.Fatalf("retributions against the same peer not batched")
+	}
+
+	// Confirm the first breach, then cancel the second while its breach
+	// transaction is still unconfirmed.
+	results := make(chan result, len(rets))
+	awaitJustice := func(i int) {
+		go func() {
+			tx, err := brar.awaitPeerJustice(
+				batches[i], rets[i], cancels[i],
+			)
+			results <- result{tx, err}
+		}()
+	}
+	awaitJustice(0)
+
+	if err := brar.CancelRetribution(&rets[1].chanPoint); err != nil {
+		t.Fatalf("unable to cancel retribution: %v", err)
+	}
+	brar.leavePeerBatch(batches[1], rets[1])
+
+	select {
+	case <-published:
+		t.Fatalf("justice broadcast before all breaches confirmed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Once the final breach confirms, a single justice transaction should
+	// be broadcast, serving both remaining retributions.
+	awaitJustice(2)
+
+	var justiceTx *wire.MsgTx
+	select {
+	case justiceTx = <-published:
+	case <-time.After(time.Second):
+		t.Fatalf("batched justice tx not broadcast")
+	}
+
+	if len(justiceTx.TxIn) != 4 {
+		t.Fatalf("expected 4 inputs, found %v", len(justiceTx.TxIn))
+	}
+	for _, i := range []int{0, 2} {
+		found := false
+		for _, txIn := range justiceTx.TxIn {
+			if txIn.PreviousOutPoint == rets[i].revokedOutput.outpoint {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("retribution %v not served by batch", i)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		res := <-results
+		if res.err != nil {
+			t.Fatalf("unable to await justice: %v", res.err)
+		}
+		if res.tx.TxHash() != justiceTx.TxHash() {
+			t.Fatalf("retribution served by unexpected tx")
+		}
+	}
+
+	select {
+	case <-published:
+		t.Fatalf("more than one justice tx broadcast")
+	default:
+	}
+}
+
+// TestWitnessTypeStats asserts that witness generation and broadcast outcomes
+// are tallied by witness type, and that snapshots are isolated from later
+// updates.
+func TestWitnessTypeStats(t *testing.T) {
+	brar := newTestBreachArbiter(&BreachConfig{
+		Wallet: &lnwallet.LightningWallet{
+			Cfg: lnwallet.Config{
+				Signer: &mockSigner{key: alicePrivKey},
+			},
+		},
+		SweepScriptGen: func() ([]byte, error) {
+			return []byte{0x00, 0x14}, nil
+		},
+	})
+
+	signDesc := breachSignDescs[0]
+	closeInfo := &lnwallet.UnilateralCloseSummary{
+		SelfOutPoint:       &breachOutPoints[0],
+		SelfOutputSignDesc: &signDesc,
+	}
+	inputs := commitSweepInputs(closeInfo)
+	if _, err := brar.craftCommitSweepTx(inputs); err != nil {
+		t.Fatalf("unable to craft sweep tx: %v", err)
+	}
+
+	// An output of an unknown witness type can't be swept.
+	unknownType := lnwallet.WitnessType(0xffff)
+	unknown := breachedOutputs[1]
+	unknown.witnessType = unknownType
+	_, err := brar.craftCommitSweepTx([]*breachedOutput{&unknown})
+	if err == nil {
+		t.Fatalf("expected sweep of unknown witness type to fail")
+	}
+
+	brar.recordBroadcastFailure(inputs)
+
+	stats := brar.Stats()
+	noDelay := stats.WitnessTypes[lnwallet.CommitmentNoDelay]
+	if noDelay.WitnessesGenerated != 1 || noDelay.WitnessFailures != 0 ||
+		noDelay.BroadcastFailures != 1 {
+
+		t.Fatalf("unexpected CommitmentNoDelay stats: %+v", noDelay)
+	}
+
+	unknownStats := stats.WitnessTypes[unknownType]
+	if unknownStats.WitnessesGenerated != 0 ||
+		unknownStats.WitnessFailures != 1 {
+
+		t.Fatalf("unexpected stats for unknown type: %+v",
+			unknownStats)
+	}
+
+	// Later updates must not be reflected within the prior snapshot.
+	brar.recordBroadcastFailure(inputs)
+	noDelay = stats.WitnessTypes[lnwallet.CommitmentNoDelay]
+	if noDelay.BroadcastFailures != 1 {
+		t.Fatalf("stats snapshot modified by later update")
+	}
+}
+
+// TestRetributionTransitions asserts that the retribution state machine is
+// idempotent with respect to redundant and out-of-order notifications, by
+// delivering sequences of events and checking the actions taken.
+func TestRetributionTransitions(t *testing.T) {
+	tests := []struct {
+		name       string
+		events     []retributionEvent
+		expActions []retributionAction
+	}{
+		{
+			name: "in order",
+			events: []retributionEvent{
+				retEventBreachConfirmed,
+				retEventJusticeConfirmed,
+			},
+			expActions: []retributionAction{
+				retActionBroadcast,
+				retActionFinalize,
+			},
+		},
+		{
+			name: "duplicate breach confirmation",
+			events: []retributionEvent{
+				retEventBreachConfirmed,
+				retEventBreachConfirmed,
+				retEventJusticeConfirmed,
+			},
+			expActions: []retributionAction{
+				retActionBroadcast,
+				retActionIgnore,
+				retActionFinalize,
+			},
+		},
+		{
+			name: "justice confirmed before breach",
+			events: []retributionEvent{
+				retEventJusticeConfirmed,
+				retEventBreachConfirmed,
+			},
+			expActions: []retributionAction{
+				retActionFinalize,
+				retActionIgnore,
+			},
+		},
+		{
+			name: "duplicate justice confirmation",
+			events: []retributionEvent{
+				retEventBreachConfirmed,
+				retEventJusticeConfirmed,
+				retEventJusticeConfirmed,
+				retEventBreachConfirmed,
+			},
+			expActions: []retributionAction{
+				retActionBroadcast,
+				retActionFinalize,
+				retActionIgnore,
+				retActionIgnore,
+			},
+		},
+		{
+			name: "blocks only bump unconfirmed justice",
+			events: []retributionEvent{
+				retEventBlockEpoch,
+				retEventBreachConfirmed,
+				retEventBlockEpoch,
+				retEventJusticeConfirmed,
+				retEventBlockEpoch,
+			},
+			expActions: []retributionAction{
+				retActionIgnore,
+				retActionBroadcast,
+				retActionBump,
+				retActionFinalize,
+				retActionIgnore,
+			},
+		},
+		{
+			name: "mempool polls only outbid unconfirmed justice",
+			events: []retributionEvent{
+				retEventMempoolPoll,
+				retEventBreachConfirmed,
+				retEventMempoolPoll,
+				retEventJusticeConfirmed,
+				retEventMempoolPoll,
+			},
+			expActions: []retributionAction{
+				retActionIgnore,
+				retActionBroadcast,
+				retActionOutbid,
+				retActionFinalize,
+				retActionIgnore,
+			},
+		},
+		{
+			name: "conf timeout halts bumps until justice confirms",
+			events: []retributionEvent{
+				retEventConfTimeout,
+				retEventBreachConfirmed,
+				retEventConfTimeout,
+				retEventBlockEpoch,
+				retEventMempoolPoll,
+				retEventConfTimeout,
+				retEventJusticeConfirmed,
+			},
+			expActions: []retributionAction{
+				retActionIgnore,
+				retActionBroadcast,
+				retActionEscalate,
+				retActionIgnore,
+				retActionIgnore,
+				retActionIgnore,
+				retActionFinalize,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// Drive the state machine as exactRetribution does, noting
+		// that a finalized retribution ignores all further events.
+		phase := retPhaseAwaitingBreachConf
+		finalized := false
+		for i, event := range test.events {
+			action := retActionIgnore
+			if !finalized {
+				action = retributionTransition(phase, event)
+			}
+
+			if action != test.expActions[i] {
+				t.Fatalf("%s: event #%d (%v) in phase %v: "+
+					"expected action %v, got %v", test.name,
+					i, event, phase, test.expActions[i],
+					action)
+			}
+
+			switch action {
+			case retActionBroadcast:
+				phase = retPhaseAwaitingJusticeConf
+			case retActionEscalate:
+				phase = retPhaseNeedsIntervention
+			case retActionFinalize:
+				finalized = true
+			}
+		}
+	}
+}
+
+// txidConfWaiter is a ConfirmationWaiter which dispatches the confirmation of
+// each transaction over its own channel.
+type txidConfWaiter struct {
+	mu    sync.Mutex
+	confs map[chainhash.Hash]chan *chainntnfs.TxConfirmation
+}
+
+func (w *txidConfWaiter) confChan(
+	txid chainhash.Hash) chan *chainntnfs.TxConfirmation {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.confs == nil {
+		w.confs = make(
+			map[chainhash.Hash]chan *chainntnfs.TxConfirmation,
+		)
+	}
+	confChan, ok := w.confs[txid]
+	if !ok {
+		confChan = make(chan *chainntnfs.TxConfirmation, 1)
+		w.confs[txid] = confChan
+	}
+
+	return confChan
+}
+
+func (w *txidConfWaiter) WaitForConf(txid *chainhash.Hash, numConfs,
+	heightHint uint32,
+	_ <-chan struct{}) (*chainntnfs.ConfirmationEvent, error) {
+
+	return &chainntnfs.ConfirmationEvent{
+		Confirmed: w.confChan(*txid),
+	}, nil
+}
+
+// testJusticeConfirmedBeforeBreach asserts that a retribution resumed after a
+// restart, whose justice transaction confirms before the confirmation of its
+// breach transaction is delivered, is finalized without justice being served
+// anew.
+func testJusticeConfirmedBeforeBreach(h *breachTestHarness, t *testing.T) {
+	waiter := &txidConfWaiter{}
+	published := make(chan *wire.MsgTx, 10)
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier:   h.notifier,
+		ConfWaiter: waiter,
+		DB:         h.db,
+		Wallet:     newTestWallet(published),
+		Store:      h.store,
+		SweepScriptGen: func() ([]byte, error) {
+			return []byte{0x00, 0x14}, nil
+		},
+	})
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	// The justice transaction of the breach of Alice's channel was
+	// broadcast before restarting.
+	ret := h.newBreachRetInfo()
+	ret.justiceTxid = chainhash.Hash{0x42}
+	if err := h.store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+	closeTestChannel(t, h.alice, ret.commitHash, channeldb.BreachClose)
+
+	brar.wg.Add(1)
+	go brar.exactRetribution(
+		&chainntnfs.ConfirmationEvent{
+			Confirmed: waiter.confChan(ret.commitHash),
+		},
+		ret,
+	)
+
+	// The justice transaction confirms before the breach transaction's
+	// confirmation is delivered.
+	waiter.confChan(ret.justiceTxid) <- &chainntnfs.TxConfirmation{
+		BlockHeight: fundingBroadcastHeight,
+	}
+
+	deadline := time.After(5 * time.Second)
+	for countRetributions(t, h.store) != 0 {
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatalf("retribution not finalized")
+		}
+	}
+	resolved, err := brar.breachResolved(&ret.chanPoint)
+	if err != nil {
+		t.Fatalf("unable to query resolution: %v", err)
+	}
+	if !resolved {
+		t.Fatalf("channel not closed once justice confirmed")
+	}
+
+	// The late confirmation of the breach transaction is ignored, rather
+	// than triggering a broadcast.
+	waiter.confChan(ret.commitHash) <- &chainntnfs.TxConfirmation{}
+	select {
+	case tx := <-published:
+		t.Fatalf("unexpected justice tx %v published", tx.TxHash())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestSweepScriptPool asserts that the sweep script pool fills itself ahead of
+// time, never hands out the same script twice under concurrent access, and
+// refills once drawn from.
+func TestSweepScriptPool(t *testing.T) {
+	t.Parallel()
+
+	const poolSize = 3
+
+	var generated uint32
+	gen := func() ([]byte, error) {
+		var script [4]byte
+		binary.BigEndian.PutUint32(
+			script[:], atomic.AddUint32(&generated, 1),
+		)
+		return script[:], nil
+	}
+
+	quit := make(chan struct{})
+	var wg sync.WaitGroup
+	pool := newSweepScriptPool(poolSize, gen, quit, &wg)
+	pool.start()
+	defer func() {
+		close(quit)
+		wg.Wait()
+	}()
+
+	waitFull := func() {
+		deadline := time.After(5 * time.Second)
+		for len(pool.scripts) < poolSize {
+			select {
+			case <-deadline:
+				t.Fatalf("pool not filled, holds %v scripts",
+					len(pool.scripts))
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}
+	waitFull()
+
+	// Draw more scripts than the pool holds concurrently, such that some
+	// are generated on demand.
+	const numDraws = 20
+	scripts := make(chan []byte, numDraws)
+	var draws sync.WaitGroup
+	for i := 0; i < numDraws; i++ {
+		draws.Add(1)
+		go func() {
+			defer draws.Done()
+
+			script, err := pool.get()
+			if err != nil {
+				t.Errorf("unable to get sweep script: %v", err)
+				return
+			}
+			scripts <- script
+		}()
+	}
+	draws.Wait()
+	close(scripts)
+
+	seen := make(map[string]struct{})
+	for script := range scripts {
+		if _, ok := seen[string(script)]; ok {
+			t.Fatalf("sweep script %x handed out twice", script)
+		}
+		seen[string(script)] = struct{}{}
+	}
+	if len(seen) != numDraws {
+		t.Fatalf("expected %v scripts, got %v", numDraws, len(seen))
+	}
+
+	// The pool must have been topped up once more.
+	waitFull()
+}
+
+// TestObserverPool asserts that the observer pool watches many contracts with
+// a bounded number of workers, delivering exactly one event for each contract
+// and ceasing to watch it thereafter.
+func TestObserverPool(t *testing.T) {
+	t.Parallel()
+
+	type observed struct {
+		contract *lnwallet.LightningChannel
+		event    observerEvent
+	}
+	events := make(chan observed, 10)
+	handle := func(c *lnwallet.LightningChannel, e observerEvent) {
+		events <- observed{c, e}
+	}
+
+	quit := make(chan struct{})
+	var wg sync.WaitGroup
+	pool := newObserverPool(2, handle, quit, &wg)
+	pool.start()
+	defer func() {
+		close(quit)
+		wg.Wait()
+	}()
+
+	// Watch more contracts than there are workers, which should be spread
+	// evenly across them.
+	const numContracts = 6
+	contracts := make([]*lnwallet.LightningChannel, numContracts)
+	settleSignals := make([]chan struct{}, numContracts)
+	for i := range contracts {
+		contracts[i] = &lnwallet.LightningChannel{
+			UnilateralClose: make(
+				chan *lnwallet.UnilateralCloseSummary, 1,
+			),
+			ContractBreach: make(
+				chan *lnwallet.BreachRetribution, 1,
+			),
+		}
+		settleSignals[i] = make(chan struct{})
+		pool.watch(contracts[i], settleSignals[i], nil, nil)
+	}
+	for i, w := range pool.workers {
+		if load := atomic.LoadInt32(&w.load); load != 3 {
+			t.Fatalf("worker %d has load %d, expected 3", i, load)
+		}
+	}
+
+	expectEvent := func(c *lnwallet.LightningChannel) observerEvent {
+		select {
+		case o := <-events:
+			if o.contract != c {
+				t.Fatalf("event delivered for wrong contract")
+			}
+			return o.event
+		case <-time.After(5 * time.Second):
+			t.Fatalf("event not delivered")
+		}
+		return observerEvent{}
+	}
+
+	// Settle the first contract, close the second, and breach the third.
+	close(settleSignals[0])
+	if e := expectEvent(contracts[0]); !e.settled {
+		t.Fatalf("expected settle event, got %v", e)
+	}
+
+	closeInfo := &lnwallet.UnilateralCloseSummary{}
+	contracts[1].UnilateralClose <- closeInfo
+	if e := expectEvent(contracts[1]); e.closeInfo != closeInfo {
+		t.Fatalf("expected close event, got %v", e)
+	}
+
+	breachInfo := &lnwallet.BreachRetribution{}
+	contracts[2].ContractBreach <- breachInfo
+	if e := expectEvent(contracts[2]); e.breachInfo != breachInfo {
+		t.Fatalf("expected breach event, got %v", e)
+	}
+
+	// Contracts which have fired should no longer be watched, so a
+	// subsequent breach of the settled contract must go unnoticed.
+	contracts[0].ContractBreach <- breachInfo
+	select {
+	case o := <-events:
+		t.Fatalf("unexpected event for unwatched contract: %v", o)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	var totalLoad int32
+	for _, w := range pool.workers {
+		totalLoad += atomic.LoadInt32(&w.load)
+	}
+	if totalLoad != numContracts-3 {
+		t.Fatalf("expected total load %d, got %d", numContracts-3,
+			totalLoad)
+	}
+}
+
+// TestObserverPoolWorkerLimit asserts that a worker's select stays within the
+// number of cases supported by reflect.Select, that contracts beyond a
+// worker's limit spill over into additional workers, and that each contract
+// remains watched as others are removed from its worker.
+func TestObserverPoolWorkerLimit(t *testing.T) {
+	t.Parallel()
+
+	numCases := numWorkerCases + numObserverCases*maxContractsPerWorker
+	if numCases > maxSelectCases {
+		t.Fatalf("worker selects over %d cases, exceeding the limit "+
+			"of %d", numCases, maxSelectCases)
+	}
+
+	events := make(chan *lnwallet.LightningChannel, 10)
+	handle := func(c *lnwallet.LightningChannel, _ observerEvent) {
+		events <- c
+	}
+
+	quit := make(chan struct{})
+	var wg sync.WaitGroup
+	pool := newObserverPool(1, handle, quit, &wg)
+	pool.maxContracts = 2
+	pool.start()
+	defer func() {
+		close(quit)
+		wg.Wait()
+	}()
+
+	// Watching five contracts, at most two per worker, requires two
+	// additional workers.
+	const numContracts = 5
+	contracts := make([]*lnwallet.LightningChannel, numContracts)
+	for i := range contracts {
+		contracts[i] = &lnwallet.LightningChannel{
+			UnilateralClose: make(
+				chan *lnwallet.UnilateralCloseSummary, 1,
+			),
+			ContractBreach: make(
+				chan *lnwallet.BreachRetribution, 1,
+			),
+		}
+		live := make(chan struct{})
+		pool.watch(contracts[i], make(chan struct{}), nil, live)
+		<-live
+	}
+
+	pool.mtx.Lock()
+	numWorkers := len(pool.workers)
+	var loads []int32
+	for _, w := range pool.workers {
+		loads = append(loads, atomic.LoadInt32(&w.load))
+	}
+	pool.mtx.Unlock()
+	if numWorkers != 3 {
+		t.Fatalf("expected 3 workers, found %d", numWorkers)
+	}
+	for i, load := range loads {
+		if load > 2 {
+			t.Fatalf("worker %d has load %d, exceeding limit", i,
+				load)
+		}
+	}
+
+	// Breach each contract in turn. Removing the first contract of a
+	// worker moves its last contract into its place, which must still be
+	// watched.
+	for _, c := range contracts {
+		c.ContractBreach <- &lnwallet.BreachRetribution{}
+
+		select {
+		case got := <-events:
+			if got != c {
+				t.Fatalf("event delivered for wrong contract")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("event not delivered")
+		}
+	}
+}
+
+// TestRetributionSweepScriptSerialization asserts that the sweep script chosen
+// for a justice transaction survives a round trip through serialization, and
+// that a rebuilt justice transaction reuses the persisted script.
+func TestRetributionSweepScriptSerialization(t *testing.T) {
+	ret := copyRetInfo(&retributions[0])
+	ret.breachDetectedAt = time.Unix(0, 1500000000123456789)
+	ret.sweepPkScript = bytes.Repeat([]byte{0x51}, 22)
+
+	var buf bytes.Buffer
+	if err := ret.Encode(&buf); err != nil {
+		t.Fatalf("unable to serialize retribution: %v", err)
+	}
+
+	desRet := &retributionInfo{}
+	if err := desRet.Decode(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("unable to deserialize retribution: %v", err)
+	}
+	if !bytes.Equal(desRet.sweepPkScript, ret.sweepPkScript) {
+		t.Fatalf("expected sweep script %x, got %x",
+			ret.sweepPkScript, desRet.sweepPkScript)
+	}
+
+	// A replacement justice transaction built from the restored
+	// retribution must pay to the persisted script, rather than to a
+	// fresh one.
+	brar := newTestBreachArbiter(&BreachConfig{
+		SweepScriptGen: func() ([]byte, error) {
+			return []byte{0x00, 0x14}, nil
+		},
+	})
+	pkScript, err := brar.justiceSweepScript(desRet, sweepTxReplacement)
+	if err != nil {
+		t.Fatalf("unable to obtain sweep script: %v", err)
+	}
+	if !bytes.Equal(pkScript, ret.sweepPkScript) {
+		t.Fatalf("expected replacement to pay to %x, got %x",
+			ret.sweepPkScript, pkScript)
+	}
+}
+
+// TestCheckSigningMaterial asserts that retributions lacking the material
+// required to sign for any of their breached outputs are detected up front,
+// and that the operator is notified of such unsweepable breaches.
+func TestCheckSigningMaterial(t *testing.T) {
+	newRet := func() *retributionInfo {
+		selfDesc := breachSignDescs[0]
+		revokedDesc := breachSignDescs[0]
+		revokedDesc.SingleTweak = nil
+		revokedDesc.DoubleTweak = alicePrivKey
+
+		return &retributionInfo{
+			selfOutput: &breachedOutput{
+				outpoint:       breachOutPoints[0],
+				signDescriptor: selfDesc,
+				witnessType:    lnwallet.CommitmentNoDelay,
+			},
+			revokedOutput: &breachedOutput{
+				outpoint:       breachOutPoints[1],
+				signDescriptor: revokedDesc,
+				witnessType:    lnwallet.CommitmentRevoke,
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*retributionInfo)
+		valid  bool
+	}{
+		{
+			name:   "complete",
+			mutate: func(*retributionInfo) {},
+			valid:  true,
+		},
+		{
+			name: "missing revocation secret",
+			mutate: func(r *retributionInfo) {
+				r.revokedOutput.signDescriptor.DoubleTweak = nil
+			},
+		},
+		{
+			name: "missing single tweak",
+			mutate: func(r *retributionInfo) {
+				r.selfOutput.signDescriptor.SingleTweak = nil
+			},
+		},
+		{
+			name: "missing public key",
+			mutate: func(r *retributionInfo) {
+				r.selfOutput.signDescriptor.PubKey = nil
+			},
+		},
+		{
+			name: "missing witness script",
+			mutate: func(r *retributionInfo) {
+				r.revokedOutput.signDescriptor.WitnessScript = nil
+			},
+		},
+		{
+			// An HTLC output lacking signing material is skipped
+			// by the justice transaction, rather than preventing
+			// the commitment outputs from being swept.
+			name: "missing htlc output",
+			mutate: func(r *retributionInfo) {
+				r.htlcOutputs = []*breachedOutput{{
+					outpoint:    breachOutPoints[2],
+					witnessType: lnwallet.CommitmentDelayOutput,
+				}}
+			},
+			valid: true,
+		},
+	}
+
+	for _, test := range tests {
+		ret := newRet()
+		test.mutate(ret)
+
+		err := ret.checkSigningMaterial()
+		if test.valid && err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Fatalf("%s: expected missing signing material to be "+
+				"detected", test.name)
+		}
+	}
+
+	// The operator should be notified of an unsweepable breach along with
+	// the txid of the breach transaction.
+	notified := make(chan *UnsweepableBreach, 1)
+	brar := newTestBreachArbiter(&BreachConfig{
+		Events: &mockBreachEvents{
+			unsweepableBreach: func(breach *UnsweepableBreach) {
+				notified <- breach
+			},
+		},
+	})
+
+	breach := &UnsweepableBreach{
+		ChanPoint:  breachOutPoints[0],
+		BreachTxid: retributions[0].commitHash,
+		Reason:     errors.New("missing revocation secret"),
+	}
+	brar.notifyUnsweepableBreach(breach)
+
+	select {
+	case got := <-notified:
+		if got != breach {
+			t.Fatalf("expected notification %v, got %v", breach,
+				got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("unsweepable breach notification not delivered")
+	}
+}
+
+// TestJusticeBumpSchedule asserts that the fee of an unconfirmed justice
+// transaction is bumped as each milestone of the bump schedule is reached, as
+// measured in blocks since its broadcast.
+func TestJusticeBumpSchedule(t *testing.T) {
+	schedule := []uint32{2, 4}
+	for elapsed, expTier := range []uint32{0, 0, 1, 1, 2, 2} {
+		tier := justiceBumpTier(schedule, uint32(elapsed))
+		if tier != expTier {
+			t.Fatalf("expected tier %v after %v blocks, got %v",
+				expTier, elapsed, tier)
+		}
+	}
+
+	notifier := &mockNotifier{
+		confChannel: make(chan *chainntnfs.TxConfirmation, 1),
+		epochChan:   make(chan *chainntnfs.BlockEpoch, 1),
+	}
+	published := make(chan *wire.MsgTx, 10)
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier: notifier,
+		Wallet:   newTestWallet(published),
+		SweepScriptGen: func() ([]byte, error) {
+			return []byte{0x00, 0x14}, nil
+		},
+		JusticeBumpSchedule: schedule,
+	})
+
+	ret := newBreachRetInfo()
+	totalAmt := ret.selfOutput.amt + ret.revokedOutput.amt
+
+	brar.wg.Add(1)
+	go brar.exactRetribution(
+		&chainntnfs.ConfirmationEvent{Confirmed: notifier.confChannel},
+		ret,
+	)
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	expectJustice := func(fee btcutil.Amount) {
+		select {
+		case tx := <-published:
+			value := btcutil.Amount(tx.TxOut[0].Value)
+			if value != totalAmt-fee {
+				t.Fatalf("expected justice tx paying fee %v, "+
+					"got %v", fee, totalAmt-value)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("justice tx not published")
+		}
+	}
+	expectNoJustice := func() {
+		select {
+		case tx := <-published:
+			t.Fatalf("unexpected justice tx published: %v",
+				tx.TxHash())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	connectBlock := func(elapsed int32) {
+		notifier.epochChan <- &chainntnfs.BlockEpoch{
+			Height: fundingBroadcastHeight + elapsed,
+		}
+	}
+
+	// Once the breach confirms, justice is broadcast at the base fee.
+	notifier.confChannel <- &chainntnfs.TxConfirmation{}
+	expectJustice(justiceBaseFee)
+
+	// A single block doesn't reach the first milestone.
+	connectBlock(1)
+	expectNoJustice()
+
+	// The second block reaches the first milestone, doubling the fee.
+	connectBlock(2)
+	expectJustice(2 * justiceBaseFee)
+
+	connectBlock(3)
+	expectNoJustice()
+
+	// Skipping past the final milestone bumps straight to the last tier.
+	connectBlock(5)
+	expectJustice(4 * justiceBaseFee)
+
+	connectBlock(6)
+	expectNoJustice()
+}
+
+// adjustableFeeEstimator is a fee estimator whose fee rate may be changed
+// while in use.
+type adjustableFeeEstimator struct {
+	lnwallet.StaticFeeEstimator
+
+	feePerWeight uint64
+}
+
+func (e *adjustableFeeEstimator) EstimateFeePerWeight(numBlocks uint32) uint64 {
+	return atomic.LoadUint64(&e.feePerWeight)
+}
+
+// TestJusticeEvictionRederivation asserts that a justice transaction evicted
+// from the mempool is re-derived at the current fee estimate on the next
+// block, rather than rebroadcast unchanged, and that the txid of the latest
+// justice transaction is persisted.
+func TestJusticeEvictionRederivation(t *testing.T) {
+	notifier := &mockNotifier{
+		confChannel: make(chan *chainntnfs.TxConfirmation, 1),
+		epochChan:   make(chan *chainntnfs.BlockEpoch, 1),
+	}
+	published := make(chan *wire.MsgTx, 10)
+	estimator := &adjustableFeeEstimator{feePerWeight: 10}
+	store := newMockRetributionStore()
+
+	var evicted int32
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier:  notifier,
+		Estimator: estimator,
+		Wallet:    newTestWallet(published),
+		Store:     store,
+		SweepScriptGen: func() ([]byte, error) {
+			return []byte{0x00, 0x14}, nil
+		},
+		TxInMempool: func(*chainhash.Hash) (bool, error) {
+			return atomic.LoadInt32(&evicted) == 0, nil
+		},
+	})
+
+	ret := newBreachRetInfo()
+	if err := store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+	inputs := []*breachedOutput{ret.selfOutput, ret.revokedOutput}
+	totalAmt := ret.selfOutput.amt + ret.revokedOutput.amt
+
+	brar.wg.Add(1)
+	go brar.exactRetribution(
+		&chainntnfs.ConfirmationEvent{Confirmed: notifier.confChannel},
+		ret,
+	)
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	expectJustice := func(feePerWeight uint64) *wire.MsgTx {
+		fee := btcutil.Amount(feePerWeight) *
+			btcutil.Amount(justiceTxWeight(inputs))
+		select {
+		case tx := <-published:
+			value := btcutil.Amount(tx.TxOut[0].Value)
+			if value != totalAmt-fee {
+				t.Fatalf("expected justice tx paying fee %v, "+
+					"got %v", fee, totalAmt-value)
+			}
+			return tx
+		case <-time.After(5 * time.Second):
+			t.Fatalf("justice tx not published")
+		}
+		return nil
+	}
+	expectPersisted := func(justiceTx *wire.MsgTx) {
+		err := store.ForAll(func(r *retributionInfo) error {
+			if r.justiceTxid != justiceTx.TxHash() {
+				return fmt.Errorf("expected justice txid %v, "+
+					"got %v", justiceTx.TxHash(),
+					r.justiceTxid)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	notifier.confChannel <- &chainntnfs.TxConfirmation{}
+	justiceTx := expectJustice(10)
+	expectPersisted(justiceTx)
+
+	// While the justice transaction remains within the mempool, new
+	// blocks leave it be.
+	notifier.epochChan <- &chainntnfs.BlockEpoch{
+		Height: fundingBroadcastHeight + 1,
+	}
+	select {
+	case tx := <-published:
+		t.Fatalf("unexpected justice tx published: %v", tx.TxHash())
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Once it has been evicted, the next block re-derives it at the
+	// current fee estimate.
+	atomic.StoreInt32(&evicted, 1)
+	atomic.StoreUint64(&estimator.feePerWeight, 25)
+	notifier.epochChan <- &chainntnfs.BlockEpoch{
+		Height: fundingBroadcastHeight + 2,
+	}
+	rederivedTx := expectJustice(25)
+	if rederivedTx.TxHash() == justiceTx.TxHash() {
+		t.Fatalf("expected re-derived justice tx to differ")
+	}
+
+	// The re-derived txid is persisted before the retribution handles
+	// its next block. As the epoch channel holds a single block, the
+	// second of these is only delivered once the first has been taken.
+	atomic.StoreInt32(&evicted, 0)
+	for i := int32(3); i <= 4; i++ {
+		notifier.epochChan <- &chainntnfs.BlockEpoch{
+			Height: fundingBroadcastHeight + i,
+		}
+	}
+	expectPersisted(rederivedTx)
+}
+
+// TestJusticeOutbidsMempoolRival asserts that a justice transaction is
+// replaced at the lowest tier outbidding a competing transaction found within
+// the mempool, and is left alone once it no longer faces competition.
+func TestJusticeOutbidsMempoolRival(t *testing.T) {
+	defer func(interval time.Duration) {
+		justiceRacePollInterval = interval
+	}(justiceRacePollInterval)
+	justiceRacePollInterval = 10 * time.Millisecond
+
+	notifier := &mockNotifier{
+		confChannel: make(chan *chainntnfs.TxConfirmation, 1),
+	}
+	published := make(chan *wire.MsgTx, 10)
+
+	var (
+		rivalMtx sync.Mutex
+		rival    *MempoolSpend
+	)
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier: notifier,
+		Wallet:   newTestWallet(published),
+		SweepScriptGen: func() ([]byte, error) {
+			return []byte{0x00, 0x14}, nil
+		},
+		MempoolSpends: func([]wire.OutPoint) ([]MempoolSpend, error) {
+			rivalMtx.Lock()
+			defer rivalMtx.Unlock()
+
+			if rival == nil {
+				return nil, nil
+			}
+			return []MempoolSpend{*rival}, nil
+		},
+		JusticeFeeRace: true,
+	})
+
+	ret := newBreachRetInfo()
+	totalAmt := ret.selfOutput.amt + ret.revokedOutput.amt
+
+	brar.wg.Add(1)
+	go brar.exactRetribution(
+		&chainntnfs.ConfirmationEvent{Confirmed: notifier.confChannel},
+		ret,
+	)
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	expectJustice := func(fee btcutil.Amount) *wire.MsgTx {
+		select {
+		case tx := <-published:
+			value := btcutil.Amount(tx.TxOut[0].Value)
+			if value != totalAmt-fee {
+				t.Fatalf("expected justice tx paying fee %v, "+
+					"got %v", fee, totalAmt-value)
+			}
+			return tx
+		case <-time.After(5 * time.Second):
+			t.Fatalf("justice tx not published")
+		}
+		return nil
+	}
+	expectNoJustice := func() {
+		select {
+		case tx := <-published:
+			t.Fatalf("unexpected justice tx published: %v",
+				tx.TxHash())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	notifier.confChannel <- &chainntnfs.TxConfirmation{}
+	justiceTx := expectJustice(justiceBaseFee)
+	weight := blockchain.GetTransactionWeight(btcutil.NewTx(justiceTx))
+
+	// A rival paying less than the justice transaction is ignored.
+	rivalMtx.Lock()
+	rival = &MempoolSpend{
+		Txid:   chainhash.Hash{0x99},
+		Fee:    justiceBaseFee / 2,
+		Weight: weight,
+	}
+	rivalMtx.Unlock()
+	expectNoJustice()
+
+	// Once the rival pays three times as much, the fee must be quadrupled
+	// to outbid it.
+	rivalMtx.Lock()
+	rival.Fee = 3 * justiceBaseFee
+	rivalMtx.Unlock()
+	expectJustice(4 * justiceBaseFee)
+
+	// Having outbid the rival, the justice transaction isn't replaced
+	// again.
+	expectNoJustice()
+}
+
+// TestJusticeConfTimeoutEscalates asserts that a retribution whose justice
+// transaction fails to confirm within JusticeConfTimeout is flagged for manual
+// intervention, ceases to replace its justice transaction, and remains
+// persisted.
+func TestJusticeConfTimeoutEscalates(t *testing.T) {
+	defer func(interval time.Duration) {
+		justiceRacePollInterval = interval
+	}(justiceRacePollInterval)
+	justiceRacePollInterval = 10 * time.Millisecond
+
+	notifier := &mockNotifier{
+		confChannel: make(chan *chainntnfs.TxConfirmation, 1),
+	}
+	published := make(chan *wire.MsgTx, 10)
+	diags := make(chan *RetributionDiagnostic, 1)
+
+	var racing int32
+	store := newMockRetributionStore()
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier: notifier,
+		Wallet:   newTestWallet(published),
+		Store:    store,
+		SweepScriptGen: func() ([]byte, error) {
+			return []byte{0x00, 0x14}, nil
+		},
+		// Once enabled, a rival always outbids the justice
+		// transaction, which would cause it to be replaced unless
+		// the retribution has been escalated.
+		MempoolSpends: func([]wire.OutPoint) ([]MempoolSpend, error) {
+			if atomic.LoadInt32(&racing) == 0 {
+				return nil, nil
+			}
+			return []MempoolSpend{{
+				Txid:   chainhash.Hash{0x99},
+				Fee:    justiceBaseFee * 1000,
+				Weight: 1,
+			}}, nil
+		},
+		JusticeFeeRace:     true,
+		JusticeConfTimeout: 50 * time.Millisecond,
+		Events: &mockBreachEvents{
+			manualIntervention: func(diag *RetributionDiagnostic) {
+				diags <- diag
+			},
+		},
+	})
+
+	ret := newBreachRetInfo()
+	if err := store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+
+	brar.wg.Add(1)
+	go brar.exactRetribution(
+		&chainntnfs.ConfirmationEvent{Confirmed: notifier.confChannel},
+		ret,
+	)
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	notifier.confChannel <- &chainntnfs.TxConfirmation{}
+	var justiceTx *wire.MsgTx
+	select {
+	case justiceTx = <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("justice tx not published")
+	}
+
+	var diag *RetributionDiagnostic
+	select {
+	case diag = <-diags:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("manual intervention not requested")
+	}
+	if diag.ChanPoint != ret.chanPoint ||
+		diag.Phase != retPhaseNeedsIntervention.String() ||
+		diag.BroadcastAttempts != 1 {
+
+		t.Fatalf("unexpected diagnostic: %v", spew.Sdump(diag))
+	}
+	if ret.justiceTxid != justiceTx.TxHash() {
+		t.Fatalf("expected justice txid %v, got %v",
+			justiceTx.TxHash(), ret.justiceTxid)
+	}
+
+	// The justice transaction must no longer be replaced, even though a
+	// rival now outbids it.
+	atomic.StoreInt32(&racing, 1)
+	select {
+	case tx := <-published:
+		t.Fatalf("unexpected justice tx published: %v", tx.TxHash())
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	health, err := brar.HealthCheck()
+	if err != nil {
+		t.Fatalf("unable to check health: %v", err)
+	}
+	if health.Healthy() || len(health.InterventionRetributions) != 1 ||
+		health.InterventionRetributions[0] != ret.chanPoint ||
+		health.PendingRetributions != 1 {
+
+		t.Fatalf("unexpected health: %v", spew.Sdump(health))
+	}
+}
+
+// failingPublisher is a broadcaster and wallet controller which fails to
+// publish any transaction.
+type failingPublisher struct {
+	mockWalletController
+
+	err error
+}
+
+func (f *failingPublisher) PublishTransaction(tx *wire.MsgTx) error {
+	return f.err
+}
+
+// TestPublishJusticeFanOut asserts that justice transactions are broadcast to
+// every target, that a failing target doesn't prevent broadcasting to the
+// others, that a transaction accepted by any target is considered broadcast,
+// and that the outcome for each target is tracked.
+func TestPublishJusticeFanOut(t *testing.T) {
+	received := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			received <- string(body)
+		},
+	))
+	defer server.Close()
+
+	walletErr := errors.New("wallet unreachable")
+	targetErr := errors.New("target unreachable")
+	brar := newTestBreachArbiter(&BreachConfig{
+		Wallet: &lnwallet.LightningWallet{
+			WalletController: &failingPublisher{err: walletErr},
+		},
+		BroadcastTargets: []BroadcastTarget{
+			{
+				Name:        "failing",
+				Broadcaster: &failingPublisher{err: targetErr},
+			},
+			{
+				Name:        "http",
+				Broadcaster: newHTTPBroadcaster(server.URL),
+			},
+		},
+	})
+
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(&wire.TxIn{PreviousOutPoint: breachOutPoints[0]})
+	tx.AddTxOut(&wire.TxOut{Value: 1000, PkScript: []byte{0x00, 0x14}})
+
+	// Although both the wallet and the first target fail, the HTTP target
+	// accepts the transaction.
+	if err := brar.publishJustice(tx); err != nil {
+		t.Fatalf("unable to publish justice tx: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		t.Fatalf("unable to serialize tx: %v", err)
+	}
+	select {
+	case body := <-received:
+		if body != hex.EncodeToString(buf.Bytes()) {
+			t.Fatalf("unexpected tx received: %v", body)
+		}
+	default:
+		t.Fatalf("tx not broadcast to http target")
+	}
+
+	stats := brar.Stats().BroadcastTargets
+	expStats := map[string]BroadcastTargetStats{
+		walletBroadcastTarget: {Rejected: 1, LastErr: walletErr},
+		"failing":             {Rejected: 1, LastErr: targetErr},
+		"http":                {Accepted: 1},
+	}
+	if !reflect.DeepEqual(stats, expStats) {
+		t.Fatalf("expected target stats %v, got %v", expStats, stats)
+	}
+
+	// Once every target fails, the wallet's error is returned.
+	server.Close()
+	if err := brar.publishJustice(tx); err != walletErr {
+		t.Fatalf("expected wallet error, got %v", err)
+	}
+	httpStats := brar.Stats().BroadcastTargets["http"]
+	if httpStats.Rejected != 1 {
+		t.Fatalf("expected http target to reject 1 tx, got %v",
+			httpStats.Rejected)
+	}
+}
+
+// TestRecoverBreachFromTx asserts that a breach is only recovered from a
+// transaction which is a revoked commitment of the backed up channel.
+func TestRecoverBreachFromTx(t *testing.T) {
+	brar := newTestBreachArbiter(&BreachConfig{})
+
+	backup := &channeldb.ChannelBackup{
+		FundingOutpoint: breachOutPoints[0],
+		IdentityPub:     alicePrivKey.PubKey(),
+	}
+
+	breachTx := wire.NewMsgTx(2)
+	breachTx.AddTxIn(&wire.TxIn{PreviousOutPoint: breachOutPoints[1]})
+
+	_, err := brar.RecoverBreachFromTx(breachTx, backup)
+	if err != lnwallet.ErrNotRevokedCommitment {
+		t.Fatalf("expected ErrNotRevokedCommitment, got %v", err)
+	}
+}
+
+// testBreachResolved asserts that the breach of a channel is deemed
+// resolved, and thus can't be recovered again, once the channel has been fully
+// closed or its breach archived.
+func testBreachResolved(h *breachTestHarness, t *testing.T) {
+	brar := newTestBreachArbiter(&BreachConfig{
+		DB:    h.db,
+		Store: h.store,
+	})
+
+	assertResolved := func(chanPoint *wire.OutPoint, expected bool) {
+		resolved, err := brar.breachResolved(chanPoint)
+		if err != nil {
+			t.Fatalf("unable to query resolution: %v", err)
+		}
+		if resolved != expected {
+			t.Fatalf("expected ChannelPoint(%v) resolved: %v, "+
+				"got %v", chanPoint, expected, resolved)
+		}
+	}
+
+	// A breach whose retribution was archived is resolved.
+	ret := copyRetInfo(&retributions[0])
+	assertResolved(&ret.chanPoint, false)
+	err := h.store.Archive(&ArchivedBreach{retribution: ret})
+	if err != nil {
+		t.Fatalf("unable to archive breach: %v", err)
+	}
+	assertResolved(&ret.chanPoint, true)
+
+	// A channel that's still pending close may yet be breached, but once
+	// fully closed, it can't be.
+	chanPoint := *h.alice.ChannelPoint()
+	closeTestChannel(
+		t, h.alice, chainhash.Hash{0x01}, channeldb.BreachClose,
+	)
+	assertResolved(&chanPoint, false)
+
+	if err := h.db.MarkChanFullyClosed(&chanPoint); err != nil {
+		t.Fatalf("unable to mark channel closed: %v", err)
+	}
+	assertResolved(&chanPoint, true)
+}
+
+// testBreachRemedyKit asserts that the breach remedy kit exported for a channel
+// survives serialization, and that combined with the revocation secrets it
+// yields a backup of the channel suitable for breach recovery.
+func testBreachRemedyKit(h *breachTestHarness, t *testing.T) {
+	brar := newTestBreachArbiter(&BreachConfig{
+		DB: h.db,
+	})
+
+	kit, err := brar.ExportBreachRemedyKit(h.alice.ChannelPoint())
+	if err != nil {
+		t.Fatalf("unable to export breach remedy kit: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := kit.Encode(&buf); err != nil {
+		t.Fatalf("unable to serialize kit: %v", err)
+	}
+	desKit := &BreachRemedyKit{}
+	if err := desKit.Decode(&buf); err != nil {
+		t.Fatalf("unable to deserialize kit: %v", err)
+	}
+
+	// The backup assembled from the restored kit must match that of the
+	// channel in every field used to recover a breach.
+	aliceState := h.alice.StateSnapshot()
+	channels, err := h.db.FetchAllChannels()
+	if err != nil {
+		t.Fatalf("unable to fetch channels: %v", err)
+	}
+	var expBackup *channeldb.ChannelBackup
+	for _, channel := range channels {
+		if channel.FundingOutpoint == *aliceState.ChannelPoint {
+			expBackup = channel.Backup()
+		}
+	}
+	if expBackup == nil {
+		t.Fatalf("channel not found")
+	}
+
+	backup := desKit.Backup(expBackup.RevocationStore)
+	switch {
+	case backup.ChainHash != expBackup.ChainHash:
+		t.Fatalf("chain hash mismatch")
+	case backup.FundingOutpoint != expBackup.FundingOutpoint:
+		t.Fatalf("funding outpoint mismatch")
+	case backup.IsInitiator != expBackup.IsInitiator:
+		t.Fatalf("initiator mismatch")
+	case backup.Capacity != expBackup.Capacity:
+		t.Fatalf("capacity mismatch")
+	case backup.RemoteChanCfg.CsvDelay != expBackup.RemoteChanCfg.CsvDelay:
+		t.Fatalf("csv delay mismatch")
+	case backup.RevocationStore != expBackup.RevocationStore:
+		t.Fatalf("revocation store mismatch")
+	}
+
+	keys := []struct {
+		name     string
+		key, exp *btcec.PublicKey
+	}{
+		{"identity", backup.IdentityPub, expBackup.IdentityPub},
+		{
+			"local payment base point",
+			backup.LocalChanCfg.PaymentBasePoint,
+			expBackup.LocalChanCfg.PaymentBasePoint,
+		},
+		{
+			"local revocation base point",
+			backup.LocalChanCfg.RevocationBasePoint,
+			expBackup.LocalChanCfg.RevocationBasePoint,
+		},
+		{
+			"remote payment base point",
+			backup.RemoteChanCfg.PaymentBasePoint,
+			expBackup.RemoteChanCfg.PaymentBasePoint,
+		},
+		{
+			"remote delay base point",
+			backup.RemoteChanCfg.DelayBasePoint,
+			expBackup.RemoteChanCfg.DelayBasePoint,
+		},
+	}
+	for _, k := range keys {
+		if !k.key.IsEqual(k.exp) {
+			t.Fatalf("%s mismatch", k.name)
+		}
+	}
+
+	// No state of the channel has been revoked yet, so a commitment
+	// spending its funding output can't be recovered as a breach.
+	breachTx := wire.NewMsgTx(2)
+	breachTx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: *aliceState.ChannelPoint,
+	})
+	_, err = brar.RecoverBreachFromKit(
+		breachTx, desKit, expBackup.RevocationStore,
+	)
+	if err != lnwallet.ErrNotRevokedCommitment {
+		t.Fatalf("expected ErrNotRevokedCommitment, got %v", err)
+	}
+}
+
+// TestRetributionStoreRecover asserts that an undecodable retribution is
+// skipped by ForAll, and moved into quarantine by Recover, while leaving the
+// remaining retributions untouched.
+func TestRetributionStoreRecover(t *testing.T) {
+	tempDirName, err := ioutil.TempDir("", "channeldb")
+	if err != nil {
+		t.Fatalf("unable to initialize temp "+
+			"directory for channeldb: %v", err)
+	}
+	defer os.RemoveAll(tempDirName)
+
+	db, err := channeldb.Open(tempDirName)
+	if err != nil {
+		t.Fatalf("unable to open channeldb: %v", err)
+	}
+	defer db.Close()
+
+	store := newRetributionStore(db)
+
+	ret := copyRetInfo(&retributions[0])
+	if err := store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+
+	// Write a truncated retribution under the key of another channel, as
+	// would be left behind by a partial write.
+	corruptChanPoint := ret.chanPoint
+	corruptChanPoint.Index++
+
+	var corruptKey bytes.Buffer
+	if err := writeOutpoint(&corruptKey, &corruptChanPoint); err != nil {
+		t.Fatalf("unable to write outpoint: %v", err)
+	}
+	corruptRet := []byte{0x01, 0x02, 0x03}
+	err = db.Update(func(tx *bolt.Tx) error {
+		retBucket := tx.Bucket(retributionBucket)
+		return retBucket.Put(corruptKey.Bytes(), corruptRet)
+	})
+	if err != nil {
+		t.Fatalf("unable to write corrupt retribution: %v", err)
+	}
+
+	// The corrupt retribution mustn't prevent the valid one from being
+	// iterated over.
+	if count := countRetributions(t, store); count != 1 {
+		t.Fatalf("expected 1 retribution, found %v", count)
+	}
+
+	recovery, err := store.Recover()
+	if err != nil {
+		t.Fatalf("unable to recover store: %v", err)
+	}
+	if recovery.BucketErr != nil {
+		t.Fatalf("unexpected bucket error: %v", recovery.BucketErr)
+	}
+	if len(recovery.Quarantined) != 1 {
+		t.Fatalf("expected 1 quarantined retribution, got %v",
+			len(recovery.Quarantined))
+	}
+	q := recovery.Quarantined[0]
+	if !bytes.Equal(q.Key, corruptKey.Bytes()) {
+		t.Fatalf("expected quarantined key %x, got %x",
+			corruptKey.Bytes(), q.Key)
+	}
+	if q.ChanPoint == nil || *q.ChanPoint != corruptChanPoint {
+		t.Fatalf("expected quarantined chan point %v, got %v",
+			corruptChanPoint, q.ChanPoint)
+	}
+	if q.Err == nil {
+		t.Fatalf("expected decode error to be reported")
+	}
+
+	// The raw retribution must have been preserved within the quarantine,
+	// and removed from the retribution bucket.
+	err = db.View(func(tx *bolt.Tx) error {
+		quarantineBucket := tx.Bucket(retributionQuarantineBucket)
+		if quarantineBucket == nil {
+			return errors.New("quarantine bucket not created")
+		}
+		raw := quarantineBucket.Get(corruptKey.Bytes())
+		if !bytes.Equal(raw, corruptRet) {
+			return fmt.Errorf("expected quarantined retribution "+
+				"%x, got %x", corruptRet, raw)
+		}
+
+		retBucket := tx.Bucket(retributionBucket)
+		if retBucket.Get(corruptKey.Bytes()) != nil {
+			return errors.New("corrupt retribution not removed")
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if count := countRetributions(t, store); count != 1 {
+		t.Fatalf("expected 1 retribution, found %v", count)
+	}
+
+	// A subsequent recovery has nothing left to quarantine.
+	recovery, err = store.Recover()
+	if err != nil {
+		t.Fatalf("unable to recover store: %v", err)
+	}
+	if len(recovery.Quarantined) != 0 {
+		t.Fatalf("expected no quarantined retributions, got %v",
+			len(recovery.Quarantined))
+	}
+}
+
+// TestFinalizeRetainsRetributionOnCloseFailure asserts that a retribution is
+// only removed from the store once its channel has been marked as fully
+// closed, so that a failure to do so can be reconciled on restart.
+func TestFinalizeRetainsRetributionOnCloseFailure(t *testing.T) {
+	tempDirName, err := ioutil.TempDir("", "channeldb")
+	if err != nil {
+		t.Fatalf("unable to initialize temp "+
+			"directory for channeldb: %v", err)
+	}
+	defer os.RemoveAll(tempDirName)
+
+	db, err := channeldb.Open(tempDirName)
+	if err != nil {
+		t.Fatalf("unable to open channeldb: %v", err)
+	}
+	defer db.Close()
+
+	// Speed up the retries of the close marking.
+	defer func(backoff time.Duration) {
+		markChanClosedBackoff = backoff
+	}(markChanClosedBackoff)
+	markChanClosedBackoff = time.Millisecond
+
+	store := newMockRetributionStore()
+	pruner := &recordingBackupPruner{
+		pruned: make(chan wire.OutPoint, 1),
+	}
+	brar := newTestBreachArbiter(&BreachConfig{
+		DB:           db,
+		Store:        store,
+		BackupPruner: pruner,
+	})
+
+	ret := newBreachRetInfo()
+	if err := store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+
+	// The database holds no close summary for the channel, so marking it
+	// as fully closed fails.
+	brar.finalizeRetribution(ret, time.Time{})
+
+	// As the channel wasn't resolved, its backup must be retained.
+	select {
+	case chanPoint := <-pruner.pruned:
+		t.Fatalf("backup of unresolved ChannelPoint(%v) pruned",
+			chanPoint)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	var numRets int
+	err = store.ForAll(func(*retributionInfo) error {
+		numRets++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unable to iterate retributions: %v", err)
+	}
+	if numRets != 1 {
+		t.Fatalf("expected retribution to be retained, found %d",
+			numRets)
+	}
+}
+
+// TestJusticeConfHeightRecorded asserts that the height at which the justice
+// transaction confirms is recorded within the retribution as it's finalized.
+func TestJusticeConfHeightRecorded(t *testing.T) {
+	tempDirName, err := ioutil.TempDir("", "channeldb")
+	if err != nil {
+		t.Fatalf("unable to initialize temp "+
+			"directory for channeldb: %v", err)
+	}
+	defer os.RemoveAll(tempDirName)
+
+	db, err := channeldb.Open(tempDirName)
+	if err != nil {
+		t.Fatalf("unable to open channeldb: %v", err)
+	}
+	defer db.Close()
+
+	defer func(backoff time.Duration) {
+		markChanClosedBackoff = backoff
+	}(markChanClosedBackoff)
+	markChanClosedBackoff = time.Millisecond
+
+	notifier := &mockNotifier{
+		confChannel: make(chan *chainntnfs.TxConfirmation, 1),
+	}
+	published := make(chan *wire.MsgTx, 10)
+	brar := newTestBreachArbiter(&BreachConfig{
+		DB:       db,
+		Notifier: notifier,
+		Wallet:   newTestWallet(published),
+		SweepScriptGen: func() ([]byte, error) {
+			return []byte{0x00, 0x14}, nil
+		},
+	})
+
+	ret := newBreachRetInfo()
+	ret.doneChan = make(chan struct{})
+
+	// The breach confirmation is delivered separately from that of the
+	// justice transaction, which is delivered via the notifier.
+	breachConf := make(chan *chainntnfs.TxConfirmation, 1)
+
+	brar.wg.Add(1)
+	go brar.exactRetribution(
+		&chainntnfs.ConfirmationEvent{Confirmed: breachConf}, ret,
+	)
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	breachConf <- &chainntnfs.TxConfirmation{BlockHeight: 499}
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("justice tx not published")
+	}
+
+	const confHeight = 500
+	notifier.confChannel <- &chainntnfs.TxConfirmation{
+		BlockHeight: confHeight,
+	}
+	select {
+	case <-ret.doneChan:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("retribution not finalized")
+	}
+
+	if ret.justiceConfHeight != confHeight {
+		t.Fatalf("expected justice conf height %v, got %v",
+			confHeight, ret.justiceConfHeight)
+	}
+}
+
+// recordingFeeEstimator is a fee estimator returning a static fee rate, which
+// records the confirmation targets it is queried for.
+type recordingFeeEstimator struct {
+	lnwallet.StaticFeeEstimator
+
+	targets chan uint32
+}
+
+func (e *recordingFeeEstimator) EstimateFeePerWeight(numBlocks uint32) uint64 {
+	e.targets <- numBlocks
+	return e.StaticFeeEstimator.EstimateFeePerWeight(numBlocks)
+}
+
+// recordingNotifier is a mock notifier which records the number of
+// confirmations requested by each confirmation registration.
+type recordingNotifier struct {
+	mockNotifier
+
+	numConfs chan uint32
+}
+
+func (n *recordingNotifier) RegisterConfirmationsNtfn(txid *chainhash.Hash,
+	numConfs, heightHint uint32) (*chainntnfs.ConfirmationEvent, error) {
+
+	n.numConfs <- numConfs
+	return n.mockNotifier.RegisterConfirmationsNtfn(
+		txid, numConfs, heightHint,
+	)
+}
+
+// TestJusticeConfTargets asserts that the justice transaction's fee is
+// estimated for the configured fee target, that the estimated weight covers
+// the actual weight of the transaction, and that its confirmation is awaited
+// at the configured depth.
+func TestJusticeConfTargets(t *testing.T) {
+	const (
+		justiceConfDepth = 3
+		justiceFeeTarget = 2
+	)
+
+	estimator := &recordingFeeEstimator{
+		StaticFeeEstimator: lnwallet.StaticFeeEstimator{FeeRate: 40},
+		targets:            make(chan uint32, 10),
+	}
+	notifier := &recordingNotifier{
+		mockNotifier: mockNotifier{
+			confChannel: make(chan *chainntnfs.TxConfirmation, 1),
+		},
+		numConfs: make(chan uint32, 10),
+	}
+	published := make(chan *wire.MsgTx, 10)
+	brar := newTestBreachArbiter(&BreachConfig{
+		Estimator: estimator,
+		Notifier:  notifier,
+		Wallet:    newTestWallet(published),
+		SweepScriptGen: func() ([]byte, error) {
+			return make([]byte, lnwallet.P2WPKHSize), nil
+		},
+		JusticeConfDepth: justiceConfDepth,
+		JusticeFeeTarget: justiceFeeTarget,
+	})
+	if brar.cfg.BreachConfDepth != defaultBreachConfDepth {
+		t.Fatalf("expected default breach conf depth %v, got %v",
+			defaultBreachConfDepth, brar.cfg.BreachConfDepth)
+	}
+
+	ret := newBreachRetInfo()
+	totalAmt := ret.selfOutput.amt + ret.revokedOutput.amt
+
+	brar.wg.Add(1)
+	go brar.exactRetribution(
+		&chainntnfs.ConfirmationEvent{Confirmed: notifier.confChannel},
+		ret,
+	)
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+	notifier.confChannel <- &chainntnfs.TxConfirmation{}
+
+	var justiceTx *wire.MsgTx
+	select {
+	case justiceTx = <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("justice tx not published")
+	}
+
+	select {
+	case target := <-estimator.targets:
+		if target != justiceFeeTarget {
+			t.Fatalf("expected fee target %v, got %v",
+				justiceFeeTarget, target)
+		}
+	default:
+		t.Fatalf("fee of justice tx not estimated")
+	}
+
+	// The fee paid must be that of the estimated weight, which shouldn't
+	// fall short of the transaction's actual weight.
+	inputs := []*breachedOutput{ret.selfOutput, ret.revokedOutput}
+	estWeight := justiceTxWeight(inputs)
+	actualWeight := blockchain.GetTransactionWeight(
+		btcutil.NewTx(justiceTx),
+	)
+	if estWeight < actualWeight {
+		t.Fatalf("estimated weight %v below actual weight %v",
+			estWeight, actualWeight)
+	}
+
+	feePerWeight := btcutil.Amount(estimator.FeeRate / 4)
+	expFee := feePerWeight * btcutil.Amount(estWeight)
+	fee := totalAmt - btcutil.Amount(justiceTx.TxOut[0].Value)
+	if fee != expFee {
+		t.Fatalf("expected fee %v, got %v", expFee, fee)
+	}
+
+	select {
+	case numConfs := <-notifier.numConfs:
+		if numConfs != justiceConfDepth {
+			t.Fatalf("expected justice conf depth %v, got %v",
+				justiceConfDepth, numConfs)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("justice conf not registered")
+	}
+}
+
+// TestJusticeConfTargetDeadline asserts that the fee of a justice transaction
+// is only estimated for the urgent, configured target when the breaching
+// party can race us for its inputs, and is otherwise relaxed according to the
+// CSV delay protecting the revoked output.
+func TestJusticeConfTargetDeadline(t *testing.T) {
+	const justiceFeeTarget = 2
+
+	selfOutput := &breachedOutput{witnessType: lnwallet.CommitmentNoDelay}
+	revoked := func(delay uint32) *breachedOutput {
+		return &breachedOutput{
+			witnessType:  lnwallet.CommitmentRevoke,
+			contestDelay: delay,
+		}
+	}
+	htlcOutput := &breachedOutput{
+		witnessType: lnwallet.HtlcAcceptedRemoteSuccess,
+	}
+
+	tests := []struct {
+		name            string
+		breachConfDepth uint32
+		inputs          []*breachedOutput
+		expTarget       uint32
+	}{
+		{
+			name:            "revoked output without delay",
+			breachConfDepth: 1,
+			inputs:          []*breachedOutput{selfOutput, revoked(0)},
+			expTarget:       justiceFeeTarget,
+		},
+		{
+			name:            "revoked output with delay",
+			breachConfDepth: 1,
+			inputs:          []*breachedOutput{selfOutput, revoked(144)},
+			expTarget:       72,
+		},
+		{
+			name:            "delay partially elapsed",
+			breachConfDepth: 21,
+			inputs:          []*breachedOutput{selfOutput, revoked(144)},
+			expTarget:       62,
+		},
+		{
+			name:            "delay elapsed",
+			breachConfDepth: 200,
+			inputs:          []*breachedOutput{selfOutput, revoked(144)},
+			expTarget:       justiceFeeTarget,
+		},
+		{
+			name:            "short delay",
+			breachConfDepth: 1,
+			inputs:          []*breachedOutput{selfOutput, revoked(3)},
+			expTarget:       justiceFeeTarget,
+		},
+		{
+			name:            "long delay",
+			breachConfDepth: 1,
+			inputs:          []*breachedOutput{selfOutput, revoked(2016)},
+			expTarget:       maxRelaxedJusticeFeeTarget,
+		},
+		{
+			name:            "racing htlc output",
+			breachConfDepth: 1,
+			inputs: []*breachedOutput{
+				selfOutput, revoked(144), htlcOutput,
+			},
+			expTarget: justiceFeeTarget,
+		},
+		{
+			name:            "uncontested",
+			breachConfDepth: 1,
+			inputs:          []*breachedOutput{selfOutput},
+			expTarget:       maxRelaxedJusticeFeeTarget,
+		},
+	}
+
+	for _, test := range tests {
+		brar := newTestBreachArbiter(&BreachConfig{
+			BreachConfDepth:  test.breachConfDepth,
+			JusticeFeeTarget: justiceFeeTarget,
+		})
+
+		target := brar.justiceConfTarget(test.inputs)
+		if target != test.expTarget {
+			t.Fatalf("%s: expected target %v, got %v", test.name,
+				test.expTarget, target)
+		}
+	}
+
+	// The revoked output's delay must survive a round trip through
+	// serialization.
+	ret := copyRetInfo(&retributions[0])
+	revokedOutput := *ret.revokedOutput
+	revokedOutput.contestDelay = 144
+	ret.revokedOutput = &revokedOutput
+
+	var buf bytes.Buffer
+	if err := ret.Encode(&buf); err != nil {
+		t.Fatalf("unable to serialize retribution: %v", err)
+	}
+	desRet := &retributionInfo{}
+	if err := desRet.Decode(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("unable to deserialize retribution: %v", err)
+	}
+	if desRet.revokedOutput.contestDelay != 144 {
+		t.Fatalf("expected delay 144, got %v",
+			desRet.revokedOutput.contestDelay)
+	}
+}
+
+// TestDumpRetribution asserts that the diagnostic report of a retribution
+// reflects its persisted state, the live status of its outputs and the
+// progress of its broadcasts.
+func TestDumpRetribution(t *testing.T) {
+	retInfo := newBreachRetInfo()
+
+	chainIO := &breachChainIO{
+		utxos: map[wire.OutPoint]*wire.TxOut{
+			retInfo.selfOutput.outpoint: {
+				Value: int64(retInfo.selfOutput.amt),
+			},
+		},
+	}
+	store := newMockRetributionStore()
+	if err := store.Add(retInfo, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+	brar := newTestBreachArbiter(&BreachConfig{
+		ChainIO: chainIO,
+		Store:   store,
+	})
+
+	if _, err := brar.DumpRetribution(&breachOutPoints[1]); err == nil {
+		t.Fatalf("expected error dumping unknown retribution")
+	}
+
+	// A retribution that isn't active should only report its persisted
+	// state.
+	diag, err := brar.DumpRetribution(&retInfo.chanPoint)
+	if err != nil {
+		t.Fatalf("unable to dump retribution: %v", err)
+	}
+	if diag.Active || diag.BreachTxid != retInfo.commitHash {
+		t.Fatalf("unexpected diagnostic: %v", spew.Sdump(diag))
+	}
+	if len(diag.Outputs) != 2 {
+		t.Fatalf("expected 2 outputs, got %v", len(diag.Outputs))
+	}
+
+	selfDiag, revokedDiag := diag.Outputs[0], diag.Outputs[1]
+	if selfDiag.OutPoint != retInfo.selfOutput.outpoint ||
+		selfDiag.Amount != retInfo.selfOutput.amt ||
+		selfDiag.WitnessType != retInfo.selfOutput.witnessType.String() {
+
+		t.Fatalf("unexpected self output: %v", spew.Sdump(selfDiag))
+	}
+	if selfDiag.Spent || selfDiag.LookupError != "" {
+		t.Fatalf("expected self output to be unspent")
+	}
+	if !revokedDiag.Spent || revokedDiag.LookupError == "" {
+		t.Fatalf("expected revoked output lookup to fail")
+	}
+
+	// Once active, the phase and broadcast progress should be reported.
+	brar.setRetributionPhase(
+		&retInfo.chanPoint, retPhaseAwaitingJusticeConf,
+	)
+	brar.recordBroadcastAttempt(
+		[]wire.OutPoint{retInfo.chanPoint}, errors.New("rejected"),
+	)
+	brar.recordBroadcastAttempt([]wire.OutPoint{retInfo.chanPoint}, nil)
+
+	diag, err = brar.DumpRetribution(&retInfo.chanPoint)
+	if err != nil {
+		t.Fatalf("unable to dump retribution: %v", err)
+	}
+	switch {
+	case !diag.Active:
+		t.Fatalf("expected retribution to be active")
+	case diag.Phase != retPhaseAwaitingJusticeConf.String():
+		t.Fatalf("unexpected phase %v", diag.Phase)
+	case diag.BroadcastAttempts != 2:
+		t.Fatalf("expected 2 broadcast attempts, got %v",
+			diag.BroadcastAttempts)
+	case diag.LastError != "rejected":
+		t.Fatalf("unexpected last error %q", diag.LastError)
+	}
+}
+
+// txConfNotifier is a mock notifier which dispatches confirmations of each
+// transaction over a distinct channel.
+type txConfNotifier struct {
+	mockNotifier
+
+	mtx   sync.Mutex
+	confs map[chainhash.Hash]chan *chainntnfs.TxConfirmation
+}
+
+func (n *txConfNotifier) confChan(
+	txid chainhash.Hash) chan *chainntnfs.TxConfirmation {
+
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	conf, ok := n.confs[txid]
+	if !ok {
+		conf = make(chan *chainntnfs.TxConfirmation, 1)
+		n.confs[txid] = conf
+	}
+
+	return conf
+}
+
+func (n *txConfNotifier) RegisterConfirmationsNtfn(txid *chainhash.Hash,
+	numConfs, heightHint uint32) (*chainntnfs.ConfirmationEvent, error) {
+
+	return &chainntnfs.ConfirmationEvent{
+		Confirmed: n.confChan(*txid),
+	}, nil
+}
+
+// TestRetributionAcrossBreachTxs asserts that a retribution whose outputs span
+// several transactions only serves justice once all of them have confirmed,
+// sweeping the outputs of each within the justice transaction.
+func TestRetributionAcrossBreachTxs(t *testing.T) {
+	ret := newBreachRetInfo()
+
+	secondStageTxid := chainhash.Hash{0x02}
+	htlcOutput := breachedOutputs[1]
+	htlcOutput.outpoint = wire.OutPoint{Hash: secondStageTxid}
+	htlcOutput.signDescriptor.DoubleTweak = alicePrivKey
+	ret.htlcOutputs = []*breachedOutput{&htlcOutput}
+
+	txids := ret.breachTxids()
+	if len(txids) != 2 || txids[0] != ret.commitHash ||
+		txids[1] != secondStageTxid {
+
+		t.Fatalf("unexpected breach txids: %v", txids)
+	}
+
+	notifier := &txConfNotifier{
+		confs: make(map[chainhash.Hash]chan *chainntnfs.TxConfirmation),
+	}
+	published := make(chan *wire.MsgTx, 10)
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier: notifier,
+		Wallet:   newTestWallet(published),
+		SweepScriptGen: func() ([]byte, error) {
+			return make([]byte, lnwallet.P2WPKHSize), nil
+		},
+	})
+
+	brar.wg.Add(1)
+	go brar.exactRetribution(
+		&chainntnfs.ConfirmationEvent{
+			Confirmed: notifier.confChan(ret.commitHash),
+		},
+		ret,
+	)
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	// Confirming the breach transaction alone isn't enough to serve
+	// justice, as the HTLC output has yet to confirm.
+	notifier.confChan(ret.commitHash) <- &chainntnfs.TxConfirmation{}
+	select {
+	case <-published:
+		t.Fatalf("justice tx published before all breach txs confirmed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	notifier.confChan(secondStageTxid) <- &chainntnfs.TxConfirmation{}
+
+	var justiceTx *wire.MsgTx
+	select {
+	case justiceTx = <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("justice tx not published")
+	}
+
+	spent := make(map[wire.OutPoint]struct{})
+	for _, txIn := range justiceTx.TxIn {
+		spent[txIn.PreviousOutPoint] = struct{}{}
+	}
+	for _, output := range []*breachedOutput{
+		ret.selfOutput, ret.revokedOutput, &htlcOutput,
+	} {
+		if _, ok := spent[output.outpoint]; !ok {
+			t.Fatalf("justice tx doesn't spend %v", output.outpoint)
+		}
+	}
+}
+
+// txidNotifier is a mock notifier which records the txid of each confirmation
+// registration.
+type txidNotifier struct {
+	mockNotifier
+
+	txids chan chainhash.Hash
+}
+
+func (n *txidNotifier) RegisterConfirmationsNtfn(txid *chainhash.Hash,
+	numConfs, heightHint uint32) (*chainntnfs.ConfirmationEvent, error) {
+
+	n.txids <- *txid
+	return n.mockNotifier.RegisterConfirmationsNtfn(
+		txid, numConfs, heightHint,
+	)
+}
+
+// testBreachedPendingCloseOverlap asserts that a breached channel, which is
+// also pending close, is left to its retribution on startup rather than being
+// watched for closure alongside the other pending close channels, and that its
+// link is closed once more, as the switch may have reinstated it.
+func testBreachedPendingCloseOverlap(h *breachTestHarness, t *testing.T) {
+	// The breach of Alice's channel was detected before restarting, so
+	// its retribution is persisted and its breach close summary written.
+	ret := h.newBreachRetInfo()
+	if err := h.store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+	closeTestChannel(t, h.alice, ret.commitHash, channeldb.BreachClose)
+
+	chainIO := newRevokedChainIO(ret)
+	closedLinks := make(chan wire.OutPoint, 10)
+	brar := newTestBreachArbiter(&BreachConfig{
+		ChainIO: chainIO,
+		CloseLink: func(chanPoint *wire.OutPoint,
+			closeType htlcswitch.ChannelCloseType) {
+
+			if closeType == htlcswitch.CloseBreach {
+				closedLinks <- *chanPoint
+			}
+		},
+		DB:       h.db,
+		Notifier: h.notifier,
+		Store:    h.store,
+	})
+	if err := brar.Start(); err != nil {
+		t.Fatalf("unable to start breach arbiter: %v", err)
+	}
+	defer brar.Stop()
+
+	select {
+	case chanPoint := <-closedLinks:
+		if chanPoint != ret.chanPoint {
+			t.Fatalf("expected link of %v to be closed, got %v",
+				ret.chanPoint, chanPoint)
+		}
+	default:
+		t.Fatalf("link of breached channel not closed")
+	}
+
+	// The breach transaction, which also closes the channel, should only
+	// be watched once, by the retribution.
+	var numRegistrations int
+	for {
+		select {
+		case txid := <-h.notifier.txids:
+			if txid != ret.commitHash {
+				t.Fatalf("unexpected registration for %v", txid)
+			}
+			numRegistrations++
+			continue
+
+		case <-time.After(100 * time.Millisecond):
+		}
+		break
+	}
+	if numRegistrations != 1 {
+		t.Fatalf("expected breach tx to be watched once, watched %d "+
+			"times", numRegistrations)
+	}
+}
+
+// testStartPromotesBreachedOpenChannel asserts that a channel whose breach was
+// persisted before restarting, but which is still open within the database, is
+// promoted to breached-and-closed by Start before its retribution is resumed:
+// its link is closed, its state is deleted, it isn't watched for breaches, and
+// its retribution is carried out.
+func testStartPromotesBreachedOpenChannel(h *breachTestHarness, t *testing.T) {
+	// The breach of Alice's channel was persisted, but the daemon went
+	// down before the channel was closed within the database.
+	ret := h.newBreachRetInfo()
+	if err := h.store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+
+	type closedLink struct {
+		chanPoint wire.OutPoint
+		closeType htlcswitch.ChannelCloseType
+	}
+	closedLinks := make(chan closedLink, 10)
+
+	chainIO := newRevokedChainIO(ret)
+	brar := newTestBreachArbiter(&BreachConfig{
+		ChainIO: chainIO,
+		CloseLink: func(chanPoint *wire.OutPoint,
+			closeType htlcswitch.ChannelCloseType) {
+
+			closedLinks <- closedLink{*chanPoint, closeType}
+		},
+		DB:       h.db,
+		Notifier: h.notifier,
+		Store:    h.store,
+	})
+	if err := brar.Start(); err != nil {
+		t.Fatalf("unable to start breach arbiter: %v", err)
+	}
+	defer brar.Stop()
+
+	// The link must have been closed due to the breach.
+	select {
+	case link := <-closedLinks:
+		if link.chanPoint != ret.chanPoint ||
+			link.closeType != htlcswitch.CloseBreach {
+
+			t.Fatalf("expected breach close of %v, got %v close "+
+				"of %v", ret.chanPoint, link.closeType,
+				link.chanPoint)
+		}
+	default:
+		t.Fatalf("link of breached channel not closed")
+	}
+
+	// The channel's state must have been deleted, leaving it pending
+	// close as breached.
+	openChans, err := h.db.FetchAllChannels()
+	if err != nil && err != channeldb.ErrNoActiveChannels {
+		t.Fatalf("unable to fetch open channels: %v", err)
+	}
+	for _, channel := range openChans {
+		if channel.FundingOutpoint == ret.chanPoint {
+			t.Fatalf("breached channel still open")
+		}
+	}
+	pendingChans, err := h.db.FetchClosedChannels(true)
+	if err != nil {
+		t.Fatalf("unable to fetch pending close channels: %v", err)
+	}
+	if len(pendingChans) != 1 ||
+		pendingChans[0].ChanPoint != ret.chanPoint ||
+		pendingChans[0].CloseType != channeldb.BreachClose {
+
+		t.Fatalf("expected breached channel to be pending close, "+
+			"got %v", spew.Sdump(pendingChans))
+	}
+
+	// Its retribution must have been resumed, awaiting the confirmation
+	// of the breach transaction.
+	select {
+	case txid := <-h.notifier.txids:
+		if txid != ret.commitHash {
+			t.Fatalf("unexpected registration for %v", txid)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("retribution not resumed")
+	}
+
+	deadline := time.After(5 * time.Second)
+	for {
+		brar.retMtx.Lock()
+		_, ok := brar.activeRetributions[ret.chanPoint]
+		brar.retMtx.Unlock()
+		if ok {
+			break
+		}
+
+		select {
+		case <-deadline:
+			t.Fatalf("retribution not active")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// Finally, the channel mustn't be watched for further breaches.
+	for {
+		health, err := brar.HealthCheck()
+		if err != nil {
+			t.Fatalf("unable to check health: %v", err)
+		}
+		if health.ObserverRunning {
+			break
+		}
+
+		select {
+		case <-deadline:
+			t.Fatalf("contract observer not running")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	<-time.After(100 * time.Millisecond)
+	health, err := brar.HealthCheck()
+	if err != nil {
+		t.Fatalf("unable to check health: %v", err)
+	}
+	if health.ActiveObservers != 0 {
+		t.Fatalf("expected no channels to be watched, found %v",
+			health.ActiveObservers)
+	}
+}
+
+// testStartFastForwardsConfirmedJustice asserts that a retribution whose
+// justice transaction confirmed before it could be removed from the store is
+// finalized upon restart, rather than being resumed.
+func testStartFastForwardsConfirmedJustice(h *breachTestHarness,
+	t *testing.T) {
+
+	// The justice transaction confirmed, spending the breached outputs,
+	// but the daemon went down before the retribution was removed.
+	ret := h.newBreachRetInfo()
+	ret.justiceTxid = chainhash.Hash{0x42}
+	if err := h.store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+
+	chainIO := &breachChainIO{
+		utxos: map[wire.OutPoint]*wire.TxOut{
+			{Hash: ret.justiceTxid}: {
+				Value:    int64(ret.revokedOutput.amt),
+				PkScript: []byte{0x00, 0x14},
+			},
+		},
+	}
+	resolved := make(chan wire.OutPoint, 1)
+	brar := newTestBreachArbiter(&BreachConfig{
+		ChainIO: chainIO,
+		CloseLink: func(*wire.OutPoint, htlcswitch.ChannelCloseType) {
+		},
+		DB:       h.db,
+		Notifier: h.notifier,
+		Store:    h.store,
+		Events: &mockBreachEvents{
+			channelResolved: func(chanPoint wire.OutPoint,
+				_ btcutil.Amount, _ uint32,
+				_ *JusticeBreakdown) {
+
+				resolved <- chanPoint
+			},
+		},
+	})
+	if err := brar.Start(); err != nil {
+		t.Fatalf("unable to start breach arbiter: %v", err)
+	}
+	defer brar.Stop()
+
+	select {
+	case chanPoint := <-resolved:
+		if chanPoint != ret.chanPoint {
+			t.Fatalf("expected %v to be resolved, got %v",
+				ret.chanPoint, chanPoint)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("retribution not finalized")
+	}
+
+	// The retribution must have been removed, and the channel marked as
+	// fully closed, without awaiting the breach transaction.
+	if count := countRetributions(t, h.store); count != 0 {
+		t.Fatalf("expected retribution to be removed, found %v", count)
+	}
+	pendingChans, err := h.db.FetchClosedChannels(true)
+	if err != nil {
+		t.Fatalf("unable to fetch pending close channels: %v", err)
+	}
+	if len(pendingChans) != 0 {
+		t.Fatalf("expected no channels pending close, got %v",
+			spew.Sdump(pendingChans))
+	}
+	select {
+	case txid := <-h.notifier.txids:
+		t.Fatalf("unexpected registration for %v", txid)
+	default:
+	}
+
+	health, err := brar.HealthCheck()
+	if err != nil {
+		t.Fatalf("unable to check health: %v", err)
+	}
+	if len(health.UnverifiedRetributions) != 0 {
+		t.Fatalf("expected no unverified retributions, got %v",
+			health.UnverifiedRetributions)
+	}
+}
+
+// slowNotifier is a mock notifier whose registration for the confirmation of a
+// single transaction blocks until released.
+type slowNotifier struct {
+	mockNotifier
+
+	slowTxid   chainhash.Hash
+	release    chan struct{}
+	registered chan chainhash.Hash
+}
+
+func (n *slowNotifier) RegisterConfirmationsNtfn(txid *chainhash.Hash,
+	numConfs, heightHint uint32) (*chainntnfs.ConfirmationEvent, error) {
+
+	if *txid == n.slowTxid {
+		<-n.release
+	}
+
+	n.registered <- *txid
+	return n.mockNotifier.RegisterConfirmationsNtfn(
+		txid, numConfs, heightHint,
+	)
+}
+
+// TestSlowBreachRegistration asserts that a slow registration for the
+// confirmation of one breach transaction doesn't prevent the contract observer
+// from processing other breaches.
+func TestSlowBreachRegistration(t *testing.T) {
+	slowRet := newBreachRetInfo()
+	ret := newBreachRetInfo()
+	ret.commitHash = chainhash.Hash{0x02}
+	ret.chanPoint = breachOutPoints[1]
+
+	notifier := &slowNotifier{
+		slowTxid:   slowRet.commitHash,
+		release:    make(chan struct{}),
+		registered: make(chan chainhash.Hash, 2),
+	}
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier: notifier,
+	})
+
+	brar.wg.Add(1)
+	go brar.contractObserver(nil)
+	defer func() {
+		close(notifier.release)
+		brar.Stop()
+	}()
+
+	for _, breachInfo := range []*retributionInfo{slowRet, ret} {
+		select {
+		case brar.breachedContracts <- breachInfo:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("breach of ChannelPoint(%v) not received",
+				breachInfo.chanPoint)
+		}
+	}
+
+	select {
+	case txid := <-notifier.registered:
+		if txid != ret.commitHash {
+			t.Fatalf("unexpected registration for %v", txid)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("breach stalled behind slow registration")
+	}
+}
+
+// TestCommitSweepSerialization asserts that a commitment sweep survives a round
+// trip through serialization, both before and after its sweep transaction has
+// been crafted.
+func TestCommitSweepSerialization(t *testing.T) {
+	input := breachedOutputs[2]
+
+	sweep := &commitSweepInfo{
+		chanPoint:   breachOutPoints[0],
+		closeTxid:   chainhash.Hash{0x01},
+		closeHeight: 500,
+		inputs:      []*breachedOutput{&input},
+	}
+
+	sweepTx := wire.NewMsgTx(2)
+	sweepTx.AddTxIn(&wire.TxIn{PreviousOutPoint: input.outpoint})
+	sweepTx.AddTxOut(&wire.TxOut{Value: 1000, PkScript: []byte{0x51}})
+
+	for _, tx := range []*wire.MsgTx{nil, sweepTx} {
+		sweep.sweepTx = tx
+
+		var b bytes.Buffer
+		if err := sweep.Encode(&b); err != nil {
+			t.Fatalf("unable to encode sweep: %v", err)
+		}
+
+		decoded := &commitSweepInfo{}
+		if err := decoded.Decode(&b); err != nil {
+			t.Fatalf("unable to decode sweep: %v", err)
+		}
+
+		// The sweep transaction is compared by its serialization,
+		// which is all that's committed to by its hash.
+		switch {
+		case (decoded.sweepTx == nil) != (tx == nil):
+			t.Fatalf("sweep tx not restored")
+		case tx != nil && decoded.sweepTx.TxHash() != tx.TxHash():
+			t.Fatalf("expected sweep tx %v, got %v", tx.TxHash(),
+				decoded.sweepTx.TxHash())
+		}
+		decoded.sweepTx = sweep.sweepTx
+
+		if !reflect.DeepEqual(sweep, decoded) {
+			t.Fatalf("original and decoded sweeps differ: "+
+				"expected %v, got %v", spew.Sdump(sweep),
+				spew.Sdump(decoded))
+		}
+	}
+}
+
+// TestResumeCommitSweep asserts that the sweep of a channel closed unilaterally
+// by the remote party survives a restart, and that the channel is only marked
+// as fully closed once the sweep has confirmed.
+func TestResumeCommitSweep(t *testing.T) {
+	notifier := &txConfNotifier{
+		confs: make(map[chainhash.Hash]chan *chainntnfs.TxConfirmation),
+	}
+	alicePeer, alice, _, cleanUp, err := createTestPeer(
+		notifier, make(chan *wire.MsgTx, 10),
+	)
+	if err != nil {
+		t.Fatalf("unable to create test channels: %v", err)
+	}
+	defer cleanUp()
+	db := alicePeer.server.chanDB
+
+	closeTxid := chainhash.Hash{0x01}
+	chanPoint := *alice.ChannelPoint()
+	closeTestChannel(t, alice, closeTxid, channeldb.ForceClose)
+
+	published := make(chan *wire.MsgTx, 10)
+	newArbiter := func() *breachArbiter {
+		return newTestBreachArbiter(&BreachConfig{
+			DB:       db,
+			Notifier: notifier,
+			Wallet:   newTestWallet(published),
+			SweepScriptGen: func() ([]byte, error) {
+				return make([]byte, lnwallet.P2WPKHSize), nil
+			},
+		})
+	}
+
+	expectPublished := func() *wire.MsgTx {
+		select {
+		case tx := <-published:
+			return tx
+		case <-time.After(5 * time.Second):
+			t.Fatalf("sweep tx not published")
+		}
+		return nil
+	}
+	assertPendingClose := func(pending bool) {
+		pendingCloses, err := db.FetchClosedChannels(true)
+		if err != nil {
+			t.Fatalf("unable to fetch pending closes: %v", err)
+		}
+		if (len(pendingCloses) == 1) != pending {
+			t.Fatalf("expected pending close: %v, found %d",
+				pending, len(pendingCloses))
+		}
+	}
+
+	input := breachedOutputs[0]
+	input.outpoint = wire.OutPoint{Hash: closeTxid}
+
+	brar := newArbiter()
+	brar.beginCommitSweep(&commitSweepInfo{
+		chanPoint:   chanPoint,
+		closeTxid:   closeTxid,
+		closeHeight: 100,
+		inputs:      []*breachedOutput{&input},
+	})
+
+	// Once the closing transaction confirms, the sweep is broadcast.
+	notifier.confChan(closeTxid) <- &chainntnfs.TxConfirmation{}
+	sweepTx := expectPublished()
+
+	// We'll now restart before the sweep confirms, which must leave the
+	// channel pending close.
+	brar.Stop()
+	assertPendingClose(true)
+
+	// Upon restart, the very same sweep should be broadcast again, once
+	// the closing transaction is known to have confirmed.
+	brar = newArbiter()
+	if err := brar.Start(); err != nil {
+		t.Fatalf("unable to start breach arbiter: %v", err)
+	}
+	defer brar.Stop()
+
+	notifier.confChan(closeTxid) <- &chainntnfs.TxConfirmation{}
+	if tx := expectPublished(); tx.TxHash() != sweepTx.TxHash() {
+		t.Fatalf("expected sweep %v to be rebroadcast, got %v",
+			sweepTx.TxHash(), tx.TxHash())
+	}
+	assertPendingClose(true)
+
+	// Finally, once the sweep confirms, the channel should be marked as
+	// fully closed, and the sweep removed from the store.
+	notifier.confChan(sweepTx.TxHash()) <- &chainntnfs.TxConfirmation{}
+
+	resolved := func() (bool, error) {
+		pendingCloses, err := db.FetchClosedChannels(true)
+		if err != nil {
+			return false, err
+		}
+
+		var numSweeps int
+		err = brar.commitSweeps.ForAll(func(*commitSweepInfo) error {
+			numSweeps++
+			return nil
+		})
+		if err != nil {
+			return false, err
+		}
+
+		return len(pendingCloses) == 0 && numSweeps == 0, nil
+	}
+	timeout := time.After(5 * time.Second)
+	for {
+		ok, err := resolved()
+		if err != nil {
+			t.Fatalf("unable to query resolution: %v", err)
+		}
+		if ok {
+			break
+		}
+
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			t.Fatalf("sweep not resolved after confirming")
+		}
+	}
+}
+
+// flakyExternalSweeper is an ExternalSweeper which fails the first handoff,
+// recording each subsequent one.
+type flakyExternalSweeper struct {
+	failed   uint32
+	deferred chan *DeferredOutput
+}
+
+func (s *flakyExternalSweeper) DeferOutput(output *DeferredOutput) error {
+	if atomic.CompareAndSwapUint32(&s.failed, 0, 1) {
+		return errors.New("sweeper unavailable")
+	}
+
+	s.deferred <- output
+	return nil
+}
+
+// TestDeferCommitSweep asserts that, if DeferCommitSweeps is set, our output
+// within the remote party's commitment transaction is handed off to the
+// external sweeper rather than swept, and that the channel is only marked as
+// fully closed once the handoff has succeeded.
+func TestDeferCommitSweep(t *testing.T) {
+	defer func(backoff time.Duration) {
+		deferOutputBackoff = backoff
+	}(deferOutputBackoff)
+	deferOutputBackoff = time.Millisecond
+
+	notifier := &txConfNotifier{
+		confs: make(map[chainhash.Hash]chan *chainntnfs.TxConfirmation),
+	}
+	alicePeer, alice, _, cleanUp, err := createTestPeer(
+		notifier, make(chan *wire.MsgTx, 10),
+	)
+	if err != nil {
+		t.Fatalf("unable to create test channels: %v", err)
+	}
+	defer cleanUp()
+	db := alicePeer.server.chanDB
+
+	closeTxid := chainhash.Hash{0x01}
+	chanPoint := *alice.ChannelPoint()
+	closeTestChannel(t, alice, closeTxid, channeldb.ForceClose)
+
+	published := make(chan *wire.MsgTx, 10)
+	sweeper := &flakyExternalSweeper{
+		deferred: make(chan *DeferredOutput, 1),
+	}
+	brar := newTestBreachArbiter(&BreachConfig{
+		DB:       db,
+		Notifier: notifier,
+		Wallet:   newTestWallet(published),
+		SweepScriptGen: func() ([]byte, error) {
+			return make([]byte, lnwallet.P2WPKHSize), nil
+		},
+		DeferCommitSweeps: true,
+		ExternalSweeper:   sweeper,
+	})
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	input := breachedOutputs[0]
+	input.outpoint = wire.OutPoint{Hash: closeTxid}
+
+	brar.beginCommitSweep(&commitSweepInfo{
+		chanPoint:   chanPoint,
+		closeTxid:   closeTxid,
+		closeHeight: 100,
+		inputs:      []*breachedOutput{&input},
+	})
+	notifier.confChan(closeTxid) <- &chainntnfs.TxConfirmation{}
+
+	// The first handoff fails, but is retried.
+	select {
+	case output := <-sweeper.deferred:
+		if output.ChanPoint != chanPoint {
+			t.Fatalf("expected chan point %v, got %v", chanPoint,
+				output.ChanPoint)
+		}
+		if output.OutPoint != input.outpoint {
+			t.Fatalf("expected outpoint %v, got %v",
+				input.outpoint, output.OutPoint)
+		}
+		if output.Amount != input.amt {
+			t.Fatalf("expected amount %v, got %v", input.amt,
+				output.Amount)
+		}
+		if output.WitnessType != input.witnessType {
+			t.Fatalf("expected witness type %v, got %v",
+				input.witnessType, output.WitnessType)
+		}
+		if !reflect.DeepEqual(output.SignDesc, input.signDescriptor) {
+			t.Fatalf("sign descriptor not handed off")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("output not handed off to external sweeper")
+	}
+
+	// Once handed off, the channel is fully closed without us having
+	// swept anything.
+	timeout := time.After(5 * time.Second)
+	for {
+		pendingCloses, err := db.FetchClosedChannels(true)
+		if err != nil {
+			t.Fatalf("unable to fetch pending closes: %v", err)
+		}
+		if len(pendingCloses) == 0 {
+			break
+		}
+
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			t.Fatalf("channel not closed after handoff")
+		}
+	}
+
+	select {
+	case tx := <-published:
+		t.Fatalf("unexpected sweep tx %v published", tx.TxHash())
+	default:
+	}
+}
+
+// recordingSigner is a signer which records the index of each input it signs
+// before delegating to a mock signer.
+type recordingSigner struct {
+	mockSigner
+
+	signedInputs []int
+}
+
+func (s *recordingSigner) SignOutputRaw(tx *wire.MsgTx,
+	signDesc *lnwallet.SignDescriptor) ([]byte, error) {
+
+	s.signedInputs = append(s.signedInputs, signDesc.InputIndex)
+	return s.mockSigner.SignOutputRaw(tx, signDesc)
+}
+
+// keyFailingSigner is a mock signer which fails to sign for a single public
+// key.
+type keyFailingSigner struct {
+	mockSigner
+
+	failKey *btcec.PublicKey
+}
+
+func (s *keyFailingSigner) SignOutputRaw(tx *wire.MsgTx,
+	signDesc *lnwallet.SignDescriptor) ([]byte, error) {
+
+	if signDesc.PubKey.IsEqual(s.failKey) {
+		return nil, errors.New("signer unavailable")
+	}
+	return s.mockSigner.SignOutputRaw(tx, signDesc)
+}
+
+// TestJusticeSkipsUnsignableHTLC asserts that HTLC outputs whose witness can't
+// be generated, whether for lack of signing material or due to a signing
+// failure, are left out of the justice transaction, which still sweeps the
+// remaining outputs, and that each skipped output is surfaced.
+func TestJusticeSkipsUnsignableHTLC(t *testing.T) {
+	ret := newBreachRetInfo()
+
+	// The first HTLC output lacks the revocation secret, while the
+	// second's key can't be signed for. The third can be swept.
+	incomplete := breachedOutputs[1]
+	incomplete.outpoint = wire.OutPoint{Hash: ret.commitHash, Index: 2}
+
+	unsignable := breachedOutputs[1]
+	unsignable.outpoint = wire.OutPoint{Hash: ret.commitHash, Index: 3}
+	unsignable.signDescriptor.PubKey = breachedOutputs[2].signDescriptor.PubKey
+	unsignable.signDescriptor.DoubleTweak = alicePrivKey
+
+	sweepable := breachedOutputs[1]
+	sweepable.outpoint = wire.OutPoint{Hash: ret.commitHash, Index: 4}
+	sweepable.signDescriptor.DoubleTweak = alicePrivKey
+
+	ret.htlcOutputs = []*breachedOutput{
+		&incomplete, &unsignable, &sweepable,
+	}
+
+	skipped := make(chan *SkippedHTLCOutput, 10)
+	brar := newTestBreachArbiter(&BreachConfig{
+		Wallet: &lnwallet.LightningWallet{},
+		Signer: &keyFailingSigner{
+			mockSigner: mockSigner{key: alicePrivKey},
+			failKey:    unsignable.signDescriptor.PubKey,
+		},
+		SweepScriptGen: func() ([]byte, error) {
+			return []byte{0x00, 0x14}, nil
+		},
+		Events: &mockBreachEvents{
+			skippedHTLC: func(s *SkippedHTLCOutput) {
+				skipped <- s
+			},
+		},
+	})
+
+	justiceTx, err := brar.createJusticeTx(ret, sweepTxNew)
+	if err != nil {
+		t.Fatalf("unable to create justice tx: %v", err)
+	}
+
+	spent := make(map[wire.OutPoint]struct{})
+	for _, txIn := range justiceTx.TxIn {
+		spent[txIn.PreviousOutPoint] = struct{}{}
+	}
+	expSpent := []wire.OutPoint{
+		ret.selfOutput.outpoint, ret.revokedOutput.outpoint,
+		sweepable.outpoint,
+	}
+	if len(spent) != len(expSpent) {
+		t.Fatalf("expected justice tx to spend %v inputs, spends %v",
+			len(expSpent), len(spent))
+	}
+	for _, op := range expSpent {
+		if _, ok := spent[op]; !ok {
+			t.Fatalf("justice tx doesn't spend %v", op)
+		}
+	}
+
+	if len(ret.htlcOutputs) != 1 || ret.htlcOutputs[0] != &sweepable {
+		t.Fatalf("expected only the sweepable htlc output to remain")
+	}
+
+	notified := make(map[wire.OutPoint]struct{})
+	for i := 0; i < 2; i++ {
+		select {
+		case s := <-skipped:
+			if s.ChanPoint != ret.chanPoint || s.Reason == nil {
+				t.Fatalf("unexpected skipped output: %v",
+					spew.Sdump(s))
+			}
+			notified[s.OutPoint] = struct{}{}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("skipped htlc output not surfaced")
+		}
+	}
+	for _, op := range []wire.OutPoint{
+		incomplete.outpoint, unsignable.outpoint,
+	} {
+		if _, ok := notified[op]; !ok {
+			t.Fatalf("skipped htlc output %v not surfaced", op)
+		}
+	}
+}
+
+// TestJusticeDropsUneconomicHTLC asserts that an HTLC output whose value
+// doesn't cover the fee its inclusion adds is left out of the justice
+// transaction, while remaining part of the retribution.
+func TestJusticeDropsUneconomicHTLC(t *testing.T) {
+	ret := newBreachRetInfo()
+
+	// At 10 sat/weight, spending an HTLC output costs several thousand
+	// satoshis, which the dust output doesn't cover.
+	dust := breachedOutputs[1]
+	dust.outpoint = wire.OutPoint{Hash: ret.commitHash, Index: 2}
+	dust.amt = 1000
+	dust.signDescriptor.DoubleTweak = alicePrivKey
+
+	economic := breachedOutputs[1]
+	economic.outpoint = wire.OutPoint{Hash: ret.commitHash, Index: 3}
+	economic.amt = 100000
+	economic.signDescriptor.DoubleTweak = alicePrivKey
+
+	ret.htlcOutputs = []*breachedOutput{&dust, &economic}
+
+	brar := newTestBreachArbiter(&BreachConfig{
+		Estimator: lnwallet.StaticFeeEstimator{FeeRate: 40},
+		Wallet:    &lnwallet.LightningWallet{},
+		Signer:    &mockSigner{key: alicePrivKey},
+		SweepScriptGen: func() ([]byte, error) {
+			return []byte{0x00, 0x14}, nil
+		},
+	})
+
+	fee := brar.justiceInputFee(&dust, brar.cfg.JusticeFeeTarget, 0)
+	if dust.amt > fee || economic.amt <= fee {
+		t.Fatalf("marginal fee %v doesn't lie between the htlc "+
+			"output values", fee)
+	}
+
+	justiceTx, err := brar.createJusticeTx(ret, sweepTxNew)
+	if err != nil {
+		t.Fatalf("unable to create justice tx: %v", err)
+	}
+
+	spent := make(map[wire.OutPoint]struct{})
+	for _, txIn := range justiceTx.TxIn {
+		spent[txIn.PreviousOutPoint] = struct{}{}
+	}
+	if _, ok := spent[dust.outpoint]; ok {
+		t.Fatalf("justice tx spends uneconomic htlc output")
+	}
+	if _, ok := spent[economic.outpoint]; !ok {
+		t.Fatalf("justice tx doesn't spend economic htlc output")
+	}
+	if len(spent) != 3 {
+		t.Fatalf("expected justice tx to spend 3 inputs, spends %v",
+			len(spent))
+	}
+
+	if len(ret.htlcOutputs) != 2 {
+		t.Fatalf("expected retribution to retain both htlc "+
+			"outputs, has %v", len(ret.htlcOutputs))
+	}
+}
+
+// TestJusticeDropsUneconomicSelfOutput asserts that, if configured, our own
+// output is left out of the justice transaction should its value not cover the
+// fee its inclusion adds, while the revoked output is swept regardless.
+func TestJusticeDropsUneconomicSelfOutput(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		ret := newBreachRetInfo()
+
+		selfOutput := *ret.selfOutput
+		selfOutput.amt = 1000
+		ret.selfOutput = &selfOutput
+
+		brar := newTestBreachArbiter(&BreachConfig{
+			Estimator: lnwallet.StaticFeeEstimator{FeeRate: 40},
+			Wallet:    &lnwallet.LightningWallet{},
+			Signer:    &mockSigner{key: alicePrivKey},
+			SweepScriptGen: func() ([]byte, error) {
+				return []byte{0x00, 0x14}, nil
+			},
+			SkipUneconomicSelfOutput: skip,
+		})
+
+		fee := brar.justiceInputFee(
+			&selfOutput, brar.cfg.JusticeFeeTarget, 0,
+		)
+		if selfOutput.amt > fee {
+			t.Fatalf("self output worth %v covers its marginal "+
+				"fee %v", selfOutput.amt, fee)
+		}
+
+		justiceTx, err := brar.createJusticeTx(ret, sweepTxNew)
+		if err != nil {
+			t.Fatalf("unable to create justice tx: %v", err)
+		}
+
+		spent := make(map[wire.OutPoint]struct{})
+		for _, txIn := range justiceTx.TxIn {
+			spent[txIn.PreviousOutPoint] = struct{}{}
+		}
+		if _, ok := spent[ret.revokedOutput.outpoint]; !ok {
+			t.Fatalf("justice tx doesn't spend revoked output")
+		}
+		if _, ok := spent[selfOutput.outpoint]; ok == skip {
+			t.Fatalf("expected self output swept: %v, spent: %v",
+				!skip, ok)
+		}
+	}
+}
+
+// goldenJusticeTx is the serialized justice transaction produced by
+// TestJusticeTxGolden. It must only change if the structure of justice
+// transactions is changed deliberately.
+const goldenJusticeTx = "" +
+	"0200000000010201000000000000000000000000000000000000000000000000" +
+	"0000000000000000000000000000000001000000000000000000000000000000" +
+	"0000000000000000000000000000000001000000000000000001f816ce770000" +
+	"00001600140102030405060708090a0b0c0d0e0f101112131402483045022100" +
+	"a79a12636e8ba016b27645a3288e1658b7ccc2ff9cc5381a557a66d2412ae409" +
+	"022015b6d6086131fda1dff056dc04b3b3f8925e2ba61acf544773369a76dbeb" +
+	"fbf30121030140d4de637a9ef397ef6575a7d9cc72ba521cdba38d79444078e5" +
+	"f32412e2e40347304402204a5761ab8243eea2df8388881f032e8710286ab0ae" +
+	"1ea8f3697266afe2cf8e2802205e3a0b3c2ec74dd0d46603f5d82beaefafe4c5" +
+	"474f961f6335d55450e5d0d9c7010101160014ee91417e856cde10a2911edcbd" +
+	"bd69e2efb5714800000000"
+
+// TestJusticeTxGolden asserts that, with its fee and sweep script pinned, a
+// justice transaction is reproduced byte-for-byte, regardless of the fee
+// estimate or any fresh scripts available to the breach arbiter.
+func TestJusticeTxGolden(t *testing.T) {
+	const pinnedFee = btcutil.Amount(5000)
+	pinnedScript := []byte{
+		0x00, 0x14, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+		0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12,
+		0x13, 0x14,
+	}
+
+	createJusticeTx := func(feeRate uint64) []byte {
+		var scriptNum uint32
+		brar := newTestBreachArbiter(&BreachConfig{
+			Estimator: lnwallet.StaticFeeEstimator{
+				FeeRate: feeRate,
+			},
+			Wallet: &lnwallet.LightningWallet{},
+			Signer: &mockSigner{key: alicePrivKey},
+			SweepScriptGen: func() ([]byte, error) {
+				scriptNum++
+				return []byte{0x00, byte(scriptNum)}, nil
+			},
+		})
+		brar.justiceInputs.fee = func([]*breachedOutput,
+			uint32) btcutil.Amount {
+
+			return pinnedFee
+		}
+		brar.justiceInputs.sweepScript = func() ([]byte, error) {
+			return pinnedScript, nil
+		}
+
+		justiceTx, err := brar.createJusticeTx(
+			newBreachRetInfo(), sweepTxNew,
+		)
+		if err != nil {
+			t.Fatalf("unable to create justice tx: %v", err)
+		}
+
+		var b bytes.Buffer
+		if err := justiceTx.Serialize(&b); err != nil {
+			t.Fatalf("unable to serialize justice tx: %v", err)
+		}
+
+		return b.Bytes()
+	}
+
+	for _, feeRate := range []uint64{1, 50} {
+		justiceTx := hex.EncodeToString(createJusticeTx(feeRate))
+		if justiceTx != goldenJusticeTx {
+			t.Fatalf("justice tx at fee rate %v doesn't match "+
+				"golden tx:\nexpected: %v\ngot:      %v",
+				feeRate, goldenJusticeTx, justiceTx)
+		}
+	}
+}
+
+// TestJusticeTxMarker asserts that a configured marker is carried by a
+// worthless OP_RETURN output following the justice output, whose weight is paid
+// for out of the amount swept, and that an oversized marker is rejected.
+func TestJusticeTxMarker(t *testing.T) {
+	const feeRate = 40
+	marker := []byte("justice served")
+
+	newArbiter := func(marker []byte) *breachArbiter {
+		return newTestBreachArbiter(&BreachConfig{
+			Estimator: lnwallet.StaticFeeEstimator{
+				FeeRate: feeRate,
+			},
+			Wallet: &lnwallet.LightningWallet{},
+			Signer: &mockSigner{key: alicePrivKey},
+			SweepScriptGen: func() ([]byte, error) {
+				return make([]byte, lnwallet.P2WPKHSize), nil
+			},
+			JusticeTxMarker: marker,
+		})
+	}
+
+	plainTx, err := newArbiter(nil).createJusticeTx(
+		newBreachRetInfo(), sweepTxNew,
+	)
+	if err != nil {
+		t.Fatalf("unable to create justice tx: %v", err)
+	}
+	brar := newArbiter(marker)
+	markedTx, err := brar.createJusticeTx(newBreachRetInfo(), sweepTxNew)
+	if err != nil {
+		t.Fatalf("unable to create marked justice tx: %v", err)
+	}
+
+	if len(markedTx.TxOut) != 2 {
+		t.Fatalf("expected 2 outputs, got %v", len(markedTx.TxOut))
+	}
+	markerOut := markedTx.TxOut[1]
+	if markerOut.Value != 0 {
+		t.Fatalf("expected worthless marker output, got %v",
+			markerOut.Value)
+	}
+	pushes, err := txscript.PushedData(markerOut.PkScript)
+	if err != nil {
+		t.Fatalf("unable to parse marker script: %v", err)
+	}
+	if txscript.GetScriptClass(markerOut.PkScript) != txscript.NullDataTy ||
+		len(pushes) != 1 || !bytes.Equal(pushes[0], marker) {
+
+		t.Fatalf("marker output doesn't carry marker: %x",
+			markerOut.PkScript)
+	}
+
+	// The marker's weight is paid for by the justice output. The static
+	// estimator disregards the confirmation target.
+	markerFee := int64(brar.feePerWeight(0)) *
+		extraOutputWeight(markerOut.PkScript)
+	if plainTx.TxOut[0].Value-markedTx.TxOut[0].Value != markerFee {
+		t.Fatalf("expected marker to cost %v, costs %v", markerFee,
+			plainTx.TxOut[0].Value-markedTx.TxOut[0].Value)
+	}
+
+	// A marker too large to be relayed is rejected upfront.
+	oversized := newArbiter(make([]byte, txscript.MaxDataCarrierSize+1))
+	if err := oversized.Start(); err == nil {
+		t.Fatalf("expected oversized marker to be rejected")
+	}
+	_, err = oversized.createJusticeTx(newBreachRetInfo(), sweepTxNew)
+	if err == nil {
+		t.Fatalf("expected oversized marker to be rejected")
+	}
+}
+
+// TestInjectedSigner asserts that every input of the transactions crafted by
+// the breach arbiter is signed by the configured signer rather than the
+// wallet's.
+func TestInjectedSigner(t *testing.T) {
+	signer := &recordingSigner{
+		mockSigner: mockSigner{key: alicePrivKey},
+	}
+	brar := newTestBreachArbiter(&BreachConfig{
+		// The wallet has no signer of its own, so any attempt to sign
+		// with it would fail.
+		Wallet: &lnwallet.LightningWallet{},
+		Signer: signer,
+		SweepScriptGen: func() ([]byte, error) {
+			return make([]byte, lnwallet.P2WPKHSize), nil
+		},
+	})
+
+	assertSigned := func(tx *wire.MsgTx) {
+		if len(signer.signedInputs) != len(tx.TxIn) {
+			t.Fatalf("expected %d inputs to be signed, signer "+
+				"invoked %d times", len(tx.TxIn),
+				len(signer.signedInputs))
+		}
+		for i, inputIndex := range signer.signedInputs {
+			if inputIndex != i {
+				t.Fatalf("expected input %d to be signed, "+
+					"got %d", i, inputIndex)
+			}
+		}
+		signer.signedInputs = nil
+	}
+
+	ret := newBreachRetInfo()
+	htlcOutput := breachedOutputs[1]
+	htlcOutput.outpoint = wire.OutPoint{Hash: ret.commitHash, Index: 2}
+	ret.htlcOutputs = []*breachedOutput{&htlcOutput}
+
+	justiceTx, err := brar.createJusticeTx(ret, sweepTxNew)
+	if err != nil {
+		t.Fatalf("unable to create justice tx: %v", err)
+	}
+	assertSigned(justiceTx)
+
+	sweepTx, err := brar.craftCommitSweepTx(
+		[]*breachedOutput{ret.selfOutput},
+	)
+	if err != nil {
+		t.Fatalf("unable to craft commitment sweep: %v", err)
+	}
+	assertSigned(sweepTx)
+}
+
+// TestJusticeBumpCPFP asserts that, if preferred, a stuck justice transaction
+// is bumped by a child spending its output, which is funded by a wallet coin
+// should the justice output alone not cover its fee.
+func TestJusticeBumpCPFP(t *testing.T) {
+	notifier := &mockNotifier{
+		confChannel: make(chan *chainntnfs.TxConfirmation, 1),
+		epochChan:   make(chan *chainntnfs.BlockEpoch, 1),
+	}
+	published := make(chan *wire.MsgTx, 10)
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier: notifier,
+		Wallet:   newTestWallet(published),
+		SweepScriptGen: func() ([]byte, error) {
+			return make([]byte, lnwallet.P2WPKHSize), nil
+		},
+		JusticeBumpSchedule: []uint32{1},
+		JusticeBumpCPFP:     true,
+	})
+
+	ret := newBreachRetInfo()
+	ret.selfOutput.amt = 3000
+	ret.revokedOutput.amt = 8000
+
+	brar.wg.Add(1)
+	go brar.exactRetribution(
+		&chainntnfs.ConfirmationEvent{Confirmed: notifier.confChannel},
+		ret,
+	)
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	expectPublished := func() *wire.MsgTx {
+		select {
+		case tx := <-published:
+			return tx
+		case <-time.After(5 * time.Second):
+			t.Fatalf("tx not published")
+		}
+		return nil
+	}
+
+	notifier.confChannel <- &chainntnfs.TxConfirmation{}
+	justiceTx := expectPublished()
+	justiceAmt := justiceTx.TxOut[0].Value
+
+	// Once the first milestone is reached, the justice transaction should
+	// be bumped by a child spending its output, rather than replaced. The
+	// pair should pay the fee of the next tier.
+	notifier.epochChan <- &chainntnfs.BlockEpoch{
+		Height: fundingBroadcastHeight + 1,
+	}
+	child := expectPublished()
+
+	justiceOutput := wire.OutPoint{Hash: justiceTx.TxHash()}
+	if len(child.TxIn) != 1 || child.TxIn[0].PreviousOutPoint != justiceOutput {
+		t.Fatalf("expected child to only spend justice output %v",
+			justiceOutput)
+	}
+	if len(child.TxIn[0].Witness) == 0 {
+		t.Fatalf("child input is missing its witness")
+	}
+	parentFee := justiceBaseFee
+	childFee := btcutil.Amount(justiceAmt - child.TxOut[0].Value)
+	if parentFee+childFee != justiceBaseFee<<1 {
+		t.Fatalf("expected package fee %v, got %v", justiceBaseFee<<1,
+			parentFee+childFee)
+	}
+
+	// Bumping to a tier the justice output can't cover requires a wallet
+	// coin to fund the replacement child, which should pay to the same
+	// script as the child it replaces.
+	replacement, err := brar.cpfpJustice(ret, justiceTx, child, 2)
+	if err != nil {
+		t.Fatalf("unable to bump justice tx via CPFP: %v", err)
+	}
+	expectPublished()
+
+	if len(replacement.TxIn) != 2 {
+		t.Fatalf("expected wallet coin to fund child, child has %d "+
+			"inputs", len(replacement.TxIn))
+	}
+	if !bytes.Equal(replacement.TxOut[0].PkScript, child.TxOut[0].PkScript) {
+		t.Fatalf("replacement child pays to a different script")
+	}
+
+	// The justice output should be swept in full, with the remainder of
+	// the wallet coin returned as change to a fresh wallet address.
+	if replacement.TxOut[0].Value != justiceAmt {
+		t.Fatalf("expected justice output of %v to be swept in full, "+
+			"got %v", justiceAmt, replacement.TxOut[0].Value)
+	}
+	if len(replacement.TxOut) != 2 {
+		t.Fatalf("expected change output, child has %d outputs",
+			len(replacement.TxOut))
+	}
+	changeScript, err := newChangePkScript(brar.cfg.Wallet)
+	if err != nil {
+		t.Fatalf("unable to create change script: %v", err)
+	}
+	if !bytes.Equal(replacement.TxOut[1].PkScript, changeScript) {
+		t.Fatalf("change isn't paid to the wallet")
+	}
+}
+
+// heightHintNotifier is a mock notifier which records the height hint of each
+// confirmation registration.
+type heightHintNotifier struct {
+	txConfNotifier
+
+	heightHints chan uint32
+}
+
+func (n *heightHintNotifier) RegisterConfirmationsNtfn(txid *chainhash.Hash,
+	numConfs, heightHint uint32) (*chainntnfs.ConfirmationEvent, error) {
+
+	n.heightHints <- heightHint
+	return n.txConfNotifier.RegisterConfirmationsNtfn(
+		txid, numConfs, heightHint,
+	)
+}
+
+// heightChainIO is a mock chain backend whose best height is configurable.
+type heightChainIO struct {
+	mockChainIO
+
+	height int32
+}
+
+func (c *heightChainIO) GetBestBlock() (*chainhash.Hash, int32, error) {
+	return activeNetParams.GenesisHash, c.height, nil
+}
+
+// TestResumeCloseWatch asserts that the confirmation of a pending close is
+// watched for from the same height hint across restarts, and that the hint is
+// removed once the close has confirmed.
+func TestResumeCloseWatch(t *testing.T) {
+	notifier := &heightHintNotifier{
+		heightHints: make(chan uint32, 1),
+	}
+	notifier.confs = make(map[chainhash.Hash]chan *chainntnfs.TxConfirmation)
+	alicePeer, alice, _, cleanUp, err := createTestPeer(
+		notifier, make(chan *wire.MsgTx, 10),
+	)
+	if err != nil {
+		t.Fatalf("unable to create test channels: %v", err)
+	}
+	defer cleanUp()
+	db := alicePeer.server.chanDB
+
+	closeTxid := chainhash.Hash{0x01}
+	closeTestChannel(t, alice, closeTxid, channeldb.CooperativeClose)
+
+	chainIO := &heightChainIO{height: 100}
+	startArbiter := func() *breachArbiter {
+		brar := newTestBreachArbiter(&BreachConfig{
+			ChainIO:  chainIO,
+			DB:       db,
+			Notifier: notifier,
+			Wallet: &lnwallet.LightningWallet{
+				WalletController: &mockWalletController{
+					rootKey: alicePrivKey,
+				},
+				Cfg: lnwallet.Config{
+					Signer: &mockSigner{key: alicePrivKey},
+				},
+			},
+		})
+		if err := brar.Start(); err != nil {
+			t.Fatalf("unable to start breach arbiter: %v", err)
+		}
+		return brar
+	}
+	assertHeightHint := func(expected uint32) {
+		select {
+		case hint := <-notifier.heightHints:
+			if hint != expected {
+				t.Fatalf("expected height hint %v, got %v",
+					expected, hint)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("close confirmation not registered")
+		}
+	}
+
+	// The close is first watched from the current height.
+	brar := startArbiter()
+	assertHeightHint(100)
+	brar.Stop()
+
+	// Should the chain tip have been reorged below the persisted height
+	// hint, the close is watched from the current height instead.
+	chainIO.height = 90
+	brar = startArbiter()
+	assertHeightHint(90)
+	brar.Stop()
+
+	// After restarting at a greater height, the close should still be
+	// watched from the original height, as it may have confirmed while we
+	// were down.
+	chainIO.height = 110
+	brar = startArbiter()
+	defer brar.Stop()
+	assertHeightHint(100)
+
+	// Once the close confirms, the channel should be fully closed and its
+	// height hint removed.
+	notifier.confChan(closeTxid) <- &chainntnfs.TxConfirmation{}
+
+	resolved := func() (bool, error) {
+		pendingCloses, err := db.FetchClosedChannels(true)
+		if err != nil {
+			return false, err
+		}
+
+		var numHints int
+		err = brar.closeWatches.ForAll(func(wire.OutPoint,
+			uint32) error {
+
+			numHints++
+			return nil
+		})
+		if err != nil {
+			return false, err
+		}
+
+		return len(pendingCloses) == 0 && numHints == 0, nil
+	}
+	timeout := time.After(5 * time.Second)
+	for {
+		ok, err := resolved()
+		if err != nil {
+			t.Fatalf("unable to query resolution: %v", err)
+		}
+		if ok {
+			break
+		}
+
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			t.Fatalf("close not resolved after confirming")
+		}
+	}
+}
+
+// TestRetributionSlots asserts that retributions beyond the limit are queued
+// until a slot frees, those with the most value at risk being served first,
+// and that waiting retributions exit upon cancellation or shutdown.
+func TestRetributionSlots(t *testing.T) {
+	t.Parallel()
+
+	quit := make(chan struct{})
+	slots := newRetributionSlots(1, quit)
+
+	if err := slots.acquire(1, nil); err != nil {
+		t.Fatalf("unable to acquire free slot: %v", err)
+	}
+
+	// Queue several retributions behind the one holding the only slot,
+	// one of which we'll cancel while it waits.
+	acquired := make(chan btcutil.Amount, 3)
+	errs := make(chan error, 3)
+	cancel := make(chan struct{})
+	waitQueued := func(n int) {
+		for i := 0; i < 500 && slots.numWaiting() != n; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if slots.numWaiting() != n {
+			t.Fatalf("expected %d waiting retributions, got %d",
+				n, slots.numWaiting())
+		}
+	}
+	for i, value := range []btcutil.Amount{10, 30, 40, 20} {
+		var cancelSignal chan struct{}
+		if value == 40 {
+			cancelSignal = cancel
+		}
+
+		go func(value btcutil.Amount) {
+			err := slots.acquire(value, cancelSignal)
+			if err != nil {
+				errs <- err
+				return
+			}
+			acquired <- value
+		}(value)
+		waitQueued(i + 1)
+	}
+
+	close(cancel)
+	select {
+	case err := <-errs:
+		if err != errRetributionCancelled {
+			t.Fatalf("expected cancellation, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("cancelled retribution still waiting")
+	}
+	waitQueued(3)
+
+	// Each released slot should be handed to the waiting retribution
+	// with the most value at risk.
+	for _, expected := range []btcutil.Amount{30, 20} {
+		slots.release()
+
+		select {
+		case value := <-acquired:
+			if value != expected {
+				t.Fatalf("expected retribution of %v to be "+
+					"served, got %v", expected, value)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("slot not handed over")
+		}
+	}
+
+	// Upon shutdown, the remaining retribution should stop waiting.
+	close(quit)
+	select {
+	case err := <-errs:
+		if err != errBreachArbiterExiting {
+			t.Fatalf("expected shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("retribution still waiting after shutdown")
+	}
+}
+
+// TestRetributionSlotAwaitsBreachConf asserts that a retribution whose breach
+// is yet to confirm doesn't occupy a retribution slot, such that it can't hold
+// up another whose justice is ready to be served.
+func TestRetributionSlotAwaitsBreachConf(t *testing.T) {
+	published := make(chan *wire.MsgTx, 10)
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier: &mockNotifier{},
+		Wallet:   newTestWallet(published),
+		SweepScriptGen: func() ([]byte, error) {
+			return []byte{0x00, 0x14}, nil
+		},
+		MaxActiveRetributions: 1,
+	})
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	// Two channels are breached, but only the second breach confirms.
+	// The first retribution is given time to settle before the second
+	// begins, such that it would hold the only slot were it taken before
+	// its breach confirms.
+	confs := make([]chan *chainntnfs.TxConfirmation, 2)
+	for i := range confs {
+		ret := newBreachRetInfo()
+		ret.chanPoint.Index = uint32(i)
+		ret.revokedOutput.witnessType = lnwallet.CommitmentRevoke
+
+		confs[i] = make(chan *chainntnfs.TxConfirmation, 1)
+		brar.wg.Add(1)
+		go brar.exactRetribution(
+			&chainntnfs.ConfirmationEvent{Confirmed: confs[i]},
+			ret,
+		)
+		time.Sleep(50 * time.Millisecond)
+	}
+	confs[1] <- &chainntnfs.TxConfirmation{}
+
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("justice tx held up by unconfirmed breach")
+	}
+}
+
+// slowWitnessInputs returns the given number of inputs, each of whose
+// witnesses takes delay to generate, and consists of the input's index. The
+// input at index fail, if any, fails to be signed.
+func slowWitnessInputs(n int, delay time.Duration,
+	fail int) []*breachedOutput {
+
+	inputs := make([]*breachedOutput, n)
+	for i := range inputs {
+		i := i
+		inputs[i] = &breachedOutput{
+			witnessType: lnwallet.CommitmentRevoke,
+			witnessFunc: func(tx *wire.MsgTx,
+				hc *txscript.TxSigHashes,
+				inputIndex int) ([][]byte, error) {
+
+				time.Sleep(delay)
+
+				switch {
+				case inputIndex != i:
+					return nil, fmt.Errorf("input %d "+
+						"signed at index %d", i,
+						inputIndex)
+				case i == fail:
+					return nil, fmt.Errorf("input %d "+
+						"failed", i)
+				}
+				return [][]byte{{byte(i)}}, nil
+			},
+		}
+	}
+
+	return inputs
+}
+
+// TestGenerateWitnessesConcurrently asserts that witnesses generated
+// concurrently are assembled in the order of their inputs, and that the first
+// input which fails to be signed is reported.
+func TestGenerateWitnessesConcurrently(t *testing.T) {
+	t.Parallel()
+
+	const numInputs = 20
+
+	brar := &breachArbiter{
+		cfg: &BreachConfig{
+			JusticeSigningWorkers: 4,
+		},
+	}
+	tx := wire.NewMsgTx(2)
+	for i := 0; i < numInputs; i++ {
+		tx.AddTxIn(&wire.TxIn{})
+	}
+	hashCache := txscript.NewTxSigHashes(tx)
+
+	witnesses, _, err := brar.generateWitnesses(
+		tx, hashCache,
+		slowWitnessInputs(numInputs, time.Millisecond, -1),
+	)
+	if err != nil {
+		t.Fatalf("unable to generate witnesses: %v", err)
+	}
+	for i, witness := range witnesses {
+		if !reflect.DeepEqual(witness, wire.TxWitness{{byte(i)}}) {
+			t.Fatalf("unexpected witness at index %d: %x", i,
+				witness)
+		}
+	}
+
+	const failIndex = 13
+	_, failed, err := brar.generateWitnesses(
+		tx, hashCache,
+		slowWitnessInputs(numInputs, time.Millisecond, failIndex),
+	)
+	if err == nil {
+		t.Fatalf("expected failure to sign input %d", failIndex)
+	}
+	if failed != failIndex {
+		t.Fatalf("expected input %d to fail, got %d", failIndex,
+			failed)
+	}
+}
+
+// BenchmarkGenerateWitnesses measures the time taken to sign a justice
+// transaction with many HTLC inputs using a slow signer, with increasing
+// numbers of signing workers.
+func BenchmarkGenerateWitnesses(b *testing.B) {
+	const numInputs = 50
+
+	tx := wire.NewMsgTx(2)
+	for i := 0; i < numInputs; i++ {
+		tx.AddTxIn(&wire.TxIn{})
+	}
+	hashCache := txscript.NewTxSigHashes(tx)
+	inputs := slowWitnessInputs(numInputs, time.Millisecond, -1)
+
+	for _, numWorkers := range []int{1, 4, 16} {
+		brar := &breachArbiter{
+			cfg: &BreachConfig{
+				JusticeSigningWorkers: numWorkers,
+			},
+		}
+
+		b.Run(fmt.Sprintf("workers=%d", numWorkers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _, err := brar.generateWitnesses(
+					tx, hashCache, inputs,
+				)
+				if err != nil {
+					b.Fatalf("unable to generate "+
+						"witnesses: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestBreachDuringCoopClose asserts that the retribution of a channel breached
+// while being cooperatively closed is only carried out if the breach
+// transaction confirms, and is abandoned should the cooperative close confirm
+// in its place.
+func TestBreachDuringCoopClose(t *testing.T) {
+	for _, coopWins := range []bool{true, false} {
+		coopWins := coopWins
+		t.Run(fmt.Sprintf("coop_wins=%v", coopWins), func(t *testing.T) {
+			testBreachDuringCoopClose(t, coopWins)
+		})
+	}
+}
+
+func testBreachDuringCoopClose(t *testing.T, coopWins bool) {
+	notifier := &txConfNotifier{
+		confs: make(map[chainhash.Hash]chan *chainntnfs.TxConfirmation),
+	}
+	alicePeer, alice, _, cleanUp, err := createTestPeer(
+		notifier, make(chan *wire.MsgTx, 10),
+	)
+	if err != nil {
+		t.Fatalf("unable to create test channels: %v", err)
+	}
+	defer cleanUp()
+	db := alicePeer.server.chanDB
+
+	ret := newBreachRetInfo()
+	ret.chanPoint = *alice.ChannelPoint()
+	closeTestChannel(t, alice, ret.commitHash, channeldb.BreachClose)
+
+	store := newMockRetributionStore()
+	if err := store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+
+	published := make(chan *wire.MsgTx, 10)
+	brar := newTestBreachArbiter(&BreachConfig{
+		DB:       db,
+		Notifier: notifier,
+		Wallet:   newTestWallet(published),
+		Store:    store,
+		SweepScriptGen: func() ([]byte, error) {
+			return make([]byte, lnwallet.P2WPKHSize), nil
+		},
+	})
+
+	// The channel was being cooperatively closed as it was breached.
+	coopTxid := chainhash.Hash{0x0c}
+	brar.TrackCoopClose(&ret.chanPoint, coopTxid)
+
+	brar.wg.Add(1)
+	go brar.exactRetribution(
+		&chainntnfs.ConfirmationEvent{
+			Confirmed: notifier.confChan(ret.commitHash),
+		},
+		ret,
+	)
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	// Should the breach transaction confirm, justice is served as usual.
+	if !coopWins {
+		notifier.confChan(ret.commitHash) <- &chainntnfs.TxConfirmation{}
+
+		select {
+		case <-published:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("justice tx not published")
+		}
+		if countRetributions(t, store) != 1 {
+			t.Fatalf("retribution removed before justice served")
+		}
+		return
+	}
+
+	// Otherwise, once the cooperative close confirms, the retribution is
+	// abandoned without publishing anything, and the channel is fully
+	// closed.
+	notifier.confChan(coopTxid) <- &chainntnfs.TxConfirmation{}
+
+	timeout := time.After(5 * time.Second)
+	for countRetributions(t, store) != 0 {
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			t.Fatalf("retribution not abandoned")
+		}
+	}
+
+	pendingCloses, err := db.FetchClosedChannels(true)
+	if err != nil {
+		t.Fatalf("unable to fetch pending closes: %v", err)
+	}
+	if len(pendingCloses) != 0 {
+		t.Fatalf("channel still pending close")
+	}
+
+	select {
+	case tx := <-published:
+		t.Fatalf("unexpected tx %v published", tx.TxHash())
+	default:
+	}
+}
+
+// TestRetributionStoreVerify asserts that verifying the retribution store
+// reports intact, undecodable and inconsistent retributions by their keys,
+// without modifying the store.
+func TestRetributionStoreVerify(t *testing.T) {
+	tempDirName, err := ioutil.TempDir("", "channeldb")
+	if err != nil {
+		t.Fatalf("unable to initialize temp "+
+			"directory for channeldb: %v", err)
+	}
+	defer os.RemoveAll(tempDirName)
+
+	db, err := channeldb.Open(tempDirName)
+	if err != nil {
+		t.Fatalf("unable to open channeldb: %v", err)
+	}
+	defer db.Close()
+
+	store := newRetributionStore(db)
+
+	// An intact retribution's outputs each match their sign descriptor,
+	// which holds all the material needed to sign for them.
+	healthy := copyRetInfo(&retributions[0])
+	for _, bo := range []*breachedOutput{
+		healthy.selfOutput, healthy.revokedOutput,
+	} {
+		bo.amt = btcutil.Amount(bo.signDescriptor.Output.Value)
+	}
+	healthy.revokedOutput.signDescriptor.SingleTweak = nil
+	healthy.revokedOutput.signDescriptor.DoubleTweak = alicePrivKey
+	if err := store.Add(healthy, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+
+	// The revoked output of an inconsistent retribution is recorded with
+	// the witness type of our own output.
+	inconsistent := copyRetInfo(healthy)
+	inconsistent.chanPoint.Index++
+	inconsistent.revokedOutput.witnessType = lnwallet.CommitmentNoDelay
+	if err := store.Add(inconsistent, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+
+	// Finally, write a truncated retribution under the key of another
+	// channel.
+	undecodableChanPoint := inconsistent.chanPoint
+	undecodableChanPoint.Index++
+
+	var undecodableKey bytes.Buffer
+	err = writeOutpoint(&undecodableKey, &undecodableChanPoint)
+	if err != nil {
+		t.Fatalf("unable to write outpoint: %v", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		retBucket := tx.Bucket(retributionBucket)
+		return retBucket.Put(undecodableKey.Bytes(), []byte{0x01})
+	})
+	if err != nil {
+		t.Fatalf("unable to write corrupt retribution: %v", err)
+	}
+
+	report, err := store.Verify()
+	if err != nil {
+		t.Fatalf("unable to verify store: %v", err)
+	}
+
+	switch {
+	case report.Intact():
+		t.Fatalf("corrupted store reported as intact")
+	case report.Healthy != 1:
+		t.Fatalf("expected 1 healthy retribution, got %v",
+			report.Healthy)
+	case report.BucketErr != nil:
+		t.Fatalf("unexpected bucket error: %v", report.BucketErr)
+	case len(report.Undecodable) != 1 || len(report.Inconsistent) != 1:
+		t.Fatalf("expected 1 undecodable and 1 inconsistent "+
+			"retribution, got %v and %v", len(report.Undecodable),
+			len(report.Inconsistent))
+	}
+
+	undecodable := report.Undecodable[0]
+	if !bytes.Equal(undecodable.Key, undecodableKey.Bytes()) ||
+		undecodable.Err == nil {
+
+		t.Fatalf("unexpected undecodable retribution: %v",
+			spew.Sdump(undecodable))
+	}
+	if c := report.Inconsistent[0]; c.ChanPoint == nil ||
+		*c.ChanPoint != inconsistent.chanPoint || c.Err == nil {
+
+		t.Fatalf("unexpected inconsistent retribution: %v",
+			spew.Sdump(c))
+	}
+
+	// Verification mustn't have modified the store.
+	err = db.View(func(tx *bolt.Tx) error {
+		retBucket := tx.Bucket(retributionBucket)
+		if retBucket.Get(undecodableKey.Bytes()) == nil {
+			return errors.New("undecodable retribution removed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if count := countRetributions(t, store); count != 2 {
+		t.Fatalf("expected 2 retributions, found %v", count)
+	}
+}
+
+// reorgNotifier is a mock notifier which hands out the confirmation event of
+// each registration over a channel, recording the number of confirmations
+// requested.
+type reorgNotifier struct {
+	mockNotifier
+
+	registrations chan *chainntnfs.ConfirmationEvent
+	numConfs      chan uint32
+}
+
+func (n *reorgNotifier) RegisterConfirmationsNtfn(txid *chainhash.Hash,
+	numConfs, heightHint uint32) (*chainntnfs.ConfirmationEvent, error) {
+
+	confNtfn := &chainntnfs.ConfirmationEvent{
+		Confirmed:    make(chan *chainntnfs.TxConfirmation, 1),
+		NegativeConf: make(chan int32, 1),
+	}
+	n.numConfs <- numConfs
+	n.registrations <- confNtfn
+
+	return confNtfn, nil
+}
+
+// TestUnilateralCloseSafetyDepth asserts that the closure of a unilaterally
+// closed channel is only considered final once its closing transaction has
+// reached the safety depth, and that should the closing transaction be reorged
+// out beforehand, we wait anew for it to reconfirm.
+func TestUnilateralCloseSafetyDepth(t *testing.T) {
+	notifier := &reorgNotifier{
+		registrations: make(chan *chainntnfs.ConfirmationEvent, 2),
+		numConfs:      make(chan uint32, 2),
+	}
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier:                   notifier,
+		UnilateralCloseSafetyDepth: 6,
+	})
+
+	type result struct {
+		height uint32
+		ok     bool
+	}
+	results := make(chan result, 1)
+	closeTxid := chainhash.Hash{0x01}
+	go func() {
+		height, ok := brar.waitForCloseConf(
+			&closeTxid, brar.cfg.UnilateralCloseSafetyDepth, 100,
+		)
+		results <- result{height, ok}
+	}()
+
+	nextRegistration := func() *chainntnfs.ConfirmationEvent {
+		select {
+		case numConfs := <-notifier.numConfs:
+			if numConfs != 6 {
+				t.Fatalf("expected 6 confs, registered for %v",
+					numConfs)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no confirmation registered")
+		}
+		return <-notifier.registrations
+	}
+
+	// Reorging out the closing transaction shouldn't conclude the wait,
+	// but rather register for its confirmation anew.
+	confNtfn := nextRegistration()
+	confNtfn.NegativeConf <- 2
+
+	confNtfn = nextRegistration()
+	select {
+	case res := <-results:
+		t.Fatalf("wait concluded after reorg: %v", res)
+	default:
+	}
+
+	confNtfn.Confirmed <- &chainntnfs.TxConfirmation{BlockHeight: 105}
+	select {
+	case res := <-results:
+		if !res.ok || res.height != 105 {
+			t.Fatalf("unexpected result: %v", res)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("wait not concluded after confirmation")
+	}
+
+	// A zero safety depth falls back to the default.
+	brar = newTestBreachArbiter(&BreachConfig{})
+	depth := brar.cfg.UnilateralCloseSafetyDepth
+	if depth != defaultCloseSafetyDepth {
+		t.Fatalf("expected default safety depth, found %v", depth)
+	}
+}
+
+// TestWatchChannel asserts that WatchChannel only returns once the registered
+// channel is being watched, both by a dedicated breachObserver and on the
+// observer pool, and that it fails once the breach arbiter has shut down.
+func TestWatchChannel(t *testing.T) {
+	notifier := &mockNotifier{
+		epochChan: make(chan *chainntnfs.BlockEpoch),
+	}
+	_, alice, _, cleanUp, err := createTestPeer(
+		notifier, make(chan *wire.MsgTx, 10),
+	)
+	if err != nil {
+		t.Fatalf("unable to create test channels: %v", err)
+	}
+	defer cleanUp()
+
+	for _, workers := range []int{0, 1} {
+		brar := newTestBreachArbiter(&BreachConfig{
+			Notifier:           notifier,
+			MaxObserverWorkers: workers,
+		})
+		if brar.observerPool != nil {
+			brar.observerPool.start()
+		}
+
+		brar.wg.Add(1)
+		go brar.contractObserver(nil)
+
+		if err := brar.WatchChannel(alice); err != nil {
+			t.Fatalf("unable to watch channel: %v", err)
+		}
+		if !brar.IsWatching(alice.ChannelPoint()) {
+			t.Fatalf("channel not watched with %d workers",
+				workers)
+		}
+
+		brar.Stop()
+
+		err := brar.WatchChannel(alice)
+		if err != errBreachArbiterExiting {
+			t.Fatalf("expected errBreachArbiterExiting, got %v",
+				err)
+		}
+	}
+}
+
+// TestSettleChannel asserts that SettleChannel only returns once the settled
+// channel's observer has been torn down, both for a dedicated breachObserver
+// and on the observer pool, and that settling a channel which isn't being
+// watched is reported.
+func TestSettleChannel(t *testing.T) {
+	notifier := &mockNotifier{
+		epochChan: make(chan *chainntnfs.BlockEpoch),
+	}
+
+	for _, workers := range []int{0, 1} {
+		_, alice, _, cleanUp, err := createTestPeer(
+			notifier, make(chan *wire.MsgTx, 10),
+		)
+		if err != nil {
+			t.Fatalf("unable to create test channels: %v", err)
+		}
+		defer cleanUp()
+
+		brar := newTestBreachArbiter(&BreachConfig{
+			Notifier:           notifier,
+			MaxObserverWorkers: workers,
+		})
+		if brar.observerPool != nil {
+			brar.observerPool.start()
+		}
+
+		brar.wg.Add(1)
+		go brar.contractObserver(nil)
+
+		if err := brar.WatchChannel(alice); err != nil {
+			t.Fatalf("unable to watch channel: %v", err)
+		}
+
+		chanPoint := alice.ChannelPoint()
+		if err := brar.SettleChannel(chanPoint); err != nil {
+			t.Fatalf("unable to settle channel: %v", err)
+		}
+
+		if n := brar.numActiveObservers(); n != 0 {
+			t.Fatalf("expected no observers with %d workers, "+
+				"found %d", workers, n)
+		}
+
+		err = brar.SettleChannel(chanPoint)
+		if err != ErrNoBreachObserver {
+			t.Fatalf("expected ErrNoBreachObserver, got %v", err)
+		}
+
+		brar.Stop()
+	}
+}
+
+// TestRetributionHtlcOutputs asserts that the HTLC outputs of a breach are
+// tracked within its retribution, each with the witness type matching the
+// party which offered the HTLC.
+func TestRetributionHtlcOutputs(t *testing.T) {
+	brar := newTestBreachArbiter(&BreachConfig{})
+
+	newSignDesc := func(value int64) lnwallet.SignDescriptor {
+		return lnwallet.SignDescriptor{
+			PubKey:        alicePrivKey.PubKey(),
+			DoubleTweak:   alicePrivKey,
+			WitnessScript: []byte{txscript.OP_TRUE},
+			Output:        &wire.TxOut{Value: value},
+		}
+	}
+	breachTx := wire.NewMsgTx(2)
+	breachInfo := &lnwallet.BreachRetribution{
+		BreachTransaction:    breachTx,
+		LocalOutputSignDesc:  newSignDesc(1000),
+		RemoteOutputSignDesc: newSignDesc(2000),
+		HtlcRetributions: []lnwallet.HtlcRetribution{
+			{
+				SignDesc:   newSignDesc(3000),
+				OutPoint:   wire.OutPoint{Index: 2},
+				IsIncoming: true,
+			},
+			{
+				SignDesc: newSignDesc(4000),
+				OutPoint: wire.OutPoint{Index: 3},
+			},
+		},
+	}
+
+	ret := brar.newRetributionInfo(
+		&breachOutPoints[0], breachInfo, *alicePrivKey.PubKey(),
+		10000, 1000,
+	)
+	if len(ret.htlcOutputs) != 2 {
+		t.Fatalf("expected 2 htlc outputs, got %v",
+			len(ret.htlcOutputs))
+	}
+
+	expTypes := []lnwallet.WitnessType{
+		lnwallet.HtlcOfferedRevoke, lnwallet.HtlcAcceptedRevoke,
+	}
+	for i, htlcOutput := range ret.htlcOutputs {
+		htlcRet := &breachInfo.HtlcRetributions[i]
+		switch {
+		case htlcOutput.witnessType != expTypes[i]:
+			t.Fatalf("expected htlc %d to have witness type %v, "+
+				"got %v", i, expTypes[i], htlcOutput.witnessType)
+		case htlcOutput.outpoint != htlcRet.OutPoint:
+			t.Fatalf("htlc %d has outpoint %v, expected %v", i,
+				htlcOutput.outpoint, htlcRet.OutPoint)
+		case int64(htlcOutput.amt) != htlcRet.SignDesc.Output.Value:
+			t.Fatalf("htlc %d has amount %v, expected %v", i,
+				htlcOutput.amt, htlcRet.SignDesc.Output.Value)
+		}
+
+		if err := htlcOutput.checkSigningMaterial(); err != nil {
+			t.Fatalf("unable to sign for htlc %d: %v", i, err)
+		}
+	}
+}
+
+// TestClampHeightHint asserts that height hints ahead of the best height are
+// clamped to it, while those at or below it are left untouched.
+func TestClampHeightHint(t *testing.T) {
+	chainIO := &heightChainIO{height: 100}
+	brar := newTestBreachArbiter(&BreachConfig{
+		ChainIO: chainIO,
+	})
+
+	tests := []struct {
+		hint     uint32
+		expected uint32
+	}{
+		{hint: 50, expected: 50},
+		{hint: 100, expected: 100},
+		{hint: 150, expected: 100},
+	}
+	for _, test := range tests {
+		hint := brar.clampHeightHint(test.hint)
+		if hint != test.expected {
+			t.Fatalf("expected hint %v to be clamped to %v, got %v",
+				test.hint, test.expected, hint)
+		}
+	}
+
+	// The hint passed to the notifier must be clamped as well.
+	notifier := &heightHintNotifier{
+		heightHints: make(chan uint32, 1),
+	}
+	notifier.confs = make(map[chainhash.Hash]chan *chainntnfs.TxConfirmation)
+	brar.cfg.Notifier = notifier
+
+	if _, err := brar.registerConf(&chainhash.Hash{}, 1, 500); err != nil {
+		t.Fatalf("unable to register for confirmation: %v", err)
+	}
+	if hint := <-notifier.heightHints; hint != 100 {
+		t.Fatalf("expected clamped height hint 100, got %v", hint)
+	}
+}
+
+// TestExpectedJusticeFee asserts that the fee the justice transaction is
+// expected to pay is estimated once the breach is detected, that it survives a
+// serialization round trip, and that it's reported along with the projected
+// recovery.
+func TestExpectedJusticeFee(t *testing.T) {
+	store := newMockRetributionStore()
+	brar := newTestBreachArbiter(&BreachConfig{
+		ChainIO:   &breachChainIO{},
+		Estimator: lnwallet.StaticFeeEstimator{FeeRate: 40},
+		Store:     store,
+	})
+
+	newSignDesc := func(value int64) lnwallet.SignDescriptor {
+		return lnwallet.SignDescriptor{
+			PubKey:        alicePrivKey.PubKey(),
+			DoubleTweak:   alicePrivKey,
+			WitnessScript: []byte{txscript.OP_TRUE},
+			Output:        &wire.TxOut{Value: value},
+		}
+	}
+	breachInfo := &lnwallet.BreachRetribution{
+		BreachTransaction:    wire.NewMsgTx(2),
+		LocalOutputSignDesc:  newSignDesc(100000),
+		RemoteOutputSignDesc: newSignDesc(200000),
+	}
+
+	ret := brar.newRetributionInfo(
+		&breachOutPoints[0], breachInfo, *alicePrivKey.PubKey(),
+		10000, 1000,
+	)
+	expFee := brar.justiceFee(
+		[]*breachedOutput{ret.selfOutput, ret.revokedOutput}, 0,
+	)
+	if expFee == 0 || ret.expectedJusticeFee != expFee {
+		t.Fatalf("expected justice fee %v, got %v", expFee,
+			ret.expectedJusticeFee)
+	}
+
+	var buf bytes.Buffer
+	if err := ret.Encode(&buf); err != nil {
+		t.Fatalf("unable to serialize retribution: %v", err)
+	}
+	desRet := &retributionInfo{}
+	if err := desRet.Decode(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("unable to deserialize retribution: %v", err)
+	}
+	if desRet.expectedJusticeFee != expFee {
+		t.Fatalf("expected justice fee %v after round trip, got %v",
+			expFee, desRet.expectedJusticeFee)
+	}
+
+	if err := store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+	diag, err := brar.DumpRetribution(&ret.chanPoint)
+	if err != nil {
+		t.Fatalf("unable to dump retribution: %v", err)
+	}
+	if diag.ExpectedJusticeFee != expFee {
+		t.Fatalf("expected diagnostic justice fee %v, got %v", expFee,
+			diag.ExpectedJusticeFee)
+	}
+	if diag.ProjectedRecovery != 300000-expFee {
+		t.Fatalf("expected projected recovery %v, got %v",
+			300000-expFee, diag.ProjectedRecovery)
+	}
+}
+
+// pollingChainIO is a mock BlockChainIO backed by a mutable chain of blocks,
+// which may be extended or reorganized while being polled.
+type pollingChainIO struct {
+	mockChainIO
+
+	mu     sync.Mutex
+	blocks []*wire.MsgBlock
+}
+
+func (c *pollingChainIO) setBlocks(blocks []*wire.MsgBlock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.blocks = blocks
+}
+
+func (c *pollingChainIO) GetBestBlock() (*chainhash.Hash, int32, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	height := len(c.blocks) - 1
+	hash := c.blocks[height].BlockHash()
+	return &hash, int32(height), nil
+}
+
+func (c *pollingChainIO) GetBlockHash(height int64) (*chainhash.Hash, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if height >= int64(len(c.blocks)) {
+		return nil, fmt.Errorf("no block at height %v", height)
+	}
+	hash := c.blocks[height].BlockHash()
+	return &hash, nil
+}
+
+func (c *pollingChainIO) GetBlock(hash *chainhash.Hash) (*wire.MsgBlock,
+	error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, block := range c.blocks {
+		if block.BlockHash() == *hash {
+			return block, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown block %v", hash)
+}
+
+// TestPollingConfWaiter asserts that a PollingConfWaiter dispatches the
+// confirmation of a transaction only once it has reached the requested depth,
+// and that a confirmation reorged out beforehand is disregarded in favour of
+// the transaction's confirmation in the new chain.
+func TestPollingConfWaiter(t *testing.T) {
+	tx := wire.NewMsgTx(2)
+	tx.AddTxOut(&wire.TxOut{Value: 1000})
+	txid := tx.TxHash()
+
+	// Each block is made unique by its nonce, which differs between the
+	// original chain and the one it's reorganized into.
+	newBlock := func(nonce uint32, txns ...*wire.MsgTx) *wire.MsgBlock {
+		block := &wire.MsgBlock{
+			Header: wire.BlockHeader{Nonce: nonce},
+		}
+		block.Transactions = append(
+			[]*wire.MsgTx{wire.NewMsgTx(1)}, txns...,
+		)
+		return block
+	}
+	var blocks []*wire.MsgBlock
+	for i := uint32(0); i < 5; i++ {
+		blocks = append(blocks, newBlock(i))
+	}
+	blocks = append(blocks, newBlock(5, tx))
+
+	chainIO := &pollingChainIO{blocks: blocks}
+	waiter := &PollingConfWaiter{
+		ChainIO:  chainIO,
+		Interval: 10 * time.Millisecond,
+	}
+	quit := make(chan struct{})
+	defer close(quit)
+
+	confEvent, err := waiter.WaitForConf(&txid, 3, 3, quit)
+	if err != nil {
+		t.Fatalf("unable to wait for conf: %v", err)
+	}
+
+	// With the transaction at a depth of one, no confirmation should be
+	// dispatched.
+	select {
+	case conf := <-confEvent.Confirmed:
+		t.Fatalf("unexpected confirmation: %v", spew.Sdump(conf))
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Reorganize the transaction into the following block, extending the
+	// chain so that it reaches a depth of three.
+	reorged := append([]*wire.MsgBlock{}, blocks[:5]...)
+	reorged = append(reorged, newBlock(100), newBlock(101, tx),
+		newBlock(102), newBlock(103))
+	chainIO.setBlocks(reorged)
+
+	select {
+	case conf := <-confEvent.Confirmed:
+		if conf.BlockHeight != 6 || conf.TxIndex != 1 {
+			t.Fatalf("unexpected confirmation: %v",
+				spew.Sdump(conf))
+		}
+		if *conf.BlockHash != reorged[6].BlockHash() {
+			t.Fatalf("expected block hash %v, got %v",
+				reorged[6].BlockHash(), conf.BlockHash)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("confirmation not dispatched")
+	}
+}
+
+// TestJusticeApproval asserts that a retribution whose value at risk exceeds
+// the JusticeApprovalThreshold withholds its justice transaction until
+// approved by the operator, escalating once approval times out, and that the
+// approval state is persisted.
+func TestJusticeApproval(t *testing.T) {
+	notifier := &mockNotifier{
+		confChannel: make(chan *chainntnfs.TxConfirmation, 1),
+	}
+	published := make(chan *wire.MsgTx, 10)
+	approvalDiags := make(chan *RetributionDiagnostic, 1)
+	interventionDiags := make(chan *RetributionDiagnostic, 1)
+
+	store := newMockRetributionStore()
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier: notifier,
+		Wallet:   newTestWallet(published),
+		Store:    store,
+		SweepScriptGen: func() ([]byte, error) {
+			return []byte{0x00, 0x14}, nil
+		},
+		JusticeApprovalThreshold: 1,
+		JusticeApprovalTimeout:   50 * time.Millisecond,
+		Events: &mockBreachEvents{
+			approvalRequired: func(diag *RetributionDiagnostic) {
+				approvalDiags <- diag
+			},
+			manualIntervention: func(diag *RetributionDiagnostic) {
+				interventionDiags <- diag
+			},
+		},
+	})
+
+	ret := newBreachRetInfo()
+	if err := store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+
+	brar.wg.Add(1)
+	go brar.exactRetribution(
+		&chainntnfs.ConfirmationEvent{Confirmed: notifier.confChannel},
+		ret,
+	)
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	// Before the breach transaction confirms, there's nothing to approve.
+	if err := brar.ApproveRetribution(&ret.chanPoint); err == nil {
+		t.Fatalf("expected approval before breach conf to fail")
+	}
+
+	notifier.confChannel <- &chainntnfs.TxConfirmation{}
+	select {
+	case diag := <-approvalDiags:
+		if diag.Phase != retPhaseAwaitingApproval.String() {
+			t.Fatalf("unexpected diagnostic: %v", spew.Sdump(diag))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("approval not requested")
+	}
+
+	// Once approval times out, the operator is alerted, but the justice
+	// transaction continues to be withheld.
+	select {
+	case <-interventionDiags:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("manual intervention not requested")
+	}
+	select {
+	case tx := <-published:
+		t.Fatalf("justice tx %v published without approval",
+			tx.TxHash())
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The pending approval should have been persisted, so that it's
+	// awaited anew after a restart.
+	err := store.ForAll(func(r *retributionInfo) error {
+		if r.approval != justiceApprovalPending {
+			return fmt.Errorf("expected pending approval, got %v",
+				r.approval)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected persisted retribution: %v", err)
+	}
+
+	if err := brar.ApproveRetribution(&ret.chanPoint); err != nil {
+		t.Fatalf("unable to approve retribution: %v", err)
+	}
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("justice tx not published after approval")
+	}
+
+	err = store.ForAll(func(r *retributionInfo) error {
+		if r.approval != justiceApprovalGranted {
+			return fmt.Errorf("expected granted approval, got %v",
+				r.approval)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected persisted retribution: %v", err)
+	}
+}
+
+// flakyRemoveStore is a mockRetributionStore whose removals fail while
+// failRemove is set.
+type flakyRemoveStore struct {
+	*mockRetributionStore
+
+	failRemove int32
+}
+
+func (s *flakyRemoveStore) Remove(key *wire.OutPoint) error {
+	if atomic.LoadInt32(&s.failRemove) != 0 {
+		return errors.New("transient db error")
+	}
+
+	return s.mockRetributionStore.Remove(key)
+}
+
+// TestRetributionReaper asserts that the reaper retries the removal of the
+// records of completed retributions until it succeeds, leaving incomplete and
+// active retributions untouched, and that it reports the channel as resolved
+// once its record is removed.
+func TestRetributionReaper(t *testing.T) {
+	store := &flakyRemoveStore{
+		mockRetributionStore: newMockRetributionStore(),
+		failRemove:           1,
+	}
+	resolved := make(chan wire.OutPoint, 3)
+	brar := newTestBreachArbiter(&BreachConfig{
+		Store: store,
+		Events: &mockBreachEvents{
+			channelResolved: func(chanPoint wire.OutPoint,
+				_ btcutil.Amount, _ uint32,
+				_ *JusticeBreakdown) {
+
+				resolved <- chanPoint
+			},
+		},
+	})
+
+	completed := copyRetInfo(&retributions[0])
+	completed.completed = true
+	active := copyRetInfo(&retributions[1])
+	active.completed = true
+	pending := copyRetInfo(&retributions[0])
+	pending.chanPoint = wire.OutPoint{Index: 99}
+	for _, ret := range []*retributionInfo{completed, active, pending} {
+		if err := store.Add(ret, nil); err != nil {
+			t.Fatalf("unable to add retribution: %v", err)
+		}
+	}
+
+	// A retribution that's still being finalized removes its own record.
+	brar.setRetributionPhase(&active.chanPoint, retPhaseAwaitingJusticeConf)
+
+	// While removals fail, every record must be retained, with the
+	// failure counted.
+	failures := make(map[wire.OutPoint]uint32)
+	brar.reapRetributions(failures)
+	if count := countRetributions(t, store); count != 3 {
+		t.Fatalf("expected 3 retributions in store, found %v", count)
+	}
+	if failures[completed.chanPoint] != 1 || len(failures) != 1 {
+		t.Fatalf("unexpected removal failures: %v", failures)
+	}
+
+	// Once the database recovers, only the completed retribution that
+	// isn't active should be removed, and its channel reported resolved.
+	atomic.StoreInt32(&store.failRemove, 0)
+	brar.reapRetributions(failures)
+	if count := countRetributions(t, store); count != 2 {
+		t.Fatalf("expected 2 retributions in store, found %v", count)
+	}
+	if len(failures) != 0 {
+		t.Fatalf("unexpected removal failures: %v", failures)
+	}
+	err := store.ForAll(func(ret *retributionInfo) error {
+		if ret.chanPoint == completed.chanPoint {
+			return fmt.Errorf("completed retribution retained")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected retribution in store: %v", err)
+	}
+
+	select {
+	case chanPoint := <-resolved:
+		if chanPoint != completed.chanPoint {
+			t.Fatalf("expected ChannelPoint(%v) resolved, got %v",
+				completed.chanPoint, chanPoint)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("channel not reported resolved")
+	}
+}
+
+// TestBreachProof asserts that the proof of a breach is assembled as it's
+// detected, that it verifies and survives a serialization round trip, that
+// it's retrievable both while the breach is pending and once archived, and
+// that a proof revealing the wrong commitment secret fails verification.
+func TestBreachProof(t *testing.T) {
+	revocationBase, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("unable to generate revocation base: %v", err)
+	}
+	commitSecret, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("unable to generate commitment secret: %v", err)
+	}
+
+	// The revoked output is locked to the revocation key derived from the
+	// commitment secret.
+	revocationKey := lnwallet.DeriveRevocationPubkey(
+		revocationBase.PubKey(),
+		lnwallet.ComputeCommitmentPoint(commitSecret.Serialize()),
+	)
+	witnessScript, err := txscript.NewScriptBuilder().
+		AddData(revocationKey.SerializeCompressed()).
+		AddOp(txscript.OP_CHECKSIG).Script()
+	if err != nil {
+		t.Fatalf("unable to build witness script: %v", err)
+	}
+	scriptHash := sha256.Sum256(witnessScript)
+	pkScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_0).
+		AddData(scriptHash[:]).Script()
+	if err != nil {
+		t.Fatalf("unable to build pk script: %v", err)
+	}
+
+	chanPoint := breachOutPoints[0]
+	breachTx := wire.NewMsgTx(2)
+	breachTx.AddTxIn(&wire.TxIn{PreviousOutPoint: chanPoint})
+	breachTx.AddTxOut(&wire.TxOut{Value: 1000, PkScript: []byte{0x00}})
+	breachTx.AddTxOut(&wire.TxOut{Value: 2000, PkScript: pkScript})
+
+	breachInfo := &lnwallet.BreachRetribution{
+		BreachTransaction: breachTx,
+		RevokedStateNum:   42,
+		LocalOutputSignDesc: lnwallet.SignDescriptor{
+			Output: &wire.TxOut{Value: 1000},
+		},
+		RemoteOutpoint: wire.OutPoint{Hash: breachTx.TxHash(), Index: 1},
+		RemoteOutputSignDesc: lnwallet.SignDescriptor{
+			PubKey:        revocationBase.PubKey(),
+			DoubleTweak:   commitSecret,
+			WitnessScript: witnessScript,
+			Output: &wire.TxOut{
+				Value:    2000,
+				PkScript: pkScript,
+			},
+		},
+	}
+
+	store := newMockRetributionStore()
+	brar := newTestBreachArbiter(&BreachConfig{
+		Store: store,
+	})
+	ret := brar.newRetributionInfo(
+		&chanPoint, breachInfo, *alicePrivKey.PubKey(), 10000, 1000,
+	)
+	if ret.breachProof == nil {
+		t.Fatalf("breach proof not assembled")
+	}
+	if err := ret.breachProof.Verify(); err != nil {
+		t.Fatalf("unable to verify breach proof: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := ret.breachProof.Encode(&buf); err != nil {
+		t.Fatalf("unable to serialize breach proof: %v", err)
+	}
+	proof := &BreachProof{}
+	if err := proof.Decode(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("unable to deserialize breach proof: %v", err)
+	}
+	var reencoded bytes.Buffer
+	if err := proof.Encode(&reencoded); err != nil {
+		t.Fatalf("unable to reserialize breach proof: %v", err)
+	}
+	if !bytes.Equal(reencoded.Bytes(), buf.Bytes()) {
+		t.Fatalf("breach proof changed across round trip: %v",
+			spew.Sdump(proof))
+	}
+	if err := proof.Verify(); err != nil {
+		t.Fatalf("unable to verify decoded breach proof: %v", err)
+	}
+
+	// The proof must be retrievable while the breach is pending, and once
+	// it has been archived.
+	if err := store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+	if _, err := brar.BreachProof(&chanPoint); err != nil {
+		t.Fatalf("unable to fetch pending breach proof: %v", err)
+	}
+	if err := store.Archive(&ArchivedBreach{retribution: ret}); err != nil {
+		t.Fatalf("unable to archive breach: %v", err)
+	}
+	archivedProof, err := brar.BreachProof(&chanPoint)
+	if err != nil {
+		t.Fatalf("unable to fetch archived breach proof: %v", err)
+	}
+	if archivedProof.RevokedStateNum != 42 {
+		t.Fatalf("expected revoked state 42, got %v",
+			archivedProof.RevokedStateNum)
+	}
+	if _, err := brar.BreachProof(&breachOutPoints[1]); err == nil {
+		t.Fatalf("expected fetching proof of unknown breach to fail")
+	}
+
+	// A proof revealing a commitment secret other than that of the
+	// revoked state must not verify.
+	proof.CommitSecret[0] ^= 0x01
+	if err := proof.Verify(); err == nil {
+		t.Fatalf("expected proof with wrong secret to fail")
+	}
+}
+
+// lockedSigner is a Signer which fails with lnwallet.ErrWalletLocked while its
+// wallet is locked.
+type lockedSigner struct {
+	lnwallet.Signer
+
+	locked int32
+}
+
+func (s *lockedSigner) SignOutputRaw(tx *wire.MsgTx,
+	signDesc *lnwallet.SignDescriptor) ([]byte, error) {
+
+	if atomic.LoadInt32(&s.locked) == 1 {
+		return nil, lnwallet.ErrWalletLocked
+	}
+
+	return s.Signer.SignOutputRaw(tx, signDesc)
+}
+
+// TestWalletLockedRetribution asserts that a retribution whose justice
+// transaction can't be signed as the wallet is locked remains persisted,
+// alerts the operator, and resumes once the wallet is unlocked, without
+// skipping any of its HTLC outputs.
+func TestWalletLockedRetribution(t *testing.T) {
+	notifier := &mockNotifier{
+		confChannel: make(chan *chainntnfs.TxConfirmation, 1),
+	}
+	published := make(chan *wire.MsgTx, 10)
+	lockedDiags := make(chan *RetributionDiagnostic, 1)
+	signer := &lockedSigner{
+		Signer: &mockSigner{key: alicePrivKey},
+		locked: 1,
+	}
+
+	store := newMockRetributionStore()
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier: notifier,
+		Wallet: &lnwallet.LightningWallet{
+			WalletController: &mockWalletController{
+				rootKey:               alicePrivKey,
+				publishedTransactions: published,
+			},
+			Cfg: lnwallet.Config{
+				Signer: signer,
+			},
+		},
+		Store: store,
+		SweepScriptGen: func() ([]byte, error) {
+			return []byte{0x00, 0x14}, nil
+		},
+		Events: &mockBreachEvents{
+			walletLocked: func(diag *RetributionDiagnostic) {
+				lockedDiags <- diag
+			},
+		},
+	})
+
+	ret := newBreachRetInfo()
+	numInputs := 2 + len(ret.htlcOutputs)
+	if err := store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+
+	brar.wg.Add(1)
+	go brar.exactRetribution(
+		&chainntnfs.ConfirmationEvent{Confirmed: notifier.confChannel},
+		ret,
+	)
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	notifier.confChannel <- &chainntnfs.TxConfirmation{}
+	select {
+	case diag := <-lockedDiags:
+		if diag.Phase != retPhaseWalletLocked.String() {
+			t.Fatalf("unexpected diagnostic: %v", spew.Sdump(diag))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("operator not alerted of locked wallet")
+	}
+
+	select {
+	case tx := <-published:
+		t.Fatalf("justice tx %v published while wallet locked",
+			tx.TxHash())
+	case <-time.After(50 * time.Millisecond):
+	}
+	if n := countRetributions(t, store); n != 1 {
+		t.Fatalf("expected retribution to remain persisted, found %v",
+			n)
+	}
+
+	atomic.StoreInt32(&signer.locked, 0)
+	brar.WalletUnlocked()
+
+	select {
+	case tx := <-published:
+		if len(tx.TxIn) != numInputs {
+			t.Fatalf("expected justice tx to sweep %v inputs, "+
+				"got %v", numInputs, len(tx.TxIn))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("justice tx not published after unlock")
+	}
+}
+
+// TestSelectCPFPFunding asserts that the wallet coins funding a CPFP child
+// cover its fee at their own weight, and that change which would be dust is
+// dropped into the fee.
+func TestSelectCPFPFunding(t *testing.T) {
+	const (
+		feeRate   = btcutil.Amount(10)
+		dustLimit = btcutil.Amount(500)
+	)
+
+	// The fee grows with each coin spent by the child, and with its change
+	// output, if any.
+	feeFor := func(numCoins int, change bool) btcutil.Amount {
+		weight := cpfpChildWeight(1 + numCoins)
+		if change {
+			weight += changeOutputWeight
+		}
+		return feeRate * btcutil.Amount(weight)
+	}
+	oneCoinFee := feeFor(1, false)
+
+	newUtxo := func(index uint32, value btcutil.Amount) *lnwallet.Utxo {
+		return &lnwallet.Utxo{
+			Value:    value,
+			OutPoint: wire.OutPoint{Index: index},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		utxos    []*lnwallet.Utxo
+		numCoins int
+		change   btcutil.Amount
+		fails    bool
+	}{
+		{
+			// The largest coin pays the fee, leaving enough
+			// change to be returned to the wallet.
+			name: "change",
+			utxos: []*lnwallet.Utxo{
+				newUtxo(0, 1000),
+				newUtxo(1, oneCoinFee+10000),
+			},
+			numCoins: 1,
+			change:   oneCoinFee + 10000 - feeFor(1, true),
+		},
+		{
+			// Change below the dust limit is dropped into the
+			// fee.
+			name: "dust change",
+			utxos: []*lnwallet.Utxo{
+				newUtxo(0, oneCoinFee+dustLimit/2),
+			},
+			numCoins: 1,
+			change:   0,
+		},
+		{
+			// Neither coin alone pays the fee, so a second round
+			// of selection adds another, whose weight raises the
+			// fee once more.
+			name: "second round",
+			utxos: []*lnwallet.Utxo{
+				newUtxo(0, oneCoinFee-1),
+				newUtxo(1, oneCoinFee-1),
+			},
+			numCoins: 2,
+			change:   2*(oneCoinFee-1) - feeFor(2, true),
+		},
+		{
+			name: "insufficient",
+			utxos: []*lnwallet.Utxo{
+				newUtxo(0, oneCoinFee/4),
+				newUtxo(1, oneCoinFee/4),
+			},
+			fails: true,
+		},
+	}
+
+	for _, test := range tests {
+		coins, change, err := selectCPFPFunding(
+			test.utxos, feeFor, dustLimit,
+		)
+		switch {
+		case test.fails && err == nil:
+			t.Fatalf("%s: expected selection to fail", test.name)
+		case test.fails:
+			continue
+		case err != nil:
+			t.Fatalf("%s: unable to select coins: %v", test.name,
+				err)
+		}
+
+		if len(coins) != test.numCoins {
+			t.Fatalf("%s: expected %d coins, got %d", test.name,
+				test.numCoins, len(coins))
+		}
+		if change != test.change {
+			t.Fatalf("%s: expected change %v, got %v", test.name,
+				test.change, change)
+		}
+
+		// The coins must pay the fee of the child they fund, and
+		// any change must not be dust.
+		var total btcutil.Amount
+		for _, coin := range coins {
+			total += coin.Value
+		}
+		if total-change < feeFor(len(coins), change != 0) {
+			t.Fatalf("%s: coins of %v with change %v don't cover "+
+				"fee", test.name, total, change)
+		}
+		if change != 0 && change < dustLimit {
+			t.Fatalf("%s: change %v is dust", test.name, change)
+		}
+	}
+}
+
+// TestBreachArbiterStartStopRace asserts that stopping the breach arbiter while
+// it's being started awaits the start, such that every goroutine it spawns is
+// shut down, and that a stopped breach arbiter can't be started.
+func TestBreachArbiterStartStopRace(t *testing.T) {
+	tempDirName, err := ioutil.TempDir("", "channeldb")
+	if err != nil {
+		t.Fatalf("unable to initialize temp "+
+			"directory for channeldb: %v", err)
+	}
+	defer os.RemoveAll(tempDirName)
+
+	db, err := channeldb.Open(tempDirName)
+	if err != nil {
+		t.Fatalf("unable to open channeldb: %v", err)
+	}
+	defer db.Close()
+
+	newArbiter := func() *breachArbiter {
+		return newTestBreachArbiter(&BreachConfig{
+			DB: db,
+			Notifier: &mockNotifier{
+				confChannel: make(
+					chan *chainntnfs.TxConfirmation,
+				),
+			},
+		})
+	}
+
+	for i := 0; i < 20; i++ {
+		brar := newArbiter()
+
+		started := make(chan error, 1)
+		go func() {
+			started <- brar.Start()
+		}()
+		if err := brar.Stop(); err != nil {
+			t.Fatalf("unable to stop breach arbiter: %v", err)
+		}
+
+		// Start either completed before Stop, or found the breach
+		// arbiter already stopped.
+		select {
+		case err := <-started:
+			if err != nil && err != errBreachArbiterStopped {
+				t.Fatalf("unable to start breach arbiter: %v",
+					err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("start didn't return")
+		}
+	}
+
+	brar := newArbiter()
+	if err := brar.Stop(); err != nil {
+		t.Fatalf("unable to stop breach arbiter: %v", err)
+	}
+	if err := brar.Start(); err != errBreachArbiterStopped {
+		t.Fatalf("expected start of stopped breach arbiter to fail, "+
+			"got %v", err)
+	}
+}
+
+// TestRetributionDeadline asserts that the deadline of a retribution is derived
+// from the earliest height at which the breaching party may claim any of the
+// breached outputs once the breach confirms, that it's persisted and reported,
+// and that the confirmation target of its justice transaction is relaxed
+// accordingly.
+func TestRetributionDeadline(t *testing.T) {
+	const breachHeight = 100
+
+	store := newMockRetributionStore()
+	brar := newTestBreachArbiter(&BreachConfig{
+		ChainIO: &heightChainIO{height: breachHeight + 20},
+		Store:   store,
+	})
+
+	ret := newBreachRetInfo()
+	ret.revokedOutput.contestDelay = 144
+
+	offered := *ret.revokedOutput
+	offered.outpoint.Index = 2
+	offered.witnessType = lnwallet.HtlcOfferedRevoke
+	offered.contestDelay = 0
+	offered.refundTimeout = breachHeight + 100
+	ret.htlcOutputs = []*breachedOutput{&offered}
+
+	// An HTLC offered by the breaching party may only be reclaimed by them
+	// once it times out, before their revoked output's CSV delay elapses.
+	brar.recordRetributionDeadline(
+		ret, &chainntnfs.TxConfirmation{BlockHeight: breachHeight},
+	)
+	if ret.deadlineHeight != offered.refundTimeout {
+		t.Fatalf("expected deadline %v, got %v", offered.refundTimeout,
+			ret.deadlineHeight)
+	}
+
+	// With 80 blocks remaining until the deadline, the confirmation target
+	// is relaxed to half of them.
+	inputs := []*breachedOutput{ret.selfOutput, ret.revokedOutput, &offered}
+	if target := brar.justiceConfTarget(inputs); target != 40 {
+		t.Fatalf("expected confirmation target of 40, got %v", target)
+	}
+
+	diag, err := brar.DumpRetribution(&ret.chanPoint)
+	if err != nil {
+		t.Fatalf("unable to dump retribution: %v", err)
+	}
+	if diag.DeadlineHeight != ret.deadlineHeight {
+		t.Fatalf("expected reported deadline %v, got %v",
+			ret.deadlineHeight, diag.DeadlineHeight)
+	}
+
+	// The deadline and refund timeouts should survive serialization.
+	var buf bytes.Buffer
+	if err := ret.Encode(&buf); err != nil {
+		t.Fatalf("unable to serialize retribution: %v", err)
+	}
+	desRet := &retributionInfo{}
+	if err := desRet.Decode(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("unable to deserialize retribution: %v", err)
+	}
+	if desRet.deadlineHeight != ret.deadlineHeight {
+		t.Fatalf("expected deadline %v, got %v", ret.deadlineHeight,
+			desRet.deadlineHeight)
+	}
+	if desRet.htlcOutputs[0].refundTimeout != offered.refundTimeout {
+		t.Fatalf("expected refund timeout %v, got %v",
+			offered.refundTimeout,
+			desRet.htlcOutputs[0].refundTimeout)
+	}
+
+	// An HTLC we offered may be claimed by the breaching party right away
+	// using its preimage, leaving no time to spare.
+	accepted := offered
+	accepted.outpoint.Index = 3
+	accepted.witnessType = lnwallet.HtlcAcceptedRevoke
+	ret.htlcOutputs = append(ret.htlcOutputs, &accepted)
+	ret.recordBreachHeight(breachHeight)
+	if ret.deadlineHeight != breachHeight {
+		t.Fatalf("expected deadline %v, got %v", breachHeight,
+			ret.deadlineHeight)
+	}
+
+	inputs = append(inputs, &accepted)
+	target := brar.justiceConfTarget(inputs)
+	if target != brar.cfg.JusticeFeeTarget {
+		t.Fatalf("expected confirmation target of %v, got %v",
+			brar.cfg.JusticeFeeTarget, target)
+	}
+}
+
+// recordingConsolidationManager is a ConsolidationManager which records the
+// sweep outputs reported to it, failing each report with err.
+type recordingConsolidationManager struct {
+	reported chan wire.OutPoint
+	amts     map[wire.OutPoint]btcutil.Amount
+	mtx      sync.Mutex
+	err      error
+}
+
+func (m *recordingConsolidationManager) ReportSweepOutput(op wire.OutPoint,
+	amt btcutil.Amount) error {
+
+	m.mtx.Lock()
+	m.amts[op] = amt
+	m.mtx.Unlock()
+
+	m.reported <- op
+	return m.err
+}
+
+// TestReportSweepOutputs asserts that each output of a confirmed sweep
+// transaction is reported to the ConsolidationManager along with its value,
+// even should reporting an earlier output fail.
+func TestReportSweepOutputs(t *testing.T) {
+	manager := &recordingConsolidationManager{
+		reported: make(chan wire.OutPoint, 2),
+		amts:     make(map[wire.OutPoint]btcutil.Amount),
+		err:      errors.New("consolidation manager unavailable"),
+	}
+	brar := newTestBreachArbiter(&BreachConfig{
+		ConsolidationManager: manager,
+	})
+
+	sweepTx := wire.NewMsgTx(2)
+	sweepTx.AddTxOut(&wire.TxOut{Value: 100000})
+	sweepTx.AddTxOut(&wire.TxOut{Value: 20000})
+	txid := sweepTx.TxHash()
+
+	brar.reportSweepOutputs(sweepTx)
+
+	for i := 0; i < len(sweepTx.TxOut); i++ {
+		select {
+		case <-manager.reported:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("sweep output %d was not reported", i)
+		}
+	}
+
+	manager.mtx.Lock()
+	defer manager.mtx.Unlock()
+	for i, txOut := range sweepTx.TxOut {
+		op := wire.OutPoint{Hash: txid, Index: uint32(i)}
+		amt, ok := manager.amts[op]
+		if !ok {
+			t.Fatalf("sweep output %v was not reported", op)
+		}
+		if amt != btcutil.Amount(txOut.Value) {
+			t.Fatalf("expected %v reported for %v, got %v",
+				btcutil.Amount(txOut.Value), op, amt)
+		}
+	}
+}
+
+// TestBreachReplacement asserts that the funding output of a breached channel
+// is only watched for a replacement of the breach transaction if the channel's
+// backup is known, and that a spend by a transaction which isn't a revoked
+// commitment of the channel leaves the retribution untouched.
+func TestBreachReplacement(t *testing.T) {
+	store := newMockRetributionStore()
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier: &mockNotifier{},
+		Store:    store,
+	})
+
+	ret := newBreachRetInfo()
+	spendEvent, err := brar.watchBreachReplacement(ret)
+	if err != nil {
+		t.Fatalf("unable to watch for replacement: %v", err)
+	}
+	if spendEvent != nil {
+		t.Fatalf("expected no replacement watch without a backup")
+	}
+
+	ret.backup = &channeldb.ChannelBackup{
+		FundingOutpoint: ret.chanPoint,
+		IdentityPub:     alicePrivKey.PubKey(),
+	}
+	spendEvent, err = brar.watchBreachReplacement(ret)
+	if err != nil {
+		t.Fatalf("unable to watch for replacement: %v", err)
+	}
+	if spendEvent == nil {
+		t.Fatalf("expected replacement watch with a backup")
+	}
+
+	// A transaction spending some other output can't be a commitment of
+	// the channel.
+	spendingTx := wire.NewMsgTx(2)
+	spendingTx.AddTxIn(&wire.TxIn{PreviousOutPoint: breachOutPoints[1]})
+	spenderTxid := spendingTx.TxHash()
+
+	commitHash := ret.commitHash
+	_, err = brar.rederiveReplacedBreach(ret, &chainntnfs.SpendDetail{
+		SpentOutPoint: &ret.chanPoint,
+		SpenderTxHash: &spenderTxid,
+		SpendingTx:    spendingTx,
+	})
+	if err != lnwallet.ErrNotRevokedCommitment {
+		t.Fatalf("expected ErrNotRevokedCommitment, got %v", err)
+	}
+	if ret.commitHash != commitHash {
+		t.Fatalf("expected breach tx %v to be retained, got %v",
+			commitHash, ret.commitHash)
+	}
+	if n := countRetributions(t, store); n != 0 {
+		t.Fatalf("expected no retributions persisted, got %d", n)
+	}
+}
+
+// recordingMetricsRegistry is a MetricsRegistry which records the metrics
+// registered with it.
+type recordingMetricsRegistry struct {
+	values       map[string]func() float64
+	observations []float64
+}
+
+func (r *recordingMetricsRegistry) RegisterCounterFunc(name, help string,
+	value func() float64) error {
+
+	r.values[name] = value
+	return nil
+}
+
+func (r *recordingMetricsRegistry) RegisterGaugeFunc(name, help string,
+	value func() float64) error {
+
+	r.values[name] = value
+	return nil
+}
+
+func (r *recordingMetricsRegistry) RegisterHistogram(name, help string,
+	buckets []float64) (func(float64), error) {
+
+	return func(v float64) {
+		r.observations = append(r.observations, v)
+	}, nil
+}
+
+// TestRegisterMetrics asserts that the metrics registered with the
+// MetricsRegistry reflect the breach arbiter's stats, and that the time to
+// justice of each retribution is observed by the histogram.
+func TestRegisterMetrics(t *testing.T) {
+	registry := &recordingMetricsRegistry{
+		values: make(map[string]func() float64),
+	}
+	brar := newTestBreachArbiter(&BreachConfig{
+		MetricsRegistry: registry,
+	})
+	if err := brar.registerMetrics(); err != nil {
+		t.Fatalf("unable to register metrics: %v", err)
+	}
+
+	assertMetric := func(name string, expected float64) {
+		value, ok := registry.values[name]
+		if !ok {
+			t.Fatalf("metric %v not registered", name)
+		}
+		if v := value(); v != expected {
+			t.Fatalf("expected %v to be %v, got %v", name,
+				expected, v)
+		}
+	}
+
+	ret := newBreachRetInfo()
+	brar.recordBreachDetected()
+	brar.setRetributionPhase(&ret.chanPoint, retPhaseAwaitingBreachConf)
+	brar.setValueAtRisk(&ret.chanPoint, ret.valueAtRisk())
+
+	assertMetric("lnd_breacharbiter_breaches_detected_total", 1)
+	assertMetric("lnd_breacharbiter_pending_retributions", 1)
+	assertMetric(
+		"lnd_breacharbiter_funds_at_risk_sat",
+		float64(ret.valueAtRisk()),
+	)
+	assertMetric("lnd_breacharbiter_justice_confirmed_total", 0)
+
+	detectedAt := time.Now()
+	brar.recordJusticeLatency(
+		detectedAt, detectedAt.Add(time.Minute),
+		detectedAt.Add(time.Hour),
+	)
+	brar.clearRetributionPhase(&ret.chanPoint)
+
+	assertMetric("lnd_breacharbiter_justice_confirmed_total", 1)
+	assertMetric("lnd_breacharbiter_pending_retributions", 0)
+	assertMetric("lnd_breacharbiter_funds_at_risk_sat", 0)
+
+	if len(registry.observations) != 1 ||
+		registry.observations[0] != time.Hour.Seconds() {
+
+		t.Fatalf("expected time to justice of %v observed, got %v",
+			time.Hour.Seconds(), registry.observations)
+	}
+}
+
+// TestJusticePenaltyOutput asserts that, if configured, the penalty is swept
+// to a separate output from our own, with the fee, including that of the
+// extra output, apportioned between them, and that the two are swept to a
+// single output should either be dust.
+func TestJusticePenaltyOutput(t *testing.T) {
+	const feeRate = 40
+	sweepScript := bytes.Repeat([]byte{0x01}, lnwallet.P2WPKHSize)
+	penaltyScript := bytes.Repeat([]byte{0x02}, lnwallet.P2WPKHSize)
+
+	newArbiter := func(penalty bool) *breachArbiter {
+		cfg := &BreachConfig{
+			Estimator: lnwallet.StaticFeeEstimator{
+				FeeRate: feeRate,
+			},
+			Wallet: &lnwallet.LightningWallet{},
+			Signer: &mockSigner{key: alicePrivKey},
+			SweepScriptGen: func() ([]byte, error) {
+				return sweepScript, nil
+			},
+		}
+		if penalty {
+			cfg.PenaltySweepScriptGen = func() ([]byte, error) {
+				return penaltyScript, nil
+			}
+		}
+		return newBreachArbiter(cfg)
+	}
+
+	// The retributions are given fixed amounts, as the fixtures they're
+	// drawn from are shared with other tests.
+	newRet := func(selfAmt btcutil.Amount) *retributionInfo {
+		ret := newBreachRetInfo()
+
+		selfOutput := *ret.selfOutput
+		selfOutput.amt = selfAmt
+		ret.selfOutput = &selfOutput
+
+		revokedOutput := *ret.revokedOutput
+		revokedOutput.amt = 2 * btcutil.SatoshiPerBitcent
+		ret.revokedOutput = &revokedOutput
+
+		return ret
+	}
+
+	plainTx, err := newArbiter(false).createJusticeTx(
+		newRet(btcutil.SatoshiPerBitcent), sweepTxNew,
+	)
+	if err != nil {
+		t.Fatalf("unable to create justice tx: %v", err)
+	}
+
+	brar := newArbiter(true)
+	ret := newRet(btcutil.SatoshiPerBitcent)
+	splitTx, err := brar.createJusticeTx(ret, sweepTxNew)
+	if err != nil {
+		t.Fatalf("unable to create split justice tx: %v", err)
+	}
+	if !bytes.Equal(ret.penaltyPkScript, penaltyScript) {
+		t.Fatalf("expected penalty script %x recorded, got %x",
+			penaltyScript, ret.penaltyPkScript)
+	}
+
+	if len(splitTx.TxOut) != 2 {
+		t.Fatalf("expected 2 outputs, got %v", len(splitTx.TxOut))
+	}
+	selfOut, penaltyOut := splitTx.TxOut[0], splitTx.TxOut[1]
+	if !bytes.Equal(selfOut.PkScript, sweepScript) ||
+		!bytes.Equal(penaltyOut.PkScript, penaltyScript) {
+
+		t.Fatalf("unexpected output scripts: %x, %x",
+			selfOut.PkScript, penaltyOut.PkScript)
+	}
+
+	// Each output pays its share of the fee, which is increased by the
+	// weight of the extra output.
+	penaltyOutputFee := int64(brar.feePerWeight(0)) *
+		extraOutputWeight(penaltyScript)
+	plainFee := int64(ret.selfOutput.amt+ret.revokedOutput.amt) -
+		plainTx.TxOut[0].Value
+	splitFee := int64(ret.selfOutput.amt+ret.revokedOutput.amt) -
+		selfOut.Value - penaltyOut.Value
+	if splitFee != plainFee+penaltyOutputFee {
+		t.Fatalf("expected fee of %v, got %v",
+			plainFee+penaltyOutputFee, splitFee)
+	}
+	if selfOut.Value >= int64(ret.selfOutput.amt) ||
+		penaltyOut.Value >= int64(ret.revokedOutput.amt) {
+
+		t.Fatalf("fee not apportioned between outputs: %v, %v",
+			selfOut.Value, penaltyOut.Value)
+	}
+
+	// Should our own output be too small to be swept separately, it's
+	// swept along with the penalty.
+	ret = newRet(lnwallet.DefaultDustLimit())
+
+	justiceTx, err := brar.createJusticeTx(ret, sweepTxNew)
+	if err != nil {
+		t.Fatalf("unable to create justice tx: %v", err)
+	}
+	if len(justiceTx.TxOut) != 1 ||
+		!bytes.Equal(justiceTx.TxOut[0].PkScript, sweepScript) {
+
+		t.Fatalf("expected a single output paying to the sweep script")
+	}
+}
+
+// TestVerifyRevocationSecret asserts that the revocation secret of a revoked
+// output is accepted only if it yields the revocation key within the output's
+// witness script, and that a retribution with a mismatched secret is deemed
+// unsweepable.
+func TestVerifyRevocationSecret(t *testing.T) {
+	revocationSecret, _ := btcec.PrivKeyFromBytes(
+		btcec.S256(), bytes.Repeat([]byte{0x02}, 32),
+	)
+	revocationKey := lnwallet.DeriveRevocationPubkey(
+		alicePrivKey.PubKey(), revocationSecret.PubKey(),
+	)
+	witnessScript, err := txscript.NewScriptBuilder().
+		AddData(revocationKey.SerializeCompressed()).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+	if err != nil {
+		t.Fatalf("unable to build witness script: %v", err)
+	}
+
+	ret := newBreachRetInfo()
+	revokedOutput := *ret.revokedOutput
+	revokedOutput.witnessType = lnwallet.CommitmentRevoke
+	revokedOutput.signDescriptor.PubKey = alicePrivKey.PubKey()
+	revokedOutput.signDescriptor.DoubleTweak = revocationSecret
+	revokedOutput.signDescriptor.WitnessScript = witnessScript
+	ret.revokedOutput = &revokedOutput
+
+	if err := revokedOutput.verifyRevocationSecret(); err != nil {
+		t.Fatalf("unable to verify matching secret: %v", err)
+	}
+
+	// A secret of another state yields a different revocation key.
+	otherSecret, _ := btcec.PrivKeyFromBytes(
+		btcec.S256(), bytes.Repeat([]byte{0x03}, 32),
+	)
+	revokedOutput.signDescriptor.DoubleTweak = otherSecret
+	err = revokedOutput.verifyRevocationSecret()
+	if err != ErrRevocationSecretMismatch {
+		t.Fatalf("expected ErrRevocationSecretMismatch, got %v", err)
+	}
+
+	if err := ret.checkSweepable(); err != ErrRevocationSecretMismatch {
+		t.Fatalf("expected ErrRevocationSecretMismatch, got %v", err)
+	}
+}
+
+// TestBreachObserversConcurrentAccess asserts that the set of watched channels
+// may be inspected from other goroutines while the contractObserver goroutine
+// modifies it. It's intended to be run with the race detector.
+func TestBreachObserversConcurrentAccess(t *testing.T) {
+	notifier := &mockNotifier{
+		epochChan: make(chan *chainntnfs.BlockEpoch),
+	}
+	_, alice, _, cleanUp, err := createTestPeer(
+		notifier, make(chan *wire.MsgTx, 10),
+	)
+	if err != nil {
+		t.Fatalf("unable to create test channels: %v", err)
+	}
+	defer cleanUp()
+
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier: notifier,
+	})
+	brar.wg.Add(1)
+	go brar.contractObserver(nil)
+	defer brar.Stop()
+
+	chanPoint := alice.ChannelPoint()
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				brar.IsWatching(chanPoint)
+				if _, err := brar.HealthCheck(); err != nil {
+					t.Errorf("unable to check health: %v",
+						err)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 20; i++ {
+		if err := brar.WatchChannel(alice); err != nil {
+			t.Fatalf("unable to watch channel: %v", err)
+		}
+		if err := brar.SettleChannel(chanPoint); err != nil {
+			t.Fatalf("unable to settle channel: %v", err)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	if brar.IsWatching(chanPoint) {
+		t.Fatalf("settled channel still watched")
+	}
+}
+
+// testBreachDeleteStateFailure asserts that a failure to delete the state of a
+// breached channel during startup doesn't prevent the breach arbiter from
+// starting, nor from carrying out the channel's retribution, and that the
+// channel is reported as awaiting reconciliation.
+func testBreachDeleteStateFailure(h *breachTestHarness, t *testing.T) {
+	// The breach of Alice's channel was detected before restarting, but
+	// its state was never deleted, so it remains open within channeldb.
+	ret := h.newBreachRetInfo()
+	if err := h.store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+
+	chainIO := newRevokedChainIO(ret)
+	deleteErr := errors.New("unable to delete state")
+	var numDeletes int
+	brar := newTestBreachArbiter(&BreachConfig{
+		ChainIO: chainIO,
+		CloseLink: func(*wire.OutPoint,
+			htlcswitch.ChannelCloseType) {
+		},
+		DB: h.db,
+		DeleteChanState: func(*lnwallet.LightningChannel,
+			*channeldb.ChannelCloseSummary) error {
+
+			numDeletes++
+			return deleteErr
+		},
+		Notifier: h.notifier,
+		Store:    h.store,
+	})
+
+	if err := brar.Start(); err != nil {
+		t.Fatalf("unable to start breach arbiter: %v", err)
+	}
+	defer brar.Stop()
+
+	if numDeletes != 1 {
+		t.Fatalf("expected 1 attempt to delete state, got %v",
+			numDeletes)
+	}
+
+	// The retribution should still have been spawned, registering for the
+	// confirmation of the breach transaction.
+	select {
+	case txid := <-h.notifier.txids:
+		if txid != ret.commitHash {
+			t.Fatalf("unexpected registration for %v", txid)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("breach transaction not watched")
+	}
+
+	// The breached channel isn't watched, and is reported as awaiting
+	// reconciliation of its close.
+	if brar.IsWatching(&ret.chanPoint) {
+		t.Fatalf("breached channel should not be watched")
+	}
+	health, err := brar.HealthCheck()
+	if err != nil {
+		t.Fatalf("unable to check health: %v", err)
+	}
+	if len(health.UnreconciledChannels) != 1 ||
+		health.UnreconciledChannels[0] != ret.chanPoint {
+
+		t.Fatalf("expected %v to be unreconciled, got %v",
+			ret.chanPoint, health.UnreconciledChannels)
+	}
+	if health.Healthy() {
+		t.Fatalf("unreconciled channel should be unhealthy")
+	}
+
+	// Once the close is retried successfully, the channel is no longer
+	// reported.
+	deleteErr = nil
+	brar.reconcileBreachClose(&ret.chanPoint)
+
+	health, err = brar.HealthCheck()
+	if err != nil {
+		t.Fatalf("unable to check health: %v", err)
+	}
+	if len(health.UnreconciledChannels) != 0 {
+		t.Fatalf("expected no unreconciled channels, got %v",
+			health.UnreconciledChannels)
+	}
+}
+
+// TestBroadcastHold asserts that a retribution whose breach transaction
+// confirms while broadcasts are held remains persisted without broadcasting
+// its justice transaction until the hold is released, and that a hold doesn't
+// affect justice transactions already broadcast.
+func TestBroadcastHold(t *testing.T) {
+	notifier := &mockNotifier{
+		confChannel: make(chan *chainntnfs.TxConfirmation, 1),
+	}
+	published := make(chan *wire.MsgTx, 10)
+
+	store := newMockRetributionStore()
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier: notifier,
+		Wallet:   newTestWallet(published),
+		Store:    store,
+		SweepScriptGen: func() ([]byte, error) {
+			return []byte{0x00, 0x14}, nil
+		},
+	})
+
+	ret := newBreachRetInfo()
+	if err := store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+
+	// awaitPhase waits for the retribution to reach the given phase.
+	awaitPhase := func(phase retributionPhase) {
+		t.Helper()
+
+		deadline := time.After(5 * time.Second)
+		for {
+			brar.retMtx.Lock()
+			status, ok := brar.activeRetributions[ret.chanPoint]
+			reached := ok && status.phase == phase
+			brar.retMtx.Unlock()
+			if reached {
+				return
+			}
+
+			select {
+			case <-time.After(10 * time.Millisecond):
+			case <-deadline:
+				t.Fatalf("retribution didn't reach phase %v",
+					phase)
+			}
+		}
+	}
+
+	brar.SetBroadcastHold(true)
+
+	brar.wg.Add(1)
+	go brar.exactRetribution(
+		&chainntnfs.ConfirmationEvent{Confirmed: notifier.confChannel},
+		ret,
+	)
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	notifier.confChannel <- &chainntnfs.TxConfirmation{}
+	awaitPhase(retPhaseBroadcastHeld)
+
+	select {
+	case tx := <-published:
+		t.Fatalf("justice tx %v published while held", tx.TxHash())
+	case <-time.After(50 * time.Millisecond):
+	}
+	if n := countRetributions(t, store); n != 1 {
+		t.Fatalf("expected retribution to remain persisted, found %v",
+			n)
+	}
+
+	brar.SetBroadcastHold(false)
+
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("justice tx not published after release")
+	}
+	awaitPhase(retPhaseAwaitingJusticeConf)
+
+	// Holding broadcasts once justice has been broadcast leaves the
+	// retribution awaiting its confirmation.
+	brar.SetBroadcastHold(true)
+	time.Sleep(50 * time.Millisecond)
+	awaitPhase(retPhaseAwaitingJusticeConf)
+}
+
+// TestRetributionPhaseClearedOnExit asserts that a retribution which fails to
+// serve justice is no longer tracked as active, and so isn't reported as
+// stuck.
+func TestRetributionPhaseClearedOnExit(t *testing.T) {
+	notifier := &mockNotifier{
+		confChannel: make(chan *chainntnfs.TxConfirmation, 1),
+	}
+	published := make(chan *wire.MsgTx, 10)
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier: notifier,
+		Wallet:   newTestWallet(published),
+		SweepScriptGen: func() ([]byte, error) {
+			return nil, fmt.Errorf("no sweep script")
+		},
+		StuckRetributionTimeout: time.Nanosecond,
+	})
+	defer close(brar.quit)
+
+	ret := newBreachRetInfo()
+	exited := make(chan struct{})
+	brar.wg.Add(1)
+	go func() {
+		brar.exactRetribution(
+			&chainntnfs.ConfirmationEvent{
+				Confirmed: notifier.confChannel,
+			},
+			ret,
+		)
+		close(exited)
+	}()
+
+	notifier.confChannel <- &chainntnfs.TxConfirmation{}
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("retribution didn't exit")
+	}
+
+	health, err := brar.HealthCheck()
+	if err != nil {
+		t.Fatalf("unable to check health: %v", err)
+	}
+	if len(health.StuckRetributions) != 0 {
+		t.Fatalf("exited retribution reported as stuck: %v",
+			health.StuckRetributions)
+	}
+	if _, ok := brar.activeRetributions[ret.chanPoint]; ok {
+		t.Fatalf("exited retribution still tracked as active")
+	}
+}
+
+// TestJusticePackageFee asserts that a justice transaction bumping the fee of
+// its unconfirmed breach transaction via CPFP covers the breach transaction's
+// shortfall from the target fee rate, but never pays less than the fee due for
+// its own weight.
+func TestJusticePackageFee(t *testing.T) {
+	const (
+		parentWeight = 1000
+		childWeight  = 500
+
+		// The estimator's fee rate is expressed per kilo-vbyte, and
+		// amounts to 10 sat/wu.
+		targetRate = btcutil.Amount(10)
+	)
+
+	brar := newTestBreachArbiter(&BreachConfig{
+		Estimator: lnwallet.StaticFeeEstimator{FeeRate: 40},
+	})
+
+	tests := []struct {
+		name       string
+		parentRate btcutil.Amount
+		childFee   btcutil.Amount
+	}{
+		{
+			// The child pays the fee of the entire package.
+			name:       "no parent fee",
+			parentRate: 0,
+			childFee:   targetRate * (parentWeight + childWeight),
+		},
+		{
+			name:       "parent below target",
+			parentRate: 2,
+			childFee: targetRate*(parentWeight+childWeight) -
+				2*parentWeight,
+		},
+		{
+			// There's no shortfall to cover, so the child pays
+			// only for itself.
+			name:       "parent at target",
+			parentRate: targetRate,
+			childFee:   targetRate * childWeight,
+		},
+		{
+			// The parent's surplus doesn't subsidize the child.
+			name:       "parent above target",
+			parentRate: 3 * targetRate,
+			childFee:   targetRate * childWeight,
+		},
+	}
+
+	for _, test := range tests {
+		parent := &breachPackageParent{
+			weight: parentWeight,
+			fee:    test.parentRate * parentWeight,
+		}
+		if rate := parent.feePerWeight(); rate != test.parentRate {
+			t.Fatalf("%v: expected parent fee rate %v, got %v",
+				test.name, test.parentRate, rate)
+		}
+
+		fee := brar.justicePackageFee(parent, childWeight, 0)
+		if fee != test.childFee {
+			t.Fatalf("%v: expected child fee %v, got %v",
+				test.name, test.childFee, fee)
+		}
+
+		// The package as a whole pays at least the target rate.
+		packageRate := (parent.fee + fee) /
+			(parentWeight + childWeight)
+		if packageRate < targetRate {
+			t.Fatalf("%v: package pays %v/wu, below target %v/wu",
+				test.name, packageRate, targetRate)
+		}
+	}
+}
+
+// TestFetchBreachPackageParent asserts that the fee of an unconfirmed breach
+// transaction is derived from the value of the outputs it spends, as looked up
+// via the ChainIO.
+func TestFetchBreachPackageParent(t *testing.T) {
+	fundingOutpoint := wire.OutPoint{Hash: chainhash.Hash{0x02}}
+	breachTx := wire.NewMsgTx(2)
+	breachTx.AddTxIn(&wire.TxIn{PreviousOutPoint: fundingOutpoint})
+	breachTx.AddTxOut(&wire.TxOut{Value: 60000})
+	breachTx.AddTxOut(&wire.TxOut{Value: 38000})
+
+	chainIO := &breachChainIO{
+		utxos: make(map[wire.OutPoint]*wire.TxOut),
+	}
+	brar := newTestBreachArbiter(&BreachConfig{
+		ChainIO: chainIO,
+	})
+
+	if _, err := brar.fetchBreachPackageParent(breachTx, 0); err == nil {
+		t.Fatalf("expected failure with unknown funding output")
+	}
+
+	chainIO.utxos[fundingOutpoint] = &wire.TxOut{Value: 100000}
+	parent, err := brar.fetchBreachPackageParent(breachTx, 0)
+	if err != nil {
+		t.Fatalf("unable to fetch breach tx: %v", err)
+	}
+	if parent.fee != 2000 {
+		t.Fatalf("expected parent fee 2000, got %v", parent.fee)
+	}
+	weight := blockchain.GetTransactionWeight(btcutil.NewTx(breachTx))
+	if parent.weight != weight {
+		t.Fatalf("expected parent weight %v, got %v", weight,
+			parent.weight)
+	}
+}
+
+// TestBreachedOutputViews asserts that the read-only accessors describe the
+// breached outputs of a retribution, including their spent status, via the
+// same view.
+func TestBreachedOutputViews(t *testing.T) {
+	ret := newBreachRetInfo()
+	ret.selfOutput.witnessType = lnwallet.CommitmentNoDelay
+	ret.revokedOutput.witnessType = lnwallet.CommitmentRevoke
+	ret.revokedOutput.twoStageClaim = true
+
+	chainIO := &breachChainIO{
+		utxos: map[wire.OutPoint]*wire.TxOut{
+			ret.selfOutput.outpoint: {
+				Value: int64(ret.selfOutput.amt),
+			},
+		},
+	}
+	store := newMockRetributionStore()
+	if err := store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+	brar := newTestBreachArbiter(&BreachConfig{
+		ChainIO: chainIO,
+		Store:   store,
+	})
+
+	// Only the self output remains within the UTXO set.
+	assertViews := func(views []BreachedOutputView) {
+		t.Helper()
+
+		outputs := ret.breachedOutputs()
+		if len(views) != len(outputs) {
+			t.Fatalf("expected %v views, got %v", len(outputs),
+				len(views))
+		}
+		for i, output := range outputs {
+			expView := BreachedOutputView{
+				OutPoint:      output.outpoint,
+				Amount:        output.amt,
+				WitnessType:   output.witnessType.String(),
+				TwoStageClaim: output.twoStageClaim,
+				Spent:         output != ret.selfOutput,
+			}
+			if views[i] != expView {
+				t.Fatalf("expected view %v, got %v",
+					spew.Sdump(expView),
+					spew.Sdump(views[i]))
+			}
+		}
+	}
+
+	diags, err := brar.PendingRetributions()
+	if err != nil {
+		t.Fatalf("unable to fetch pending retributions: %v", err)
+	}
+	if len(diags) != 1 || diags[0].ChanPoint != ret.chanPoint {
+		t.Fatalf("unexpected pending retributions: %v",
+			spew.Sdump(diags))
+	}
+	var views []BreachedOutputView
+	for _, outputDiag := range diags[0].Outputs {
+		views = append(views, outputDiag.BreachedOutputView)
+	}
+	assertViews(views)
+
+	// Once archived, the outputs are described the same way.
+	err = store.Archive(&ArchivedBreach{retribution: ret})
+	if err != nil {
+		t.Fatalf("unable to archive breach: %v", err)
+	}
+	breaches, err := brar.ArchivedBreaches()
+	if err != nil {
+		t.Fatalf("unable to fetch archived breaches: %v", err)
+	}
+	if len(breaches) != 1 {
+		t.Fatalf("expected 1 archived breach, got %v", len(breaches))
+	}
+	assertViews(breaches[0].Outputs)
+}
+
+// flakyNotifier is a txidNotifier whose registrations for confirmation
+// notifications fail until failures reaches zero.
+type flakyNotifier struct {
+	txidNotifier
+
+	failures int32
+}
+
+func (n *flakyNotifier) RegisterConfirmationsNtfn(txid *chainhash.Hash,
+	numConfs, heightHint uint32) (*chainntnfs.ConfirmationEvent, error) {
+
+	if atomic.AddInt32(&n.failures, -1) >= 0 {
+		return nil, errors.New("notifier unavailable")
+	}
+
+	return n.txidNotifier.RegisterConfirmationsNtfn(
+		txid, numConfs, heightHint,
+	)
+}
+
+// TestStartRegistrationRetry asserts that a failure to register for the
+// confirmation of a breach transaction on startup is retried, and that should
+// the registration ultimately fail, the breach arbiter still starts, and the
+// retribution is resumed once the registration is retried later on.
+func TestStartRegistrationRetry(t *testing.T) {
+	defer func(backoff time.Duration) {
+		startupRegisterBackoff = backoff
+	}(startupRegisterBackoff)
+	startupRegisterBackoff = time.Millisecond
+
+	// startArbiter starts a breach arbiter resuming the retribution of a
+	// breached channel, whose first failures registrations fail.
+	startArbiter := func(failures int32) (*breachArbiter,
+		*flakyNotifier, *retributionInfo, func()) {
+
+		notifier := &flakyNotifier{
+			txidNotifier: txidNotifier{
+				mockNotifier: mockNotifier{
+					confChannel: make(
+						chan *chainntnfs.TxConfirmation,
+					),
+				},
+				txids: make(chan chainhash.Hash, 10),
+			},
+			failures: failures,
+		}
+		alicePeer, alice, _, cleanUp, err := createTestPeer(
+			notifier, make(chan *wire.MsgTx, 10),
+		)
+		if err != nil {
+			t.Fatalf("unable to create test channels: %v", err)
+		}
+
+		aliceState := alice.StateSnapshot()
+		ret := newBreachRetInfo()
+		ret.chanPoint = *aliceState.ChannelPoint
+		ret.remoteIdentity = aliceState.RemoteIdentity
+		ret.capacity = aliceState.Capacity
+
+		store := newMockRetributionStore()
+		if err := store.Add(ret, nil); err != nil {
+			t.Fatalf("unable to add retribution: %v", err)
+		}
+		closeTestChannel(
+			t, alice, ret.commitHash, channeldb.BreachClose,
+		)
+
+		brar := newTestBreachArbiter(&BreachConfig{
+			ChainIO: newRevokedChainIO(ret),
+			CloseLink: func(*wire.OutPoint,
+				htlcswitch.ChannelCloseType) {
+			},
+			DB:       alicePeer.server.chanDB,
+			Notifier: notifier,
+			Store:    store,
+		})
+		if err := brar.Start(); err != nil {
+			t.Fatalf("unable to start breach arbiter: %v", err)
+		}
+
+		return brar, notifier, ret, func() {
+			brar.Stop()
+			cleanUp()
+		}
+	}
+
+	// assertRegistered asserts that the breach transaction of the given
+	// retribution has been registered for.
+	assertRegistered := func(notifier *flakyNotifier,
+		ret *retributionInfo) {
+
+		t.Helper()
+
+		select {
+		case txid := <-notifier.txids:
+			if txid != ret.commitHash {
+				t.Fatalf("unexpected registration for %v", txid)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("breach transaction not registered for")
+		}
+	}
+
+	// assertUnregistered asserts the channel points of the retributions
+	// reported as awaiting registration.
+	assertUnregistered := func(brar *breachArbiter,
+		expected ...wire.OutPoint) {
+
+		t.Helper()
+
+		health, err := brar.HealthCheck()
+		if err != nil {
+			t.Fatalf("unable to check health: %v", err)
+		}
+		if !reflect.DeepEqual(
+			health.UnregisteredRetributions, expected,
+		) {
+			t.Fatalf("expected unregistered retributions %v, "+
+				"got %v", expected,
+				health.UnregisteredRetributions)
+		}
+	}
+
+	// A transient failure is overcome by retrying during startup.
+	brar, notifier, ret, cleanUp := startArbiter(
+		startupRegisterAttempts - 1,
+	)
+	assertRegistered(notifier, ret)
+	assertUnregistered(brar)
+	cleanUp()
+
+	// Should every attempt fail, the breach arbiter starts regardless,
+	// and the registration is retried later on.
+	brar, notifier, ret, cleanUp = startArbiter(startupRegisterAttempts)
+	defer cleanUp()
+
+	assertUnregistered(brar, ret.chanPoint)
+	select {
+	case txid := <-notifier.txids:
+		t.Fatalf("unexpected registration for %v", txid)
+	default:
+	}
+
+	brar.reregisterRetributions()
+	assertRegistered(notifier, ret)
+	assertUnregistered(brar)
+}
+
+// TestVerifyJusticeRelay asserts that a justice transaction which fails to
+// reach the mempool after being broadcast is re-broadcast, until either it's
+// found within the mempool, or justiceRelayAttempts broadcasts are made.
+func TestVerifyJusticeRelay(t *testing.T) {
+	tests := []struct {
+		name string
+
+		// relayedAfter is the number of broadcasts after which the
+		// justice tx is found within the mempool, or zero if it never
+		// is.
+		relayedAfter int
+
+		numBroadcasts int
+	}{
+		{
+			name:          "relayed",
+			relayedAfter:  1,
+			numBroadcasts: 1,
+		},
+		{
+			name:          "relayed after re-broadcast",
+			relayedAfter:  2,
+			numBroadcasts: 2,
+		},
+		{
+			name:          "never relayed",
+			relayedAfter:  0,
+			numBroadcasts: justiceRelayAttempts,
+		},
+	}
+
+	for _, test := range tests {
+		published := make(chan *wire.MsgTx, justiceRelayAttempts+1)
+
+		justiceTx := wire.NewMsgTx(2)
+		justiceTx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: breachOutPoints[0],
+		})
+		justiceTx.AddTxOut(&wire.TxOut{Value: 10000})
+
+		var numBroadcasts int32
+		brar := newTestBreachArbiter(&BreachConfig{
+			Wallet: &lnwallet.LightningWallet{
+				WalletController: &mockWalletController{
+					publishedTransactions: published,
+				},
+			},
+			VerifyJusticeRelay:  true,
+			JusticeRelayTimeout: 10 * time.Millisecond,
+			MempoolSpends: func(ops []wire.OutPoint) ([]MempoolSpend,
+				error) {
+
+				n := atomic.LoadInt32(&numBroadcasts)
+				if test.relayedAfter == 0 ||
+					int(n) < test.relayedAfter {
+
+					return nil, nil
+				}
+
+				return []MempoolSpend{{
+					Txid: justiceTx.TxHash(),
+				}}, nil
+			},
+		})
+
+		// Each broadcast is counted as it's published, such that the
+		// mempool reflects it by the time it's next queried.
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for range published {
+				atomic.AddInt32(&numBroadcasts, 1)
+			}
+		}()
+
+		err := brar.publishJustice(justiceTx)
+		if err != nil {
+			t.Fatalf("%v: unable to publish justice tx: %v",
+				test.name, err)
+		}
+
+		// Give the verification time to complete any broadcasts
+		// beyond those expected.
+		time.Sleep(5 * time.Duration(justiceRelayAttempts+1) *
+			brar.cfg.JusticeRelayTimeout)
+
+		close(brar.quit)
+		brar.wg.Wait()
+		close(published)
+		<-done
+
+		n := atomic.LoadInt32(&numBroadcasts)
+		if int(n) != test.numBroadcasts {
+			t.Fatalf("%v: expected %v broadcasts, got %v",
+				test.name, test.numBroadcasts, n)
+		}
+	}
+}
+
+// testStartRegistrationOrder asserts that on startup, the confirmations of the
+// breach transactions of any pending retributions are registered for before
+// those of the closing transactions of any pending close channels.
+func testStartRegistrationOrder(h *breachTestHarness, t *testing.T) {
+	// Alice's channel was cooperatively closed, and its closing
+	// transaction awaits confirmation.
+	coopTxid := chainhash.Hash{0x0c}
+	closeTestChannel(t, h.alice, coopTxid, channeldb.CooperativeClose)
+
+	// Meanwhile, the retribution of another channel is pending.
+	ret := newBreachRetInfo()
+	if err := h.store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+
+	brar := newTestBreachArbiter(&BreachConfig{
+		ChainIO: newRevokedChainIO(ret),
+		CloseLink: func(*wire.OutPoint,
+			htlcswitch.ChannelCloseType) {
+		},
+		DB:       h.db,
+		Notifier: h.notifier,
+		Store:    h.store,
+	})
+	if err := brar.Start(); err != nil {
+		t.Fatalf("unable to start breach arbiter: %v", err)
+	}
+	defer brar.Stop()
+
+	for _, expected := range []chainhash.Hash{ret.commitHash, coopTxid} {
+		select {
+		case txid := <-h.notifier.txids:
+			if txid != expected {
+				t.Fatalf("expected registration for %v, got %v",
+					expected, txid)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no registration for %v", expected)
+		}
+	}
+}
+
+// TestSweepDustThreshold asserts that the SweepDustThreshold defaults to the
+// network's dust limit, and that once raised, it governs whether the penalty
+// is swept to its own output and whether a commitment sweep is made.
+func TestSweepDustThreshold(t *testing.T) {
+	dustLimit := lnwallet.DefaultDustLimit()
+
+	newArbiter := func(threshold btcutil.Amount) *breachArbiter {
+		return newTestBreachArbiter(&BreachConfig{
+			SweepDustThreshold: threshold,
+			SweepScriptGen: func() ([]byte, error) {
+				return []byte{0x00, 0x14}, nil
+			},
+		})
+	}
+
+	// The threshold can't be lowered below the network's dust limit.
+	for _, threshold := range []btcutil.Amount{0, dustLimit / 2} {
+		brar := newArbiter(threshold)
+		if brar.cfg.SweepDustThreshold != dustLimit {
+			t.Fatalf("expected threshold %v, got %v", dustLimit,
+				brar.cfg.SweepDustThreshold)
+		}
+	}
+
+	// A penalty exceeding the dust limit, but not the raised threshold,
+	// is swept along with our own funds.
+	const (
+		penaltyAmt = btcutil.Amount(2000)
+		totalAmt   = btcutil.Amount(100000)
+	)
+	threshold := penaltyAmt * 2
+	pkScript, penaltyPkScript := []byte{0x01}, []byte{0x02}
+
+	outputs, err := newArbiter(0).justiceOutputs(
+		totalAmt, penaltyAmt, 0, 0, pkScript, penaltyPkScript,
+	)
+	if err != nil {
+		t.Fatalf("unable to compute justice outputs: %v", err)
+	}
+	if len(outputs) != 2 {
+		t.Fatalf("expected penalty to be swept separately, got %v "+
+			"outputs", len(outputs))
+	}
+
+	outputs, err = newArbiter(threshold).justiceOutputs(
+		totalAmt, penaltyAmt, 0, 0, pkScript, penaltyPkScript,
+	)
+	if err != nil {
+		t.Fatalf("unable to compute justice outputs: %v", err)
+	}
+	if len(outputs) != 1 || outputs[0].Value != int64(totalAmt) {
+		t.Fatalf("expected a single output of %v, got %v", totalAmt,
+			spew.Sdump(outputs))
+	}
+
+	// A commitment sweep exceeding the dust limit, but not the raised
+	// threshold, isn't made.
+	input := breachedOutputs[0]
+	input.amt = commitSweepBaseFee + threshold - 1
+
+	_, err = newArbiter(0).distributeSweep(
+		input.amt, commitSweepBaseFee, 1,
+	)
+	if err != nil {
+		t.Fatalf("expected sweep above dust limit, got %v", err)
+	}
+	_, err = newArbiter(threshold).craftCommitSweepTx(
+		[]*breachedOutput{&input},
+	)
+	if err == nil {
+		t.Fatalf("expected sweep below threshold to fail")
+	}
+
+	// The threshold also applies to sweep amount policies other than the
+	// default.
+	brar := newArbiter(threshold)
+	brar.cfg.SweepAmountPolicy = &EvenSweepPolicy{DustLimit: dustLimit}
+	_, err = brar.distributeSweep(threshold, 1, 1)
+	if err != ErrSweepOutputDust {
+		t.Fatalf("expected ErrSweepOutputDust, got %v", err)
+	}
+}
+
+// excessSweepPolicy is a SweepAmountPolicy which distributes the gross amount
+// swept, ignoring the fee.
+type excessSweepPolicy struct{}
+
+func (excessSweepPolicy) Distribute(gross, fee btcutil.Amount,
+	numOutputs int) ([]btcutil.Amount, error) {
+
+	return []btcutil.Amount{gross}, nil
+}
+
+// TestSweepPolicyExcess asserts that a sweep amount policy distributing more
+// than the net amount being swept is rejected, while one leaving satoshis
+// undistributed, donating them to the fee, is accepted.
+func TestSweepPolicyExcess(t *testing.T) {
+	brar := newTestBreachArbiter(&BreachConfig{
+		SweepAmountPolicy: excessSweepPolicy{},
+	})
+
+	if _, err := brar.distributeSweep(100000, 1000, 1); err == nil {
+		t.Fatalf("expected excess distribution to be rejected")
+	}
+
+	brar.cfg.SweepAmountPolicy = &EvenSweepPolicy{
+		Remainder: RemainderToFee,
+	}
+	amts, err := brar.distributeSweep(100001, 1000, 2)
+	if err != nil {
+		t.Fatalf("unable to distribute sweep: %v", err)
+	}
+	if amts[0]+amts[1] != 99000 {
+		t.Fatalf("expected 99000 to be distributed, got %v", amts)
+	}
+}
+
+// spentChainIO is a mockChainIO which reports the given outputs as spent.
+type spentChainIO struct {
+	mockChainIO
+
+	spent map[wire.OutPoint]struct{}
+}
+
+func (c *spentChainIO) GetUtxo(op *wire.OutPoint,
+	heightHint uint32) (*wire.TxOut, error) {
+
+	if _, ok := c.spent[*op]; ok {
+		return nil, lnwallet.ErrOutputSpent
+	}
+
+	return c.mockChainIO.GetUtxo(op, heightHint)
+}
+
+// TestCommitSweepSelfOutputClaimed asserts that our self output is left out of
+// a commitment sweep once it's been claimed elsewhere, and that a sweep whose
+// every input has been claimed isn't made.
+func TestCommitSweepSelfOutputClaimed(t *testing.T) {
+	// The mock wallet knows of a single output, with the zero outpoint.
+	walletOutpoint := wire.OutPoint{}
+	spentOutpoint := wire.OutPoint{Hash: chainhash.Hash{0x01}}
+	mempoolOutpoint := wire.OutPoint{Hash: chainhash.Hash{0x02}}
+	unclaimedOutpoint := wire.OutPoint{Hash: chainhash.Hash{0x03}}
+	spenderTxid := chainhash.Hash{0x04}
+
+	published := make(chan *wire.MsgTx, 1)
+	brar := newTestBreachArbiter(&BreachConfig{
+		ChainIO: &spentChainIO{
+			spent: map[wire.OutPoint]struct{}{
+				spentOutpoint: {},
+			},
+		},
+		Wallet: &lnwallet.LightningWallet{
+			WalletController: &mockWalletController{
+				publishedTransactions: published,
+			},
+		},
+		MempoolSpends: func(ops []wire.OutPoint) ([]MempoolSpend,
+			error) {
+
+			if ops[0] != mempoolOutpoint {
+				return nil, nil
+			}
+			return []MempoolSpend{{Txid: spenderTxid}}, nil
+		},
+	})
+
+	newInput := func(op wire.OutPoint,
+		witnessType lnwallet.WitnessType) *breachedOutput {
+
+		input := breachedOutputs[0]
+		input.outpoint = op
+		input.witnessType = witnessType
+		return &input
+	}
+
+	tests := []struct {
+		name    string
+		input   *breachedOutput
+		claimed bool
+	}{
+		{
+			name: "spent",
+			input: newInput(
+				spentOutpoint, lnwallet.CommitmentNoDelay,
+			),
+			claimed: true,
+		},
+		{
+			name: "spent within mempool",
+			input: newInput(
+				mempoolOutpoint, lnwallet.CommitmentNoDelay,
+			),
+			claimed: true,
+		},
+		{
+			name: "known to wallet",
+			input: newInput(
+				walletOutpoint, lnwallet.CommitmentNoDelay,
+			),
+			claimed: true,
+		},
+		{
+			name: "unclaimed",
+			input: newInput(
+				unclaimedOutpoint, lnwallet.CommitmentNoDelay,
+			),
+			claimed: false,
+		},
+		{
+			// The wallet can't claim our time-locked output.
+			name: "time-locked",
+			input: newInput(
+				walletOutpoint, lnwallet.CommitmentTimeLock,
+			),
+			claimed: false,
+		},
+	}
+	for _, test := range tests {
+		claimed, _ := brar.commitInputClaimed(test.input, 0)
+		if claimed != test.claimed {
+			t.Fatalf("%v: expected claimed %v, got %v", test.name,
+				test.claimed, claimed)
+		}
+	}
+
+	// Only the unclaimed inputs remain within the sweep.
+	sweep := &commitSweepInfo{
+		inputs: []*breachedOutput{
+			tests[0].input, tests[3].input, tests[4].input,
+		},
+	}
+	brar.pruneClaimedCommitInputs(sweep)
+	if len(sweep.inputs) != 2 || sweep.inputs[0] != tests[3].input ||
+		sweep.inputs[1] != tests[4].input {
+
+		t.Fatalf("unexpected inputs after pruning: %v",
+			spew.Sdump(sweep.inputs))
+	}
+
+	// A sweep whose every input has been claimed is resolved without
+	// being made.
+	sweep = &commitSweepInfo{
+		inputs: []*breachedOutput{tests[0].input, tests[2].input},
+	}
+	sweptAmt, _, ok := brar.sweepCommitInputs(sweep)
+	if !ok || sweptAmt != 0 || sweep.sweepTx != nil {
+		t.Fatalf("expected claimed sweep to be resolved without " +
+			"sweeping")
+	}
+	select {
+	case tx := <-published:
+		t.Fatalf("unexpected sweep %v published", tx.TxHash())
+	default:
+	}
+}
+
+// TestBlacklistHits asserts that peers having breached one of our channels,
+// whether their retribution is pending or archived, are reported as
+// blacklisted, and that each such hit is delivered to the subscribers.
+func TestBlacklistHits(t *testing.T) {
+	pendingKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	archivedKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	honestKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+
+	store := newMockRetributionStore()
+
+	pending := newBreachRetInfo()
+	pending.remoteIdentity = *pendingKey.PubKey()
+	if err := store.Add(pending, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+
+	archived := newBreachRetInfo()
+	archived.chanPoint = breachOutPoints[1]
+	archived.remoteIdentity = *archivedKey.PubKey()
+	if err := store.Add(archived, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+	err = store.Archive(&ArchivedBreach{retribution: archived})
+	if err != nil {
+		t.Fatalf("unable to archive breach: %v", err)
+	}
+
+	brar := newTestBreachArbiter(&BreachConfig{
+		Store: store,
+	})
+	defer close(brar.quit)
+
+	sub := brar.SubscribeBlacklistHits()
+
+	assertHit := func(key *btcec.PrivateKey, action BlacklistAction) {
+		t.Helper()
+
+		blacklisted, err := brar.IsBlacklisted(key.PubKey(), action)
+		if err != nil {
+			t.Fatalf("unable to check blacklist: %v", err)
+		}
+		if !blacklisted {
+			t.Fatalf("expected peer to be blacklisted")
+		}
+
+		select {
+		case hit := <-sub.Hits:
+			var expPub [33]byte
+			copy(expPub[:], key.PubKey().SerializeCompressed())
+			if hit.PeerPub != expPub {
+				t.Fatalf("expected hit for %x, got %x",
+					expPub[:], hit.PeerPub[:])
+			}
+			if hit.Action != action {
+				t.Fatalf("expected action %v, got %v", action,
+					hit.Action)
+			}
+			if hit.Timestamp.IsZero() {
+				t.Fatalf("expected hit to be timestamped")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("blacklist hit not delivered")
+		}
+	}
+
+	assertHit(pendingKey, BlacklistConnect)
+	assertHit(archivedKey, BlacklistOpenChannel)
+
+	// A peer that never breached isn't blacklisted, and no hit is
+	// delivered.
+	blacklisted, err := brar.IsBlacklisted(
+		honestKey.PubKey(), BlacklistConnect,
+	)
+	if err != nil {
+		t.Fatalf("unable to check blacklist: %v", err)
+	}
+	if blacklisted {
+		t.Fatalf("expected honest peer not to be blacklisted")
+	}
+
+	// Once cancelled, the subscription no longer receives hits.
+	sub.Cancel()
+	if _, err := brar.IsBlacklisted(
+		pendingKey.PubKey(), BlacklistConnect,
+	); err != nil {
+		t.Fatalf("unable to check blacklist: %v", err)
+	}
+
+	select {
+	case hit := <-sub.Hits:
+		t.Fatalf("unexpected hit after cancel: %v", hit)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// memRetributionKV is an in-memory RetributionKV. Each read-write transaction
+// operates upon a copy of the buckets, which replaces them only if the
+// transaction succeeds.
+type memRetributionKV struct {
+	mu      sync.Mutex
+	buckets map[string]map[string][]byte
+}
+
+func newMemRetributionKV() *memRetributionKV {
+	return &memRetributionKV{
+		buckets: make(map[string]map[string][]byte),
+	}
+}
+
+func (m *memRetributionKV) View(f func(RetributionKVTx) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return f(&memRetributionKVTx{buckets: m.buckets})
+}
+
+func (m *memRetributionKV) Update(f func(RetributionKVTx) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	buckets := make(map[string]map[string][]byte, len(m.buckets))
+	for name, bucket := range m.buckets {
+		buckets[name] = make(map[string][]byte, len(bucket))
+		for k, v := range bucket {
+			buckets[name][k] = v
+		}
+	}
+
+	err := f(&memRetributionKVTx{buckets: buckets, writable: true})
+	if err != nil {
+		return err
+	}
+	m.buckets = buckets
+
+	return nil
+}
+
+type memRetributionKVTx struct {
+	buckets  map[string]map[string][]byte
+	writable bool
+}
+
+func (t *memRetributionKVTx) Get(bucket, key []byte) []byte {
+	return t.buckets[string(bucket)][string(key)]
+}
+
+func (t *memRetributionKVTx) Put(bucket, key, value []byte) error {
+	if !t.writable {
+		return errors.New("transaction not writable")
+	}
+
+	b, ok := t.buckets[string(bucket)]
+	if !ok {
+		b = make(map[string][]byte)
+		t.buckets[string(bucket)] = b
+	}
+	b[string(key)] = append([]byte(nil), value...)
+
+	return nil
+}
+
+func (t *memRetributionKVTx) Delete(bucket, key []byte) error {
+	if !t.writable {
+		return errors.New("transaction not writable")
+	}
+
+	delete(t.buckets[string(bucket)], string(key))
+	return nil
+}
+
+func (t *memRetributionKVTx) ForEach(bucket []byte,
+	cb func(k, v []byte) error) error {
+
+	b := t.buckets[string(bucket)]
+
+	keys := make([]string, 0, len(b))
+	for k := range b {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := cb([]byte(k), b[k]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// TestKVRetributionStore asserts that a retributionStore backed by an
+// alternate RetributionKV behaves as the default bolt-backed store, using the
+// general RetributionStore test suite.
+func TestKVRetributionStore(t *testing.T) {
+	for _, test := range retributionStoreTestSuite {
+		t.Run(
+			"kvRetributionStore."+test.name,
+			func(tt *testing.T) {
+				kv := newMemRetributionKV()
+				frs := newFailingRetributionStore(
+					func() RetributionStore {
+						return newKVRetributionStore(
+							kv, 0,
+						)
+					},
+				)
+				test.test(frs, tt)
+			},
+		)
+	}
+
+	// A batched store commits each batch within a single transaction.
+	kv := newMemRetributionKV()
+	rs := newKVRetributionStore(kv, 50*time.Millisecond)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(retributions))
+	for i := range retributions {
+		wg.Add(1)
+		go func(ret *retributionInfo) {
+			defer wg.Done()
+			errs <- rs.Add(ret, nil)
+		}(&retributions[i])
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unable to add retribution: %v", err)
+		}
+	}
+
+	count := countRetributions(t, newKVRetributionStore(kv, 0))
+	if count != len(retributions) {
+		t.Fatalf("expected %v retributions, found %v",
+			len(retributions), count)
+	}
+
+	// Archiving a retribution moves it out of the retribution bucket.
+	err := rs.Archive(&ArchivedBreach{retribution: &retributions[0]})
+	if err != nil {
+		t.Fatalf("unable to archive breach: %v", err)
+	}
+	err = kv.View(func(tx RetributionKVTx) error {
+		var outBuf bytes.Buffer
+		err := writeOutpoint(&outBuf, &retributions[0].chanPoint)
+		if err != nil {
+			return err
+		}
+
+		if tx.Get(retributionBucket, outBuf.Bytes()) != nil {
+			t.Fatalf("archived retribution still pending")
+		}
+		if tx.Get(retributionArchiveBucket, outBuf.Bytes()) == nil {
+			t.Fatalf("retribution not archived")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unable to view store: %v", err)
+	}
+}
+
+// TestJusticeBreakdown asserts that the funds swept by a justice transaction,
+// and the fee it pays, are attributed to each retribution it serves, and that
+// the breakdown is persisted along with the retribution.
+func TestJusticeBreakdown(t *testing.T) {
+	first := newBreachRetInfo()
+	first.selfOutput.amt = 20000
+	first.revokedOutput.amt = 50000
+	htlc := breachedOutputs[2]
+	htlc.amt = 10000
+	htlc.outpoint = wire.OutPoint{Hash: first.commitHash, Index: 2}
+	first.htlcOutputs = []*breachedOutput{&htlc}
+
+	second := newBreachRetInfo()
+	second.chanPoint = breachOutPoints[1]
+	second.commitHash = chainhash.Hash{0x02}
+	second.selfOutput.outpoint = wire.OutPoint{Hash: second.commitHash}
+	second.selfOutput.amt = 5000
+	second.revokedOutput.outpoint = wire.OutPoint{
+		Hash:  second.commitHash,
+		Index: 1,
+	}
+	second.revokedOutput.amt = 20000
+
+	// Our own output of the second retribution is left out of the justice
+	// transaction, which pays a fee of 1,000 satoshis.
+	justiceTx := &wire.MsgTx{
+		TxIn: []*wire.TxIn{
+			{PreviousOutPoint: first.selfOutput.outpoint},
+			{PreviousOutPoint: first.revokedOutput.outpoint},
+			{PreviousOutPoint: htlc.outpoint},
+			{PreviousOutPoint: second.revokedOutput.outpoint},
+		},
+		TxOut: []*wire.TxOut{
+			{Value: 99000},
+		},
+	}
+
+	breakdowns := justiceBreakdowns(
+		[]*retributionInfo{first, second}, justiceTx,
+	)
+	expBreakdowns := []*JusticeBreakdown{
+		{
+			SelfAmount:    20000,
+			RevokedAmount: 50000,
+			HTLCAmounts: []JusticeHTLCAmount{
+				{OutPoint: htlc.outpoint, Amount: 10000},
+			},
+			FeePaid:      800,
+			NetRecovered: 79200,
+		},
+		{
+			RevokedAmount: 20000,
+			FeePaid:       200,
+			NetRecovered:  19800,
+		},
+	}
+	if !reflect.DeepEqual(breakdowns, expBreakdowns) {
+		t.Fatalf("expected breakdowns %v, got %v",
+			spew.Sdump(expBreakdowns), spew.Sdump(breakdowns))
+	}
+
+	// A fee that doesn't split evenly leaves the remainder to the first
+	// retribution, such that the total fee is accounted for.
+	justiceTx.TxOut[0].Value = 99001
+	breakdowns = justiceBreakdowns(
+		[]*retributionInfo{first, second}, justiceTx,
+	)
+	if breakdowns[0].FeePaid+breakdowns[1].FeePaid != 999 {
+		t.Fatalf("expected total fee of 999, got %v",
+			breakdowns[0].FeePaid+breakdowns[1].FeePaid)
+	}
+	if breakdowns[1].FeePaid != 199 {
+		t.Fatalf("expected fee of 199, got %v", breakdowns[1].FeePaid)
+	}
+
+	// The breakdown survives a serialization round trip of the
+	// retribution, and is carried over to its archived breach.
+	first.justiceBreakdown = expBreakdowns[0]
+
+	var buf bytes.Buffer
+	if err := first.Encode(&buf); err != nil {
+		t.Fatalf("unable to serialize retribution: %v", err)
+	}
+	desRet := &retributionInfo{}
+	if err := desRet.Decode(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("unable to deserialize retribution: %v", err)
+	}
+	if !reflect.DeepEqual(desRet.justiceBreakdown, expBreakdowns[0]) {
+		t.Fatalf("expected breakdown %v, got %v", expBreakdowns[0],
+			desRet.justiceBreakdown)
+	}
+
+	store := newMockRetributionStore()
+	if err := store.Add(first, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+	err := store.Archive(&ArchivedBreach{retribution: first})
+	if err != nil {
+		t.Fatalf("unable to archive breach: %v", err)
+	}
+	err = store.ForAllArchived(func(a *ArchivedBreach) error {
+		if !reflect.DeepEqual(a.Breakdown, expBreakdowns[0]) {
+			t.Fatalf("expected archived breakdown %v, got %v",
+				expBreakdowns[0], a.Breakdown)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unable to fetch archived breaches: %v", err)
+	}
+}
+
+// TestJusticeMinBumpSpacing asserts that, however rapidly blocks are
+// connected, successive fee bumps of a justice transaction are spaced by at
+// least JusticeMinBumpBlocks, and that JusticeMinBumpInterval holds back bumps
+// until it has elapsed.
+func TestJusticeMinBumpSpacing(t *testing.T) {
+	runRetribution := func(minBlocks uint32,
+		minInterval time.Duration) []btcutil.Amount {
+
+		notifier := &mockNotifier{
+			confChannel: make(chan *chainntnfs.TxConfirmation, 1),
+			epochChan:   make(chan *chainntnfs.BlockEpoch, 1),
+		}
+		published := make(chan *wire.MsgTx, 20)
+		brar := newTestBreachArbiter(&BreachConfig{
+			Notifier: notifier,
+			Wallet:   newTestWallet(published),
+			SweepScriptGen: func() ([]byte, error) {
+				return []byte{0x00, 0x14}, nil
+			},
+			JusticeBumpSchedule:    []uint32{1, 2, 3, 4},
+			JusticeMinBumpBlocks:   minBlocks,
+			JusticeMinBumpInterval: minInterval,
+		})
+
+		ret := newBreachRetInfo()
+		totalAmt := ret.selfOutput.amt + ret.revokedOutput.amt
+
+		brar.wg.Add(1)
+		go brar.exactRetribution(
+			&chainntnfs.ConfirmationEvent{
+				Confirmed: notifier.confChannel,
+			},
+			ret,
+		)
+		defer func() {
+			close(brar.quit)
+			brar.wg.Wait()
+		}()
+
+		// Once the breach confirms and justice is broadcast, a burst
+		// of blocks is connected in quick succession.
+		notifier.confChannel <- &chainntnfs.TxConfirmation{}
+		select {
+		case <-published:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("justice tx not published")
+		}
+		for i := int32(1); i <= 8; i++ {
+			notifier.epochChan <- &chainntnfs.BlockEpoch{
+				Height: fundingBroadcastHeight + i,
+			}
+		}
+
+		var fees []btcutil.Amount
+		for {
+			select {
+			case tx := <-published:
+				fees = append(
+					fees, totalAmt-btcutil.Amount(
+						tx.TxOut[0].Value,
+					),
+				)
+			case <-time.After(200 * time.Millisecond):
+				return fees
+			}
+		}
+	}
+
+	// Without a minimum, each block reaches the next tier of the
+	// schedule, bumping the fee every block.
+	fees := runRetribution(0, 0)
+	expFees := []btcutil.Amount{
+		2 * justiceBaseFee, 4 * justiceBaseFee, 8 * justiceBaseFee,
+		16 * justiceBaseFee,
+	}
+	if !reflect.DeepEqual(fees, expFees) {
+		t.Fatalf("expected bumps paying %v, got %v", expFees, fees)
+	}
+
+	// Requiring two blocks between bumps only bumps at the second and
+	// fourth blocks, at the tier the schedule has reached by then.
+	fees = runRetribution(2, 0)
+	expFees = []btcutil.Amount{4 * justiceBaseFee, 16 * justiceBaseFee}
+	if !reflect.DeepEqual(fees, expFees) {
+		t.Fatalf("expected bumps paying %v, got %v", expFees, fees)
+	}
+
+	// A minimum interval that has yet to elapse holds back all bumps.
+	fees = runRetribution(0, time.Hour)
+	if len(fees) != 0 {
+		t.Fatalf("expected no bumps, got %v", fees)
+	}
+}
+
+// newTestBreach returns the breach of the second state of a channel, whose
+// revocation secret we hold, along with the channel's backup from which the
+// breach's sign descriptors are derived.
+func newTestBreach(t *testing.T) (*lnwallet.BreachRetribution,
+	*channeldb.ChannelBackup) {
+
+	newPubKey := func() *btcec.PublicKey {
+		priv, err := btcec.NewPrivateKey(btcec.S256())
+		if err != nil {
+			t.Fatalf("unable to generate key: %v", err)
+		}
+		return priv.PubKey()
+	}
+
+	// We hold the revocation secrets of the first two states.
+	producer := shachain.NewRevocationProducer(chainhash.Hash{0x01})
+	revocations := shachain.NewRevocationStore()
+	for i := uint64(0); i < 2; i++ {
+		secret, err := producer.AtIndex(i)
+		if err != nil {
+			t.Fatalf("unable to derive revocation secret: %v", err)
+		}
+		if err := revocations.AddNextEntry(secret); err != nil {
+			t.Fatalf("unable to add revocation secret: %v", err)
+		}
+	}
+	backup := &channeldb.ChannelBackup{
+		FundingOutpoint: breachOutPoints[0],
+		IdentityPub:     alicePrivKey.PubKey(),
+		LocalChanCfg: channeldb.ChannelConfig{
+			PaymentBasePoint:    newPubKey(),
+			RevocationBasePoint: newPubKey(),
+		},
+		RemoteChanCfg: channeldb.ChannelConfig{
+			CsvDelay:         144,
+			PaymentBasePoint: newPubKey(),
+			DelayBasePoint:   newPubKey(),
+		},
+		RevocationStore: revocations,
+	}
+
+	localDesc, remoteDesc, err := lnwallet.DeriveBreachSignDescs(
+		backup, 1, 20000, 50000,
+	)
+	if err != nil {
+		t.Fatalf("unable to derive sign descriptors: %v", err)
+	}
+	breachTx := wire.NewMsgTx(2)
+	breachTx.AddTxIn(&wire.TxIn{PreviousOutPoint: breachOutPoints[0]})
+	breachTx.AddTxOut(&wire.TxOut{
+		Value:    20000,
+		PkScript: localDesc.WitnessScript,
+	})
+	breachTx.AddTxOut(remoteDesc.Output)
+	breachTxid := breachTx.TxHash()
+	breachInfo := &lnwallet.BreachRetribution{
+		BreachTransaction:    breachTx,
+		RevokedStateNum:      1,
+		LocalOutpoint:        wire.OutPoint{Hash: breachTxid},
+		LocalOutputSignDesc:  *localDesc,
+		RemoteOutpoint:       wire.OutPoint{Hash: breachTxid, Index: 1},
+		RemoteOutputSignDesc: *remoteDesc,
+		RemoteDelay:          144,
+	}
+
+	return breachInfo, backup
+}
+
+// TestCompactSignDescriptors asserts that, if configured, the sign descriptors
+// of a retribution's commitment outputs are persisted by reference, and are
+// re-derived from the channel's backup upon recovery.
+func TestCompactSignDescriptors(t *testing.T) {
+	breachInfo, backup := newTestBreach(t)
+
+	fetchBackup := func(*wire.OutPoint) (*channeldb.ChannelBackup, error) {
+		return backup, nil
+	}
+	newRetribution := func(compact bool) ([]byte, *retributionInfo) {
+		brar := newTestBreachArbiter(&BreachConfig{
+			CompactSignDescriptors: compact,
+			FetchChannelBackup:     fetchBackup,
+		})
+		ret := brar.newRetributionInfo(
+			&breachOutPoints[0], breachInfo,
+			*alicePrivKey.PubKey(), 100000, 20000,
+		)
+
+		var buf bytes.Buffer
+		if err := ret.Encode(&buf); err != nil {
+			t.Fatalf("unable to serialize retribution: %v", err)
+		}
+		return buf.Bytes(), ret
+	}
+
+	// By default, the full descriptors are persisted.
+	fullBytes, fullRet := newRetribution(false)
+	compactBytes, compactRet := newRetribution(true)
+	if len(compactBytes) >= len(fullBytes) {
+		t.Fatalf("expected compact record to be smaller than %v "+
+			"bytes, got %v", len(fullBytes), len(compactBytes))
+	}
+
+	desRet := &retributionInfo{}
+	if err := desRet.Decode(bytes.NewReader(fullBytes)); err != nil {
+		t.Fatalf("unable to deserialize retribution: %v", err)
+	}
+	if desRet.selfOutput.signDescPending() {
+		t.Fatalf("full descriptor should not be pending")
+	}
+
+	// A record persisted by reference still passes verification, despite
+	// its descriptors having yet to be re-derived.
+	desRet = &retributionInfo{}
+	if err := desRet.Decode(bytes.NewReader(compactBytes)); err != nil {
+		t.Fatalf("unable to deserialize retribution: %v", err)
+	}
+	if !desRet.selfOutput.signDescPending() ||
+		!desRet.revokedOutput.signDescPending() {
+
+		t.Fatalf("expected sign descriptors to be pending")
+	}
+	if desRet.revokedStateNum != 1 {
+		t.Fatalf("expected revoked state 1, got %v",
+			desRet.revokedStateNum)
+	}
+	if err := desRet.verifyIntegrity(); err != nil {
+		t.Fatalf("unable to verify retribution: %v", err)
+	}
+
+	// Without access to the channel's backup, the descriptors can't be
+	// re-derived.
+	brar := newTestBreachArbiter(&BreachConfig{})
+	if err := brar.rederiveSignDescs(desRet); err == nil {
+		t.Fatalf("expected re-derivation without backups to fail")
+	}
+
+	// With it, they match the descriptors of the original retribution,
+	// and the record remains compact when persisted anew.
+	brar = newTestBreachArbiter(&BreachConfig{
+		FetchChannelBackup: fetchBackup,
+	})
+	if err := brar.rederiveSignDescs(desRet); err != nil {
+		t.Fatalf("unable to re-derive sign descriptors: %v", err)
+	}
+	if !reflect.DeepEqual(desRet.selfOutput.signDescriptor,
+		fullRet.selfOutput.signDescriptor) {
+
+		t.Fatalf("re-derived local sign descriptor doesn't match")
+	}
+	if !reflect.DeepEqual(desRet.revokedOutput.signDescriptor,
+		fullRet.revokedOutput.signDescriptor) {
+
+		t.Fatalf("re-derived remote sign descriptor doesn't match")
+	}
+	if err := desRet.checkSweepable(); err != nil {
+		t.Fatalf("re-derived retribution not sweepable: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := desRet.Encode(&buf); err != nil {
+		t.Fatalf("unable to serialize retribution: %v", err)
+	}
+	var expBuf bytes.Buffer
+	compactRet.breachDetectedAt = desRet.breachDetectedAt
+	if err := compactRet.Encode(&expBuf); err != nil {
+		t.Fatalf("unable to serialize retribution: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), expBuf.Bytes()) {
+		t.Fatalf("expected re-derived retribution to remain compact")
+	}
+}
+
+// testRestoredFromBackup asserts that a channel marked as restored from a
+// backup has its apparent breach deferred to recovery, rather than punished,
+// that the mark only applies to the marked channel and survives a restart, and
+// that it's cleared once the channel is fully closed.
+func testRestoredFromBackup(h *breachTestHarness, t *testing.T) {
+	chanPoint := h.alice.ChannelPoint()
+	newArbiter := func() *breachArbiter {
+		return newTestBreachArbiter(&BreachConfig{
+			Notifier: h.notifier,
+			DB:       h.db,
+			Store:    h.store,
+			CloseLink: func(*wire.OutPoint,
+				htlcswitch.ChannelCloseType) {
+			},
+		})
+	}
+	brar := newArbiter()
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	if brar.dataLossSuspected(chanPoint) {
+		t.Fatalf("unmarked channel suspected of data loss")
+	}
+	if err := brar.MarkRestoredFromBackup(chanPoint); err != nil {
+		t.Fatalf("unable to mark channel: %v", err)
+	}
+	if !brar.dataLossSuspected(chanPoint) {
+		t.Fatalf("marked channel not suspected of data loss")
+	}
+	if brar.dataLossSuspected(&breachOutPoints[0]) {
+		t.Fatalf("unmarked channel suspected of data loss")
+	}
+
+	// The mark is persisted, so it survives a restart.
+	if !newArbiter().dataLossSuspected(chanPoint) {
+		t.Fatalf("mark lost across restart")
+	}
+
+	// An apparent breach of the marked channel is deferred to recovery:
+	// no retribution is persisted, and the channel is closed as a force
+	// close rather than a breach.
+	brar.handleContractBreach(h.alice, &lnwallet.BreachRetribution{
+		BreachTransaction:   wire.NewMsgTx(2),
+		LocalOutputSignDesc: breachSignDescs[0],
+	})
+	if n := countRetributions(t, h.store); n != 0 {
+		t.Fatalf("expected no retribution, found %v", n)
+	}
+	pendingCloses, err := h.db.FetchClosedChannels(true)
+	if err != nil {
+		t.Fatalf("unable to fetch pending closes: %v", err)
+	}
+	if len(pendingCloses) != 1 ||
+		pendingCloses[0].CloseType != channeldb.ForceClose {
+
+		t.Fatalf("expected a single pending force close, got %v",
+			spew.Sdump(pendingCloses))
+	}
+
+	// Once the channel is fully closed, the mark is of no further use.
+	if err := brar.markChanFullyClosed(chanPoint); err != nil {
+		t.Fatalf("unable to mark channel closed: %v", err)
+	}
+	restored, err := brar.restoredChans.Has(chanPoint)
+	if err != nil {
+		t.Fatalf("unable to query mark: %v", err)
+	}
+	if restored {
+		t.Fatalf("mark not cleared once channel closed")
+	}
+}
+
+// addFailingRetributionStore is a mock retribution store which fails to persist
+// any retribution added to it.
+type addFailingRetributionStore struct {
+	*mockRetributionStore
+}
+
+func (rs *addFailingRetributionStore) Add(*retributionInfo,
+	<-chan struct{}) error {
+
+	return errors.New("unable to add retribution")
+}
+
+// testBreachPersistFailure asserts that a breach whose retribution can't be
+// persisted is left untouched, rather than having its channel's state deleted
+// with nothing to resume the retribution from after a restart.
+func testBreachPersistFailure(h *breachTestHarness, t *testing.T) {
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier: h.notifier,
+		DB:       h.db,
+		Store: &addFailingRetributionStore{
+			mockRetributionStore: newMockRetributionStore(),
+		},
+		CloseLink: func(*wire.OutPoint,
+			htlcswitch.ChannelCloseType) {
+		},
+	})
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	breachInfo, _ := newTestBreach(t)
+	brar.handleContractBreach(h.alice, breachInfo)
+
+	select {
+	case ret := <-brar.breachedContracts:
+		t.Fatalf("unpersisted retribution for %v handed off",
+			ret.chanPoint)
+	default:
+	}
+
+	pendingCloses, err := h.db.FetchClosedChannels(true)
+	if err != nil {
+		t.Fatalf("unable to fetch pending closes: %v", err)
+	}
+	if len(pendingCloses) != 0 {
+		t.Fatalf("expected channel state to be kept, got closes %v",
+			spew.Sdump(pendingCloses))
+	}
+}
+
+// testUneconomicBreach asserts that a confirmed breach whose outputs are worth
+// less than the fee required to sweep them, and which the breaching party may
+// already claim, is acknowledged by archiving it, closing the channel and
+// blacklisting the peer, without any justice transaction being broadcast.
+func testUneconomicBreach(h *breachTestHarness, t *testing.T) {
+	notified := make(chan *UneconomicBreach, 1)
+	resolved := make(chan wire.OutPoint, 1)
+	published := make(chan *wire.MsgTx, 10)
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier: h.notifier,
+		DB:       h.db,
+		Wallet: &lnwallet.LightningWallet{
+			WalletController: &mockWalletController{
+				rootKey:               alicePrivKey,
+				publishedTransactions: published,
+			},
+		},
+		Store: h.store,
+		Events: &mockBreachEvents{
+			uneconomicBreach: func(breach *UneconomicBreach) {
+				notified <- breach
+			},
+			channelResolved: func(chanPoint wire.OutPoint,
+				_ btcutil.Amount, _ uint32,
+				_ *JusticeBreakdown) {
+
+				resolved <- chanPoint
+			},
+		},
+	})
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	// A breach worth well above the fee of its justice transaction is
+	// pursued as usual.
+	ret := h.newBreachRetInfo()
+	ret.expectedJusticeFee = brar.estimateJusticeFee(ret)
+	if brar.uneconomicBreach(ret) {
+		t.Fatalf("breach worth %v with a fee of %v deemed uneconomic",
+			ret.valueAtRisk(), ret.expectedJusticeFee)
+	}
+
+	// Once its outputs are together worth less than the fee, however,
+	// there's nothing to recover.
+	ret.selfOutput.amt = justiceBaseFee / 4
+	ret.revokedOutput.amt = justiceBaseFee / 4
+	ret.expectedJusticeFee = brar.estimateJusticeFee(ret)
+	if !brar.uneconomicBreach(ret) {
+		t.Fatalf("breach worth %v with a fee of %v not deemed "+
+			"uneconomic", ret.valueAtRisk(), ret.expectedJusticeFee)
+	}
+
+	// As with any breach, the retribution is persisted and the channel's
+	// state deleted once the breach is detected.
+	ret.revokedOutput.witnessType = lnwallet.CommitmentRevoke
+	ret.revokedOutput.contestDelay = 10
+	if err := h.store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+	closeTestChannel(t, h.alice, ret.commitHash, channeldb.BreachClose)
+
+	// The breach confirms late enough for the breaching party to already
+	// be able to claim the revoked output, so there's no point in waiting
+	// for fees to fall.
+	brar.wg.Add(1)
+	go brar.exactRetribution(
+		&chainntnfs.ConfirmationEvent{Confirmed: h.notifier.confChannel},
+		ret,
+	)
+	h.notifier.confChannel <- &chainntnfs.TxConfirmation{
+		BlockHeight: fundingBroadcastHeight - 10,
+	}
+
+	// The operator should be informed of the breach, along with the value
+	// that was left unswept.
+	select {
+	case breach := <-notified:
+		if breach.ChanPoint != ret.chanPoint ||
+			breach.BreachTxid != ret.commitHash {
+
+			t.Fatalf("unexpected uneconomic breach %v",
+				spew.Sdump(breach))
+		}
+		if breach.ValueAtRisk != justiceBaseFee/2 {
+			t.Fatalf("expected %v at risk, got %v",
+				justiceBaseFee/2, breach.ValueAtRisk)
+		}
+		if breach.ExpectedFee != brar.estimateJusticeFee(ret) {
+			t.Fatalf("expected fee %v, got %v",
+				brar.estimateJusticeFee(ret), breach.ExpectedFee)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("uneconomic breach notification not delivered")
+	}
+
+	select {
+	case chanPoint := <-resolved:
+		if chanPoint != ret.chanPoint {
+			t.Fatalf("expected ChannelPoint(%v) resolved, got %v",
+				ret.chanPoint, chanPoint)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("channel resolution not notified")
+	}
+
+	// The channel should be fully closed.
+	closed, err := brar.breachResolved(&ret.chanPoint)
+	if err != nil {
+		t.Fatalf("unable to query resolution: %v", err)
+	}
+	pendingCloses, err := h.db.FetchClosedChannels(true)
+	if err != nil {
+		t.Fatalf("unable to fetch pending closes: %v", err)
+	}
+	if !closed || len(pendingCloses) != 0 {
+		t.Fatalf("uneconomic breach not closed")
+	}
+
+	// The breach should be archived as uneconomic, rather than pending
+	// retribution, even without RetainBreachEvidence.
+	if countRetributions(t, h.store) != 0 {
+		t.Fatalf("uneconomic breach pending retribution")
+	}
+	archived, err := brar.ArchivedBreaches()
+	if err != nil {
+		t.Fatalf("unable to fetch archived breaches: %v", err)
+	}
+	if len(archived) != 1 || !archived[0].Uneconomic {
+		t.Fatalf("expected a single uneconomic archived breach, got %v",
+			spew.Sdump(archived))
+	}
+
+	// The flag should survive a round trip through serialization.
+	var buf bytes.Buffer
+	if err := archived[0].retribution.Encode(&buf); err != nil {
+		t.Fatalf("unable to serialize retribution: %v", err)
+	}
+	desRet := &retributionInfo{}
+	if err := desRet.Decode(&buf); err != nil {
+		t.Fatalf("unable to deserialize retribution: %v", err)
+	}
+	if !desRet.uneconomic {
+		t.Fatalf("uneconomic flag lost in serialization")
+	}
+
+	// Having breached, the peer should be blacklisted.
+	blacklisted, err := brar.IsBlacklisted(
+		&ret.remoteIdentity, BlacklistConnect,
+	)
+	if err != nil {
+		t.Fatalf("unable to query blacklist: %v", err)
+	}
+	if !blacklisted {
+		t.Fatalf("uneconomic breach didn't blacklist peer")
+	}
+
+	// At no point should a justice transaction have been broadcast.
+	select {
+	case tx := <-published:
+		t.Fatalf("unexpected tx %v published", tx.TxHash())
+	default:
+	}
+}
+
+// TestUneconomicBreachAwaitsLowerFees asserts that a confirmed breach which is
+// uneconomic to punish at the current fee estimate, but which the breaching
+// party can't yet claim, is re-checked with each block, and that justice is
+// served once fees fall.
+func TestUneconomicBreachAwaitsLowerFees(t *testing.T) {
+	notifier := &mockNotifier{
+		confChannel: make(chan *chainntnfs.TxConfirmation, 1),
+		epochChan:   make(chan *chainntnfs.BlockEpoch, 1),
+	}
+	published := make(chan *wire.MsgTx, 10)
+	estimator := &adjustableFeeEstimator{feePerWeight: 1000}
+	store := newMockRetributionStore()
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier:  notifier,
+		Estimator: estimator,
+		Wallet:    newTestWallet(published),
+		Store:     store,
+		SweepScriptGen: func() ([]byte, error) {
+			return []byte{0x00, 0x14}, nil
+		},
+		Events: &mockBreachEvents{
+			uneconomicBreach: func(breach *UneconomicBreach) {
+				t.Errorf("breach of ChannelPoint(%v) "+
+					"acknowledged as uneconomic",
+					breach.ChanPoint)
+			},
+		},
+	})
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	ret := newBreachRetInfo()
+	ret.selfOutput.amt = 20000
+	ret.revokedOutput.amt = 20000
+	ret.revokedOutput.witnessType = lnwallet.CommitmentRevoke
+	ret.revokedOutput.contestDelay = 10
+	if err := store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+
+	brar.wg.Add(1)
+	go brar.exactRetribution(
+		&chainntnfs.ConfirmationEvent{Confirmed: notifier.confChannel},
+		ret,
+	)
+	notifier.confChannel <- &chainntnfs.TxConfirmation{
+		BlockHeight: fundingBroadcastHeight,
+	}
+
+	// At the current fee estimate, the breach is uneconomic, so the
+	// retribution remains persisted awaiting lower fees.
+	deadline := time.After(5 * time.Second)
+	for {
+		brar.retMtx.Lock()
+		status, ok := brar.activeRetributions[ret.chanPoint]
+		reached := ok && status.phase == retPhaseUneconomic
+		brar.retMtx.Unlock()
+		if reached {
+			break
+		}
+
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatalf("retribution not deemed uneconomic")
+		}
+	}
+	select {
+	case tx := <-published:
+		t.Fatalf("uneconomic justice tx %v published", tx.TxHash())
+	case <-time.After(50 * time.Millisecond):
+	}
+	if n := countRetributions(t, store); n != 1 {
+		t.Fatalf("expected retribution to remain persisted, found %v",
+			n)
+	}
+
+	// A new block without any change in fees leaves it waiting.
+	notifier.epochChan <- &chainntnfs.BlockEpoch{
+		Height: fundingBroadcastHeight + 1,
+	}
+	select {
+	case tx := <-published:
+		t.Fatalf("uneconomic justice tx %v published", tx.TxHash())
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Once fees fall, justice is served on the next block.
+	atomic.StoreUint64(&estimator.feePerWeight, 1)
+	notifier.epochChan <- &chainntnfs.BlockEpoch{
+		Height: fundingBroadcastHeight + 2,
+	}
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("justice tx not published once fees fell")
+	}
+}
+
+// mempoolChainIO is a mock chain backend which, like btcd, only reports the
+// outputs of confirmed transactions within the UTXO set, ignoring those of
+// transactions still within the mempool.
+type mempoolChainIO struct {
+	mockChainIO
+
+	mu        sync.Mutex
+	mempool   map[chainhash.Hash]*wire.MsgTx
+	confirmed map[chainhash.Hash]*wire.MsgTx
+}
+
+func (c *mempoolChainIO) GetUtxo(op *wire.OutPoint,
+	heightHint uint32) (*wire.TxOut, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tx, ok := c.confirmed[op.Hash]
+	if !ok || int(op.Index) >= len(tx.TxOut) {
+		return nil, fmt.Errorf("output %v not found", op)
+	}
+
+	return tx.TxOut[op.Index], nil
+}
+
+func (c *mempoolChainIO) confirm(txid chainhash.Hash) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.confirmed[txid] = c.mempool[txid]
+	delete(c.mempool, txid)
+}
+
+// testStartResumesUnconfirmedBreach asserts that a persisted retribution whose
+// breach transaction is yet to confirm at startup isn't mistaken for an
+// unverifiable one. Its channel must remain closed, and its retribution
+// resumed, such that justice is served once the breach confirms.
+func testStartResumesUnconfirmedBreach(h *breachTestHarness, t *testing.T) {
+	// The breach transaction was broadcast, and its retribution persisted,
+	// but it still sits within the mempool as the daemon restarts.
+	ret := h.newBreachRetInfo()
+
+	breachTx := wire.NewMsgTx(2)
+	breachTx.AddTxOut(&wire.TxOut{
+		Value:    int64(ret.selfOutput.amt),
+		PkScript: ret.selfOutput.signDescriptor.Output.PkScript,
+	})
+	breachTx.AddTxOut(&wire.TxOut{
+		Value:    int64(ret.revokedOutput.amt),
+		PkScript: ret.revokedOutput.signDescriptor.Output.PkScript,
+	})
+	ret.commitHash = breachTx.TxHash()
+	ret.selfOutput.outpoint = wire.OutPoint{Hash: ret.commitHash}
+	ret.revokedOutput.outpoint = wire.OutPoint{
+		Hash:  ret.commitHash,
+		Index: 1,
+	}
+	if err := h.store.Add(ret, nil); err != nil {
+		t.Fatalf("unable to add retribution: %v", err)
+	}
+
+	chainIO := &mempoolChainIO{
+		mempool: map[chainhash.Hash]*wire.MsgTx{
+			ret.commitHash: breachTx,
+		},
+		confirmed: make(map[chainhash.Hash]*wire.MsgTx),
+	}
+	closedLinks := make(chan wire.OutPoint, 10)
+	published := make(chan *wire.MsgTx, 10)
+	brar := newTestBreachArbiter(&BreachConfig{
+		ChainIO: chainIO,
+		CloseLink: func(chanPoint *wire.OutPoint,
+			_ htlcswitch.ChannelCloseType) {
+
+			closedLinks <- *chanPoint
+		},
+		DB:       h.db,
+		Notifier: h.notifier,
+		Wallet:   newTestWallet(published),
+		Store:    h.store,
+		SweepScriptGen: func() ([]byte, error) {
+			return make([]byte, lnwallet.P2WPKHSize), nil
+		},
+	})
+	if err := brar.Start(); err != nil {
+		t.Fatalf("unable to start breach arbiter: %v", err)
+	}
+	defer brar.Stop()
+
+	// The link of the breached channel must have been closed, and its
+	// state deleted, rather than it being watched once more.
+	select {
+	case chanPoint := <-closedLinks:
+		if chanPoint != ret.chanPoint {
+			t.Fatalf("unexpected link of %v closed", chanPoint)
+		}
+	default:
+		t.Fatalf("link of breached channel not closed")
+	}
+	openChans, err := h.db.FetchAllChannels()
+	if err != nil && err != channeldb.ErrNoActiveChannels {
+		t.Fatalf("unable to fetch open channels: %v", err)
+	}
+	for _, channel := range openChans {
+		if channel.FundingOutpoint == ret.chanPoint {
+			t.Fatalf("breached channel still open")
+		}
+	}
+
+	// The retribution must await the confirmation of the breach, rather
+	// than being flagged as unverified.
+	select {
+	case txid := <-h.notifier.txids:
+		if txid != ret.commitHash {
+			t.Fatalf("unexpected registration for %v", txid)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("retribution not resumed")
+	}
+	brar.retMtx.Lock()
+	_, unverified := brar.unverifiedRetributions[ret.chanPoint]
+	brar.retMtx.Unlock()
+	if unverified {
+		t.Fatalf("unconfirmed breach flagged as unverified")
+	}
+	if countRetributions(t, h.store) != 1 {
+		t.Fatalf("retribution of unconfirmed breach dropped")
+	}
+
+	// Once the breach confirms, justice should be served.
+	chainIO.confirm(ret.commitHash)
+	h.notifier.confChannel <- &chainntnfs.TxConfirmation{}
+
+	select {
+	case justiceTx := <-published:
+		spent := make(map[wire.OutPoint]struct{})
+		for _, txIn := range justiceTx.TxIn {
+			spent[txIn.PreviousOutPoint] = struct{}{}
+		}
+		if _, ok := spent[ret.revokedOutput.outpoint]; !ok {
+			t.Fatalf("justice tx doesn't spend revoked output")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("justice tx not published")
+	}
+}
+
+// TestPeerJusticeBatchUnknownIdentity asserts that, with peer batching enabled,
+// a retribution whose persisted remote identity couldn't be parsed is served
+// on its own, rather than crashing the breach arbiter as it's matched up with
+// others from the same peer.
+func TestPeerJusticeBatchUnknownIdentity(t *testing.T) {
+	notifier := &mockNotifier{
+		confChannel: make(chan *chainntnfs.TxConfirmation, 1),
+	}
+	published := make(chan *wire.MsgTx, 10)
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier: notifier,
+		Wallet:   newTestWallet(published),
+		SweepScriptGen: func() ([]byte, error) {
+			return make([]byte, lnwallet.P2WPKHSize), nil
+		},
+		BatchPeerJustice: true,
+	})
+
+	// A record whose remote identity failed to parse is restored with a
+	// zero identity.
+	ret := newBreachRetInfo()
+	ret.remoteIdentity = btcec.PublicKey{}
+	if batch := brar.joinPeerBatch(ret); batch != nil {
+		t.Fatalf("retribution of unknown peer joined a batch")
+	}
+
+	brar.wg.Add(1)
+	go brar.exactRetribution(
+		&chainntnfs.ConfirmationEvent{
+			Confirmed: notifier.confChannel,
+		},
+		ret,
+	)
+	defer func() {
+		close(brar.quit)
+		brar.wg.Wait()
+	}()
+
+	notifier.confChannel <- &chainntnfs.TxConfirmation{}
+
+	select {
+	case justiceTx := <-published:
+		if len(justiceTx.TxIn) != 2 {
+			t.Fatalf("expected justice tx with 2 inputs, found %v",
+				len(justiceTx.TxIn))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("justice tx not published")
+	}
+}
+
+// TestPeerJusticeBatchDeadline asserts that a retribution whose breach has
+// confirmed doesn't wait indefinitely for the breaches of the rest of its
+// peer's batch. Should the breaching party hold one of them back, the
+// retribution is served alone once halfway to the height at which they may
+// claim its outputs.
+func TestPeerJusticeBatchDeadline(t *testing.T) {
+	epochs := make(chan *chainntnfs.BlockEpoch)
+	published := make(chan *wire.MsgTx, 10)
+	brar := newTestBreachArbiter(&BreachConfig{
+		Notifier: &mockNotifier{epochChan: epochs},
+		Wallet:   newTestWallet(published),
+		SweepScriptGen: func() ([]byte, error) {
+			return []byte{0x00, 0x14}, nil
+		},
+		BatchPeerJustice: true,
+	})
+
+	// Two channels with the same peer are breached, but only the first
+	// breach confirms, after which the peer may claim its revoked output
+	// within ten blocks.
+	const contestDelay = 10
+	var rets []*retributionInfo
+	for i := 0; i < 2; i++ {
+		ret := newBreachRetInfo()
+		ret.commitHash = chainhash.Hash{byte(i + 1)}
+		ret.chanPoint.Index = uint32(i)
+		ret.selfOutput.outpoint.Hash = ret.commitHash
+		ret.revokedOutput.outpoint.Hash = ret.commitHash
+		ret.revokedOutput.witnessType = lnwallet.CommitmentRevoke
+		ret.revokedOutput.contestDelay = contestDelay
+		ret.remoteIdentity = *alicePrivKey.PubKey()
+
+		rets = append(rets, ret)
+	}
+	batch := brar.joinPeerBatch(rets[0])
+	if brar.joinPeerBatch(rets[1]) != batch {
+		t.Fatalf("retributions against the same peer not batched")
+	}
+	rets[0].recordBreachHeight(uint32(fundingBroadcastHeight))
+
+	type result struct {
+		tx  *wire.MsgTx
+		err error
+	}
+	results := make(chan result, 1)
+	cancel := brar.setRetributionPhase(
+		&rets[0].chanPoint, retPhaseAwaitingBreachConf,
+	)
+	go func() {
+		tx, err := brar.awaitPeerJustice(batch, rets[0], cancel)
+		results <- result{tx, err}
+	}()
+
+	sendEpoch := func(height int32) {
+		select {
+		case epochs <- &chainntnfs.BlockEpoch{Height: height}:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("block epoch not consumed")
+		}
+	}
+
+	// Before the deadline, the retribution keeps waiting for its batch.
+	deadline := int32(fundingBroadcastHeight) + contestDelay/2
+	sendEpoch(deadline - 1)
+	select {
+	case tx := <-published:
+		t.Fatalf("justice tx %v broadcast before deadline",
+			tx.TxHash())
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Once the deadline is reached, it's served alone.
+	sendEpoch(deadline)
+
+	var justiceTx *wire.MsgTx
+	select {
+	case justiceTx = <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("justice tx not broadcast at deadline")
+	}
+	if len(justiceTx.TxIn) != 2 {
+		t.Fatalf("expected 2 inputs, found %v", len(justiceTx.TxIn))
+	}
+	for _, txIn := range justiceTx.TxIn {
+		if txIn.PreviousOutPoint.Hash != rets[0].commitHash {
+			t.Fatalf("justice tx spends unconfirmed breach")
+		}
+	}
+
+	res := <-results
+	if res.err != nil {
+		t.Fatalf("unable to await justice: %v", res.err)
+	}
+	if res.tx.TxHash() != justiceTx.TxHash() {
+		t.Fatalf("retribution served by unexpected tx")
+	}
+
+	// The unconfirmed breach remains batched, to be served once it
+	// confirms.
+	brar.batchMtx.Lock()
+	_, pending := batch.pending[rets[1].chanPoint]
+	numConfirmed := len(batch.confirmed)
+	brar.batchMtx.Unlock()
+	if !pending || numConfirmed != 0 {
+		t.Fatalf("expected only the unconfirmed breach to remain " +
+			"batched")
+	}
+}
+
+var breachArbiterTestSuite = []struct {
+	name string
+	test func(*breachTestHarness, *testing.T)
+}{
+	{
+		"BreachResolved",
+		testBreachResolved,
+	},
+	{
+		"BreachRemedyKit",
+		testBreachRemedyKit,
+	},
+	{
+		"JusticeConfirmedBeforeBreach",
+		testJusticeConfirmedBeforeBreach,
+	},
+	{
+		"BreachedPendingCloseOverlap",
+		testBreachedPendingCloseOverlap,
+	},
+	{
+		"StartPromotesBreachedOpenChannel",
+		testStartPromotesBreachedOpenChannel,
+	},
+	{
+		"StartFastForwardsConfirmedJustice",
+		testStartFastForwardsConfirmedJustice,
+	},
+	{
+		"StartResumesUnconfirmedBreach",
+		testStartResumesUnconfirmedBreach,
+	},
+	{
+		"StartRegistrationOrder",
+		testStartRegistrationOrder,
+	},
+	{
+		"BreachDeleteStateFailure",
+		testBreachDeleteStateFailure,
+	},
+	{
+		"BreachPersistFailure",
+		testBreachPersistFailure,
+	},
+	{
+		"RestoredFromBackup",
+		testRestoredFromBackup,
+	},
+	{
+		"UneconomicBreach",
+		testUneconomicBreach,
+	},
+}
+
+// TestBreachArbiter runs each test of breachArbiterTestSuite against a fresh
+// breachTestHarness.
+func TestBreachArbiter(t *testing.T) {
+	for _, test := range breachArbiterTestSuite {
+		t.Run(test.name, func(tt *testing.T) {
+			h := newBreachTestHarness(tt)
+			defer h.cleanUp()
+
+			test.test(h, tt)
+		})
+	}
+}
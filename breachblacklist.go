@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/roasbeef/btcd/btcec"
+)
+
+// BlacklistAction is the action attempted by a blacklisted peer.
+type BlacklistAction uint8
+
+const (
+	// BlacklistConnect denotes a blacklisted peer attempting to connect.
+	BlacklistConnect BlacklistAction = iota
+
+	// BlacklistOpenChannel denotes a blacklisted peer attempting to open
+	// a channel.
+	BlacklistOpenChannel
+)
+
+// String returns a human readable description of the blacklist action.
+func (a BlacklistAction) String() string {
+	switch a {
+	case BlacklistConnect:
+		return "Connect"
+	case BlacklistOpenChannel:
+		return "OpenChannel"
+	default:
+		return fmt.Sprintf("BlacklistAction(%d)", uint8(a))
+	}
+}
+
+// BlacklistHit describes an attempt by a peer that has previously breached
+// one of our channels to interact with us once again.
+type BlacklistHit struct {
+	// PeerPub is the identity public key of the blacklisted peer.
+	PeerPub [33]byte
+
+	// Timestamp is the time at which the attempt was made.
+	Timestamp time.Time
+
+	// Action is the action the peer attempted.
+	Action BlacklistAction
+}
+
+// BlacklistHitSubscription delivers the blacklist hits observed by the
+// breach arbiter. Hits are delivered on a best-effort basis, and the
+// subscription must be cancelled once the caller is no longer interested.
+type BlacklistHitSubscription struct {
+	Hits chan *BlacklistHit
+
+	brar *breachArbiter
+	id   uint32
+}
+
+// Cancel unregisters the subscription, after which no further hits are
+// delivered.
+func (s *BlacklistHitSubscription) Cancel() {
+	s.brar.hitMtx.Lock()
+	delete(s.brar.hitClients, s.id)
+	s.brar.hitMtx.Unlock()
+}
+
+// SubscribeBlacklistHits returns a subscription delivering an event each time
+// a blacklisted peer attempts to connect or open a channel.
+func (b *breachArbiter) SubscribeBlacklistHits() *BlacklistHitSubscription {
+	client := &BlacklistHitSubscription{
+		Hits: make(chan *BlacklistHit),
+		brar: b,
+	}
+
+	b.hitMtx.Lock()
+	b.hitClients[b.nextHitClientID] = client
+	client.id = b.nextHitClientID
+	b.nextHitClientID++
+	b.hitMtx.Unlock()
+
+	return client
+}
+
+// IsBlacklisted returns true if the peer with the given identity has breached
+// one of our channels, whether its retribution is still pending or has since
+// been archived. The caller passes the action the peer is attempting, which
+// is reported to the subscribers of the blacklist hits if the peer is
+// blacklisted.
+func (b *breachArbiter) IsBlacklisted(pub *btcec.PublicKey,
+	action BlacklistAction) (bool, error) {
+
+	var peerPub [33]byte
+	copy(peerPub[:], pub.SerializeCompressed())
+
+	blacklisted, err := b.breachedBy(peerPub)
+	if err != nil || !blacklisted {
+		return false, err
+	}
+
+	brarLog.Warnf("Blacklisted peer %x attempted action %v", peerPub[:],
+		action)
+
+	b.notifyBlacklistHit(&BlacklistHit{
+		PeerPub:   peerPub,
+		Timestamp: time.Now(),
+		Action:    action,
+	})
+
+	return true, nil
+}
+
+// breachedBy returns true if a pending or archived breach was committed by
+// the peer with the given identity.
+func (b *breachArbiter) breachedBy(peerPub [33]byte) (bool, error) {
+	var breached bool
+	err := b.cfg.Store.ForAll(func(ret *retributionInfo) error {
+		if !ret.hasRemoteIdentity() {
+			return nil
+		}
+
+		var remotePub [33]byte
+		copy(remotePub[:], ret.remoteIdentity.SerializeCompressed())
+		if remotePub == peerPub {
+			breached = true
+		}
+		return nil
+	})
+	if err != nil || breached {
+		return breached, err
+	}
+
+	err = b.cfg.Store.ForAllArchived(func(a *ArchivedBreach) error {
+		if a.RemoteIdentity == peerPub {
+			breached = true
+		}
+		return nil
+	})
+
+	return breached, err
+}
+
+// notifyBlacklistHit delivers the hit to each subscriber. Each delivery is
+// made within its own goroutine, so that a slow subscriber can't block the
+// caller checking the blacklist.
+func (b *breachArbiter) notifyBlacklistHit(hit *BlacklistHit) {
+	b.hitMtx.Lock()
+	defer b.hitMtx.Unlock()
+
+	for _, client := range b.hitClients {
+		b.wg.Add(1)
+		go func(c *BlacklistHitSubscription) {
+			defer b.wg.Done()
+
+			select {
+			case c.Hits <- hit:
+			case <-b.quit:
+			}
+		}(client)
+	}
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/roasbeef/btcd/chaincfg/chainhash"
+	"github.com/roasbeef/btcd/wire"
+	"github.com/roasbeef/btcutil"
+)
+
+// BreachHealth is a point-in-time snapshot of the breach arbiter's state,
+// suitable for use by readiness and liveness probes.
+type BreachHealth struct {
+	// ObserverRunning is true if the main contractObserver goroutine is
+	// currently running.
+	ObserverRunning bool
+
+	// ActiveObservers is the number of channels currently being watched
+	// for breaches.
+	ActiveObservers int
+
+	// PendingRetributions is the number of retributions persisted within
+	// the retribution store that have yet to be completed.
+	PendingRetributions int
+
+	// StuckRetributions holds the channel points of any retribution that
+	// has remained in a single non-terminal phase for longer than the
+	// configured StuckRetributionTimeout.
+	StuckRetributions []wire.OutPoint
+
+	// UnverifiedRetributions holds the channel points of any persisted
+	// retribution whose breach could not be corroborated by the chain,
+	// and thus requires manual inspection by the operator.
+	UnverifiedRetributions []wire.OutPoint
+
+	// UnreconciledChannels holds the channel points of any breached
+	// channel whose state failed to be deleted during startup, and which
+	// thus remains open within channeldb until the close is retried.
+	UnreconciledChannels []wire.OutPoint
+
+	// UnregisteredRetributions holds the channel points of any
+	// retribution whose registration for the confirmation of its breach
+	// transaction failed during startup, and which thus isn't carried out
+	// until the registration is retried.
+	UnregisteredRetributions []wire.OutPoint
+
+	// InterventionRetributions holds the channel points of any
+	// retribution whose justice transaction failed to confirm within the
+	// configured JusticeConfTimeout, and thus requires manual
+	// intervention by the operator.
+	InterventionRetributions []wire.OutPoint
+
+	// QuarantinedRetributions holds each persisted retribution that could
+	// not be decoded during startup, and was therefore moved out of the
+	// retribution store for manual inspection by the operator.
+	QuarantinedRetributions []QuarantinedRetribution
+
+	// StoreErr is non-nil if the retribution store could not be read in
+	// its entirety during startup.
+	StoreErr error
+
+	// OldestBreachSeenAt is the time at which the breach of the oldest
+	// pending retribution was first seen, or zero if none are pending.
+	OldestBreachSeenAt time.Time
+
+	// OverdueRetributions holds each pending retribution whose breach was
+	// first seen longer ago than the configured JusticeSLA.
+	OverdueRetributions []OverdueRetribution
+}
+
+// OverdueRetribution is a pending retribution whose justice transaction has
+// failed to confirm within the configured JusticeSLA.
+type OverdueRetribution struct {
+	// ChanPoint is the channel point of the breached channel.
+	ChanPoint wire.OutPoint
+
+	// BreachSeenAt is the time at which the breach was first seen.
+	BreachSeenAt time.Time
+}
+
+// Healthy returns true if the contract observer is running, no retribution
+// has been stuck in a single phase beyond the configured timeout, or pending
+// beyond the JusticeSLA, no retribution is awaiting manual verification or
+// intervention, no breached channel awaits the reconciliation of its close or
+// the registration of its retribution, and the retribution store was recovered
+// without loss.
+func (h *BreachHealth) Healthy() bool {
+	return h.ObserverRunning && len(h.StuckRetributions) == 0 &&
+		len(h.UnverifiedRetributions) == 0 &&
+		len(h.UnreconciledChannels) == 0 &&
+		len(h.UnregisteredRetributions) == 0 &&
+		len(h.InterventionRetributions) == 0 &&
+		len(h.QuarantinedRetributions) == 0 && h.StoreErr == nil &&
+		len(h.OverdueRetributions) == 0
+}
+
+// HealthCheck reports whether the breach arbiter is currently functioning,
+// along with the size of its backlog. An error is returned only if the
+// pending retributions could not be read from the retribution store.
+func (b *breachArbiter) HealthCheck() (BreachHealth, error) {
+	health := BreachHealth{
+		ObserverRunning: atomic.LoadInt32(&b.observerActive) == 1,
+		ActiveObservers: b.numActiveObservers(),
+	}
+
+	now := time.Now()
+	err := b.cfg.Store.ForAll(func(ret *retributionInfo) error {
+		health.PendingRetributions++
+
+		seenAt := ret.breachDetectedAt
+		if seenAt.IsZero() {
+			return nil
+		}
+		if health.OldestBreachSeenAt.IsZero() ||
+			seenAt.Before(health.OldestBreachSeenAt) {
+
+			health.OldestBreachSeenAt = seenAt
+		}
+		if b.cfg.JusticeSLA != 0 && now.Sub(seenAt) > b.cfg.JusticeSLA {
+			health.OverdueRetributions = append(
+				health.OverdueRetributions, OverdueRetribution{
+					ChanPoint:    ret.chanPoint,
+					BreachSeenAt: seenAt,
+				},
+			)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return health, err
+	}
+
+	b.retMtx.Lock()
+	for chanPoint, status := range b.activeRetributions {
+		switch status.phase {
+		case retPhaseNeedsIntervention:
+			health.InterventionRetributions = append(
+				health.InterventionRetributions, chanPoint,
+			)
+			continue
+
+		// Retributions held by the operator, or awaiting fees to
+		// fall, aren't stuck.
+		case retPhaseBroadcastHeld, retPhaseUneconomic:
+			continue
+		}
+		if now.Sub(status.since) > b.cfg.StuckRetributionTimeout {
+			health.StuckRetributions = append(
+				health.StuckRetributions, chanPoint,
+			)
+		}
+	}
+	for chanPoint := range b.unverifiedRetributions {
+		health.UnverifiedRetributions = append(
+			health.UnverifiedRetributions, chanPoint,
+		)
+	}
+	for chanPoint := range b.unreconciledCloses {
+		health.UnreconciledChannels = append(
+			health.UnreconciledChannels, chanPoint,
+		)
+	}
+	for chanPoint := range b.unregisteredRetributions {
+		health.UnregisteredRetributions = append(
+			health.UnregisteredRetributions, chanPoint,
+		)
+	}
+	if b.storeRecovery != nil {
+		health.QuarantinedRetributions = b.storeRecovery.Quarantined
+		health.StoreErr = b.storeRecovery.BucketErr
+	}
+	b.retMtx.Unlock()
+
+	return health, nil
+}
+
+// RetributionDiagnostic is a report of everything the breach arbiter knows of
+// a single retribution, intended to aid the debugging of a stuck retribution.
+// It deliberately omits any secret material, such as the tweaks used to
+// derive the revocation key, so that it may be logged or returned over RPC.
+type RetributionDiagnostic struct {
+	// ChanPoint is the channel point of the breached channel.
+	ChanPoint wire.OutPoint
+
+	// BreachTxid is the txid of the revoked commitment transaction
+	// broadcast by the remote party.
+	BreachTxid chainhash.Hash
+
+	// RemoteIdentity is the identity public key of the breaching party.
+	RemoteIdentity [33]byte
+
+	// Capacity is the capacity of the breached channel.
+	Capacity btcutil.Amount
+
+	// SettledBalance is our settled balance within the channel at the time
+	// the breach was detected.
+	SettledBalance btcutil.Amount
+
+	// BreachDetectedAt is the time at which the breach was first detected,
+	// or zero if it wasn't recorded.
+	BreachDetectedAt time.Time
+
+	// SweepPkScript is the output script paid to by the most recently
+	// crafted justice transaction, if any.
+	SweepPkScript []byte
+
+	// ExpectedJusticeFee is the fee the justice transaction was expected
+	// to pay at the time the breach was detected, or zero if it wasn't
+	// recorded.
+	ExpectedJusticeFee btcutil.Amount
+
+	// ProjectedRecovery is the total value of the breached outputs, net
+	// of the ExpectedJusticeFee.
+	ProjectedRecovery btcutil.Amount
+
+	// DeadlineHeight is the height by which the justice transaction must
+	// confirm, before the breaching party may claim any of the breached
+	// outputs. It's zero until the breach transaction confirms, or if
+	// none of the outputs may be claimed by them.
+	DeadlineHeight uint32
+
+	// Outputs describes each of the outputs of the breach transaction we
+	// intend to sweep.
+	Outputs []BreachedOutputDiagnostic
+
+	// Active is true if the retribution is currently being carried out.
+	// The phase, broadcast attempts and last error are only populated for
+	// an active retribution.
+	Active bool
+
+	// Phase is the phase the retribution is currently in.
+	Phase string
+
+	// PhaseSince is the time at which the retribution entered its current
+	// phase.
+	PhaseSince time.Time
+
+	// BroadcastAttempts is the number of times a justice transaction has
+	// been broadcast for the retribution, including fee bumps.
+	BroadcastAttempts uint32
+
+	// LastError is the error returned by the most recent failed broadcast
+	// of a justice transaction, if any.
+	LastError string
+
+	// Unverified is true if the breach could not be corroborated by the
+	// chain during startup, and awaits inspection by the operator.
+	Unverified bool
+}
+
+// BreachedOutputView is a read-only view of a single output of a breach
+// transaction. Unlike the breachedOutput it's derived from, it carries no
+// signing material or witness generator, so it may be safely returned over
+// RPC.
+type BreachedOutputView struct {
+	// OutPoint is the outpoint of the breached output.
+	OutPoint wire.OutPoint
+
+	// Amount is the value of the breached output.
+	Amount btcutil.Amount
+
+	// WitnessType is the name of the type of witness used to sweep the
+	// output.
+	WitnessType string
+
+	// TwoStageClaim is true if the output is claimed in two stages, i.e.
+	// via a second-level HTLC transaction.
+	TwoStageClaim bool
+
+	// Spent is true if the output wasn't found within the UTXO set when
+	// the view was assembled.
+	Spent bool
+}
+
+// newBreachedOutputView returns the view of the given breached output. Its
+// spent status is left for the caller to populate.
+func newBreachedOutputView(bo *breachedOutput) BreachedOutputView {
+	return BreachedOutputView{
+		OutPoint:      bo.outpoint,
+		Amount:        bo.amt,
+		WitnessType:   bo.witnessType.String(),
+		TwoStageClaim: bo.twoStageClaim,
+	}
+}
+
+// viewBreachedOutput returns the view of the given breached output, querying
+// the chain for its spent status. Should the query fail, the output is
+// reported as spent, and the error returned alongside the view.
+func (b *breachArbiter) viewBreachedOutput(
+	bo *breachedOutput) (BreachedOutputView, error) {
+
+	view := newBreachedOutputView(bo)
+
+	txOut, err := b.cfg.ChainIO.GetUtxo(&bo.outpoint, 0)
+	view.Spent = err != nil || txOut == nil
+
+	return view, err
+}
+
+// BreachedOutputDiagnostic describes a single output of a breach transaction
+// as part of a RetributionDiagnostic.
+type BreachedOutputDiagnostic struct {
+	BreachedOutputView
+
+	// HasPreimage is true if the payment preimage required to sweep the
+	// output is known. The preimage itself is never reported.
+	HasPreimage bool
+
+	// LookupError is the error encountered while querying the chain for
+	// the output, if any.
+	LookupError string
+}
+
+// DumpRetribution assembles a diagnostic report of the persisted retribution
+// for the given channel point, querying the chain for the current status of
+// each of its breached outputs.
+func (b *breachArbiter) DumpRetribution(
+	chanPoint *wire.OutPoint) (*RetributionDiagnostic, error) {
+
+	var ret *retributionInfo
+	err := b.cfg.Store.ForAll(func(r *retributionInfo) error {
+		if r.chanPoint == *chanPoint {
+			ret = r
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ret == nil {
+		return nil, fmt.Errorf("no retribution found for "+
+			"ChannelPoint(%v)", chanPoint)
+	}
+
+	return b.diagnoseRetribution(ret), nil
+}
+
+// PendingRetributions assembles a diagnostic report, as returned by
+// DumpRetribution, of each persisted retribution.
+func (b *breachArbiter) PendingRetributions() ([]*RetributionDiagnostic,
+	error) {
+
+	var diags []*RetributionDiagnostic
+	err := b.cfg.Store.ForAll(func(ret *retributionInfo) error {
+		diags = append(diags, b.diagnoseRetribution(ret))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return diags, nil
+}
+
+// diagnoseRetribution assembles a diagnostic report of the given retribution.
+func (b *breachArbiter) diagnoseRetribution(
+	ret *retributionInfo) *RetributionDiagnostic {
+
+	diag := &RetributionDiagnostic{
+		ChanPoint:        ret.chanPoint,
+		BreachTxid:       ret.commitHash,
+		Capacity:         ret.capacity,
+		SettledBalance:   ret.settledBalance,
+		BreachDetectedAt: ret.breachDetectedAt,
+		SweepPkScript:    ret.sweepPkScript,
+
+		ExpectedJusticeFee: ret.expectedJusticeFee,
+		ProjectedRecovery:  ret.projectedRecovery(),
+		DeadlineHeight:     ret.deadlineHeight,
+	}
+	if ret.hasRemoteIdentity() {
+		copy(
+			diag.RemoteIdentity[:],
+			ret.remoteIdentity.SerializeCompressed(),
+		)
+	}
+
+	for _, output := range ret.breachedOutputs() {
+		view, err := b.viewBreachedOutput(output)
+		outputDiag := BreachedOutputDiagnostic{
+			BreachedOutputView: view,
+			HasPreimage: witnessRequiresPreimage(output.witnessType) &&
+				output.preimage != [32]byte{},
+		}
+		if err != nil {
+			outputDiag.LookupError = err.Error()
+		}
+
+		diag.Outputs = append(diag.Outputs, outputDiag)
+	}
+
+	b.retMtx.Lock()
+	if status, ok := b.activeRetributions[ret.chanPoint]; ok {
+		diag.Active = true
+		diag.Phase = status.phase.String()
+		diag.PhaseSince = status.since
+		diag.BroadcastAttempts = status.broadcastAttempts
+		if status.lastErr != nil {
+			diag.LastError = status.lastErr.Error()
+		}
+	}
+	_, diag.Unverified = b.unverifiedRetributions[ret.chanPoint]
+	b.retMtx.Unlock()
+
+	return diag
+}
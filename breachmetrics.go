@@ -0,0 +1,374 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/roasbeef/btcutil"
+)
+
+// LatencySummary aggregates a series of observed durations.
+type LatencySummary struct {
+	// Count is the number of durations observed.
+	Count uint64
+
+	// Total is the sum of all durations observed.
+	Total time.Duration
+
+	// Min is the shortest duration observed.
+	Min time.Duration
+
+	// Max is the longest duration observed.
+	Max time.Duration
+
+	// Last is the most recently observed duration.
+	Last time.Duration
+}
+
+// observe folds a new duration into the summary.
+func (l *LatencySummary) observe(d time.Duration) {
+	if l.Count == 0 || d < l.Min {
+		l.Min = d
+	}
+	if d > l.Max {
+		l.Max = d
+	}
+	l.Count++
+	l.Total += d
+	l.Last = d
+}
+
+// Mean returns the average of all durations observed, or zero if none have
+// been.
+func (l *LatencySummary) Mean() time.Duration {
+	if l.Count == 0 {
+		return 0
+	}
+
+	return l.Total / time.Duration(l.Count)
+}
+
+// BreachStats is a snapshot of the breach arbiter's time-to-justice
+// measurements.
+type BreachStats struct {
+	// BreachesDetected is the number of breaches for which a retribution
+	// has been launched.
+	BreachesDetected uint64
+
+	// JusticeServed is the number of retributions whose justice
+	// transaction has confirmed.
+	JusticeServed uint64
+
+	// PendingRetributions is the number of retributions currently
+	// underway.
+	PendingRetributions int
+
+	// FundsAtRisk is the total value at risk of the retributions
+	// currently underway.
+	FundsAtRisk btcutil.Amount
+
+	// DetectionToBroadcast summarizes the time between detecting a breach
+	// and broadcasting the justice transaction.
+	DetectionToBroadcast LatencySummary
+
+	// BroadcastToConfirmation summarizes the time between broadcasting the
+	// justice transaction and witnessing its confirmation.
+	BroadcastToConfirmation LatencySummary
+
+	// DetectionToConfirmation summarizes the end-to-end time between
+	// detecting a breach and the justice transaction confirming.
+	DetectionToConfirmation LatencySummary
+
+	// WitnessTypes tallies the outcomes of sweeping outputs, broken down
+	// by the witness type used to spend them.
+	WitnessTypes map[lnwallet.WitnessType]WitnessTypeStats
+
+	// BroadcastTargets tallies the outcomes of broadcasting justice
+	// transactions, broken down by the name of the target broadcast to,
+	// including the wallet.
+	BroadcastTargets map[string]BroadcastTargetStats
+}
+
+// BroadcastTargetStats tallies the outcomes of broadcasting justice
+// transactions to a single target.
+type BroadcastTargetStats struct {
+	// Accepted is the number of transactions accepted by the target.
+	Accepted uint64
+
+	// Rejected is the number of transactions the target failed to
+	// broadcast.
+	Rejected uint64
+
+	// LastErr is the error returned by the most recent failed broadcast,
+	// if any.
+	LastErr error
+}
+
+// WitnessTypeStats tallies the outcomes of sweeping outputs of a single
+// witness type.
+type WitnessTypeStats struct {
+	// WitnessesGenerated is the number of witnesses successfully
+	// generated.
+	WitnessesGenerated uint64
+
+	// WitnessFailures is the number of witnesses that failed to be
+	// generated.
+	WitnessFailures uint64
+
+	// BroadcastFailures is the number of outputs swept by a transaction
+	// that failed to be broadcast.
+	BroadcastFailures uint64
+}
+
+// Stats returns a snapshot of the breach arbiter's time-to-justice
+// measurements.
+func (b *breachArbiter) Stats() BreachStats {
+	pending, fundsAtRisk := b.retributionsAtRisk()
+
+	b.statsMtx.Lock()
+	defer b.statsMtx.Unlock()
+
+	stats := b.stats
+	stats.PendingRetributions = pending
+	stats.FundsAtRisk = fundsAtRisk
+	stats.WitnessTypes = make(
+		map[lnwallet.WitnessType]WitnessTypeStats,
+		len(b.stats.WitnessTypes),
+	)
+	for witnessType, witnessStats := range b.stats.WitnessTypes {
+		stats.WitnessTypes[witnessType] = witnessStats
+	}
+	stats.BroadcastTargets = make(
+		map[string]BroadcastTargetStats, len(b.stats.BroadcastTargets),
+	)
+	for name, targetStats := range b.stats.BroadcastTargets {
+		stats.BroadcastTargets[name] = targetStats
+	}
+
+	return stats
+}
+
+// retributionsAtRisk returns the number of retributions currently underway,
+// along with their total value at risk.
+func (b *breachArbiter) retributionsAtRisk() (int, btcutil.Amount) {
+	b.retMtx.Lock()
+	defer b.retMtx.Unlock()
+
+	var fundsAtRisk btcutil.Amount
+	for _, status := range b.activeRetributions {
+		fundsAtRisk += status.valueAtRisk
+	}
+
+	return len(b.activeRetributions), fundsAtRisk
+}
+
+// recordBreachDetected records the launch of a retribution for a newly
+// detected breach.
+func (b *breachArbiter) recordBreachDetected() {
+	b.statsMtx.Lock()
+	defer b.statsMtx.Unlock()
+
+	b.stats.BreachesDetected++
+}
+
+// recordTargetBroadcast records the outcome of broadcasting a transaction to
+// the named target.
+func (b *breachArbiter) recordTargetBroadcast(name string, err error) {
+	b.statsMtx.Lock()
+	defer b.statsMtx.Unlock()
+
+	if b.stats.BroadcastTargets == nil {
+		b.stats.BroadcastTargets = make(map[string]BroadcastTargetStats)
+	}
+
+	targetStats := b.stats.BroadcastTargets[name]
+	if err != nil {
+		targetStats.Rejected++
+		targetStats.LastErr = err
+	} else {
+		targetStats.Accepted++
+	}
+	b.stats.BroadcastTargets[name] = targetStats
+}
+
+// recordWitnessResult records the outcome of generating a witness of the given
+// type.
+func (b *breachArbiter) recordWitnessResult(witnessType lnwallet.WitnessType,
+	err error) {
+
+	b.statsMtx.Lock()
+	defer b.statsMtx.Unlock()
+
+	if b.stats.WitnessTypes == nil {
+		b.stats.WitnessTypes = make(
+			map[lnwallet.WitnessType]WitnessTypeStats,
+		)
+	}
+
+	witnessStats := b.stats.WitnessTypes[witnessType]
+	if err != nil {
+		witnessStats.WitnessFailures++
+	} else {
+		witnessStats.WitnessesGenerated++
+	}
+	b.stats.WitnessTypes[witnessType] = witnessStats
+}
+
+// recordBroadcastFailure records that a transaction sweeping the given outputs
+// failed to be broadcast.
+func (b *breachArbiter) recordBroadcastFailure(inputs []*breachedOutput) {
+	b.statsMtx.Lock()
+	defer b.statsMtx.Unlock()
+
+	if b.stats.WitnessTypes == nil {
+		b.stats.WitnessTypes = make(
+			map[lnwallet.WitnessType]WitnessTypeStats,
+		)
+	}
+
+	for _, input := range inputs {
+		witnessStats := b.stats.WitnessTypes[input.witnessType]
+		witnessStats.BroadcastFailures++
+		b.stats.WitnessTypes[input.witnessType] = witnessStats
+	}
+}
+
+// recordJusticeLatency records the latencies of a retribution whose justice
+// transaction has confirmed. If the detection time is unknown, as is the case
+// for retributions persisted by older versions, only the broadcast to
+// confirmation latency is recorded.
+func (b *breachArbiter) recordJusticeLatency(detectedAt, broadcastAt,
+	confirmedAt time.Time) {
+
+	b.statsMtx.Lock()
+	defer b.statsMtx.Unlock()
+
+	b.stats.JusticeServed++
+	b.stats.BroadcastToConfirmation.observe(confirmedAt.Sub(broadcastAt))
+
+	if detectedAt.IsZero() {
+		return
+	}
+
+	b.stats.DetectionToBroadcast.observe(broadcastAt.Sub(detectedAt))
+	b.stats.DetectionToConfirmation.observe(confirmedAt.Sub(detectedAt))
+
+	if b.observeTimeToJustice != nil {
+		b.observeTimeToJustice(confirmedAt.Sub(detectedAt).Seconds())
+	}
+}
+
+// MetricsRegistry registers the breach arbiter's metrics with a metrics
+// system. Its methods mirror the constructors of the Prometheus client's
+// collectors, such that a prometheus.Registerer is readily adapted to it.
+type MetricsRegistry interface {
+	// RegisterCounterFunc registers a counter whose value is read from
+	// value each time it's collected.
+	RegisterCounterFunc(name, help string, value func() float64) error
+
+	// RegisterGaugeFunc registers a gauge whose value is read from value
+	// each time it's collected.
+	RegisterGaugeFunc(name, help string, value func() float64) error
+
+	// RegisterHistogram registers a histogram with the given bucket upper
+	// bounds, returning the function via which observations are made.
+	RegisterHistogram(name, help string,
+		buckets []float64) (func(float64), error)
+}
+
+// timeToJusticeBuckets are the upper bounds, in seconds, of the buckets of the
+// time to justice histogram, ranging from a minute to a week.
+var timeToJusticeBuckets = []float64{
+	60, 600, 3600, 6 * 3600, 24 * 3600, 3 * 24 * 3600, 7 * 24 * 3600,
+}
+
+// registerMetrics registers the breach arbiter's metrics with the configured
+// MetricsRegistry, if any. The counters and gauges are read from Stats when
+// collected, such that they never drift from it.
+func (b *breachArbiter) registerMetrics() error {
+	registry := b.cfg.MetricsRegistry
+	if registry == nil {
+		return nil
+	}
+
+	counters := []struct {
+		name, help string
+		value      func(BreachStats) float64
+	}{
+		{
+			name: "lnd_breacharbiter_breaches_detected_total",
+			help: "Number of breaches detected.",
+			value: func(s BreachStats) float64 {
+				return float64(s.BreachesDetected)
+			},
+		},
+		{
+			name: "lnd_breacharbiter_justice_confirmed_total",
+			help: "Number of justice transactions confirmed.",
+			value: func(s BreachStats) float64 {
+				return float64(s.JusticeServed)
+			},
+		},
+	}
+	for _, c := range counters {
+		value := c.value
+		err := registry.RegisterCounterFunc(c.name, c.help,
+			func() float64 {
+				return value(b.Stats())
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("unable to register %v: %v", c.name,
+				err)
+		}
+	}
+
+	gauges := []struct {
+		name, help string
+		value      func(BreachStats) float64
+	}{
+		{
+			name: "lnd_breacharbiter_pending_retributions",
+			help: "Number of retributions underway.",
+			value: func(s BreachStats) float64 {
+				return float64(s.PendingRetributions)
+			},
+		},
+		{
+			name: "lnd_breacharbiter_funds_at_risk_sat",
+			help: "Value at risk of the retributions underway.",
+			value: func(s BreachStats) float64 {
+				return float64(s.FundsAtRisk)
+			},
+		},
+	}
+	for _, g := range gauges {
+		value := g.value
+		err := registry.RegisterGaugeFunc(g.name, g.help,
+			func() float64 {
+				return value(b.Stats())
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("unable to register %v: %v", g.name,
+				err)
+		}
+	}
+
+	observe, err := registry.RegisterHistogram(
+		"lnd_breacharbiter_time_to_justice_seconds",
+		"Time between detecting a breach and its justice "+
+			"transaction confirming.",
+		timeToJusticeBuckets,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to register time to justice "+
+			"histogram: %v", err)
+	}
+	b.statsMtx.Lock()
+	b.observeTimeToJustice = observe
+	b.statsMtx.Unlock()
+
+	return nil
+}
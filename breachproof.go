@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/roasbeef/btcd/btcec"
+	"github.com/roasbeef/btcd/txscript"
+	"github.com/roasbeef/btcd/wire"
+)
+
+// BreachProof returns the proof that the remote party of the given channel
+// broadcast a revoked state, for use in dispute resolution. Both pending and
+// archived breaches are searched. ErrNoBreachProof is returned if the breach
+// is known, but no proof was recorded for it.
+func (b *breachArbiter) BreachProof(
+	chanPoint *wire.OutPoint) (*BreachProof, error) {
+
+	var (
+		proof *BreachProof
+		found bool
+	)
+	err := b.cfg.Store.ForAll(func(ret *retributionInfo) error {
+		if ret.chanPoint == *chanPoint {
+			proof, found = ret.breachProof, true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		err = b.cfg.Store.ForAllArchived(func(a *ArchivedBreach) error {
+			if a.ChanPoint == *chanPoint {
+				proof, found = a.Proof, true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case !found:
+		return nil, fmt.Errorf("no breach found for "+
+			"ChannelPoint(%v)", chanPoint)
+	case proof == nil:
+		return nil, ErrNoBreachProof
+	}
+
+	return proof, nil
+}
+
+// BreachProof proves that the remote party of a channel broadcast a revoked
+// commitment transaction. Upon revoking each state, the remote party reveals
+// its commitment secret, which is combined with our revocation base point to
+// derive the revocation key of the remote party's output within the revoked
+// commitment. That the breach transaction spends the channel's funding output
+// and pays to a script locked to this revocation key thus demonstrates that
+// the remote party broadcast a state they had already revoked. The proof can
+// be verified independently of the breach arbiter's state, see Verify.
+type BreachProof struct {
+	// ChanPoint is the funding outpoint of the breached channel.
+	ChanPoint wire.OutPoint
+
+	// RevokedStateNum is the number of the revoked state broadcast by the
+	// remote party, as recorded by us. It's not covered by Verify, as
+	// confirming it requires the channel's state hint obfuscator.
+	RevokedStateNum uint64
+
+	// BreachTx is the revoked commitment transaction broadcast by the
+	// remote party.
+	BreachTx *wire.MsgTx
+
+	// OutputIndex is the index of the remote party's revoked output within
+	// BreachTx.
+	OutputIndex uint32
+
+	// RevocationBasePoint is our revocation base point within the channel.
+	RevocationBasePoint *btcec.PublicKey
+
+	// CommitSecret is the commitment secret the remote party revealed upon
+	// revoking the broadcast state.
+	CommitSecret [32]byte
+
+	// WitnessScript is the script of the remote party's revoked output,
+	// which may be spent by the revocation key.
+	WitnessScript []byte
+}
+
+// newBreachProof assembles the proof of the given breach of the channel, or
+// returns nil if the breach retribution lacks the material required.
+func newBreachProof(chanPoint *wire.OutPoint,
+	breachInfo *lnwallet.BreachRetribution) *BreachProof {
+
+	signDesc := &breachInfo.RemoteOutputSignDesc
+	if breachInfo.BreachTransaction == nil || signDesc.PubKey == nil ||
+		signDesc.DoubleTweak == nil || len(signDesc.WitnessScript) == 0 {
+
+		return nil
+	}
+
+	proof := &BreachProof{
+		ChanPoint:           *chanPoint,
+		RevokedStateNum:     breachInfo.RevokedStateNum,
+		BreachTx:            breachInfo.BreachTransaction,
+		OutputIndex:         breachInfo.RemoteOutpoint.Index,
+		RevocationBasePoint: signDesc.PubKey,
+		WitnessScript:       signDesc.WitnessScript,
+	}
+	secret := signDesc.DoubleTweak.Serialize()
+	copy(proof.CommitSecret[32-len(secret):], secret)
+
+	return proof
+}
+
+// Verify checks that the proof demonstrates the breach it claims, returning an
+// error describing the first discrepancy found, if any.
+func (p *BreachProof) Verify() error {
+	if p.BreachTx == nil || p.RevocationBasePoint == nil {
+		return errors.New("incomplete breach proof")
+	}
+
+	// The breach transaction must be a commitment of the channel, spending
+	// its funding output.
+	var spendsFunding bool
+	for _, txIn := range p.BreachTx.TxIn {
+		if txIn.PreviousOutPoint == p.ChanPoint {
+			spendsFunding = true
+			break
+		}
+	}
+	if !spendsFunding {
+		return fmt.Errorf("breach tx %v doesn't spend "+
+			"ChannelPoint(%v)", p.BreachTx.TxHash(), p.ChanPoint)
+	}
+
+	if int(p.OutputIndex) >= len(p.BreachTx.TxOut) {
+		return fmt.Errorf("breach tx %v has no output %v",
+			p.BreachTx.TxHash(), p.OutputIndex)
+	}
+	scriptHash := sha256.Sum256(p.WitnessScript)
+	pkScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_0).
+		AddData(scriptHash[:]).Script()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(p.BreachTx.TxOut[p.OutputIndex].PkScript, pkScript) {
+		return fmt.Errorf("output %v of breach tx %v doesn't pay to "+
+			"the witness script", p.OutputIndex,
+			p.BreachTx.TxHash())
+	}
+
+	// Finally, the revocation key derived from the revealed commitment
+	// secret must be able to spend the output.
+	commitPoint := lnwallet.ComputeCommitmentPoint(p.CommitSecret[:])
+	revocationKey := lnwallet.DeriveRevocationPubkey(
+		p.RevocationBasePoint, commitPoint,
+	)
+	revocationKeyBytes := revocationKey.SerializeCompressed()
+	if !bytes.Contains(p.WitnessScript, revocationKeyBytes) {
+		return errors.New("witness script isn't locked to the " +
+			"revocation key of the commitment secret")
+	}
+
+	return nil
+}
+
+// Encode serializes the breach proof into the passed byte stream.
+func (p *BreachProof) Encode(w io.Writer) error {
+	var scratch [8]byte
+
+	if err := writeOutpoint(w, &p.ChanPoint); err != nil {
+		return err
+	}
+
+	binary.BigEndian.PutUint64(scratch[:], p.RevokedStateNum)
+	if _, err := w.Write(scratch[:]); err != nil {
+		return err
+	}
+
+	if err := p.BreachTx.Serialize(w); err != nil {
+		return err
+	}
+
+	binary.BigEndian.PutUint32(scratch[:4], p.OutputIndex)
+	if _, err := w.Write(scratch[:4]); err != nil {
+		return err
+	}
+
+	basePoint := p.RevocationBasePoint.SerializeCompressed()
+	if _, err := w.Write(basePoint); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(p.CommitSecret[:]); err != nil {
+		return err
+	}
+
+	return wire.WriteVarBytes(w, 0, p.WitnessScript)
+}
+
+// Decode deserializes a breach proof from the passed byte stream.
+func (p *BreachProof) Decode(r io.Reader) error {
+	var scratch [33]byte
+
+	if err := readOutpoint(r, &p.ChanPoint); err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(r, scratch[:8]); err != nil {
+		return err
+	}
+	p.RevokedStateNum = binary.BigEndian.Uint64(scratch[:8])
+
+	p.BreachTx = &wire.MsgTx{}
+	if err := p.BreachTx.Deserialize(r); err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(r, scratch[:4]); err != nil {
+		return err
+	}
+	p.OutputIndex = binary.BigEndian.Uint32(scratch[:4])
+
+	if _, err := io.ReadFull(r, scratch[:33]); err != nil {
+		return err
+	}
+	basePoint, err := btcec.ParsePubKey(scratch[:33], btcec.S256())
+	if err != nil {
+		return err
+	}
+	p.RevocationBasePoint = basePoint
+
+	if _, err := io.ReadFull(r, p.CommitSecret[:]); err != nil {
+		return err
+	}
+
+	p.WitnessScript, err = wire.ReadVarBytes(
+		r, 0, txscript.MaxScriptSize, "witnessScript",
+	)
+	return err
+}
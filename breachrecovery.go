@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/shachain"
+	"github.com/roasbeef/btcd/btcec"
+	"github.com/roasbeef/btcd/chaincfg/chainhash"
+	"github.com/roasbeef/btcd/wire"
+	"github.com/roasbeef/btcutil"
+)
+
+// RecoverBreachFromTx reconstructs the retribution for a breach of a channel
+// from its static backup and the revoked commitment transaction broadcast by
+// the remote party, as observed on chain. This allows funds to be recovered by
+// disaster recovery tooling, even if the channel's state has been lost. The
+// retribution is persisted and handed off to be carried out like any other,
+// and is returned to the caller for inspection.
+func (b *breachArbiter) RecoverBreachFromTx(breachTx *wire.MsgTx,
+	backup *channeldb.ChannelBackup) (*retributionInfo, error) {
+
+	breachInfo, err := lnwallet.NewBreachRetributionFromBackup(
+		backup, breachTx,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	chanPoint := &backup.FundingOutpoint
+
+	b.retMtx.Lock()
+	_, active := b.activeRetributions[*chanPoint]
+	b.retMtx.Unlock()
+	if active {
+		return nil, fmt.Errorf("retribution for ChannelPoint(%v) is "+
+			"already in progress", chanPoint)
+	}
+
+	// A breach that has already been punished mustn't be resurrected,
+	// e.g. by replaying an old breach transaction.
+	resolved, err := b.breachResolved(chanPoint)
+	if err != nil {
+		return nil, err
+	}
+	if resolved {
+		brarLog.Warnf("Refusing to recover breach of ChannelPoint(%v) "+
+			"by txid %v, as it has already been resolved",
+			chanPoint, breachTx.TxHash())
+		return nil, ErrBreachAlreadyResolved
+	}
+
+	// The balance we had settled within the revoked state isn't part of
+	// the backup, so we'll use the value of our output instead.
+	retInfo := b.newRetributionInfo(
+		chanPoint, breachInfo, *backup.IdentityPub, backup.Capacity,
+		btcutil.Amount(breachInfo.LocalOutputSignDesc.Output.Value),
+	)
+	if err := retInfo.checkSweepable(); err != nil {
+		return nil, err
+	}
+
+	brarLog.Warnf("Recovering breach of ChannelPoint(%v) at revoked "+
+		"state #%v by txid %v", chanPoint, breachInfo.RevokedStateNum,
+		retInfo.commitHash)
+
+	if err := b.cfg.Store.Add(retInfo, b.quit); err != nil {
+		return nil, err
+	}
+
+	select {
+	case b.breachedContracts <- retInfo:
+	case <-b.quit:
+		return nil, errors.New("breach arbiter shutting down")
+	}
+
+	return retInfo, nil
+}
+
+// breachResolved returns true if the channel with the given channel point has
+// already been fully closed, or its breach archived. Either way, the funding
+// output has been spent for good, so no breach of the channel can be pursued.
+func (b *breachArbiter) breachResolved(chanPoint *wire.OutPoint) (bool, error) {
+	var archived bool
+	err := b.cfg.Store.ForAllArchived(func(a *ArchivedBreach) error {
+		if a.ChanPoint == *chanPoint {
+			archived = true
+		}
+		return nil
+	})
+	if err != nil || archived {
+		return archived, err
+	}
+
+	closedChans, err := b.cfg.DB.FetchClosedChannels(false)
+	if err != nil {
+		return false, err
+	}
+	for _, closed := range closedChans {
+		if closed.ChanPoint == *chanPoint && !closed.IsPending {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// BreachRemedyKit holds the static data of a channel required to punish a
+// breach of any of its states, which may be exported as soon as the channel is
+// opened and stored offline. It holds no secrets: the keys needed to sign the
+// justice transaction are identified by their base points, from which the
+// sign descriptors of the breached outputs are derived, while the revocation
+// secret of the breached state must be supplied upon recovery.
+type BreachRemedyKit struct {
+	// ChainHash is the identifier of the chain the channel was opened
+	// within.
+	ChainHash chainhash.Hash
+
+	// FundingOutpoint is the outpoint of the funding transaction, which
+	// every commitment transaction spends.
+	FundingOutpoint wire.OutPoint
+
+	// IsInitiator is true if we initiated the channel, which determines
+	// the obfuscator of the state hints within commitment transactions.
+	IsInitiator bool
+
+	// IdentityPub is the identity public key of the remote node.
+	IdentityPub *btcec.PublicKey
+
+	// Capacity is the total capacity of the channel.
+	Capacity btcutil.Amount
+
+	// LocalPaymentBasePoint is the base point of the key paid to by our
+	// output within the remote party's commitment transactions.
+	LocalPaymentBasePoint *btcec.PublicKey
+
+	// LocalRevocationBasePoint is the base point of the revocation key
+	// which allows us to claim the remote party's revoked output.
+	LocalRevocationBasePoint *btcec.PublicKey
+
+	// RemotePaymentBasePoint is the remote party's payment base point,
+	// which obfuscates the state hints along with our own.
+	RemotePaymentBasePoint *btcec.PublicKey
+
+	// RemoteDelayBasePoint is the base point of the key paid to by the
+	// remote party's delayed output within their commitment transactions.
+	RemoteDelayBasePoint *btcec.PublicKey
+
+	// RemoteCsvDelay is the relative timelock of the remote party's
+	// delayed output.
+	RemoteCsvDelay uint16
+}
+
+// NewBreachRemedyKit assembles the breach remedy kit of the given channel from
+// its static configuration.
+func NewBreachRemedyKit(channel *channeldb.OpenChannel) *BreachRemedyKit {
+	channel.RLock()
+	defer channel.RUnlock()
+
+	return &BreachRemedyKit{
+		ChainHash:                channel.ChainHash,
+		FundingOutpoint:          channel.FundingOutpoint,
+		IsInitiator:              channel.IsInitiator,
+		IdentityPub:              channel.IdentityPub,
+		Capacity:                 channel.Capacity,
+		LocalPaymentBasePoint:    channel.LocalChanCfg.PaymentBasePoint,
+		LocalRevocationBasePoint: channel.LocalChanCfg.RevocationBasePoint,
+		RemotePaymentBasePoint:   channel.RemoteChanCfg.PaymentBasePoint,
+		RemoteDelayBasePoint:     channel.RemoteChanCfg.DelayBasePoint,
+		RemoteCsvDelay:           channel.RemoteChanCfg.CsvDelay,
+	}
+}
+
+// ExportBreachRemedyKit returns the breach remedy kit of the open channel with
+// the given channel point.
+func (b *breachArbiter) ExportBreachRemedyKit(
+	chanPoint *wire.OutPoint) (*BreachRemedyKit, error) {
+
+	channels, err := b.cfg.DB.FetchAllChannels()
+	if err != nil && err != channeldb.ErrNoActiveChannels {
+		return nil, err
+	}
+
+	for _, channel := range channels {
+		if channel.FundingOutpoint == *chanPoint {
+			return NewBreachRemedyKit(channel), nil
+		}
+	}
+
+	return nil, fmt.Errorf("no open channel with ChannelPoint(%v)",
+		chanPoint)
+}
+
+// Backup combines the kit with the given revocation secrets of the remote
+// party's revoked states, yielding a channel backup from which the breach of
+// any of those states can be recovered.
+func (k *BreachRemedyKit) Backup(
+	revocations shachain.Store) *channeldb.ChannelBackup {
+
+	return &channeldb.ChannelBackup{
+		ChainHash:       k.ChainHash,
+		FundingOutpoint: k.FundingOutpoint,
+		IsInitiator:     k.IsInitiator,
+		IdentityPub:     k.IdentityPub,
+		Capacity:        k.Capacity,
+		LocalChanCfg: channeldb.ChannelConfig{
+			PaymentBasePoint:    k.LocalPaymentBasePoint,
+			RevocationBasePoint: k.LocalRevocationBasePoint,
+		},
+		RemoteChanCfg: channeldb.ChannelConfig{
+			CsvDelay:         k.RemoteCsvDelay,
+			PaymentBasePoint: k.RemotePaymentBasePoint,
+			DelayBasePoint:   k.RemoteDelayBasePoint,
+		},
+		RevocationStore: revocations,
+	}
+}
+
+// RecoverBreachFromKit recovers the breach of a channel from its breach remedy
+// kit and the given revocation secrets, see RecoverBreachFromTx.
+func (b *breachArbiter) RecoverBreachFromKit(breachTx *wire.MsgTx,
+	kit *BreachRemedyKit,
+	revocations shachain.Store) (*retributionInfo, error) {
+
+	return b.RecoverBreachFromTx(breachTx, kit.Backup(revocations))
+}
+
+// Encode serializes the breach remedy kit into the passed byte stream.
+func (k *BreachRemedyKit) Encode(w io.Writer) error {
+	var scratch [8]byte
+
+	if _, err := w.Write(k.ChainHash[:]); err != nil {
+		return err
+	}
+
+	if err := writeOutpoint(w, &k.FundingOutpoint); err != nil {
+		return err
+	}
+
+	scratch[0] = 0
+	if k.IsInitiator {
+		scratch[0] = 1
+	}
+	if _, err := w.Write(scratch[:1]); err != nil {
+		return err
+	}
+
+	binary.BigEndian.PutUint64(scratch[:8], uint64(k.Capacity))
+	if _, err := w.Write(scratch[:8]); err != nil {
+		return err
+	}
+
+	keys := []*btcec.PublicKey{
+		k.IdentityPub, k.LocalPaymentBasePoint,
+		k.LocalRevocationBasePoint, k.RemotePaymentBasePoint,
+		k.RemoteDelayBasePoint,
+	}
+	for _, key := range keys {
+		if _, err := w.Write(key.SerializeCompressed()); err != nil {
+			return err
+		}
+	}
+
+	binary.BigEndian.PutUint16(scratch[:2], k.RemoteCsvDelay)
+	_, err := w.Write(scratch[:2])
+	return err
+}
+
+// Decode deserializes a breach remedy kit from the passed byte stream.
+func (k *BreachRemedyKit) Decode(r io.Reader) error {
+	var scratch [33]byte
+
+	if _, err := io.ReadFull(r, k.ChainHash[:]); err != nil {
+		return err
+	}
+
+	if err := readOutpoint(r, &k.FundingOutpoint); err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(r, scratch[:1]); err != nil {
+		return err
+	}
+	k.IsInitiator = scratch[0] == 1
+
+	if _, err := io.ReadFull(r, scratch[:8]); err != nil {
+		return err
+	}
+	k.Capacity = btcutil.Amount(binary.BigEndian.Uint64(scratch[:8]))
+
+	keys := []**btcec.PublicKey{
+		&k.IdentityPub, &k.LocalPaymentBasePoint,
+		&k.LocalRevocationBasePoint, &k.RemotePaymentBasePoint,
+		&k.RemoteDelayBasePoint,
+	}
+	for _, key := range keys {
+		if _, err := io.ReadFull(r, scratch[:33]); err != nil {
+			return err
+		}
+
+		var err error
+		*key, err = btcec.ParsePubKey(scratch[:33], btcec.S256())
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.ReadFull(r, scratch[:2]); err != nil {
+		return err
+	}
+	k.RemoteCsvDelay = binary.BigEndian.Uint16(scratch[:2])
+
+	return nil
+}
@@ -20,8 +20,11 @@ import (
 	"github.com/lightningnetwork/lnd/lnwallet/btcwallet"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/routing/chainview"
+	"github.com/roasbeef/btcd/blockchain"
 	"github.com/roasbeef/btcd/chaincfg/chainhash"
 	"github.com/roasbeef/btcd/rpcclient"
+	"github.com/roasbeef/btcd/wire"
+	"github.com/roasbeef/btcutil"
 	"github.com/roasbeef/btcwallet/chain"
 	"github.com/roasbeef/btcwallet/walletdb"
 )
@@ -96,6 +99,16 @@ type chainControl struct {
 	wallet *lnwallet.LightningWallet
 
 	routingPolicy htlcswitch.ForwardingPolicy
+
+	// txInMempool reports whether a transaction is within the mempool of
+	// the chain backend. It's nil for backends lacking a mempool, such as
+	// neutrino.
+	txInMempool func(txid *chainhash.Hash) (bool, error)
+
+	// mempoolSpends returns the transactions within the mempool of the
+	// chain backend spending any of the given outpoints. Like txInMempool,
+	// it's nil for backends lacking a mempool.
+	mempoolSpends func(ops []wire.OutPoint) ([]MempoolSpend, error)
 }
 
 // newChainControlFromConfig attempts to create a chainControl instance
@@ -262,6 +275,71 @@ func newChainControlFromConfig(cfg *config, chanDB *channeldb.DB) (*chainControl
 		}
 
 		walletConfig.ChainSource = chainRPC
+
+		cc.txInMempool = func(txid *chainhash.Hash) (bool, error) {
+			mempool, err := chainRPC.GetRawMempool()
+			if err != nil {
+				return false, err
+			}
+
+			for _, memTxid := range mempool {
+				if *memTxid == *txid {
+					return true, nil
+				}
+			}
+
+			return false, nil
+		}
+
+		cc.mempoolSpends = func(ops []wire.OutPoint) ([]MempoolSpend,
+			error) {
+
+			watched := make(map[wire.OutPoint]struct{}, len(ops))
+			for _, op := range ops {
+				watched[op] = struct{}{}
+			}
+
+			mempool, err := chainRPC.GetRawMempoolVerbose()
+			if err != nil {
+				return nil, err
+			}
+
+			var spends []MempoolSpend
+			for txidStr, entry := range mempool {
+				txid, err := chainhash.NewHashFromStr(txidStr)
+				if err != nil {
+					return nil, err
+				}
+				tx, err := chainRPC.GetRawTransaction(txid)
+				if err != nil {
+					// The transaction may have left the
+					// mempool in the meantime.
+					continue
+				}
+
+				for _, txIn := range tx.MsgTx().TxIn {
+					_, ok := watched[txIn.PreviousOutPoint]
+					if !ok {
+						continue
+					}
+
+					fee, err := btcutil.NewAmount(entry.Fee)
+					if err != nil {
+						return nil, err
+					}
+					spends = append(spends, MempoolSpend{
+						Txid: *txid,
+						Fee:  fee,
+						Weight: blockchain.GetTransactionWeight(
+							tx,
+						),
+					})
+					break
+				}
+			}
+
+			return spends, nil
+		}
 	}
 
 	wc, err := btcwallet.New(*walletConfig)
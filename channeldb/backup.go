@@ -0,0 +1,61 @@
+package channeldb
+
+import (
+	"github.com/lightningnetwork/lnd/shachain"
+	"github.com/roasbeef/btcd/btcec"
+	"github.com/roasbeef/btcd/chaincfg/chainhash"
+	"github.com/roasbeef/btcd/wire"
+	"github.com/roasbeef/btcutil"
+)
+
+// ChannelBackup is the static subset of a channel's state which suffices to
+// recognize a revoked commitment transaction broadcast by the remote party,
+// and to reconstruct the material required to sweep its outputs. Unlike the
+// full channel state, it doesn't change as the channel is updated, with the
+// exception of the revocation store, which grows with each revoked state.
+type ChannelBackup struct {
+	// ChainHash is the identifier of the chain the channel was opened
+	// within.
+	ChainHash chainhash.Hash
+
+	// FundingOutpoint is the outpoint of the funding transaction, which
+	// every commitment transaction spends.
+	FundingOutpoint wire.OutPoint
+
+	// IsInitiator is true if we initiated the channel, which determines
+	// the obfuscator of the state hints within commitment transactions.
+	IsInitiator bool
+
+	// IdentityPub is the identity public key of the remote node.
+	IdentityPub *btcec.PublicKey
+
+	// Capacity is the total capacity of the channel.
+	Capacity btcutil.Amount
+
+	// LocalChanCfg is the channel configuration for the local node.
+	LocalChanCfg ChannelConfig
+
+	// RemoteChanCfg is the channel configuration for the remote node.
+	RemoteChanCfg ChannelConfig
+
+	// RevocationStore holds the revocation secrets of the remote party's
+	// revoked commitment states.
+	RevocationStore shachain.Store
+}
+
+// Backup returns a ChannelBackup of the channel's current state.
+func (c *OpenChannel) Backup() *ChannelBackup {
+	c.RLock()
+	defer c.RUnlock()
+
+	return &ChannelBackup{
+		ChainHash:       c.ChainHash,
+		FundingOutpoint: c.FundingOutpoint,
+		IsInitiator:     c.IsInitiator,
+		IdentityPub:     c.IdentityPub,
+		Capacity:        c.Capacity,
+		LocalChanCfg:    c.LocalChanCfg,
+		RemoteChanCfg:   c.RemoteChanCfg,
+		RevocationStore: c.RevocationStore,
+	}
+}
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/roasbeef/btcd/chaincfg/chainhash"
+	"github.com/roasbeef/btcd/txscript"
+	"github.com/roasbeef/btcd/wire"
+	"github.com/roasbeef/btcutil"
+)
+
+// commitSweepInputs returns the set of outputs within the remote party's
+// commitment transaction that we're able to sweep back into our wallet after a
+// unilateral close. Only our own output, which is never encumbered by a
+// relative timelock, is swept, so the inputs are spendable as soon as the
+// commitment transaction confirms.
+//
+// TODO(roasbeef): include outgoing HTLCs once they can be swept directly from
+// the remote commitment
+func commitSweepInputs(
+	closeInfo *lnwallet.UnilateralCloseSummary) []*breachedOutput {
+
+	var inputs []*breachedOutput
+	if closeInfo.SelfOutPoint != nil {
+		signDesc := *closeInfo.SelfOutputSignDesc
+		inputs = append(inputs, &breachedOutput{
+			amt:            btcutil.Amount(signDesc.Output.Value),
+			outpoint:       *closeInfo.SelfOutPoint,
+			signDescriptor: signDesc,
+			witnessType:    lnwallet.CommitmentNoDelay,
+		})
+	}
+
+	return inputs
+}
+
+// commitSweepInfo tracks the sweep of our outputs from the remote party's
+// commitment transaction after a unilateral close. It is persisted within the
+// commitSweepStore until the sweep has confirmed and the channel has been
+// marked as fully closed.
+type commitSweepInfo struct {
+	// chanPoint is the channel point of the closed channel.
+	chanPoint wire.OutPoint
+
+	// closeTxid is the txid of the commitment transaction that closed the
+	// channel.
+	closeTxid chainhash.Hash
+
+	// closeHeight is the height at which the close was detected, which is
+	// used as the height hint when awaiting confirmations.
+	closeHeight uint32
+
+	// inputs are the outputs of the commitment transaction that pay to
+	// us, which may be empty if our balance was trimmed.
+	inputs []*breachedOutput
+
+	// sweepTx is the transaction sweeping the inputs. It is nil until the
+	// sweep has been crafted, and is persisted before being broadcast, so
+	// that the very same transaction is rebroadcast after a restart. If
+	// the sweep was batched, the transaction sweeps the inputs of the
+	// other sweeps within the batch as well.
+	sweepTx *wire.MsgTx
+
+	// sweptAmt is the value swept back into the wallet on behalf of this
+	// sweep, which is its share of the output of a batched sweepTx. If
+	// zero, e.g. for sweeps persisted by an older version, the entire
+	// output of sweepTx is attributed to this sweep.
+	sweptAmt btcutil.Amount
+}
+
+// beginCommitSweep persists the given sweep and launches a goroutine tracked by
+// the breach arbiter's wait group to carry it out.
+func (b *breachArbiter) beginCommitSweep(sweep *commitSweepInfo) {
+	if err := b.commitSweeps.Add(sweep); err != nil {
+		brarLog.Errorf("unable to persist sweep of ChannelPoint(%v), "+
+			"it won't be resumed after a restart: %v",
+			sweep.chanPoint, err)
+	}
+
+	b.wg.Add(1)
+	go b.resolveCommitSweep(sweep)
+}
+
+// resolveCommitSweep carries out the sweep of our outputs from the remote
+// party's commitment transaction after a unilateral close, once the
+// commitment transaction has confirmed. Only once the sweep has reached
+// CommitSweepConfDepth confirmations, and the commitment transaction
+// UnilateralCloseSafetyDepth confirmations, is the channel marked as fully
+// closed and the sweep removed from the store. Should the breach arbiter shut
+// down beforehand, the sweep is resumed from its persisted state upon restart.
+//
+// NOTE: This MUST be run as a goroutine.
+func (b *breachArbiter) resolveCommitSweep(sweep *commitSweepInfo) {
+	defer b.wg.Done()
+
+	_, ok := b.waitForConf(&sweep.closeTxid, 1, sweep.closeHeight)
+	if !ok {
+		return
+	}
+
+	// Should our outputs be left to an external sweeper, nothing is swept
+	// back into the wallet by us. A sweep crafted before a restart may
+	// have been broadcast though, so it must be seen through regardless.
+	var (
+		recovered  btcutil.Amount
+		confHeight uint32
+	)
+	if b.deferCommitSweep(sweep) {
+		if !b.handOffCommitSweep(sweep) {
+			return
+		}
+	} else {
+		recovered, confHeight, ok = b.sweepCommitInputs(sweep)
+		if !ok {
+			return
+		}
+	}
+
+	// The channel remains pending close until its closure is unlikely to
+	// be reorged out.
+	if b.cfg.UnilateralCloseSafetyDepth > 1 {
+		_, ok = b.waitForCloseConf(
+			&sweep.closeTxid, b.cfg.UnilateralCloseSafetyDepth,
+			sweep.closeHeight,
+		)
+		if !ok {
+			return
+		}
+	}
+
+	brarLog.Infof("Force closed ChannelPoint(%v) is fully closed, "+
+		"recovered %v, updating DB", sweep.chanPoint, recovered)
+
+	if err := b.markChanFullyClosed(&sweep.chanPoint); err != nil {
+		brarLog.Errorf("unable to mark chan as closed: %v", err)
+		return
+	}
+	if err := b.commitSweeps.Remove(&sweep.chanPoint); err != nil {
+		brarLog.Errorf("unable to remove sweep of ChannelPoint(%v): "+
+			"%v", sweep.chanPoint, err)
+	}
+
+	b.notifyChannelResolved(sweep.chanPoint, recovered, confHeight, nil)
+	b.pruneChannelBackup(sweep.chanPoint)
+}
+
+// deferCommitSweep returns true if the outputs of the given commitment sweep
+// are to be handed off to the ExternalSweeper rather than swept by us.
+func (b *breachArbiter) deferCommitSweep(sweep *commitSweepInfo) bool {
+	return b.cfg.DeferCommitSweeps && b.cfg.ExternalSweeper != nil &&
+		sweep.sweepTx == nil
+}
+
+// handOffCommitSweep hands each of the outputs of the given commitment sweep
+// off to the ExternalSweeper, retrying each failed handoff until it succeeds.
+// It returns false if the breach arbiter shut down beforehand, in which case
+// the handoff is resumed from the persisted sweep upon restart.
+func (b *breachArbiter) handOffCommitSweep(sweep *commitSweepInfo) bool {
+	for _, input := range sweep.inputs {
+		output := &DeferredOutput{
+			ChanPoint:   sweep.chanPoint,
+			OutPoint:    input.outpoint,
+			Amount:      input.amt,
+			SignDesc:    input.signDescriptor,
+			WitnessType: input.witnessType,
+		}
+
+		for {
+			err := b.cfg.ExternalSweeper.DeferOutput(output)
+			if err == nil {
+				break
+			}
+
+			brarLog.Errorf("Unable to hand output %v of "+
+				"ChannelPoint(%v) off to external sweeper: %v",
+				input.outpoint, sweep.chanPoint, err)
+
+			select {
+			case <-time.After(deferOutputBackoff):
+			case <-b.quit:
+				return false
+			}
+		}
+
+		brarLog.Infof("Handed output %v of ChannelPoint(%v) worth %v "+
+			"off to external sweeper", input.outpoint,
+			sweep.chanPoint, input.amt)
+	}
+
+	return true
+}
+
+// sweepCommitInputs sweeps the inputs of the given commitment sweep, and waits
+// for the sweep to reach CommitSweepConfDepth confirmations. The total value
+// swept back into the wallet is returned, which will be zero if nothing could
+// be swept, along with the height at which the sweep confirmed. The final
+// return value is false if the sweep must be resumed later, e.g. as the breach
+// arbiter is shutting down.
+func (b *breachArbiter) sweepCommitInputs(
+	sweep *commitSweepInfo) (btcutil.Amount, uint32, bool) {
+
+	if len(sweep.inputs) == 0 {
+		return 0, 0, true
+	}
+
+	// Unless the sweep was crafted before a restart, we'll craft it now,
+	// persisting it before it's broadcast. If batching, the sweep is
+	// crafted along with those of any other channels closed within the
+	// batch window. Any input already claimed elsewhere is left out, as
+	// sweeping it would only conflict with the claim.
+	if sweep.sweepTx == nil {
+		b.pruneClaimedCommitInputs(sweep)
+		if len(sweep.inputs) == 0 {
+			return 0, 0, true
+		}
+
+		var (
+			sweepTx  *wire.MsgTx
+			sweptAmt btcutil.Amount
+			err      error
+		)
+		if b.cfg.CommitSweepBatchWindow > 0 {
+			batch := b.joinCommitSweepBatch(sweep)
+			select {
+			case <-batch.done:
+			case <-b.quit:
+				return 0, 0, false
+			}
+
+			sweepTx, err = batch.sweepTx, batch.err
+			sweptAmt = batch.sweptAmts[sweep.chanPoint]
+		} else {
+			sweepTx, err = b.craftCommitSweepTx(sweep.inputs)
+			if err == nil {
+				sweptAmt = btcutil.Amount(sweepTx.TxOut[0].Value)
+			}
+		}
+		if err != nil {
+			brarLog.Errorf("unable to generate sweep tx: %v", err)
+			return 0, 0, true
+		}
+
+		sweep.sweepTx = sweepTx
+		sweep.sweptAmt = sweptAmt
+		if err := b.commitSweeps.Add(sweep); err != nil {
+			brarLog.Errorf("unable to persist sweep tx for "+
+				"ChannelPoint(%v): %v", sweep.chanPoint, err)
+			return 0, 0, false
+		}
+	}
+
+	// The sweep may have already been broadcast before a restart, in which
+	// case rebroadcasting it may fail, so we'll await its confirmation
+	// regardless.
+	if err := b.cfg.Wallet.PublishTransaction(sweep.sweepTx); err != nil {
+		brarLog.Errorf("unable to broadcast tx: %v", err)
+		b.recordBroadcastFailure(sweep.inputs)
+	}
+
+	sweepTxid := sweep.sweepTx.TxHash()
+	confHeight, ok := b.waitForConf(
+		&sweepTxid, b.cfg.CommitSweepConfDepth, sweep.closeHeight,
+	)
+	if !ok {
+		return 0, 0, false
+	}
+
+	b.reportSweepOutputs(sweep.sweepTx)
+
+	if sweep.sweptAmt != 0 {
+		return sweep.sweptAmt, confHeight, true
+	}
+
+	return btcutil.Amount(sweep.sweepTx.TxOut[0].Value), confHeight, true
+}
+
+// pruneClaimedCommitInputs removes any input of the given commitment sweep
+// which has already been claimed elsewhere, see commitInputClaimed.
+func (b *breachArbiter) pruneClaimedCommitInputs(sweep *commitSweepInfo) {
+	inputs := sweep.inputs[:0]
+	for _, input := range sweep.inputs {
+		claimed, reason := b.commitInputClaimed(input, sweep.closeHeight)
+		if claimed {
+			brarLog.Infof("Skipping sweep of output %v of "+
+				"ChannelPoint(%v), as it %v", input.outpoint,
+				sweep.chanPoint, reason)
+			continue
+		}
+
+		inputs = append(inputs, input)
+	}
+	sweep.inputs = inputs
+}
+
+// commitInputClaimed returns true, along with the reason, if the given input
+// of a commitment sweep has already been claimed elsewhere. Only our
+// non-delayed self output may be claimed: it pays to a tweaked key of the
+// wallet, which may have learnt how to spend it, and sweep it independently.
+// The output is deemed claimed once spent, whether on chain or by a
+// transaction within the mempool, or once it's known to the wallet.
+func (b *breachArbiter) commitInputClaimed(input *breachedOutput,
+	heightHint uint32) (bool, string) {
+
+	if input.witnessType != lnwallet.CommitmentNoDelay {
+		return false, ""
+	}
+
+	if spent, reason := b.outputSpent(&input.outpoint, heightHint); spent {
+		return true, reason
+	}
+
+	if b.cfg.Wallet != nil {
+		utxos, err := b.cfg.Wallet.ListUnspentWitness(0)
+		if err != nil {
+			brarLog.Warnf("Unable to list wallet outputs: %v", err)
+			return false, ""
+		}
+		for _, utxo := range utxos {
+			if utxo.OutPoint == input.outpoint {
+				return true, "is known to the wallet"
+			}
+		}
+	}
+
+	return false, ""
+}
+
+// outputSpent returns true, along with the reason, if the given output has
+// been spent, either within the main chain or by a transaction within the
+// mempool. As the chain backend only reports confirmed spends, the mempool is
+// consulted through MempoolSpends, if set.
+func (b *breachArbiter) outputSpent(op *wire.OutPoint,
+	heightHint uint32) (bool, string) {
+
+	_, err := b.cfg.ChainIO.GetUtxo(op, heightHint)
+	if err == lnwallet.ErrOutputSpent {
+		return true, "has already been spent"
+	}
+
+	if b.cfg.MempoolSpends == nil {
+		return false, ""
+	}
+
+	spends, err := b.cfg.MempoolSpends([]wire.OutPoint{*op})
+	switch {
+	case err != nil:
+		brarLog.Warnf("Unable to query mempool for spends of %v: %v",
+			op, err)
+
+	case len(spends) > 0:
+		return true, fmt.Sprintf("is being spent by tx %v",
+			spends[0].Txid)
+	}
+
+	return false, ""
+}
+
+// commitSweepBatch is a set of commitment sweeps whose inputs are swept by a
+// single transaction.
+//
+// TODO(roasbeef): also include the HTLC outputs left out of justice
+// transactions as being uneconomic on their own
+type commitSweepBatch struct {
+	sweeps []*commitSweepInfo
+
+	// sweepTx, sweptAmts and err hold the outcome of crafting the batch's
+	// transaction, and may only be read once done has been closed.
+	sweepTx   *wire.MsgTx
+	sweptAmts map[wire.OutPoint]btcutil.Amount
+	err       error
+
+	// done is closed once the batch's transaction has been crafted.
+	done chan struct{}
+}
+
+// joinCommitSweepBatch adds the sweep to the pending batch, creating one if
+// none is pending, which is crafted once CommitSweepBatchWindow has elapsed.
+func (b *breachArbiter) joinCommitSweepBatch(
+	sweep *commitSweepInfo) *commitSweepBatch {
+
+	b.batchMtx.Lock()
+	defer b.batchMtx.Unlock()
+
+	batch := b.pendingCommitBatch
+	if batch == nil {
+		batch = &commitSweepBatch{
+			done: make(chan struct{}),
+		}
+		b.pendingCommitBatch = batch
+
+		time.AfterFunc(
+			b.cfg.CommitSweepBatchWindow, b.craftCommitSweepBatch,
+		)
+	}
+	batch.sweeps = append(batch.sweeps, sweep)
+
+	return batch
+}
+
+// craftCommitSweepBatch crafts the transaction sweeping the inputs of each
+// sweep within the pending batch, notifying all sweeps waiting on it. The fee
+// is split across the sweeps in proportion to their number of inputs.
+func (b *breachArbiter) craftCommitSweepBatch() {
+	b.batchMtx.Lock()
+	batch := b.pendingCommitBatch
+	b.pendingCommitBatch = nil
+	b.batchMtx.Unlock()
+
+	defer close(batch.done)
+
+	var (
+		inputs   []*breachedOutput
+		totalAmt btcutil.Amount
+	)
+	for _, sweep := range batch.sweeps {
+		inputs = append(inputs, sweep.inputs...)
+		for _, input := range sweep.inputs {
+			totalAmt += input.amt
+		}
+	}
+
+	batch.sweepTx, batch.err = b.craftCommitSweepTx(inputs)
+	if batch.err != nil {
+		return
+	}
+
+	fee := totalAmt - btcutil.Amount(batch.sweepTx.TxOut[0].Value)
+	feeShares := make([]btcutil.Amount, len(batch.sweeps))
+	var allotted btcutil.Amount
+	for i, sweep := range batch.sweeps {
+		feeShares[i] = fee * btcutil.Amount(len(sweep.inputs)) /
+			btcutil.Amount(len(inputs))
+		allotted += feeShares[i]
+	}
+	feeShares[0] += fee - allotted
+
+	batch.sweptAmts = make(map[wire.OutPoint]btcutil.Amount)
+	for i, sweep := range batch.sweeps {
+		var sweepAmt btcutil.Amount
+		for _, input := range sweep.inputs {
+			sweepAmt += input.amt
+		}
+		batch.sweptAmts[sweep.chanPoint] = sweepAmt - feeShares[i]
+	}
+
+	brarLog.Infof("Sweeping outputs of %v force closed channels within "+
+		"a single transaction", len(batch.sweeps))
+}
+
+// craftCommitSweepTx creates a transaction to sweep the outputs within the
+// remote party's commitment transaction that pay to us. We must manually sweep
+// these outputs as they use a tweaked public key in their pkScript, so the
+// wallet won't immediately be aware of them.
+//
+// TODO(roasbeef): alternative options
+//  * leave the output in the chain, use as input to future funding tx
+//  * leave output in the chain, extend wallet to add knowledge of how to claim
+func (b *breachArbiter) craftCommitSweepTx(
+	inputs []*breachedOutput) (*wire.MsgTx, error) {
+
+	if len(inputs) == 0 {
+		return nil, errors.New("no outputs to sweep")
+	}
+
+	// First, we'll fetch a fresh script that we can use to sweep the funds
+	// under the control of the wallet. A commitment sweep never replaces a
+	// prior transaction, so it always pays to a new script.
+	sweepPkScript, err := b.sweepScript()
+	if err != nil {
+		return nil, err
+	}
+
+	var totalAmt btcutil.Amount
+	for _, input := range inputs {
+		totalAmt += input.amt
+	}
+
+	// TODO(roasbeef): use proper fees
+	//
+	// The sweep has the same shape as a justice transaction, so its
+	// weight is estimated likewise.
+	fee := b.floorFee(commitSweepBaseFee, justiceTxWeight(inputs))
+	outputAmts, err := b.distributeSweep(totalAmt, fee, 1)
+	if err != nil {
+		// TODO(roasbeef): add output to special pool, can be swept
+		// when: funding a channel, sweeping time locked outputs, or
+		// delivering
+		// justice after a channel breach
+		return nil, fmt.Errorf("output to small to sweep in "+
+			"isolation: %v", err)
+	}
+	sweepAmt := int64(outputAmts[0])
+
+	// With the amount we're sweeping computed, we can now creating the
+	// sweep transaction itself.
+	sweepTx := wire.NewMsgTx(1)
+	for _, input := range inputs {
+		sweepTx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: input.outpoint,
+		})
+	}
+	sweepTx.AddTxOut(&wire.TxOut{
+		PkScript: sweepPkScript,
+		Value:    int64(sweepAmt),
+	})
+
+	// Finally, we'll generate the witness for each input. This is the
+	// exact same as a regular p2wkh witness, but using the tweaked public
+	// key which was originally used to create the pkScript we're
+	// spending.
+	hashCache := txscript.NewTxSigHashes(sweepTx)
+	signer := &b.cfg.Signer
+	for i, input := range inputs {
+		witnessFunc := input.genWitnessFunc(signer)
+		witness, err := witnessFunc(sweepTx, hashCache, i)
+		b.recordWitnessResult(input.witnessType, err)
+		if err != nil {
+			return nil, err
+		}
+
+		sweepTx.TxIn[i].Witness = witness
+	}
+
+	brarLog.Infof("Sweeping commitment output with: %v", spew.Sdump(sweepTx))
+
+	return sweepTx, nil
+}
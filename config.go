@@ -120,6 +120,18 @@ type config struct {
 
 	DefaultNumChanConfs int `long:"defaultchanconfs" description:"The default number of confirmations a channel must have before it's considered open."`
 
+	RestoredFromBackup bool `long:"restoredfrombackup" description:"Indicates that the channel database was restored from a backup and may be stale. Each channel within it is marked as restored upon startup, and a spend of a marked channel by a commitment we believe to be revoked is treated as a possibly legitimate force close: our own output is swept, but no justice transaction is broadcast. Channels opened afterwards are unaffected"`
+
+	RetainBreachEvidence bool `long:"retainbreachevidence" description:"Once justice has been served for a breach, archive its retribution along with the outcome for later forensic analysis, rather than deleting it"`
+
+	JusticeBroadcastURLs []string `long:"justicebroadcasturl" description:"An HTTP endpoint, such as that of a block explorer, to which the hex encoding of justice transactions is POSTed, in addition to broadcasting them via the wallet. May be specified multiple times"`
+
+	JusticeConfTimeout time.Duration `long:"justiceconftimeout" description:"The maximum time to await confirmation of a justice transaction, across all fee bumps, after which it's no longer bumped and the retribution is flagged for manual intervention. Disabled by default"`
+
+	JusticeApprovalThreshold   uint64        `long:"justiceapprovalthreshold" description:"The value at risk, in satoshis, above which a justice transaction isn't broadcast until approved by the operator. Disabled by default"`
+	JusticeApprovalTimeout     time.Duration `long:"justiceapprovaltimeout" description:"The time a justice transaction awaits approval before it's either approved automatically or escalated to the operator. Valid time units are {s, m, h}. If zero, approval is awaited indefinitely"`
+	JusticeApprovalAutoApprove bool          `long:"justiceapprovalautoapprove" description:"Approve justice transactions automatically once their approval times out, rather than escalating them to the operator"`
+
 	NeutrinoMode *neutrinoConfig `group:"neutrino" namespace:"neutrino"`
 
 	Autopilot *autoPilotConfig `group:"autopilot" namespace:"autopilot"`
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/roasbeef/btcd/chaincfg/chainhash"
+)
+
+// waitForConf blocks until the given transaction reaches the target number of
+// confirmations, returning the height of the block in which it confirmed, or
+// zero if unknown. It returns false if the notifier or the breach arbiter shut
+// down beforehand.
+func (b *breachArbiter) waitForConf(txid *chainhash.Hash, numConfs,
+	heightHint uint32) (uint32, bool) {
+
+	confNtfn, err := b.registerConf(
+		txid, numConfs, heightHint,
+	)
+	if err != nil {
+		brarLog.Errorf("unable to register for conf updates for "+
+			"txid: %v, err: %v", txid, err)
+		return 0, false
+	}
+
+	select {
+	case conf, ok := <-confNtfn.Confirmed:
+		if !ok {
+			return 0, false
+		}
+		if conf == nil {
+			return 0, true
+		}
+		return conf.BlockHeight, true
+	case <-b.quit:
+		return 0, false
+	}
+}
+
+// registerConf registers for numConfs confirmations of the given transaction,
+// scanning from the given height hint clamped by clampHeightHint.
+func (b *breachArbiter) registerConf(txid *chainhash.Hash, numConfs,
+	heightHint uint32) (*chainntnfs.ConfirmationEvent, error) {
+
+	confWaiter := b.cfg.ConfWaiter
+	if confWaiter == nil {
+		confWaiter = &NotifierConfWaiter{Notifier: b.cfg.Notifier}
+	}
+
+	return confWaiter.WaitForConf(
+		txid, numConfs, b.clampHeightHint(heightHint), b.quit,
+	)
+}
+
+// ConfirmationWaiter abstracts the means by which the breach arbiter learns of
+// the confirmation of the transactions it tracks.
+type ConfirmationWaiter interface {
+	// WaitForConf returns a ConfirmationEvent that's dispatched once the
+	// given transaction has reached numConfs confirmations. The height
+	// hint is the earliest height at which the transaction may have
+	// confirmed. Any work carried out on behalf of the event must cease
+	// once quit is closed.
+	WaitForConf(txid *chainhash.Hash, numConfs, heightHint uint32,
+		quit <-chan struct{}) (*chainntnfs.ConfirmationEvent, error)
+}
+
+// NotifierConfWaiter is a ConfirmationWaiter which relies on the confirmation
+// notifications pushed by a ChainNotifier.
+type NotifierConfWaiter struct {
+	// Notifier is the ChainNotifier with which confirmation notifications
+	// are registered.
+	Notifier chainntnfs.ChainNotifier
+}
+
+// WaitForConf registers for a confirmation notification of the given
+// transaction with the ChainNotifier.
+//
+// NOTE: This is part of the ConfirmationWaiter interface.
+func (n *NotifierConfWaiter) WaitForConf(txid *chainhash.Hash, numConfs,
+	heightHint uint32,
+	_ <-chan struct{}) (*chainntnfs.ConfirmationEvent, error) {
+
+	return n.Notifier.RegisterConfirmationsNtfn(txid, numConfs, heightHint)
+}
+
+// PollingConfWaiter is a ConfirmationWaiter which periodically queries the
+// chain backend for the confirmation of a transaction, for deployments in
+// which confirmation notifications are unreliable. Blocks are scanned for the
+// transaction from the height hint onwards, and rescanned from the height
+// hint should the chain be reorganized beneath the scanned blocks.
+type PollingConfWaiter struct {
+	// ChainIO is queried for the best block, and the blocks in which the
+	// transaction may have confirmed.
+	ChainIO lnwallet.BlockChainIO
+
+	// Interval is the time between successive polls of ChainIO.
+	Interval time.Duration
+}
+
+// WaitForConf launches a goroutine polling for the confirmation of the given
+// transaction, which exits once it's dispatched or quit is closed.
+//
+// NOTE: This is part of the ConfirmationWaiter interface.
+func (p *PollingConfWaiter) WaitForConf(txid *chainhash.Hash, numConfs,
+	heightHint uint32,
+	quit <-chan struct{}) (*chainntnfs.ConfirmationEvent, error) {
+
+	if p.Interval <= 0 {
+		return nil, fmt.Errorf("invalid poll interval: %v", p.Interval)
+	}
+	if numConfs == 0 {
+		numConfs = 1
+	}
+
+	confEvent := &chainntnfs.ConfirmationEvent{
+		Confirmed:    make(chan *chainntnfs.TxConfirmation, 1),
+		NegativeConf: make(chan int32, 1),
+	}
+
+	go func() {
+		ticker := time.NewTicker(p.Interval)
+		defer ticker.Stop()
+
+		scan := &confScan{
+			txid:       *txid,
+			heightHint: heightHint,
+			nextHeight: heightHint,
+		}
+		for {
+			conf, err := p.poll(scan, numConfs)
+			switch {
+			case err != nil:
+				brarLog.Errorf("unable to poll for "+
+					"confirmation of txid %v: %v", txid,
+					err)
+
+			case conf != nil:
+				confEvent.Confirmed <- conf
+				return
+			}
+
+			select {
+			case <-ticker.C:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	return confEvent, nil
+}
+
+// confScan tracks the progress of a PollingConfWaiter scanning the chain for
+// the confirmation of a transaction.
+type confScan struct {
+	txid       chainhash.Hash
+	heightHint uint32
+
+	// nextHeight is the height of the next block to be scanned, and
+	// lastHash the hash of the block preceding it, if any was scanned.
+	nextHeight uint32
+	lastHash   *chainhash.Hash
+
+	// conf is the confirmation of the transaction, once found.
+	conf *chainntnfs.TxConfirmation
+}
+
+// poll advances the given scan to the current best block, returning the
+// transaction's confirmation once it has reached numConfs confirmations.
+func (p *PollingConfWaiter) poll(scan *confScan,
+	numConfs uint32) (*chainntnfs.TxConfirmation, error) {
+
+	_, bestHeight, err := p.ChainIO.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// Should the block in which the transaction confirmed, or the last
+	// one we scanned, have been reorged out, we'll rescan from the height
+	// hint.
+	checkHash := scan.lastHash
+	checkHeight := scan.nextHeight - 1
+	if scan.conf != nil {
+		checkHash = scan.conf.BlockHash
+		checkHeight = scan.conf.BlockHeight
+	}
+	if checkHash != nil {
+		hash, err := p.ChainIO.GetBlockHash(int64(checkHeight))
+		if err != nil || *hash != *checkHash {
+			brarLog.Warnf("Block %v at height %v reorged out, "+
+				"rescanning for txid %v", checkHash,
+				checkHeight, scan.txid)
+
+			scan.nextHeight = scan.heightHint
+			scan.lastHash = nil
+			scan.conf = nil
+		}
+	}
+
+	for scan.conf == nil && int64(scan.nextHeight) <= int64(bestHeight) {
+		hash, err := p.ChainIO.GetBlockHash(int64(scan.nextHeight))
+		if err != nil {
+			return nil, err
+		}
+		block, err := p.ChainIO.GetBlock(hash)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, tx := range block.Transactions {
+			if tx.TxHash() != scan.txid {
+				continue
+			}
+
+			scan.conf = &chainntnfs.TxConfirmation{
+				BlockHash:   hash,
+				BlockHeight: scan.nextHeight,
+				TxIndex:     uint32(i),
+			}
+			break
+		}
+
+		scan.lastHash = hash
+		scan.nextHeight++
+	}
+
+	if scan.conf == nil {
+		return nil, nil
+	}
+	depth := int64(bestHeight) - int64(scan.conf.BlockHeight) + 1
+	if depth < int64(numConfs) {
+		return nil, nil
+	}
+
+	return scan.conf, nil
+}
+
+// clampHeightHint returns the given height hint, clamped to the current best
+// height. A persisted height may lie ahead of the chain tip, e.g. after a
+// reorg, and a height hint beyond the tip could lead the notifier to miss the
+// confirmation. Should the best height be unavailable, the hint is returned
+// unchanged.
+func (b *breachArbiter) clampHeightHint(heightHint uint32) uint32 {
+	if b.cfg.ChainIO == nil {
+		return heightHint
+	}
+
+	_, bestHeight, err := b.cfg.ChainIO.GetBestBlock()
+	if err != nil {
+		brarLog.Errorf("unable to get best height to clamp height "+
+			"hint %v: %v", heightHint, err)
+		return heightHint
+	}
+	if bestHeight < 0 || uint32(bestHeight) >= heightHint {
+		return heightHint
+	}
+
+	brarLog.Warnf("Height hint %v is ahead of best height %v, clamping",
+		heightHint, bestHeight)
+
+	return uint32(bestHeight)
+}
+
+// waitForCloseConf waits for the given transaction closing a channel to reach
+// numConfs confirmations. Should the transaction be reorged out beforehand,
+// the channel is left pending close, and we wait anew for the transaction to
+// reconfirm. The height at which the transaction confirmed is returned, along
+// with false if the breach arbiter or ChainNotifier shut down beforehand.
+func (b *breachArbiter) waitForCloseConf(txid *chainhash.Hash, numConfs,
+	heightHint uint32) (uint32, bool) {
+
+	for {
+		confNtfn, err := b.registerConfWithRetry(
+			txid, numConfs, heightHint,
+		)
+		if err != nil {
+			brarLog.Errorf("unable to register for conf updates "+
+				"for txid: %v, err: %v", txid, err)
+			return 0, false
+		}
+
+		// In the case that the ChainNotifier is shutting down, all
+		// subscriber notification channels will be closed, generating
+		// a nil receive.
+		select {
+		case conf, ok := <-confNtfn.Confirmed:
+			if !ok {
+				return 0, false
+			}
+			if conf == nil {
+				return 0, true
+			}
+			return conf.BlockHeight, true
+
+		case reorgDepth, ok := <-confNtfn.NegativeConf:
+			if !ok {
+				return 0, false
+			}
+
+			brarLog.Warnf("Closing transaction %v was reorged out "+
+				"at depth %v, waiting for it to reconfirm",
+				txid, reorgDepth)
+
+		case <-b.quit:
+			return 0, false
+		}
+	}
+}
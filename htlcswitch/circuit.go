@@ -119,6 +119,15 @@ func (m *circuitMap) remove(key circuitKey) (*paymentCircuit, error) {
 		" for key %v", key)
 }
 
+// lookup returns the active circuit for the target key, if one exists.
+func (m *circuitMap) lookup(key circuitKey) (*paymentCircuit, bool) {
+	m.RLock()
+	defer m.RUnlock()
+
+	circuit, ok := m.circuits[key]
+	return circuit, ok
+}
+
 // pending returns number of circuits which are waiting for to be completed
 // (settle/fail responses to be received).
 func (m *circuitMap) pending() int {
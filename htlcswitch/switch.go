@@ -615,6 +615,49 @@ func (s *Switch) handlePacketForward(packet *htlcPacket) error {
 	}
 }
 
+// ResolveHTLC resolves an HTLC whose outgoing link is no longer able to do so
+// itself, e.g. because the channel was breached and the HTLC's output has
+// been swept on-chain. If the preimage is known the HTLC is settled back
+// towards its source, otherwise it's failed back with a temporary channel
+// failure. This tears down the payment circuit, ensuring the upstream hop, or
+// the local payment, isn't left hanging.
+func (s *Switch) ResolveHTLC(payHash [sha256.Size]byte,
+	amount lnwire.MilliSatoshi, preimage *[sha256.Size]byte) error {
+
+	if preimage != nil {
+		return s.forward(newSettlePacket(
+			lnwire.ShortChannelID{},
+			&lnwire.UpdateFufillHTLC{
+				PaymentPreimage: *preimage,
+			},
+			payHash, amount,
+		))
+	}
+
+	// If the HTLC was forwarded on behalf of another hop, we'll encrypt
+	// the failure for the source of the circuit, just as if it had been
+	// generated by us when the HTLC was first forwarded.
+	var reason lnwire.OpaqueReason
+	if circuit, ok := s.circuits.lookup(payHash); ok {
+		failure := lnwire.NewTemporaryChannelFailure(nil)
+
+		var err error
+		reason, err = circuit.Obfuscator.InitialObfuscate(failure)
+		if err != nil {
+			return errors.Errorf("unable to obfuscate error: %v",
+				err)
+		}
+	}
+
+	return s.forward(newFailPacket(
+		lnwire.ShortChannelID{},
+		&lnwire.UpdateFailHTLC{
+			Reason: reason,
+		},
+		payHash, amount, true,
+	))
+}
+
 // CloseLink creates and sends the close channel command.
 func (s *Switch) CloseLink(chanPoint *wire.OutPoint,
 	closeType ChannelCloseType) (chan *lnrpc.CloseStatusUpdate, chan error) {
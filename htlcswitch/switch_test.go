@@ -189,6 +189,90 @@ func TestSwitchCancel(t *testing.T) {
 	}
 }
 
+// TestSwitchResolveHTLC checks that HTLCs resolved outside of their outgoing
+// link are settled or failed back to the source of their circuit.
+func TestSwitchResolveHTLC(t *testing.T) {
+	t.Parallel()
+
+	alicePeer := newMockServer(t, "alice")
+	bobPeer := newMockServer(t, "bob")
+
+	aliceChannelLink := newMockChannelLink(chanID1, aliceChanID, alicePeer)
+	bobChannelLink := newMockChannelLink(chanID2, bobChanID, bobPeer)
+
+	s := New(Config{
+		UpdateTopology: func(msg *lnwire.ChannelUpdate) error {
+			return nil
+		},
+	})
+	s.Start()
+	if err := s.AddLink(aliceChannelLink); err != nil {
+		t.Fatalf("unable to add alice link: %v", err)
+	}
+	if err := s.AddLink(bobChannelLink); err != nil {
+		t.Fatalf("unable to add bob link: %v", err)
+	}
+
+	// forwardHTLC forwards a new HTLC from alice channel link to bob
+	// channel link, creating a circuit for it.
+	forwardHTLC := func(rhash [sha256.Size]byte) {
+		packet := newAddPacket(
+			aliceChannelLink.ShortChanID(),
+			bobChannelLink.ShortChanID(),
+			&lnwire.UpdateAddHTLC{
+				PaymentHash: rhash,
+				Amount:      1,
+			}, newMockObfuscator(),
+		)
+		if err := s.forward(packet); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case <-bobChannelLink.packets:
+		case <-time.After(time.Second):
+			t.Fatal("request was not propogated to destination")
+		}
+	}
+
+	// assertResolved asserts that alice channel link received a resolution
+	// of the expected type.
+	assertResolved := func(expSettle bool) {
+		select {
+		case packet := <-aliceChannelLink.packets:
+			_, isSettle := packet.htlc.(*lnwire.UpdateFufillHTLC)
+			if isSettle != expSettle {
+				t.Fatalf("unexpected resolution: %T",
+					packet.htlc)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("resolution was not propagated to source")
+		}
+	}
+
+	// With the preimage known, the HTLC should be settled back.
+	preimage := [sha256.Size]byte{1}
+	rhash := fastsha256.Sum256(preimage[:])
+	forwardHTLC(rhash)
+	if err := s.ResolveHTLC(rhash, 1, &preimage); err != nil {
+		t.Fatalf("unable to resolve htlc: %v", err)
+	}
+	assertResolved(true)
+
+	// Without it, the HTLC should be failed back instead.
+	preimage = [sha256.Size]byte{2}
+	rhash = fastsha256.Sum256(preimage[:])
+	forwardHTLC(rhash)
+	if err := s.ResolveHTLC(rhash, 1, nil); err != nil {
+		t.Fatalf("unable to resolve htlc: %v", err)
+	}
+	assertResolved(false)
+
+	if s.circuits.pending() != 0 {
+		t.Fatal("wrong amount of circuits")
+	}
+}
+
 // TestSwitchAddSamePayment tests that we send the payment with the same
 // payment hash.
 func TestSwitchAddSamePayment(t *testing.T) {
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/roasbeef/btcd/wire"
+)
+
+// peerJusticeBatch collects the retributions for concurrent breaches by a
+// single peer, so that they may be served by one justice transaction once all
+// of the breach transactions have confirmed.
+type peerJusticeBatch struct {
+	// peer is the identity of the peer responsible for the breaches.
+	peer [33]byte
+
+	// pending holds the retributions whose breach transaction has yet to
+	// confirm.
+	pending map[wire.OutPoint]*retributionInfo
+
+	// confirmed holds the retributions whose breach transaction has
+	// confirmed, in order of confirmation.
+	confirmed []*retributionInfo
+
+	// justiceTx and err hold the outcome of serving the batch, and may
+	// only be read once done has been closed.
+	justiceTx *wire.MsgTx
+	err       error
+
+	// done is closed once the batch has been served.
+	done chan struct{}
+}
+
+// joinPeerBatch adds the retribution to the batch of its peer, creating the
+// batch if the peer has none outstanding. Without the identity of the
+// breaching party, the retribution can't be matched up with others from the
+// same peer, so nil is returned and it's to be served alone.
+func (b *breachArbiter) joinPeerBatch(
+	ret *retributionInfo) *peerJusticeBatch {
+
+	if !ret.hasRemoteIdentity() {
+		brarLog.Warnf("Remote identity of ChannelPoint(%v) unknown, "+
+			"serving its justice alone", ret.chanPoint)
+		return nil
+	}
+
+	var peer [33]byte
+	copy(peer[:], ret.remoteIdentity.SerializeCompressed())
+
+	b.batchMtx.Lock()
+	defer b.batchMtx.Unlock()
+
+	batch, ok := b.peerBatches[peer]
+	if !ok {
+		batch = &peerJusticeBatch{
+			peer:    peer,
+			pending: make(map[wire.OutPoint]*retributionInfo),
+			done:    make(chan struct{}),
+		}
+		b.peerBatches[peer] = batch
+	}
+	batch.pending[ret.chanPoint] = ret
+
+	return batch
+}
+
+// awaitPeerJustice records that the retribution's breach transaction has
+// confirmed, then waits for the justice transaction serving its peer's batch.
+// The last retribution of the batch to confirm serves it on behalf of all.
+// Should the rest of the batch fail to confirm by the retribution's batch
+// deadline, see peerBatchDeadline, it leaves the batch and is served alone.
+func (b *breachArbiter) awaitPeerJustice(batch *peerJusticeBatch,
+	ret *retributionInfo, cancel <-chan struct{}) (*wire.MsgTx, error) {
+
+	b.batchMtx.Lock()
+	delete(batch.pending, ret.chanPoint)
+	batch.confirmed = append(batch.confirmed, ret)
+	ready := b.closePeerBatchIfReady(batch)
+	b.batchMtx.Unlock()
+
+	if ready {
+		b.servePeerBatch(batch)
+	}
+
+	// The breaching party may hold back the breach transactions of the
+	// rest of the batch, so we'll only wait for them until the batch
+	// deadline of this retribution.
+	var epochs <-chan *chainntnfs.BlockEpoch
+	deadline, expires := b.peerBatchDeadline(ret)
+	if !ready && expires {
+		epochEvent, err := b.cfg.Notifier.RegisterBlockEpochNtfn()
+		if err != nil {
+			b.leavePeerBatch(batch, ret)
+			return nil, err
+		}
+		defer epochEvent.Cancel()
+
+		epochs = epochEvent.Epochs
+	}
+
+waitForBatch:
+	for {
+		select {
+		case <-batch.done:
+			break waitForBatch
+
+		case epoch, ok := <-epochs:
+			if !ok {
+				return nil, errors.New("block epochs closed")
+			}
+			if uint32(epoch.Height) < deadline {
+				continue
+			}
+
+			// Should the batch already be being served, we'll
+			// await its outcome as usual.
+			if !b.expirePeerBatch(batch, ret) {
+				epochs = nil
+				continue
+			}
+
+			brarLog.Warnf("Breaches of peer %x batched with "+
+				"ChannelPoint(%v) unconfirmed at height %v, "+
+				"serving its justice alone", batch.peer[:],
+				ret.chanPoint, epoch.Height)
+
+			return b.serveJustice([]*retributionInfo{ret})
+
+		case <-cancel:
+			b.leavePeerBatch(batch, ret)
+			return nil, errRetributionCancelled
+
+		case <-b.quit:
+			return nil, errors.New("breach arbiter shutting down")
+		}
+	}
+
+	if batch.err != nil {
+		return nil, batch.err
+	}
+
+	// The retribution may have been cancelled after the batch began to be
+	// served, in which case it won't have been included.
+	for _, txIn := range batch.justiceTx.TxIn {
+		if txIn.PreviousOutPoint == ret.revokedOutput.outpoint {
+			return batch.justiceTx, nil
+		}
+	}
+
+	return nil, errRetributionCancelled
+}
+
+// peerBatchDeadline returns the height at which a retribution whose breach
+// transaction has confirmed stops waiting for the rest of its peer's batch,
+// being halfway to the height at which the breaching party may first claim any
+// of its outputs. False is returned if they may never claim any, in which case
+// there's no deadline.
+func (b *breachArbiter) peerBatchDeadline(ret *retributionInfo) (uint32, bool) {
+	var (
+		remaining uint32
+		contested bool
+	)
+	for _, output := range ret.breachedOutputs() {
+		left, ok := b.blocksUntilContested(output)
+		if !ok {
+			continue
+		}
+		if !contested || left < remaining {
+			remaining = left
+		}
+		contested = true
+	}
+	if !contested {
+		return 0, false
+	}
+
+	_, height, err := b.cfg.ChainIO.GetBestBlock()
+	if err != nil {
+		brarLog.Errorf("Unable to get best height, serving justice "+
+			"for ChannelPoint(%v) alone: %v", ret.chanPoint, err)
+		return 0, true
+	}
+
+	return uint32(height) + remaining/2, true
+}
+
+// expirePeerBatch removes a retribution whose batch deadline has passed from
+// its peer's batch, such that it may be served alone. False is returned if the
+// batch has already been closed, in which case the retribution is to be served
+// along with it.
+func (b *breachArbiter) expirePeerBatch(batch *peerJusticeBatch,
+	ret *retributionInfo) bool {
+
+	b.batchMtx.Lock()
+	defer b.batchMtx.Unlock()
+
+	if b.peerBatches[batch.peer] != batch {
+		return false
+	}
+
+	for i, confirmed := range batch.confirmed {
+		if confirmed.chanPoint == ret.chanPoint {
+			batch.confirmed = append(
+				batch.confirmed[:i], batch.confirmed[i+1:]...,
+			)
+			break
+		}
+	}
+
+	return true
+}
+
+// leavePeerBatch removes a cancelled retribution from its peer's batch, which
+// may allow the batch to be served if all others have confirmed.
+func (b *breachArbiter) leavePeerBatch(batch *peerJusticeBatch,
+	ret *retributionInfo) {
+
+	b.batchMtx.Lock()
+	delete(batch.pending, ret.chanPoint)
+	for i, confirmed := range batch.confirmed {
+		if confirmed.chanPoint == ret.chanPoint {
+			batch.confirmed = append(
+				batch.confirmed[:i], batch.confirmed[i+1:]...,
+			)
+			break
+		}
+	}
+	ready := b.closePeerBatchIfReady(batch)
+	b.batchMtx.Unlock()
+
+	if ready {
+		b.servePeerBatch(batch)
+	}
+}
+
+// closePeerBatchIfReady closes the batch to further retributions, which will
+// instead form a new batch, if the breach transactions of all of its
+// retributions have confirmed. It returns true if the batch was closed, in
+// which case the caller is responsible for serving it.
+//
+// NOTE: This MUST be called with the batchMtx held.
+func (b *breachArbiter) closePeerBatchIfReady(batch *peerJusticeBatch) bool {
+	if len(batch.pending) > 0 || b.peerBatches[batch.peer] != batch {
+		return false
+	}
+
+	delete(b.peerBatches, batch.peer)
+	return true
+}
+
+// servePeerBatch serves justice for all retributions within a closed batch,
+// notifying those waiting on it of the outcome.
+func (b *breachArbiter) servePeerBatch(batch *peerJusticeBatch) {
+	rets := batch.confirmed
+
+	// If every retribution left the batch, there's nothing to serve.
+	if len(rets) == 0 {
+		batch.err = errRetributionCancelled
+		close(batch.done)
+		return
+	}
+
+	brarLog.Infof("Serving justice for %v breached channel(s) with "+
+		"peer %x in a single transaction", len(rets), batch.peer[:])
+
+	batch.justiceTx, batch.err = b.serveJustice(rets)
+	close(batch.done)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/roasbeef/btcd/wire"
+)
+
+// Broadcaster is capable of broadcasting a transaction to the network.
+type Broadcaster interface {
+	// PublishTransaction broadcasts the passed transaction, returning an
+	// error if it wasn't accepted.
+	PublishTransaction(tx *wire.MsgTx) error
+}
+
+// BroadcastTarget is a named endpoint to which justice transactions are
+// broadcast.
+type BroadcastTarget struct {
+	// Name identifies the target within logs and stats.
+	Name string
+
+	// Broadcaster broadcasts transactions to the target.
+	Broadcaster Broadcaster
+}
+
+// walletBroadcastTarget is the name under which broadcasts via the wallet are
+// tracked.
+const walletBroadcastTarget = "wallet"
+
+// httpBroadcaster broadcasts transactions by POSTing their hex encoding to an
+// HTTP endpoint, as accepted by block explorer APIs such as Esplora's.
+type httpBroadcaster struct {
+	url    string
+	client *http.Client
+}
+
+// newHTTPBroadcaster creates a broadcaster POSTing transactions to the given
+// url.
+func newHTTPBroadcaster(url string) *httpBroadcaster {
+	return &httpBroadcaster{
+		url: url,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// PublishTransaction POSTs the hex encoding of the transaction to the
+// broadcaster's url, which must respond with a status of 200.
+//
+// NOTE: This is part of the Broadcaster interface.
+func (h *httpBroadcaster) PublishTransaction(tx *wire.MsgTx) error {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return err
+	}
+
+	resp, err := h.client.Post(
+		h.url, "text/plain",
+		bytes.NewReader([]byte(hex.EncodeToString(buf.Bytes()))),
+	)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		reason, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%v: %s", resp.Status, reason)
+	}
+
+	return nil
+}
+
+// publishJustice broadcasts a justice transaction, or a child bumping its fee,
+// via the wallet and each of the configured BroadcastTargets. A failure of any
+// one target doesn't prevent broadcasting to the others, and the transaction
+// is considered broadcast if accepted by at least one of them. Otherwise, the
+// wallet's error is returned. If VerifyJusticeRelay is set, the transaction is
+// then verified to reach the mempool in the background.
+func (b *breachArbiter) publishJustice(tx *wire.MsgTx) error {
+	if err := b.broadcastJustice(tx); err != nil {
+		return err
+	}
+
+	if b.cfg.VerifyJusticeRelay && b.cfg.MempoolSpends != nil {
+		b.wg.Add(1)
+		go b.verifyJusticeRelay(tx)
+	}
+
+	return nil
+}
+
+// broadcastJustice broadcasts the given justice transaction, see
+// publishJustice.
+func (b *breachArbiter) broadcastJustice(tx *wire.MsgTx) error {
+	walletErr := b.cfg.Wallet.PublishTransaction(tx)
+	b.recordTargetBroadcast(walletBroadcastTarget, walletErr)
+	if walletErr != nil {
+		brarLog.Warnf("Wallet failed to broadcast tx %v: %v",
+			tx.TxHash(), walletErr)
+	}
+
+	accepted := walletErr == nil
+	for _, target := range b.cfg.BroadcastTargets {
+		err := target.Broadcaster.PublishTransaction(tx)
+		b.recordTargetBroadcast(target.Name, err)
+		if err != nil {
+			brarLog.Warnf("Broadcast target %v failed to broadcast "+
+				"tx %v: %v", target.Name, tx.TxHash(), err)
+			continue
+		}
+
+		accepted = true
+	}
+
+	if !accepted {
+		return walletErr
+	}
+
+	return nil
+}
+
+// verifyJusticeRelay verifies that the given justice transaction reaches the
+// mempool of the backing node within JusticeRelayTimeout of its broadcast,
+// re-broadcasting it otherwise, up to justiceRelayAttempts broadcasts in
+// total. Verification ceases once the transaction, or any other spending its
+// inputs such as a replacement, is found within the mempool, or once the
+// transaction has confirmed.
+//
+// NOTE: This MUST be run as a goroutine.
+func (b *breachArbiter) verifyJusticeRelay(tx *wire.MsgTx) {
+	defer b.wg.Done()
+
+	txid := tx.TxHash()
+	inputs := make([]wire.OutPoint, 0, len(tx.TxIn))
+	for _, txIn := range tx.TxIn {
+		inputs = append(inputs, txIn.PreviousOutPoint)
+	}
+
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-time.After(b.cfg.JusticeRelayTimeout):
+		case <-b.quit:
+			return
+		}
+
+		spends, err := b.cfg.MempoolSpends(inputs)
+		if err != nil {
+			brarLog.Errorf("Unable to verify relay of justice tx "+
+				"%v: %v", txid, err)
+			return
+		}
+
+		// Any transaction spending the inputs, be it the justice
+		// transaction or a replacement, has been relayed.
+		if len(spends) > 0 {
+			return
+		}
+
+		// Having left the mempool, the transaction may have already
+		// confirmed.
+		txOut, err := b.cfg.ChainIO.GetUtxo(
+			&wire.OutPoint{Hash: txid}, 0,
+		)
+		if err == nil && txOut != nil {
+			return
+		}
+
+		if attempt == justiceRelayAttempts {
+			brarLog.Errorf("Justice tx %v still absent from the "+
+				"mempool after %d broadcasts", txid, attempt)
+			return
+		}
+
+		brarLog.Warnf("Justice tx %v absent from the mempool %v after "+
+			"broadcast, re-broadcasting", txid,
+			b.cfg.JusticeRelayTimeout)
+
+		if err := b.broadcastJustice(tx); err != nil {
+			brarLog.Errorf("Unable to re-broadcast justice tx "+
+				"%v: %v", txid, err)
+		}
+	}
+}
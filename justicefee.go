@@ -0,0 +1,762 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/roasbeef/btcd/blockchain"
+	"github.com/roasbeef/btcd/txscript"
+	"github.com/roasbeef/btcd/wire"
+	"github.com/roasbeef/btcutil"
+)
+
+// justiceBumpTier returns the fee tier a justice transaction should be at once
+// the given number of blocks have elapsed since its broadcast, which is the
+// number of milestones within the schedule that have been reached.
+func justiceBumpTier(schedule []uint32, elapsed uint32) uint32 {
+	var tier uint32
+	for _, milestone := range schedule {
+		if elapsed >= milestone {
+			tier++
+		}
+	}
+
+	return tier
+}
+
+// justiceFee returns the fee paid by a justice transaction spending the given
+// inputs at the given bump tier. The fee is estimated for the confirmation
+// target returned by justiceConfTarget, falling back to justiceBaseFee if no
+// estimator is available.
+func (b *breachArbiter) justiceFee(inputs []*breachedOutput,
+	tier uint32) btcutil.Amount {
+
+	return b.justiceFeeAtTarget(
+		justiceTxWeight(inputs), b.justiceConfTarget(inputs), tier,
+	)
+}
+
+// justiceFeeForWeight returns the fee paid at the given bump tier by
+// transactions of the given total weight, which is estimated for the
+// configured JusticeFeeTarget, falling back to justiceBaseFee if no estimator
+// is available.
+func (b *breachArbiter) justiceFeeForWeight(weight int64,
+	tier uint32) btcutil.Amount {
+
+	return b.justiceFeeAtTarget(weight, b.cfg.JusticeFeeTarget, tier)
+}
+
+// justiceFeeAtTarget returns the fee paid at the given bump tier by
+// transactions of the given total weight, which is estimated for the given
+// confirmation target, falling back to justiceBaseFee if no estimator is
+// available.
+func (b *breachArbiter) justiceFeeAtTarget(weight int64, confTarget uint32,
+	tier uint32) btcutil.Amount {
+
+	fee := justiceBaseFee
+	if b.cfg.Estimator != nil {
+		fee = b.feePerWeight(confTarget) * btcutil.Amount(weight)
+	}
+
+	return b.floorFee(fee, weight) << tier
+}
+
+// feePerWeight returns the fee rate, in satoshis per weight unit, estimated
+// for the given confirmation target, which is no lower than the configured
+// MinFeeRate. Absent an estimator, the MinFeeRate is returned.
+func (b *breachArbiter) feePerWeight(confTarget uint32) btcutil.Amount {
+	var feePerWeight btcutil.Amount
+	if b.cfg.Estimator != nil {
+		feePerWeight = btcutil.Amount(
+			b.cfg.Estimator.EstimateFeePerWeight(confTarget),
+		)
+	}
+
+	if feePerWeight < b.cfg.MinFeeRate {
+		return b.cfg.MinFeeRate
+	}
+
+	return feePerWeight
+}
+
+// floorFee returns the given fee of a transaction of the given weight, raised
+// to that paid at the configured MinFeeRate should it fall short.
+func (b *breachArbiter) floorFee(fee btcutil.Amount,
+	weight int64) btcutil.Amount {
+
+	if minFee := b.cfg.MinFeeRate * btcutil.Amount(weight); fee < minFee {
+		return minFee
+	}
+
+	return fee
+}
+
+// justiceInputFee returns the marginal fee added to a justice transaction by
+// spending the given input, at the given confirmation target and bump tier.
+// Absent an estimator, justice transactions pay the flat justiceBaseFee, so
+// the marginal fee of any input is zero unless a MinFeeRate is configured.
+func (b *breachArbiter) justiceInputFee(input *breachedOutput,
+	confTarget uint32, tier uint32) btcutil.Amount {
+
+	weight := blockchain.WitnessScaleFactor*lnwallet.FundingInputSize +
+		input.witnessSize()
+
+	return (b.feePerWeight(confTarget) * btcutil.Amount(weight)) << tier
+}
+
+// justiceConfTarget returns the confirmation target, in blocks, used to
+// estimate the fee of a justice transaction spending the given inputs. The
+// configured JusticeFeeTarget buys urgency, which is only worth paying for if
+// the breaching party can race us for any of the inputs right away. Should
+// they instead have to wait for a timelock to expire before claiming any of
+// them, the target is relaxed to half of the blocks remaining until the
+// earliest such deadline, leaving the remainder for fee bumps. Should they be
+// unable to claim any of the inputs, there's no race at all.
+func (b *breachArbiter) justiceConfTarget(inputs []*breachedOutput) uint32 {
+	var (
+		remaining uint32
+		contested bool
+	)
+	for _, input := range inputs {
+		left, ok := b.blocksUntilContested(input)
+		if !ok {
+			continue
+		}
+		if !contested || left < remaining {
+			remaining = left
+		}
+		contested = true
+	}
+
+	target := uint32(maxRelaxedJusticeFeeTarget)
+	if contested {
+		if remaining == 0 {
+			return b.cfg.JusticeFeeTarget
+		}
+
+		if half := remaining / 2; half < target {
+			target = half
+		}
+	}
+
+	if target < b.cfg.JusticeFeeTarget {
+		return b.cfg.JusticeFeeTarget
+	}
+
+	return target
+}
+
+// blocksUntilContested returns the number of blocks remaining until the
+// breaching party may claim the given input, racing our justice transaction,
+// and false if they may never claim it.
+func (b *breachArbiter) blocksUntilContested(
+	input *breachedOutput) (uint32, bool) {
+
+	delay, ok := input.contestDeadline()
+	if !ok {
+		return 0, false
+	}
+
+	// Once the breach has confirmed, the height at which the input may be
+	// claimed is known, so the blocks remaining are counted from the
+	// current height.
+	if input.claimHeight != 0 {
+		_, height, err := b.cfg.ChainIO.GetBestBlock()
+		if err == nil {
+			if uint32(height) >= input.claimHeight {
+				return 0, true
+			}
+			return input.claimHeight - uint32(height), true
+		}
+	}
+
+	// Otherwise, justice is only served once the breach has reached
+	// BreachConfDepth confirmations, by which time the CSV delay has
+	// partially elapsed.
+	elapsed := b.cfg.BreachConfDepth
+	if elapsed > 0 {
+		elapsed--
+	}
+	if delay <= elapsed {
+		return 0, true
+	}
+
+	return delay - elapsed, true
+}
+
+// justiceTxWeight estimates the weight of a justice transaction sweeping the
+// given inputs into a single p2wkh output.
+func justiceTxWeight(inputs []*breachedOutput) int64 {
+	return sweepTxWeight(inputs, lnwallet.P2WPKHSize)
+}
+
+// sweepTxWeight estimates the weight of a transaction sweeping the given
+// inputs into a single output, whose public key script is of the given size.
+func sweepTxWeight(inputs []*breachedOutput, pkScriptSize int) int64 {
+	// The non-witness data consists of the version, input and output
+	// counts, the output, and the lock time, along with each input. The
+	// witness data adds the segwit marker and flag, and each witness.
+	baseSize := 4 + 1 + 1 + 8 +
+		wire.VarIntSerializeSize(uint64(pkScriptSize)) + pkScriptSize + 4
+	weight := int64(blockchain.WitnessScaleFactor*baseSize + 2)
+	for _, input := range inputs {
+		weight += blockchain.WitnessScaleFactor *
+			lnwallet.FundingInputSize
+		weight += input.witnessSize()
+	}
+
+	return weight
+}
+
+// justiceOutputSpendable returns true if the wallet is able to spend the output
+// of the given justice transaction, and thus bump its fee via CPFP.
+func (b *breachArbiter) justiceOutputSpendable(justiceTx *wire.MsgTx) bool {
+	if justiceTx == nil || len(justiceTx.TxOut) == 0 {
+		return false
+	}
+
+	justiceOutput := wire.OutPoint{Hash: justiceTx.TxHash()}
+	_, err := b.cfg.Wallet.FetchInputInfo(&justiceOutput)
+	return err == nil
+}
+
+// cpfpChildWeight returns the weight of a CPFP child spending the given number
+// of p2wkh outputs into a single p2wkh output.
+func cpfpChildWeight(numInputs int) int64 {
+	inputs := make([]*breachedOutput, numInputs)
+	for i := range inputs {
+		inputs[i] = &breachedOutput{
+			witnessType: lnwallet.CommitmentNoDelay,
+		}
+	}
+
+	return justiceTxWeight(inputs)
+}
+
+// justiceMarkerScript returns the OP_RETURN script carrying the configured
+// JusticeTxMarker, or nil if none is configured.
+func (b *breachArbiter) justiceMarkerScript() ([]byte, error) {
+	if len(b.cfg.JusticeTxMarker) == 0 {
+		return nil, nil
+	}
+
+	script, err := txscript.NullDataScript(b.cfg.JusticeTxMarker)
+	if err != nil {
+		return nil, fmt.Errorf("invalid justice tx marker: %v", err)
+	}
+
+	return script, nil
+}
+
+// extraOutputWeight returns the weight added to a transaction by an output
+// paying to the given script.
+func extraOutputWeight(pkScript []byte) int64 {
+	return blockchain.WitnessScaleFactor * int64(
+		8+wire.VarIntSerializeSize(uint64(len(pkScript)))+
+			len(pkScript),
+	)
+}
+
+// cpfpJustice bumps the fee of the unconfirmed justice transaction of the
+// retribution to the given tier by broadcasting a child that spends the
+// justice transaction's output, such that the pair pays the bumped fee rate.
+// If the justice output alone can't cover the child's fee, wallet coins are
+// added to the child to pay it, and any change is returned to a fresh wallet
+// address. A prior child, if any, is replaced, and the replacement pays to the
+// same output script.
+func (b *breachArbiter) cpfpJustice(breachInfo *retributionInfo,
+	justiceTx, prevChild *wire.MsgTx, tier uint32) (*wire.MsgTx, error) {
+
+	// The fee already paid by the justice transaction is the difference
+	// between the value of the breached outputs it spends and that of its
+	// output.
+	outputs := []*breachedOutput{
+		breachInfo.selfOutput, breachInfo.revokedOutput,
+	}
+	outputs = append(outputs, breachInfo.htlcOutputs...)
+	byOutPoint := make(map[wire.OutPoint]*breachedOutput, len(outputs))
+	for _, output := range outputs {
+		byOutPoint[output.outpoint] = output
+	}
+
+	var (
+		parentFee    btcutil.Amount
+		parentInputs []*breachedOutput
+	)
+	for _, txIn := range justiceTx.TxIn {
+		output, ok := byOutPoint[txIn.PreviousOutPoint]
+		if !ok {
+			return nil, fmt.Errorf("unknown input %v of justice tx",
+				txIn.PreviousOutPoint)
+		}
+
+		parentFee += output.amt
+		parentInputs = append(parentInputs, output)
+	}
+	for _, txOut := range justiceTx.TxOut {
+		parentFee -= btcutil.Amount(txOut.Value)
+	}
+
+	justiceOutput := &wire.OutPoint{Hash: justiceTx.TxHash()}
+	childInputs := []*wire.OutPoint{justiceOutput}
+	prevOutputs := []*wire.TxOut{justiceTx.TxOut[0]}
+	justiceAmt := btcutil.Amount(justiceTx.TxOut[0].Value)
+
+	// The justice output always comes first, followed by the penalty
+	// output and the marker, if any.
+	parentWeight := sweepTxWeight(
+		parentInputs, len(justiceTx.TxOut[0].PkScript),
+	)
+	for _, txOut := range justiceTx.TxOut[1:] {
+		parentWeight += extraOutputWeight(txOut.PkScript)
+	}
+	childFee := func(numWalletInputs int, change bool) btcutil.Amount {
+		weight := parentWeight + cpfpChildWeight(1+numWalletInputs)
+		if change {
+			weight += changeOutputWeight
+		}
+		return b.justiceFeeForWeight(weight, tier) - parentFee
+	}
+
+	// Should the justice output not cover the child's fee without leaving
+	// dust, the fee is instead paid by wallet coins, and the justice
+	// output is swept in full.
+	dustLimit := b.cfg.SweepDustThreshold
+	sweepAmt := justiceAmt - childFee(0, false)
+	var change btcutil.Amount
+	if sweepAmt < dustLimit {
+		utxos, err := b.cfg.Wallet.ListUnspentWitness(1)
+		if err != nil {
+			return nil, err
+		}
+
+		var coins []*lnwallet.Utxo
+		coins, change, err = selectCPFPFunding(
+			utxos, childFee, dustLimit,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, coin := range coins {
+			txOut, err := b.cfg.Wallet.FetchInputInfo(&coin.OutPoint)
+			if err != nil {
+				return nil, err
+			}
+
+			childInputs = append(childInputs, &coin.OutPoint)
+			prevOutputs = append(prevOutputs, txOut)
+		}
+		sweepAmt = justiceAmt
+	}
+
+	// A replacement of a prior child pays to the same script, while a new
+	// child pays to a fresh one.
+	var pkScript []byte
+	if prevChild != nil {
+		pkScript = prevChild.TxOut[0].PkScript
+	} else {
+		var err error
+		pkScript, err = b.sweepScript()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	child := wire.NewMsgTx(2)
+	for _, childInput := range childInputs {
+		child.AddTxIn(&wire.TxIn{PreviousOutPoint: *childInput})
+	}
+	child.AddTxOut(&wire.TxOut{
+		PkScript: pkScript,
+		Value:    int64(sweepAmt),
+	})
+	if change != 0 {
+		changeScript, err := newChangePkScript(b.cfg.Wallet)
+		if err != nil {
+			return nil, err
+		}
+		child.AddTxOut(&wire.TxOut{
+			PkScript: changeScript,
+			Value:    int64(change),
+		})
+	}
+
+	// Each input of the child is controlled by the wallet, so we'll have
+	// the signer compute the input scripts.
+	hashCache := txscript.NewTxSigHashes(child)
+	for i, prevOutput := range prevOutputs {
+		inputScript, err := b.cfg.Signer.ComputeInputScript(
+			child, &lnwallet.SignDescriptor{
+				Output:     prevOutput,
+				HashType:   txscript.SigHashAll,
+				SigHashes:  hashCache,
+				InputIndex: i,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		child.TxIn[i].SignatureScript = inputScript.ScriptSig
+		child.TxIn[i].Witness = inputScript.Witness
+	}
+
+	brarLog.Infof("Bumping fee of justice tx %v for ChannelPoint(%v) to "+
+		"tier %v via CPFP child %v", justiceTx.TxHash(),
+		breachInfo.chanPoint, tier, child.TxHash())
+
+	if err := b.publishJustice(child); err != nil {
+		return nil, err
+	}
+	breachInfo.bumpTier = tier
+
+	return child, nil
+}
+
+// breachPackageParent describes an unconfirmed breach transaction, whose fee
+// is to be bumped via CPFP by a justice transaction spending its outputs.
+type breachPackageParent struct {
+	// weight is the weight of the breach transaction.
+	weight int64
+
+	// fee is the absolute fee paid by the breach transaction.
+	fee btcutil.Amount
+}
+
+// feePerWeight returns the fee rate, in satoshis per weight unit, currently
+// paid by the breach transaction.
+func (p *breachPackageParent) feePerWeight() btcutil.Amount {
+	if p.weight == 0 {
+		return 0
+	}
+
+	return p.fee / btcutil.Amount(p.weight)
+}
+
+// fetchBreachPackageParent returns the weight and fee of the given unconfirmed
+// breach transaction. Its fee is the difference between the value of the
+// outputs it spends, which are looked up via the ChainIO, and that of its own
+// outputs.
+func (b *breachArbiter) fetchBreachPackageParent(breachTx *wire.MsgTx,
+	heightHint uint32) (*breachPackageParent, error) {
+
+	var fee btcutil.Amount
+	for _, txIn := range breachTx.TxIn {
+		prevOut, err := b.cfg.ChainIO.GetUtxo(
+			&txIn.PreviousOutPoint, heightHint,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch input %v of "+
+				"breach tx %v: %v", txIn.PreviousOutPoint,
+				breachTx.TxHash(), err)
+		}
+
+		fee += btcutil.Amount(prevOut.Value)
+	}
+	for _, txOut := range breachTx.TxOut {
+		fee -= btcutil.Amount(txOut.Value)
+	}
+	if fee < 0 {
+		return nil, fmt.Errorf("breach tx %v spends less than it "+
+			"pays", breachTx.TxHash())
+	}
+
+	return &breachPackageParent{
+		weight: blockchain.GetTransactionWeight(btcutil.NewTx(breachTx)),
+		fee:    fee,
+	}, nil
+}
+
+// justicePackageFee returns the fee a justice transaction of the given weight
+// must pay, at the given bump tier, to bump the fee of its unconfirmed parent
+// breach transaction via CPFP, such that the pair pays the target fee rate.
+func (b *breachArbiter) justicePackageFee(parent *breachPackageParent,
+	childWeight int64, tier uint32) btcutil.Amount {
+
+	packageFee := b.justiceFeeForWeight(parent.weight+childWeight, tier)
+	childFee := b.justiceFeeForWeight(childWeight, tier)
+
+	fee := packageChildFee(parent.fee, packageFee, childFee)
+
+	brarLog.Debugf("Breach tx paying %v (%v/wu) requires justice fee of "+
+		"%v to reach package fee of %v", parent.fee,
+		parent.feePerWeight(), fee, packageFee)
+
+	return fee
+}
+
+// packageChildFee returns the fee a child must pay for it and its parent, which
+// pays parentFee, to together pay packageFee, i.e. the child's own fee plus the
+// parent's shortfall. The child never pays less than childFee, the fee due at
+// the target fee rate for its own weight, as a parent already paying beyond the
+// target rate would otherwise leave the child short.
+func packageChildFee(parentFee, packageFee,
+	childFee btcutil.Amount) btcutil.Amount {
+
+	if fee := packageFee - parentFee; fee > childFee {
+		return fee
+	}
+
+	return childFee
+}
+
+// changeOutputWeight is the weight added to a transaction by a p2wkh change
+// output.
+const changeOutputWeight = blockchain.WitnessScaleFactor *
+	(8 + 1 + lnwallet.P2WPKHSize)
+
+// selectCPFPFunding selects the wallet coins paying the fee of a CPFP child,
+// given the fee of the child as a function of the number of wallet coins it
+// spends, and whether it returns change to the wallet. Coins are selected
+// largest first. As each coin added grows the child, and thus its fee,
+// selection continues until the coins cover the fee at their own weight. The
+// change is returned alongside the coins, and is zero if it would have been
+// dust, in which case it's dropped into the fee instead.
+func selectCPFPFunding(utxos []*lnwallet.Utxo,
+	feeFor func(numCoins int, change bool) btcutil.Amount,
+	dustLimit btcutil.Amount) ([]*lnwallet.Utxo, btcutil.Amount, error) {
+
+	coins := append([]*lnwallet.Utxo(nil), utxos...)
+	sort.Slice(coins, func(i, j int) bool {
+		return coins[i].Value > coins[j].Value
+	})
+
+	var total btcutil.Amount
+	for i, coin := range coins {
+		total += coin.Value
+		numCoins := i + 1
+
+		if total < feeFor(numCoins, false) {
+			continue
+		}
+
+		change := total - feeFor(numCoins, true)
+		if change < dustLimit {
+			change = 0
+		}
+
+		return coins[:numCoins], change, nil
+	}
+
+	return nil, 0, errors.New("insufficient wallet funds to bump " +
+		"justice tx via CPFP")
+}
+
+// newChangePkScript creates a public key script paying to a fresh p2wkh change
+// address of the wallet.
+func newChangePkScript(wallet lnwallet.WalletController) ([]byte, error) {
+	changeAddr, err := wallet.NewAddress(lnwallet.WitnessPubKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return txscript.PayToAddrScript(changeAddr)
+}
+
+// bumpTooSoon returns true if bumping the fee of a justice transaction at the
+// given height would follow its last bump, or broadcast, more closely than
+// JusticeMinBumpBlocks or JusticeMinBumpInterval permit.
+func (b *breachArbiter) bumpTooSoon(lastHeight int32, lastAt time.Time,
+	height int32) bool {
+
+	minBlocks := int32(b.cfg.JusticeMinBumpBlocks)
+	if minBlocks > 0 && height-lastHeight < minBlocks {
+		return true
+	}
+
+	minInterval := b.cfg.JusticeMinBumpInterval
+	return minInterval > 0 && time.Since(lastAt) < minInterval
+}
+
+// bumpJustice replaces the unconfirmed justice transaction of the retribution
+// with one paying the fee of the given tier, and broadcasts it.
+func (b *breachArbiter) bumpJustice(breachInfo *retributionInfo,
+	tier uint32) (*wire.MsgTx, error) {
+
+	prevTier := breachInfo.bumpTier
+	breachInfo.bumpTier = tier
+
+	justiceTx, err := b.createJusticeTx(breachInfo, sweepTxReplacement)
+	if err != nil {
+		breachInfo.bumpTier = prevTier
+		return nil, err
+	}
+
+	brarLog.Infof("Bumping fee of justice tx for ChannelPoint(%v) to "+
+		"tier %v, replacing with txid %v", breachInfo.chanPoint, tier,
+		justiceTx.TxHash())
+
+	err = b.publishJustice(justiceTx)
+	b.recordBroadcastAttempt([]wire.OutPoint{breachInfo.chanPoint}, err)
+	if err != nil {
+		breachInfo.bumpTier = prevTier
+		b.recordBroadcastFailure(
+			[]*breachedOutput{
+				breachInfo.selfOutput, breachInfo.revokedOutput,
+			},
+		)
+
+		return nil, err
+	}
+
+	b.recordJusticeTxid([]*retributionInfo{breachInfo}, justiceTx)
+
+	return justiceTx, nil
+}
+
+// outbidJusticeRivals polls the mempool for transactions spending any of the
+// inputs of the given justice transaction. Should the strongest of them pay a
+// higher fee rate than the justice transaction, the latter is replaced at the
+// lowest bump tier paying both a higher fee rate and a higher absolute fee
+// than the rival, as a replacement must. The replacement is returned, or nil
+// if no rival outbids the justice transaction.
+//
+// NOTE: A rival within the mempool conflicts with the justice transaction, so
+// a child of the latter can't displace it, and so it's always replaced.
+func (b *breachArbiter) outbidJusticeRivals(breachInfo *retributionInfo,
+	justiceTx *wire.MsgTx) (*wire.MsgTx, error) {
+
+	ops := make([]wire.OutPoint, 0, len(justiceTx.TxIn))
+	for _, txIn := range justiceTx.TxIn {
+		ops = append(ops, txIn.PreviousOutPoint)
+	}
+
+	spends, err := b.cfg.MempoolSpends(ops)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pick the rival paying the highest fee rate, ignoring the justice
+	// transaction itself.
+	justiceTXID := justiceTx.TxHash()
+	var rival *MempoolSpend
+	for i := range spends {
+		spend := &spends[i]
+		if spend.Txid == justiceTXID || spend.Weight <= 0 {
+			continue
+		}
+		if rival == nil ||
+			spend.Fee*btcutil.Amount(rival.Weight) >
+				rival.Fee*btcutil.Amount(spend.Weight) {
+
+			rival = spend
+		}
+	}
+	if rival == nil {
+		return nil, nil
+	}
+
+	fee := justiceTxFee(breachInfo, justiceTx)
+	weight := blockchain.GetTransactionWeight(btcutil.NewTx(justiceTx))
+	outbids := func(fee btcutil.Amount) bool {
+		return fee > rival.Fee &&
+			fee*btcutil.Amount(rival.Weight) >
+				rival.Fee*btcutil.Amount(weight)
+	}
+	if outbids(fee) {
+		return nil, nil
+	}
+
+	brarLog.Warnf("Tx %v paying %v is racing justice tx %v paying %v "+
+		"for ChannelPoint(%v)", rival.Txid, rival.Fee, justiceTXID, fee,
+		breachInfo.chanPoint)
+
+	// As the fee doubles with each tier, find the lowest tier at which
+	// we'd outbid the rival.
+	tier := breachInfo.bumpTier
+	for i := 0; i < maxJusticeOutbidDoublings; i++ {
+		tier++
+		fee <<= 1
+		if outbids(fee) {
+			return b.bumpJustice(breachInfo, tier)
+		}
+	}
+
+	return nil, fmt.Errorf("rival tx %v paying %v can't be outbid",
+		rival.Txid, rival.Fee)
+}
+
+// justiceTxFee returns the fee paid by the given justice transaction serving
+// the retribution.
+func justiceTxFee(breachInfo *retributionInfo,
+	justiceTx *wire.MsgTx) btcutil.Amount {
+
+	outputs := append(
+		[]*breachedOutput{breachInfo.selfOutput, breachInfo.revokedOutput},
+		breachInfo.htlcOutputs...,
+	)
+
+	amts := make(map[wire.OutPoint]btcutil.Amount, len(outputs))
+	for _, output := range outputs {
+		amts[output.outpoint] = output.amt
+	}
+
+	var fee btcutil.Amount
+	for _, txIn := range justiceTx.TxIn {
+		fee += amts[txIn.PreviousOutPoint]
+	}
+	for _, txOut := range justiceTx.TxOut {
+		fee -= btcutil.Amount(txOut.Value)
+	}
+
+	return fee
+}
+
+// justiceEvicted returns true if the given justice transaction is known to be
+// absent from the mempool of the backing node, e.g. as its fee fell below the
+// minimum relay fee. Should it have just confirmed, any transaction re-derived
+// in its place is rejected as a double spend, while its confirmation is
+// delivered as usual.
+func (b *breachArbiter) justiceEvicted(justiceTx *wire.MsgTx) bool {
+	if b.cfg.TxInMempool == nil || justiceTx == nil {
+		return false
+	}
+
+	justiceTXID := justiceTx.TxHash()
+	inMempool, err := b.cfg.TxInMempool(&justiceTXID)
+	if err != nil {
+		brarLog.Errorf("unable to query mempool for justice tx %v: %v",
+			justiceTXID, err)
+		return false
+	}
+
+	return !inMempool
+}
+
+// rederiveJustice crafts a new justice transaction for the retribution, whose
+// prior one has been evicted from the mempool, and broadcasts it. Rather than
+// rebroadcasting the evicted transaction, whose fee is likely stale, the new
+// one pays a fee derived from the current estimate at the retribution's bump
+// tier.
+func (b *breachArbiter) rederiveJustice(
+	breachInfo *retributionInfo) (*wire.MsgTx, error) {
+
+	justiceTx, err := b.createJusticeTx(breachInfo, sweepTxReplacement)
+	if err != nil {
+		return nil, err
+	}
+
+	brarLog.Infof("Justice tx %v for ChannelPoint(%v) was evicted from "+
+		"the mempool, re-deriving as txid %v", breachInfo.justiceTxid,
+		breachInfo.chanPoint, justiceTx.TxHash())
+
+	err = b.publishJustice(justiceTx)
+	b.recordBroadcastAttempt([]wire.OutPoint{breachInfo.chanPoint}, err)
+	if err != nil {
+		b.recordBroadcastFailure(
+			[]*breachedOutput{
+				breachInfo.selfOutput, breachInfo.revokedOutput,
+			},
+		)
+
+		return nil, err
+	}
+
+	b.recordJusticeTxid([]*retributionInfo{breachInfo}, justiceTx)
+
+	return justiceTx, nil
+}
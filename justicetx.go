@@ -0,0 +1,883 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/roasbeef/btcd/txscript"
+	"github.com/roasbeef/btcd/wire"
+	"github.com/roasbeef/btcutil"
+)
+
+// recordJusticeTxid records the given justice transaction as the latest
+// serving each of the retributions, persisting it so that it survives a
+// restart. Failing to persist it is logged, rather than interrupting the
+// retributions.
+func (b *breachArbiter) recordJusticeTxid(rets []*retributionInfo,
+	justiceTx *wire.MsgTx) {
+
+	justiceTXID := justiceTx.TxHash()
+	breakdowns := justiceBreakdowns(rets, justiceTx)
+	for i, ret := range rets {
+		ret.justiceTxid = justiceTXID
+		ret.justiceBreakdown = breakdowns[i]
+		if err := b.cfg.Store.Add(ret, b.quit); err != nil {
+			brarLog.Errorf("unable to persist justice txid for "+
+				"ChannelPoint(%v): %v", ret.chanPoint, err)
+		}
+	}
+}
+
+// JusticeHTLCAmount is the value of an HTLC output swept by a justice
+// transaction.
+type JusticeHTLCAmount struct {
+	// OutPoint is the outpoint of the HTLC output.
+	OutPoint wire.OutPoint
+
+	// Amount is the value of the HTLC output.
+	Amount btcutil.Amount
+}
+
+// JusticeBreakdown is the exact breakdown, in satoshis, of the funds swept from
+// a breached channel by its justice transaction, for reconciliation against the
+// chain. Outputs left out of the justice transaction, e.g. as they weren't
+// economical to sweep, aren't included. The fee is that paid by the justice
+// transaction itself, excluding any child bumping it. Should a single justice
+// transaction serve several breached channels, the fee is split between them in
+// proportion to the value swept from each.
+type JusticeBreakdown struct {
+	// SelfAmount is the value of our own output swept.
+	SelfAmount btcutil.Amount
+
+	// RevokedAmount is the value of the revoked output swept.
+	RevokedAmount btcutil.Amount
+
+	// HTLCAmounts are the values of the HTLC outputs swept.
+	HTLCAmounts []JusticeHTLCAmount
+
+	// FeePaid is the portion of the justice transaction's fee borne by
+	// the channel.
+	FeePaid btcutil.Amount
+
+	// NetRecovered is the value swept less the fee paid.
+	NetRecovered btcutil.Amount
+}
+
+// Swept returns the total value of the outputs swept.
+func (j *JusticeBreakdown) Swept() btcutil.Amount {
+	swept := j.SelfAmount + j.RevokedAmount
+	for _, htlc := range j.HTLCAmounts {
+		swept += htlc.Amount
+	}
+
+	return swept
+}
+
+// String returns a human readable summary of the breakdown.
+func (j *JusticeBreakdown) String() string {
+	htlcs := make([]string, 0, len(j.HTLCAmounts))
+	for _, htlc := range j.HTLCAmounts {
+		htlcs = append(htlcs, fmt.Sprintf("%v: %v", htlc.OutPoint,
+			htlc.Amount))
+	}
+
+	return fmt.Sprintf("self=%v, revoked=%v, htlcs=[%v], fee=%v, net=%v",
+		j.SelfAmount, j.RevokedAmount, strings.Join(htlcs, ", "),
+		j.FeePaid, j.NetRecovered)
+}
+
+// Encode serializes the breakdown into the passed byte stream. The net
+// recovered value isn't written, as it's derived from the other fields.
+func (j *JusticeBreakdown) Encode(w io.Writer) error {
+	var scratch [8]byte
+
+	amts := []btcutil.Amount{j.SelfAmount, j.RevokedAmount, j.FeePaid}
+	for _, amt := range amts {
+		binary.BigEndian.PutUint64(scratch[:], uint64(amt))
+		if _, err := w.Write(scratch[:]); err != nil {
+			return err
+		}
+	}
+
+	err := wire.WriteVarInt(w, 0, uint64(len(j.HTLCAmounts)))
+	if err != nil {
+		return err
+	}
+	for _, htlc := range j.HTLCAmounts {
+		if err := writeOutpoint(w, &htlc.OutPoint); err != nil {
+			return err
+		}
+
+		binary.BigEndian.PutUint64(scratch[:], uint64(htlc.Amount))
+		if _, err := w.Write(scratch[:]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Decode deserializes a breakdown from the passed byte stream.
+func (j *JusticeBreakdown) Decode(r io.Reader) error {
+	var scratch [8]byte
+
+	amts := []*btcutil.Amount{&j.SelfAmount, &j.RevokedAmount, &j.FeePaid}
+	for _, amt := range amts {
+		if _, err := io.ReadFull(r, scratch[:]); err != nil {
+			return err
+		}
+		*amt = btcutil.Amount(binary.BigEndian.Uint64(scratch[:]))
+	}
+
+	numHTLCs, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return err
+	}
+	j.HTLCAmounts = make([]JusticeHTLCAmount, numHTLCs)
+	for i := range j.HTLCAmounts {
+		err := readOutpoint(r, &j.HTLCAmounts[i].OutPoint)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.ReadFull(r, scratch[:]); err != nil {
+			return err
+		}
+		j.HTLCAmounts[i].Amount = btcutil.Amount(
+			binary.BigEndian.Uint64(scratch[:]),
+		)
+	}
+
+	j.NetRecovered = j.Swept() - j.FeePaid
+
+	return nil
+}
+
+// justiceBreakdowns returns the breakdown of the funds swept from each of the
+// given retributions by the justice transaction serving them. The fee of the
+// justice transaction is split in proportion to the value swept from each
+// retribution, with any remainder borne by the first.
+func justiceBreakdowns(rets []*retributionInfo,
+	justiceTx *wire.MsgTx) []*JusticeBreakdown {
+
+	swept := make(map[wire.OutPoint]struct{}, len(justiceTx.TxIn))
+	for _, txIn := range justiceTx.TxIn {
+		swept[txIn.PreviousOutPoint] = struct{}{}
+	}
+	isSwept := func(output *breachedOutput) bool {
+		if output == nil {
+			return false
+		}
+		_, ok := swept[output.outpoint]
+		return ok
+	}
+
+	var totalSwept btcutil.Amount
+	breakdowns := make([]*JusticeBreakdown, len(rets))
+	for i, ret := range rets {
+		breakdown := &JusticeBreakdown{}
+		if isSwept(ret.selfOutput) {
+			breakdown.SelfAmount = ret.selfOutput.amt
+		}
+		if isSwept(ret.revokedOutput) {
+			breakdown.RevokedAmount = ret.revokedOutput.amt
+		}
+		for _, htlc := range ret.htlcOutputs {
+			if !isSwept(htlc) {
+				continue
+			}
+			breakdown.HTLCAmounts = append(
+				breakdown.HTLCAmounts, JusticeHTLCAmount{
+					OutPoint: htlc.outpoint,
+					Amount:   htlc.amt,
+				},
+			)
+		}
+
+		breakdowns[i] = breakdown
+		totalSwept += breakdown.Swept()
+	}
+
+	var totalOut btcutil.Amount
+	for _, txOut := range justiceTx.TxOut {
+		totalOut += btcutil.Amount(txOut.Value)
+	}
+	totalFee := totalSwept - totalOut
+
+	var attributed btcutil.Amount
+	for _, breakdown := range breakdowns {
+		if totalSwept > 0 {
+			breakdown.FeePaid = btcutil.Amount(
+				int64(totalFee) * int64(breakdown.Swept()) /
+					int64(totalSwept),
+			)
+		}
+		attributed += breakdown.FeePaid
+	}
+	breakdowns[0].FeePaid += totalFee - attributed
+
+	for _, breakdown := range breakdowns {
+		breakdown.NetRecovered = breakdown.Swept() - breakdown.FeePaid
+	}
+
+	return breakdowns
+}
+
+// serveJustice creates and broadcasts a justice transaction sweeping the funds
+// of the given retributions. Any retribution cancelled in the meantime is left
+// out, and errRetributionCancelled is returned if none remain.
+func (b *breachArbiter) serveJustice(
+	rets []*retributionInfo) (*wire.MsgTx, error) {
+
+	// Once we enter the broadcast phase a retribution can no longer be
+	// cancelled, so we'll only serve those that haven't been already.
+	var toServe []*retributionInfo
+	for _, ret := range rets {
+		if b.beginJusticeBroadcast(&ret.chanPoint) {
+			toServe = append(toServe, ret)
+		}
+	}
+	if len(toServe) == 0 {
+		return nil, errRetributionCancelled
+	}
+
+	// Until the justice transaction has been broadcast, the retributions
+	// may still be cancelled, so we'll restore their phases on failure.
+	var broadcast bool
+	defer func() {
+		if broadcast {
+			return
+		}
+		for _, ret := range toServe {
+			b.abortJusticeBroadcast(&ret.chanPoint)
+		}
+	}()
+
+	// If a sweep script was already chosen for a prior justice
+	// transaction, e.g. one broadcast before a restart, then the new
+	// transaction replaces it and must pay to the same script.
+	kind := sweepTxNew
+	if len(toServe[0].sweepPkScript) != 0 {
+		kind = sweepTxReplacement
+	}
+
+	// With the breach transactions confirmed, we now create the justice tx
+	// which will claim ALL the funds within the channels.
+	justiceTx, err := b.createBatchJusticeTx(toServe, kind)
+	switch {
+	case err == lnwallet.ErrWalletLocked:
+		return nil, err
+	case err != nil:
+		return nil, fmt.Errorf("unable to create justice tx: %v", err)
+	}
+
+	// Persist the justice txid, along with a newly chosen sweep script,
+	// before broadcasting, so that any later replacement pays to the same
+	// script. Failing to do so only costs us a clean replacement, so we'll
+	// still serve justice.
+	b.recordJusticeTxid(toServe, justiceTx)
+
+	brarLog.Debugf("Broadcasting justice tx: %v",
+		newLogClosure(func() string {
+			return spew.Sdump(justiceTx)
+		}))
+
+	// Finally, broadcast the transaction, finalizing the channels'
+	// retribution against the cheating counterparty.
+	chanPoints := make([]wire.OutPoint, 0, len(toServe))
+	for _, ret := range toServe {
+		chanPoints = append(chanPoints, ret.chanPoint)
+	}
+	err = b.publishJustice(justiceTx)
+	b.recordBroadcastAttempt(chanPoints, err)
+	if err != nil {
+		var inputs []*breachedOutput
+		for _, ret := range toServe {
+			inputs = append(inputs, ret.selfOutput, ret.revokedOutput)
+		}
+		b.recordBroadcastFailure(inputs)
+
+		return nil, fmt.Errorf("unable to broadcast justice tx: %v",
+			err)
+	}
+	broadcast = true
+
+	return justiceTx, nil
+}
+
+// sweepTxKind distinguishes sweep transactions that replace a previously
+// crafted transaction from those that are genuinely new, which determines the
+// output script they pay to.
+type sweepTxKind uint8
+
+const (
+	// sweepTxNew denotes a transaction that doesn't conflict with any
+	// previously crafted sweep, e.g. the initial justice transaction, a
+	// CPFP child, or one of several transactions partitioning the breached
+	// outputs. Such transactions pay to a fresh script from the wallet to
+	// avoid address reuse.
+	sweepTxNew sweepTxKind = iota
+
+	// sweepTxReplacement denotes a transaction that replaces a previously
+	// crafted sweep by fee, i.e. an RBF bump. It reuses the output script
+	// of the transaction it replaces, so that the replacement differs only
+	// in the fee paid and no additional addresses are revealed.
+	sweepTxReplacement
+)
+
+// justiceSweepScript returns the output script a justice transaction of the
+// given kind should pay to, recording it within the retribution so that any
+// later replacement pays to the same script.
+func (b *breachArbiter) justiceSweepScript(r *retributionInfo,
+	kind sweepTxKind) ([]byte, error) {
+
+	switch kind {
+	case sweepTxNew:
+		pkScript, err := b.justiceInputs.sweepScript()
+		if err != nil {
+			return nil, err
+		}
+		r.sweepPkScript = pkScript
+
+		return pkScript, nil
+
+	case sweepTxReplacement:
+		if len(r.sweepPkScript) == 0 {
+			return nil, fmt.Errorf("unable to replace justice tx "+
+				"for ChannelPoint(%v): no prior sweep script",
+				r.chanPoint)
+		}
+
+		return r.sweepPkScript, nil
+
+	default:
+		return nil, fmt.Errorf("unknown sweep tx kind: %v", kind)
+	}
+}
+
+// justicePenaltyScript returns the output script the penalty of a justice
+// transaction of the given kind should be swept to, recording it within the
+// retribution so that any later replacement pays to the same script. Nil is
+// returned if the penalty isn't to be swept separately, in which case a
+// single output is swept to.
+func (b *breachArbiter) justicePenaltyScript(r *retributionInfo,
+	kind sweepTxKind) ([]byte, error) {
+
+	switch {
+	case kind == sweepTxReplacement:
+		return r.penaltyPkScript, nil
+
+	case b.cfg.PenaltySweepScriptGen == nil:
+		r.penaltyPkScript = nil
+		return nil, nil
+	}
+
+	pkScript, err := b.cfg.PenaltySweepScriptGen()
+	if err != nil {
+		return nil, err
+	}
+	r.penaltyPkScript = pkScript
+
+	return pkScript, nil
+}
+
+// sweepScript returns a fresh output script to sweep funds to, drawn from the
+// pool of pre-generated scripts if one is configured.
+func (b *breachArbiter) sweepScript() ([]byte, error) {
+	if b.sweepScripts != nil {
+		return b.sweepScripts.get()
+	}
+
+	return b.cfg.SweepScriptGen()
+}
+
+// sweepScriptPool holds fresh sweep scripts generated ahead of time, and tops
+// itself up in the background as they're handed out. Should the pool run dry,
+// scripts are generated on demand instead.
+type sweepScriptPool struct {
+	gen func() ([]byte, error)
+
+	// scripts buffers the pre-generated scripts. As each is received
+	// exactly once, no script is ever handed out twice.
+	scripts chan []byte
+
+	// refill signals the filler that scripts have been drawn from the
+	// pool.
+	refill chan struct{}
+
+	quit chan struct{}
+	wg   *sync.WaitGroup
+}
+
+// newSweepScriptPool creates a sweepScriptPool holding up to size scripts
+// generated by gen. The filler exits once quit is closed, and is tracked by
+// wg.
+func newSweepScriptPool(size int, gen func() ([]byte, error),
+	quit chan struct{}, wg *sync.WaitGroup) *sweepScriptPool {
+
+	return &sweepScriptPool{
+		gen:     gen,
+		scripts: make(chan []byte, size),
+		refill:  make(chan struct{}, 1),
+		quit:    quit,
+		wg:      wg,
+	}
+}
+
+// start launches the goroutine filling the pool.
+func (p *sweepScriptPool) start() {
+	p.wg.Add(1)
+	go p.fill()
+}
+
+// fill tops up the pool each time scripts are drawn from it.
+//
+// NOTE: This MUST be run as a goroutine.
+func (p *sweepScriptPool) fill() {
+	defer p.wg.Done()
+
+	for {
+		// As the filler is the only goroutine adding scripts to the
+		// pool, it never generates more than there's room for.
+		for len(p.scripts) < cap(p.scripts) {
+			script, err := p.gen()
+			if err != nil {
+				brarLog.Errorf("unable to pre-generate sweep "+
+					"script: %v", err)
+				break
+			}
+
+			p.scripts <- script
+		}
+
+		select {
+		case <-p.refill:
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// get hands out a fresh script from the pool, generating one on demand should
+// the pool be empty.
+func (p *sweepScriptPool) get() ([]byte, error) {
+	defer func() {
+		select {
+		case p.refill <- struct{}{}:
+		default:
+		}
+	}()
+
+	select {
+	case script := <-p.scripts:
+		return script, nil
+	default:
+		return p.gen()
+	}
+}
+
+// justiceTxInputs are the sources of the inputs to a justice transaction which
+// vary from one run to the next. They default to the breach arbiter's own fee
+// estimation and sweep script generation, and are only ever overridden by
+// tests, which pin them so that justice transactions are reproduced
+// byte-for-byte.
+type justiceTxInputs struct {
+	// fee returns the fee paid by a justice transaction spending the
+	// given inputs at the given bump tier.
+	fee func(inputs []*breachedOutput, tier uint32) btcutil.Amount
+
+	// sweepScript returns a fresh output script to sweep funds to.
+	sweepScript func() ([]byte, error)
+}
+
+// createJusticeTx creates a transaction which exacts "justice" by sweeping ALL
+// the funds within the channel which we are now entitled to due to a breach of
+// the channel's contract by the counterparty. This function returns a *fully*
+// signed transaction with the witness for each input fully in place.
+//
+// The kind of the transaction determines its output script: a new justice
+// transaction pays to a fresh script from the wallet, while a replacement of
+// a prior justice transaction pays to the same script as the one it replaces.
+func (b *breachArbiter) createJusticeTx(r *retributionInfo,
+	kind sweepTxKind) (*wire.MsgTx, error) {
+
+	return b.createBatchJusticeTx([]*retributionInfo{r}, kind)
+}
+
+// createBatchJusticeTx creates a single justice transaction sweeping the funds
+// of each of the given retributions, which may stem from distinct breach
+// transactions, into one output. The output script is chosen as described
+// within createJusticeTx, using the first retribution, and recorded within
+// all of them.
+func (b *breachArbiter) createBatchJusticeTx(rets []*retributionInfo,
+	kind sweepTxKind) (*wire.MsgTx, error) {
+
+	if len(rets) == 0 {
+		return nil, errors.New("no retributions to serve")
+	}
+
+	// First, we obtain the public key script which we'll sweep the funds
+	// to.
+	// TODO(roasbeef): possibly create many outputs to minimize change in
+	// the future?
+	pkScriptOfJustice, err := b.justiceSweepScript(rets[0], kind)
+	if err != nil {
+		return nil, err
+	}
+	penaltyPkScript, err := b.justicePenaltyScript(rets[0], kind)
+	if err != nil {
+		return nil, err
+	}
+
+	// An HTLC output whose witness can't be generated, e.g. as its
+	// persisted signing material is incomplete, mustn't prevent us from
+	// sweeping the remaining outputs, so it's skipped.
+	for _, r := range rets {
+		for _, htlc := range append([]*breachedOutput(nil),
+			r.htlcOutputs...) {
+
+			if err := htlc.checkSigningMaterial(); err != nil {
+				b.skipHTLCOutput(r, htlc, err)
+			}
+		}
+	}
+
+	for {
+		justiceTx, failedHTLC, err := b.signBatchJusticeTx(
+			rets, pkScriptOfJustice, penaltyPkScript,
+		)
+
+		// A locked wallet can't sign any input, so no HTLC output is
+		// at fault.
+		if failedHTLC == nil || err == lnwallet.ErrWalletLocked {
+			return justiceTx, err
+		}
+
+		// As the signatures of all inputs commit to the full set of
+		// inputs, the transaction is rebuilt without the failed HTLC
+		// output.
+		for _, r := range rets {
+			for _, htlc := range r.htlcOutputs {
+				if htlc == failedHTLC {
+					b.skipHTLCOutput(r, htlc, err)
+					break
+				}
+			}
+		}
+	}
+}
+
+// signBatchJusticeTx creates and signs a justice transaction sweeping the
+// outputs of the given retributions to the given script, sweeping the penalty
+// separately to penaltyPkScript if non-nil. Should the witness of an HTLC
+// output fail to be generated, that output is returned along with the error.
+func (b *breachArbiter) signBatchJusticeTx(rets []*retributionInfo,
+	pkScriptOfJustice, penaltyPkScript []byte) (*wire.MsgTx,
+	*breachedOutput, error) {
+
+	// Each retribution contributes both of its commitment outputs, along
+	// with any HTLC outputs, which may belong to a different transaction
+	// than the breach transaction itself.
+	signer := &b.cfg.Signer
+	var (
+		inputs   []*breachedOutput
+		totalAmt btcutil.Amount
+	)
+	htlcInputs := make(map[*breachedOutput]struct{})
+	selfInputs := make(map[*breachedOutput]struct{})
+	revokedInputs := make(map[*breachedOutput]struct{})
+	for _, r := range rets {
+		r.sweepPkScript = pkScriptOfJustice
+		r.penaltyPkScript = penaltyPkScript
+		selfInputs[r.selfOutput] = struct{}{}
+		revokedInputs[r.revokedOutput] = struct{}{}
+
+		r.selfOutput.witnessFunc = r.selfOutput.genWitnessFunc(signer)
+		r.revokedOutput.witnessFunc = r.revokedOutput.genWitnessFunc(
+			signer,
+		)
+		for i := range r.htlcOutputs {
+			r.htlcOutputs[i].witnessFunc = r.htlcOutputs[i].genWitnessFunc(
+				signer,
+			)
+			htlcInputs[r.htlcOutputs[i]] = struct{}{}
+		}
+
+		inputs = append(inputs, r.selfOutput, r.revokedOutput)
+		totalAmt += r.selfOutput.amt + r.revokedOutput.amt
+
+		for _, htlc := range r.htlcOutputs {
+			inputs = append(inputs, htlc)
+			totalAmt += htlc.amt
+		}
+	}
+
+	// An HTLC output whose value doesn't cover the fee its inclusion adds
+	// to the justice transaction would only reduce the value recovered,
+	// so it's left out, as is our own output if so configured. Each input
+	// is judged on its own, so that a small output never holds back the
+	// sweep of the revoked output, which is always included. Unlike a
+	// skipped output, a left out output remains part of the retribution,
+	// and may be swept by a justice transaction crafted at a lower fee
+	// rate.
+	tier := rets[0].bumpTier
+	confTarget := b.justiceConfTarget(inputs)
+	economic := make([]*breachedOutput, 0, len(inputs))
+	for _, input := range inputs {
+		_, isHTLC := htlcInputs[input]
+		_, isSelf := selfInputs[input]
+
+		var kind string
+		switch {
+		case isHTLC:
+			kind = "HTLC"
+		case isSelf && b.cfg.SkipUneconomicSelfOutput:
+			kind = "self"
+		}
+
+		if kind != "" {
+			fee := b.justiceInputFee(input, confTarget, tier)
+			if input.amt <= fee {
+				brarLog.Infof("Leaving %v output %v worth %v "+
+					"out of justice tx, as sweeping it "+
+					"would cost %v in fees", kind,
+					input.outpoint, input.amt, fee)
+
+				totalAmt -= input.amt
+				continue
+			}
+		}
+
+		economic = append(economic, input)
+	}
+	inputs = economic
+
+	// The penalty is the value of the revoked and HTLC outputs, which
+	// stem from the breaching party's balance, while our own output makes
+	// up the remainder.
+	var penaltyAmt btcutil.Amount
+	for _, input := range inputs {
+		_, isHTLC := htlcInputs[input]
+		_, isRevoked := revokedInputs[input]
+		if isHTLC || isRevoked {
+			penaltyAmt += input.amt
+		}
+	}
+
+	// Before creating the actual TxOut, we'll need to calculate the proper
+	// fee to attach to the transaction to ensure a timely confirmation.
+	// Any marker output adds to the weight of the transaction, so its
+	// marginal fee is added as well, though being worthless, it doesn't
+	// take a share of the amount swept.
+	fee := b.justiceInputs.fee(inputs, tier)
+	markerScript, err := b.justiceMarkerScript()
+	if err != nil {
+		return nil, nil, err
+	}
+	if markerScript != nil {
+		markerWeight := extraOutputWeight(markerScript)
+		fee += (b.feePerWeight(confTarget) *
+			btcutil.Amount(markerWeight)) << tier
+	}
+	var penaltyOutputFee btcutil.Amount
+	if penaltyPkScript != nil {
+		penaltyWeight := extraOutputWeight(penaltyPkScript)
+		penaltyOutputFee = (b.feePerWeight(confTarget) *
+			btcutil.Amount(penaltyWeight)) << tier
+	}
+	sweepOutputs, err := b.justiceOutputs(
+		totalAmt, penaltyAmt, fee, penaltyOutputFee,
+		pkScriptOfJustice, penaltyPkScript,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// With the fee calculated, we can now create the justice transaction
+	// using the information gathered above. The sweep outputs must come
+	// first, as the first is the one spent by any CPFP child.
+	justiceTx := wire.NewMsgTx(2)
+	for _, txOut := range sweepOutputs {
+		justiceTx.AddTxOut(txOut)
+	}
+	if markerScript != nil {
+		justiceTx.AddTxOut(&wire.TxOut{
+			PkScript: markerScript,
+			Value:    0,
+		})
+	}
+	for _, input := range inputs {
+		justiceTx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: input.outpoint,
+		})
+	}
+
+	hashCache := txscript.NewTxSigHashes(justiceTx)
+
+	// Finally, using the witness generation functions attached to the
+	// retribution information, we'll populate the inputs with fully valid
+	// witnesses for both commitment outputs, and all the pending HTLCs at
+	// this state in the channel's history.
+	// TODO(roasbeef): handle the 2-layer HTLCs
+	witnesses, failed, err := b.generateWitnesses(
+		justiceTx, hashCache, inputs,
+	)
+	if err != nil {
+		if _, ok := htlcInputs[inputs[failed]]; ok {
+			return nil, inputs[failed], err
+		}
+		return nil, nil, err
+	}
+	for i, witness := range witnesses {
+		justiceTx.TxIn[i].Witness = witness
+	}
+
+	return justiceTx, nil, nil
+}
+
+// justiceOutputs returns the sweep outputs of a justice transaction sweeping
+// totalAmt, of which penaltyAmt is the penalty, while paying the given fee.
+// Should a penalty script be given, the penalty is swept to it separately from
+// the remainder, which is swept to pkScript, with the fee, plus the
+// penaltyOutputFee added by the extra output, apportioned between the two in
+// proportion to their value. Should either output be dust, the two are swept
+// to a single output paying to pkScript instead, unless the entire amount is
+// the penalty, in which case it's paid to the penalty script.
+func (b *breachArbiter) justiceOutputs(totalAmt, penaltyAmt, fee,
+	penaltyOutputFee btcutil.Amount, pkScript,
+	penaltyPkScript []byte) ([]*wire.TxOut, error) {
+
+	selfAmt := totalAmt - penaltyAmt
+
+	switch {
+	case penaltyPkScript != nil && selfAmt == 0:
+		pkScript = penaltyPkScript
+
+	case penaltyPkScript != nil:
+		splitFee := fee + penaltyOutputFee
+		penaltyFee := btcutil.Amount(
+			float64(splitFee) * float64(penaltyAmt) /
+				float64(totalAmt),
+		)
+
+		selfAmts, err := b.distributeSweep(
+			selfAmt, splitFee-penaltyFee, 1,
+		)
+		if err != nil && err != ErrSweepOutputDust {
+			return nil, err
+		}
+		penaltyAmts, penaltyErr := b.distributeSweep(
+			penaltyAmt, penaltyFee, 1,
+		)
+		if penaltyErr != nil && penaltyErr != ErrSweepOutputDust {
+			return nil, penaltyErr
+		}
+
+		if err == nil && penaltyErr == nil {
+			return []*wire.TxOut{
+				{
+					PkScript: pkScript,
+					Value:    int64(selfAmts[0]),
+				},
+				{
+					PkScript: penaltyPkScript,
+					Value:    int64(penaltyAmts[0]),
+				},
+			}, nil
+		}
+
+		brarLog.Infof("Sweeping penalty of %v along with our own "+
+			"funds of %v, as sweeping them separately would "+
+			"create a dust output", penaltyAmt, selfAmt)
+	}
+
+	outputAmts, err := b.distributeSweep(totalAmt, fee, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*wire.TxOut{{
+		PkScript: pkScript,
+		Value:    int64(outputAmts[0]),
+	}}, nil
+}
+
+// generateWitnesses generates the witness of each of the given inputs, spent
+// by the transaction at the same index. Up to JusticeSigningWorkers witnesses
+// are generated concurrently. Should any input fail to be signed, the index of
+// the first such input is returned along with its error.
+func (b *breachArbiter) generateWitnesses(tx *wire.MsgTx,
+	hashCache *txscript.TxSigHashes,
+	inputs []*breachedOutput) ([]wire.TxWitness, int, error) {
+
+	witnesses := make([]wire.TxWitness, len(inputs))
+
+	numWorkers := b.cfg.JusticeSigningWorkers
+	if numWorkers > len(inputs) {
+		numWorkers = len(inputs)
+	}
+	if numWorkers <= 1 {
+		for i, input := range inputs {
+			witness, err := input.witnessFunc(tx, hashCache, i)
+			b.recordWitnessResult(input.witnessType, err)
+			if err != nil {
+				return nil, i, err
+			}
+			witnesses[i] = witness
+		}
+
+		return witnesses, 0, nil
+	}
+
+	// The inputs are handed out in order, and none are handed out once
+	// any has failed. Thus every input preceding the first failure is
+	// signed, and it's that failure which is reported, as when signing
+	// serially.
+	var (
+		wg     sync.WaitGroup
+		next   int32 = -1
+		failed int32
+		errs   = make([]error, len(inputs))
+	)
+	wg.Add(numWorkers)
+	for w := 0; w < numWorkers; w++ {
+		go func() {
+			defer wg.Done()
+
+			for atomic.LoadInt32(&failed) == 0 {
+				i := int(atomic.AddInt32(&next, 1))
+				if i >= len(inputs) {
+					return
+				}
+
+				input := inputs[i]
+				witness, err := input.witnessFunc(
+					tx, hashCache, i,
+				)
+				b.recordWitnessResult(input.witnessType, err)
+				if err != nil {
+					errs[i] = err
+					atomic.StoreInt32(&failed, 1)
+					return
+				}
+				witnesses[i] = witness
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, i, err
+		}
+	}
+
+	return witnesses, 0, nil
+}
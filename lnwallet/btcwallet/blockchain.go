@@ -2,7 +2,6 @@ package btcwallet
 
 import (
 	"encoding/hex"
-	"errors"
 	"fmt"
 
 	"github.com/roasbeef/btcd/chaincfg/chainhash"
@@ -17,7 +16,7 @@ import (
 var (
 	// ErrOutputSpent is returned by the GetUtxo method if the target output
 	// for lookup has already been spent.
-	ErrOutputSpent = errors.New("target output has been spent")
+	ErrOutputSpent = lnwallet.ErrOutputSpent
 )
 
 // GetBestBlock returns the current height and hash of the best known block
@@ -87,7 +87,12 @@ func (b *BtcWallet) fetchPrivKey(pub *btcec.PublicKey) (*btcec.PrivateKey, error
 		return nil, err
 	}
 
-	return b.wallet.PrivKeyForAddress(addr)
+	privKey, err := b.wallet.PrivKeyForAddress(addr)
+	if waddrmgr.IsError(err, waddrmgr.ErrLocked) {
+		return nil, lnwallet.ErrWalletLocked
+	}
+
+	return privKey, err
 }
 
 // maybeTweakPrivKey examines the single and double tweak parameters on the
@@ -168,7 +173,9 @@ func (b *BtcWallet) ComputeInputScript(tx *wire.MsgTx,
 
 	pka := walletAddr.(waddrmgr.ManagedPubKeyAddress)
 	privKey, err := pka.PrivKey()
-	if err != nil {
+	if waddrmgr.IsError(err, waddrmgr.ErrLocked) {
+		return nil, lnwallet.ErrWalletLocked
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -52,6 +52,11 @@ var (
 	// ErrInsufficientBalance is returned when a proposed HTLC would
 	// exceed the available balance.
 	ErrInsufficientBalance = fmt.Errorf("insufficient local balance")
+
+	// ErrNotRevokedCommitment is returned when a transaction presented as
+	// a breach isn't a revoked commitment transaction of the channel.
+	ErrNotRevokedCommitment = fmt.Errorf("transaction is not a revoked " +
+		"commitment of the channel")
 )
 
 // channelState is an enum like type which represents the current state of a
@@ -1032,6 +1037,32 @@ type HtlcRetribution struct {
 	// OutPoint is the target outpoint of this HTLC pointing to the
 	// breached commitment transaction.
 	OutPoint wire.OutPoint
+
+	// IsIncoming is true if the HTLC was offered to us by the remote
+	// party, in which case its output pays to the offerer's version of the
+	// HTLC script. Otherwise, the HTLC was offered by us, and its output
+	// pays to the receiver's version of the script. As the revocation
+	// clauses of the two scripts differ, this determines the witness type
+	// used to sweep the output.
+	IsIncoming bool
+
+	// RefundTimeout is the absolute height at which the offerer of the
+	// HTLC may reclaim it via its timeout clause.
+	RefundTimeout uint32
+
+	// PaymentHash is the payment hash of the HTLC, identifying the
+	// circuit it forms part of within the switch.
+	PaymentHash [32]byte
+}
+
+// WitnessType returns the witness type capable of sweeping the HTLC output via
+// the revocation clause of its script.
+func (h *HtlcRetribution) WitnessType() WitnessType {
+	if h.IsIncoming {
+		return HtlcOfferedRevoke
+	}
+
+	return HtlcAcceptedRevoke
 }
 
 // BreachRetribution contains all the data necessary to bring a channel
@@ -1073,6 +1104,11 @@ type BreachRetribution struct {
 	// party within the breach transaction.
 	RemoteOutpoint wire.OutPoint
 
+	// RemoteDelay is the CSV delay, in blocks, after which the remote
+	// party may sweep their revoked output via its delayed path. Our
+	// justice transaction must confirm before then.
+	RemoteDelay uint32
+
 	// HtlcRetributions is a slice of HTLC retributions for each output
 	// active HTLC output within the breached commitment transaction.
 	HtlcRetributions []HtlcRetribution
@@ -1162,8 +1198,16 @@ func newBreachRetribution(chanState *channeldb.OpenChannel, stateNum uint64,
 	// With the commitment outputs located, we'll now generate all the
 	// retribution structs for each of the HTLC transactions active on the
 	// remote commitment transaction.
-	htlcRetributions := make([]HtlcRetribution, len(chanState.Htlcs))
-	for i, htlc := range revokedSnapshot.Htlcs {
+	htlcRetributions := make(
+		[]HtlcRetribution, 0, len(revokedSnapshot.Htlcs),
+	)
+	for _, htlc := range revokedSnapshot.Htlcs {
+		// HTLCs which were dust within this state have no output on
+		// the commitment transaction, so there's nothing to sweep.
+		if htlc.OutputIndex < 0 {
+			continue
+		}
+
 		var (
 			htlcScript []byte
 			err        error
@@ -1173,7 +1217,7 @@ func newBreachRetribution(chanState *channeldb.OpenChannel, stateNum uint64,
 		// the sender of the HTLC (relative to us). So we'll
 		// re-generate the sender HTLC script.
 		if htlc.Incoming {
-			htlcScript, err = senderHTLCScript(localKey, remoteKey,
+			htlcScript, err = senderHTLCScript(remoteKey, localKey,
 				revocationKey, htlc.RHash[:])
 			if err != nil {
 				return nil, err
@@ -1192,13 +1236,19 @@ func newBreachRetribution(chanState *channeldb.OpenChannel, stateNum uint64,
 			}
 		}
 
-		htlcRetributions[i] = HtlcRetribution{
+		htlcWitnessHash, err := witnessScriptHash(htlcScript)
+		if err != nil {
+			return nil, err
+		}
+
+		htlcRetributions = append(htlcRetributions, HtlcRetribution{
 			SignDesc: SignDescriptor{
 				PubKey:        chanState.LocalChanCfg.RevocationBasePoint,
 				DoubleTweak:   commitmentSecret,
 				WitnessScript: htlcScript,
 				Output: &wire.TxOut{
-					Value: int64(htlc.Amt.ToSatoshis()),
+					PkScript: htlcWitnessHash,
+					Value:    int64(htlc.Amt.ToSatoshis()),
 				},
 				HashType: txscript.SigHashAll,
 			},
@@ -1206,7 +1256,10 @@ func newBreachRetribution(chanState *channeldb.OpenChannel, stateNum uint64,
 				Hash:  commitHash,
 				Index: uint32(htlc.OutputIndex),
 			},
-		}
+			IsIncoming:    htlc.Incoming,
+			RefundTimeout: htlc.RefundTimeout,
+			PaymentHash:   htlc.RHash,
+		})
 	}
 
 	// We'll need to reconstruct the single tweak so we can sweep our
@@ -1243,10 +1296,198 @@ func newBreachRetribution(chanState *channeldb.OpenChannel, stateNum uint64,
 			},
 			HashType: txscript.SigHashAll,
 		},
+		RemoteDelay:      remoteDelay,
 		HtlcRetributions: htlcRetributions,
 	}, nil
 }
 
+// NewBreachRetributionFromBackup reconstructs the BreachRetribution for the
+// given breach transaction, as observed on chain, using only the static backup
+// of the channel. The transaction is validated to be a revoked commitment of
+// the channel: it must spend the funding outpoint, encode a state number whose
+// revocation secret we hold, and pay to the scripts of that state.
+//
+// As the backup lacks the revocation log, the HTLCs active within the revoked
+// state are unknown, and so no HTLC retributions are reconstructed. The values
+// of the commitment outputs are instead taken from the transaction itself.
+func NewBreachRetributionFromBackup(backup *channeldb.ChannelBackup,
+	breachTx *wire.MsgTx) (*BreachRetribution, error) {
+
+	if len(breachTx.TxIn) != 1 ||
+		breachTx.TxIn[0].PreviousOutPoint != backup.FundingOutpoint {
+
+		return nil, ErrNotRevokedCommitment
+	}
+
+	// Recover the state number encoded within the transaction, which is
+	// obfuscated with the payment base points of the initiator and
+	// responder.
+	var obfuscator [StateHintSize]byte
+	if backup.IsInitiator {
+		obfuscator = deriveStateHintObfuscator(
+			backup.LocalChanCfg.PaymentBasePoint,
+			backup.RemoteChanCfg.PaymentBasePoint,
+		)
+	} else {
+		obfuscator = deriveStateHintObfuscator(
+			backup.RemoteChanCfg.PaymentBasePoint,
+			backup.LocalChanCfg.PaymentBasePoint,
+		)
+	}
+	stateNum := GetStateNumHint(breachTx, obfuscator)
+
+	// We only hold the revocation secrets of revoked states, so failing to
+	// derive the commitment of this state means this isn't a revoked
+	// state.
+	commit, err := deriveBackupCommitment(backup, stateNum)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both commitment outputs must be present within the transaction for
+	// it to be a commitment of this state that we're able to sweep.
+	commitHash := breachTx.TxHash()
+	var localOutput, remoteOutput *wire.TxOut
+	localOutpoint := wire.OutPoint{Hash: commitHash}
+	remoteOutpoint := wire.OutPoint{Hash: commitHash}
+	for i, txOut := range breachTx.TxOut {
+		switch {
+		case bytes.Equal(txOut.PkScript, commit.localPkScript):
+			localOutput = txOut
+			localOutpoint.Index = uint32(i)
+		case bytes.Equal(txOut.PkScript, commit.remotePkScript):
+			remoteOutput = txOut
+			remoteOutpoint.Index = uint32(i)
+		}
+	}
+	if localOutput == nil || remoteOutput == nil {
+		return nil, ErrNotRevokedCommitment
+	}
+
+	commit.localSignDesc.Output.Value = localOutput.Value
+	commit.remoteSignDesc.Output.Value = remoteOutput.Value
+
+	return &BreachRetribution{
+		BreachTransaction:    breachTx,
+		RevokedStateNum:      stateNum,
+		LocalOutpoint:        localOutpoint,
+		LocalOutputSignDesc:  commit.localSignDesc,
+		RemoteOutpoint:       remoteOutpoint,
+		RemoteOutputSignDesc: commit.remoteSignDesc,
+		RemoteDelay:          commit.remoteDelay,
+	}, nil
+}
+
+// DeriveBreachSignDescs re-derives the sign descriptors of our own output and
+// the remote party's revoked output within the remote party's commitment at
+// the given revoked state, using only the static backup of the channel. As the
+// backup doesn't record the values of the outputs, they're provided by the
+// caller. ErrNotRevokedCommitment is returned if we don't hold the revocation
+// secret of the state.
+func DeriveBreachSignDescs(backup *channeldb.ChannelBackup, stateNum uint64,
+	localAmt, remoteAmt btcutil.Amount) (*SignDescriptor, *SignDescriptor,
+	error) {
+
+	commit, err := deriveBackupCommitment(backup, stateNum)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	commit.localSignDesc.Output.Value = int64(localAmt)
+	commit.remoteSignDesc.Output.Value = int64(remoteAmt)
+
+	return &commit.localSignDesc, &commit.remoteSignDesc, nil
+}
+
+// backupCommitment describes the outputs of the remote party's commitment at a
+// revoked state, as derived from the static backup of the channel.
+type backupCommitment struct {
+	// localPkScript and remotePkScript are the output scripts of our own
+	// output and the remote party's revoked output.
+	localPkScript  []byte
+	remotePkScript []byte
+
+	// localSignDesc and remoteSignDesc are the sign descriptors of the
+	// outputs, whose values are left zero.
+	localSignDesc  SignDescriptor
+	remoteSignDesc SignDescriptor
+
+	// remoteDelay is the relative timelock of the revoked output.
+	remoteDelay uint32
+}
+
+// deriveBackupCommitment derives the outputs of the remote party's commitment
+// at the given revoked state from the static backup of the channel.
+func deriveBackupCommitment(backup *channeldb.ChannelBackup,
+	stateNum uint64) (*backupCommitment, error) {
+
+	// We only hold the revocation secrets of revoked states, so failing to
+	// find one means this isn't a revoked state.
+	revocationPreimage, err := backup.RevocationStore.LookUp(stateNum)
+	if err != nil {
+		return nil, ErrNotRevokedCommitment
+	}
+	commitmentSecret, commitmentPoint := btcec.PrivKeyFromBytes(btcec.S256(),
+		revocationPreimage[:])
+
+	// With the commitment point known, we reconstruct the scripts of both
+	// commitment outputs as they were at this state.
+	localKey := TweakPubKey(backup.LocalChanCfg.PaymentBasePoint,
+		commitmentPoint)
+	remoteDelayKey := TweakPubKey(backup.RemoteChanCfg.DelayBasePoint,
+		commitmentPoint)
+	revocationKey := DeriveRevocationPubkey(
+		backup.LocalChanCfg.RevocationBasePoint,
+		commitmentPoint,
+	)
+
+	remoteDelay := uint32(backup.RemoteChanCfg.CsvDelay)
+	remotePkScript, err := commitScriptToSelf(remoteDelay, remoteDelayKey,
+		revocationKey)
+	if err != nil {
+		return nil, err
+	}
+	remoteWitnessHash, err := witnessScriptHash(remotePkScript)
+	if err != nil {
+		return nil, err
+	}
+	localPkScript, err := commitScriptUnencumbered(localKey)
+	if err != nil {
+		return nil, err
+	}
+	localWitnessHash, err := witnessScriptHash(localPkScript)
+	if err != nil {
+		return nil, err
+	}
+
+	singleTweak := SingleTweakBytes(commitmentPoint,
+		backup.LocalChanCfg.PaymentBasePoint)
+
+	return &backupCommitment{
+		localPkScript:  localPkScript,
+		remotePkScript: remoteWitnessHash,
+		localSignDesc: SignDescriptor{
+			SingleTweak:   singleTweak,
+			PubKey:        backup.LocalChanCfg.PaymentBasePoint,
+			WitnessScript: localPkScript,
+			Output: &wire.TxOut{
+				PkScript: localWitnessHash,
+			},
+			HashType: txscript.SigHashAll,
+		},
+		remoteSignDesc: SignDescriptor{
+			PubKey:        backup.LocalChanCfg.RevocationBasePoint,
+			DoubleTweak:   commitmentSecret,
+			WitnessScript: remotePkScript,
+			Output: &wire.TxOut{
+				PkScript: remoteWitnessHash,
+			},
+			HashType: txscript.SigHashAll,
+		},
+		remoteDelay: remoteDelay,
+	}, nil
+}
+
 // closeObserver is a goroutine which watches the network for any spends of the
 // multi-sig funding output. A spend from the multi-sig output may occur under
 // the following three scenarios: a cooperative close, a unilateral close, and
@@ -3820,6 +4061,15 @@ func (lc *LightningChannel) StateSnapshot() *channeldb.ChannelSnapshot {
 	return lc.channelState.Snapshot()
 }
 
+// Backup returns a static backup of the channel's current state, from which
+// the breach of any of its revoked states can be reconstructed.
+func (lc *LightningChannel) Backup() *channeldb.ChannelBackup {
+	lc.RLock()
+	defer lc.RUnlock()
+
+	return lc.channelState.Backup()
+}
+
 // UpdateFee initiates a fee update for this channel. Must only be called by
 // the channel initiator, and must be called before sending update_fee to
 // the remote.
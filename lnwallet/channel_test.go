@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
@@ -2159,3 +2160,270 @@ func TestUpdateFeeMultipleUpdates(t *testing.T) {
 		t.Fatalf("bob unable to process alive's revocation: %v", err)
 	}
 }
+
+// TestBreachRetributionFromBackup asserts that the retribution for a revoked
+// commitment can be reconstructed from the channel's static backup alone, and
+// that commitments which haven't been revoked are rejected.
+func TestBreachRetributionFromBackup(t *testing.T) {
+	t.Parallel()
+
+	aliceChannel, bobChannel, cleanUp, err := createTestChannels(1)
+	if err != nil {
+		t.Fatalf("unable to create test channels: %v", err)
+	}
+	defer cleanUp()
+
+	// Transition to a new state, then grab Bob's commitment for it, which
+	// we'll revoke by transitioning to yet another state.
+	var revokedCommit *wire.MsgTx
+	htlcAmt := lnwire.NewMSatFromSatoshis(btcutil.SatoshiPerBitcoin)
+	for i := 0; i < 2; i++ {
+		htlc, _ := createHTLC(i, htlcAmt)
+		if _, err := aliceChannel.AddHTLC(htlc); err != nil {
+			t.Fatalf("unable to add htlc: %v", err)
+		}
+		if _, err := bobChannel.ReceiveHTLC(htlc); err != nil {
+			t.Fatalf("unable to recv htlc: %v", err)
+		}
+		err := forceStateTransition(aliceChannel, bobChannel)
+		if err != nil {
+			t.Fatalf("unable to complete state transition: %v", err)
+		}
+
+		if i == 0 {
+			revokedCommit, err = bobChannel.getSignedCommitTx()
+			if err != nil {
+				t.Fatalf("unable to obtain bob's "+
+					"commitment: %v", err)
+			}
+		}
+	}
+
+	backup := aliceChannel.channelState.Backup()
+	retribution, err := NewBreachRetributionFromBackup(
+		backup, revokedCommit,
+	)
+	if err != nil {
+		t.Fatalf("unable to reconstruct retribution: %v", err)
+	}
+
+	// The reconstructed retribution should match the one derived from
+	// the full channel state.
+	expRetribution, err := newBreachRetribution(
+		aliceChannel.channelState, 1, revokedCommit,
+	)
+	if err != nil {
+		t.Fatalf("unable to derive retribution: %v", err)
+	}
+	if retribution.RevokedStateNum != 1 {
+		t.Fatalf("expected revoked state 1, got %v",
+			retribution.RevokedStateNum)
+	}
+	if retribution.LocalOutpoint != expRetribution.LocalOutpoint ||
+		retribution.RemoteOutpoint != expRetribution.RemoteOutpoint {
+
+		t.Fatalf("outpoints don't match: expected %v/%v, got %v/%v",
+			expRetribution.LocalOutpoint,
+			expRetribution.RemoteOutpoint,
+			retribution.LocalOutpoint, retribution.RemoteOutpoint)
+	}
+	if !reflect.DeepEqual(retribution.LocalOutputSignDesc,
+		expRetribution.LocalOutputSignDesc) {
+
+		t.Fatalf("local sign descriptors don't match")
+	}
+	if !reflect.DeepEqual(retribution.RemoteOutputSignDesc,
+		expRetribution.RemoteOutputSignDesc) {
+
+		t.Fatalf("remote sign descriptors don't match")
+	}
+	expDelay := uint32(aliceChannel.channelState.RemoteChanCfg.CsvDelay)
+	if retribution.RemoteDelay != expDelay ||
+		expRetribution.RemoteDelay != expDelay {
+
+		t.Fatalf("expected remote delay %v, got %v/%v", expDelay,
+			retribution.RemoteDelay, expRetribution.RemoteDelay)
+	}
+
+	// The sign descriptors may also be re-derived from the backup given
+	// only the state number and the values of the outputs.
+	localDesc, remoteDesc, err := DeriveBreachSignDescs(
+		backup, 1,
+		btcutil.Amount(expRetribution.LocalOutputSignDesc.Output.Value),
+		btcutil.Amount(expRetribution.RemoteOutputSignDesc.Output.Value),
+	)
+	if err != nil {
+		t.Fatalf("unable to derive sign descriptors: %v", err)
+	}
+	if !reflect.DeepEqual(*localDesc, expRetribution.LocalOutputSignDesc) {
+		t.Fatalf("derived local sign descriptor doesn't match")
+	}
+	if !reflect.DeepEqual(*remoteDesc, expRetribution.RemoteOutputSignDesc) {
+		t.Fatalf("derived remote sign descriptor doesn't match")
+	}
+	_, _, err = DeriveBreachSignDescs(backup, 2, 0, 0)
+	if err != ErrNotRevokedCommitment {
+		t.Fatalf("expected ErrNotRevokedCommitment, got %v", err)
+	}
+
+	// Bob's current commitment hasn't been revoked, so it must be
+	// rejected, as must any transaction not spending the funding output.
+	currentCommit, err := bobChannel.getSignedCommitTx()
+	if err != nil {
+		t.Fatalf("unable to obtain bob's commitment: %v", err)
+	}
+	_, err = NewBreachRetributionFromBackup(backup, currentCommit)
+	if err != ErrNotRevokedCommitment {
+		t.Fatalf("expected ErrNotRevokedCommitment, got %v", err)
+	}
+
+	unrelatedTx := revokedCommit.Copy()
+	unrelatedTx.TxIn[0].PreviousOutPoint.Index++
+	_, err = NewBreachRetributionFromBackup(backup, unrelatedTx)
+	if err != ErrNotRevokedCommitment {
+		t.Fatalf("expected ErrNotRevokedCommitment, got %v", err)
+	}
+}
+
+// TestBreachRetributionHtlcs asserts that the retribution for a revoked
+// commitment bearing both an HTLC offered by the breaching party and one
+// offered to them distinguishes the two, and that the witness generated for
+// each HTLC output validates against the output's script.
+func TestBreachRetributionHtlcs(t *testing.T) {
+	t.Parallel()
+
+	aliceChannel, bobChannel, cleanUp, err := createTestChannels(1)
+	if err != nil {
+		t.Fatalf("unable to create test channels: %v", err)
+	}
+	defer cleanUp()
+
+	// Alice offers an HTLC to Bob, and Bob one to Alice, both of which
+	// are locked in within Bob's commitment, which we'll then revoke.
+	htlcAmt := lnwire.NewMSatFromSatoshis(btcutil.SatoshiPerBitcoin)
+	aliceHtlc, _ := createHTLC(0, htlcAmt)
+	if _, err := aliceChannel.AddHTLC(aliceHtlc); err != nil {
+		t.Fatalf("unable to add htlc: %v", err)
+	}
+	if _, err := bobChannel.ReceiveHTLC(aliceHtlc); err != nil {
+		t.Fatalf("unable to recv htlc: %v", err)
+	}
+	bobHtlc, _ := createHTLC(1, htlcAmt/2)
+	if _, err := bobChannel.AddHTLC(bobHtlc); err != nil {
+		t.Fatalf("unable to add htlc: %v", err)
+	}
+	if _, err := aliceChannel.ReceiveHTLC(bobHtlc); err != nil {
+		t.Fatalf("unable to recv htlc: %v", err)
+	}
+	if err := forceStateTransition(aliceChannel, bobChannel); err != nil {
+		t.Fatalf("unable to complete state transition: %v", err)
+	}
+	if err := forceStateTransition(bobChannel, aliceChannel); err != nil {
+		t.Fatalf("unable to complete state transition: %v", err)
+	}
+
+	revokedCommit, err := bobChannel.getSignedCommitTx()
+	if err != nil {
+		t.Fatalf("unable to obtain bob's commitment: %v", err)
+	}
+	revokedHeight := aliceChannel.remoteCommitChain.tip().height
+
+	htlc, _ := createHTLC(2, htlcAmt)
+	if _, err := aliceChannel.AddHTLC(htlc); err != nil {
+		t.Fatalf("unable to add htlc: %v", err)
+	}
+	if _, err := bobChannel.ReceiveHTLC(htlc); err != nil {
+		t.Fatalf("unable to recv htlc: %v", err)
+	}
+	if err := forceStateTransition(aliceChannel, bobChannel); err != nil {
+		t.Fatalf("unable to complete state transition: %v", err)
+	}
+
+	retribution, err := newBreachRetribution(
+		aliceChannel.channelState, revokedHeight, revokedCommit,
+	)
+	if err != nil {
+		t.Fatalf("unable to derive retribution: %v", err)
+	}
+	if len(retribution.HtlcRetributions) != 2 {
+		t.Fatalf("expected 2 htlc retributions, got %v",
+			len(retribution.HtlcRetributions))
+	}
+
+	witnessTypes := make(map[WitnessType]struct{})
+	for _, htlcRet := range retribution.HtlcRetributions {
+		// The HTLC offered by Bob is incoming to Alice.
+		witnessType := htlcRet.WitnessType()
+		expIncoming := htlcRet.SignDesc.Output.Value ==
+			int64(bobHtlc.Amount.ToSatoshis())
+		if htlcRet.IsIncoming != expIncoming {
+			t.Fatalf("htlc %v misclassified as %v",
+				htlcRet.OutPoint, witnessType)
+		}
+
+		// Both HTLCs share the same expiry, which bounds the time
+		// within which their offerer can't reclaim them.
+		if htlcRet.RefundTimeout != aliceHtlc.Expiry {
+			t.Fatalf("htlc %v has refund timeout %v, expected %v",
+				htlcRet.OutPoint, htlcRet.RefundTimeout,
+				aliceHtlc.Expiry)
+		}
+		witnessTypes[witnessType] = struct{}{}
+
+		// Each HTLC must carry the payment hash of its circuit.
+		expHash := aliceHtlc.PaymentHash
+		if expIncoming {
+			expHash = bobHtlc.PaymentHash
+		}
+		if htlcRet.PaymentHash != expHash {
+			t.Fatalf("htlc %v has payment hash %x, expected %x",
+				htlcRet.OutPoint, htlcRet.PaymentHash, expHash)
+		}
+
+		// The sign descriptor must describe the output it sweeps.
+		htlcOutput := revokedCommit.TxOut[htlcRet.OutPoint.Index]
+		if !bytes.Equal(htlcOutput.PkScript,
+			htlcRet.SignDesc.Output.PkScript) {
+
+			t.Fatalf("htlc %v has an unexpected pkScript",
+				htlcRet.OutPoint)
+		}
+
+		sweepTx := wire.NewMsgTx(2)
+		sweepTx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: htlcRet.OutPoint,
+		})
+		sweepTx.AddTxOut(&wire.TxOut{
+			PkScript: htlcOutput.PkScript,
+			Value:    htlcOutput.Value - 1000,
+		})
+
+		signDesc := htlcRet.SignDesc
+		genWitness := witnessType.GenWitnessFunc(
+			&aliceChannel.signer, &signDesc,
+		)
+		witness, err := genWitness(
+			sweepTx, txscript.NewTxSigHashes(sweepTx), 0,
+		)
+		if err != nil {
+			t.Fatalf("unable to generate %v witness: %v",
+				witnessType, err)
+		}
+		sweepTx.TxIn[0].Witness = witness
+
+		vm, err := txscript.NewEngine(htlcOutput.PkScript,
+			sweepTx, 0, txscript.StandardVerifyFlags, nil,
+			nil, htlcOutput.Value)
+		if err != nil {
+			t.Fatalf("unable to create engine: %v", err)
+		}
+		if err := vm.Execute(); err != nil {
+			t.Fatalf("%v witness is invalid: %v", witnessType, err)
+		}
+	}
+
+	if len(witnessTypes) != 2 {
+		t.Fatalf("expected both htlc witness types, got %v",
+			witnessTypes)
+	}
+}
@@ -15,6 +15,14 @@ import (
 // to spend a specifid output.
 var ErrNotMine = errors.New("the passed output doesn't belong to the wallet")
 
+// ErrWalletLocked is an error denoting that a Signer is unable to produce a
+// signature, as the wallet holding the required private keys is locked.
+var ErrWalletLocked = errors.New("wallet is locked")
+
+// ErrOutputSpent is returned by a BlockChainIO's GetUtxo method if the target
+// output for lookup has already been spent within the main chain.
+var ErrOutputSpent = errors.New("target output has been spent")
+
 // AddressType is a enum-like type which denotes the possible address types
 // WalletController supports.
 type AddressType uint8
@@ -212,7 +220,9 @@ type BlockChainIO interface {
 	// member of the utxo set. The passed height hint should be the "birth
 	// height" of the passed outpoint. In the case that the output is in
 	// the UTXO set, then the output corresponding to that output is
-	// returned.  Otherwise, a non-nil error will be returned.
+	// returned.  Otherwise, a non-nil error will be returned, which is
+	// ErrOutputSpent if the output is known to have been spent. Spends
+	// which have yet to confirm aren't taken into account.
 	GetUtxo(op *wire.OutPoint, heightHint uint32) (*wire.TxOut, error)
 
 	// GetBlockHash returns the hash of the block in the best blockchain
@@ -297,6 +297,40 @@ func senderHtlcSpendRevoke(signer Signer, signDesc *SignDescriptor,
 	return witnessStack, nil
 }
 
+// HtlcOfferedSpendRevoke constructs a valid witness allowing us to sweep an
+// HTLC output offered to us by the remote party, within a revoked commitment
+// transaction they broadcast, via the revocation clause of the sender's HTLC
+// script.
+//
+// NOTE: The passed SignDescriptor should include the raw (untweaked)
+// revocation base public key of the receiver and also the proper double tweak
+// value based on the commitment secret of the revoked commitment.
+func HtlcOfferedSpendRevoke(signer Signer, signDesc *SignDescriptor,
+	sweepTx *wire.MsgTx) (wire.TxWitness, error) {
+
+	revokeKey, err := signDescRevocationKey(signDesc)
+	if err != nil {
+		return nil, err
+	}
+
+	return senderHtlcSpendRevoke(signer, signDesc, revokeKey, sweepTx)
+}
+
+// signDescRevocationKey derives the revocation public key that the given
+// SignDescriptor is capable of signing for, from its revocation base point
+// and the commitment secret within its double tweak.
+func signDescRevocationKey(signDesc *SignDescriptor) (*btcec.PublicKey,
+	error) {
+
+	if signDesc.PubKey == nil || signDesc.DoubleTweak == nil {
+		return nil, fmt.Errorf("sign descriptor lacks the revocation " +
+			"base point or commitment secret")
+	}
+
+	commitPoint := signDesc.DoubleTweak.PubKey()
+	return DeriveRevocationPubkey(signDesc.PubKey, commitPoint), nil
+}
+
 // senderHtlcSpendRedeem constructs a valid witness allowing the receiver of an
 // HTLC to redeem the pending output in the scenario that the sender broadcasts
 // their version of the commitment transaction. A valid spend requires
@@ -321,6 +355,15 @@ func senderHtlcSpendRedeem(signer Signer, signDesc *SignDescriptor,
 	return witnessStack, nil
 }
 
+// HtlcSpendRemoteSuccess constructs a valid witness allowing us to redeem an
+// HTLC paying to us from the counterparty's commitment transaction, using the
+// payment preimage.
+func HtlcSpendRemoteSuccess(signer Signer, signDesc *SignDescriptor,
+	sweepTx *wire.MsgTx, paymentPreimage []byte) (wire.TxWitness, error) {
+
+	return senderHtlcSpendRedeem(signer, signDesc, sweepTx, paymentPreimage)
+}
+
 // senderHtlcSpendTimeout constructs a valid witness allowing the sender of an
 // HTLC to activate the time locked covenant clause of a soon to be expired
 // HTLC.  This script simply spends the multi-sig output using the
@@ -528,6 +571,25 @@ func receiverHtlcSpendRevoke(signer Signer, signDesc *SignDescriptor,
 	return witnessStack, nil
 }
 
+// HtlcAcceptedSpendRevoke constructs a valid witness allowing us to sweep an
+// HTLC output we offered to the remote party, within a revoked commitment
+// transaction they broadcast, via the revocation clause of the receiver's HTLC
+// script.
+//
+// NOTE: The passed SignDescriptor should include the raw (untweaked)
+// revocation base public key of the receiver and also the proper double tweak
+// value based on the commitment secret of the revoked commitment.
+func HtlcAcceptedSpendRevoke(signer Signer, signDesc *SignDescriptor,
+	sweepTx *wire.MsgTx) (wire.TxWitness, error) {
+
+	revokeKey, err := signDescRevocationKey(signDesc)
+	if err != nil {
+		return nil, err
+	}
+
+	return receiverHtlcSpendRevoke(signer, signDesc, revokeKey, sweepTx)
+}
+
 // receiverHtlcSpendTimeout constructs a valid witness allowing the sender of
 // an HTLC to recover the pending funds after an absolute timeout in the
 // scenario that the receiver of the HTLC broadcasts their version of the
@@ -963,6 +1025,18 @@ func CommitSpendNoDelay(signer Signer, signDesc *SignDescriptor,
 	return witness, nil
 }
 
+// CommitSpendAnchorAnyone constructs a witness allowing anyone to spend an
+// anchor output once AnchorCSVDelay blocks have elapsed. No signature is
+// required, though the spending input must set its sequence accordingly.
+func CommitSpendAnchorAnyone(anchorScript []byte) (wire.TxWitness, error) {
+	// An empty signature forces the timelocked branch of the script.
+	witnessStack := wire.TxWitness(make([][]byte, 2))
+	witnessStack[0] = nil
+	witnessStack[1] = anchorScript
+
+	return witnessStack, nil
+}
+
 // SingleTweakBytes computes set of bytes we call the single tweak. The purpose
 // of the single tweak is to randomize all regular delay and payment base
 // points. To do this, we generate a hash that binds the commitment point to
@@ -25,8 +25,45 @@ const (
 	// of a malicious counterparty's who broadcasts a revoked commitment
 	// transaction.
 	CommitmentRevoke WitnessType = 2
+
+	// HtlcAcceptedRemoteSuccess is a witness that allows us to sweep an
+	// HTLC output paying to us on the counterparty's commitment
+	// transaction using the payment preimage.
+	HtlcAcceptedRemoteSuccess WitnessType = 3
+
+	// HtlcOfferedRevoke is a witness that allows us to sweep an HTLC
+	// output offered to us by a malicious counterparty who broadcasts a
+	// revoked commitment transaction, via the revocation clause of the
+	// offerer's HTLC script.
+	HtlcOfferedRevoke WitnessType = 4
+
+	// HtlcAcceptedRevoke is a witness that allows us to sweep an HTLC
+	// output we offered to a malicious counterparty who broadcasts a
+	// revoked commitment transaction, via the revocation clause of the
+	// receiver's HTLC script.
+	HtlcAcceptedRevoke WitnessType = 5
 )
 
+// String returns a human readable version of the target WitnessType.
+func (wt WitnessType) String() string {
+	switch wt {
+	case CommitmentTimeLock:
+		return "CommitmentTimeLock"
+	case CommitmentNoDelay:
+		return "CommitmentNoDelay"
+	case CommitmentRevoke:
+		return "CommitmentRevoke"
+	case HtlcAcceptedRemoteSuccess:
+		return "HtlcAcceptedRemoteSuccess"
+	case HtlcOfferedRevoke:
+		return "HtlcOfferedRevoke"
+	case HtlcAcceptedRevoke:
+		return "HtlcAcceptedRevoke"
+	default:
+		return fmt.Sprintf("Unknown WitnessType: %d", uint16(wt))
+	}
+}
+
 // WitnessGenerator represents a function which is able to generate the final
 // witness for a particular public key script. This function acts as an
 // abstraction layer, hiding the details of the underlying script.
@@ -52,6 +89,13 @@ func (wt WitnessType) GenWitnessFunc(signer *Signer,
 			return CommitSpendNoDelay(*signer, desc, tx)
 		case CommitmentRevoke:
 			return CommitSpendRevoke(*signer, desc, tx)
+		case HtlcOfferedRevoke:
+			return HtlcOfferedSpendRevoke(*signer, desc, tx)
+		case HtlcAcceptedRevoke:
+			return HtlcAcceptedSpendRevoke(*signer, desc, tx)
+		case HtlcAcceptedRemoteSuccess:
+			return nil, fmt.Errorf("witness type %v requires a "+
+				"payment preimage", wt)
 		default:
 			return nil, fmt.Errorf("unknown witness type: %v", wt)
 		}
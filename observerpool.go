@@ -0,0 +1,250 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lightningnetwork/lnd/lnwallet"
+)
+
+// watchedContract is a contract being watched by an observerWorker, along with
+// the signal which is closed once it no longer needs watching, and optional
+// ones closed once the worker has begun watching it, and once the event which
+// ended its watch has been handled.
+type watchedContract struct {
+	contract     *lnwallet.LightningChannel
+	settleSignal chan struct{}
+	stopped      chan struct{}
+	live         chan struct{}
+}
+
+// observerPool multiplexes the watching of many contracts over a fixed number
+// of worker goroutines. Each contract is watched until exactly one event is
+// received for it, at which point it's removed from its worker and the event
+// is handed off to its own goroutine, such that the per-channel semantics of a
+// breachObserver are preserved. Should every worker be watching its maximum
+// number of contracts, an additional worker is spawned.
+type observerPool struct {
+	// mtx guards workers and started.
+	mtx     sync.Mutex
+	workers []*observerWorker
+	started bool
+
+	// maxContracts is the maximum number of contracts watched by a single
+	// worker.
+	maxContracts int32
+
+	// handle is invoked, within a new goroutine, with the event received
+	// for each watched contract.
+	handle func(*lnwallet.LightningChannel, observerEvent)
+
+	quit chan struct{}
+	wg   *sync.WaitGroup
+}
+
+// observerWorker watches a subset of the pool's contracts.
+type observerWorker struct {
+	// load is the number of contracts assigned to this worker.
+	//
+	// NOTE: This MUST be used atomically.
+	load int32
+
+	// newContracts delivers contracts the worker should begin watching.
+	newContracts chan *watchedContract
+}
+
+// newObserverPool creates an observerPool of numWorkers workers, which hands
+// off received events to handle. The workers exit once quit is closed, and
+// are tracked, along with the handlers they launch, by wg.
+func newObserverPool(numWorkers int,
+	handle func(*lnwallet.LightningChannel, observerEvent),
+	quit chan struct{}, wg *sync.WaitGroup) *observerPool {
+
+	workers := make([]*observerWorker, numWorkers)
+	for i := range workers {
+		workers[i] = newObserverWorker()
+	}
+
+	return &observerPool{
+		workers:      workers,
+		maxContracts: maxContractsPerWorker,
+		handle:       handle,
+		quit:         quit,
+		wg:           wg,
+	}
+}
+
+// newObserverWorker creates a worker watching no contracts.
+func newObserverWorker() *observerWorker {
+	return &observerWorker{
+		newContracts: make(chan *watchedContract),
+	}
+}
+
+// start launches the pool's workers.
+func (p *observerPool) start() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.started = true
+	for _, w := range p.workers {
+		p.wg.Add(1)
+		go p.runWorker(w)
+	}
+}
+
+// watch assigns the contract to the least loaded worker, which watches it
+// until the settle signal is closed, or the contract is closed or breached. If
+// non-nil, the live channel is closed once the worker has begun watching it,
+// and the stopped channel once the event ending its watch has been handled.
+func (p *observerPool) watch(contract *lnwallet.LightningChannel,
+	settleSignal, stopped, live chan struct{}) {
+
+	p.mtx.Lock()
+	worker := p.workers[0]
+	for _, w := range p.workers[1:] {
+		if atomic.LoadInt32(&w.load) < atomic.LoadInt32(&worker.load) {
+			worker = w
+		}
+	}
+
+	// A worker can only select over so many contracts, so once all are
+	// full, the contract is assigned to a new worker.
+	if atomic.LoadInt32(&worker.load) >= p.maxContracts {
+		worker = newObserverWorker()
+		p.workers = append(p.workers, worker)
+		if p.started {
+			p.wg.Add(1)
+			go p.runWorker(worker)
+		}
+	}
+	atomic.AddInt32(&worker.load, 1)
+	p.mtx.Unlock()
+
+	select {
+	case worker.newContracts <- &watchedContract{
+		contract:     contract,
+		settleSignal: settleSignal,
+		stopped:      stopped,
+		live:         live,
+	}:
+	case <-p.quit:
+	}
+}
+
+// Each watched contract occupies three consecutive cases in a worker's select,
+// which follow the quit and newContracts cases.
+const (
+	observerCaseSettle = iota
+	observerCaseClose
+	observerCaseBreach
+	numObserverCases
+
+	numWorkerCases = 2
+)
+
+const (
+	// maxSelectCases is the maximum number of cases reflect.Select
+	// accepts.
+	maxSelectCases = 65536
+
+	// maxContractsPerWorker is the maximum number of contracts watched by
+	// a single worker, such that its select stays within maxSelectCases.
+	maxContractsPerWorker = (maxSelectCases - numWorkerCases) /
+		numObserverCases
+)
+
+// runWorker watches the contracts assigned to the worker until the pool's
+// quit channel is closed.
+//
+// NOTE: This MUST be run as a goroutine.
+func (p *observerPool) runWorker(w *observerWorker) {
+	defer p.wg.Done()
+
+	// The cases are kept across iterations, with those of each contract
+	// appended as it's assigned, such that an event costs no more than
+	// the select itself.
+	var contracts []*watchedContract
+	cases := []reflect.SelectCase{
+		{
+			Dir:  reflect.SelectRecv,
+			Chan: reflect.ValueOf(p.quit),
+		},
+		{
+			Dir:  reflect.SelectRecv,
+			Chan: reflect.ValueOf(w.newContracts),
+		},
+	}
+	for {
+		chosen, recv, _ := reflect.Select(cases)
+		switch chosen {
+		case 0:
+			return
+
+		case 1:
+			c := recv.Interface().(*watchedContract)
+			contracts = append(contracts, c)
+			cases = append(cases,
+				reflect.SelectCase{
+					Dir:  reflect.SelectRecv,
+					Chan: reflect.ValueOf(c.settleSignal),
+				},
+				reflect.SelectCase{
+					Dir:  reflect.SelectRecv,
+					Chan: reflect.ValueOf(c.contract.UnilateralClose),
+				},
+				reflect.SelectCase{
+					Dir:  reflect.SelectRecv,
+					Chan: reflect.ValueOf(c.contract.ContractBreach),
+				},
+			)
+			if c.live != nil {
+				close(c.live)
+			}
+			continue
+		}
+
+		// Otherwise, one of the watched contracts has fired, so we
+		// stop watching it and hand off the event. Its cases are
+		// replaced by those of the last contract, which takes its
+		// place.
+		idx := (chosen - numWorkerCases) / numObserverCases
+		c := contracts[idx]
+		last := len(contracts) - 1
+		contracts[idx] = contracts[last]
+		contracts[last] = nil
+		contracts = contracts[:last]
+
+		firstCase := numWorkerCases + idx*numObserverCases
+		lastCase := numWorkerCases + last*numObserverCases
+		copy(cases[firstCase:], cases[lastCase:])
+		for i := lastCase; i < len(cases); i++ {
+			cases[i] = reflect.SelectCase{}
+		}
+		cases = cases[:lastCase]
+		atomic.AddInt32(&w.load, -1)
+
+		var event observerEvent
+		switch (chosen - numWorkerCases) % numObserverCases {
+		case observerCaseSettle:
+			event.settled = true
+		case observerCaseClose:
+			closeInfo := recv.Interface()
+			event.closeInfo = closeInfo.(*lnwallet.UnilateralCloseSummary)
+		case observerCaseBreach:
+			breachInfo := recv.Interface()
+			event.breachInfo = breachInfo.(*lnwallet.BreachRetribution)
+		}
+
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.handle(c.contract, event)
+
+			if c.stopped != nil {
+				close(c.stopped)
+			}
+		}()
+	}
+}
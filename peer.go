@@ -300,12 +300,12 @@ func (p *peer) loadActiveChannels(chans []*channeldb.OpenChannel) error {
 
 		peerLog.Infof("peerID(%v) loading ChannelPoint(%v)", p.id, chanPoint)
 
-		select {
-		case p.server.breachArbiter.newContracts <- lnChan:
-		case <-p.server.quit:
-			return fmt.Errorf("server shutting down")
-		case <-p.quit:
-			return fmt.Errorf("peer shutting down")
+		// Before the channel is used, we'll ensure that the breach
+		// arbiter is watching it, so that no breach of it goes
+		// unnoticed.
+		err = p.server.breachArbiter.WatchChannel(lnChan)
+		if err != nil {
+			return err
 		}
 
 		blockEpoch, err := p.server.cc.chainNotifier.RegisterBlockEpochNtfn()
@@ -1427,6 +1427,11 @@ func (p *peer) handleClosingSigned(localReq *htlcswitch.ChanClose,
 		return nil, 0
 	}
 
+	// Should the remote party broadcast a revoked state in the meantime,
+	// the breach arbiter must know that our closing transaction may
+	// confirm in its place.
+	p.server.breachArbiter.TrackCoopClose(chanPoint, closeTx.TxHash())
+
 	// We agreed on a fee, and we can broadcast the closure transaction to
 	// the network.
 	peerLog.Infof("Broadcasting cooperative close tx: %v",
@@ -1521,9 +1526,11 @@ func (p *peer) handleClosingSigned(localReq *htlcswitch.ChanClose,
 		errChan = localReq.Err
 	}
 
-	go waitForChanToClose(uint32(bestHeight), notifier, errChan,
+	go waitForChanToClose(uint32(bestHeight), 1, notifier, errChan,
 		chanPoint, &closingTxid, func() {
 
+			p.server.breachArbiter.UntrackCoopClose(chanPoint)
+
 			// First, we'll mark the database as being fully closed
 			// so we'll no longer watch for its ultimate closure
 			// upon startup.
@@ -1647,33 +1654,54 @@ func (p *peer) negotiateFeeAndCreateCloseTx(channel *lnwallet.LightningChannel,
 }
 
 // waitForChanToClose uses the passed notifier to wait until the channel has
-// been detected as closed on chain and then concludes by executing the
+// been detected as closed on chain, with the closing transaction having
+// reached numConfs confirmations, and then concludes by executing the
 // following actions: the channel point will be sent over the settleChan, and
-// finally the callback will be executed. If any error is encountered within
-// the function, then it will be sent over the errChan.
-func waitForChanToClose(bestHeight uint32, notifier chainntnfs.ChainNotifier,
-	errChan chan error, chanPoint *wire.OutPoint,
-	closingTxID *chainhash.Hash, cb func()) {
-
-	peerLog.Infof("Waiting for confirmation of cooperative close of "+
-		"ChannelPoint(%v) with txid: %v", chanPoint,
+// finally the callback will be executed. Should the closing transaction be
+// reorged out beforehand, we wait anew for it to reconfirm. If any error is
+// encountered within the function, then it will be sent over the errChan.
+func waitForChanToClose(bestHeight, numConfs uint32,
+	notifier chainntnfs.ChainNotifier, errChan chan error,
+	chanPoint *wire.OutPoint, closingTxID *chainhash.Hash, cb func()) {
+
+	peerLog.Infof("Waiting for %v confirmation(s) of close of "+
+		"ChannelPoint(%v) with txid: %v", numConfs, chanPoint,
 		closingTxID)
 
-	// TODO(roasbeef): add param for num needed confs
-	confNtfn, err := notifier.RegisterConfirmationsNtfn(closingTxID, 1,
-		bestHeight)
-	if err != nil {
-		if errChan != nil {
-			errChan <- err
+	var (
+		height    *chainntnfs.TxConfirmation
+		confirmed bool
+	)
+	for !confirmed {
+		confNtfn, err := notifier.RegisterConfirmationsNtfn(
+			closingTxID, numConfs, bestHeight,
+		)
+		if err != nil {
+			if errChan != nil {
+				errChan <- err
+			}
+			return
 		}
-		return
-	}
 
-	// In the case that the ChainNotifier is shutting down, all subscriber
-	// notification channels will be closed, generating a nil receive.
-	height, ok := <-confNtfn.Confirmed
-	if !ok {
-		return
+		// In the case that the ChainNotifier is shutting down, all
+		// subscriber notification channels will be closed, generating
+		// a nil receive.
+		select {
+		case height, confirmed = <-confNtfn.Confirmed:
+			if !confirmed {
+				return
+			}
+
+		case reorgDepth, ok := <-confNtfn.NegativeConf:
+			if !ok {
+				return
+			}
+
+			peerLog.Warnf("Closing transaction %v of "+
+				"ChannelPoint(%v) was reorged out at depth "+
+				"%v, waiting for it to reconfirm", closingTxID,
+				chanPoint, reorgDepth)
+		}
 	}
 
 	// The channel has been closed, remove it from any active indexes, and
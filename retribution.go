@@ -0,0 +1,584 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/roasbeef/btcd/btcec"
+	"github.com/roasbeef/btcd/chaincfg/chainhash"
+	"github.com/roasbeef/btcd/txscript"
+	"github.com/roasbeef/btcd/wire"
+	"github.com/roasbeef/btcutil"
+)
+
+// breachedOutput contains all the information needed to sweep a breached
+// output. A breached output is an output that we are now entitled to due to a
+// revoked commitment transaction being broadcast.
+type breachedOutput struct {
+	amt btcutil.Amount
+
+	// outpoint identifies the breached output. Its hash is the txid of
+	// the transaction containing the output, which is usually, but not
+	// necessarily, the breach transaction itself, see breachTxids.
+	outpoint wire.OutPoint
+
+	signDescriptor lnwallet.SignDescriptor
+	witnessType    lnwallet.WitnessType
+	witnessFunc    lnwallet.WitnessGenerator
+
+	// signDescRef is true if the sign descriptor is persisted by
+	// reference, in which case it's re-derived from the channel's backup
+	// upon recovery, see rederiveSignDescs.
+	signDescRef bool
+
+	twoStageClaim bool
+
+	// contestDelay is the CSV delay, in blocks, after the output's parent
+	// transaction confirms, at which the breaching party may claim the
+	// output via its non-revocation path, racing our justice transaction.
+	// A value of zero indicates they may do so immediately. It's only
+	// recorded for the revoked output, see contestDeadline.
+	contestDelay uint32
+
+	// refundTimeout is the absolute height at which the offerer of an
+	// HTLC may reclaim it via its timeout clause. It's only recorded for
+	// HTLC outputs.
+	refundTimeout uint32
+
+	// paymentHash is the payment hash of an HTLC output, identifying its
+	// circuit within the switch once it's been swept, see resolveHTLCs.
+	// It's only recorded for HTLC outputs, and is zero for those persisted
+	// before it was.
+	paymentHash [32]byte
+
+	// claimHeight is the height at which the breaching party may claim
+	// the output, racing our justice transaction. It's only known once
+	// the breach transaction has confirmed, see contestHeight, and is zero
+	// otherwise.
+	claimHeight uint32
+
+	// preimage is the payment preimage required to sweep an HTLC output
+	// via its success path. It is only populated, and only persisted, for
+	// outputs whose witness type requires a preimage.
+	preimage [32]byte
+}
+
+// witnessSize returns the size, in bytes, of the witness spending the output,
+// assuming signatures of the maximum size.
+func (bo *breachedOutput) witnessSize() int64 {
+	scriptSize := int64(len(bo.signDescriptor.WitnessScript))
+
+	// Each witness begins with the number of elements, and consists of a
+	// length prefixed signature followed by the witness script, aside
+	// from the exceptions below.
+	const sigSize = 1 + 73
+	switch bo.witnessType {
+	// A p2wkh spend reveals the public key rather than a script.
+	case lnwallet.CommitmentNoDelay:
+		return 1 + sigSize + 1 + 33
+
+	// The revocation clause and timeout path are selected by pushing a
+	// single byte, or an empty vector respectively.
+	case lnwallet.CommitmentRevoke:
+		return 1 + sigSize + 1 + 1 + 1 + scriptSize
+	case lnwallet.CommitmentTimeLock:
+		return 1 + sigSize + 1 + 1 + scriptSize
+
+	// The revocation clause of an HTLC script is selected by revealing
+	// the revocation key.
+	case lnwallet.HtlcOfferedRevoke, lnwallet.HtlcAcceptedRevoke:
+		return 1 + sigSize + 1 + 33 + 1 + scriptSize
+
+	// The success path of an HTLC additionally reveals the preimage.
+	case lnwallet.HtlcAcceptedRemoteSuccess:
+		return 1 + sigSize + 1 + 32 + 1 + scriptSize
+
+	default:
+		return 1 + sigSize + 1 + scriptSize
+	}
+}
+
+// contestDeadline returns the number of blocks after the output's parent
+// transaction confirms at which the breaching party may claim it themselves,
+// and false if they may never claim it. Outputs paying to us are never
+// contested by the breaching party.
+func (bo *breachedOutput) contestDeadline() (uint32, bool) {
+	switch bo.witnessType {
+	case lnwallet.CommitmentNoDelay:
+		return 0, false
+	}
+
+	return bo.contestDelay, true
+}
+
+// contestHeight returns the height at which the breaching party may claim the
+// output, given the height at which the breach transaction confirmed, and
+// false if they may never claim it. An HTLC they offered may only be
+// reclaimed once it times out, while any other output may be claimed once its
+// contestDeadline has elapsed.
+func (bo *breachedOutput) contestHeight(breachHeight uint32) (uint32, bool) {
+	delay, ok := bo.contestDeadline()
+	if !ok {
+		return 0, false
+	}
+
+	if bo.witnessType == lnwallet.HtlcOfferedRevoke &&
+		bo.refundTimeout > breachHeight {
+
+		return bo.refundTimeout, true
+	}
+
+	return breachHeight + delay, true
+}
+
+// witnessRequiresRevocation returns true if spending an output with the given
+// witness type requires the revocation secret of the breached commitment.
+func witnessRequiresRevocation(wt lnwallet.WitnessType) bool {
+	switch wt {
+	case lnwallet.CommitmentRevoke, lnwallet.HtlcOfferedRevoke,
+		lnwallet.HtlcAcceptedRevoke:
+
+		return true
+	}
+
+	return false
+}
+
+// witnessRequiresPreimage returns true if spending an output with the given
+// witness type requires knowledge of the HTLC's payment preimage.
+func witnessRequiresPreimage(wt lnwallet.WitnessType) bool {
+	return wt == lnwallet.HtlcAcceptedRemoteSuccess
+}
+
+// htlcResolution returns the payment hash of the HTLC swept by this output,
+// along with its preimage if the HTLC is swept via the success path. The final
+// return value is false if the payment hash can't be determined.
+func (bo *breachedOutput) htlcResolution() ([32]byte, *[32]byte, bool) {
+	if witnessRequiresPreimage(bo.witnessType) {
+		preimage := bo.preimage
+		return sha256.Sum256(preimage[:]), &preimage, true
+	}
+
+	return bo.paymentHash, nil, bo.paymentHash != [32]byte{}
+}
+
+// genWitnessFunc returns a WitnessGenerator capable of producing a valid
+// witness for the breached output. Outputs whose witness type requires a
+// payment preimage are handled here, as the generic witness generators have
+// no knowledge of the preimage.
+func (bo *breachedOutput) genWitnessFunc(
+	signer *lnwallet.Signer) lnwallet.WitnessGenerator {
+
+	if !witnessRequiresPreimage(bo.witnessType) {
+		return bo.witnessType.GenWitnessFunc(signer, &bo.signDescriptor)
+	}
+
+	return func(tx *wire.MsgTx, hc *txscript.TxSigHashes,
+		inputIndex int) ([][]byte, error) {
+
+		desc := bo.signDescriptor
+		desc.SigHashes = hc
+		desc.InputIndex = inputIndex
+
+		return lnwallet.HtlcSpendRemoteSuccess(
+			*signer, &desc, tx, bo.preimage[:],
+		)
+	}
+}
+
+// retributionInfo encapsulates all the data needed to sweep all the contested
+// funds within a channel whose contract has been breached by the prior
+// counterparty. This struct is used to create the justice transaction which
+// spends all outputs of the commitment transaction into an output controlled
+// by the wallet.
+type retributionInfo struct {
+	commitHash chainhash.Hash
+	chanPoint  wire.OutPoint
+
+	// Fields copied from channel snapshot when a breach is detected. This
+	// is necessary for deterministically constructing the channel close
+	// summary in the event that the breach arbiter crashes before closing
+	// the channel.
+	remoteIdentity btcec.PublicKey
+	capacity       btcutil.Amount
+	settledBalance btcutil.Amount
+
+	// rawRemoteIdentity is the remote identity as it was persisted. It's
+	// only retained if it failed to parse, in which case remoteIdentity
+	// is left zero, so that the record is re-encoded unchanged.
+	rawRemoteIdentity [33]byte
+
+	selfOutput *breachedOutput
+
+	revokedOutput *breachedOutput
+
+	htlcOutputs []*breachedOutput
+
+	// bumpTier is the fee tier of the most recently crafted justice
+	// transaction, which is raised as the JusticeBumpSchedule elapses
+	// without the transaction confirming.
+	bumpTier uint32
+
+	// breachDetectedAt is the time at which the breach was first detected.
+	// It is persisted so that the time-to-justice can be measured even if
+	// the daemon restarts mid-retribution. A zero value indicates a record
+	// written before this field was introduced.
+	breachDetectedAt time.Time
+
+	// sweepPkScript is the output script paid to by the most recently
+	// crafted justice transaction. Replacements of that transaction must
+	// pay to the same script, see sweepTxKind. It is persisted before the
+	// first justice transaction is broadcast, so that it survives a
+	// restart.
+	sweepPkScript []byte
+
+	// penaltyPkScript is the output script paid the penalty by the most
+	// recently crafted justice transaction, should the penalty be swept
+	// separately from our own output, see PenaltySweepScriptGen.
+	penaltyPkScript []byte
+
+	// justiceTxid is the txid of the most recently broadcast justice
+	// transaction, which changes as it's bumped or re-derived. It is
+	// persisted each time a new justice transaction is crafted.
+	justiceTxid chainhash.Hash
+
+	// justiceConfHeight is the height of the block in which the justice
+	// transaction confirmed, or zero if it has yet to confirm.
+	justiceConfHeight uint32
+
+	// expectedJusticeFee is the fee the justice transaction was expected
+	// to pay at the time the breach was detected. It is persisted so that
+	// the projected recovery can be reported before the justice
+	// transaction is crafted. A zero value indicates a record written
+	// before this field was introduced.
+	expectedJusticeFee btcutil.Amount
+
+	// justiceBreakdown is the breakdown of the funds swept by the latest
+	// justice transaction, or nil if none has been broadcast.
+	justiceBreakdown *JusticeBreakdown
+
+	// revokedStateNum is the number of the revoked state broadcast by the
+	// breaching party, from which the sign descriptors of the commitment
+	// outputs are re-derived should they be persisted by reference. It's
+	// zero for records written before it was introduced.
+	revokedStateNum uint64
+
+	// uneconomic is true if the breach was acknowledged without justice
+	// being served, as the fee required to sweep the breached outputs
+	// exceeded their value.
+	uneconomic bool
+
+	// approval records whether the justice transaction awaits, or has
+	// been granted, the operator's approval.
+	approval justiceApproval
+
+	// completed is true once justice has been served and the channel
+	// marked fully closed, such that all that remains is the removal of
+	// the retribution's record.
+	completed bool
+
+	// breachProof is the proof that the remote party broadcast a revoked
+	// state, assembled when the breach was detected. It is nil if the
+	// proof couldn't be assembled, or for records written before proofs
+	// were recorded.
+	breachProof *BreachProof
+
+	// deadlineHeight is the height by which the justice transaction must
+	// confirm, before the breaching party may claim any of the breached
+	// outputs. It's zero until the breach transaction confirms, or if
+	// none of the outputs may be claimed by them.
+	deadlineHeight uint32
+
+	// backup is the static backup of the channel as of the breach, from
+	// which the retribution may be re-derived should the breach
+	// transaction be replaced by another revoked commitment before it
+	// confirms. It isn't persisted, so is nil for retributions restored
+	// from disk.
+	backup *channeldb.ChannelBackup
+
+	doneChan chan struct{}
+}
+
+// projectedRecovery returns the value expected to be recovered by the justice
+// transaction, net of the fee it was expected to pay when the breach was
+// detected.
+func (ret *retributionInfo) projectedRecovery() btcutil.Amount {
+	recovery := ret.valueAtRisk() - ret.expectedJusticeFee
+	if recovery < 0 {
+		return 0
+	}
+
+	return recovery
+}
+
+// breachedOutputs returns each of the outputs of the breach transaction we
+// intend to sweep: our own, the revoked output, then any HTLC outputs.
+func (ret *retributionInfo) breachedOutputs() []*breachedOutput {
+	outputs := []*breachedOutput{ret.selfOutput, ret.revokedOutput}
+	outputs = append(outputs, ret.htlcOutputs...)
+
+	return outputs
+}
+
+// recordBreachHeight records the height at which the breach transaction
+// confirmed, from which the heights at which the breaching party may claim
+// each of the breached outputs, and thus the retribution's deadline, are
+// derived.
+func (ret *retributionInfo) recordBreachHeight(breachHeight uint32) {
+	outputs := []*breachedOutput{ret.selfOutput, ret.revokedOutput}
+	outputs = append(outputs, ret.htlcOutputs...)
+
+	ret.deadlineHeight = 0
+	for _, output := range outputs {
+		claimHeight, ok := output.contestHeight(breachHeight)
+		if !ok {
+			continue
+		}
+
+		output.claimHeight = claimHeight
+		if ret.deadlineHeight == 0 || claimHeight < ret.deadlineHeight {
+			ret.deadlineHeight = claimHeight
+		}
+	}
+}
+
+// recordJusticeConf records the height at which the justice transaction of the
+// retribution confirmed. A nil confirmation leaves the height unknown.
+func (ret *retributionInfo) recordJusticeConf(
+	conf *chainntnfs.TxConfirmation) {
+
+	if conf != nil {
+		ret.justiceConfHeight = conf.BlockHeight
+	}
+}
+
+// hasRemoteIdentity returns true if the identity of the breaching party is
+// known, which is only false if the persisted identity failed to parse.
+func (ret *retributionInfo) hasRemoteIdentity() bool {
+	return ret.remoteIdentity.X != nil
+}
+
+// recoveredFunds returns the funds reported as recovered once justice has been
+// served, being the value of the revoked output and our own output.
+func (ret *retributionInfo) recoveredFunds() btcutil.Amount {
+	return ret.revokedOutput.amt + ret.selfOutput.amt
+}
+
+// valueAtRisk returns the total value of the outputs of the retribution, which
+// is lost should justice not be served in time.
+func (ret *retributionInfo) valueAtRisk() btcutil.Amount {
+	outputs := []*breachedOutput{ret.selfOutput, ret.revokedOutput}
+	outputs = append(outputs, ret.htlcOutputs...)
+
+	var total btcutil.Amount
+	for _, output := range outputs {
+		if output != nil {
+			total += output.amt
+		}
+	}
+
+	return total
+}
+
+// breachTxids returns the txids of the transactions containing the outputs of
+// the retribution, beginning with that of the breach transaction itself. The
+// outputs usually all belong to the breach transaction, but an HTLC output may
+// instead belong to a second-stage transaction broadcast by the remote party.
+func (r *retributionInfo) breachTxids() []chainhash.Hash {
+	txids := []chainhash.Hash{r.commitHash}
+	seen := map[chainhash.Hash]struct{}{r.commitHash: {}}
+
+	outputs := []*breachedOutput{r.selfOutput, r.revokedOutput}
+	outputs = append(outputs, r.htlcOutputs...)
+	for _, output := range outputs {
+		txid := output.outpoint.Hash
+		if _, ok := seen[txid]; ok {
+			continue
+		}
+
+		seen[txid] = struct{}{}
+		txids = append(txids, txid)
+	}
+
+	return txids
+}
+
+// verifyIntegrity returns an error describing the first inconsistency found
+// within a decoded retribution, such as a missing commitment output, an
+// unparsable remote identity, or an output whose witness type or sign
+// descriptor doesn't match its role.
+func (ret *retributionInfo) verifyIntegrity() error {
+	if ret.selfOutput == nil || ret.revokedOutput == nil {
+		return errors.New("missing commitment output")
+	}
+	if !ret.hasRemoteIdentity() {
+		return errors.New("unparsable remote identity")
+	}
+
+	// A sign descriptor persisted by reference is only re-derived upon
+	// recovery, so can't be checked here.
+	outputs := []*breachedOutput{ret.selfOutput, ret.revokedOutput}
+	for _, bo := range outputs {
+		if bo.signDescPending() {
+			continue
+		}
+		if err := bo.checkSigningMaterial(); err != nil {
+			return err
+		}
+	}
+
+	// Each output must be of a witness type befitting its role, and its
+	// value must match that of the output its sign descriptor commits to.
+	type role struct {
+		name    string
+		outputs []*breachedOutput
+		valid   func(lnwallet.WitnessType) bool
+	}
+	roles := []role{
+		{
+			name:    "self",
+			outputs: []*breachedOutput{ret.selfOutput},
+			valid: func(wt lnwallet.WitnessType) bool {
+				return wt == lnwallet.CommitmentNoDelay
+			},
+		},
+		{
+			name:    "revoked",
+			outputs: []*breachedOutput{ret.revokedOutput},
+			valid: func(wt lnwallet.WitnessType) bool {
+				return wt == lnwallet.CommitmentRevoke
+			},
+		},
+		{
+			name:    "HTLC",
+			outputs: ret.htlcOutputs,
+			valid: func(wt lnwallet.WitnessType) bool {
+				return wt <= lnwallet.HtlcAcceptedRevoke
+			},
+		},
+	}
+	for _, r := range roles {
+		for _, bo := range r.outputs {
+			switch {
+			case bo == nil:
+				return fmt.Errorf("missing %v output", r.name)
+
+			case !r.valid(bo.witnessType):
+				return fmt.Errorf("%v output %v has invalid "+
+					"witness type %v", r.name, bo.outpoint,
+					bo.witnessType)
+
+			case bo.signDescPending():
+
+			case bo.signDescriptor.Output == nil:
+				return fmt.Errorf("%v output %v lacks its "+
+					"output", r.name, bo.outpoint)
+
+			case bo.signDescriptor.Output.Value != int64(bo.amt):
+				return fmt.Errorf("%v output %v worth %v, "+
+					"but its sign descriptor commits to "+
+					"%v", r.name, bo.outpoint, bo.amt,
+					btcutil.Amount(
+						bo.signDescriptor.Output.Value,
+					))
+			}
+		}
+	}
+
+	return nil
+}
+
+// signDescPending returns true if the output's sign descriptor is persisted by
+// reference, and has yet to be re-derived.
+func (bo *breachedOutput) signDescPending() bool {
+	return bo.signDescRef && bo.signDescriptor.Output == nil
+}
+
+// checkSigningMaterial returns an error describing the first commitment output
+// of the retribution for which we lack the material to generate a witness.
+// HTLC outputs aren't checked, as any lacking signing material are skipped by
+// the justice transaction, rather than preventing it altogether.
+func (ret *retributionInfo) checkSigningMaterial() error {
+	outputs := []*breachedOutput{ret.selfOutput, ret.revokedOutput}
+	for _, bo := range outputs {
+		if err := bo.checkSigningMaterial(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkSweepable returns an error if a justice transaction sweeping the
+// retribution's commitment outputs can't be created, as the material needed to
+// sign for either of them is missing, or the revocation secret doesn't match
+// the revoked output.
+func (ret *retributionInfo) checkSweepable() error {
+	if err := ret.checkSigningMaterial(); err != nil {
+		return err
+	}
+
+	return ret.revokedOutput.verifyRevocationSecret()
+}
+
+// verifyRevocationSecret returns ErrRevocationSecretMismatch if the revocation
+// key derived from the revocation base point and secret within the output's
+// sign descriptor isn't the one within its witness script. As the witness of a
+// revoked output is only checked by the network once broadcast, this catches a
+// corrupt or mismatched secret before we commit to sweeping the output.
+func (bo *breachedOutput) verifyRevocationSecret() error {
+	desc := &bo.signDescriptor
+	commitmentPoint := desc.DoubleTweak.PubKey()
+	revocationKey := lnwallet.DeriveRevocationPubkey(
+		desc.PubKey, commitmentPoint,
+	).SerializeCompressed()
+
+	pushes, err := txscript.PushedData(desc.WitnessScript)
+	if err != nil {
+		return err
+	}
+	for _, push := range pushes {
+		if bytes.Equal(push, revocationKey) {
+			return nil
+		}
+	}
+
+	brarLog.Errorf("Revocation secret of %v output %v yields key %x, "+
+		"absent from its witness script", bo.witnessType, bo.outpoint,
+		revocationKey)
+
+	return ErrRevocationSecretMismatch
+}
+
+// checkSigningMaterial returns an error if the output's sign descriptor lacks
+// any of the material needed to generate a witness of its witness type.
+func (bo *breachedOutput) checkSigningMaterial() error {
+	desc := &bo.signDescriptor
+
+	var missing string
+	switch {
+	case desc.PubKey == nil:
+		missing = "public key"
+	case len(desc.WitnessScript) == 0:
+		missing = "witness script"
+	case desc.Output == nil:
+		missing = "output"
+	case bo.witnessType == lnwallet.CommitmentNoDelay &&
+		len(desc.SingleTweak) == 0:
+		missing = "single tweak"
+	case witnessRequiresRevocation(bo.witnessType) &&
+		desc.DoubleTweak == nil:
+		missing = "revocation secret"
+	default:
+		return nil
+	}
+
+	return fmt.Errorf("unable to sign for %v output %v: missing %v",
+		bo.witnessType, bo.outpoint, missing)
+}
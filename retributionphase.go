@@ -0,0 +1,503 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/roasbeef/btcd/wire"
+	"github.com/roasbeef/btcutil"
+)
+
+// retributionEvent is a chain event that drives a retribution forward.
+type retributionEvent uint8
+
+const (
+	// retEventBreachConfirmed signals that the breach transaction has
+	// confirmed.
+	retEventBreachConfirmed retributionEvent = iota
+
+	// retEventJusticeConfirmed signals that the justice transaction has
+	// confirmed.
+	retEventJusticeConfirmed
+
+	// retEventBlockEpoch signals that a new block has been connected.
+	retEventBlockEpoch
+
+	// retEventMempoolPoll signals that the mempool should be polled for
+	// transactions racing the justice transaction.
+	retEventMempoolPoll
+
+	// retEventConfTimeout signals that the justice transaction has failed
+	// to confirm within JusticeConfTimeout.
+	retEventConfTimeout
+
+	// retEventApproved signals that the operator has approved the
+	// broadcast of the justice transaction.
+	retEventApproved
+
+	// retEventApprovalTimeout signals that the justice transaction has
+	// awaited approval for longer than JusticeApprovalTimeout.
+	retEventApprovalTimeout
+
+	// retEventWalletUnlocked signals that the wallet, which blocked the
+	// signing of the justice transaction, has been unlocked.
+	retEventWalletUnlocked
+
+	// retEventHoldReleased signals that the broadcast hold, which held
+	// the justice transaction, has been released.
+	retEventHoldReleased
+)
+
+// String returns a human readable version of the retributionEvent.
+func (e retributionEvent) String() string {
+	switch e {
+	case retEventBreachConfirmed:
+		return "BreachConfirmed"
+	case retEventJusticeConfirmed:
+		return "JusticeConfirmed"
+	case retEventBlockEpoch:
+		return "BlockEpoch"
+	case retEventMempoolPoll:
+		return "MempoolPoll"
+	case retEventConfTimeout:
+		return "ConfTimeout"
+	case retEventApproved:
+		return "Approved"
+	case retEventApprovalTimeout:
+		return "ApprovalTimeout"
+	case retEventWalletUnlocked:
+		return "WalletUnlocked"
+	case retEventHoldReleased:
+		return "HoldReleased"
+	default:
+		return fmt.Sprintf("Unknown(%d)", uint8(e))
+	}
+}
+
+// retributionAction is the action a retribution takes in response to an
+// event.
+type retributionAction uint8
+
+const (
+	// retActionIgnore indicates the event is redundant, and should be
+	// ignored.
+	retActionIgnore retributionAction = iota
+
+	// retActionBroadcast indicates the justice transaction should be
+	// created and broadcast.
+	retActionBroadcast
+
+	// retActionFinalize indicates that justice has been served, and the
+	// retribution should be finalized.
+	retActionFinalize
+
+	// retActionBump indicates that the fee of the unconfirmed justice
+	// transaction should be bumped, if the bump schedule calls for it.
+	retActionBump
+
+	// retActionOutbid indicates that the unconfirmed justice transaction
+	// should be replaced, should a competing transaction outbid it.
+	retActionOutbid
+
+	// retActionEscalate indicates that the justice transaction has failed
+	// to confirm in time, and the retribution requires manual
+	// intervention.
+	retActionEscalate
+
+	// retActionEscalateApproval indicates that the justice transaction
+	// has awaited approval for too long, and the operator should be
+	// alerted.
+	retActionEscalateApproval
+)
+
+// retributionTransition determines the action a retribution in the given
+// phase takes in response to an event. Transitions are idempotent: a repeated
+// breach confirmation never triggers a second broadcast, and a confirmation of
+// the justice transaction finalizes the retribution regardless of whether the
+// breach confirmation has been seen, as the former implies the latter.
+func retributionTransition(phase retributionPhase,
+	event retributionEvent) retributionAction {
+
+	switch event {
+	case retEventBreachConfirmed:
+		if phase == retPhaseAwaitingBreachConf {
+			return retActionBroadcast
+		}
+
+	case retEventJusticeConfirmed:
+		if phase == retPhaseAwaitingBreachConf ||
+			phase == retPhaseAwaitingApproval ||
+			phase == retPhaseWalletLocked ||
+			phase == retPhaseBroadcastHeld ||
+			phase == retPhaseUneconomic ||
+			phase == retPhaseAwaitingJusticeConf ||
+			phase == retPhaseNeedsIntervention {
+
+			return retActionFinalize
+		}
+
+	case retEventBlockEpoch:
+		switch phase {
+		case retPhaseAwaitingJusticeConf:
+			return retActionBump
+		case retPhaseUneconomic:
+			return retActionBroadcast
+		}
+
+	case retEventMempoolPoll:
+		if phase == retPhaseAwaitingJusticeConf {
+			return retActionOutbid
+		}
+
+	case retEventConfTimeout:
+		if phase == retPhaseAwaitingJusticeConf {
+			return retActionEscalate
+		}
+
+	case retEventApproved:
+		if phase == retPhaseAwaitingApproval {
+			return retActionBroadcast
+		}
+
+	case retEventApprovalTimeout:
+		if phase == retPhaseAwaitingApproval {
+			return retActionEscalateApproval
+		}
+
+	case retEventWalletUnlocked:
+		if phase == retPhaseWalletLocked {
+			return retActionBroadcast
+		}
+
+	case retEventHoldReleased:
+		if phase == retPhaseBroadcastHeld {
+			return retActionBroadcast
+		}
+	}
+
+	return retActionIgnore
+}
+
+// retributionPhase denotes the stage of the retribution process that an
+// exactRetribution goroutine has reached for a particular breached channel.
+type retributionPhase uint8
+
+const (
+	// retPhaseAwaitingBreachConf indicates that we are waiting for the
+	// breach transaction to confirm before sweeping the channel's funds.
+	retPhaseAwaitingBreachConf retributionPhase = iota
+
+	// retPhaseAwaitingJusticeConf indicates that the justice transaction
+	// has been broadcast, and we are waiting for it to confirm.
+	retPhaseAwaitingJusticeConf
+
+	// retPhaseNeedsIntervention indicates that the justice transaction
+	// failed to confirm within JusticeConfTimeout. It's no longer bumped
+	// or replaced, and the retribution awaits action by the operator.
+	retPhaseNeedsIntervention
+
+	// retPhaseAwaitingApproval indicates that the breach transaction has
+	// confirmed, but the value at risk exceeds JusticeApprovalThreshold,
+	// so the justice transaction awaits the operator's approval.
+	retPhaseAwaitingApproval
+
+	// retPhaseWalletLocked indicates that the breach transaction has
+	// confirmed, but the justice transaction can't be signed as the
+	// wallet is locked. The retribution remains persisted, and resumes
+	// once the wallet is unlocked.
+	retPhaseWalletLocked
+
+	// retPhaseBroadcastHeld indicates that the breach transaction has
+	// confirmed, but the broadcast of the justice transaction is held by
+	// the operator. The retribution remains persisted, and resumes once
+	// the hold is released.
+	retPhaseBroadcastHeld
+
+	// retPhaseUneconomic indicates that the breach transaction has
+	// confirmed, but the fee of the justice transaction exceeds the value
+	// it would recover. The retribution remains persisted, and is
+	// re-checked with each block until fees fall, or the breaching party
+	// may claim the breached outputs.
+	retPhaseUneconomic
+)
+
+// String returns a human readable description of the retribution phase.
+func (p retributionPhase) String() string {
+	switch p {
+	case retPhaseAwaitingBreachConf:
+		return "AwaitingBreachConf"
+	case retPhaseAwaitingJusticeConf:
+		return "AwaitingJusticeConf"
+	case retPhaseNeedsIntervention:
+		return "NeedsIntervention"
+	case retPhaseAwaitingApproval:
+		return "AwaitingApproval"
+	case retPhaseWalletLocked:
+		return "BlockedWalletLocked"
+	case retPhaseBroadcastHeld:
+		return "BroadcastHeld"
+	case retPhaseUneconomic:
+		return "Uneconomic"
+	default:
+		return fmt.Sprintf("UnknownPhase(%d)", uint8(p))
+	}
+}
+
+// justiceApproval records the state of the operator's approval of a
+// retribution's justice transaction.
+type justiceApproval uint8
+
+const (
+	// justiceApprovalNone indicates that approval has never been
+	// requested.
+	justiceApprovalNone justiceApproval = iota
+
+	// justiceApprovalPending indicates that the justice transaction
+	// awaits the operator's approval.
+	justiceApprovalPending
+
+	// justiceApprovalGranted indicates that the operator has approved the
+	// justice transaction.
+	justiceApprovalGranted
+)
+
+// retributionStatus records the phase an active retribution is in, along
+// with the time at which it entered that phase.
+type retributionStatus struct {
+	phase retributionPhase
+	since time.Time
+
+	// priorPhase and priorSince record the phase the retribution was in
+	// before the broadcast of its justice transaction began, such that
+	// it may be restored should the broadcast fail.
+	priorPhase retributionPhase
+	priorSince time.Time
+
+	// cancel is closed if the retribution is cancelled by the operator
+	// before its justice transaction is broadcast.
+	cancel chan struct{}
+
+	// approve is closed once the operator approves the broadcast of the
+	// retribution's justice transaction.
+	approve chan struct{}
+
+	// broadcastAttempts is the number of times a justice transaction for
+	// the retribution has been broadcast, including fee bumps.
+	broadcastAttempts uint32
+
+	// lastErr is the error returned by the most recent failed broadcast,
+	// if any.
+	lastErr error
+
+	// valueAtRisk is the value of the breached outputs which the
+	// retribution is to sweep.
+	valueAtRisk btcutil.Amount
+}
+
+// setValueAtRisk records the value at risk of the active retribution for the
+// given channel point.
+func (b *breachArbiter) setValueAtRisk(chanPoint *wire.OutPoint,
+	valueAtRisk btcutil.Amount) {
+
+	b.retMtx.Lock()
+	defer b.retMtx.Unlock()
+
+	if status, ok := b.activeRetributions[*chanPoint]; ok {
+		status.valueAtRisk = valueAtRisk
+	}
+}
+
+// recordBroadcastAttempt records an attempt to broadcast a justice transaction
+// for each of the given channel points, along with its resulting error.
+func (b *breachArbiter) recordBroadcastAttempt(chanPoints []wire.OutPoint,
+	err error) {
+
+	b.retMtx.Lock()
+	defer b.retMtx.Unlock()
+
+	for _, chanPoint := range chanPoints {
+		status, ok := b.activeRetributions[chanPoint]
+		if !ok {
+			continue
+		}
+
+		status.broadcastAttempts++
+		if err != nil {
+			status.lastErr = err
+		}
+	}
+}
+
+// setRetributionPhase records that the retribution for the given channel point
+// has entered the target phase. The returned channel is closed should the
+// retribution be cancelled.
+func (b *breachArbiter) setRetributionPhase(chanPoint *wire.OutPoint,
+	phase retributionPhase) <-chan struct{} {
+
+	b.retMtx.Lock()
+	defer b.retMtx.Unlock()
+
+	status, ok := b.activeRetributions[*chanPoint]
+	if !ok {
+		status = &retributionStatus{
+			cancel:  make(chan struct{}),
+			approve: make(chan struct{}),
+		}
+		b.activeRetributions[*chanPoint] = status
+	}
+	status.phase = phase
+	status.since = time.Now()
+
+	return status.cancel
+}
+
+// awaitRetributionApproval moves the retribution for the given channel point
+// into the phase in which its justice transaction awaits approval. The
+// returned channel is closed once the operator approves it.
+func (b *breachArbiter) awaitRetributionApproval(
+	chanPoint *wire.OutPoint) <-chan struct{} {
+
+	b.setRetributionPhase(chanPoint, retPhaseAwaitingApproval)
+
+	b.retMtx.Lock()
+	defer b.retMtx.Unlock()
+
+	return b.activeRetributions[*chanPoint].approve
+}
+
+// ApproveRetribution approves the broadcast of the justice transaction for the
+// given channel point, which awaits approval as its value at risk exceeds the
+// JusticeApprovalThreshold. ErrNotAwaitingApproval is returned if the
+// retribution isn't awaiting approval. Approving a retribution more than once
+// has no further effect.
+func (b *breachArbiter) ApproveRetribution(chanPoint *wire.OutPoint) error {
+	b.retMtx.Lock()
+	defer b.retMtx.Unlock()
+
+	status, ok := b.activeRetributions[*chanPoint]
+	switch {
+	case !ok:
+		return fmt.Errorf("no active retribution for "+
+			"ChannelPoint(%v)", chanPoint)
+
+	case status.phase != retPhaseAwaitingApproval:
+		return ErrNotAwaitingApproval
+	}
+
+	select {
+	case <-status.approve:
+		return nil
+	default:
+	}
+	close(status.approve)
+
+	brarLog.Infof("Operator approved justice for ChannelPoint(%v)",
+		chanPoint)
+
+	return nil
+}
+
+// beginJusticeBroadcast moves the retribution for the given channel point into
+// the phase in which its justice transaction is broadcast, after which it can
+// no longer be cancelled. It returns false if the retribution has already been
+// cancelled, in which case the justice transaction must not be broadcast.
+func (b *breachArbiter) beginJusticeBroadcast(chanPoint *wire.OutPoint) bool {
+	b.retMtx.Lock()
+	defer b.retMtx.Unlock()
+
+	status, ok := b.activeRetributions[*chanPoint]
+	if !ok {
+		return false
+	}
+
+	select {
+	case <-status.cancel:
+		return false
+	default:
+	}
+
+	status.priorPhase = status.phase
+	status.priorSince = status.since
+	status.phase = retPhaseAwaitingJusticeConf
+	status.since = time.Now()
+
+	return true
+}
+
+// abortJusticeBroadcast restores the retribution for the given channel point
+// to the phase it was in before beginJusticeBroadcast, as its justice
+// transaction failed to be broadcast. It may then be cancelled once again, if
+// that phase allows.
+func (b *breachArbiter) abortJusticeBroadcast(chanPoint *wire.OutPoint) {
+	b.retMtx.Lock()
+	defer b.retMtx.Unlock()
+
+	status, ok := b.activeRetributions[*chanPoint]
+	if !ok || status.phase != retPhaseAwaitingJusticeConf {
+		return
+	}
+
+	status.phase = status.priorPhase
+	status.since = status.priorSince
+}
+
+// CancelRetribution withdraws the retribution for the given channel point,
+// allowing an operator to halt it, e.g. after recognizing a false positive.
+// A retribution may only be cancelled while awaiting confirmation of the
+// breach transaction, approval of its justice transaction, the unlocking of
+// the wallet, the release of a broadcast hold, or fees low enough for it to
+// be economic: once its justice transaction has been broadcast it can't be
+// unwound, and ErrJusticeBroadcast is returned. The channel remains pending
+// close, and is marked fully closed once the breach transaction confirms
+// after the next restart.
+func (b *breachArbiter) CancelRetribution(chanPoint *wire.OutPoint) error {
+	b.retMtx.Lock()
+	status, ok := b.activeRetributions[*chanPoint]
+	switch {
+	case !ok:
+		b.retMtx.Unlock()
+		return fmt.Errorf("no active retribution for "+
+			"ChannelPoint(%v)", chanPoint)
+
+	case status.phase != retPhaseAwaitingBreachConf &&
+		status.phase != retPhaseAwaitingApproval &&
+		status.phase != retPhaseWalletLocked &&
+		status.phase != retPhaseBroadcastHeld &&
+		status.phase != retPhaseUneconomic:
+
+		b.retMtx.Unlock()
+		return ErrJusticeBroadcast
+	}
+
+	close(status.cancel)
+	delete(b.activeRetributions, *chanPoint)
+	b.retMtx.Unlock()
+
+	brarLog.Warnf("Operator override: retribution for ChannelPoint(%v) "+
+		"cancelled before broadcasting justice", chanPoint)
+
+	return b.cfg.Store.Remove(chanPoint)
+}
+
+// clearRetributionPhase stops tracking the retribution for the given channel
+// point, signalling that it has reached a terminal state.
+func (b *breachArbiter) clearRetributionPhase(chanPoint *wire.OutPoint) {
+	b.retMtx.Lock()
+	delete(b.activeRetributions, *chanPoint)
+	b.retMtx.Unlock()
+}
+
+// releaseRetributionPhase stops tracking the retribution for the given channel
+// point, unless it has since been cancelled and its tracking taken over by
+// another retribution, as identified by the cancel channel of its status.
+func (b *breachArbiter) releaseRetributionPhase(chanPoint *wire.OutPoint,
+	cancel <-chan struct{}) {
+
+	b.retMtx.Lock()
+	defer b.retMtx.Unlock()
+
+	status, ok := b.activeRetributions[*chanPoint]
+	if ok && status.cancel == cancel {
+		delete(b.activeRetributions, *chanPoint)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"container/heap"
+	"sync"
+
+	"github.com/roasbeef/btcutil"
+)
+
+// retributionSlots bounds the number of retributions carried out concurrently.
+// Retributions beyond the limit wait for a free slot, which is handed to the
+// waiting retribution with the most value at risk.
+type retributionSlots struct {
+	mtx     sync.Mutex
+	active  int
+	limit   int
+	waiting retributionQueue
+
+	quit chan struct{}
+}
+
+// newRetributionSlots creates a retributionSlots allowing at most limit
+// retributions to be active at once. Any waiting retributions are released
+// once quit is closed.
+func newRetributionSlots(limit int, quit chan struct{}) *retributionSlots {
+	return &retributionSlots{
+		limit: limit,
+		quit:  quit,
+	}
+}
+
+// acquire blocks until a slot is available for a retribution with the given
+// value at risk, which must later be returned via release. If the
+// retribution is cancelled or the breach arbiter shuts down while waiting, an
+// error is returned and no slot is held.
+func (s *retributionSlots) acquire(value btcutil.Amount,
+	cancel <-chan struct{}) error {
+
+	s.mtx.Lock()
+	if s.active < s.limit {
+		s.active++
+		s.mtx.Unlock()
+		return nil
+	}
+
+	waiter := &retributionWaiter{
+		value: value,
+		ready: make(chan struct{}),
+	}
+	heap.Push(&s.waiting, waiter)
+	s.mtx.Unlock()
+
+	var err error
+	select {
+	case <-waiter.ready:
+		return nil
+	case <-cancel:
+		err = errRetributionCancelled
+	case <-s.quit:
+		err = errBreachArbiterExiting
+	}
+
+	// The slot may have been handed to us as we gave up, in which case
+	// it's passed on in turn.
+	s.mtx.Lock()
+	if waiter.index >= 0 {
+		heap.Remove(&s.waiting, waiter.index)
+		s.mtx.Unlock()
+		return err
+	}
+	s.mtx.Unlock()
+
+	s.release()
+	return err
+}
+
+// release returns a slot obtained via acquire, handing it to the waiting
+// retribution with the most value at risk, if any.
+func (s *retributionSlots) release() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.waiting.Len() == 0 {
+		s.active--
+		return
+	}
+
+	waiter := heap.Pop(&s.waiting).(*retributionWaiter)
+	close(waiter.ready)
+}
+
+// numWaiting returns the number of retributions awaiting a free slot.
+func (s *retributionSlots) numWaiting() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.waiting.Len()
+}
+
+// retributionWaiter is a retribution awaiting a free slot.
+type retributionWaiter struct {
+	value btcutil.Amount
+	ready chan struct{}
+
+	// index is the waiter's position within the retributionQueue, or -1
+	// once it has been removed.
+	index int
+}
+
+// retributionQueue is a max-heap of waiting retributions, ordered by their
+// value at risk.
+type retributionQueue []*retributionWaiter
+
+// Len returns the number of waiting retributions.
+//
+// NOTE: This is part of the heap.Interface interface.
+func (q retributionQueue) Len() int { return len(q) }
+
+// Less orders the retributions with the most value at risk first.
+//
+// NOTE: This is part of the heap.Interface interface.
+func (q retributionQueue) Less(i, j int) bool {
+	return q[i].value > q[j].value
+}
+
+// Swap swaps the waiting retributions at the given positions.
+//
+// NOTE: This is part of the heap.Interface interface.
+func (q retributionQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+// Push appends a waiting retribution to the queue.
+//
+// NOTE: This is part of the heap.Interface interface.
+func (q *retributionQueue) Push(x interface{}) {
+	waiter := x.(*retributionWaiter)
+	waiter.index = len(*q)
+	*q = append(*q, waiter)
+}
+
+// Pop removes the last waiting retribution from the queue.
+//
+// NOTE: This is part of the heap.Interface interface.
+func (q *retributionQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	waiter := old[n-1]
+	old[n-1] = nil
+	waiter.index = -1
+	*q = old[:n-1]
+
+	return waiter
+}
@@ -0,0 +1,1759 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/roasbeef/btcd/btcec"
+	"github.com/roasbeef/btcd/chaincfg/chainhash"
+	"github.com/roasbeef/btcd/wire"
+	"github.com/roasbeef/btcutil"
+)
+
+// retributionBucket stores retribution state on disk between detecting a
+// contract breach, broadcasting a justice transaction that sweeps the channel,
+// and finally witnessing the justice transaction confirm on the blockchain. It
+// is critical that such state is persisted on disk, so that if our node
+// restarts at any point during the retribution procedure, we can recover and
+// continue from the persisted state.
+var retributionBucket = []byte("retribution")
+
+// commitSweepBucket stores the state of sweeping our outputs from the remote
+// party's commitment transaction after a unilateral close, between detecting
+// the close, and the sweep transaction confirming. This allows the sweep to be
+// resumed, and the channel to be marked as fully closed only once it has
+// confirmed, should our node restart in the meantime.
+var commitSweepBucket = []byte("commit-sweep")
+
+// closeWatchBucket stores the height hint used to watch for the confirmation
+// of each pending close transaction. The hint is recorded when the close is
+// first watched, so that it's re-registered with the same hint after a restart,
+// allowing a confirmation that occurred in the meantime to be found.
+var closeWatchBucket = []byte("close-watch")
+
+// restoredChanBucket marks the channels whose state was restored from a backup,
+// and so may be stale, keyed by channel point.
+var restoredChanBucket = []byte("restored-channels")
+
+// retributionArchiveBucket stores the retributions of breaches whose justice
+// has been served, along with their outcome, if RetainBreachEvidence is set.
+// It's kept apart from the retributionBucket, so archived breaches are never
+// mistaken for retributions that are still in progress.
+var retributionArchiveBucket = []byte("retribution-archive")
+
+// retributionQuarantineBucket stores the raw contents of any persisted
+// retribution that could no longer be decoded, keyed as it was within the
+// retributionBucket. These are moved aside on startup, so that a single
+// corrupted entry can't prevent the remaining retributions from being carried
+// out, while being preserved for the operator to inspect.
+var retributionQuarantineBucket = []byte("retribution-quarantine")
+
+// errStoreAddAborted is returned by RetributionStore.Add if it's aborted via
+// its quit channel before the retribution has been written.
+var errStoreAddAborted = errors.New("retribution store addition aborted")
+
+// RetributionStore provides an interface for managing a persistent map from
+// wire.OutPoint -> retributionInfo. Upon learning of a breach, a BreachArbiter
+// should record the retributionInfo for the breached channel, which serves a
+// checkpoint in the event that retribution needs to be resumed after failure.
+// A RetributionStore provides an interface for managing the persisted set, as
+// well as mapping user defined functions over the entire on-disk contents.
+//
+// Calls to RetributionStore may occur concurrently. A concrete instance of
+// RetributionStore should use appropriate synchronization primitives, or
+// be otherwise safe for concurrent access.
+type RetributionStore interface {
+	// Add persists the retributionInfo to disk, using the information's
+	// chanPoint as the key. This method should overwrite any existing
+	// entires found under the same key, and an error should be raised if
+	// the addition fails. Should quit be closed before the addition has
+	// been written, errStoreAddAborted is returned without awaiting it.
+	Add(retInfo *retributionInfo, quit <-chan struct{}) error
+
+	// Remove deletes the retributionInfo from disk, if any exists, under
+	// the given key. An error should be re raised if the removal fails.
+	Remove(key *wire.OutPoint) error
+
+	// ForAll iterates over the existing on-disk contents and applies a
+	// chosen, read-only callback to each. This method should ensure that it
+	// immediately propagate any errors generated by the callback. Archived
+	// retributions are never included.
+	ForAll(cb func(*retributionInfo) error) error
+
+	// Archive atomically moves the retribution of the given archived
+	// breach out of the set of retributions, into a separate archive
+	// along with its outcome.
+	Archive(breach *ArchivedBreach) error
+
+	// ForAllArchived iterates over the archived breaches and applies a
+	// chosen, read-only callback to each, immediately propagating any
+	// errors generated by the callback.
+	ForAllArchived(cb func(*ArchivedBreach) error) error
+
+	// Recover moves any retributions that can no longer be decoded out of
+	// the set of retributions into a quarantine, returning a summary of
+	// those moved. An error is returned only if the quarantine couldn't
+	// be written.
+	Recover() (*RetributionRecovery, error)
+
+	// Verify decodes and validates each retribution, returning a report
+	// of any found to be corrupted. The store is left unmodified.
+	Verify() (*StoreIntegrityReport, error)
+}
+
+// StoreIntegrityReport summarizes the integrity of the retributions held
+// within a RetributionStore, as established by Verify.
+type StoreIntegrityReport struct {
+	// Healthy is the number of retributions found to be intact.
+	Healthy int
+
+	// Undecodable holds each retribution which could not be decoded.
+	Undecodable []CorruptRetribution
+
+	// Inconsistent holds each retribution which was decoded, but failed
+	// validation, e.g. as an output lacks its sign descriptor.
+	Inconsistent []CorruptRetribution
+
+	// BucketErr is non-nil if the retribution bucket itself could not be
+	// read in its entirety. Any retributions beyond the point of failure
+	// weren't verified.
+	BucketErr error
+}
+
+// Intact returns true if every retribution was verified to be intact.
+func (r *StoreIntegrityReport) Intact() bool {
+	return len(r.Undecodable) == 0 && len(r.Inconsistent) == 0 &&
+		r.BucketErr == nil
+}
+
+// CorruptRetribution describes a persisted retribution found to be corrupted
+// by RetributionStore.Verify.
+type CorruptRetribution struct {
+	// Key is the raw key under which the retribution is stored.
+	Key []byte
+
+	// ChanPoint is the channel point decoded from Key, or nil if the key
+	// itself is corrupted.
+	ChanPoint *wire.OutPoint
+
+	// Err describes the corruption.
+	Err error
+}
+
+// QuarantinedRetribution describes a persisted retribution which could not be
+// decoded, and was therefore moved out of the set of retributions.
+type QuarantinedRetribution struct {
+	// Key is the raw key under which the retribution was stored.
+	Key []byte
+
+	// ChanPoint is the channel point decoded from Key, or nil if the key
+	// itself is corrupted.
+	ChanPoint *wire.OutPoint
+
+	// Err is the error encountered while decoding the retribution.
+	Err error
+}
+
+// RetributionRecovery summarizes the recovery of the retribution store
+// performed on startup, for the operator to follow up on.
+type RetributionRecovery struct {
+	// Quarantined holds each retribution which could not be decoded.
+	Quarantined []QuarantinedRetribution
+
+	// BucketErr is non-nil if the retribution bucket itself could not be
+	// read in its entirety. Any retributions beyond the point of failure
+	// were neither recovered nor quarantined.
+	BucketErr error
+}
+
+// retributionBucketError is returned by the retributionStore when the
+// retribution bucket itself is corrupted, as opposed to a single retribution
+// within it.
+type retributionBucketError struct {
+	err error
+}
+
+// Error returns a human readable description of the corruption.
+func (e *retributionBucketError) Error() string {
+	return fmt.Sprintf("retribution bucket is corrupted: %v", e.err)
+}
+
+// ArchivedBreach is the evidence of a breach whose justice has been served,
+// retained if RetainBreachEvidence is set. Breaches too small to be worth
+// punishing are always archived, as no other record of them remains.
+type ArchivedBreach struct {
+	// ChanPoint is the channel point of the breached channel.
+	ChanPoint wire.OutPoint
+
+	// BreachTxid is the txid of the revoked commitment transaction
+	// broadcast by the remote party.
+	BreachTxid chainhash.Hash
+
+	// JusticeTxid is the txid of the most recently broadcast justice
+	// transaction, or zero if it wasn't recorded.
+	JusticeTxid chainhash.Hash
+
+	// RemoteIdentity is the identity public key of the breaching party.
+	RemoteIdentity [33]byte
+
+	// Capacity is the capacity of the breached channel.
+	Capacity btcutil.Amount
+
+	// Recovered is the value of the breached outputs claimed by the
+	// justice transaction.
+	Recovered btcutil.Amount
+
+	// BreachDetectedAt is the time at which the breach was first detected,
+	// or zero if it wasn't recorded.
+	BreachDetectedAt time.Time
+
+	// ArchivedAt is the time at which the breach was archived.
+	ArchivedAt time.Time
+
+	// JusticeConfHeight is the height of the block in which the justice
+	// transaction confirmed, or zero if it wasn't recorded.
+	JusticeConfHeight uint32
+
+	// Proof is the proof that the remote party broadcast a revoked state,
+	// or nil if it wasn't recorded.
+	Proof *BreachProof
+
+	// Breakdown is the breakdown of the funds swept by the justice
+	// transaction, or nil if it wasn't recorded.
+	Breakdown *JusticeBreakdown
+
+	// Uneconomic is true if the breach was acknowledged without justice
+	// being served, as the fee required to sweep the breached outputs
+	// exceeded their value.
+	Uneconomic bool
+
+	// Outputs describes each of the outputs of the breach transaction we
+	// intended to sweep. It's populated by ArchivedBreaches.
+	Outputs []BreachedOutputView
+
+	// retribution is the full retribution record at the time justice was
+	// served.
+	retribution *retributionInfo
+}
+
+// Encode serializes the archived breach into the passed byte stream. Only the
+// outcome is written alongside the retribution, as the remaining fields are
+// restored from it.
+func (a *ArchivedBreach) Encode(w io.Writer) error {
+	var scratch [8]byte
+
+	if _, err := w.Write(a.JusticeTxid[:]); err != nil {
+		return err
+	}
+
+	binary.BigEndian.PutUint64(scratch[:], uint64(a.Recovered))
+	if _, err := w.Write(scratch[:]); err != nil {
+		return err
+	}
+
+	binary.BigEndian.PutUint64(scratch[:], uint64(a.ArchivedAt.UnixNano()))
+	if _, err := w.Write(scratch[:]); err != nil {
+		return err
+	}
+
+	return a.retribution.Encode(w)
+}
+
+// Decode deserializes an archived breach from the passed byte stream.
+func (a *ArchivedBreach) Decode(r io.Reader) error {
+	var scratch [8]byte
+
+	if _, err := io.ReadFull(r, a.JusticeTxid[:]); err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(r, scratch[:]); err != nil {
+		return err
+	}
+	a.Recovered = btcutil.Amount(binary.BigEndian.Uint64(scratch[:]))
+
+	if _, err := io.ReadFull(r, scratch[:]); err != nil {
+		return err
+	}
+	a.ArchivedAt = time.Unix(0, int64(binary.BigEndian.Uint64(scratch[:])))
+
+	a.retribution = &retributionInfo{}
+	if err := a.retribution.Decode(r); err != nil {
+		return err
+	}
+	a.fillFromRetribution()
+
+	return nil
+}
+
+// fillFromRetribution populates the fields of the archived breach that are
+// derived from its retribution.
+func (a *ArchivedBreach) fillFromRetribution() {
+	ret := a.retribution
+
+	a.ChanPoint = ret.chanPoint
+	a.BreachTxid = ret.commitHash
+	a.Capacity = ret.capacity
+	a.BreachDetectedAt = ret.breachDetectedAt
+	a.JusticeConfHeight = ret.justiceConfHeight
+	a.Proof = ret.breachProof
+	a.Breakdown = ret.justiceBreakdown
+	a.Uneconomic = ret.uneconomic
+	if ret.hasRemoteIdentity() {
+		copy(
+			a.RemoteIdentity[:],
+			ret.remoteIdentity.SerializeCompressed(),
+		)
+	}
+}
+
+// VerifyStore verifies the integrity of every retribution held within the
+// retribution store, without modifying it, so that any corruption can be
+// addressed before the retributions are needed.
+func (b *breachArbiter) VerifyStore() (*StoreIntegrityReport, error) {
+	return b.cfg.Store.Verify()
+}
+
+// ArchivedBreaches returns the evidence of each breach whose justice has been
+// served and archived, see RetainBreachEvidence.
+func (b *breachArbiter) ArchivedBreaches() ([]*ArchivedBreach, error) {
+	var breaches []*ArchivedBreach
+	err := b.cfg.Store.ForAllArchived(func(a *ArchivedBreach) error {
+		// A failed lookup leaves an output reported as spent, as it
+		// almost certainly is once justice has been served.
+		a.Outputs = nil
+		if a.retribution != nil {
+			for _, bo := range a.retribution.breachedOutputs() {
+				view, _ := b.viewBreachedOutput(bo)
+				a.Outputs = append(a.Outputs, view)
+			}
+		}
+
+		breaches = append(breaches, a)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return breaches, nil
+}
+
+// RetributionKV is the minimal transactional key-value store backing the
+// retributionStore. Keys are grouped within buckets, each created upon the
+// first write to it. By default, the retributionStore is backed by buckets
+// within the channel database, however an alternate backend may be provided,
+// e.g. to back up the breach recovery state apart from the channel database,
+// or to replicate it.
+type RetributionKV interface {
+	// View executes the passed function within a read-only transaction.
+	View(func(RetributionKVTx) error) error
+
+	// Update executes the passed function within a read-write
+	// transaction, which is committed only if the function returns nil.
+	Update(func(RetributionKVTx) error) error
+}
+
+// RetributionKVTx is a transaction over a RetributionKV.
+type RetributionKVTx interface {
+	// Get returns the value stored under the key within the bucket, or
+	// nil if either doesn't exist. The value is only valid for the
+	// lifetime of the transaction.
+	Get(bucket, key []byte) []byte
+
+	// Put stores the value under the key within the bucket, creating the
+	// bucket if it doesn't yet exist.
+	Put(bucket, key, value []byte) error
+
+	// Delete removes the key from the bucket. Removing a key that doesn't
+	// exist, or from a bucket that doesn't exist, isn't an error.
+	Delete(bucket, key []byte) error
+
+	// ForEach applies the callback to each key/value pair within the
+	// bucket, in key order, a bucket that doesn't exist being empty. The
+	// bucket mustn't be modified by the callback. Should the bucket be
+	// found to be corrupted, a *retributionBucketError is returned once
+	// the pairs preceding the corruption have been visited.
+	ForEach(bucket []byte, cb func(k, v []byte) error) error
+}
+
+// boltRetributionKV is the default RetributionKV, backed by buckets within the
+// channel database.
+type boltRetributionKV struct {
+	db *channeldb.DB
+}
+
+// newBoltRetributionKV creates a RetributionKV backed by the given channel
+// database.
+func newBoltRetributionKV(db *channeldb.DB) *boltRetributionKV {
+	return &boltRetributionKV{
+		db: db,
+	}
+}
+
+// View executes the passed function within a read-only bolt transaction.
+func (b *boltRetributionKV) View(f func(RetributionKVTx) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return f(&boltRetributionKVTx{tx: tx})
+	})
+}
+
+// Update executes the passed function within a read-write bolt transaction.
+func (b *boltRetributionKV) Update(f func(RetributionKVTx) error) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return f(&boltRetributionKVTx{tx: tx})
+	})
+}
+
+// boltRetributionKVTx is a RetributionKVTx wrapping a bolt transaction.
+type boltRetributionKVTx struct {
+	tx *bolt.Tx
+}
+
+// Get returns the value stored under the key within the bucket, or nil if
+// either doesn't exist.
+func (t *boltRetributionKVTx) Get(bucket, key []byte) []byte {
+	b := t.tx.Bucket(bucket)
+	if b == nil {
+		return nil
+	}
+
+	return b.Get(key)
+}
+
+// Put stores the value under the key within the bucket, creating the bucket if
+// it doesn't yet exist.
+func (t *boltRetributionKVTx) Put(bucket, key, value []byte) error {
+	b, err := t.tx.CreateBucketIfNotExists(bucket)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(key, value)
+}
+
+// Delete removes the key from the bucket, if both exist.
+func (t *boltRetributionKVTx) Delete(bucket, key []byte) error {
+	b := t.tx.Bucket(bucket)
+	if b == nil {
+		return nil
+	}
+
+	return b.Delete(key)
+}
+
+// ForEach applies the callback to each key/value pair within the bucket, if it
+// exists, guarding against a corrupted bucket.
+func (t *boltRetributionKVTx) ForEach(bucket []byte,
+	cb func(k, v []byte) error) error {
+
+	b := t.tx.Bucket(bucket)
+	if b == nil {
+		return nil
+	}
+
+	return walkBucket(b, cb)
+}
+
+// retributionStore handles persistence of retribution states to disk and is
+// backed by a RetributionKV, by default buckets within the channel database.
+// The primary responsibility of the retribution store is to ensure that we can
+// recover from a restart in the middle of a breached contract retribution.
+type retributionStore struct {
+	kv RetributionKV
+
+	// batchInterval is the duration for which an addition is held back
+	// so that any further additions arriving in the meantime can be
+	// written within the same database transaction. If zero, each
+	// addition is written within its own transaction.
+	batchInterval time.Duration
+
+	// batchMtx guards pendingBatch.
+	batchMtx sync.Mutex
+
+	// pendingBatch is the batch of additions currently waiting to be
+	// written, or nil if there are none.
+	pendingBatch *retributionBatch
+}
+
+// retributionBatch is a set of retributions that are written to disk within a
+// single database transaction.
+type retributionBatch struct {
+	rets []*retributionInfo
+
+	// err is the result of writing the batch, and may only be read once
+	// done has been closed.
+	err error
+
+	// done is closed once the batch has been written.
+	done chan struct{}
+}
+
+// newRetributionStore creates a new instance of a retributionStore.
+func newRetributionStore(db *channeldb.DB) *retributionStore {
+	return newKVRetributionStore(newBoltRetributionKV(db), 0)
+}
+
+// newBatchedRetributionStore creates a new instance of a retributionStore
+// which batches additions arriving within batchInterval of one another into a
+// single database transaction. This trades a small delay in persisting each
+// addition for fewer disk syncs under heavy load. A batchInterval of zero
+// disables batching.
+func newBatchedRetributionStore(db *channeldb.DB,
+	batchInterval time.Duration) *retributionStore {
+
+	return newKVRetributionStore(newBoltRetributionKV(db), batchInterval)
+}
+
+// newKVRetributionStore creates a new instance of a retributionStore backed by
+// the given RetributionKV, batching additions as newBatchedRetributionStore
+// does.
+func newKVRetributionStore(kv RetributionKV,
+	batchInterval time.Duration) *retributionStore {
+
+	return &retributionStore{
+		kv:            kv,
+		batchInterval: batchInterval,
+	}
+}
+
+// Add adds a retribution state to the retributionStore, which is then persisted
+// to disk. If batching is enabled, the addition may be written along with
+// others, however Add never returns successfully before the retribution has
+// been durably written, so that a breach is never acted upon before it has
+// been recorded. Should quit be closed while the batch is pending, Add returns
+// errStoreAddAborted, though the batch is still written once due.
+func (rs *retributionStore) Add(ret *retributionInfo,
+	quit <-chan struct{}) error {
+
+	if rs.batchInterval == 0 {
+		return rs.writeRetributions([]*retributionInfo{ret})
+	}
+
+	rs.batchMtx.Lock()
+	batch := rs.pendingBatch
+	if batch == nil {
+		batch = &retributionBatch{
+			done: make(chan struct{}),
+		}
+		rs.pendingBatch = batch
+
+		time.AfterFunc(rs.batchInterval, rs.flushBatch)
+	}
+	batch.rets = append(batch.rets, ret)
+	rs.batchMtx.Unlock()
+
+	select {
+	case <-batch.done:
+		return batch.err
+	case <-quit:
+		return errStoreAddAborted
+	}
+}
+
+// flushBatch writes the pending batch of additions to disk, notifying all
+// callers waiting on it.
+func (rs *retributionStore) flushBatch() {
+	rs.batchMtx.Lock()
+	batch := rs.pendingBatch
+	rs.pendingBatch = nil
+	rs.batchMtx.Unlock()
+
+	batch.err = rs.writeRetributions(batch.rets)
+	close(batch.done)
+}
+
+// writeRetributions persists the given retributions within a single database
+// transaction. Should any of them fail to be written, none are.
+func (rs *retributionStore) writeRetributions(rets []*retributionInfo) error {
+	return rs.kv.Update(func(tx RetributionKVTx) error {
+		for _, ret := range rets {
+			var outBuf bytes.Buffer
+			err := writeOutpoint(&outBuf, &ret.chanPoint)
+			if err != nil {
+				return err
+			}
+
+			var retBuf bytes.Buffer
+			if err := ret.Encode(&retBuf); err != nil {
+				return err
+			}
+
+			err = tx.Put(
+				retributionBucket, outBuf.Bytes(),
+				retBuf.Bytes(),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Remove removes a retribution state from the retributionStore database.
+func (rs *retributionStore) Remove(key *wire.OutPoint) error {
+	return rs.kv.Update(func(tx RetributionKVTx) error {
+		var outBuf bytes.Buffer
+		if err := writeOutpoint(&outBuf, key); err != nil {
+			return err
+		}
+
+		return tx.Delete(retributionBucket, outBuf.Bytes())
+	})
+}
+
+// ForAll iterates through all stored retributions and executes the passed
+// callback function on each retribution. Retributions which can't be decoded
+// are skipped, as they're left for Recover to quarantine. Should the bucket
+// itself be corrupted, the callback is executed on each retribution preceding
+// the corruption before a *retributionBucketError is returned.
+func (rs *retributionStore) ForAll(cb func(*retributionInfo) error) error {
+	return rs.kv.View(func(tx RetributionKVTx) error {
+		// We fetch each serialized retribution info, deserialize it,
+		// and execute the passed in callback function on it.
+		return tx.ForEach(retributionBucket, func(outBytes,
+			retBytes []byte) error {
+
+			ret := &retributionInfo{}
+			if err := ret.Decode(
+				bytes.NewBuffer(retBytes),
+			); err != nil {
+				brarLog.Warnf("Skipping undecodable retribution "+
+					"stored under %x: %v", outBytes, err)
+				return nil
+			}
+
+			return cb(ret)
+		})
+	})
+}
+
+// Recover moves each retribution which can no longer be decoded into the
+// quarantine bucket, within a single database transaction. If the retribution
+// bucket itself is corrupted, those retributions preceding the corruption are
+// still recovered, with the corruption being reported within the summary.
+func (rs *retributionStore) Recover() (*RetributionRecovery, error) {
+	recovery := &RetributionRecovery{}
+	err := rs.kv.Update(func(tx RetributionKVTx) error {
+		// As a bucket mustn't be modified while iterating over it, we
+		// first gather the undecodable retributions, only moving them
+		// once the walk is complete.
+		var (
+			quarantined []QuarantinedRetribution
+			rawRets     [][]byte
+		)
+		err := tx.ForEach(retributionBucket, func(outBytes,
+			retBytes []byte) error {
+
+			ret := &retributionInfo{}
+			err := ret.Decode(bytes.NewReader(retBytes))
+			if err == nil {
+				return nil
+			}
+
+			q := QuarantinedRetribution{
+				Key: append([]byte(nil), outBytes...),
+				Err: err,
+			}
+			var chanPoint wire.OutPoint
+			if readOutpoint(
+				bytes.NewReader(outBytes), &chanPoint,
+			) == nil {
+				q.ChanPoint = &chanPoint
+			}
+
+			quarantined = append(quarantined, q)
+			rawRets = append(rawRets, append([]byte(nil), retBytes...))
+
+			return nil
+		})
+		if bucketErr, ok := err.(*retributionBucketError); ok {
+			recovery.BucketErr = bucketErr
+		} else if err != nil {
+			return err
+		}
+
+		if len(quarantined) == 0 {
+			return nil
+		}
+
+		for i, q := range quarantined {
+			err := tx.Put(
+				retributionQuarantineBucket, q.Key, rawRets[i],
+			)
+			if err != nil {
+				return err
+			}
+			err = tx.Delete(retributionBucket, q.Key)
+			if err != nil {
+				return err
+			}
+		}
+		recovery.Quarantined = quarantined
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return recovery, nil
+}
+
+// Verify decodes and validates each retribution within the store, within a
+// single read-only database transaction. If the retribution bucket itself is
+// corrupted, the retributions preceding the corruption are still verified,
+// with the corruption being reported within the report.
+func (rs *retributionStore) Verify() (*StoreIntegrityReport, error) {
+	report := &StoreIntegrityReport{}
+	err := rs.kv.View(func(tx RetributionKVTx) error {
+		err := tx.ForEach(retributionBucket, func(k, v []byte) error {
+			corrupt := CorruptRetribution{
+				Key: append([]byte(nil), k...),
+			}
+			var chanPoint wire.OutPoint
+			if readOutpoint(
+				bytes.NewReader(k), &chanPoint,
+			) == nil {
+				corrupt.ChanPoint = &chanPoint
+			}
+
+			ret := &retributionInfo{}
+			err := ret.Decode(bytes.NewReader(v))
+			if err != nil {
+				corrupt.Err = err
+				report.Undecodable = append(
+					report.Undecodable, corrupt,
+				)
+				return nil
+			}
+
+			err = ret.verifyIntegrity()
+			if err == nil && corrupt.ChanPoint == nil {
+				err = errors.New("undecodable key")
+			}
+			if err == nil && *corrupt.ChanPoint != ret.chanPoint {
+				err = fmt.Errorf("stored under "+
+					"ChannelPoint(%v)", corrupt.ChanPoint)
+			}
+			if err != nil {
+				corrupt.Err = err
+				report.Inconsistent = append(
+					report.Inconsistent, corrupt,
+				)
+				return nil
+			}
+
+			report.Healthy++
+			return nil
+		})
+		if bucketErr, ok := err.(*retributionBucketError); ok {
+			report.BucketErr = bucketErr
+			return nil
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// walkBucket applies the given callback to each key/value pair within the
+// bucket, skipping any nested buckets. Unlike bolt's ForEach, a corrupted
+// page encountered while advancing the cursor doesn't bring down the daemon,
+// but is instead reported as a *retributionBucketError once the pairs
+// preceding it have been visited.
+func walkBucket(bucket *bolt.Bucket, cb func(k, v []byte) error) error {
+	c := bucket.Cursor()
+
+	k, v, err := guardCursor(c.First)
+	for ; err == nil && k != nil; k, v, err = guardCursor(c.Next) {
+		if v == nil {
+			continue
+		}
+		if err := cb(k, v); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// guardCursor advances a bolt cursor using the given step, converting any
+// panic caused by a corrupted page into a *retributionBucketError.
+func guardCursor(step func() ([]byte, []byte)) (k, v []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &retributionBucketError{
+				err: fmt.Errorf("%v", r),
+			}
+		}
+	}()
+
+	k, v = step()
+	return k, v, nil
+}
+
+// commitSweepStore persists the sweeps of our outputs from the remote party's
+// commitment transactions after unilateral closes, keyed by channel point. It
+// is backed by a boltdb bucket.
+type commitSweepStore struct {
+	db *channeldb.DB
+}
+
+// newCommitSweepStore creates a new instance of a commitSweepStore.
+func newCommitSweepStore(db *channeldb.DB) *commitSweepStore {
+	return &commitSweepStore{
+		db: db,
+	}
+}
+
+// Add persists the given sweep, overwriting any existing sweep of the same
+// channel.
+func (cs *commitSweepStore) Add(sweep *commitSweepInfo) error {
+	return cs.db.Update(func(tx *bolt.Tx) error {
+		sweepBucket, err := tx.CreateBucketIfNotExists(
+			commitSweepBucket,
+		)
+		if err != nil {
+			return err
+		}
+
+		var outBuf bytes.Buffer
+		if err := writeOutpoint(&outBuf, &sweep.chanPoint); err != nil {
+			return err
+		}
+
+		var sweepBuf bytes.Buffer
+		if err := sweep.Encode(&sweepBuf); err != nil {
+			return err
+		}
+
+		return sweepBucket.Put(outBuf.Bytes(), sweepBuf.Bytes())
+	})
+}
+
+// Remove deletes the sweep of the given channel, if any exists.
+func (cs *commitSweepStore) Remove(chanPoint *wire.OutPoint) error {
+	return cs.db.Update(func(tx *bolt.Tx) error {
+		sweepBucket := tx.Bucket(commitSweepBucket)
+		if sweepBucket == nil {
+			return nil
+		}
+
+		var outBuf bytes.Buffer
+		if err := writeOutpoint(&outBuf, chanPoint); err != nil {
+			return err
+		}
+
+		return sweepBucket.Delete(outBuf.Bytes())
+	})
+}
+
+// ForAll iterates through all persisted sweeps and executes the passed
+// callback function on each.
+func (cs *commitSweepStore) ForAll(cb func(*commitSweepInfo) error) error {
+	return cs.db.View(func(tx *bolt.Tx) error {
+		sweepBucket := tx.Bucket(commitSweepBucket)
+		if sweepBucket == nil {
+			return nil
+		}
+
+		return sweepBucket.ForEach(func(_, sweepBytes []byte) error {
+			sweep := &commitSweepInfo{}
+			err := sweep.Decode(bytes.NewReader(sweepBytes))
+			if err != nil {
+				return err
+			}
+
+			return cb(sweep)
+		})
+	})
+}
+
+// closeWatchStore persists the height hints used to watch for the
+// confirmation of pending close transactions, keyed by channel point. It is
+// backed by a boltdb bucket.
+type closeWatchStore struct {
+	db *channeldb.DB
+}
+
+// newCloseWatchStore creates a new instance of a closeWatchStore.
+func newCloseWatchStore(db *channeldb.DB) *closeWatchStore {
+	return &closeWatchStore{
+		db: db,
+	}
+}
+
+// Add persists the height hint of the close of the given channel, overwriting
+// any existing hint.
+func (cs *closeWatchStore) Add(chanPoint *wire.OutPoint,
+	heightHint uint32) error {
+
+	return cs.db.Update(func(tx *bolt.Tx) error {
+		watchBucket, err := tx.CreateBucketIfNotExists(
+			closeWatchBucket,
+		)
+		if err != nil {
+			return err
+		}
+
+		var outBuf bytes.Buffer
+		if err := writeOutpoint(&outBuf, chanPoint); err != nil {
+			return err
+		}
+
+		var hintBytes [4]byte
+		binary.BigEndian.PutUint32(hintBytes[:], heightHint)
+
+		return watchBucket.Put(outBuf.Bytes(), hintBytes[:])
+	})
+}
+
+// Remove deletes the height hint of the close of the given channel, if any
+// exists.
+func (cs *closeWatchStore) Remove(chanPoint *wire.OutPoint) error {
+	return cs.db.Update(func(tx *bolt.Tx) error {
+		watchBucket := tx.Bucket(closeWatchBucket)
+		if watchBucket == nil {
+			return nil
+		}
+
+		var outBuf bytes.Buffer
+		if err := writeOutpoint(&outBuf, chanPoint); err != nil {
+			return err
+		}
+
+		return watchBucket.Delete(outBuf.Bytes())
+	})
+}
+
+// ForAll iterates through all persisted height hints and executes the passed
+// callback function on each.
+func (cs *closeWatchStore) ForAll(
+	cb func(chanPoint wire.OutPoint, heightHint uint32) error) error {
+
+	return cs.db.View(func(tx *bolt.Tx) error {
+		watchBucket := tx.Bucket(closeWatchBucket)
+		if watchBucket == nil {
+			return nil
+		}
+
+		return watchBucket.ForEach(func(k, v []byte) error {
+			var chanPoint wire.OutPoint
+			err := readOutpoint(bytes.NewReader(k), &chanPoint)
+			if err != nil {
+				return err
+			}
+			if len(v) != 4 {
+				return fmt.Errorf("invalid height hint of "+
+					"ChannelPoint(%v)", chanPoint)
+			}
+
+			return cb(chanPoint, binary.BigEndian.Uint32(v))
+		})
+	})
+}
+
+// restoredChanStore persists the channels marked as restored from a backup,
+// whose state may be stale, keyed by channel point. It is backed by a boltdb
+// bucket.
+type restoredChanStore struct {
+	db *channeldb.DB
+}
+
+// newRestoredChanStore creates a new instance of a restoredChanStore.
+func newRestoredChanStore(db *channeldb.DB) *restoredChanStore {
+	return &restoredChanStore{
+		db: db,
+	}
+}
+
+// Add marks the given channel as restored from a backup.
+func (rs *restoredChanStore) Add(chanPoint *wire.OutPoint) error {
+	return rs.db.Update(func(tx *bolt.Tx) error {
+		restoredBucket, err := tx.CreateBucketIfNotExists(
+			restoredChanBucket,
+		)
+		if err != nil {
+			return err
+		}
+
+		var outBuf bytes.Buffer
+		if err := writeOutpoint(&outBuf, chanPoint); err != nil {
+			return err
+		}
+
+		return restoredBucket.Put(outBuf.Bytes(), []byte{})
+	})
+}
+
+// Remove clears the mark of the given channel as restored from a backup, if
+// any exists.
+func (rs *restoredChanStore) Remove(chanPoint *wire.OutPoint) error {
+	if rs.db == nil {
+		return nil
+	}
+
+	return rs.db.Update(func(tx *bolt.Tx) error {
+		restoredBucket := tx.Bucket(restoredChanBucket)
+		if restoredBucket == nil {
+			return nil
+		}
+
+		var outBuf bytes.Buffer
+		if err := writeOutpoint(&outBuf, chanPoint); err != nil {
+			return err
+		}
+
+		return restoredBucket.Delete(outBuf.Bytes())
+	})
+}
+
+// Has returns true if the given channel is marked as restored from a backup.
+func (rs *restoredChanStore) Has(chanPoint *wire.OutPoint) (bool, error) {
+	if rs.db == nil {
+		return false, nil
+	}
+
+	var restored bool
+	err := rs.db.View(func(tx *bolt.Tx) error {
+		restoredBucket := tx.Bucket(restoredChanBucket)
+		if restoredBucket == nil {
+			return nil
+		}
+
+		var outBuf bytes.Buffer
+		if err := writeOutpoint(&outBuf, chanPoint); err != nil {
+			return err
+		}
+
+		restored = restoredBucket.Get(outBuf.Bytes()) != nil
+		return nil
+	})
+
+	return restored, err
+}
+
+// Encode serializes the commitment sweep into the passed byte stream.
+func (cs *commitSweepInfo) Encode(w io.Writer) error {
+	var scratch [4]byte
+
+	if err := writeOutpoint(w, &cs.chanPoint); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(cs.closeTxid[:]); err != nil {
+		return err
+	}
+
+	binary.BigEndian.PutUint32(scratch[:], cs.closeHeight)
+	if _, err := w.Write(scratch[:]); err != nil {
+		return err
+	}
+
+	err := wire.WriteVarInt(w, 0, uint64(len(cs.inputs)))
+	if err != nil {
+		return err
+	}
+	for _, input := range cs.inputs {
+		if err := input.Encode(w); err != nil {
+			return err
+		}
+	}
+
+	if cs.sweepTx == nil {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	if _, err := w.Write([]byte{1}); err != nil {
+		return err
+	}
+
+	if err := cs.sweepTx.Serialize(w); err != nil {
+		return err
+	}
+
+	var amtScratch [8]byte
+	binary.BigEndian.PutUint64(amtScratch[:], uint64(cs.sweptAmt))
+	_, err = w.Write(amtScratch[:])
+	return err
+}
+
+// Decode deserializes a commitment sweep from the passed byte stream.
+func (cs *commitSweepInfo) Decode(r io.Reader) error {
+	var scratch [4]byte
+
+	if err := readOutpoint(r, &cs.chanPoint); err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(r, cs.closeTxid[:]); err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(r, scratch[:]); err != nil {
+		return err
+	}
+	cs.closeHeight = binary.BigEndian.Uint32(scratch[:])
+
+	numInputs, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return err
+	}
+	cs.inputs = make([]*breachedOutput, 0, numInputs)
+	for i := uint64(0); i < numInputs; i++ {
+		input := &breachedOutput{}
+		if err := input.Decode(r); err != nil {
+			return err
+		}
+
+		cs.inputs = append(cs.inputs, input)
+	}
+
+	if _, err := io.ReadFull(r, scratch[:1]); err != nil {
+		return err
+	}
+	if scratch[0] == 0 {
+		return nil
+	}
+
+	cs.sweepTx = &wire.MsgTx{}
+	if err := cs.sweepTx.Deserialize(r); err != nil {
+		return err
+	}
+
+	// Sweeps persisted by an older version lack the swept amount.
+	var amtScratch [8]byte
+	_, err = io.ReadFull(r, amtScratch[:])
+	switch {
+	case err == io.EOF:
+		return nil
+	case err != nil:
+		return err
+	}
+	cs.sweptAmt = btcutil.Amount(binary.BigEndian.Uint64(amtScratch[:]))
+
+	return nil
+}
+
+// Archive moves the retribution of the given breach from the retribution
+// bucket into the archive bucket, along with its outcome, within a single
+// database transaction.
+func (rs *retributionStore) Archive(breach *ArchivedBreach) error {
+	return rs.kv.Update(func(tx RetributionKVTx) error {
+		var outBuf bytes.Buffer
+		err := writeOutpoint(&outBuf, &breach.retribution.chanPoint)
+		if err != nil {
+			return err
+		}
+
+		var archiveBuf bytes.Buffer
+		if err := breach.Encode(&archiveBuf); err != nil {
+			return err
+		}
+
+		err = tx.Put(
+			retributionArchiveBucket, outBuf.Bytes(),
+			archiveBuf.Bytes(),
+		)
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(retributionBucket, outBuf.Bytes())
+	})
+}
+
+// ForAllArchived iterates through all archived breaches and executes the
+// passed callback function on each.
+func (rs *retributionStore) ForAllArchived(
+	cb func(*ArchivedBreach) error) error {
+
+	return rs.kv.View(func(tx RetributionKVTx) error {
+		return tx.ForEach(retributionArchiveBucket, func(_,
+			archiveBytes []byte) error {
+
+			breach := &ArchivedBreach{}
+			err := breach.Decode(bytes.NewReader(archiveBytes))
+			if err != nil {
+				return err
+			}
+
+			return cb(breach)
+		})
+	})
+}
+
+// Encode serializes the retribution into the passed byte stream. The fields
+// common to every version of the encoding are written first, followed by the
+// retributionRecordsMarker, the encoding version and the remaining fields as a
+// set of type-length-value records.
+func (ret *retributionInfo) Encode(w io.Writer) error {
+	if err := ret.encodeCommon(w); err != nil {
+		return err
+	}
+
+	_, err := w.Write(
+		[]byte{retributionRecordsMarker, retributionEncodingVersion},
+	)
+	if err != nil {
+		return err
+	}
+
+	records, err := ret.records()
+	if err != nil {
+		return err
+	}
+	if err := wire.WriteVarInt(w, 0, uint64(len(records))); err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := wire.WriteVarInt(w, 0, record.typ); err != nil {
+			return err
+		}
+		if err := wire.WriteVarBytes(w, 0, record.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeCommon serializes the fields of the retribution common to every
+// version of its encoding into the passed byte stream.
+func (ret *retributionInfo) encodeCommon(w io.Writer) error {
+	var scratch [8]byte
+
+	if _, err := w.Write(ret.commitHash[:]); err != nil {
+		return err
+	}
+
+	if err := writeOutpoint(w, &ret.chanPoint); err != nil {
+		return err
+	}
+
+	remoteIdentity := ret.rawRemoteIdentity[:]
+	if ret.hasRemoteIdentity() {
+		remoteIdentity = ret.remoteIdentity.SerializeCompressed()
+	}
+	if _, err := w.Write(remoteIdentity); err != nil {
+		return err
+	}
+
+	binary.BigEndian.PutUint64(scratch[:8], uint64(ret.capacity))
+	if _, err := w.Write(scratch[:8]); err != nil {
+		return err
+	}
+
+	binary.BigEndian.PutUint64(scratch[:8], uint64(ret.settledBalance))
+	if _, err := w.Write(scratch[:8]); err != nil {
+		return err
+	}
+
+	if err := ret.selfOutput.Encode(w); err != nil {
+		return err
+	}
+
+	if err := ret.revokedOutput.Encode(w); err != nil {
+		return err
+	}
+
+	numHtlcOutputs := len(ret.htlcOutputs)
+	if err := wire.WriteVarInt(w, 0, uint64(numHtlcOutputs)); err != nil {
+		return err
+	}
+
+	for i := 0; i < numHtlcOutputs; i++ {
+		if err := ret.htlcOutputs[i].Encode(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Dencode deserializes a retribution from the passed byte stream.
+func (ret *retributionInfo) Decode(r io.Reader) error {
+	var scratch [33]byte
+
+	if _, err := io.ReadFull(r, scratch[:32]); err != nil {
+		return err
+	}
+	hash, err := chainhash.NewHash(scratch[:32])
+	if err != nil {
+		return err
+	}
+	ret.commitHash = *hash
+
+	if err := readOutpoint(r, &ret.chanPoint); err != nil {
+		return err
+	}
+
+	if _, err = io.ReadFull(r, scratch[:33]); err != nil {
+		return err
+	}
+	// The remote identity isn't required to sweep the breached outputs,
+	// so should it be corrupted, we'll still decode the remainder of the
+	// record rather than abandon the retribution.
+	remoteIdentity, err := btcec.ParsePubKey(scratch[:33], btcec.S256())
+	if err != nil {
+		brarLog.Warnf("Unable to parse remote identity of "+
+			"ChannelPoint(%v), proceeding without it: %v",
+			ret.chanPoint, err)
+		copy(ret.rawRemoteIdentity[:], scratch[:33])
+	} else {
+		ret.remoteIdentity = *remoteIdentity
+	}
+
+	if _, err := io.ReadFull(r, scratch[:8]); err != nil {
+		return err
+	}
+	ret.capacity = btcutil.Amount(binary.BigEndian.Uint64(scratch[:8]))
+
+	if _, err := io.ReadFull(r, scratch[:8]); err != nil {
+		return err
+	}
+	ret.settledBalance = btcutil.Amount(
+		binary.BigEndian.Uint64(scratch[:8]))
+
+	ret.selfOutput = &breachedOutput{}
+	if err := ret.selfOutput.Decode(r); err != nil {
+		return err
+	}
+
+	ret.revokedOutput = &breachedOutput{}
+	if err := ret.revokedOutput.Decode(r); err != nil {
+		return err
+	}
+
+	numHtlcOutputsU64, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return err
+	}
+	numHtlcOutputs := int(numHtlcOutputsU64)
+
+	ret.htlcOutputs = make([]*breachedOutput, numHtlcOutputs)
+	for i := 0; i < numHtlcOutputs; i++ {
+		ret.htlcOutputs[i] = &breachedOutput{}
+		if err := ret.htlcOutputs[i].Decode(r); err != nil {
+			return err
+		}
+	}
+
+	// Retributions persisted before records were introduced end here.
+	// Otherwise, the remaining fields follow as records.
+	_, err = io.ReadFull(r, scratch[:1])
+	switch {
+	case err == io.EOF:
+		return nil
+	case err != nil:
+		return err
+	case scratch[0] != retributionRecordsMarker:
+		return fmt.Errorf("unknown retribution records marker %v",
+			scratch[0])
+	}
+
+	if _, err := io.ReadFull(r, scratch[:1]); err != nil {
+		return err
+	}
+	if scratch[0] != retributionEncodingVersion {
+		return fmt.Errorf("unknown retribution encoding version %v",
+			scratch[0])
+	}
+
+	numRecords, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < numRecords; i++ {
+		typ, err := wire.ReadVarInt(r, 0)
+		if err != nil {
+			return err
+		}
+		value, err := wire.ReadVarBytes(
+			r, 0, wire.MaxBlockPayload, "record",
+		)
+		if err != nil {
+			return err
+		}
+		if err := ret.decodeRecord(typ, value); err != nil {
+			return fmt.Errorf("unable to decode record %v: %v",
+				typ, err)
+		}
+	}
+
+	return nil
+}
+
+// retributionRecordsMarker precedes the records of an encoded retribution.
+const retributionRecordsMarker = 0xff
+
+// retributionEncodingVersion is the version of the encoding of the records
+// of a retribution.
+const retributionEncodingVersion = 1
+
+// The types of the records of an encoded retribution. Each record is written
+// as its type, followed by its length prefixed value.
+const (
+	retRecordDetectedAt uint64 = iota + 1
+	retRecordSweepPkScript
+	retRecordJusticeTxid
+	retRecordContestDelay
+	retRecordJusticeConfHeight
+	retRecordExpectedJusticeFee
+	retRecordApproval
+	retRecordCompleted
+	retRecordBreachProof
+	retRecordDeadlineHeight
+	retRecordRefundTimeouts
+	retRecordPenaltyPkScript
+	retRecordJusticeBreakdown
+	retRecordRevokedStateNum
+	retRecordUneconomic
+	retRecordPaymentHashes
+	retRecordSignDescRefs
+)
+
+// retributionRecord is a single type-length-value record of an encoded
+// retribution.
+type retributionRecord struct {
+	typ   uint64
+	value []byte
+}
+
+// records returns the records holding the fields of the retribution which
+// follow those common to every version of its encoding. A breach proof or
+// justice breakdown is only recorded if known.
+func (ret *retributionInfo) records() ([]retributionRecord, error) {
+	uint32Value := func(v uint32) []byte {
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], v)
+		return b[:]
+	}
+	uint64Value := func(v uint64) []byte {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], v)
+		return b[:]
+	}
+	boolValue := func(v bool) []byte {
+		if v {
+			return []byte{1}
+		}
+		return []byte{0}
+	}
+
+	// The detection time is recorded as nanoseconds since the unix epoch,
+	// with zero denoting an unknown time.
+	var detectedAt int64
+	if !ret.breachDetectedAt.IsZero() {
+		detectedAt = ret.breachDetectedAt.UnixNano()
+	}
+
+	// The refund timeouts and payment hashes of the HTLC outputs are
+	// recorded in the order of the outputs themselves.
+	var refundTimeouts, paymentHashes []byte
+	for _, htlc := range ret.htlcOutputs {
+		refundTimeouts = append(
+			refundTimeouts, uint32Value(htlc.refundTimeout)...,
+		)
+		paymentHashes = append(paymentHashes, htlc.paymentHash[:]...)
+	}
+
+	records := []retributionRecord{
+		{retRecordDetectedAt, uint64Value(uint64(detectedAt))},
+		{retRecordSweepPkScript, ret.sweepPkScript},
+		{retRecordJusticeTxid, ret.justiceTxid[:]},
+		{
+			retRecordContestDelay,
+			uint32Value(ret.revokedOutput.contestDelay),
+		},
+		{retRecordJusticeConfHeight, uint32Value(ret.justiceConfHeight)},
+		{
+			retRecordExpectedJusticeFee,
+			uint64Value(uint64(ret.expectedJusticeFee)),
+		},
+		{retRecordApproval, []byte{byte(ret.approval)}},
+		{retRecordCompleted, boolValue(ret.completed)},
+		{retRecordDeadlineHeight, uint32Value(ret.deadlineHeight)},
+		{retRecordRefundTimeouts, refundTimeouts},
+		{retRecordPenaltyPkScript, ret.penaltyPkScript},
+		{retRecordRevokedStateNum, uint64Value(ret.revokedStateNum)},
+		{retRecordUneconomic, boolValue(ret.uneconomic)},
+		{retRecordPaymentHashes, paymentHashes},
+		{
+			retRecordSignDescRefs,
+			append(
+				boolValue(ret.selfOutput.signDescRef),
+				boolValue(ret.revokedOutput.signDescRef)...,
+			),
+		},
+	}
+
+	if ret.breachProof != nil {
+		var buf bytes.Buffer
+		if err := ret.breachProof.Encode(&buf); err != nil {
+			return nil, err
+		}
+		records = append(records, retributionRecord{
+			retRecordBreachProof, buf.Bytes(),
+		})
+	}
+
+	if ret.justiceBreakdown != nil {
+		var buf bytes.Buffer
+		if err := ret.justiceBreakdown.Encode(&buf); err != nil {
+			return nil, err
+		}
+		records = append(records, retributionRecord{
+			retRecordJusticeBreakdown, buf.Bytes(),
+		})
+	}
+
+	return records, nil
+}
+
+// decodeRecord populates the field of the retribution held by the record of
+// the given type. Records of an unknown type are ignored, such that those
+// added by later versions don't render the retribution undecodable.
+func (ret *retributionInfo) decodeRecord(typ uint64, value []byte) error {
+	// fixedLen checks that the record's value is of the given length.
+	fixedLen := func(n int) error {
+		if len(value) != n {
+			return fmt.Errorf("expected %v bytes, got %v", n,
+				len(value))
+		}
+		return nil
+	}
+
+	switch typ {
+	case retRecordDetectedAt:
+		if err := fixedLen(8); err != nil {
+			return err
+		}
+		detectedAt := int64(binary.BigEndian.Uint64(value))
+		if detectedAt != 0 {
+			ret.breachDetectedAt = time.Unix(0, detectedAt)
+		}
+
+	case retRecordSweepPkScript:
+		if len(value) != 0 {
+			ret.sweepPkScript = value
+		}
+
+	case retRecordJusticeTxid:
+		if err := fixedLen(chainhash.HashSize); err != nil {
+			return err
+		}
+		copy(ret.justiceTxid[:], value)
+
+	case retRecordContestDelay:
+		if err := fixedLen(4); err != nil {
+			return err
+		}
+		ret.revokedOutput.contestDelay = binary.BigEndian.Uint32(value)
+
+	case retRecordJusticeConfHeight:
+		if err := fixedLen(4); err != nil {
+			return err
+		}
+		ret.justiceConfHeight = binary.BigEndian.Uint32(value)
+
+	case retRecordExpectedJusticeFee:
+		if err := fixedLen(8); err != nil {
+			return err
+		}
+		ret.expectedJusticeFee = btcutil.Amount(
+			binary.BigEndian.Uint64(value),
+		)
+
+	case retRecordApproval:
+		if err := fixedLen(1); err != nil {
+			return err
+		}
+		ret.approval = justiceApproval(value[0])
+
+	case retRecordCompleted:
+		if err := fixedLen(1); err != nil {
+			return err
+		}
+		ret.completed = value[0] == 1
+
+	case retRecordBreachProof:
+		ret.breachProof = &BreachProof{}
+		return ret.breachProof.Decode(bytes.NewReader(value))
+
+	case retRecordDeadlineHeight:
+		if err := fixedLen(4); err != nil {
+			return err
+		}
+		ret.deadlineHeight = binary.BigEndian.Uint32(value)
+
+	case retRecordRefundTimeouts:
+		if err := fixedLen(4 * len(ret.htlcOutputs)); err != nil {
+			return err
+		}
+		for i, htlc := range ret.htlcOutputs {
+			htlc.refundTimeout = binary.BigEndian.Uint32(
+				value[4*i:],
+			)
+		}
+
+	case retRecordPenaltyPkScript:
+		if len(value) != 0 {
+			ret.penaltyPkScript = value
+		}
+
+	case retRecordJusticeBreakdown:
+		ret.justiceBreakdown = &JusticeBreakdown{}
+		return ret.justiceBreakdown.Decode(bytes.NewReader(value))
+
+	case retRecordRevokedStateNum:
+		if err := fixedLen(8); err != nil {
+			return err
+		}
+		ret.revokedStateNum = binary.BigEndian.Uint64(value)
+
+	case retRecordUneconomic:
+		if err := fixedLen(1); err != nil {
+			return err
+		}
+		ret.uneconomic = value[0] == 1
+
+	case retRecordPaymentHashes:
+		n := chainhash.HashSize
+		if err := fixedLen(n * len(ret.htlcOutputs)); err != nil {
+			return err
+		}
+		for i, htlc := range ret.htlcOutputs {
+			copy(htlc.paymentHash[:], value[n*i:])
+		}
+
+	case retRecordSignDescRefs:
+		if err := fixedLen(2); err != nil {
+			return err
+		}
+
+		// The descriptors of outputs persisted by reference hold just
+		// their public key until re-derived, see rederiveSignDescs.
+		outputs := []*breachedOutput{ret.selfOutput, ret.revokedOutput}
+		for i, bo := range outputs {
+			if value[i] != 1 {
+				continue
+			}
+			bo.signDescRef = true
+			bo.signDescriptor = lnwallet.SignDescriptor{
+				PubKey: bo.signDescriptor.PubKey,
+			}
+		}
+	}
+
+	return nil
+}
+
+// Encode serializes a breachedOutput into the passed byte stream.
+func (bo *breachedOutput) Encode(w io.Writer) error {
+	var scratch [8]byte
+
+	binary.BigEndian.PutUint64(scratch[:8], uint64(bo.amt))
+	if _, err := w.Write(scratch[:8]); err != nil {
+		return err
+	}
+
+	if err := writeOutpoint(w, &bo.outpoint); err != nil {
+		return err
+	}
+
+	// A descriptor persisted by reference is written with just its
+	// public key, as the remainder is re-derived from the channel's
+	// backup.
+	signDesc := &bo.signDescriptor
+	if bo.signDescRef {
+		signDesc = &lnwallet.SignDescriptor{
+			PubKey: bo.signDescriptor.PubKey,
+			Output: &wire.TxOut{},
+		}
+	}
+	if err := lnwallet.WriteSignDescriptor(w, signDesc); err != nil {
+		return err
+	}
+
+	binary.BigEndian.PutUint16(scratch[:2], uint16(bo.witnessType))
+	if _, err := w.Write(scratch[:2]); err != nil {
+		return err
+	}
+
+	if bo.twoStageClaim {
+		scratch[0] = 1
+	} else {
+		scratch[0] = 0
+	}
+	if _, err := w.Write(scratch[:1]); err != nil {
+		return err
+	}
+
+	// The preimage is only serialized for outputs that need it to be
+	// swept, keeping the encoding of all other outputs unchanged.
+	if witnessRequiresPreimage(bo.witnessType) {
+		if _, err := w.Write(bo.preimage[:]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Decode deserializes a breachedOutput from the passed byte stream.
+func (bo *breachedOutput) Decode(r io.Reader) error {
+	var scratch [8]byte
+
+	if _, err := io.ReadFull(r, scratch[:8]); err != nil {
+		return err
+	}
+	bo.amt = btcutil.Amount(binary.BigEndian.Uint64(scratch[:8]))
+
+	if err := readOutpoint(r, &bo.outpoint); err != nil {
+		return err
+	}
+
+	if err := lnwallet.ReadSignDescriptor(
+		r, &bo.signDescriptor); err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(r, scratch[:2]); err != nil {
+		return err
+	}
+	bo.witnessType = lnwallet.WitnessType(
+		binary.BigEndian.Uint16(scratch[:2]))
+
+	if _, err := io.ReadFull(r, scratch[:1]); err != nil {
+		return err
+	}
+	if scratch[0] == 1 {
+		bo.twoStageClaim = true
+	} else {
+		bo.twoStageClaim = false
+	}
+
+	if witnessRequiresPreimage(bo.witnessType) {
+		if _, err := io.ReadFull(r, bo.preimage[:]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
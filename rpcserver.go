@@ -779,10 +779,24 @@ func (r *rpcServer) CloseChannel(in *lnrpc.CloseChannelRequest,
 			},
 		}
 
+		// If we don't have an output active on the commitment
+		// transaction, nor any outgoing HTLC's, then the channel is
+		// marked as closed once the commitment transaction confirms.
+		// As nothing is left to sweep which would otherwise keep it
+		// pending, we'll wait for the commitment transaction to reach
+		// the reorg safety depth beforehand.
+		numConfs := uint32(1)
+		if closeSummary.SelfOutputSignDesc == nil &&
+			len(closeSummary.HtlcResolutions) == 0 {
+
+			brarCfg := r.server.breachArbiter.cfg
+			numConfs = brarCfg.UnilateralCloseSafetyDepth
+		}
+
 		errChan = make(chan error, 1)
 		notifier := r.server.cc.chainNotifier
-		go waitForChanToClose(uint32(bestHeight), notifier, errChan, chanPoint,
-			closingTxid, func() {
+		go waitForChanToClose(uint32(bestHeight), numConfs, notifier,
+			errChan, chanPoint, closingTxid, func() {
 				// Respond to the local subsystem which
 				// requested the channel closure.
 				updateChan <- &lnrpc.CloseStatusUpdate{
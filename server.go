@@ -22,6 +22,7 @@ import (
 	"github.com/roasbeef/btcd/btcec"
 	"github.com/roasbeef/btcd/chaincfg/chainhash"
 	"github.com/roasbeef/btcd/connmgr"
+	"github.com/roasbeef/btcd/wire"
 	"github.com/roasbeef/btcutil"
 
 	"github.com/go-errors/errors"
@@ -286,8 +287,43 @@ func newServer(listenAddrs []string, chanDB *channeldb.DB, cc *chainControl,
 		return nil, err
 	}
 
-	s.breachArbiter = newBreachArbiter(cc.wallet, chanDB, cc.chainNotifier,
-		s.htlcSwitch, s.cc.chainIO, s.cc.feeEstimator)
+	broadcastTargets := make([]BroadcastTarget, 0, len(cfg.JusticeBroadcastURLs))
+	for _, url := range cfg.JusticeBroadcastURLs {
+		broadcastTargets = append(broadcastTargets, BroadcastTarget{
+			Name:        url,
+			Broadcaster: newHTTPBroadcaster(url),
+		})
+	}
+
+	s.breachArbiter = newBreachArbiter(&BreachConfig{
+		ChainIO: s.cc.chainIO,
+		CloseLink: func(chanPoint *wire.OutPoint,
+			closeType htlcswitch.ChannelCloseType) {
+
+			s.htlcSwitch.CloseLink(chanPoint, closeType)
+		},
+		DB:                   chanDB,
+		Estimator:            s.cc.feeEstimator,
+		Notifier:             cc.chainNotifier,
+		Wallet:               cc.wallet,
+		Signer:               cc.wallet.Cfg.Signer,
+		Store:                newRetributionStore(chanDB),
+		JusticeConfTimeout:   cfg.JusticeConfTimeout,
+		RetainBreachEvidence: cfg.RetainBreachEvidence,
+		TxInMempool:          cc.txInMempool,
+		BroadcastTargets:     broadcastTargets,
+		MempoolSpends:        cc.mempoolSpends,
+		ResolveHTLC: func(payHash [32]byte, amt lnwire.MilliSatoshi,
+			preimage *[32]byte) error {
+
+			return s.htlcSwitch.ResolveHTLC(payHash, amt, preimage)
+		},
+		JusticeApprovalThreshold: btcutil.Amount(
+			cfg.JusticeApprovalThreshold,
+		),
+		JusticeApprovalTimeout:     cfg.JusticeApprovalTimeout,
+		JusticeApprovalAutoApprove: cfg.JusticeApprovalAutoApprove,
+	})
 
 	// TODO(roasbeef): introduce closure and config system to decouple the
 	// initialization above ^
@@ -342,6 +378,23 @@ func (s *server) Start() error {
 	if err := s.utxoNursery.Start(); err != nil {
 		return err
 	}
+	// Should the channel database have been restored from a backup, the
+	// state of the channels within it may be stale, so each is marked as
+	// such before the breach arbiter begins watching them.
+	if cfg.RestoredFromBackup {
+		channels, err := s.chanDB.FetchAllChannels()
+		if err != nil {
+			return err
+		}
+		for _, channel := range channels {
+			err := s.breachArbiter.MarkRestoredFromBackup(
+				&channel.FundingOutpoint,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
 	if err := s.breachArbiter.Start(); err != nil {
 		return err
 	}
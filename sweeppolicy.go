@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/roasbeef/btcutil"
+)
+
+// distributeSweep distributes the gross amount swept, less the fee, across
+// numOutputs outputs as decided by the SweepAmountPolicy. ErrSweepOutputDust is
+// returned should any of the outputs fall below the SweepDustThreshold.
+func (b *breachArbiter) distributeSweep(gross, fee btcutil.Amount,
+	numOutputs int) ([]btcutil.Amount, error) {
+
+	amts, err := b.cfg.SweepAmountPolicy.Distribute(gross, fee, numOutputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(amts) != numOutputs {
+		return nil, fmt.Errorf("sweep amount policy returned %v "+
+			"amounts, expected %v", len(amts), numOutputs)
+	}
+
+	var distributed btcutil.Amount
+	for _, amt := range amts {
+		if amt < b.cfg.SweepDustThreshold {
+			return nil, ErrSweepOutputDust
+		}
+		distributed += amt
+	}
+
+	// Any satoshis left undistributed are donated to the miners, but the
+	// policy must never create value out of thin air.
+	if distributed > gross-fee {
+		return nil, fmt.Errorf("sweep amount policy distributed %v, "+
+			"exceeding the net amount of %v", distributed,
+			gross-fee)
+	}
+
+	return amts, nil
+}
+
+// ErrSweepOutputDust is returned by a SweepAmountPolicy if the amount being
+// swept can't be distributed without creating a dust output.
+var ErrSweepOutputDust = errors.New("sweep output would be dust")
+
+// SweepAmountPolicy decides how the value swept by a transaction, net of its
+// fee, is distributed across the transaction's outputs.
+type SweepAmountPolicy interface {
+	// Distribute splits the gross amount being swept, less the fee, across
+	// numOutputs outputs, returning the value of each. Implementations
+	// must never distribute more than the net amount, though they may
+	// distribute less: any satoshis left undistributed are paid to miners
+	// in addition to the fee. ErrSweepOutputDust must be returned rather
+	// than producing an output below dust.
+	Distribute(gross, fee btcutil.Amount,
+		numOutputs int) ([]btcutil.Amount, error)
+}
+
+// SweepRemainder denotes where an EvenSweepPolicy places the satoshis left
+// over after evenly dividing the net amount across all outputs.
+type SweepRemainder uint8
+
+const (
+	// RemainderToFirst assigns the rounding remainder to the first output.
+	RemainderToFirst SweepRemainder = iota
+
+	// RemainderToLast assigns the rounding remainder to the last output.
+	RemainderToLast
+
+	// RemainderToFee donates the rounding remainder to the miner fee.
+	RemainderToFee
+)
+
+// EvenSweepPolicy is a SweepAmountPolicy which splits the net amount being
+// swept evenly across all outputs, placing any rounding remainder as
+// directed.
+type EvenSweepPolicy struct {
+	// DustLimit is the smallest output value the policy will produce.
+	DustLimit btcutil.Amount
+
+	// Remainder determines where the rounding remainder is placed.
+	Remainder SweepRemainder
+}
+
+// Distribute splits the gross amount, less the fee, evenly across numOutputs
+// outputs.
+//
+// NOTE: This is part of the SweepAmountPolicy interface.
+func (p *EvenSweepPolicy) Distribute(gross, fee btcutil.Amount,
+	numOutputs int) ([]btcutil.Amount, error) {
+
+	if numOutputs <= 0 {
+		return nil, fmt.Errorf("invalid number of outputs: %v",
+			numOutputs)
+	}
+
+	net := gross - fee
+	if net <= 0 {
+		return nil, ErrSweepOutputDust
+	}
+
+	share := net / btcutil.Amount(numOutputs)
+	remainder := net % btcutil.Amount(numOutputs)
+
+	amts := make([]btcutil.Amount, numOutputs)
+	for i := range amts {
+		amts[i] = share
+	}
+
+	switch p.Remainder {
+	case RemainderToFirst:
+		amts[0] += remainder
+	case RemainderToLast:
+		amts[numOutputs-1] += remainder
+	case RemainderToFee:
+	default:
+		return nil, fmt.Errorf("unknown remainder placement: %v",
+			p.Remainder)
+	}
+
+	// As the remainder is only ever added to an output, it suffices to
+	// check the smallest share against the dust limit.
+	if share < p.DustLimit {
+		return nil, ErrSweepOutputDust
+	}
+
+	return amts, nil
+}
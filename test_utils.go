@@ -195,11 +195,6 @@ func createTestPeer(notifier chainntnfs.ChainNotifier,
 		return nil, nil, nil, nil, err
 	}
 
-	cleanUpFunc := func() {
-		os.RemoveAll(bobPath)
-		os.RemoveAll(alicePath)
-	}
-
 	aliceSigner := &mockSigner{aliceKeyPriv}
 	bobSigner := &mockSigner{bobKeyPriv}
 
@@ -214,6 +209,13 @@ func createTestPeer(notifier chainntnfs.ChainNotifier,
 		return nil, nil, nil, nil, err
 	}
 
+	cleanUpFunc := func() {
+		channelAlice.Stop()
+		channelBob.Stop()
+		os.RemoveAll(bobPath)
+		os.RemoveAll(alicePath)
+	}
+
 	chainIO := &mockChainIO{}
 	wallet := &lnwallet.LightningWallet{
 		WalletController: &mockWalletController{
@@ -230,6 +232,7 @@ func createTestPeer(notifier chainntnfs.ChainNotifier,
 
 	breachArbiter := &breachArbiter{
 		settledContracts: make(chan *wire.OutPoint, 10),
+		coopCloses:       make(map[wire.OutPoint]chainhash.Hash),
 	}
 
 	s := &server{